@@ -20,6 +20,9 @@ func runDev() error {
 	if err := checkTool("entr", "brew install entr"); err != nil {
 		return err
 	}
+	if err := loadProjectConfig(); err != nil {
+		return err
+	}
 
 	// Check if dev.sh exists (user project) or we're in framework
 	if _, err := os.Stat("dev.sh"); err == nil {
@@ -40,6 +43,10 @@ func runDev() error {
 
 // runServe starts the server without file watching
 func runServe() error {
+	if err := loadProjectConfig(); err != nil {
+		return err
+	}
+
 	// Check if main.go exists
 	if _, err := os.Stat("main.go"); err == nil {
 		// User project
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/stukennedy/irgo/pkg/config"
+)
+
+// runConfig handles "irgo config <subcommand>"
+func runConfig(args []string) error {
+	if len(args) < 1 || args[0] != "check" {
+		return fmt.Errorf("usage: irgo config check [file]")
+	}
+	path := config.FileName
+	if len(args) > 1 {
+		path = args[1]
+	}
+	return checkConfig(path)
+}
+
+// checkConfig validates a config file, printing warnings for unknown keys
+func checkConfig(path string) error {
+	warnings, err := config.Check(path)
+	for _, w := range warnings {
+		fmt.Printf("Warning: %s\n", w)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	fmt.Printf("%s is valid\n", path)
+	return nil
+}
+
+// loadProjectConfig loads gohtmx.toml (if present) before dev/serve so
+// problems are reported up front. The app itself reads the same file.
+func loadProjectConfig() error {
+	if _, err := config.Load(""); err != nil {
+		return fmt.Errorf("invalid %s: %w", config.FileName, err)
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigCheck(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.toml")
+	os.WriteFile(valid, []byte("[app]\ntitle = \"Todo\"\n"), 0644)
+	bad := filepath.Join(dir, "bad.toml")
+	os.WriteFile(bad, []byte("[window]\nwidth = \"wide\"\n"), 0644)
+
+	if err := runConfig([]string{"check", valid}); err != nil {
+		t.Errorf("expected valid config, got %v", err)
+	}
+	if err := runConfig([]string{"check", bad}); err == nil || !strings.Contains(err.Error(), "window.width") {
+		t.Errorf("expected window.width error, got %v", err)
+	}
+	if err := runConfig([]string{"check", filepath.Join(dir, "missing.toml")}); err == nil {
+		t.Error("expected error for missing file")
+	}
+	if err := runConfig(nil); err == nil || !strings.Contains(err.Error(), "usage") {
+		t.Errorf("expected usage error, got %v", err)
+	}
+}
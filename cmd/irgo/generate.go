@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/stukennedy/irgo/pkg/routemeta"
+)
+
+//go:embed codegen/*.tmpl
+var codegenFS embed.FS
+
+// clientTemplates maps --lang values to codegen templates
+var clientTemplates = map[string]string{
+	"swift":  "codegen/client.swift.tmpl",
+	"kotlin": "codegen/client.kt.tmpl",
+}
+
+// runGenerate handles "irgo generate <kind> [flags]"
+func runGenerate(args []string) error {
+	if len(args) < 1 || args[0] != "client" {
+		return fmt.Errorf("usage: irgo generate client --lang <swift|kotlin> --routes <routes.json>")
+	}
+
+	fs := flag.NewFlagSet("generate client", flag.ContinueOnError)
+	lang := fs.String("lang", "", "target language (swift or kotlin)")
+	routes := fs.String("routes", "routes.json", "route metadata written with routemeta.Write")
+	out := fs.String("out", "", "output file (default: stdout)")
+	name := fs.String("name", "IrgoRoutes", "generated type name")
+	pkg := fs.String("package", "irgo", "Kotlin package name")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	f, err := os.Open(*routes)
+	if err != nil {
+		return fmt.Errorf("failed to read route metadata: %w", err)
+	}
+	docs, err := routemeta.Read(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("invalid route metadata in %s: %w", *routes, err)
+	}
+
+	var buf bytes.Buffer
+	if err := generateClient(&buf, *lang, clientOptions{Name: *name, Package: *pkg}, docs); err != nil {
+		return err
+	}
+
+	if *out == "" {
+		_, err = os.Stdout.Write(buf.Bytes())
+		return err
+	}
+	if err := os.WriteFile(*out, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Generated %s client for %d routes: %s\n", *lang, len(docs), *out)
+	return nil
+}
+
+// clientOptions configures generated client naming
+type clientOptions struct {
+	Name    string // Type/object name
+	Package string // Kotlin package
+}
+
+// generateClient renders a typed client for docs in the given language
+func generateClient(w io.Writer, lang string, opts clientOptions, docs []routemeta.Doc) error {
+	path, ok := clientTemplates[lang]
+	if !ok {
+		return fmt.Errorf("unsupported language %q (expected swift or kotlin)", lang)
+	}
+
+	tmpl, err := template.New("").Funcs(template.FuncMap{
+		"join":         strings.Join,
+		"swiftParams":  swiftParams,
+		"swiftPath":    swiftPath,
+		"kotlinParams": kotlinParams,
+		"kotlinPath":   kotlinPath,
+	}).ParseFS(codegenFS, path)
+	if err != nil {
+		return err
+	}
+
+	data := struct {
+		clientOptions
+		Routes []clientRoute
+	}{clientOptions: opts}
+
+	seen := make(map[string]bool)
+	for _, d := range docs {
+		route := newClientRoute(d)
+		if seen[route.Func] {
+			return fmt.Errorf("duplicate route name %q (%s %s)", route.Func, d.Method, d.Pattern)
+		}
+		seen[route.Func] = true
+		data.Routes = append(data.Routes, route)
+	}
+
+	return tmpl.ExecuteTemplate(w, path[strings.LastIndex(path, "/")+1:], data)
+}
+
+// clientRoute is the template model for one documented route
+type clientRoute struct {
+	Func     string
+	Method   string
+	Pattern  string
+	Summary  string
+	Triggers []string
+	Segments []pathSegment
+	Params   []string // Path param identifiers, in order
+	Fields   []clientField
+}
+
+// pathSegment is a literal piece of a pattern or a path param reference
+type pathSegment struct {
+	Literal string
+	Param   string // Identifier; empty for literals
+}
+
+type clientField struct {
+	Name     string // Form field name
+	Ident    string // Parameter identifier
+	Type     string
+	Required bool
+}
+
+var patternParamRe = regexp.MustCompile(`\{([^}:]+)(?::[^}]*)?\}`)
+
+func newClientRoute(d routemeta.Doc) clientRoute {
+	r := clientRoute{
+		Func:     identifier(d.Identifier()),
+		Method:   d.Method,
+		Pattern:  d.Pattern,
+		Summary:  d.Summary,
+		Triggers: d.Triggers,
+	}
+
+	last := 0
+	for _, m := range patternParamRe.FindAllStringSubmatchIndex(d.Pattern, -1) {
+		if m[0] > last {
+			r.Segments = append(r.Segments, pathSegment{Literal: d.Pattern[last:m[0]]})
+		}
+		param := identifier(d.Pattern[m[2]:m[3]])
+		r.Segments = append(r.Segments, pathSegment{Param: param})
+		r.Params = append(r.Params, param)
+		last = m[1]
+	}
+	if last < len(d.Pattern) {
+		r.Segments = append(r.Segments, pathSegment{Literal: d.Pattern[last:]})
+	}
+
+	for _, f := range d.Fields {
+		typ := f.Type
+		if typ == "" {
+			typ = "string"
+		}
+		r.Fields = append(r.Fields, clientField{
+			Name:     f.Name,
+			Ident:    identifier(f.Name),
+			Type:     typ,
+			Required: f.Required,
+		})
+	}
+	return r
+}
+
+// identifier converts a name like "todo_id" or "due-date" to lowerCamelCase
+func identifier(name string) string {
+	var b strings.Builder
+	upper := false
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = b.Len() > 0
+			continue
+		}
+		if b.Len() == 0 {
+			if unicode.IsDigit(r) {
+				b.WriteRune('_')
+			}
+			r = unicode.ToLower(r)
+		} else if upper {
+			r = unicode.ToUpper(r)
+		}
+		upper = false
+		b.WriteRune(r)
+	}
+	if b.Len() == 0 {
+		return "_"
+	}
+	return b.String()
+}
+
+var swiftTypes = map[string]string{"string": "String", "int": "Int", "bool": "Bool"}
+
+func swiftParams(r clientRoute) string {
+	var params []string
+	for _, p := range r.Params {
+		params = append(params, p+": String")
+	}
+	for _, f := range r.Fields {
+		if f.Required {
+			params = append(params, f.Ident+": "+swiftTypes[f.Type])
+		} else {
+			params = append(params, f.Ident+": "+swiftTypes[f.Type]+"? = nil")
+		}
+	}
+	return strings.Join(params, ", ")
+}
+
+func swiftPath(r clientRoute) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, s := range r.Segments {
+		if s.Param != "" {
+			b.WriteString(`\(escape(` + s.Param + `))`)
+			continue
+		}
+		b.WriteString(strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s.Literal))
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+var kotlinTypes = map[string]string{"string": "String", "int": "Int", "bool": "Boolean"}
+
+func kotlinParams(r clientRoute) string {
+	var params []string
+	for _, p := range r.Params {
+		params = append(params, p+": String")
+	}
+	for _, f := range r.Fields {
+		if f.Required {
+			params = append(params, f.Ident+": "+kotlinTypes[f.Type])
+		} else {
+			params = append(params, f.Ident+": "+kotlinTypes[f.Type]+"? = null")
+		}
+	}
+	return strings.Join(params, ", ")
+}
+
+func kotlinPath(r clientRoute) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, s := range r.Segments {
+		if s.Param != "" {
+			b.WriteString("${escape(" + s.Param + ")}")
+			continue
+		}
+		b.WriteString(strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`).Replace(s.Literal))
+	}
+	b.WriteByte('"')
+	return b.String()
+}
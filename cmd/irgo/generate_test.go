@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stukennedy/irgo/pkg/routemeta"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func loadFixture(t *testing.T) []routemeta.Doc {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", "routes.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	docs, err := routemeta.Read(f)
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	return docs
+}
+
+func TestGenerateClientSnapshots(t *testing.T) {
+	docs := loadFixture(t)
+
+	tests := []struct {
+		lang   string
+		golden string
+	}{
+		{"swift", "client.swift.golden"},
+		{"kotlin", "client.kt.golden"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.lang, func(t *testing.T) {
+			var buf bytes.Buffer
+			opts := clientOptions{Name: "IrgoRoutes", Package: "com.example.app"}
+			if err := generateClient(&buf, tt.lang, opts, docs); err != nil {
+				t.Fatalf("generateClient failed: %v", err)
+			}
+
+			path := filepath.Join("testdata", tt.golden)
+			if *update {
+				if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("missing golden file (run with -update): %v", err)
+			}
+			if buf.String() != string(want) {
+				t.Errorf("generated %s client does not match %s:\n%s", tt.lang, path, buf.String())
+			}
+		})
+	}
+}
+
+func TestGenerateClientUnsupportedLang(t *testing.T) {
+	err := generateClient(&bytes.Buffer{}, "dart", clientOptions{}, nil)
+	if err == nil || !strings.Contains(err.Error(), "unsupported language") {
+		t.Errorf("expected unsupported language error, got %v", err)
+	}
+}
+
+func TestGenerateClientDuplicateName(t *testing.T) {
+	docs := []routemeta.Doc{
+		{Name: "todos", Method: "GET", Pattern: "/todos"},
+		{Name: "todos", Method: "POST", Pattern: "/todos"},
+	}
+	err := generateClient(&bytes.Buffer{}, "swift", clientOptions{Name: "IrgoRoutes"}, docs)
+	if err == nil || !strings.Contains(err.Error(), "duplicate route name") {
+		t.Errorf("expected duplicate name error, got %v", err)
+	}
+}
+
+func TestIdentifier(t *testing.T) {
+	tests := map[string]string{
+		"todo_id":  "todoId",
+		"due-date": "dueDate",
+		"ID":       "iD",
+		"2fa":      "_2fa",
+	}
+	for in, want := range tests {
+		if got := identifier(in); got != want {
+			t.Errorf("identifier(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
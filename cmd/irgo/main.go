@@ -24,7 +24,11 @@ func main() {
 		err = newProject(os.Args[2])
 
 	case "dev":
-		err = runDev()
+		if hasFlag(os.Args[2:], "--workspace", "-w") {
+			err = runWorkspace()
+		} else {
+			err = runDev()
+		}
 
 	case "serve":
 		err = runServe()
@@ -65,6 +69,12 @@ func main() {
 	case "test":
 		err = runTest()
 
+	case "generate":
+		err = runGenerate(os.Args[2:])
+
+	case "config":
+		err = runConfig(os.Args[2:])
+
 	case "install-tools":
 		err = installTools()
 
@@ -99,11 +109,14 @@ Usage:
 Commands:
   new <name>       Create a new irgo project
   dev              Run development server with hot reload
+                   (--workspace runs every app in a monorepo)
   serve            Run server without file watching
   build <target>   Build for mobile/desktop (ios, android, desktop, or all)
   run <platform>   Build and run on simulator or desktop
   templ            Generate templ files
   test             Run tests
+  generate client  Generate a typed Swift/Kotlin client from route metadata
+  config check     Validate gohtmx.toml
   install-tools    Install required dev tools (gomobile, templ, air)
   version          Print version information
   help [command]   Show help for a command
@@ -142,13 +155,29 @@ Creates a new project with:
 
 Usage:
   irgo dev
+  irgo dev --workspace
 
 Starts:
   - Air for Go hot reloading
   - Templ file watcher
   - Tailwind CSS watcher (if configured)
 
-Server runs at http://localhost:8080`)
+Server runs at http://localhost:8080
+
+With --workspace, every app below the current directory (a directory
+with gohtmx.toml, or a main.go using irgo) is built, watched and run on
+its own port behind one proxy. Requests are routed by host or path
+prefix (default /<app>), set in the root gohtmx.toml:
+
+  [workspace]
+  port = 8080
+
+  [workspace.admin]
+  prefix = "/admin"
+  host = "admin.localhost"
+
+Build state for each app is shown at /_workspace. A failing app
+doesn't stop the others.`)
 
 	case "build":
 		fmt.Println(`irgo build - Build for mobile and desktop platforms
@@ -185,6 +214,37 @@ Usage:
 
 Runs 'templ generate' to compile .templ files to Go code.`)
 
+	case "generate":
+		fmt.Println(`irgo generate - Generate code from route metadata
+
+Usage:
+  irgo generate client --lang swift  [--routes routes.json] [--out file]
+  irgo generate client --lang kotlin [--routes routes.json] [--out file]
+
+Flags:
+  --lang      Target language: swift or kotlin
+  --routes    Route metadata JSON (default: routes.json)
+  --out       Output file (default: stdout)
+  --name      Generated type name (default: IrgoRoutes)
+  --package   Kotlin package name (default: irgo)
+
+Route metadata comes from routes documented with Route.Describe:
+
+  routemeta.Write(f, r.Docs())
+
+Each documented route becomes a function that calls the mobile bridge
+(MobileHandleRequest on iOS, Mobile.handleRequest on Android).`)
+
+	case "config":
+		fmt.Println(`irgo config - Manage app configuration
+
+Usage:
+  irgo config check [file]   Validate gohtmx.toml (default: ./gohtmx.toml)
+
+gohtmx.toml is optional and read by desktop, mobile and the dev/serve
+commands. Environment variables (GOHTMX_DEBUG, GOHTMX_PORT, ...) override
+the file; values set in code override both. Unknown keys produce warnings.`)
+
 	case "run":
 		fmt.Println(`irgo run - Build and run on simulator or desktop
 
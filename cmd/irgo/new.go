@@ -11,6 +11,8 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+
+	"github.com/stukennedy/irgo/pkg/render"
 )
 
 //go:embed templates/*
@@ -179,6 +181,27 @@ func isRemoteModulePath(path string) bool {
 	return false
 }
 
+// writePackageJSON writes the project's package.json, which builds the
+// Tailwind 4 CSS into static/css.
+func writePackageJSON(projectDir, projectName string) error {
+	content := render.PackageJSON(render.PackageJSONOptions{
+		Name:            projectName,
+		Version:         "0.1.0",
+		Private:         true,
+		InputCSS:        "./static/css/input.css",
+		OutputCSS:       "./static/css/output.css",
+		BuildScript:     "css",
+		WatchScript:     "css:watch",
+		TailwindPackage: "@tailwindcss/cli",
+		TailwindVersion: "^4.0.0",
+	})
+	if err := os.WriteFile(filepath.Join(projectDir, "package.json"), []byte(content), 0644); err != nil {
+		return fmt.Errorf("writing package.json: %w", err)
+	}
+	fmt.Println("  created: package.json")
+	return nil
+}
+
 func newProject(name string) error {
 	// Determine project directory, project name, and module path
 	var projectDir string
@@ -296,6 +319,10 @@ func newProject(name string) error {
 		return fmt.Errorf("copying templates: %w", err)
 	}
 
+	if err := writePackageJSON(projectDir, projectName); err != nil {
+		return err
+	}
+
 	// Download Datastar files
 	fmt.Println("Downloading Datastar...")
 	if err := downloadDatastar(projectDir); err != nil {
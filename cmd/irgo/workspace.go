@@ -0,0 +1,448 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stukennedy/irgo/pkg/config"
+)
+
+// frameworkImport identifies main packages built on the framework.
+const frameworkImport = `"github.com/stukennedy/irgo/`
+
+// workspaceStatusPath serves the workspace status page on the proxy.
+const workspaceStatusPath = "/_workspace"
+
+// Build states shown on the status page.
+const (
+	stateBuilding = "building"
+	stateRunning  = "running"
+	stateFailed   = "failed"
+)
+
+// wsApp is one app of a workspace and its current build state.
+type wsApp struct {
+	Name   string
+	Dir    string
+	Prefix string
+	Host   string
+	Port   int
+
+	proxy *httputil.ReverseProxy
+
+	mu      sync.RWMutex
+	state   string
+	message string
+	updated time.Time
+}
+
+func (a *wsApp) setState(state, message string) {
+	a.mu.Lock()
+	a.state, a.message, a.updated = state, message, time.Now()
+	a.mu.Unlock()
+}
+
+func (a *wsApp) status() (state, message string) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.state, a.message
+}
+
+// appRunner builds app and serves it on app.Port until ctx is cancelled,
+// calling ready once it accepts requests. It returns build and run errors.
+type appRunner func(ctx context.Context, app *wsApp, ready func()) error
+
+// workspace supervises the apps of a monorepo behind one proxy.
+type workspace struct {
+	apps  []*wsApp
+	run   appRunner
+	watch time.Duration // File polling interval; 0 disables rebuilds
+}
+
+// runWorkspace handles "irgo dev --workspace": it discovers the apps under
+// the current directory and serves them behind a proxy until interrupted.
+func runWorkspace() error {
+	cfg, err := config.Load("")
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", config.FileName, err)
+	}
+	apps, err := discoverApps(".", cfg.WorkspaceApps)
+	if err != nil {
+		return err
+	}
+	if len(apps) == 0 {
+		return fmt.Errorf("no apps found - add a %s or a main.go using irgo", config.FileName)
+	}
+	for _, app := range apps {
+		if app.Port, err = freePort(); err != nil {
+			return err
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	ws := newWorkspace(apps, execRunner, time.Second)
+	ws.start(ctx)
+
+	addr := fmt.Sprintf(":%d", cfg.WorkspacePort)
+	fmt.Printf("Workspace proxy at http://localhost%s (status: %s)\n", addr, workspaceStatusPath)
+	for _, app := range apps {
+		fmt.Printf("  %-12s %-16s %s → :%d\n", app.Name, app.Prefix, app.Host, app.Port)
+	}
+
+	srv := &http.Server{Addr: addr, Handler: ws}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// discoverApps finds app directories under root: those with a gohtmx.toml
+// or a main.go importing the framework. The root's own gohtmx.toml holds
+// the workspace settings, so it only counts as an app with such a
+// main.go. Directories inside an app are not searched.
+func discoverApps(root string, routes map[string]config.WorkspaceApp) ([]*wsApp, error) {
+	var apps []*wsApp
+	names := make(map[string]string)
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return err
+		}
+		name := d.Name()
+		if path != root && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") ||
+			name == "node_modules" || name == "vendor" || name == "testdata") {
+			return filepath.SkipDir
+		}
+
+		isApp := usesFramework(filepath.Join(path, "main.go"))
+		if !isApp && path != root {
+			_, err := os.Stat(filepath.Join(path, config.FileName))
+			isApp = err == nil
+		}
+		if !isApp {
+			return nil
+		}
+
+		if path == root {
+			abs, err := filepath.Abs(root)
+			if err != nil {
+				return err
+			}
+			name = filepath.Base(abs)
+		}
+		if other, dup := names[name]; dup {
+			return fmt.Errorf("workspace apps %s and %s share the name %q", other, path, name)
+		}
+		names[name] = path
+
+		route := routes[name]
+		if route.Prefix == "" && route.Host == "" {
+			route.Prefix = "/" + name
+		}
+		apps = append(apps, &wsApp{Name: name, Dir: path, Prefix: route.Prefix, Host: route.Host})
+		if path != root {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(apps, func(i, j int) bool { return apps[i].Name < apps[j].Name })
+	return apps, nil
+}
+
+// usesFramework reports whether a main.go file imports the framework.
+func usesFramework(path string) bool {
+	data, err := os.ReadFile(path)
+	return err == nil && strings.Contains(string(data), frameworkImport)
+}
+
+func newWorkspace(apps []*wsApp, run appRunner, watch time.Duration) *workspace {
+	for _, app := range apps {
+		target := &url.URL{Scheme: "http", Host: "127.0.0.1:" + strconv.Itoa(app.Port)}
+		app.proxy = httputil.NewSingleHostReverseProxy(target)
+		app.proxy.ErrorHandler = app.unavailable
+		app.setState(stateBuilding, "")
+	}
+	return &workspace{apps: apps, run: run, watch: watch}
+}
+
+// start supervises every app in its own goroutine until ctx is done.
+func (ws *workspace) start(ctx context.Context) {
+	for _, app := range ws.apps {
+		go ws.supervise(ctx, app)
+	}
+}
+
+// supervise builds and runs app, rebuilding when its files change. A
+// failed build or crash marks only this app failed until the next change.
+func (ws *workspace) supervise(ctx context.Context, app *wsApp) {
+	var changes <-chan struct{}
+	if ws.watch > 0 {
+		changes = watchFiles(ctx, app.Dir, ws.watch)
+	}
+
+	for ctx.Err() == nil {
+		app.setState(stateBuilding, "")
+		runCtx, cancel := context.WithCancel(ctx)
+		done := make(chan error, 1)
+		go func() {
+			done <- ws.run(runCtx, app, func() { app.setState(stateRunning, "") })
+		}()
+
+		select {
+		case err := <-done:
+			cancel()
+			if ctx.Err() != nil {
+				return
+			}
+			if err == nil {
+				err = errors.New("exited")
+			}
+			app.setState(stateFailed, err.Error())
+			fmt.Printf("[%s] %v\n", app.Name, err)
+			select {
+			case <-changes:
+			case <-ctx.Done():
+				return
+			}
+		case <-changes:
+			cancel()
+			<-done
+		case <-ctx.Done():
+			cancel()
+			<-done
+			return
+		}
+	}
+}
+
+// ServeHTTP routes a request to the app matching its host, then the
+// longest matching path prefix, or serves the status page.
+func (ws *workspace) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == workspaceStatusPath {
+		ws.serveStatus(w, http.StatusOK)
+		return
+	}
+	if app := ws.route(r); app != nil {
+		app.proxy.ServeHTTP(w, r)
+		return
+	}
+	ws.serveStatus(w, http.StatusNotFound)
+}
+
+func (ws *workspace) route(r *http.Request) *wsApp {
+	host, _, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		host = r.Host
+	}
+	for _, app := range ws.apps {
+		if app.Host != "" && strings.EqualFold(app.Host, host) {
+			return app
+		}
+	}
+
+	var best *wsApp
+	for _, app := range ws.apps {
+		if app.Prefix == "" || !hasPathPrefix(r.URL.Path, app.Prefix) {
+			continue
+		}
+		if best == nil || len(app.Prefix) > len(best.Prefix) {
+			best = app
+		}
+	}
+	return best
+}
+
+// hasPathPrefix matches whole path segments: "/admin" matches "/admin"
+// and "/admin/users" but not "/administrators".
+func hasPathPrefix(path, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return prefix == "" || path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// unavailable answers for an app that is building, failed or down.
+func (a *wsApp) unavailable(w http.ResponseWriter, r *http.Request, err error) {
+	state, message := a.status()
+	if state == stateRunning {
+		message = err.Error()
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusBadGateway)
+	fmt.Fprintf(w, "<h1>%s: %s</h1><pre>%s</pre><p><a href=%q>Workspace status</a></p>",
+		template.HTMLEscapeString(a.Name), state, template.HTMLEscapeString(message), workspaceStatusPath)
+}
+
+var statusTemplate = template.Must(template.New("status").Parse(`<!DOCTYPE html>
+<html><head><title>irgo workspace</title><meta http-equiv="refresh" content="2"></head>
+<body><h1>irgo workspace</h1>
+<table><tr><th>App</th><th>Route</th><th>Port</th><th>State</th><th>Details</th></tr>
+{{range .}}<tr><td>{{.Name}}</td><td>{{.Host}} {{.Prefix}}</td><td>{{.Port}}</td><td class="{{.State}}">{{.State}}</td><td><pre>{{.Message}}</pre></td></tr>
+{{end}}</table></body></html>`))
+
+func (ws *workspace) serveStatus(w http.ResponseWriter, status int) {
+	type row struct {
+		Name, Prefix, Host, State, Message string
+		Port                               int
+	}
+	rows := make([]row, 0, len(ws.apps))
+	for _, app := range ws.apps {
+		state, message := app.status()
+		rows = append(rows, row{app.Name, app.Prefix, app.Host, state, message, app.Port})
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	statusTemplate.Execute(w, rows)
+}
+
+// watchFiles polls dir for changes to source files, sending on the
+// returned channel after each change. Generated _templ.go files are
+// ignored so regenerating templates doesn't trigger a second rebuild.
+func watchFiles(ctx context.Context, dir string, interval time.Duration) <-chan struct{} {
+	changes := make(chan struct{}, 1)
+	go func() {
+		last := snapshot(dir)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if now := snapshot(dir); now != last {
+				last = now
+				select {
+				case changes <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+	return changes
+}
+
+// snapshot summarises the source files under dir.
+func snapshot(dir string) string {
+	var b strings.Builder
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if path != dir && (strings.HasPrefix(d.Name(), ".") || d.Name() == "node_modules") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		switch filepath.Ext(path) {
+		case ".go", ".templ", ".html", ".css", ".toml":
+		default:
+			return nil
+		}
+		if strings.HasSuffix(path, "_templ.go") {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			fmt.Fprintf(&b, "%s %d %d\n", path, info.Size(), info.ModTime().UnixNano())
+		}
+		return nil
+	})
+	return b.String()
+}
+
+// execRunner generates templates, builds app with the go tool and runs
+// it with "serve" on app.Port (passed as GOHTMX_PORT and PORT).
+func execRunner(ctx context.Context, app *wsApp, ready func()) error {
+	if _, err := exec.LookPath("templ"); err == nil {
+		if out, err := runIn(ctx, app.Dir, "templ", "generate"); err != nil {
+			return fmt.Errorf("templ generate failed:\n%s", out)
+		}
+	}
+
+	bin := filepath.Join(os.TempDir(), "irgo-workspace-"+app.Name)
+	if out, err := runIn(ctx, app.Dir, "go", "build", "-o", bin, "."); err != nil {
+		return fmt.Errorf("build failed:\n%s", out)
+	}
+
+	cmd := exec.CommandContext(ctx, bin, "serve")
+	cmd.Dir = app.Dir
+	port := strconv.Itoa(app.Port)
+	cmd.Env = append(os.Environ(), "GOHTMX_PORT="+port, "PORT="+port)
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	cmd.Stderr = cmd.Stdout
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go prefixLines(app.Name, out)
+
+	go func() {
+		for ctx.Err() == nil {
+			if conn, err := net.DialTimeout("tcp", "127.0.0.1:"+port, time.Second); err == nil {
+				conn.Close()
+				ready()
+				return
+			}
+			time.Sleep(200 * time.Millisecond)
+		}
+	}()
+
+	err = cmd.Wait()
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+func runIn(ctx context.Context, dir, name string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
+
+// prefixLines copies an app's output to stdout, tagged with its name.
+func prefixLines(name string, r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fmt.Printf("[%s] %s\n", name, scanner.Text())
+	}
+}
+
+// freePort asks the OS for an unused TCP port.
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
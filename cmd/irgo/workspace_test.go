@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stukennedy/irgo/pkg/config"
+)
+
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, contents := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+const irgoMain = "package main\n\nimport \"github.com/stukennedy/irgo/pkg/router\"\n"
+
+func TestDiscoverApps(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"gohtmx.toml":                "[workspace]\nport = 3000\n",
+		"apps/admin/main.go":         irgoMain,
+		"apps/admin/tools/main.go":   irgoMain, // inside an app: not separate
+		"marketing/gohtmx.toml":      "[app]\ntitle = \"Site\"\n",
+		"cli/main.go":                "package main\n\nimport \"fmt\"\n",
+		"node_modules/x/gohtmx.toml": "",
+		".git/gohtmx.toml":           "",
+	})
+
+	apps, err := discoverApps(root, map[string]config.WorkspaceApp{
+		"marketing": {Prefix: "/"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, app := range apps {
+		rel, _ := filepath.Rel(root, app.Dir)
+		got = append(got, app.Name+"="+rel+" "+app.Prefix)
+	}
+	want := []string{"admin=apps/admin /admin", "marketing=marketing /"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("discovered %v, want %v", got, want)
+	}
+}
+
+func TestDiscoverAppsRootAndDuplicates(t *testing.T) {
+	root := writeTree(t, map[string]string{"main.go": irgoMain, "admin/gohtmx.toml": ""})
+	apps, err := discoverApps(root, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(apps) != 2 {
+		t.Fatalf("expected root app plus admin, got %d apps", len(apps))
+	}
+	for _, app := range apps {
+		if app.Dir == root && app.Name != filepath.Base(root) {
+			t.Errorf("root app named %q, want directory name", app.Name)
+		}
+	}
+
+	dup := writeTree(t, map[string]string{"a/web/gohtmx.toml": "", "b/web/gohtmx.toml": ""})
+	if _, err := discoverApps(dup, nil); err == nil || !strings.Contains(err.Error(), "share the name") {
+		t.Errorf("expected duplicate name error, got %v", err)
+	}
+}
+
+// fakeRunner serves each app's name on its port, or fails the build for
+// apps listed in broken.
+func fakeRunner(broken map[string]bool) appRunner {
+	return func(ctx context.Context, app *wsApp, ready func()) error {
+		if broken[app.Name] {
+			return errors.New("build failed: syntax error")
+		}
+		l, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", app.Port))
+		if err != nil {
+			return err
+		}
+		srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "%s %s", app.Name, r.URL.Path)
+		})}
+		go func() {
+			<-ctx.Done()
+			srv.Close()
+		}()
+		ready()
+		srv.Serve(l)
+		return nil
+	}
+}
+
+func startWorkspace(t *testing.T, broken map[string]bool, apps ...*wsApp) *httptest.Server {
+	t.Helper()
+	for _, app := range apps {
+		port, err := freePort()
+		if err != nil {
+			t.Fatal(err)
+		}
+		app.Port = port
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	ws := newWorkspace(apps, fakeRunner(broken), 0)
+	ws.start(ctx)
+	deadline := time.Now().Add(5 * time.Second)
+	for _, app := range apps {
+		for {
+			if state, _ := app.status(); state != stateBuilding {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("%s still building", app.Name)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	srv := httptest.NewServer(ws)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func fetch(t *testing.T, srv *httptest.Server, host, path string) (int, string) {
+	t.Helper()
+	req, _ := http.NewRequest("GET", srv.URL+path, nil)
+	if host != "" {
+		req.Host = host
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestWorkspaceProxyRouting(t *testing.T) {
+	srv := startWorkspace(t, nil,
+		&wsApp{Name: "site", Prefix: "/"},
+		&wsApp{Name: "admin", Prefix: "/admin"},
+		&wsApp{Name: "docs", Host: "docs.localhost"},
+	)
+
+	tests := []struct{ host, path, want string }{
+		{"", "/", "site /"},
+		{"", "/pricing", "site /pricing"},
+		{"", "/admin", "admin /admin"},
+		{"", "/admin/users", "admin /admin/users"},
+		{"", "/administrators", "site /administrators"},
+		{"docs.localhost:8080", "/admin", "docs /admin"},
+	}
+	for _, tt := range tests {
+		if status, body := fetch(t, srv, tt.host, tt.path); status != 200 || body != tt.want {
+			t.Errorf("%s%s: got %d %q, want %q", tt.host, tt.path, status, body, tt.want)
+		}
+	}
+
+	status, body := fetch(t, srv, "", workspaceStatusPath)
+	if status != 200 || !strings.Contains(body, "admin") || !strings.Contains(body, stateRunning) {
+		t.Errorf("status page: %d\n%s", status, body)
+	}
+}
+
+func TestWorkspaceIsolatesFailingApp(t *testing.T) {
+	srv := startWorkspace(t, map[string]bool{"broken": true},
+		&wsApp{Name: "main", Prefix: "/main"},
+		&wsApp{Name: "broken", Prefix: "/broken"},
+	)
+
+	if status, body := fetch(t, srv, "", "/main/x"); status != 200 || body != "main /main/x" {
+		t.Errorf("healthy app: got %d %q", status, body)
+	}
+	status, body := fetch(t, srv, "", "/broken/x")
+	if status != http.StatusBadGateway || !strings.Contains(body, "syntax error") {
+		t.Errorf("failing app: got %d %q", status, body)
+	}
+
+	_, page := fetch(t, srv, "", workspaceStatusPath)
+	if !strings.Contains(page, `class="failed"`) || !strings.Contains(page, `class="running"`) {
+		t.Errorf("status page missing states:\n%s", page)
+	}
+	if status, _ := fetch(t, srv, "", "/nowhere"); status != http.StatusNotFound {
+		t.Errorf("unrouted path: got %d, want 404", status)
+	}
+}
+
+func TestWorkspaceRebuildsOnChange(t *testing.T) {
+	dir := writeTree(t, map[string]string{"main.go": irgoMain})
+	app := &wsApp{Name: "app", Dir: dir, Prefix: "/"}
+	app.Port, _ = freePort()
+
+	builds := make(chan int, 10)
+	n := 0
+	run := func(ctx context.Context, a *wsApp, ready func()) error {
+		n++
+		builds <- n
+		if n == 1 {
+			return errors.New("build failed")
+		}
+		ready()
+		<-ctx.Done()
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ws := newWorkspace([]*wsApp{app}, run, 10*time.Millisecond)
+	ws.start(ctx)
+
+	<-builds
+	time.Sleep(30 * time.Millisecond)
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte(irgoMain+"// edited\n"), 0644)
+
+	select {
+	case <-builds:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no rebuild after change")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if state, _ := app.status(); state == stateRunning {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("app not running after rebuild")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
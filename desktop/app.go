@@ -5,7 +5,9 @@ package desktop
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"os"
 	"sync"
@@ -13,6 +15,7 @@ import (
 
 	webview "github.com/webview/webview_go"
 
+	"github.com/stukennedy/irgo/pkg/config"
 	"github.com/stukennedy/irgo/pkg/transport"
 	ws "github.com/stukennedy/irgo/pkg/websocket"
 )
@@ -28,20 +31,33 @@ type Config struct {
 	Transport string // "loopback" (default) or "inprocess"
 	Version   string // App version (shown in About menu on macOS)
 	SetupMenu bool   // Setup native menu bar (macOS)
+
+	AllowedOrigins []string // Extra origins allowed by the loopback server
 }
 
-// DefaultConfig returns sensible defaults for a desktop app
+// DefaultConfig returns sensible defaults for a desktop app, overlaid with
+// gohtmx.toml and GOHTMX_* environment variables if present (see pkg/config).
+// Fields set on the result in code take precedence. An invalid config file
+// is logged and ignored.
 func DefaultConfig() Config {
+	app, err := config.Load("")
+	if err != nil {
+		log.Printf("desktop: ignoring %s: %v", config.FileName, err)
+		def := config.Default()
+		app = &def
+	}
+
 	return Config{
-		Title:     "Irgo App",
-		Width:     1024,
-		Height:    768,
-		Resizable: true,
-		Debug:     false,
-		Port:      0,
-		Transport: "loopback",
-		Version:   "1.0.0",
-		SetupMenu: true,
+		Title:          app.Title,
+		Width:          app.Width,
+		Height:         app.Height,
+		Resizable:      app.Resizable,
+		Debug:          app.Debug,
+		Port:           app.Port,
+		Transport:      "loopback",
+		Version:        app.Version,
+		SetupMenu:      true,
+		AllowedOrigins: app.AllowedOrigins,
 	}
 }
 
@@ -53,6 +69,8 @@ type App struct {
 	transport transport.Transport
 	wv        webview.WebView
 	wg        sync.WaitGroup
+
+	stopCleanup func() // Stops the hub's pending request cleanup
 }
 
 // New creates a new desktop app with the given HTTP handler
@@ -96,6 +114,7 @@ func (a *App) Run() error {
 	default:
 		t = transport.NewLoopbackTransport(a.handler, a.wsHub,
 			transport.WithPort(a.config.Port),
+			transport.WithAllowedOrigins(a.config.AllowedOrigins...),
 		)
 	}
 	a.transport = t
@@ -104,6 +123,7 @@ func (a *App) Run() error {
 	if err := t.Start(); err != nil {
 		return fmt.Errorf("starting transport: %w", err)
 	}
+	a.stopCleanup = a.wsHub.StartCleanup(ws.DefaultCleanupInterval, ws.DefaultPendingTTL)
 
 	// Run webview (blocks until window closed)
 	a.runWebview()
@@ -136,18 +156,77 @@ func (a *App) URL() string {
 	return ""
 }
 
-// Secret returns the per-launch authentication secret
+// Secret returns the current authentication secret, which changes on
+// Rotate (empty for inprocess transport)
 func (a *App) Secret() string {
-	if a.transport == nil {
-		return ""
-	}
-	cfg := a.transport.Config()
-	if cfg != nil {
-		return cfg.Secret
+	if r, ok := a.transport.(secretRotator); ok {
+		return r.Secret()
 	}
 	return ""
 }
 
+// ErrRotateUnsupported is returned by Rotate when the transport has no
+// secret to rotate (the inprocess transport).
+var ErrRotateUnsupported = errors.New("desktop: transport does not support secret rotation")
+
+// secretRotator is implemented by transports with a rotatable secret.
+type secretRotator interface {
+	Secret() string
+	Rotate() (string, error)
+}
+
+// Rotate replaces the authentication secret, for long-running apps such
+// as kiosks, and pushes the new secret to the webview. Requests still
+// using the old secret are accepted for the transport's grace window
+// (see transport.WithSecretGrace).
+func (a *App) Rotate() (string, error) {
+	r, ok := a.transport.(secretRotator)
+	if !ok {
+		return "", ErrRotateUnsupported
+	}
+	secret, err := r.Rotate()
+	if err != nil {
+		return "", err
+	}
+
+	if a.wv != nil {
+		js := rotateScript(secret)
+		a.wv.Dispatch(func() {
+			a.wv.Eval(js)
+		})
+	}
+	return secret, nil
+}
+
+// secretKey is the sessionStorage key holding the secret after a
+// rotation. sessionStorage survives page loads but not the window, so it
+// is the single place later pages read the rotated secret from.
+const secretKey = "__irgo_secret__"
+
+// secretScript defines window.__IRGO_SECRET__, read by irgo-bridge.js, to
+// return the rotated secret if there is one and the launch secret
+// otherwise. It is installed once with Init, so it runs on every page.
+func secretScript(launch string) string {
+	return `(function () {
+  var launch = '` + launch + `';
+  Object.defineProperty(window, "__IRGO_SECRET__", {
+    configurable: true,
+    get: function () {
+      try {
+        return sessionStorage.getItem("` + secretKey + `") || launch;
+      } catch (e) {
+        return launch;
+      }
+    }
+  });
+})();`
+}
+
+// rotateScript stores a rotated secret for secretScript to read.
+func rotateScript(secret string) string {
+	return `try { sessionStorage.setItem("` + secretKey + `", '` + secret + `'); } catch (e) {}`
+}
+
 // Transport returns the underlying transport for advanced usage
 func (a *App) Transport() transport.Transport {
 	return a.transport
@@ -172,10 +251,8 @@ func (a *App) runWebview() {
 
 	// Inject the secret into the webview before navigation
 	// Using Init() ensures the script runs before any page scripts
-	cfg := a.transport.Config()
-	if cfg != nil && cfg.Secret != "" {
-		js := "window.__IRGO_SECRET__ = '" + cfg.Secret + "';"
-		a.wv.Init(js)
+	if secret := a.Secret(); secret != "" {
+		a.wv.Init(secretScript(secret))
 	}
 
 	// Navigate to the server URL
@@ -193,6 +270,9 @@ func (a *App) Shutdown() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	if a.stopCleanup != nil {
+		a.stopCleanup()
+	}
 	if a.transport != nil {
 		a.transport.Stop(ctx)
 	}
@@ -2,6 +2,8 @@ package desktop
 
 import (
 	"net/http"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -138,3 +140,24 @@ func TestGenerateSecret(t *testing.T) {
 		t.Error("expected unique secrets on each call")
 	}
 }
+
+func TestDefaultConfigFromFile(t *testing.T) {
+	dir := t.TempDir()
+	contents := "[app]\ntitle = \"Todo\"\n[window]\nwidth = 800\n[server]\nallowed_origins = [\"http://localhost:5173\"]\n"
+	if err := os.WriteFile(filepath.Join(dir, "gohtmx.toml"), []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(dir)
+	t.Setenv("GOHTMX_WINDOW_HEIGHT", "600")
+
+	config := DefaultConfig()
+	if config.Title != "Todo" || config.Width != 800 || config.Height != 600 {
+		t.Errorf("expected file and env values, got %+v", config)
+	}
+	if len(config.AllowedOrigins) != 1 || config.AllowedOrigins[0] != "http://localhost:5173" {
+		t.Errorf("unexpected allowed origins %v", config.AllowedOrigins)
+	}
+	if config.Transport != "loopback" {
+		t.Errorf("expected default Transport 'loopback', got %q", config.Transport)
+	}
+}
@@ -0,0 +1,178 @@
+// Package toml parses the subset of TOML used by gohtmx.toml and i18n
+// message files.
+package toml
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Entry is a parsed key/value with its source line.
+type Entry struct {
+	Key   string // Dotted key, e.g. "window.width"
+	Value any    // string, int64, bool, float64 or []string
+	Line  int
+}
+
+// Parse parses [tables], key = value pairs, strings, integers, floats,
+// booleans, single-line string arrays and # comments. Tables prefix
+// their keys, so "width" under [window] is "window.width". Errors are
+// prefixed with name and the line number.
+func Parse(name string, data []byte) ([]Entry, error) {
+	var (
+		entries []Entry
+		table   string
+		seen    = make(map[string]bool)
+	)
+
+	for i, raw := range strings.Split(string(data), "\n") {
+		lineNo := i + 1
+		line := strings.TrimSpace(stripComment(raw))
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") || strings.HasPrefix(line, "[[") {
+				return nil, fmt.Errorf("%s:%d: invalid table header %q", name, lineNo, line)
+			}
+			table = strings.TrimSpace(line[1 : len(line)-1])
+			if table == "" {
+				return nil, fmt.Errorf("%s:%d: empty table name", name, lineNo)
+			}
+			continue
+		}
+
+		eq := strings.Index(line, "=")
+		if eq < 0 {
+			return nil, fmt.Errorf("%s:%d: expected key = value", name, lineNo)
+		}
+		key := strings.TrimSpace(line[:eq])
+		if key == "" {
+			return nil, fmt.Errorf("%s:%d: missing key", name, lineNo)
+		}
+		if table != "" {
+			key = table + "." + key
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("%s:%d: duplicate key %q", name, lineNo, key)
+		}
+		seen[key] = true
+
+		value, err := parseValue(strings.TrimSpace(line[eq+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %s: %w", name, lineNo, key, err)
+		}
+		entries = append(entries, Entry{Key: key, Value: value, Line: lineNo})
+	}
+	return entries, nil
+}
+
+// stripComment removes a trailing # comment outside of strings.
+func stripComment(line string) string {
+	inString := false
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			if inString {
+				i++
+			}
+		case '"':
+			inString = !inString
+		case '#':
+			if !inString {
+				return line[:i]
+			}
+		}
+	}
+	return line
+}
+
+func parseValue(s string) (any, error) {
+	switch {
+	case s == "":
+		return nil, fmt.Errorf("missing value")
+	case s == "true":
+		return true, nil
+	case s == "false":
+		return false, nil
+	case strings.HasPrefix(s, `"`):
+		v, rest, err := parseString(s)
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(rest) != "" {
+			return nil, fmt.Errorf("unexpected %q after string", rest)
+		}
+		return v, nil
+	case strings.HasPrefix(s, "["):
+		return parseArray(s)
+	}
+
+	clean := strings.ReplaceAll(s, "_", "")
+	if n, err := strconv.ParseInt(clean, 10, 64); err == nil {
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(clean, 64); err == nil {
+		return f, nil
+	}
+	return nil, fmt.Errorf("invalid value %q", s)
+}
+
+// parseString parses a basic string at the start of s and returns the rest.
+func parseString(s string) (string, string, error) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch c {
+		case '"':
+			return b.String(), s[i+1:], nil
+		case '\\':
+			i++
+			if i == len(s) {
+				return "", "", fmt.Errorf("unterminated string")
+			}
+			switch s[i] {
+			case '"', '\\':
+				b.WriteByte(s[i])
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			default:
+				return "", "", fmt.Errorf("invalid escape \\%c", s[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", "", fmt.Errorf("unterminated string")
+}
+
+func parseArray(s string) ([]string, error) {
+	rest := strings.TrimSpace(s[1:])
+	items := []string{}
+	for {
+		if strings.HasPrefix(rest, "]") {
+			if strings.TrimSpace(rest[1:]) != "" {
+				return nil, fmt.Errorf("unexpected %q after array", rest[1:])
+			}
+			return items, nil
+		}
+		if !strings.HasPrefix(rest, `"`) {
+			return nil, fmt.Errorf("arrays may only contain strings")
+		}
+		v, after, err := parseString(rest)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, v)
+		rest = strings.TrimSpace(after)
+		if strings.HasPrefix(rest, ",") {
+			rest = strings.TrimSpace(rest[1:])
+		} else if !strings.HasPrefix(rest, "]") {
+			return nil, fmt.Errorf("unterminated array")
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package toml
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	data := `
+title = "Todo # list" # comment
+debug = true
+
+[window]
+width = 1_024
+scale = 1.5
+origins = ["a", "b\"c"]
+`
+	got, err := Parse("app.toml", []byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Entry{
+		{Key: "title", Value: "Todo # list", Line: 2},
+		{Key: "debug", Value: true, Line: 3},
+		{Key: "window.width", Value: int64(1024), Line: 6},
+		{Key: "window.scale", Value: 1.5, Line: 7},
+		{Key: "window.origins", Value: []string{"a", `b"c`}, Line: 8},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse =\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]string{
+		"a = 1\na = 2":   "app.toml:2: duplicate key",
+		"[[tables]]":     "app.toml:1: invalid table header",
+		"[ ]":            "app.toml:1: empty table name",
+		"novalue":        "app.toml:1: expected key = value",
+		`s = "open`:      "app.toml:1: s: unterminated string",
+		`s = "\q"`:       `app.toml:1: s: invalid escape \q`,
+		"a = [1]":        "app.toml:1: a: arrays may only contain strings",
+		"a = maybe":      `app.toml:1: a: invalid value "maybe"`,
+		`s = "x" "y"`:    `app.toml:1: s: unexpected`,
+		`a = ["x"] junk`: `app.toml:1: a: unexpected`,
+	}
+	for data, want := range tests {
+		_, err := Parse("app.toml", []byte(data))
+		if err == nil || !strings.HasPrefix(err.Error(), want) {
+			t.Errorf("Parse(%q) err = %v, want %s...", data, err, want)
+		}
+	}
+}
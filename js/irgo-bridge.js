@@ -45,19 +45,20 @@
     return headers;
   }
 
-  // Add secret to WebSocket URL as query parameter
-  // (WebSocket API doesn't support custom headers on connect)
-  function addSecretToWsUrl(url) {
+  // Add the secret to WebSocket protocols as "irgo-secret.<base64url>"
+  // (WebSocket API doesn't support custom headers on connect, and a
+  // query parameter would end up in logs and history)
+  function addSecretToWsProtocols(protocols) {
+    const list = protocols ? [].concat(protocols) : [];
     const secret = getSecret();
     if (!secret) {
-      return url;
-    }
-    // Don't add if already present
-    if (url.includes("secret=")) {
-      return url;
+      return list;
     }
-    const separator = url.includes("?") ? "&" : "?";
-    return `${url}${separator}secret=${encodeURIComponent(secret)}`;
+    const encoded = btoa(unescape(encodeURIComponent(secret)))
+      .replace(/\+/g, "-")
+      .replace(/\//g, "_")
+      .replace(/=+$/, "");
+    return list.concat(`irgo-secret.${encoded}`);
   }
 
   // ========================================
@@ -191,9 +192,11 @@
           this.readyState = VirtualWebSocket.OPEN;
           this._dispatchEvent("open", { target: this });
         } else {
-          // Desktop/web: use real WebSocket with secret in URL
-          const secureUrl = addSecretToWsUrl(this.url);
-          this._native = new NativeWebSocket(secureUrl, this.protocols);
+          // Desktop/web: use real WebSocket with secret as a protocol
+          this._native = new NativeWebSocket(
+            this.url,
+            addSecretToWsProtocols(this.protocols),
+          );
           this._native.binaryType = this.binaryType;
 
           this._native.onopen = (e) => {
@@ -4,24 +4,67 @@
 package mobile
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"net/http"
 	"sync"
+	"time"
 
 	"github.com/stukennedy/irgo/pkg/adapter"
+	"github.com/stukennedy/irgo/pkg/config"
 	"github.com/stukennedy/irgo/pkg/core"
+	"github.com/stukennedy/irgo/pkg/crash"
+	"github.com/stukennedy/irgo/pkg/httpclient"
+	"github.com/stukennedy/irgo/pkg/memory"
+	"github.com/stukennedy/irgo/pkg/render"
+	"github.com/stukennedy/irgo/pkg/router"
+	"github.com/stukennedy/irgo/pkg/transport"
+	"github.com/stukennedy/irgo/pkg/watchdog"
 	"github.com/stukennedy/irgo/pkg/websocket"
 )
 
 var (
 	globalBridge *Bridge
 	bridgeMu     sync.RWMutex
+
+	// appConfig is set by InitializeWithConfig.
+	appConfig *config.Config
+
+	// stopMemory stops the memory budget coordinator and unregisters the
+	// bridge's components; nil when not running.
+	stopMemory func()
+
+	// stopWatchdog stops the stall watchdog; nil when not running.
+	stopWatchdog func()
 )
 
+// memoryCheckInterval is how often the memory budget is enforced.
+const memoryCheckInterval = 5 * time.Second
+
+// StallEvent is the NativeCallback.OnTrigger event fired when the
+// watchdog detects a stall. Its detail is JSON with "stall", a
+// description, and "report_id", the crash report holding the dump.
+const StallEvent = "irgo-stall"
+
 // Bridge is the main interface between native code and Go.
 type Bridge struct {
-	adapter *adapter.HTTPAdapter
-	wsHub   *websocket.Hub
-	mu      sync.RWMutex
+	adapter   *adapter.HTTPAdapter
+	wsHub     *websocket.Hub
+	scheduler *transport.Scheduler // nil without [server] max_concurrent_requests
+	mu        sync.RWMutex
+
+	stopCleanup func() // Stops the hub's pending request cleanup
+}
+
+// newBridge creates a bridge with a fresh hub, purging its expired
+// pending requests in the background until Shutdown.
+func newBridge() *Bridge {
+	hub := websocket.NewHub()
+	return &Bridge{
+		wsHub:       hub,
+		stopCleanup: hub.StartCleanup(websocket.DefaultCleanupInterval, websocket.DefaultPendingTTL),
+	}
 }
 
 // NativeCallback is implemented by Swift/Kotlin to receive async callbacks.
@@ -46,10 +89,150 @@ func Initialize() {
 	defer bridgeMu.Unlock()
 
 	if globalBridge == nil {
-		globalBridge = &Bridge{
-			wsHub: websocket.NewHub(),
-		}
+		globalBridge = newBridge()
+	}
+}
+
+// InitializeWithConfig loads gohtmx.toml from path (typically inside the app
+// bundle) with GOHTMX_* environment overrides, then initializes the bridge.
+// A missing file uses defaults. In debug mode the WebSocket hub keeps recent
+// envelope traces for inspection and template helpers log malformed
+// Datastar expressions. A [memory] budget_mb keeps the components
+// registered with memory.Default within that soft limit, and a [watchdog]
+// stall_seconds starts the stall watchdog (see StallEvent). A [server]
+// max_concurrent_requests queues requests beyond it by priority, so taps
+// run ahead of polling and prefetches.
+func InitializeWithConfig(path string) error {
+	cfg, err := config.Load(path)
+	if err != nil {
+		return err
+	}
+
+	Initialize()
+
+	bridgeMu.Lock()
+	defer bridgeMu.Unlock()
+	appConfig = cfg
+	if cfg.Debug {
+		globalBridge.wsHub.SetTraceHistory(50)
+		render.CheckExpressions(true)
+	}
+	globalBridge.scheduler = nil
+	if cfg.MaxConcurrentRequests > 0 {
+		globalBridge.scheduler = transport.NewScheduler(cfg.MaxConcurrentRequests)
+	}
+	startMemoryBudget(int64(cfg.MemoryBudgetMB)<<20, globalBridge.wsHub)
+	startWatchdog(time.Duration(cfg.WatchdogStallSeconds) * time.Second)
+	return nil
+}
+
+// startMemoryBudget sets the default budget's limit, registers the hub's
+// trace history and enforces the limit periodically. Called with
+// bridgeMu held.
+func startMemoryBudget(limit int64, hub *websocket.Hub) {
+	if stopMemory != nil {
+		stopMemory()
+	}
+	budget := memory.Default()
+	budget.SetLimit(limit)
+	unregister := budget.Register("websocket traces", hub.TraceBytes, hub.ShrinkTraces)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if limit > 0 {
+		go budget.Run(ctx, memoryCheckInterval)
+	}
+	stopMemory = func() {
+		cancel()
+		unregister()
+	}
+}
+
+// startWatchdog samples the adapter's in-flight requests and the hub's
+// session queues for stalls, writing dumps to the crash reporter set with
+// EnableCrashReports. A zero timeout leaves it off. Called with bridgeMu
+// held.
+func startWatchdog(timeout time.Duration) {
+	if stopWatchdog != nil {
+		stopWatchdog()
+		stopWatchdog = nil
+	}
+	if timeout <= 0 {
+		return
+	}
+	w := watchdog.New(watchdog.Config{
+		RequestTimeout: timeout,
+		Requests:       sampleRequests,
+		Queues:         sampleQueues,
+		OnStall: func(s watchdog.Stall) {
+			if nativeCallback == nil {
+				return
+			}
+			detail, _ := json.Marshal(map[string]string{"stall": s.String(), "report_id": s.ReportID})
+			nativeCallback.OnTrigger(StallEvent, string(detail))
+		},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	go w.Run(ctx)
+	stopWatchdog = cancel
+}
+
+func sampleRequests() []watchdog.Request {
+	bridgeMu.RLock()
+	b := globalBridge
+	bridgeMu.RUnlock()
+	if b == nil || b.adapter == nil {
+		return nil
+	}
+	inflight := b.adapter.InFlight()
+	reqs := make([]watchdog.Request, len(inflight))
+	for i, r := range inflight {
+		reqs[i] = watchdog.Request{Method: r.Method, URL: r.URL, Started: r.Started}
+	}
+	return reqs
+}
+
+func sampleQueues() []watchdog.Queue {
+	bridgeMu.RLock()
+	b := globalBridge
+	bridgeMu.RUnlock()
+	if b == nil || b.wsHub == nil {
+		return nil
 	}
+	sessions := b.wsHub.AllSessions()
+	queues := make([]watchdog.Queue, len(sessions))
+	for i, s := range sessions {
+		queues[i] = watchdog.Queue{SessionID: s.ID, URL: s.URL, Depth: s.QueueDepth()}
+	}
+	return queues
+}
+
+// OnLowMemory shrinks caches and buffers aggressively. Call it from the
+// OS low-memory warning: applicationDidReceiveMemoryWarning on iOS,
+// onTrimMemory or onLowMemory on Android.
+func OnLowMemory() {
+	memory.Default().LowMemory()
+}
+
+// MemoryUsage returns the memory held by each component registered with
+// the budget as a JSON array of {"name", "bytes"}.
+func MemoryUsage() string {
+	data, err := json.Marshal(memory.Default().Usage())
+	if err != nil {
+		return "[]"
+	}
+	return string(data)
+}
+
+// AppConfig returns the configuration loaded by InitializeWithConfig, or
+// defaults if it wasn't called. For use from Go app code.
+func AppConfig() *config.Config {
+	bridgeMu.RLock()
+	defer bridgeMu.RUnlock()
+	if appConfig == nil {
+		def := config.Default()
+		return &def
+	}
+	return appConfig
 }
 
 // SetHandler sets the HTTP handler for the bridge.
@@ -59,9 +242,7 @@ func SetHandler(handler http.Handler) {
 	defer bridgeMu.Unlock()
 
 	if globalBridge == nil {
-		globalBridge = &Bridge{
-			wsHub: websocket.NewHub(),
-		}
+		globalBridge = newBridge()
 	}
 	globalBridge.adapter = adapter.NewHTTPAdapter(handler)
 }
@@ -107,9 +288,34 @@ func HandleRequest(method, url, headers string, body []byte) *core.Response {
 		Body:    body,
 	}
 
+	if b.scheduler != nil {
+		release, err := b.scheduler.Acquire(context.Background(), req.EffectivePriority())
+		if err != nil {
+			return transport.OverloadedResponse()
+		}
+		defer release()
+	}
 	return b.adapter.HandleRequest(req)
 }
 
+// RequestStats returns request queueing statistics per priority as a
+// JSON array of {"priority", "waiting", "served", "rejected",
+// "total_wait_ns", "max_wait_ns"}, or "[]" without [server]
+// max_concurrent_requests.
+func RequestStats() string {
+	bridgeMu.RLock()
+	b := globalBridge
+	bridgeMu.RUnlock()
+	if b == nil || b.scheduler == nil {
+		return "[]"
+	}
+	data, err := json.Marshal(b.scheduler.Stats())
+	if err != nil {
+		return "[]"
+	}
+	return string(data)
+}
+
 // HandleRequestSimple is a simplified version for basic requests.
 func HandleRequestSimple(method, url string) *core.Response {
 	return HandleRequest(method, url, "{}", nil)
@@ -125,6 +331,60 @@ func RenderInitialPage() string {
 	return resp.BodyString()
 }
 
+// SetNetworkReachable reports device connectivity from native reachability
+// monitoring. While offline, httpclient requests fail fast with ErrOffline.
+func SetNetworkReachable(reachable bool) {
+	httpclient.SetOnline(reachable)
+}
+
+// SetBatterySaver reports the device's low power mode from native
+// lifecycle events. While on, polling routes back off further.
+func SetBatterySaver(enabled bool) {
+	router.SetBatterySaver(enabled)
+}
+
+// EnableCrashReports saves Go panics recovered by the router, WebSocket
+// hub and request adapter as reports under dir, an app-private directory
+// such as Application Support or Context.getFilesDir(). Call it right
+// after InitializeWithConfig so reports carry the app version, then check
+// PendingCrashReports for reports from earlier runs.
+func EnableCrashReports(dir string) error {
+	rep, err := crash.NewReporter(dir, AppConfig().Version, 0)
+	if err != nil {
+		return err
+	}
+	crash.SetDefault(rep)
+	return nil
+}
+
+// PendingCrashReports returns the saved crash reports as a JSON array,
+// oldest first, or "[]" if there are none or reporting isn't enabled.
+// Show a "send report" prompt or upload them, then call MarkReported.
+func PendingCrashReports() string {
+	rep := crash.Default()
+	if rep == nil {
+		return "[]"
+	}
+	reports, err := rep.Pending()
+	if err != nil || len(reports) == 0 {
+		return "[]"
+	}
+	data, err := json.Marshal(reports)
+	if err != nil {
+		return "[]"
+	}
+	return string(data)
+}
+
+// MarkReported deletes a crash report by its "id" once it has been sent.
+func MarkReported(id string) error {
+	rep := crash.Default()
+	if rep == nil {
+		return errors.New("crash reports not enabled")
+	}
+	return rep.MarkReported(id)
+}
+
 // IsReady returns true if the bridge is initialized and ready.
 func IsReady() bool {
 	bridgeMu.RLock()
@@ -137,7 +397,18 @@ func Shutdown() {
 	bridgeMu.Lock()
 	defer bridgeMu.Unlock()
 
+	if stopMemory != nil {
+		stopMemory()
+		stopMemory = nil
+	}
+	if stopWatchdog != nil {
+		stopWatchdog()
+		stopWatchdog = nil
+	}
 	if globalBridge != nil {
+		if globalBridge.stopCleanup != nil {
+			globalBridge.stopCleanup()
+		}
 		if globalBridge.wsHub != nil {
 			globalBridge.wsHub.Close()
 		}
@@ -1,9 +1,11 @@
 package mobile
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"sync"
+	"time"
 
 	"github.com/stukennedy/irgo/pkg/websocket"
 )
@@ -21,6 +23,14 @@ type WebSocketCallback interface {
 	OnError(sessionID string, errorMsg string)
 }
 
+// BinaryCallback is implemented alongside WebSocketCallback by native code
+// that takes binary frames (see WebSocketSetBinaryFrames).
+type BinaryCallback interface {
+	// OnBinary is called instead of OnMessage for envelopes with binary
+	// data. frame is a websocket.FrameEnvelope frame.
+	OnBinary(sessionID string, frame []byte)
+}
+
 var (
 	wsCallback   WebSocketCallback
 	wsCallbackMu sync.RWMutex
@@ -83,7 +93,41 @@ func WebSocketSend(sessionID string, data string) (string, error) {
 		return "", errors.New("bridge not initialized")
 	}
 
-	envelope, err := hub.HandleMessage(sessionID, []byte(data))
+	return handleWebSocketMessage(hub, sessionID, []byte(data))
+}
+
+// WebSocketSendBinary sends a binary frame (websocket.FrameRequest) from
+// the WebView to Go. Returns the response envelope as JSON, or empty
+// string if no immediate response.
+func WebSocketSendBinary(sessionID string, frame []byte) (string, error) {
+	hub := GetHub()
+	if hub == nil {
+		return "", errors.New("bridge not initialized")
+	}
+
+	// Native code may reuse frame once this returns
+	return handleWebSocketMessage(hub, sessionID, bytes.Clone(frame))
+}
+
+// WebSocketSetBinaryFrames sets whether a session's envelopes with binary
+// data go to BinaryCallback.OnBinary as frames, rather than to OnMessage
+// as JSON with the data base64 encoded. Polling always returns JSON.
+func WebSocketSetBinaryFrames(sessionID string, enabled bool) error {
+	hub := GetHub()
+	if hub == nil {
+		return errors.New("bridge not initialized")
+	}
+
+	session, ok := hub.GetSession(sessionID)
+	if !ok {
+		return websocket.ErrSessionNotFound
+	}
+	session.SetBinaryFrames(enabled)
+	return nil
+}
+
+func handleWebSocketMessage(hub *websocket.Hub, sessionID string, data []byte) (string, error) {
+	envelope, err := hub.HandleMessage(sessionID, data)
 	if err != nil {
 		return "", err
 	}
@@ -141,6 +185,7 @@ func WebSocketPoll(sessionID string) string {
 			return ""
 		}
 		data, _ := json.Marshal(envelope)
+		hub.MarkDelivered(sessionID, envelope)
 		return string(data)
 	default:
 		return ""
@@ -171,16 +216,140 @@ func WebSocketPollBlocking(sessionID string, timeoutMs int) string {
 		return ""
 	}
 	data, _ := json.Marshal(envelope)
+	hub.MarkDelivered(sessionID, envelope)
 	return string(data)
 }
 
+// WebSocketConnectQueued creates a session read with NextEnvelope instead
+// of callbacks, so no goroutine outlives the WebView. Returns the session ID.
+func WebSocketConnectQueued(url string) (string, error) {
+	hub := GetHub()
+	if hub == nil {
+		return "", errors.New("bridge not initialized")
+	}
+
+	session, err := hub.ConnectQueued(url)
+	if err != nil {
+		return "", err
+	}
+	return session.ID, nil
+}
+
+// WebSocketConnectQueuedWithID reconnects a queued session. Envelopes
+// returned by NextEnvelope but not acknowledged are delivered again.
+func WebSocketConnectQueuedWithID(sessionID, url string) error {
+	hub := GetHub()
+	if hub == nil {
+		return errors.New("bridge not initialized")
+	}
+
+	_, err := hub.ConnectQueuedWithID(sessionID, url)
+	return err
+}
+
+// NextEnvelope returns the next JSON-encoded envelope for a queued session,
+// waiting up to timeoutMs (not at all if 0). Returns "" on timeout, for
+// unknown or closed sessions, and for sessions connected with callbacks.
+//
+// Native code polls from its own thread and acknowledges each envelope
+// once the WebView has applied it:
+//
+//	// Swift
+//	DispatchQueue.global().async {
+//	    while let json = MobileNextEnvelope(id, 30000) as String?, !json.isEmpty {
+//	        let seq = decodeSeq(json)
+//	        DispatchQueue.main.async {
+//	            webView.evaluateJavaScript("irgo.ws.deliver(\(json))") { _, _ in
+//	                MobileAckEnvelope(id, seq)
+//	            }
+//	        }
+//	    }
+//	}
+//
+//	// Kotlin
+//	scope.launch(Dispatchers.IO) {
+//	    while (isActive) {
+//	        val json = Mobile.nextEnvelope(id, 30000)
+//	        if (json.isEmpty()) continue
+//	        withContext(Dispatchers.Main) { deliver(json) }
+//	        Mobile.ackEnvelope(id, seqOf(json))
+//	    }
+//	}
+//
+// A loop that stops with its WebView leaves nothing running in Go; after
+// a reload, WebSocketConnectQueuedWithID replays what wasn't acknowledged.
+func NextEnvelope(sessionID string, timeoutMs int) string {
+	hub := GetHub()
+	if hub == nil {
+		return ""
+	}
+
+	session, ok := hub.GetSession(sessionID)
+	if !ok {
+		return ""
+	}
+
+	envelope, err := session.Next(time.Duration(timeoutMs) * time.Millisecond)
+	if err != nil || envelope == nil {
+		return ""
+	}
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		return ""
+	}
+	hub.MarkDelivered(sessionID, envelope)
+	return string(data)
+}
+
+// EnvelopeCount returns how many envelopes a queued session has waiting.
+func EnvelopeCount(sessionID string) int {
+	hub := GetHub()
+	if hub == nil {
+		return 0
+	}
+
+	session, ok := hub.GetSession(sessionID)
+	if !ok {
+		return 0
+	}
+	return session.Queued()
+}
+
+// AckEnvelope acknowledges every envelope up to and including seq (the
+// envelope's "seq" field), so they are not replayed on reconnect.
+func AckEnvelope(sessionID string, seq int64) {
+	hub := GetHub()
+	if hub == nil {
+		return
+	}
+
+	if session, ok := hub.GetSession(sessionID); ok {
+		session.Ack(seq)
+	}
+}
+
 // forwardSessionMessages forwards messages from a session to native code.
 func forwardSessionMessages(session *websocket.Session) {
 	wsCallbackMu.RLock()
 	cb := wsCallback
 	wsCallbackMu.RUnlock()
 
+	binaryCb, _ := cb.(BinaryCallback)
+
 	for envelope := range session.SendChan {
+		if binaryCb != nil && envelope.Binary != nil && session.BinaryFrames() {
+			frame, err := envelope.Frame()
+			if err != nil {
+				cb.OnError(session.ID, err.Error())
+				continue
+			}
+			binaryCb.OnBinary(session.ID, frame)
+			if hub := GetHub(); hub != nil {
+				hub.MarkDelivered(session.ID, envelope)
+			}
+			continue
+		}
+
 		data, err := json.Marshal(envelope)
 		if err != nil {
 			if cb != nil {
@@ -191,6 +360,9 @@ func forwardSessionMessages(session *websocket.Session) {
 
 		if cb != nil {
 			cb.OnMessage(session.ID, string(data))
+			if hub := GetHub(); hub != nil {
+				hub.MarkDelivered(session.ID, envelope)
+			}
 		} else {
 			// If no callback, try poll channel
 			pollChannelsMu.RLock()
@@ -209,7 +381,8 @@ func forwardSessionMessages(session *websocket.Session) {
 
 	// Session closed
 	if cb != nil {
-		cb.OnClose(session.ID, 1000, "Session closed")
+		reason := session.CloseReason()
+		cb.OnClose(session.ID, reason.Code, reason.Text)
 	}
 }
 
@@ -5,10 +5,17 @@ package adapter
 import (
 	"bytes"
 	"io"
+	"log"
 	"net/http"
 	"net/http/httptest"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/stukennedy/irgo/pkg/core"
+	"github.com/stukennedy/irgo/pkg/crash"
 )
 
 // HTTPAdapter bridges core.Request/Response to net/http.Handler.
@@ -16,6 +23,16 @@ import (
 // HTTP handlers without any network I/O.
 type HTTPAdapter struct {
 	handler http.Handler
+
+	inflight sync.Map // uint64 → InFlight, for requests being served
+	seq      atomic.Uint64
+}
+
+// InFlight describes a request the adapter is serving.
+type InFlight struct {
+	Method  string
+	URL     string
+	Started time.Time
 }
 
 // NewHTTPAdapter creates an adapter for the given http.Handler.
@@ -47,7 +64,16 @@ func (a *HTTPAdapter) HandleRequest(req *core.Request) *core.Response {
 	recorder := httptest.NewRecorder()
 
 	// Execute handler directly - no network!
-	a.handler.ServeHTTP(recorder, httpReq)
+	id := a.seq.Add(1)
+	a.inflight.Store(id, InFlight{Method: req.Method, URL: req.URL, Started: time.Now()})
+	ok := serve(a.handler, recorder, httpReq)
+	a.inflight.Delete(id)
+	if !ok {
+		return &core.Response{
+			Status: http.StatusInternalServerError,
+			Body:   []byte(`<div class="error" role="alert">Internal Server Error</div>`),
+		}
+	}
 
 	// Convert back to core.Response
 	result := recorder.Result()
@@ -72,6 +98,33 @@ func (a *HTTPAdapter) HandleRequest(req *core.Request) *core.Response {
 	return resp
 }
 
+// serve runs handler, reporting false if it panicked. A panic that gets
+// past the router's recoverer would otherwise crash the app, since the
+// adapter runs on the native caller's thread.
+func serve(handler http.Handler, w http.ResponseWriter, r *http.Request) (ok bool) {
+	defer func() {
+		if p := recover(); p != nil {
+			stack := debug.Stack()
+			log.Printf("adapter: panic serving %s %s: %v\n%s", r.Method, r.URL.Path, p, stack)
+			crash.Capture(crash.SourceAdapter, p, stack, crash.RequestSummary(r), nil)
+			ok = false
+		}
+	}()
+	handler.ServeHTTP(w, r)
+	return true
+}
+
+// InFlight returns the requests currently being served, oldest first.
+func (a *HTTPAdapter) InFlight() []InFlight {
+	var reqs []InFlight
+	a.inflight.Range(func(_, v any) bool {
+		reqs = append(reqs, v.(InFlight))
+		return true
+	})
+	sort.Slice(reqs, func(i, j int) bool { return reqs[i].Started.Before(reqs[j].Started) })
+	return reqs
+}
+
 // Handler returns the underlying http.Handler.
 func (a *HTTPAdapter) Handler() http.Handler {
 	return a.handler
@@ -2,9 +2,11 @@ package adapter
 
 import (
 	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/stukennedy/irgo/pkg/core"
+	"github.com/stukennedy/irgo/pkg/crash"
 )
 
 func TestHTTPAdapterBasicRequest(t *testing.T) {
@@ -128,3 +130,62 @@ func TestHTTPAdapterNotFound(t *testing.T) {
 		t.Errorf("expected status 404, got %d", resp.Status)
 	}
 }
+
+func TestHTTPAdapterPanic(t *testing.T) {
+	rep, err := crash.NewReporter(t.TempDir(), "2.0.0", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	crash.SetDefault(rep)
+	defer crash.SetDefault(nil)
+
+	adapter := NewHTTPAdapter(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("adapter boom")
+	}))
+	resp := adapter.HandleRequest(core.NewRequest("GET", "/items?session_id=s3cret"))
+
+	if resp.Status != http.StatusInternalServerError {
+		t.Errorf("expected status 500, got %d", resp.Status)
+	}
+	reports, _ := rep.Pending()
+	if len(reports) != 1 {
+		t.Fatalf("expected 1 crash report, got %d", len(reports))
+	}
+	r := reports[0]
+	if r.Source != crash.SourceAdapter || r.Panic != "adapter boom" || r.Version != "2.0.0" {
+		t.Errorf("unexpected report %+v", r)
+	}
+	if !strings.Contains(r.Stack, "TestHTTPAdapterPanic") {
+		t.Error("stack missing test frame")
+	}
+	if r.Request == nil || strings.Contains(r.Request.URL, "s3cret") {
+		t.Errorf("request summary not redacted: %+v", r.Request)
+	}
+}
+
+func TestHTTPAdapterInFlight(t *testing.T) {
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	adapter := NewHTTPAdapter(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-release
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		adapter.HandleRequest(core.NewRequest("POST", "/slow?x=1"))
+		close(done)
+	}()
+	<-entered
+
+	reqs := adapter.InFlight()
+	if len(reqs) != 1 || reqs[0].Method != "POST" || reqs[0].URL != "/slow?x=1" || reqs[0].Started.IsZero() {
+		t.Errorf("unexpected in-flight requests %+v", reqs)
+	}
+
+	close(release)
+	<-done
+	if reqs := adapter.InFlight(); len(reqs) != 0 {
+		t.Errorf("expected no in-flight requests, got %+v", reqs)
+	}
+}
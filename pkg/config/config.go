@@ -0,0 +1,397 @@
+// Package config loads app configuration from an optional gohtmx.toml file
+// with GOHTMX_* environment overrides. Desktop, mobile and the CLI share it.
+//
+// Precedence, highest first: values set in code (overrides passed to Load or
+// fields set on the result), environment variables, the file, defaults.
+//
+// Example gohtmx.toml:
+//
+//	[app]
+//	title = "Todo"
+//	debug = true
+//
+//	[window]
+//	width = 1280
+//
+//	[server]
+//	allowed_origins = ["http://localhost:5173"]
+//
+//	[features]
+//	offline_sync = true
+//
+//	[memory]
+//	budget_mb = 64       # soft limit for caches and buffers on mobile
+//
+//	[watchdog]
+//	stall_seconds = 10   # dump goroutines when a request runs this long
+//
+//	[workspace]          # "irgo dev --workspace" in a monorepo root
+//	port = 8080
+//
+//	[workspace.admin]    # routing for the app in a directory named admin
+//	prefix = "/admin"
+//	host = "admin.localhost"
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/stukennedy/irgo/internal/toml"
+)
+
+// FileName is the default config file name.
+const FileName = "gohtmx.toml"
+
+// Config holds app configuration.
+type Config struct {
+	Title     string // [app] title
+	Version   string // [app] version
+	Debug     bool   // [app] debug
+	Width     int    // [window] width
+	Height    int    // [window] height
+	Resizable bool   // [window] resizable
+
+	Port           int      // [server] port (0 = auto-select)
+	AllowedOrigins []string // [server] allowed_origins
+
+	// MaxConcurrentRequests caps requests the mobile bridge runs at once,
+	// queueing the rest by priority. [server] max_concurrent_requests
+	// (0 = unlimited)
+	MaxConcurrentRequests int
+
+	StaticDir    string // [assets] static_dir
+	TemplatesDir string // [assets] templates_dir
+
+	// Features holds [features] flags.
+	Features map[string]bool
+
+	MemoryBudgetMB int // [memory] budget_mb (0 = unlimited); see pkg/memory
+
+	WatchdogStallSeconds int // [watchdog] stall_seconds (0 = off); see pkg/watchdog
+
+	WorkspacePort int // [workspace] port; the dev proxy's port
+
+	// WorkspaceApps holds [workspace.<app>] routing, keyed by app name.
+	WorkspaceApps map[string]WorkspaceApp
+}
+
+// WorkspaceApp routes proxy requests to one app of a workspace.
+type WorkspaceApp struct {
+	Prefix string // [workspace.<app>] prefix, e.g. "/admin"
+	Host   string // [workspace.<app>] host, e.g. "admin.localhost"
+}
+
+// Default returns the built-in defaults.
+func Default() Config {
+	return Config{
+		Title:         "Irgo App",
+		Version:       "1.0.0",
+		Width:         1024,
+		Height:        768,
+		Resizable:     true,
+		StaticDir:     "static",
+		TemplatesDir:  "templates",
+		Features:      make(map[string]bool),
+		WorkspacePort: 8080,
+		WorkspaceApps: make(map[string]WorkspaceApp),
+	}
+}
+
+// Feature reports whether a feature flag is enabled.
+func (c *Config) Feature(name string) bool {
+	return c.Features[name]
+}
+
+// Validate checks values for consistency.
+func (c *Config) Validate() error {
+	var errs []error
+	if c.Width <= 0 || c.Height <= 0 {
+		errs = append(errs, fmt.Errorf("window size must be positive, got %dx%d", c.Width, c.Height))
+	}
+	if c.Port < 0 || c.Port > 65535 {
+		errs = append(errs, fmt.Errorf("server port %d out of range", c.Port))
+	}
+	if c.MaxConcurrentRequests < 0 {
+		errs = append(errs, fmt.Errorf("max concurrent requests %d must not be negative", c.MaxConcurrentRequests))
+	}
+	if c.MemoryBudgetMB < 0 {
+		errs = append(errs, fmt.Errorf("memory budget %d MB must not be negative", c.MemoryBudgetMB))
+	}
+	if c.WatchdogStallSeconds < 0 {
+		errs = append(errs, fmt.Errorf("watchdog stall timeout %ds must not be negative", c.WatchdogStallSeconds))
+	}
+	if c.WorkspacePort < 0 || c.WorkspacePort > 65535 {
+		errs = append(errs, fmt.Errorf("workspace port %d out of range", c.WorkspacePort))
+	}
+	for name, app := range c.WorkspaceApps {
+		if app.Prefix != "" && !strings.HasPrefix(app.Prefix, "/") {
+			errs = append(errs, fmt.Errorf("workspace app %q prefix %q must start with /", name, app.Prefix))
+		}
+	}
+	for _, o := range c.AllowedOrigins {
+		if !strings.HasPrefix(o, "http://") && !strings.HasPrefix(o, "https://") {
+			errs = append(errs, fmt.Errorf("allowed origin %q must start with http:// or https://", o))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// field describes a config key, its environment variable and how to set it.
+type field struct {
+	env      string
+	set      func(c *Config, v any) error
+	parseEnv func(raw string) (any, error)
+}
+
+func stringField(env string, ptr func(*Config) *string) field {
+	return field{env, setString(ptr), func(raw string) (any, error) { return raw, nil }}
+}
+
+func boolField(env string, ptr func(*Config) *bool) field {
+	return field{env, setBool(ptr), func(raw string) (any, error) { return strconv.ParseBool(raw) }}
+}
+
+func intField(env string, ptr func(*Config) *int) field {
+	return field{env, setInt(ptr), func(raw string) (any, error) { return strconv.ParseInt(raw, 10, 64) }}
+}
+
+// listField reads a comma-separated list from the environment.
+func listField(env string, ptr func(*Config) *[]string) field {
+	return field{env, setStrings(ptr), func(raw string) (any, error) { return splitList(raw), nil }}
+}
+
+var fields = map[string]field{
+	"app.title":                      stringField("GOHTMX_TITLE", func(c *Config) *string { return &c.Title }),
+	"app.version":                    stringField("GOHTMX_VERSION", func(c *Config) *string { return &c.Version }),
+	"app.debug":                      boolField("GOHTMX_DEBUG", func(c *Config) *bool { return &c.Debug }),
+	"window.width":                   intField("GOHTMX_WINDOW_WIDTH", func(c *Config) *int { return &c.Width }),
+	"window.height":                  intField("GOHTMX_WINDOW_HEIGHT", func(c *Config) *int { return &c.Height }),
+	"window.resizable":               boolField("GOHTMX_WINDOW_RESIZABLE", func(c *Config) *bool { return &c.Resizable }),
+	"server.port":                    intField("GOHTMX_PORT", func(c *Config) *int { return &c.Port }),
+	"server.allowed_origins":         listField("GOHTMX_ALLOWED_ORIGINS", func(c *Config) *[]string { return &c.AllowedOrigins }),
+	"server.max_concurrent_requests": intField("GOHTMX_MAX_CONCURRENT_REQUESTS", func(c *Config) *int { return &c.MaxConcurrentRequests }),
+	"assets.static_dir":              stringField("GOHTMX_STATIC_DIR", func(c *Config) *string { return &c.StaticDir }),
+	"assets.templates_dir":           stringField("GOHTMX_TEMPLATES_DIR", func(c *Config) *string { return &c.TemplatesDir }),
+	"memory.budget_mb":               intField("GOHTMX_MEMORY_BUDGET_MB", func(c *Config) *int { return &c.MemoryBudgetMB }),
+	"watchdog.stall_seconds":         intField("GOHTMX_WATCHDOG_STALL_SECONDS", func(c *Config) *int { return &c.WatchdogStallSeconds }),
+	"workspace.port":                 intField("GOHTMX_WORKSPACE_PORT", func(c *Config) *int { return &c.WorkspacePort }),
+}
+
+// featureEnvPrefix prefixes environment overrides for feature flags,
+// e.g. GOHTMX_FEATURE_OFFLINE_SYNC=1 sets features.offline_sync.
+const featureEnvPrefix = "GOHTMX_FEATURE_"
+
+// Load builds a Config from defaults, the file at path (if it exists), the
+// environment and finally overrides, in that order. Unknown keys are logged
+// as warnings. An empty path means FileName in the working directory.
+func Load(path string, overrides ...func(*Config)) (*Config, error) {
+	cfg, warnings, err := load(path)
+	for _, w := range warnings {
+		log.Printf("config: %s", w)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, fn := range overrides {
+		fn(cfg)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Check loads and validates the file at path without environment overrides,
+// returning warnings for unknown keys. Used by "irgo config check".
+func Check(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg := Default()
+	warnings, err := applyFile(&cfg, path, data)
+	if err != nil {
+		return warnings, err
+	}
+	return warnings, cfg.Validate()
+}
+
+func load(path string) (*Config, []string, error) {
+	if path == "" {
+		path = FileName
+	}
+	cfg := Default()
+
+	var warnings []string
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if warnings, err = applyFile(&cfg, path, data); err != nil {
+			return nil, warnings, err
+		}
+	case !errors.Is(err, fs.ErrNotExist):
+		return nil, nil, err
+	}
+
+	if err := applyEnv(&cfg); err != nil {
+		return nil, warnings, err
+	}
+	return &cfg, warnings, nil
+}
+
+func applyFile(cfg *Config, name string, data []byte) ([]string, error) {
+	entries, err := toml.Parse(name, data)
+	if err != nil {
+		return nil, err
+	}
+
+	var warnings []string
+	for _, e := range entries {
+		if feature, ok := strings.CutPrefix(e.Key, "features."); ok {
+			b, ok := e.Value.(bool)
+			if !ok {
+				return warnings, fmt.Errorf("%s:%d: %s: expected boolean", name, e.Line, e.Key)
+			}
+			cfg.Features[feature] = b
+			continue
+		}
+		if handled, err := applyWorkspaceApp(cfg, e); handled {
+			if err != nil {
+				return warnings, fmt.Errorf("%s:%d: %s: %w", name, e.Line, e.Key, err)
+			}
+			continue
+		}
+		f, ok := fields[e.Key]
+		if !ok {
+			warnings = append(warnings, fmt.Sprintf("%s:%d: unknown key %q", name, e.Line, e.Key))
+			continue
+		}
+		if err := f.set(cfg, e.Value); err != nil {
+			return warnings, fmt.Errorf("%s:%d: %s: %w", name, e.Line, e.Key, err)
+		}
+	}
+	return warnings, nil
+}
+
+// applyWorkspaceApp sets a workspace.<app>.prefix or .host entry,
+// reporting whether e was one.
+func applyWorkspaceApp(cfg *Config, e toml.Entry) (bool, error) {
+	rest, ok := strings.CutPrefix(e.Key, "workspace.")
+	if !ok {
+		return false, nil
+	}
+	app, key, ok := strings.Cut(rest, ".")
+	if !ok || (key != "prefix" && key != "host") {
+		return false, nil
+	}
+	s, ok := e.Value.(string)
+	if !ok {
+		return true, fmt.Errorf("expected string")
+	}
+	wa := cfg.WorkspaceApps[app]
+	if key == "prefix" {
+		wa.Prefix = s
+	} else {
+		wa.Host = s
+	}
+	cfg.WorkspaceApps[app] = wa
+	return true, nil
+}
+
+func applyEnv(cfg *Config) error {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		f := fields[key]
+		raw, ok := os.LookupEnv(f.env)
+		if !ok {
+			continue
+		}
+		v, err := f.parseEnv(raw)
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.env, err)
+		}
+		if err := f.set(cfg, v); err != nil {
+			return fmt.Errorf("%s: %w", f.env, err)
+		}
+	}
+
+	for _, kv := range os.Environ() {
+		name, raw, _ := strings.Cut(kv, "=")
+		feature, ok := strings.CutPrefix(name, featureEnvPrefix)
+		if !ok || feature == "" {
+			continue
+		}
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		cfg.Features[strings.ToLower(feature)] = b
+	}
+	return nil
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func setString(ptr func(*Config) *string) func(*Config, any) error {
+	return func(c *Config, v any) error {
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("expected string")
+		}
+		*ptr(c) = s
+		return nil
+	}
+}
+
+func setBool(ptr func(*Config) *bool) func(*Config, any) error {
+	return func(c *Config, v any) error {
+		b, ok := v.(bool)
+		if !ok {
+			return fmt.Errorf("expected boolean")
+		}
+		*ptr(c) = b
+		return nil
+	}
+}
+
+func setInt(ptr func(*Config) *int) func(*Config, any) error {
+	return func(c *Config, v any) error {
+		n, ok := v.(int64)
+		if !ok {
+			return fmt.Errorf("expected integer")
+		}
+		*ptr(c) = int(n)
+		return nil
+	}
+}
+
+func setStrings(ptr func(*Config) *[]string) func(*Config, any) error {
+	return func(c *Config, v any) error {
+		s, ok := v.([]string)
+		if !ok {
+			return fmt.Errorf("expected array of strings")
+		}
+		*ptr(c) = s
+		return nil
+	}
+}
@@ -0,0 +1,201 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const sampleFile = `
+# App settings
+[app]
+title = "Todo # List"  # trailing comment
+debug = false
+
+[window]
+width = 1_280
+resizable = false
+
+[server]
+port = 9000
+allowed_origins = ["http://localhost:5173", "https://app.example.com"]
+
+[features]
+offline_sync = true
+`
+
+func writeFile(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), FileName)
+	if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadFile(t *testing.T) {
+	cfg, err := Load(writeFile(t, sampleFile))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if cfg.Title != "Todo # List" {
+		t.Errorf("expected title with '#', got %q", cfg.Title)
+	}
+	if cfg.Width != 1280 || cfg.Height != 768 {
+		t.Errorf("expected 1280x768 (height from defaults), got %dx%d", cfg.Width, cfg.Height)
+	}
+	if cfg.Resizable {
+		t.Error("expected resizable false from file")
+	}
+	if cfg.Port != 9000 {
+		t.Errorf("expected port 9000, got %d", cfg.Port)
+	}
+	want := []string{"http://localhost:5173", "https://app.example.com"}
+	if !reflect.DeepEqual(cfg.AllowedOrigins, want) {
+		t.Errorf("expected origins %v, got %v", want, cfg.AllowedOrigins)
+	}
+	if !cfg.Feature("offline_sync") || cfg.Feature("other") {
+		t.Errorf("unexpected features %v", cfg.Features)
+	}
+}
+
+func TestLoadMissingFileUsesDefaults(t *testing.T) {
+	cfg, err := Load(filepath.Join(t.TempDir(), FileName))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !reflect.DeepEqual(*cfg, Default()) {
+		t.Errorf("expected defaults, got %+v", cfg)
+	}
+}
+
+func TestEnvOverride(t *testing.T) {
+	t.Setenv("GOHTMX_DEBUG", "true")
+	t.Setenv("GOHTMX_PORT", "9100")
+	t.Setenv("GOHTMX_ALLOWED_ORIGINS", "http://a.test, http://b.test")
+	t.Setenv("GOHTMX_FEATURE_OFFLINE_SYNC", "false")
+	t.Setenv("GOHTMX_MEMORY_BUDGET_MB", "48")
+
+	cfg, err := Load(writeFile(t, sampleFile))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !cfg.Debug {
+		t.Error("expected GOHTMX_DEBUG to override file")
+	}
+	if cfg.Port != 9100 {
+		t.Errorf("expected port 9100 from env, got %d", cfg.Port)
+	}
+	if !reflect.DeepEqual(cfg.AllowedOrigins, []string{"http://a.test", "http://b.test"}) {
+		t.Errorf("unexpected origins %v", cfg.AllowedOrigins)
+	}
+	if cfg.Feature("offline_sync") {
+		t.Error("expected feature env to override file")
+	}
+	if cfg.MemoryBudgetMB != 48 {
+		t.Errorf("expected memory budget 48 from env, got %d", cfg.MemoryBudgetMB)
+	}
+}
+
+func TestEnvInvalidValue(t *testing.T) {
+	t.Setenv("GOHTMX_DEBUG", "maybe")
+	if _, err := Load(writeFile(t, "")); err == nil || !strings.Contains(err.Error(), "GOHTMX_DEBUG") {
+		t.Errorf("expected GOHTMX_DEBUG error, got %v", err)
+	}
+}
+
+func TestPrecedence(t *testing.T) {
+	path := writeFile(t, "[app]\ntitle = \"file\"\nversion = \"file\"\n[window]\nwidth = 800\n")
+	t.Setenv("GOHTMX_TITLE", "env")
+	t.Setenv("GOHTMX_VERSION", "env")
+
+	cfg, err := Load(path, func(c *Config) { c.Title = "code" })
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	// code > env > file > defaults
+	got := []string{cfg.Title, cfg.Version, strconv.Itoa(cfg.Width), strconv.Itoa(cfg.Height)}
+	want := []string{"code", "env", "800", "768"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	// Loading again yields the same result
+	again, _ := Load(path, func(c *Config) { c.Title = "code" })
+	if !reflect.DeepEqual(cfg, again) {
+		t.Errorf("expected deterministic result, got %+v and %+v", cfg, again)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	warnings, err := Check(writeFile(t, sampleFile+"\n[window]\ncolour = \"red\"\n"))
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], `unknown key "window.colour"`) {
+		t.Errorf("expected unknown key warning, got %v", warnings)
+	}
+}
+
+func TestCheckBadFiles(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		want     string
+	}{
+		{"syntax", "[app\ntitle = 1", "invalid table header"},
+		{"missing equals", "[app]\ntitle", ":2: expected key = value"},
+		{"wrong type", "[window]\nwidth = \"wide\"", "window.width: expected integer"},
+		{"unterminated string", "[app]\ntitle = \"Todo", "unterminated string"},
+		{"duplicate key", "[app]\ntitle = \"a\"\ntitle = \"b\"", "duplicate key"},
+		{"feature not bool", "[features]\nbeta = 1", "features.beta: expected boolean"},
+		{"invalid port", "[server]\nport = 70000", "out of range"},
+		{"bad origin", "[server]\nallowed_origins = [\"example.com\"]", "must start with http"},
+		{"negative request cap", "[server]\nmax_concurrent_requests = -1", "must not be negative"},
+		{"negative memory budget", "[memory]\nbudget_mb = -1", "must not be negative"},
+		{"negative watchdog timeout", "[watchdog]\nstall_seconds = -5", "must not be negative"},
+		{"workspace prefix", "[workspace.admin]\nprefix = \"admin\"", "must start with /"},
+		{"workspace host type", "[workspace.admin]\nhost = 1", "workspace.admin.host: expected string"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Check(writeFile(t, tt.contents))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestWorkspace(t *testing.T) {
+	cfg, err := Load(writeFile(t, `
+[workspace]
+port = 3000
+
+[workspace.admin]
+prefix = "/admin"
+host = "admin.localhost"
+
+[workspace.site]
+prefix = "/"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.WorkspacePort != 3000 {
+		t.Errorf("expected workspace port 3000, got %d", cfg.WorkspacePort)
+	}
+	want := map[string]WorkspaceApp{
+		"admin": {Prefix: "/admin", Host: "admin.localhost"},
+		"site":  {Prefix: "/"},
+	}
+	if !reflect.DeepEqual(cfg.WorkspaceApps, want) {
+		t.Errorf("expected apps %v, got %v", want, cfg.WorkspaceApps)
+	}
+}
@@ -0,0 +1,149 @@
+// Package content loads signed over-the-air content bundles: replacement
+// templates and handler data tables applied to a running app without a
+// new binary.
+//
+// A bundle is a 64-byte ed25519 signature followed by the zip archive it
+// signs. Files under templates/ replace the engine's templates; each
+// data/<key>.json file replaces the handler data stored under key.
+package content
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/stukennedy/irgo/pkg/render"
+	"github.com/stukennedy/irgo/pkg/router"
+)
+
+var (
+	// ErrBadSignature is returned when a bundle's signature doesn't match
+	// the public key.
+	ErrBadSignature = errors.New("content: invalid bundle signature")
+
+	// ErrInvalidKey is returned for a public or private key of the wrong size.
+	ErrInvalidKey = errors.New("content: invalid ed25519 key")
+)
+
+// Bundle is a verified content bundle.
+type Bundle struct {
+	// Templates holds the bundle's templates/ directory, or nil if it has none.
+	Templates fs.FS
+	// Data maps handler data keys to their decoded JSON values.
+	Data map[string]any
+}
+
+// LoadContentBundle verifies data against pubKey and unpacks it. Nothing in
+// the bundle is read before the signature checks out.
+func LoadContentBundle(data []byte, pubKey ed25519.PublicKey) (*Bundle, error) {
+	if len(pubKey) != ed25519.PublicKeySize {
+		return nil, ErrInvalidKey
+	}
+	if len(data) < ed25519.SignatureSize {
+		return nil, ErrBadSignature
+	}
+	sig, payload := data[:ed25519.SignatureSize], data[ed25519.SignatureSize:]
+	if !ed25519.Verify(pubKey, payload, sig) {
+		return nil, ErrBadSignature
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(payload), int64(len(payload)))
+	if err != nil {
+		return nil, fmt.Errorf("content: reading bundle: %w", err)
+	}
+
+	b := &Bundle{Data: make(map[string]any)}
+	for _, f := range zr.File {
+		switch {
+		case strings.HasPrefix(f.Name, "templates/") && b.Templates == nil:
+			if b.Templates, err = fs.Sub(zr, "templates"); err != nil {
+				return nil, err
+			}
+		case strings.HasPrefix(f.Name, "data/") && path.Ext(f.Name) == ".json":
+			key := strings.TrimSuffix(strings.TrimPrefix(f.Name, "data/"), ".json")
+			v, err := decodeJSON(f)
+			if err != nil {
+				return nil, fmt.Errorf("content: %s: %w", f.Name, err)
+			}
+			b.Data[key] = v
+		}
+	}
+	return b, nil
+}
+
+// Apply swaps the bundle's templates into e and its data into r; either may
+// be nil. If the templates fail to parse or a smoke render fails, the
+// engine keeps its previous set and no data is applied.
+func (b *Bundle) Apply(e *render.Engine, r *router.Router) error {
+	if e != nil && b.Templates != nil {
+		if err := e.SwapTemplates(b.Templates); err != nil {
+			return err
+		}
+	}
+	if r != nil {
+		for key, v := range b.Data {
+			r.SwapHandlerData(key, v)
+		}
+	}
+	log.Printf("content: applied bundle (%d data tables)", len(b.Data))
+	return nil
+}
+
+// Pack builds a signed bundle from files keyed by path, e.g.
+// "templates/home.html" or "data/prices.json". It is meant for build
+// tooling; apps only need LoadContentBundle.
+func Pack(files map[string][]byte, privKey ed25519.PrivateKey) ([]byte, error) {
+	if len(privKey) != ed25519.PrivateKeySize {
+		return nil, ErrInvalidKey
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range names {
+		w, err := zw.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(files[name]); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	payload := buf.Bytes()
+	return append(ed25519.Sign(privKey, payload), payload...), nil
+}
+
+func decodeJSON(f *zip.File) (any, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	raw, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, err
+	}
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
@@ -0,0 +1,108 @@
+package content
+
+import (
+	"crypto/ed25519"
+	"testing"
+
+	"github.com/stukennedy/irgo/pkg/render"
+	"github.com/stukennedy/irgo/pkg/router"
+)
+
+func testKeys(t *testing.T) (ed25519.PublicKey, ed25519.PrivateKey) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pub, priv
+}
+
+func TestLoadContentBundle(t *testing.T) {
+	pub, priv := testKeys(t)
+	data, err := Pack(map[string][]byte{
+		"templates/home.html": []byte(`{{define "home"}}v2 {{.}}{{end}}`),
+		"data/prices.json":    []byte(`{"tea": 3}`),
+	}, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := LoadContentBundle(data, pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := render.New()
+	if err := e.Parse("home", `{{define "home"}}v1{{end}}`); err != nil {
+		t.Fatal(err)
+	}
+	r := router.New()
+	if err := b.Apply(e, r); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, _ := e.Render("home", "ok"); got != "v2 ok" {
+		t.Errorf("template = %q, want %q", got, "v2 ok")
+	}
+	prices, _ := r.HandlerData("prices").(map[string]any)
+	if prices["tea"] != 3.0 {
+		t.Errorf("prices = %v", r.HandlerData("prices"))
+	}
+}
+
+func TestLoadContentBundleRejectsBadSignature(t *testing.T) {
+	pub, priv := testKeys(t)
+	otherPub, _ := testKeys(t)
+	data, err := Pack(map[string][]byte{"data/a.json": []byte(`1`)}, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadContentBundle(data, otherPub); err != ErrBadSignature {
+		t.Errorf("wrong key: err = %v, want ErrBadSignature", err)
+	}
+
+	tampered := append([]byte(nil), data...)
+	tampered[len(tampered)-1] ^= 0xff
+	if _, err := LoadContentBundle(tampered, pub); err != ErrBadSignature {
+		t.Errorf("tampered: err = %v, want ErrBadSignature", err)
+	}
+	if _, err := LoadContentBundle(data[:10], pub); err != ErrBadSignature {
+		t.Errorf("short: err = %v, want ErrBadSignature", err)
+	}
+	if _, err := LoadContentBundle(data, pub[:5]); err != ErrInvalidKey {
+		t.Errorf("short key: err = %v, want ErrInvalidKey", err)
+	}
+}
+
+func TestBundleApplyRollsBack(t *testing.T) {
+	pub, priv := testKeys(t)
+	data, err := Pack(map[string][]byte{
+		"templates/home.html": []byte(`{{define "home"}}{{template "gone"}}{{end}}`),
+		"data/prices.json":    []byte(`{"tea": 3}`),
+	}, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := LoadContentBundle(data, pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := render.New()
+	if err := e.Parse("home", `{{define "home"}}v1{{end}}`); err != nil {
+		t.Fatal(err)
+	}
+	e.SmokeTest("home", nil)
+	r := router.New()
+
+	if err := b.Apply(e, r); err == nil {
+		t.Fatal("expected smoke render failure")
+	}
+	if got, _ := e.Render("home", nil); got != "v1" {
+		t.Errorf("template = %q, want v1 restored", got)
+	}
+	if r.HandlerData("prices") != nil {
+		t.Error("data applied despite failed template swap")
+	}
+}
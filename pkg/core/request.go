@@ -15,6 +15,58 @@ type Request struct {
 	URL     string // Full URL path with query string, e.g., "/tasks?filter=active"
 	Headers string // JSON-encoded map[string]string for headers
 	Body    []byte // Request body (form data, JSON, etc.)
+
+	// Priority schedules the request when the bridge is busy: one of the
+	// Priority constants, or 0 to derive it with EffectivePriority.
+	Priority int
+}
+
+// Request priorities, most urgent first. Plain ints for gomobile.
+const (
+	PriorityInteractive = 1 // A user action, such as a tap
+	PriorityNormal      = 2
+	PriorityBackground  = 3 // Prefetches, polling and sync
+)
+
+// PriorityHeader sets a request's priority by name: "interactive",
+// "normal" or "background". The dsPoll template helper sends it.
+const PriorityHeader = "X-Irgo-Priority"
+
+// EffectivePriority returns the request's Priority if set, else derives
+// it: PriorityHeader if present, PriorityBackground for prefetches
+// (Purpose or Sec-Purpose: prefetch), PriorityInteractive for Datastar
+// actions, and PriorityNormal otherwise.
+func (r *Request) EffectivePriority() int {
+	if r.Priority >= PriorityInteractive && r.Priority <= PriorityBackground {
+		return r.Priority
+	}
+	headers := r.GetHeaders()
+	switch strings.ToLower(headers[PriorityHeader]) {
+	case "interactive":
+		return PriorityInteractive
+	case "normal":
+		return PriorityNormal
+	case "background":
+		return PriorityBackground
+	}
+	if strings.Contains(headers["Purpose"], "prefetch") || strings.Contains(headers["Sec-Purpose"], "prefetch") {
+		return PriorityBackground
+	}
+	if headers["Datastar-Request"] == "true" || headers["Accept"] == "text/event-stream" {
+		return PriorityInteractive
+	}
+	return PriorityNormal
+}
+
+// PriorityName returns "interactive", "normal" or "background".
+func PriorityName(priority int) string {
+	switch priority {
+	case PriorityInteractive:
+		return "interactive"
+	case PriorityBackground:
+		return "background"
+	}
+	return "normal"
 }
 
 // NewRequest creates a new Request with the given method and URL.
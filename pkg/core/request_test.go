@@ -98,3 +98,29 @@ func TestRequestBody(t *testing.T) {
 		t.Errorf("BodyString() = %q, want %q", s, `{"name": "test"}`)
 	}
 }
+
+func TestRequestEffectivePriority(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		set     int
+		want    int
+	}{
+		{"plain", nil, 0, PriorityNormal},
+		{"datastar action", map[string]string{"Datastar-Request": "true"}, 0, PriorityInteractive},
+		{"poll", map[string]string{"Datastar-Request": "true", PriorityHeader: "background"}, 0, PriorityBackground},
+		{"prefetch", map[string]string{"Sec-Purpose": "prefetch;prerender"}, 0, PriorityBackground},
+		{"explicit", map[string]string{"Datastar-Request": "true"}, PriorityBackground, PriorityBackground},
+		{"out of range", nil, 9, PriorityNormal},
+	}
+	for _, tt := range tests {
+		req := NewRequest("GET", "/")
+		req.Priority = tt.set
+		if tt.headers != nil {
+			req.SetHeaders(tt.headers)
+		}
+		if got := req.EffectivePriority(); got != tt.want {
+			t.Errorf("%s: EffectivePriority() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
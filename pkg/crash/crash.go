@@ -0,0 +1,271 @@
+// Package crash records panics as structured reports on disk, so they
+// survive the app being killed and can be shown or uploaded on the next
+// launch.
+//
+// The router's recoverer, the WebSocket hub and the virtual HTTP adapter
+// report to the process-wide Reporter installed with SetDefault:
+//
+//	rep, err := crash.NewReporter(filepath.Join(dataDir, "crashes"), "1.2.0", 0)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	crash.SetDefault(rep)
+//
+// Header, query and message values whose names look secret (tokens,
+// cookies, passwords and the like) are redacted before writing.
+package crash
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultMaxReports is how many reports a Reporter keeps when created
+// with max <= 0. The oldest are deleted first.
+const DefaultMaxReports = 20
+
+// Redacted replaces secret values in reports.
+const Redacted = "[redacted]"
+
+// Report sources.
+const (
+	SourceHTTP      = "http"
+	SourceWebSocket = "websocket"
+	SourceAdapter   = "adapter"
+	SourceWatchdog  = "watchdog" // A stall dump rather than a panic; see pkg/watchdog
+)
+
+// ErrInvalidID is returned by MarkReported for IDs that can't name a report.
+var ErrInvalidID = errors.New("crash: invalid report ID")
+
+// Report describes one recovered panic.
+type Report struct {
+	ID      string    `json:"id"`
+	Time    time.Time `json:"time"`
+	Version string    `json:"version,omitempty"` // App version
+	Source  string    `json:"source"`            // SourceHTTP, SourceWebSocket, SourceAdapter or SourceWatchdog
+	Panic   string    `json:"panic"`
+	Stack   string    `json:"stack"`
+
+	Request *Request `json:"request,omitempty"`
+	Message *Message `json:"message,omitempty"`
+}
+
+// Request summarises the HTTP request being served when a panic occurred.
+type Request struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// Message summarises the WebSocket message being handled when a panic
+// occurred.
+type Message struct {
+	SessionID string            `json:"session_id"`
+	URL       string            `json:"url"`
+	Event     string            `json:"event,omitempty"`
+	Path      string            `json:"path,omitempty"`
+	Values    map[string]string `json:"values,omitempty"`
+}
+
+// Reporter writes reports as JSON files in a directory, keeping at most
+// a fixed number.
+type Reporter struct {
+	dir     string
+	version string
+	max     int
+
+	mu  sync.Mutex
+	now func() time.Time
+}
+
+// NewReporter creates dir if needed and returns a Reporter writing to it.
+// version is recorded in each report; max <= 0 means DefaultMaxReports.
+func NewReporter(dir, version string, max int) (*Reporter, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	if max <= 0 {
+		max = DefaultMaxReports
+	}
+	return &Reporter{dir: dir, version: version, max: max, now: time.Now}, nil
+}
+
+// Capture fills in the report's ID, time and version, writes it and
+// deletes the oldest reports over the limit. It returns the ID.
+func (r *Reporter) Capture(report Report) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report.Time = r.now().UTC()
+	report.Version = r.version
+	report.ID = newID(report.Time)
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	// Write then rename so a crash mid-write never leaves a partial report
+	tmp := filepath.Join(r.dir, report.ID+".tmp")
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp, r.path(report.ID)); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+
+	ids, err := r.ids()
+	if err != nil {
+		return report.ID, err
+	}
+	for len(ids) > r.max {
+		os.Remove(r.path(ids[0]))
+		ids = ids[1:]
+	}
+	return report.ID, nil
+}
+
+// Pending returns the reports not yet marked reported, oldest first.
+// Unreadable files are skipped.
+func (r *Reporter) Pending() ([]Report, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids, err := r.ids()
+	if err != nil {
+		return nil, err
+	}
+	reports := make([]Report, 0, len(ids))
+	for _, id := range ids {
+		data, err := os.ReadFile(r.path(id))
+		if err != nil {
+			continue
+		}
+		var report Report
+		if json.Unmarshal(data, &report) == nil {
+			reports = append(reports, report)
+		}
+	}
+	return reports, nil
+}
+
+// MarkReported deletes a report once it has been shown or uploaded.
+func (r *Reporter) MarkReported(id string) error {
+	if id == "" || id != filepath.Base(id) || strings.HasPrefix(id, ".") {
+		return ErrInvalidID
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return os.Remove(r.path(id))
+}
+
+func (r *Reporter) path(id string) string {
+	return filepath.Join(r.dir, id+".json")
+}
+
+// ids lists stored report IDs, oldest first.
+func (r *Reporter) ids() ([]string, error) {
+	entries, err := os.ReadDir(r.dir)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, e := range entries {
+		if id, ok := strings.CutSuffix(e.Name(), ".json"); ok && !e.IsDir() {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// newID returns a unique ID that sorts by time.
+func newID(t time.Time) string {
+	var b [4]byte
+	rand.Read(b[:])
+	return t.Format("20060102T150405.000000000") + "-" + hex.EncodeToString(b[:])
+}
+
+var defaultReporter atomic.Pointer[Reporter]
+
+// SetDefault installs the Reporter that Capture uses; nil disables
+// reporting.
+func SetDefault(r *Reporter) {
+	defaultReporter.Store(r)
+}
+
+// Default returns the Reporter installed with SetDefault, or nil.
+func Default() *Reporter {
+	return defaultReporter.Load()
+}
+
+// Capture records a recovered panic with the default Reporter. It does
+// nothing if none is installed, and only logs if writing fails.
+func Capture(source string, value any, stack []byte, req *Request, msg *Message) {
+	r := Default()
+	if r == nil {
+		return
+	}
+	report := Report{
+		Source:  source,
+		Panic:   fmt.Sprint(value),
+		Stack:   string(stack),
+		Request: req,
+		Message: msg,
+	}
+	if _, err := r.Capture(report); err != nil {
+		log.Printf("crash: writing report: %v", err)
+	}
+}
+
+// RequestSummary summarises r for a report, redacting secret headers and
+// query parameters.
+func RequestSummary(r *http.Request) *Request {
+	u := *r.URL
+	if q := u.Query(); len(q) > 0 {
+		for name, values := range q {
+			for i := range values {
+				values[i] = RedactValue(name, values[i])
+			}
+		}
+		u.RawQuery = q.Encode()
+	}
+
+	req := &Request{Method: r.Method, URL: u.String()}
+	if len(r.Header) > 0 {
+		req.Headers = make(map[string]string, len(r.Header))
+		for name, values := range r.Header {
+			req.Headers[name] = RedactValue(name, strings.Join(values, ", "))
+		}
+	}
+	return req
+}
+
+// secretWords mark header, parameter and field names whose values are
+// redacted.
+var secretWords = []string{"auth", "cookie", "token", "secret", "password", "passwd", "session", "csrf", "apikey", "api_key", "api-key"}
+
+// RedactValue returns Redacted if name looks like it holds a secret, and
+// value otherwise.
+func RedactValue(name, value string) string {
+	lower := strings.ToLower(name)
+	for _, w := range secretWords {
+		if strings.Contains(lower, w) {
+			return Redacted
+		}
+	}
+	return value
+}
@@ -0,0 +1,122 @@
+package crash
+
+import (
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCaptureAndPending(t *testing.T) {
+	rep, err := NewReporter(t.TempDir(), "1.2.0", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := rep.Capture(Report{Source: SourceHTTP, Panic: "boom", Stack: "goroutine 1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reports, err := rep.Pending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 1 {
+		t.Fatalf("expected 1 report, got %d", len(reports))
+	}
+	r := reports[0]
+	if r.ID != id || r.Version != "1.2.0" || r.Source != SourceHTTP || r.Panic != "boom" || r.Stack != "goroutine 1" {
+		t.Errorf("unexpected report %+v", r)
+	}
+	if time.Since(r.Time) > time.Minute {
+		t.Errorf("report time %v", r.Time)
+	}
+
+	if err := rep.MarkReported(id); err != nil {
+		t.Fatal(err)
+	}
+	if reports, _ := rep.Pending(); len(reports) != 0 {
+		t.Errorf("expected no pending reports after ack, got %d", len(reports))
+	}
+	if err := rep.MarkReported(id); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("second ack err = %v, want ErrNotExist", err)
+	}
+	for _, bad := range []string{"", "../x", "a/b", ".hidden"} {
+		if err := rep.MarkReported(bad); !errors.Is(err, ErrInvalidID) {
+			t.Errorf("MarkReported(%q) err = %v, want ErrInvalidID", bad, err)
+		}
+	}
+}
+
+func TestRotation(t *testing.T) {
+	rep, err := NewReporter(t.TempDir(), "", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	n := 0
+	rep.now = func() time.Time { n++; return base.Add(time.Duration(n) * time.Second) }
+
+	for i := 0; i < 5; i++ {
+		if _, err := rep.Capture(Report{Source: SourceAdapter, Panic: fmt.Sprint("panic ", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	reports, _ := rep.Pending()
+	var got []string
+	for _, r := range reports {
+		got = append(got, r.Panic)
+	}
+	if want := "panic 2,panic 3,panic 4"; strings.Join(got, ",") != want {
+		t.Errorf("kept %v, want %s", got, want)
+	}
+}
+
+func TestRequestSummaryRedacts(t *testing.T) {
+	req := httptest.NewRequest("POST", "/login?next=/home&token=abc123&api_key=k", nil)
+	req.Header.Set("Authorization", "Bearer xyz")
+	req.Header.Set("Cookie", "session=s1")
+	req.Header.Set("X-CSRF-Token", "t")
+	req.Header.Set("Accept", "text/html")
+
+	s := RequestSummary(req)
+	if s.Method != "POST" {
+		t.Errorf("method %q", s.Method)
+	}
+	for _, secret := range []string{"abc123", "api_key=k&", "xyz", "s1"} {
+		if strings.Contains(s.URL, secret) || strings.Contains(fmt.Sprint(s.Headers), secret) {
+			t.Errorf("summary leaks %q: %+v", secret, s)
+		}
+	}
+	if !strings.Contains(s.URL, "next=%2Fhome") || !strings.Contains(s.URL, "token=%5Bredacted%5D") {
+		t.Errorf("URL = %q", s.URL)
+	}
+	if s.Headers["Accept"] != "text/html" {
+		t.Errorf("Accept = %q", s.Headers["Accept"])
+	}
+	for _, h := range []string{"Authorization", "Cookie", "X-Csrf-Token"} {
+		if s.Headers[h] != Redacted {
+			t.Errorf("%s = %q, want redacted", h, s.Headers[h])
+		}
+	}
+}
+
+func TestCaptureDefault(t *testing.T) {
+	Capture(SourceHTTP, "ignored", nil, nil, nil) // no reporter installed
+
+	rep, err := NewReporter(t.TempDir(), "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetDefault(rep)
+	defer SetDefault(nil)
+
+	Capture(SourceWebSocket, errors.New("bad"), []byte("stack"), nil, &Message{SessionID: "s1"})
+	reports, _ := rep.Pending()
+	if len(reports) != 1 || reports[0].Panic != "bad" || reports[0].Message.SessionID != "s1" {
+		t.Errorf("unexpected reports %+v", reports)
+	}
+}
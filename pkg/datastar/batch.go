@@ -0,0 +1,106 @@
+package datastar
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"github.com/a-h/templ"
+	"github.com/starfederation/datastar-go/datastar"
+)
+
+// Batch buffers SSE events so several patches are sent together or not at
+// all. Components and signals are rendered when added, so a failure is
+// known before anything is written; Flush then sends every event.
+//
+//	b := datastar.NewBatch(ctx)
+//	b.PatchTempl(TodoRow(todo)).PatchHTMLByID("count", count)
+//	if err := b.Flush(sse); err != nil { ... }
+type Batch struct {
+	ctx    context.Context
+	events []func(*SSE) error
+	err    error
+}
+
+// NewBatch creates a batch that renders components with ctx.
+func NewBatch(ctx context.Context) *Batch {
+	return &Batch{ctx: ctx}
+}
+
+// PatchHTML buffers an element patch.
+func (b *Batch) PatchHTML(html string, opts ...datastar.PatchElementOption) *Batch {
+	return b.add(func(s *SSE) error { return s.PatchHTML(html, opts...) })
+}
+
+// PatchHTMLByID buffers an element patch targeting an element by ID.
+func (b *Batch) PatchHTMLByID(id, html string, opts ...datastar.PatchElementOption) *Batch {
+	return b.add(func(s *SSE) error { return s.PatchHTMLByID(id, html, opts...) })
+}
+
+// PatchTempl renders a component now and buffers the patch.
+func (b *Batch) PatchTempl(c templ.Component, opts ...datastar.PatchElementOption) *Batch {
+	if b.err != nil {
+		return b
+	}
+	var buf bytes.Buffer
+	if err := c.Render(b.ctx, &buf); err != nil {
+		b.err = err
+		return b
+	}
+	return b.PatchHTML(buf.String(), opts...)
+}
+
+// PatchTemplByID renders a component now and buffers a patch by ID.
+func (b *Batch) PatchTemplByID(id string, c templ.Component, opts ...datastar.PatchElementOption) *Batch {
+	return b.PatchTempl(c, append(opts, datastar.WithSelectorID(id))...)
+}
+
+// PatchSignals marshals signals now and buffers the patch.
+func (b *Batch) PatchSignals(signals any, opts ...datastar.PatchSignalsOption) *Batch {
+	if b.err != nil {
+		return b
+	}
+	data, err := json.Marshal(signals)
+	if err != nil {
+		b.err = err
+		return b
+	}
+	return b.add(func(s *SSE) error { return s.ServerSentEventGenerator.PatchSignals(data, opts...) })
+}
+
+// RemoveByID buffers removal of an element by ID.
+func (b *Batch) RemoveByID(id string) *Batch {
+	return b.add(func(s *SSE) error { return s.RemoveByID(id) })
+}
+
+// Err returns the first error from rendering or marshaling, if any.
+func (b *Batch) Err() error {
+	return b.err
+}
+
+// Len returns the number of buffered events.
+func (b *Batch) Len() int {
+	return len(b.events)
+}
+
+// Flush sends all buffered events in order. If any event failed to render
+// it returns that error without sending anything.
+func (b *Batch) Flush(s *SSE) error {
+	if b.err != nil {
+		return b.err
+	}
+	for _, send := range b.events {
+		if err := send(s); err != nil {
+			return err
+		}
+	}
+	b.events = nil
+	return nil
+}
+
+func (b *Batch) add(send func(*SSE) error) *Batch {
+	if b.err == nil {
+		b.events = append(b.events, send)
+	}
+	return b
+}
@@ -0,0 +1,186 @@
+package datastar
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sync"
+)
+
+// DefaultSignalDifferSize is how many keys a SignalDiffer tracks when
+// created with max <= 0.
+const DefaultSignalDifferSize = 1024
+
+// ErrSignalsNotObject is returned when signals don't marshal to a JSON
+// object.
+var ErrSignalsNotObject = errors.New("datastar: signals must marshal to a JSON object")
+
+// SignalDiffer remembers the signals last sent on each connection and
+// turns a full view-model into a JSON merge patch (RFC 7396) holding
+// only what changed. Changed objects are diffed field by field, arrays
+// and scalars are replaced whole, and removed fields are sent as null.
+//
+// Keys name connections, e.g. a session or tab ID. The least recently
+// used key is dropped past the size limit; its next Diff sends
+// everything again.
+type SignalDiffer struct {
+	mu      sync.Mutex
+	max     int
+	bytes   int64 // Encoded size of all tracked signals
+	entries map[string]*list.Element
+	lru     *list.List // Front is most recently used
+}
+
+type signalEntry struct {
+	key     string
+	signals map[string]any
+	size    int64
+}
+
+// NewSignalDiffer creates a SignalDiffer tracking at most max keys;
+// max <= 0 means DefaultSignalDifferSize.
+func NewSignalDiffer(max int) *SignalDiffer {
+	if max <= 0 {
+		max = DefaultSignalDifferSize
+	}
+	return &SignalDiffer{max: max, entries: make(map[string]*list.Element), lru: list.New()}
+}
+
+// Diff returns the merge patch from the signals last recorded for key
+// to signals, and records signals as sent. The first Diff for a key, or
+// the first after Resync, returns all of them. The patch is nil when
+// nothing changed.
+func (d *SignalDiffer) Diff(key string, signals any) ([]byte, error) {
+	next, size, err := decodeSignals(signals)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var patch map[string]any
+	if el, ok := d.entries[key]; ok {
+		entry := el.Value.(*signalEntry)
+		patch = mergePatch(entry.signals, next)
+		d.bytes += size - entry.size
+		entry.signals, entry.size = next, size
+		d.lru.MoveToFront(el)
+		if len(patch) == 0 {
+			return nil, nil
+		}
+	} else {
+		patch = next
+		d.entries[key] = d.lru.PushFront(&signalEntry{key: key, signals: next, size: size})
+		d.bytes += size
+		for d.lru.Len() > d.max {
+			d.remove(d.lru.Back())
+		}
+	}
+	return json.Marshal(patch)
+}
+
+// Resync forgets the signals recorded for key, so the next Diff sends
+// all of them. Call it when the client's state is unknown: after a
+// reconnect, a failed send or a full page render.
+func (d *SignalDiffer) Resync(key string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if el, ok := d.entries[key]; ok {
+		d.remove(el)
+	}
+}
+
+// Bytes returns the approximate memory held, as the encoded size of the
+// tracked signals. For use with memory.Budget.
+func (d *SignalDiffer) Bytes() int64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.bytes
+}
+
+// Shrink drops least recently used keys until at most target bytes are
+// held. Dropped keys resync on their next Diff. For use with
+// memory.Budget.
+func (d *SignalDiffer) Shrink(target int64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for d.bytes > target && d.lru.Len() > 0 {
+		d.remove(d.lru.Back())
+	}
+}
+
+func (d *SignalDiffer) remove(el *list.Element) {
+	entry := el.Value.(*signalEntry)
+	d.lru.Remove(el)
+	delete(d.entries, entry.key)
+	d.bytes -= entry.size
+}
+
+// Len returns the number of keys tracked.
+func (d *SignalDiffer) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.lru.Len()
+}
+
+// decodeSignals round-trips signals through JSON so structs, maps and
+// raw JSON compare alike, also returning the encoded size. Numbers stay
+// json.Number to keep their exact text.
+func decodeSignals(signals any) (map[string]any, int64, error) {
+	var data []byte
+	switch v := signals.(type) {
+	case []byte:
+		data = v
+	case json.RawMessage:
+		data = v
+	default:
+		var err error
+		if data, err = json.Marshal(signals); err != nil {
+			return nil, 0, err
+		}
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var decoded any
+	if err := dec.Decode(&decoded); err != nil {
+		return nil, 0, err
+	}
+	m, ok := decoded.(map[string]any)
+	if !ok {
+		return nil, 0, ErrSignalsNotObject
+	}
+	return m, int64(len(data)), nil
+}
+
+// mergePatch returns the RFC 7396 merge patch turning prev into next.
+func mergePatch(prev, next map[string]any) map[string]any {
+	patch := make(map[string]any)
+	for k := range prev {
+		if _, ok := next[k]; !ok {
+			patch[k] = nil
+		}
+	}
+	for k, nv := range next {
+		pv, ok := prev[k]
+		if !ok {
+			if nv != nil {
+				patch[k] = nv
+			}
+			continue
+		}
+		pm, pIsObj := pv.(map[string]any)
+		nm, nIsObj := nv.(map[string]any)
+		switch {
+		case pIsObj && nIsObj:
+			if sub := mergePatch(pm, nm); len(sub) > 0 {
+				patch[k] = sub
+			}
+		case !reflect.DeepEqual(pv, nv):
+			patch[k] = nv
+		}
+	}
+	return patch
+}
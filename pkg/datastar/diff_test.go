@@ -0,0 +1,205 @@
+package datastar
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type viewModel struct {
+	Title string         `json:"title"`
+	User  map[string]any `json:"user,omitempty"`
+	Tags  []string       `json:"tags"`
+	Count int            `json:"count"`
+}
+
+func decode(t *testing.T, data []byte) map[string]any {
+	t.Helper()
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatalf("decoding %s: %v", data, err)
+	}
+	return m
+}
+
+func TestSignalDifferFirstSendsAll(t *testing.T) {
+	d := NewSignalDiffer(0)
+	patch, err := d.Diff("tab", viewModel{Title: "Todos", Tags: []string{"a"}, Count: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{"title": "Todos", "tags": []any{"a"}, "count": float64(1)}
+	if got := decode(t, patch); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestSignalDifferUnchanged(t *testing.T) {
+	d := NewSignalDiffer(0)
+	vm := viewModel{Title: "Todos", Count: 1}
+	d.Diff("tab", vm)
+	patch, err := d.Diff("tab", vm)
+	if err != nil || patch != nil {
+		t.Errorf("expected no patch, got %s, %v", patch, err)
+	}
+}
+
+func TestSignalDifferNested(t *testing.T) {
+	d := NewSignalDiffer(0)
+	d.Diff("tab", map[string]any{
+		"user":  map[string]any{"name": "Ada", "prefs": map[string]any{"theme": "dark", "lang": "en"}},
+		"count": 1,
+	})
+	patch, _ := d.Diff("tab", map[string]any{
+		"user":  map[string]any{"name": "Ada", "prefs": map[string]any{"theme": "light", "lang": "en"}},
+		"count": 1,
+	})
+	want := map[string]any{"user": map[string]any{"prefs": map[string]any{"theme": "light"}}}
+	if got := decode(t, patch); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestSignalDifferArraysReplaced(t *testing.T) {
+	d := NewSignalDiffer(0)
+	d.Diff("tab", map[string]any{"tags": []string{"a", "b"}, "count": 2})
+	patch, _ := d.Diff("tab", map[string]any{"tags": []string{"a", "c"}, "count": 2})
+	want := map[string]any{"tags": []any{"a", "c"}}
+	if got := decode(t, patch); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestSignalDifferRemoval(t *testing.T) {
+	d := NewSignalDiffer(0)
+	d.Diff("tab", map[string]any{"error": "required", "user": map[string]any{"name": "Ada", "tmp": 1}})
+	patch, _ := d.Diff("tab", map[string]any{"user": map[string]any{"name": "Ada"}})
+	want := map[string]any{"error": nil, "user": map[string]any{"tmp": nil}}
+	if got := decode(t, patch); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestSignalDifferResync(t *testing.T) {
+	d := NewSignalDiffer(0)
+	d.Diff("tab", map[string]any{"a": 1, "b": 2})
+	d.Resync("tab")
+	patch, _ := d.Diff("tab", map[string]any{"a": 1, "b": 2})
+	if got := decode(t, patch); len(got) != 2 {
+		t.Errorf("expected full resync, got %v", got)
+	}
+}
+
+func TestSignalDifferBounded(t *testing.T) {
+	d := NewSignalDiffer(2)
+	d.Diff("a", map[string]any{"x": 1})
+	d.Diff("b", map[string]any{"x": 1})
+	d.Diff("a", map[string]any{"x": 1}) // a is now most recent
+	d.Diff("c", map[string]any{"x": 1}) // evicts b
+
+	if d.Len() != 2 {
+		t.Errorf("expected 2 keys, got %d", d.Len())
+	}
+	if patch, _ := d.Diff("a", map[string]any{"x": 1}); patch != nil {
+		t.Errorf("a should still be tracked, got %s", patch)
+	}
+	if patch, _ := d.Diff("b", map[string]any{"x": 1}); patch == nil {
+		t.Error("b should have been evicted and resent")
+	}
+}
+
+func TestSignalDifferNotObject(t *testing.T) {
+	d := NewSignalDiffer(0)
+	if _, err := d.Diff("tab", []int{1}); err != ErrSignalsNotObject {
+		t.Errorf("expected ErrSignalsNotObject, got %v", err)
+	}
+}
+
+func TestPatchSignalsDiff(t *testing.T) {
+	d := NewSignalDiffer(0)
+	send := func(lastEventID string, signals any) string {
+		req := httptest.NewRequest("GET", "/", nil)
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		w := httptest.NewRecorder()
+		sse := NewSSE(w, req)
+		sse.UseSignalDiffer(d, "tab-1")
+		if err := sse.PatchSignalsDiff(signals); err != nil {
+			t.Fatal(err)
+		}
+		return w.Body.String()
+	}
+
+	body := send("", map[string]any{"count": 1, "title": "Todos"})
+	if !strings.Contains(body, `"count":1`) || !strings.Contains(body, `"title":"Todos"`) {
+		t.Errorf("first request should send all signals:\n%s", body)
+	}
+
+	body = send("", map[string]any{"count": 2, "title": "Todos"})
+	if !strings.Contains(body, `{"count":2}`) {
+		t.Errorf("expected only count:\n%s", body)
+	}
+
+	body = send("", map[string]any{"count": 2, "title": "Todos"})
+	if strings.Contains(body, "datastar-patch-signals") {
+		t.Errorf("expected nothing sent:\n%s", body)
+	}
+
+	// A reconnecting stream may have missed patches
+	body = send("42", map[string]any{"count": 2, "title": "Todos"})
+	if !strings.Contains(body, `"count":2`) || !strings.Contains(body, `"title":"Todos"`) {
+		t.Errorf("reconnect should resync all signals:\n%s", body)
+	}
+}
+
+func TestPatchSignalsDiffWithinStream(t *testing.T) {
+	w := httptest.NewRecorder()
+	sse := NewSSE(w, httptest.NewRequest("GET", "/", nil))
+	sse.PatchSignalsDiff(map[string]any{"a": 1, "b": 1})
+	sse.PatchSignalsDiff(map[string]any{"a": 1, "b": 2})
+	sse.ResyncSignals()
+	sse.PatchSignalsDiff(map[string]any{"a": 1, "b": 2})
+
+	body := w.Body.String()
+	if n := strings.Count(body, "event: datastar-patch-signals"); n != 3 {
+		t.Errorf("expected 3 patches, got %d:\n%s", n, body)
+	}
+	if !strings.Contains(body, `{"b":2}`) {
+		t.Errorf("expected diff patch:\n%s", body)
+	}
+	if w.Code != http.StatusOK {
+		t.Errorf("unexpected status %d", w.Code)
+	}
+}
+
+func TestSignalDifferShrink(t *testing.T) {
+	d := NewSignalDiffer(0)
+	d.Diff("a", map[string]any{"x": 1}) // {"x":1} is 7 bytes
+	d.Diff("b", map[string]any{"x": 2})
+	d.Diff("c", map[string]any{"x": 3})
+	if d.Bytes() != 21 {
+		t.Fatalf("expected 21 bytes, got %d", d.Bytes())
+	}
+
+	d.Diff("a", map[string]any{"x": 10}) // a is now most recent, 8 bytes
+	if d.Bytes() != 22 {
+		t.Fatalf("expected 22 bytes, got %d", d.Bytes())
+	}
+
+	d.Shrink(10)
+	if d.Len() != 1 || d.Bytes() != 8 {
+		t.Errorf("expected only a left, got %d keys, %d bytes", d.Len(), d.Bytes())
+	}
+	if patch, _ := d.Diff("a", map[string]any{"x": 10}); patch != nil {
+		t.Errorf("a should still be tracked, got %s", patch)
+	}
+
+	d.Resync("a")
+	if d.Bytes() != 0 {
+		t.Errorf("expected 0 bytes after resync, got %d", d.Bytes())
+	}
+}
@@ -14,6 +14,10 @@ import (
 // SSE wraps the datastar ServerSentEventGenerator with additional convenience methods.
 type SSE struct {
 	*datastar.ServerSentEventGenerator
+
+	reconnect bool          // The request carried Last-Event-ID
+	differ    *SignalDiffer // Set by UseSignalDiffer or on first PatchSignalsDiff
+	diffKey   string
 }
 
 // NewSSE creates a new SSE writer for streaming responses to the client.
@@ -21,6 +25,7 @@ type SSE struct {
 func NewSSE(w http.ResponseWriter, r *http.Request, opts ...datastar.SSEOption) *SSE {
 	return &SSE{
 		ServerSentEventGenerator: datastar.NewSSE(w, r, opts...),
+		reconnect:                r.Header.Get("Last-Event-ID") != "",
 	}
 }
 
@@ -87,6 +92,43 @@ func (s *SSE) PatchSignals(signals any, opts ...datastar.PatchSignalsOption) err
 	return s.ServerSentEventGenerator.MarshalAndPatchSignals(signals, opts...)
 }
 
+// UseSignalDiffer makes PatchSignalsDiff diff against the signals last
+// sent under key by any request, e.g. a tab ID shared by a page's
+// actions. A reconnecting stream (one sending Last-Event-ID) may have
+// missed patches, so its key is resynced.
+func (s *SSE) UseSignalDiffer(d *SignalDiffer, key string) {
+	s.differ, s.diffKey = d, key
+	if s.reconnect {
+		d.Resync(key)
+	}
+}
+
+// PatchSignalsDiff sends only the signals that changed since the last
+// PatchSignalsDiff, as a merge patch with removed fields set to null.
+// Without UseSignalDiffer it diffs within this stream, so the first
+// call sends everything. Nothing is sent when nothing changed.
+func (s *SSE) PatchSignalsDiff(signals any, opts ...datastar.PatchSignalsOption) error {
+	if s.differ == nil {
+		s.differ = NewSignalDiffer(1)
+	}
+	patch, err := s.differ.Diff(s.diffKey, signals)
+	if err != nil || patch == nil {
+		return err
+	}
+	if err := s.ServerSentEventGenerator.PatchSignals(patch, opts...); err != nil {
+		s.differ.Resync(s.diffKey)
+		return err
+	}
+	return nil
+}
+
+// ResyncSignals makes the next PatchSignalsDiff send every signal.
+func (s *SSE) ResyncSignals() {
+	if s.differ != nil {
+		s.differ.Resync(s.diffKey)
+	}
+}
+
 // PatchSignalsIfMissing updates signals only if they don't already exist on the client.
 func (s *SSE) PatchSignalsIfMissing(signals any, opts ...datastar.PatchSignalsOption) error {
 	return s.ServerSentEventGenerator.MarshalAndPatchSignalsIfMissing(signals, opts...)
@@ -0,0 +1,69 @@
+// Package events provides a small in-process publish/subscribe bus for
+// decoupling application features, e.g. plugins reacting to each other.
+//
+// Example:
+//
+//	bus := events.NewBus()
+//	unsubscribe := bus.Subscribe("user.login", func(topic string, payload any) {
+//	    log.Printf("login: %v", payload)
+//	})
+//	defer unsubscribe()
+//	bus.Publish("user.login", userID)
+package events
+
+import "sync"
+
+// Handler receives a published event.
+type Handler func(topic string, payload any)
+
+// Bus delivers events to subscribers synchronously, in subscription order.
+// It is safe for concurrent use.
+type Bus struct {
+	mu   sync.RWMutex
+	subs map[string][]*subscription
+}
+
+type subscription struct {
+	handler Handler
+}
+
+// NewBus creates an empty Bus.
+func NewBus() *Bus {
+	return &Bus{subs: make(map[string][]*subscription)}
+}
+
+// Subscribe registers h for topic. The "*" topic receives every event.
+// The returned function removes the subscription.
+func (b *Bus) Subscribe(topic string, h Handler) (unsubscribe func()) {
+	sub := &subscription{handler: h}
+	b.mu.Lock()
+	b.subs[topic] = append(b.subs[topic], sub)
+	b.mu.Unlock()
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		subs := b.subs[topic]
+		for i, s := range subs {
+			if s == sub {
+				b.subs[topic] = append(subs[:i:i], subs[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// Publish delivers payload to the subscribers of topic, then to "*"
+// subscribers. Handlers run on the caller's goroutine.
+func (b *Bus) Publish(topic string, payload any) {
+	b.mu.RLock()
+	subs := append([]*subscription(nil), b.subs[topic]...)
+	if topic != "*" {
+		subs = append(subs, b.subs["*"]...)
+	}
+	b.mu.RUnlock()
+
+	for _, s := range subs {
+		s.handler(topic, payload)
+	}
+}
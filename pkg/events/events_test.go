@@ -0,0 +1,30 @@
+package events
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBus(t *testing.T) {
+	bus := NewBus()
+	var got []string
+	record := func(name string) Handler {
+		return func(topic string, payload any) {
+			got = append(got, name+":"+topic+":"+payload.(string))
+		}
+	}
+
+	unsubscribe := bus.Subscribe("login", record("a"))
+	bus.Subscribe("login", record("b"))
+	bus.Subscribe("*", record("all"))
+	bus.Subscribe("logout", record("c"))
+
+	bus.Publish("login", "ada")
+	unsubscribe()
+	bus.Publish("login", "bob")
+
+	want := []string{"a:login:ada", "b:login:ada", "all:login:ada", "b:login:bob", "all:login:bob"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
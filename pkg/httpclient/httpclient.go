@@ -0,0 +1,328 @@
+// Package httpclient provides an outbound HTTP client for handlers that call
+// external APIs. It adds context-aware retries, offline short-circuiting on
+// mobile, per-request timeouts and logging hooks on top of net/http.
+//
+// Each attempt is recorded as an "http" Server-Timing metric on the request
+// context's servertiming.Timing, which router.ServerTimingMiddleware
+// provides, so pass the handler's context to outbound requests.
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stukennedy/irgo/pkg/servertiming"
+)
+
+var (
+	// ErrOffline is returned when the device reports no network connectivity.
+	// Requests are not attempted while offline.
+	ErrOffline = errors.New("network offline")
+)
+
+// online tracks device reachability. The mobile bridge updates it from
+// native reachability callbacks; desktop and web builds are always online.
+var online atomic.Bool
+
+func init() {
+	online.Store(true)
+}
+
+// SetOnline records whether the device currently has network connectivity.
+func SetOnline(v bool) {
+	online.Store(v)
+}
+
+// IsOnline returns true if the device currently has network connectivity.
+func IsOnline() bool {
+	return online.Load()
+}
+
+// Config holds client configuration.
+type Config struct {
+	// Timeout is the default per-request timeout applied when the request
+	// context has no deadline (default: 30s).
+	Timeout time.Duration
+
+	// MaxRetries is the number of retries after the first attempt.
+	// DefaultConfig uses 2; a zero Config disables retries.
+	MaxRetries int
+
+	// BaseDelay is the initial backoff delay, doubled after each retry (default: 200ms).
+	BaseDelay time.Duration
+
+	// MaxDelay caps the backoff delay (default: 5s).
+	MaxDelay time.Duration
+
+	// RetryAll enables retries for non-idempotent methods (POST, PATCH).
+	RetryAll bool
+
+	// Transport is the underlying RoundTripper (default: http.DefaultTransport).
+	Transport http.RoundTripper
+
+	// OnRequest is called before each attempt.
+	OnRequest func(req *http.Request, attempt int)
+
+	// OnResponse is called after each attempt with the response or error
+	// and the time the attempt took. LogResponse returns one that logs
+	// through slog.
+	OnResponse func(req *http.Request, resp *http.Response, err error, elapsed time.Duration)
+}
+
+// DefaultConfig returns a Config with sensible defaults.
+func DefaultConfig() Config {
+	return Config{
+		Timeout:    30 * time.Second,
+		MaxRetries: 2,
+		BaseDelay:  200 * time.Millisecond,
+		MaxDelay:   5 * time.Second,
+	}
+}
+
+// Client wraps http.Client with retries and offline awareness.
+type Client struct {
+	config Config
+	http   *http.Client
+
+	// after is the clock used for backoff delays. Tests replace it.
+	after func(d time.Duration) <-chan time.Time
+}
+
+// New creates a new Client. Zero durations and a nil Transport in cfg are
+// replaced by defaults.
+func New(cfg Config) *Client {
+	def := DefaultConfig()
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = def.Timeout
+	}
+	if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 0
+	}
+	if cfg.BaseDelay <= 0 {
+		cfg.BaseDelay = def.BaseDelay
+	}
+	if cfg.MaxDelay <= 0 {
+		cfg.MaxDelay = def.MaxDelay
+	}
+	if cfg.Transport == nil {
+		cfg.Transport = http.DefaultTransport
+	}
+
+	return &Client{
+		config: cfg,
+		http:   &http.Client{Transport: cfg.Transport},
+		after:  time.After,
+	}
+}
+
+// HTTPClient returns the underlying http.Client (without retries).
+func (c *Client) HTTPClient() *http.Client {
+	return c.http
+}
+
+// Do sends the request, retrying transient failures with exponential backoff.
+// Only idempotent methods are retried unless Config.RetryAll is set.
+// Returns ErrOffline without sending if the device is offline.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	if !IsOnline() {
+		return nil, ErrOffline
+	}
+
+	ctx := req.Context()
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.config.Timeout)
+		// The response body may outlive Do, so cancel when it is closed.
+		resp, err := c.do(req.WithContext(ctx))
+		if err != nil || resp == nil {
+			cancel()
+			return resp, err
+		}
+		resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+		return resp, nil
+	}
+	return c.do(req)
+}
+
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	// Buffer the body so it can be replayed on retry
+	var body []byte
+	if req.Body != nil && req.GetBody == nil {
+		b, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		body = b
+	}
+
+	retries := 0
+	if c.config.RetryAll || isIdempotent(req.Method) {
+		retries = c.config.MaxRetries
+	}
+
+	var (
+		resp *http.Response
+		err  error
+	)
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-req.Context().Done():
+				return nil, req.Context().Err()
+			case <-c.after(c.backoff(attempt)):
+			}
+			if !IsOnline() {
+				return nil, ErrOffline
+			}
+		}
+
+		r := req
+		if body != nil {
+			r = req.Clone(req.Context())
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		} else if req.GetBody != nil && attempt > 0 {
+			r = req.Clone(req.Context())
+			if r.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+
+		if c.config.OnRequest != nil {
+			c.config.OnRequest(r, attempt)
+		}
+		start := time.Now()
+		resp, err = c.http.Do(r)
+		elapsed := time.Since(start)
+		servertiming.FromContext(r.Context()).Add("http", elapsed, describe(r, resp, err))
+		if c.config.OnResponse != nil {
+			c.config.OnResponse(r, resp, err, elapsed)
+		}
+
+		if !shouldRetry(resp, err) || attempt == retries {
+			break
+		}
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+	}
+	return resp, err
+}
+
+// LogResponse returns an OnResponse hook that logs one record per attempt
+// with its method, URL, status, duration and the chi request ID of the
+// handler that made it, like router.LoggerMiddleware. Failed attempts and
+// server errors log at Error, client errors at Warn, the rest at Info. A
+// nil logger uses slog.Default().
+func LogResponse(logger *slog.Logger) func(req *http.Request, resp *http.Response, err error, elapsed time.Duration) {
+	return func(req *http.Request, resp *http.Response, err error, elapsed time.Duration) {
+		level := slog.LevelInfo
+		attrs := []slog.Attr{
+			slog.String("method", req.Method),
+			slog.String("url", req.URL.Redacted()),
+		}
+		switch {
+		case err != nil:
+			level = slog.LevelError
+			attrs = append(attrs, slog.String("error", err.Error()))
+		case resp.StatusCode >= 500:
+			level = slog.LevelError
+		case resp.StatusCode >= 400:
+			level = slog.LevelWarn
+		}
+		if resp != nil {
+			attrs = append(attrs, slog.Int("status", resp.StatusCode))
+		}
+		attrs = append(attrs,
+			slog.Duration("duration", elapsed),
+			slog.String("request_id", middleware.GetReqID(req.Context())),
+		)
+
+		l := logger
+		if l == nil {
+			l = slog.Default()
+		}
+		l.LogAttrs(req.Context(), level, "outbound request", attrs...)
+	}
+}
+
+// describe summarises an attempt for its Server-Timing metric, e.g.
+// "GET api.example.com 200".
+func describe(req *http.Request, resp *http.Response, err error) string {
+	result := "error"
+	if err == nil {
+		result = strconv.Itoa(resp.StatusCode)
+	}
+	return req.Method + " " + req.URL.Host + " " + result
+}
+
+// Get issues a GET request to the given URL.
+func (c *Client) Get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(req)
+}
+
+// Post issues a POST request with the given content type and body.
+func (c *Client) Post(ctx context.Context, url, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	return c.Do(req)
+}
+
+// backoff returns the delay before the given retry attempt (1-based).
+func (c *Client) backoff(attempt int) time.Duration {
+	d := c.config.BaseDelay << (attempt - 1)
+	if d <= 0 || d > c.config.MaxDelay {
+		d = c.config.MaxDelay
+	}
+	return d
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions,
+		http.MethodPut, http.MethodDelete, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		// Context cancellation is final
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// cancelBody cancels the request context when the body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,222 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stukennedy/irgo/pkg/servertiming"
+)
+
+// fakeClock records requested delays and fires immediately.
+type fakeClock struct {
+	delays []time.Duration
+}
+
+func (f *fakeClock) after(d time.Duration) <-chan time.Time {
+	f.delays = append(f.delays, d)
+	ch := make(chan time.Time, 1)
+	ch <- time.Time{}
+	return ch
+}
+
+func newTestClient(cfg Config, stub *StubTransport) (*Client, *fakeClock) {
+	cfg.Transport = stub
+	c := New(cfg)
+	clock := &fakeClock{}
+	c.after = clock.after
+	return c, clock
+}
+
+func TestRetryBackoff(t *testing.T) {
+	stub := NewStubTransport().
+		On("GET", "http://api.test/items", 503, "").
+		On("GET", "http://api.test/items", 503, "").
+		On("GET", "http://api.test/items", 200, "ok")
+
+	c, clock := newTestClient(Config{MaxRetries: 3, BaseDelay: 100 * time.Millisecond}, stub)
+
+	resp, err := c.Get(context.Background(), "http://api.test/items")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		t.Errorf("expected status 200, got %d", resp.StatusCode)
+	}
+	if len(stub.Requests()) != 3 {
+		t.Errorf("expected 3 attempts, got %d", len(stub.Requests()))
+	}
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond}
+	if len(clock.delays) != len(want) {
+		t.Fatalf("expected delays %v, got %v", want, clock.delays)
+	}
+	for i, d := range want {
+		if clock.delays[i] != d {
+			t.Errorf("delay %d: expected %v, got %v", i, d, clock.delays[i])
+		}
+	}
+}
+
+func TestRetryMaxDelay(t *testing.T) {
+	c := New(Config{BaseDelay: time.Second, MaxDelay: 3 * time.Second})
+	if d := c.backoff(3); d != 3*time.Second {
+		t.Errorf("expected backoff capped at 3s, got %v", d)
+	}
+}
+
+func TestNoRetryForPost(t *testing.T) {
+	stub := NewStubTransport().
+		On("POST", "http://api.test/items", 503, "").
+		On("POST", "http://api.test/items", 200, "ok")
+
+	c, _ := newTestClient(Config{MaxRetries: 3}, stub)
+
+	resp, err := c.Post(context.Background(), "http://api.test/items", "text/plain", strings.NewReader("x"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != 503 {
+		t.Errorf("expected status 503, got %d", resp.StatusCode)
+	}
+	if len(stub.Requests()) != 1 {
+		t.Errorf("expected 1 attempt, got %d", len(stub.Requests()))
+	}
+}
+
+func TestRetryAllReplaysBody(t *testing.T) {
+	stub := NewStubTransport().
+		OnError("POST", "http://api.test/items", errors.New("connection reset")).
+		On("POST", "http://api.test/items", 201, "created")
+
+	c, _ := newTestClient(Config{MaxRetries: 1, RetryAll: true}, stub)
+
+	resp, err := c.Post(context.Background(), "http://api.test/items", "text/plain", strings.NewReader("payload"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+
+	reqs := stub.Requests()
+	if len(reqs) != 2 {
+		t.Fatalf("expected 2 attempts, got %d", len(reqs))
+	}
+	body, _ := io.ReadAll(reqs[1].Body)
+	if string(body) != "payload" {
+		t.Errorf("expected replayed body 'payload', got %q", body)
+	}
+}
+
+func TestOfflineShortCircuit(t *testing.T) {
+	SetOnline(false)
+	defer SetOnline(true)
+
+	stub := NewStubTransport().On("GET", "http://api.test/items", 200, "ok")
+	c, _ := newTestClient(Config{}, stub)
+
+	_, err := c.Get(context.Background(), "http://api.test/items")
+	if !errors.Is(err, ErrOffline) {
+		t.Errorf("expected ErrOffline, got %v", err)
+	}
+	if len(stub.Requests()) != 0 {
+		t.Errorf("expected no requests while offline, got %d", len(stub.Requests()))
+	}
+}
+
+func TestStubTransportMissingRoute(t *testing.T) {
+	c, _ := newTestClient(Config{MaxRetries: 0}, NewStubTransport())
+
+	_, err := c.Get(context.Background(), "http://api.test/missing")
+	if err == nil || !strings.Contains(err.Error(), "no stub") {
+		t.Errorf("expected missing stub error, got %v", err)
+	}
+}
+
+func TestHooks(t *testing.T) {
+	stub := NewStubTransport().On("GET", "http://api.test/items", 200, "ok")
+
+	var attempts []int
+	var statuses []int
+	c, _ := newTestClient(Config{
+		OnRequest: func(_ *http.Request, attempt int) {
+			attempts = append(attempts, attempt)
+		},
+		OnResponse: func(_ *http.Request, resp *http.Response, _ error, _ time.Duration) {
+			statuses = append(statuses, resp.StatusCode)
+		},
+	}, stub)
+
+	resp, err := c.Get(context.Background(), "http://api.test/items")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+
+	if len(attempts) != 1 || attempts[0] != 0 {
+		t.Errorf("expected one attempt hook, got %v", attempts)
+	}
+	if len(statuses) != 1 || statuses[0] != 200 {
+		t.Errorf("expected one response hook with 200, got %v", statuses)
+	}
+}
+
+func TestServerTimingSpans(t *testing.T) {
+	stub := NewStubTransport().
+		On("GET", "http://api.test/items", 503, "").
+		On("GET", "http://api.test/items", 200, "ok")
+	c, _ := newTestClient(Config{MaxRetries: 1}, stub)
+
+	timing := &servertiming.Timing{}
+	resp, err := c.Get(servertiming.WithContext(context.Background(), timing), "http://api.test/items")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+
+	metrics := timing.Metrics()
+	if len(metrics) != 2 {
+		t.Fatalf("expected a span per attempt, got %v", metrics)
+	}
+	for i, want := range []string{"GET api.test 503", "GET api.test 200"} {
+		if metrics[i].Name != "http" || metrics[i].Desc != want {
+			t.Errorf("span %d: expected http %q, got %s %q", i, want, metrics[i].Name, metrics[i].Desc)
+		}
+	}
+}
+
+func TestLogResponse(t *testing.T) {
+	stub := NewStubTransport().
+		On("GET", "http://api.test/items", 200, "ok").
+		On("GET", "http://api.test/missing", 404, "")
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	c, _ := newTestClient(Config{OnResponse: LogResponse(logger)}, stub)
+
+	for _, url := range []string{"http://api.test/items", "http://api.test/missing"} {
+		resp, err := c.Get(context.Background(), url)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		`level=INFO msg="outbound request" method=GET url=http://api.test/items status=200`,
+		`level=WARN msg="outbound request" method=GET url=http://api.test/missing status=404`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected log to contain %q, got:\n%s", want, out)
+		}
+	}
+}
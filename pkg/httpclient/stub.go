@@ -0,0 +1,90 @@
+package httpclient
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// StubTransport is an http.RoundTripper that returns canned responses.
+// Use it as Config.Transport to stub external APIs in handler tests.
+//
+// Example:
+//
+//	stub := httpclient.NewStubTransport()
+//	stub.On("GET", "https://api.example.com/users", 200, `[{"id":1}]`)
+//	client := httpclient.New(httpclient.Config{Transport: stub})
+type StubTransport struct {
+	routes   map[string][]stubResponse
+	requests []*http.Request
+	mu       sync.Mutex
+}
+
+type stubResponse struct {
+	status int
+	body   string
+	err    error
+}
+
+// NewStubTransport creates an empty stub transport.
+func NewStubTransport() *StubTransport {
+	return &StubTransport{
+		routes: make(map[string][]stubResponse),
+	}
+}
+
+// On queues a response for method and URL. Multiple responses for the same
+// route are returned in order; the last one repeats once the queue drains.
+func (s *StubTransport) On(method, url string, status int, body string) *StubTransport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := method + " " + url
+	s.routes[key] = append(s.routes[key], stubResponse{status: status, body: body})
+	return s
+}
+
+// OnError queues a transport error for method and URL.
+func (s *StubTransport) OnError(method, url string, err error) *StubTransport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := method + " " + url
+	s.routes[key] = append(s.routes[key], stubResponse{err: err})
+	return s
+}
+
+// Requests returns the requests received so far.
+func (s *StubTransport) Requests() []*http.Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*http.Request(nil), s.requests...)
+}
+
+// RoundTrip implements http.RoundTripper.
+func (s *StubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	key := req.Method + " " + req.URL.String()
+	queue := s.routes[key]
+	if len(queue) == 0 {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("httpclient: no stub for %s", key)
+	}
+	r := queue[0]
+	if len(queue) > 1 {
+		s.routes[key] = queue[1:]
+	}
+	s.mu.Unlock()
+
+	if r.err != nil {
+		return nil, r.err
+	}
+	return &http.Response{
+		StatusCode: r.status,
+		Status:     fmt.Sprintf("%d %s", r.status, http.StatusText(r.status)),
+		Header:     make(http.Header),
+		Body:       io.NopCloser(strings.NewReader(r.body)),
+		Request:    req,
+	}, nil
+}
@@ -0,0 +1,375 @@
+// Package i18n is a small translation catalog for localized apps.
+//
+// A Bundle holds messages per locale, loaded from JSON or TOML files such
+// as locales/fr.json:
+//
+//	{
+//	  "greeting": "Bonjour %s",
+//	  "todos": {"one": "%d tâche", "other": "%d tâches"}
+//	}
+//
+// Nested objects (or TOML tables) become dotted keys ("nav.home"). A key
+// with "one" and "other" entries is plural: the form is picked by the
+// first argument passed to T. Missing messages fall back to the parent
+// locale ("pt-BR" to "pt"), then the default locale, then the key itself.
+//
+// router.I18nMiddleware picks the request's locale and stores a Localizer
+// in its context for router.Context.T and the t template function.
+package i18n
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Message is a translation. Singular messages only set Other.
+type Message struct {
+	One   string
+	Other string
+}
+
+// Bundle holds messages for a set of locales. It is safe for concurrent
+// use.
+type Bundle struct {
+	defaultLocale string
+
+	mu       sync.RWMutex
+	locales  map[string]string // Normalized tag → tag as added
+	messages map[string]map[string]Message
+}
+
+// NewBundle returns an empty bundle falling back to defaultLocale, e.g. "en".
+func NewBundle(defaultLocale string) *Bundle {
+	b := &Bundle{
+		defaultLocale: defaultLocale,
+		locales:       make(map[string]string),
+		messages:      make(map[string]map[string]Message),
+	}
+	b.locales[normalize(defaultLocale)] = defaultLocale
+	return b
+}
+
+// DefaultLocale returns the locale used when no other matches.
+func (b *Bundle) DefaultLocale() string {
+	return b.defaultLocale
+}
+
+// Locales returns the bundle's locales, sorted.
+func (b *Bundle) Locales() []string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	out := make([]string, 0, len(b.locales))
+	for _, name := range b.locales {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Add merges messages into locale. Keys ending in ".one" or ".other" set
+// the plural forms of the key without the suffix.
+func (b *Bundle) Add(locale string, messages map[string]string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	norm := normalize(locale)
+	if _, ok := b.locales[norm]; !ok {
+		b.locales[norm] = locale
+	}
+	catalog := b.messages[norm]
+	if catalog == nil {
+		catalog = make(map[string]Message)
+		b.messages[norm] = catalog
+	}
+	for key, text := range messages {
+		switch {
+		case strings.HasSuffix(key, ".one"):
+			key = strings.TrimSuffix(key, ".one")
+			m := catalog[key]
+			m.One = text
+			catalog[key] = m
+		case strings.HasSuffix(key, ".other"):
+			key = strings.TrimSuffix(key, ".other")
+			m := catalog[key]
+			m.Other = text
+			catalog[key] = m
+		default:
+			catalog[key] = Message{Other: text}
+		}
+	}
+}
+
+// LoadJSON adds the messages in a JSON object to locale.
+func (b *Bundle) LoadJSON(locale string, data []byte) error {
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("i18n: %s: %w", locale, err)
+	}
+	messages := make(map[string]string)
+	if err := flatten("", raw, messages); err != nil {
+		return fmt.Errorf("i18n: %s: %w", locale, err)
+	}
+	b.Add(locale, messages)
+	return nil
+}
+
+// LoadTOML adds the messages in a TOML document to locale. Only tables,
+// string values and # comments are supported.
+func (b *Bundle) LoadTOML(locale string, data []byte) error {
+	messages, err := parseTOML(locale, data)
+	if err != nil {
+		return err
+	}
+	b.Add(locale, messages)
+	return nil
+}
+
+// LoadFS loads every <locale>.json and <locale>.toml file in dir, e.g.
+// locales/en.json and locales/pt-BR.toml. Other files are ignored.
+func (b *Bundle) LoadFS(fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return fmt.Errorf("i18n: %w", err)
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		ext := path.Ext(e.Name())
+		if ext != ".json" && ext != ".toml" {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, path.Join(dir, e.Name()))
+		if err != nil {
+			return fmt.Errorf("i18n: %w", err)
+		}
+		locale := strings.TrimSuffix(e.Name(), ext)
+		if ext == ".json" {
+			err = b.LoadJSON(locale, data)
+		} else {
+			err = b.LoadTOML(locale, data)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Supported returns the bundle's name for locale, trying its parent
+// language if the exact locale isn't present ("en-GB" matches "en").
+func (b *Bundle) Supported(locale string) (string, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, tag := range parents(normalize(locale)) {
+		if name, ok := b.locales[tag]; ok {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// Match returns the best supported locale for an Accept-Language header,
+// or the default locale if none matches.
+func (b *Bundle) Match(acceptLanguage string) string {
+	for _, tag := range parseAcceptLanguage(acceptLanguage) {
+		if name, ok := b.Supported(tag); ok {
+			return name
+		}
+	}
+	return b.defaultLocale
+}
+
+// Translate returns the message for key in locale, formatted with args by
+// fmt.Sprintf. For plural messages the first argument is the count: 1
+// picks the one form, anything else the other form. Unknown keys return
+// the key itself.
+func (b *Bundle) Translate(locale, key string, args ...any) string {
+	m, ok := b.lookup(locale, key)
+	if !ok {
+		return key
+	}
+	text := m.Other
+	if m.One != "" && len(args) > 0 && isOne(args[0]) {
+		text = m.One
+	}
+	if len(args) == 0 || !strings.Contains(text, "%") {
+		return text
+	}
+	return fmt.Sprintf(text, args...)
+}
+
+// lookup finds key in locale, its parents, then the default locale.
+func (b *Bundle) lookup(locale, key string) (Message, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	tags := append(parents(normalize(locale)), normalize(b.defaultLocale))
+	for _, tag := range tags {
+		if m, ok := b.messages[tag][key]; ok {
+			return m, true
+		}
+	}
+	return Message{}, false
+}
+
+// Localizer translates messages into one locale.
+type Localizer struct {
+	bundle *Bundle
+	locale string
+}
+
+// Localizer returns a Localizer for locale.
+func (b *Bundle) Localizer(locale string) *Localizer {
+	return &Localizer{bundle: b, locale: locale}
+}
+
+// Locale returns the localizer's locale.
+func (l *Localizer) Locale() string {
+	return l.locale
+}
+
+// T translates key (see Bundle.Translate).
+func (l *Localizer) T(key string, args ...any) string {
+	return l.bundle.Translate(l.locale, key, args...)
+}
+
+type contextKey struct{}
+
+// WithContext returns a context carrying l.
+func WithContext(ctx context.Context, l *Localizer) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the localizer stored in ctx, or nil and false if
+// there is none.
+func FromContext(ctx context.Context) (*Localizer, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	l, ok := ctx.Value(contextKey{}).(*Localizer)
+	return l, ok
+}
+
+// T translates key with the localizer stored in ctx. Without one it
+// returns the key.
+func T(ctx context.Context, key string, args ...any) string {
+	l, ok := FromContext(ctx)
+	if !ok {
+		return key
+	}
+	return l.T(key, args...)
+}
+
+// flatten turns nested JSON objects into dotted keys.
+func flatten(prefix string, raw map[string]any, out map[string]string) error {
+	for k, v := range raw {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		switch v := v.(type) {
+		case string:
+			out[key] = v
+		case map[string]any:
+			if err := flatten(key, v, out); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("%s: message must be a string or object", key)
+		}
+	}
+	return nil
+}
+
+// isOne reports whether a plural count is exactly 1.
+func isOne(n any) bool {
+	switch n := n.(type) {
+	case int:
+		return n == 1
+	case int8:
+		return n == 1
+	case int16:
+		return n == 1
+	case int32:
+		return n == 1
+	case int64:
+		return n == 1
+	case uint:
+		return n == 1
+	case uint8:
+		return n == 1
+	case uint16:
+		return n == 1
+	case uint32:
+		return n == 1
+	case uint64:
+		return n == 1
+	case float32:
+		return n == 1
+	case float64:
+		return n == 1
+	}
+	return false
+}
+
+// normalize lowercases a language tag and uses hyphens: "pt_BR" → "pt-br".
+func normalize(tag string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+}
+
+// parents returns tag and its less specific forms: "zh-hant-tw",
+// "zh-hant", "zh".
+func parents(tag string) []string {
+	var out []string
+	for tag != "" {
+		out = append(out, tag)
+		i := strings.LastIndex(tag, "-")
+		if i < 0 {
+			break
+		}
+		tag = tag[:i]
+	}
+	return out
+}
+
+// parseAcceptLanguage returns the tags in an Accept-Language header by
+// descending quality, skipping "*" and q=0.
+func parseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			n, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = n
+		}
+		if q <= 0 {
+			continue
+		}
+		tags = append(tags, weighted{tag, q})
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+	out := make([]string, len(tags))
+	for i, t := range tags {
+		out[i] = t.tag
+	}
+	return out
+}
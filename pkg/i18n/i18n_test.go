@@ -0,0 +1,130 @@
+package i18n
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+)
+
+func testBundle(t *testing.T) *Bundle {
+	t.Helper()
+	b := NewBundle("en")
+	err := b.LoadFS(fstest.MapFS{
+		"locales/en.json": {Data: []byte(`{
+			"greeting": "Hello %s",
+			"farewell": "Goodbye",
+			"todos": {"one": "%d todo", "other": "%d todos"},
+			"nav": {"home": "Home"}
+		}`)},
+		"locales/fr.toml": {Data: []byte(`
+greeting = "Bonjour %s" # informal enough
+
+[todos]
+one = "%d tâche"
+other = "%d tâches"
+`)},
+		"locales/pt-BR.json": {Data: []byte(`{"greeting": "Olá %s"}`)},
+		"locales/README.md":  {Data: []byte("ignored")},
+	}, "locales")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestTranslateFallback(t *testing.T) {
+	b := testBundle(t)
+	tests := []struct {
+		locale, key string
+		args        []any
+		want        string
+	}{
+		{"fr", "greeting", []any{"Ada"}, "Bonjour Ada"},
+		{"fr", "farewell", nil, "Goodbye"}, // Missing in fr
+		{"fr", "nav.home", nil, "Home"},
+		{"pt-BR", "greeting", []any{"Ada"}, "Olá Ada"},
+		{"pt-br", "greeting", []any{"Ada"}, "Olá Ada"},
+		{"de", "greeting", []any{"Ada"}, "Hello Ada"}, // Unknown locale
+		{"fr", "missing.key", nil, "missing.key"},
+	}
+	for _, tt := range tests {
+		if got := b.Translate(tt.locale, tt.key, tt.args...); got != tt.want {
+			t.Errorf("Translate(%q, %q) = %q, want %q", tt.locale, tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestTranslatePlural(t *testing.T) {
+	b := testBundle(t)
+	tests := []struct {
+		locale string
+		n      any
+		want   string
+	}{
+		{"en", 0, "0 todos"},
+		{"en", 1, "1 todo"},
+		{"en", 2, "2 todos"},
+		{"en", int64(1), "1 todo"},
+		{"fr", 1, "1 tâche"},
+		{"fr", 5, "5 tâches"},
+		{"pt-BR", 1, "1 todo"}, // Plural forms fall back too
+	}
+	for _, tt := range tests {
+		if got := b.Translate(tt.locale, "todos", tt.n); got != tt.want {
+			t.Errorf("Translate(%q, todos, %v) = %q, want %q", tt.locale, tt.n, got, tt.want)
+		}
+	}
+	if got := b.Translate("en", "todos"); got != "%d todos" {
+		t.Errorf("plural without count = %q, want the other form", got)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	b := testBundle(t)
+	tests := []struct {
+		header, want string
+	}{
+		{"fr-CA,fr;q=0.9,en;q=0.8", "fr"},
+		{"de;q=0.9, pt-BR;q=0.5", "pt-BR"},
+		{"en;q=0.2, fr", "fr"},
+		{"de, *", "en"},
+		{"fr;q=0, pt", "en"},
+		{"", "en"},
+	}
+	for _, tt := range tests {
+		if got := b.Match(tt.header); got != tt.want {
+			t.Errorf("Match(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+	if got, want := b.Locales(), []string{"en", "fr", "pt-BR"}; len(got) != len(want) || got[0] != want[0] || got[2] != want[2] {
+		t.Errorf("Locales() = %v, want %v", got, want)
+	}
+}
+
+func TestContext(t *testing.T) {
+	b := testBundle(t)
+	if got := T(context.Background(), "farewell"); got != "farewell" {
+		t.Errorf("T without localizer = %q, want the key", got)
+	}
+	ctx := WithContext(context.Background(), b.Localizer("fr"))
+	if got := T(ctx, "todos", 3); got != "3 tâches" {
+		t.Errorf("T = %q", got)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	b := NewBundle("en")
+	for name, data := range map[string]string{
+		"number":       `count = 3`,
+		"unterminated": `greeting = "Hello`,
+		"duplicate":    "a = \"x\"\na = \"y\"",
+		"header":       `[[items]]`,
+	} {
+		if err := b.LoadTOML("en", []byte(data)); err == nil {
+			t.Errorf("LoadTOML %s: expected error", name)
+		}
+	}
+	if err := b.LoadJSON("en", []byte(`{"count": 3}`)); err == nil {
+		t.Error("LoadJSON with a number: expected error")
+	}
+}
@@ -0,0 +1,31 @@
+package i18n
+
+import (
+	"fmt"
+
+	"github.com/stukennedy/irgo/internal/toml"
+)
+
+// parseTOML parses a message file: [tables], key = "string" pairs and #
+// comments. Tables prefix their keys, so
+//
+//	[todos]
+//	one = "%d todo"
+//
+// sets "todos.one".
+func parseTOML(name string, data []byte) (map[string]string, error) {
+	entries, err := toml.Parse(name, data)
+	if err != nil {
+		return nil, fmt.Errorf("i18n: %w", err)
+	}
+
+	messages := make(map[string]string, len(entries))
+	for _, e := range entries {
+		text, ok := e.Value.(string)
+		if !ok {
+			return nil, fmt.Errorf("i18n: %s:%d: %s: messages must be strings", name, e.Line, e.Key)
+		}
+		messages[e.Key] = text
+	}
+	return messages, nil
+}
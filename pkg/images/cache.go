@@ -0,0 +1,134 @@
+package images
+
+import (
+	"container/list"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// diskCache stores rendered images on disk with LRU eviction by total size.
+type diskCache struct {
+	dir      string
+	maxBytes int64
+	size     int64
+	lru      *list.List // front = most recently used
+	entries  map[string]*list.Element
+	mu       sync.Mutex
+}
+
+type cacheEntry struct {
+	key  string
+	size int64
+}
+
+func newDiskCache(dir string, maxBytes int64) (*diskCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	c := &diskCache{
+		dir:      dir,
+		maxBytes: maxBytes,
+		lru:      list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+
+	// Index results left by a previous run, oldest first
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	type existing struct {
+		key  string
+		size int64
+		mod  int64
+	}
+	var found []existing
+	for _, f := range files {
+		if info, err := f.Info(); err == nil && info.Mode().IsRegular() {
+			found = append(found, existing{f.Name(), info.Size(), info.ModTime().UnixNano()})
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].mod < found[j].mod })
+	for _, e := range found {
+		c.entries[e.key] = c.lru.PushFront(&cacheEntry{key: e.key, size: e.size})
+		c.size += e.size
+	}
+	c.mu.Lock()
+	c.evict()
+	c.mu.Unlock()
+	return c, nil
+}
+
+func (c *diskCache) get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	el, ok := c.entries[key]
+	if ok {
+		c.lru.MoveToFront(el)
+	}
+	c.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	data, err := os.ReadFile(filepath.Join(c.dir, key))
+	if err != nil {
+		c.remove(key)
+		return nil, false
+	}
+	return data, true
+}
+
+func (c *diskCache) put(key string, data []byte) {
+	size := int64(len(data))
+	if size > c.maxBytes {
+		return
+	}
+	if err := os.WriteFile(filepath.Join(c.dir, key), data, 0o644); err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		c.size -= el.Value.(*cacheEntry).size
+		c.lru.Remove(el)
+	}
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key: key, size: size})
+	c.size += size
+	c.evict()
+}
+
+func (c *diskCache) remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		c.size -= el.Value.(*cacheEntry).size
+		c.lru.Remove(el)
+		delete(c.entries, key)
+	}
+}
+
+// evict removes least recently used entries until within bounds.
+// Must be called with c.mu held.
+func (c *diskCache) evict() {
+	for c.size > c.maxBytes {
+		el := c.lru.Back()
+		if el == nil {
+			return
+		}
+		e := el.Value.(*cacheEntry)
+		c.lru.Remove(el)
+		delete(c.entries, e.key)
+		c.size -= e.size
+		os.Remove(filepath.Join(c.dir, e.key))
+	}
+}
+
+// Len returns the number of cached results.
+func (c *diskCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
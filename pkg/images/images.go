@@ -0,0 +1,389 @@
+// Package images serves resized images with format negotiation and an
+// on-disk result cache. Resizing uses only the standard library.
+//
+// Example:
+//
+//	r.Images("/img", images.FromFS(assets), images.Options{CacheDir: "cache/img"})
+//
+// serves /img/avatar.png?w=120&dpr=2 as a 240px wide image.
+package images
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/fs"
+	"math"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/stukennedy/irgo/pkg/viewport"
+
+	// Register GIF decoding for sources
+	_ "image/gif"
+)
+
+var (
+	// ErrTooLarge is returned when a source image exceeds the configured limits.
+	ErrTooLarge = errors.New("source image too large")
+
+	// ErrInvalidParams is returned for malformed size parameters.
+	ErrInvalidParams = errors.New("invalid image parameters")
+)
+
+// Loader opens a source image by name.
+type Loader func(name string) (io.ReadCloser, error)
+
+// FromFS returns a Loader reading source images from fsys.
+func FromFS(fsys fs.FS) Loader {
+	return func(name string) (io.ReadCloser, error) {
+		return fsys.Open(name)
+	}
+}
+
+// Encoder encodes an image in a particular output format.
+type Encoder func(w io.Writer, img image.Image, quality int) error
+
+// Options configures the image handler.
+type Options struct {
+	MaxWidth        int   // Largest output width or height in pixels (default: 2048)
+	MaxDPR          int   // Largest device pixel ratio (default: 3)
+	MaxSourceBytes  int64 // Largest source file (default: 20MB)
+	MaxSourcePixels int   // Largest source width*height (default: 40 megapixels)
+	Quality         int   // JPEG and lossy encoder quality (default: 82)
+
+	// CacheDir stores resized results on disk. Empty disables caching.
+	CacheDir string
+
+	// CacheMaxBytes bounds the cache; least recently used results are
+	// evicted beyond it (default: 256MB).
+	CacheMaxBytes int64
+
+	// Encoders adds output formats keyed by MIME type, e.g. "image/webp".
+	// They are offered when the request's Accept header allows them.
+	// The standard library has no WebP encoder, so WebP needs one here.
+	Encoders map[string]Encoder
+}
+
+func (o *Options) defaults() {
+	if o.MaxWidth <= 0 {
+		o.MaxWidth = 2048
+	}
+	if o.MaxDPR <= 0 {
+		o.MaxDPR = 3
+	}
+	if o.MaxSourceBytes <= 0 {
+		o.MaxSourceBytes = 20 << 20
+	}
+	if o.MaxSourcePixels <= 0 {
+		o.MaxSourcePixels = 40_000_000
+	}
+	if o.Quality <= 0 || o.Quality > 100 {
+		o.Quality = 82
+	}
+	if o.CacheMaxBytes <= 0 {
+		o.CacheMaxBytes = 256 << 20
+	}
+}
+
+// Params are the requested output dimensions.
+type Params struct {
+	Width  int // Requested CSS width (0 = derive from height or source)
+	Height int // Requested CSS height (0 = derive from width or source)
+	DPR    int // Device pixel ratio multiplier (default 1)
+}
+
+// ParseParams reads w, h and dpr query parameters, clamping them to opts.
+func ParseParams(q map[string][]string, opts Options) (Params, error) {
+	opts.defaults()
+	get := func(key string, def int) (int, error) {
+		vs := q[key]
+		if len(vs) == 0 || vs[0] == "" {
+			return def, nil
+		}
+		n, err := strconv.Atoi(vs[0])
+		if err != nil || n < 0 {
+			return 0, ErrInvalidParams
+		}
+		return n, nil
+	}
+
+	var p Params
+	var err error
+	if p.Width, err = get("w", 0); err != nil {
+		return p, err
+	}
+	if p.Height, err = get("h", 0); err != nil {
+		return p, err
+	}
+	if p.DPR, err = get("dpr", 1); err != nil {
+		return p, err
+	}
+	p.DPR = clamp(p.DPR, 1, opts.MaxDPR)
+	p.Width = clamp(p.Width, 0, opts.MaxWidth)
+	p.Height = clamp(p.Height, 0, opts.MaxWidth)
+	return p, nil
+}
+
+// TargetSize computes output dimensions for a source of srcW x srcH.
+// Aspect ratio is preserved, images are never upscaled, and the result is
+// bounded by maxDim on both axes.
+func TargetSize(srcW, srcH int, p Params, maxDim int) (int, int) {
+	if srcW <= 0 || srcH <= 0 {
+		return 0, 0
+	}
+	dpr := p.DPR
+	if dpr < 1 {
+		dpr = 1
+	}
+	w, h := p.Width*dpr, p.Height*dpr
+
+	switch {
+	case w > 0 && h > 0:
+		// Fit inside the box
+		if w*srcH > h*srcW {
+			w = 0
+		} else {
+			h = 0
+		}
+	case w == 0 && h == 0:
+		w = srcW
+	}
+	if w > 0 {
+		h = divRound(srcH*w, srcW)
+	} else {
+		w = divRound(srcW*h, srcH)
+	}
+
+	// Never upscale
+	if w > srcW || h > srcH {
+		w, h = srcW, srcH
+	}
+	// Bound to maxDim
+	if w > maxDim {
+		w, h = maxDim, divRound(srcH*maxDim, srcW)
+	}
+	if h > maxDim {
+		w, h = divRound(srcW*maxDim, srcH), maxDim
+	}
+	return max(w, 1), max(h, 1)
+}
+
+// Handler serves resized images. The image name is taken from the URL path
+// (with any leading slash removed), so mount it with http.StripPrefix.
+type Handler struct {
+	load  Loader
+	opts  Options
+	cache *diskCache
+}
+
+// NewHandler creates an image handler reading sources from load.
+func NewHandler(load Loader, opts Options) (*Handler, error) {
+	opts.defaults()
+	h := &Handler{load: load, opts: opts}
+	if opts.CacheDir != "" {
+		c, err := newDiskCache(opts.CacheDir, opts.CacheMaxBytes)
+		if err != nil {
+			return nil, err
+		}
+		h.cache = c
+	}
+	return h, nil
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	if name == "" || name == "." {
+		http.NotFound(w, r)
+		return
+	}
+
+	q := r.URL.Query()
+	params, err := ParseParams(q, h.opts)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Sizes are CSS pixels; without an explicit dpr use the client's
+	// viewport hints (see router.ViewportMiddleware)
+	vary := "Accept"
+	if q.Get("dpr") == "" && (params.Width > 0 || params.Height > 0) {
+		if v, _ := viewport.FromContext(r.Context()); v.Reported {
+			params.DPR = clamp(int(math.Ceil(v.DPR)), 1, h.opts.MaxDPR)
+			vary = "Accept, " + viewport.Header
+		}
+	}
+
+	format := h.negotiate(r.Header.Get("Accept"), name)
+	key := cacheKey(name, params, format)
+
+	var data []byte
+	if h.cache != nil {
+		data, _ = h.cache.get(key)
+	}
+	if data == nil {
+		data, err = h.render(name, params, format)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			http.NotFound(w, r)
+			return
+		case errors.Is(err, ErrTooLarge):
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		case err != nil:
+			http.Error(w, "image processing failed", http.StatusUnprocessableEntity)
+			return
+		}
+		if h.cache != nil {
+			h.cache.put(key, data)
+		}
+	}
+
+	sum := sha256.Sum256(data)
+	etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Vary", vary)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", format)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Write(data)
+}
+
+// negotiate picks the output MIME type: a registered encoder the client
+// accepts, else PNG for PNG/GIF sources and JPEG otherwise.
+func (h *Handler) negotiate(accept, name string) string {
+	for mime := range h.opts.Encoders {
+		if strings.Contains(accept, mime) {
+			return mime
+		}
+	}
+	switch strings.ToLower(path.Ext(name)) {
+	case ".png", ".gif":
+		return "image/png"
+	}
+	return "image/jpeg"
+}
+
+// render loads, bounds-checks, resizes and encodes a source image.
+func (h *Handler) render(name string, p Params, format string) ([]byte, error) {
+	rc, err := h.load(name)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	// Read at most MaxSourceBytes+1 to detect oversized files
+	src, err := io.ReadAll(io.LimitReader(rc, h.opts.MaxSourceBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(src)) > h.opts.MaxSourceBytes {
+		return nil, ErrTooLarge
+	}
+
+	// Check dimensions from the header before decoding pixel data
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width > h.opts.MaxSourcePixels/max(cfg.Height, 1) {
+		return nil, ErrTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+
+	tw, th := TargetSize(cfg.Width, cfg.Height, p, h.opts.MaxWidth)
+	if tw != cfg.Width || th != cfg.Height {
+		img = Resize(img, tw, th)
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case "image/png":
+		err = png.Encode(&buf, img)
+	case "image/jpeg":
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: h.opts.Quality})
+	default:
+		enc, ok := h.opts.Encoders[format]
+		if !ok {
+			return nil, fmt.Errorf("no encoder for %s", format)
+		}
+		err = enc(&buf, img, h.opts.Quality)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Resize scales img to w x h using box filtering (area averaging).
+func Resize(img image.Image, w, h int) *image.NRGBA {
+	src := image.NewNRGBA(img.Bounds())
+	draw.Draw(src, src.Bounds(), img, img.Bounds().Min, draw.Src)
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0 := y * sh / h
+		y1 := max((y+1)*sh/h, y0+1)
+		for x := 0; x < w; x++ {
+			x0 := x * sw / w
+			x1 := max((x+1)*sw/w, x0+1)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				off := sy*src.Stride + x0*4
+				for sx := x0; sx < x1; sx++ {
+					r += uint64(src.Pix[off])
+					g += uint64(src.Pix[off+1])
+					b += uint64(src.Pix[off+2])
+					a += uint64(src.Pix[off+3])
+					off += 4
+					n++
+				}
+			}
+			i := y*dst.Stride + x*4
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+func cacheKey(name string, p Params, format string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%d|%d|%d|%s", name, p.Width, p.Height, p.DPR, format)))
+	return hex.EncodeToString(sum[:16])
+}
+
+func clamp(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+func divRound(a, b int) int {
+	return (a + b/2) / b
+}
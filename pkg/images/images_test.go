@@ -0,0 +1,213 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"testing/fstest"
+)
+
+func testPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.NRGBA{uint8(x), uint8(y), 128, 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// bombPNG returns a tiny PNG whose header claims huge dimensions.
+func bombPNG(t *testing.T) []byte {
+	data := testPNG(t, 1, 1)
+	// IHDR data starts after the 8-byte signature and 8-byte chunk header
+	binary.BigEndian.PutUint32(data[16:], 100000)
+	binary.BigEndian.PutUint32(data[20:], 100000)
+	crc := crc32.ChecksumIEEE(data[12:29])
+	binary.BigEndian.PutUint32(data[29:], crc)
+	return data
+}
+
+func TestTargetSize(t *testing.T) {
+	tests := []struct {
+		srcW, srcH int
+		p          Params
+		wantW      int
+		wantH      int
+	}{
+		{1000, 500, Params{Width: 300, DPR: 1}, 300, 150},
+		{1000, 500, Params{Width: 300, DPR: 2}, 600, 300},
+		{1000, 500, Params{Height: 100, DPR: 1}, 200, 100},
+		{1000, 500, Params{Width: 200, Height: 200, DPR: 1}, 200, 100},
+		{1000, 500, Params{DPR: 1}, 1000, 500},
+		{100, 50, Params{Width: 300, DPR: 3}, 100, 50}, // no upscaling
+		{4000, 2000, Params{DPR: 1}, 2048, 1024},       // bounded
+		{1000, 3, Params{Width: 10, DPR: 1}, 10, 1},    // minimum 1px
+	}
+
+	for _, tt := range tests {
+		w, h := TargetSize(tt.srcW, tt.srcH, tt.p, 2048)
+		if w != tt.wantW || h != tt.wantH {
+			t.Errorf("TargetSize(%d, %d, %+v) = %dx%d, want %dx%d",
+				tt.srcW, tt.srcH, tt.p, w, h, tt.wantW, tt.wantH)
+		}
+	}
+}
+
+func TestParseParamsClamps(t *testing.T) {
+	p, err := ParseParams(url.Values{"w": {"99999"}, "dpr": {"10"}}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Width != 2048 || p.DPR != 3 {
+		t.Errorf("expected clamped params, got %+v", p)
+	}
+
+	if _, err := ParseParams(url.Values{"w": {"-1"}}, Options{}); err != ErrInvalidParams {
+		t.Errorf("expected ErrInvalidParams, got %v", err)
+	}
+}
+
+func serve(h http.Handler, target, accept string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", target, nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func TestResizeAndCache(t *testing.T) {
+	loads := 0
+	src := testPNG(t, 200, 100)
+	load := func(name string) (io.ReadCloser, error) {
+		loads++
+		return FromFS(fstest.MapFS{"photo.png": {Data: src}})(name)
+	}
+
+	h, err := NewHandler(load, Options{CacheDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := serve(h, "/photo.png?w=50&dpr=2", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	cfg, err := png.DecodeConfig(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Width != 100 || cfg.Height != 50 {
+		t.Errorf("expected 100x50, got %dx%d", cfg.Width, cfg.Height)
+	}
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Error("expected ETag")
+	}
+
+	// Same params are served from cache
+	w = serve(h, "/photo.png?w=50&dpr=2", "")
+	if loads != 1 {
+		t.Errorf("expected cache hit, got %d loads", loads)
+	}
+	if w.Header().Get("ETag") != etag {
+		t.Error("expected stable ETag")
+	}
+
+	// Conditional request
+	req := httptest.NewRequest("GET", "/photo.png?w=50&dpr=2", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("expected 304, got %d", rec.Code)
+	}
+
+	// Different params miss
+	serve(h, "/photo.png?w=20", "")
+	if loads != 2 {
+		t.Errorf("expected cache miss for new params, got %d loads", loads)
+	}
+}
+
+func TestCacheEviction(t *testing.T) {
+	c, err := newDiskCache(t.TempDir(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.put("a", []byte("12345"))
+	c.put("b", []byte("12345"))
+	c.get("a") // a is now most recently used
+	c.put("c", []byte("12345"))
+
+	if _, ok := c.get("b"); ok {
+		t.Error("expected least recently used entry to be evicted")
+	}
+	if _, ok := c.get("a"); !ok {
+		t.Error("expected recently used entry to remain")
+	}
+	if c.Len() != 2 {
+		t.Errorf("expected 2 entries, got %d", c.Len())
+	}
+}
+
+func TestFormatNegotiation(t *testing.T) {
+	fsys := fstest.MapFS{"photo.jpg": {Data: testPNG(t, 10, 10)}, "icon.png": {Data: testPNG(t, 10, 10)}}
+	fakeWebP := func(w io.Writer, img image.Image, quality int) error {
+		_, err := w.Write([]byte("WEBP"))
+		return err
+	}
+	h, _ := NewHandler(FromFS(fsys), Options{Encoders: map[string]Encoder{"image/webp": fakeWebP}})
+
+	tests := []struct {
+		path   string
+		accept string
+		want   string
+	}{
+		{"/photo.jpg", "image/avif,image/webp,*/*", "image/webp"},
+		{"/photo.jpg", "image/*", "image/jpeg"},
+		{"/icon.png", "", "image/png"},
+	}
+	for _, tt := range tests {
+		w := serve(h, tt.path, tt.accept)
+		if ct := w.Header().Get("Content-Type"); ct != tt.want {
+			t.Errorf("%s with Accept %q: expected %s, got %s", tt.path, tt.accept, tt.want, ct)
+		}
+	}
+}
+
+func TestBombGuard(t *testing.T) {
+	fsys := fstest.MapFS{"bomb.png": {Data: bombPNG(t)}, "big.png": {Data: testPNG(t, 10, 10)}}
+
+	h, _ := NewHandler(FromFS(fsys), Options{})
+	if w := serve(h, "/bomb.png", ""); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 for oversized dimensions, got %d", w.Code)
+	}
+
+	h, _ = NewHandler(FromFS(fsys), Options{MaxSourceBytes: 16})
+	if w := serve(h, "/big.png", ""); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 for oversized file, got %d", w.Code)
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	h, _ := NewHandler(FromFS(fstest.MapFS{}), Options{})
+	if w := serve(h, "/missing.png", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", w.Code)
+	}
+}
@@ -0,0 +1,184 @@
+// Package memory keeps the in-memory caches, buffers and queues of an app
+// within a global budget, for low-memory devices.
+//
+// Each bounded component registers a function reporting its current
+// usage in bytes and a function that shrinks it to a target:
+//
+//	unregister := memory.Default().Register("signals", differ.Bytes, differ.Shrink)
+//	defer unregister()
+//
+// When the total exceeds the soft limit, Enforce asks every component to
+// shrink in proportion to its share. Run enforces periodically, and
+// LowMemory runs an aggressive pass for the OS's low-memory warning.
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// LowMemoryFactor is how much LowMemory shrinks total usage by: to a
+// quarter of the current total, or of the limit if that is lower.
+const LowMemoryFactor = 4
+
+// Usage is one component's memory use.
+type Usage struct {
+	Name  string `json:"name"`
+	Bytes int64  `json:"bytes"`
+}
+
+type component struct {
+	usage  func() int64
+	shrink func(target int64)
+}
+
+// Budget is a registry of bounded components sharing a soft limit.
+type Budget struct {
+	mu         sync.Mutex
+	limit      int64
+	components map[string]*component
+}
+
+// NewBudget creates a Budget with a soft limit in bytes; 0 means
+// unlimited, so only LowMemory shrinks.
+func NewBudget(limit int64) *Budget {
+	return &Budget{limit: limit, components: make(map[string]*component)}
+}
+
+var defaultBudget = NewBudget(0)
+
+// Default returns the process-wide Budget framework components register
+// with. It starts unlimited; mobile.InitializeWithConfig sets the limit
+// from the app config.
+func Default() *Budget {
+	return defaultBudget
+}
+
+// SetLimit changes the soft limit in bytes; 0 means unlimited.
+func (b *Budget) SetLimit(limit int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.limit = limit
+}
+
+// Limit returns the soft limit in bytes.
+func (b *Budget) Limit() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.limit
+}
+
+// Register adds a component, replacing any registered under the same
+// name. usage returns its size in bytes; shrink must bring it to at most
+// target bytes, evicting whatever it can rebuild. Both are called
+// without the Budget's lock held, but may run on any goroutine. The
+// returned function unregisters the component.
+func (b *Budget) Register(name string, usage func() int64, shrink func(target int64)) (unregister func()) {
+	c := &component{usage: usage, shrink: shrink}
+	b.mu.Lock()
+	b.components[name] = c
+	b.mu.Unlock()
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if b.components[name] == c {
+			delete(b.components, name)
+		}
+	}
+}
+
+// Usage returns each component's usage, sorted by name.
+func (b *Budget) Usage() []Usage {
+	names, comps := b.snapshot()
+	usage := make([]Usage, len(names))
+	for i, c := range comps {
+		usage[i] = Usage{Name: names[i], Bytes: c.usage()}
+	}
+	return usage
+}
+
+// Total returns the combined usage of all components.
+func (b *Budget) Total() int64 {
+	var total int64
+	for _, u := range b.Usage() {
+		total += u.Bytes
+	}
+	return total
+}
+
+// Enforce shrinks components proportionally if their total exceeds the
+// limit, reporting whether it did.
+func (b *Budget) Enforce() bool {
+	limit := b.Limit()
+	if limit <= 0 {
+		return false
+	}
+	return b.shrinkTo(limit)
+}
+
+// LowMemory shrinks total usage to a LowMemoryFactor fraction of itself,
+// or of the limit if that is lower. Call it when the OS warns that
+// memory is low.
+func (b *Budget) LowMemory() {
+	target := b.Total() / LowMemoryFactor
+	if limit := b.Limit(); limit > 0 {
+		target = min(target, limit/LowMemoryFactor)
+	}
+	b.shrinkTo(target)
+}
+
+// Run calls Enforce every interval until ctx is done.
+func (b *Budget) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			b.Enforce()
+		}
+	}
+}
+
+// shrinkTo asks each component to shrink to its share of target, in
+// proportion to its current usage, if the total is over target.
+func (b *Budget) shrinkTo(target int64) bool {
+	_, comps := b.snapshot()
+	usage := make([]int64, len(comps))
+	var total int64
+	for i, c := range comps {
+		usage[i] = c.usage()
+		total += usage[i]
+	}
+	if total <= target {
+		return false
+	}
+
+	ratio := float64(target) / float64(total)
+	for i, c := range comps {
+		if usage[i] > 0 {
+			c.shrink(int64(float64(usage[i]) * ratio))
+		}
+	}
+	return true
+}
+
+// snapshot returns the registered components sorted by name.
+func (b *Budget) snapshot() ([]string, []*component) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	names := make([]string, 0, len(b.components))
+	for name := range b.components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	comps := make([]*component, len(names))
+	for i, name := range names {
+		comps[i] = b.components[name]
+	}
+	return names, comps
+}
@@ -0,0 +1,153 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fake is a component whose shrink calls are recorded.
+type fake struct {
+	mu      sync.Mutex
+	bytes   int64
+	targets []int64
+}
+
+func (f *fake) usage() int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.bytes
+}
+
+func (f *fake) shrink(target int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.targets = append(f.targets, target)
+	f.bytes = min(f.bytes, target)
+}
+
+func (f *fake) calls() []int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]int64(nil), f.targets...)
+}
+
+func TestEnforceUnderLimit(t *testing.T) {
+	b := NewBudget(1000)
+	a := &fake{bytes: 400}
+	b.Register("a", a.usage, a.shrink)
+
+	if b.Enforce() {
+		t.Error("Enforce shrank under the limit")
+	}
+	if len(a.calls()) != 0 {
+		t.Errorf("unexpected shrink calls %v", a.calls())
+	}
+}
+
+func TestEnforceProportional(t *testing.T) {
+	b := NewBudget(1000)
+	cache := &fake{bytes: 1500}
+	queue := &fake{bytes: 500}
+	idle := &fake{}
+	b.Register("cache", cache.usage, cache.shrink)
+	b.Register("queue", queue.usage, queue.shrink)
+	b.Register("idle", idle.usage, idle.shrink)
+
+	if !b.Enforce() {
+		t.Fatal("Enforce did nothing over the limit")
+	}
+	if got := cache.calls(); len(got) != 1 || got[0] != 750 {
+		t.Errorf("cache: expected shrink to 750, got %v", got)
+	}
+	if got := queue.calls(); len(got) != 1 || got[0] != 250 {
+		t.Errorf("queue: expected shrink to 250, got %v", got)
+	}
+	if got := idle.calls(); len(got) != 0 {
+		t.Errorf("idle component asked to shrink: %v", got)
+	}
+	if b.Total() != 1000 {
+		t.Errorf("expected total 1000, got %d", b.Total())
+	}
+}
+
+func TestUnlimitedNeverEnforces(t *testing.T) {
+	b := NewBudget(0)
+	a := &fake{bytes: 1 << 30}
+	b.Register("a", a.usage, a.shrink)
+	if b.Enforce() {
+		t.Error("unlimited budget enforced")
+	}
+}
+
+func TestLowMemory(t *testing.T) {
+	b := NewBudget(0)
+	a := &fake{bytes: 800}
+	c := &fake{bytes: 400}
+	b.Register("a", a.usage, a.shrink)
+	b.Register("c", c.usage, c.shrink)
+
+	b.LowMemory()
+	if got := a.calls(); len(got) != 1 || got[0] != 200 {
+		t.Errorf("a: expected shrink to 200, got %v", got)
+	}
+	if got := c.calls(); len(got) != 1 || got[0] != 100 {
+		t.Errorf("c: expected shrink to 100, got %v", got)
+	}
+
+	// With a limit, the pass goes to a quarter of the limit if lower
+	b = NewBudget(400)
+	a = &fake{bytes: 300}
+	b.Register("a", a.usage, a.shrink)
+	b.LowMemory()
+	if got := a.calls(); len(got) != 1 || got[0] != 75 {
+		t.Errorf("expected shrink to 75, got %v", got)
+	}
+}
+
+func TestRegisterReplaceAndUnregister(t *testing.T) {
+	b := NewBudget(0)
+	first := &fake{bytes: 10}
+	second := &fake{bytes: 20}
+	unregisterFirst := b.Register("cache", first.usage, first.shrink)
+	unregisterSecond := b.Register("cache", second.usage, second.shrink)
+
+	usage := b.Usage()
+	if len(usage) != 1 || usage[0] != (Usage{Name: "cache", Bytes: 20}) {
+		t.Errorf("unexpected usage %v", usage)
+	}
+
+	// The replaced registration's unregister leaves the new one alone
+	unregisterFirst()
+	if b.Total() != 20 {
+		t.Errorf("expected 20, got %d", b.Total())
+	}
+	unregisterSecond()
+	if len(b.Usage()) != 0 {
+		t.Errorf("expected no components, got %v", b.Usage())
+	}
+}
+
+func TestRun(t *testing.T) {
+	b := NewBudget(100)
+	a := &fake{bytes: 200}
+	b.Register("a", a.usage, a.shrink)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		b.Run(ctx, time.Millisecond)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for len(a.calls()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+	if got := a.calls(); len(got) == 0 || got[0] != 100 {
+		t.Errorf("expected shrink to 100, got %v", got)
+	}
+}
@@ -0,0 +1,190 @@
+// Package module bundles a router, template engine and WebSocket handlers
+// into a self-contained mini-app that can be mounted under a URL prefix.
+//
+// Example:
+//
+//	help := module.New("help")
+//	help.Engine.Parse("index", `<h1>Help</h1>`)
+//	help.Router.GET("/", func(ctx *router.Context) (string, error) {
+//	    return help.Engine.Render("index", nil)
+//	})
+//	help.Handle("/chat", chatHandler) // served at /ws/help/chat
+//
+//	app := module.NewApp(r, hub)
+//	if err := app.Mount("/help", help); err != nil {
+//	    log.Fatal(err)
+//	}
+package module
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/stukennedy/irgo/pkg/render"
+	"github.com/stukennedy/irgo/pkg/router"
+	"github.com/stukennedy/irgo/pkg/websocket"
+)
+
+// Module is an isolated mini-app with its own routes, templates and
+// WebSocket handlers.
+type Module struct {
+	// Name identifies the module and namespaces its WebSocket URLs.
+	Name string
+
+	// Router holds the module's routes, relative to its mount prefix.
+	Router *router.Router
+
+	// Engine is the module's private template set.
+	Engine *render.Engine
+
+	hubHandlers map[string]websocket.MessageHandler
+	static      http.FileSystem
+}
+
+// New creates an empty module. Its router has no default middleware;
+// the parent router's middleware applies once the module is mounted.
+func New(name string, opts ...router.Option) *Module {
+	return &Module{
+		Name:        name,
+		Router:      router.NewWithoutMiddleware(opts...),
+		Engine:      render.New(),
+		hubHandlers: make(map[string]websocket.MessageHandler),
+	}
+}
+
+// Handle registers a WebSocket handler relative to the module's hub
+// namespace. The pattern "/chat" is served at "/ws/<name>/chat".
+func (m *Module) Handle(pattern string, handler websocket.MessageHandler) {
+	m.hubHandlers[pattern] = handler
+}
+
+// HandleFunc registers a function WebSocket handler.
+func (m *Module) HandleFunc(pattern string, handler func(*websocket.Session, *websocket.Request) (*websocket.Envelope, error)) {
+	m.Handle(pattern, websocket.MessageHandlerFunc(handler))
+}
+
+// Static serves root at "<prefix>/static/" once mounted.
+func (m *Module) Static(root http.FileSystem) {
+	m.static = root
+}
+
+// HubPrefix returns the WebSocket URL namespace for the module.
+func (m *Module) HubPrefix() string {
+	return "/ws/" + m.Name + "/"
+}
+
+// App mounts modules onto a parent router and hub.
+type App struct {
+	router  *router.Router
+	hub     *websocket.Hub
+	modules map[string]*mounted
+	mu      sync.Mutex
+}
+
+type mounted struct {
+	module *Module
+	prefix string
+}
+
+// Info describes a mounted module.
+type Info struct {
+	Name        string
+	Prefix      string
+	HubPrefix   string
+	HubPatterns []string
+	Templates   []string
+	Static      bool
+}
+
+// NewApp creates an App that mounts modules onto r and hub.
+// hub may be nil if no module uses WebSocket handlers.
+func NewApp(r *router.Router, hub *websocket.Hub) *App {
+	return &App{
+		router:  r,
+		hub:     hub,
+		modules: make(map[string]*mounted),
+	}
+}
+
+// Mount attaches a module under prefix. It returns an error without mounting
+// anything if the module name or prefix conflicts with one already mounted.
+func (a *App) Mount(prefix string, m *Module) error {
+	prefix = "/" + strings.Trim(prefix, "/")
+	if prefix == "/" {
+		return fmt.Errorf("module %q: cannot mount at root", m.Name)
+	}
+	if m.Name == "" || strings.Contains(m.Name, "/") {
+		return fmt.Errorf("module %q: invalid name", m.Name)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, exists := a.modules[m.Name]; exists {
+		return fmt.Errorf("module %q: already mounted", m.Name)
+	}
+	for _, other := range a.modules {
+		if overlaps(prefix, other.prefix) {
+			return fmt.Errorf("module %q: prefix %s conflicts with module %q at %s",
+				m.Name, prefix, other.module.Name, other.prefix)
+		}
+	}
+	if len(m.hubHandlers) > 0 && a.hub == nil {
+		return fmt.Errorf("module %q: has WebSocket handlers but app has no hub", m.Name)
+	}
+
+	if m.static != nil {
+		m.Router.Static("/static", m.static)
+	}
+	a.router.Mount(prefix, m.Router)
+	for pattern, handler := range m.hubHandlers {
+		a.hub.Handle(m.HubPrefix()+strings.TrimPrefix(pattern, "/"), handler)
+	}
+
+	a.modules[m.Name] = &mounted{module: m, prefix: prefix}
+	return nil
+}
+
+// Module returns a mounted module by name.
+func (a *App) Module(name string) (*Module, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	mt, ok := a.modules[name]
+	if !ok {
+		return nil, false
+	}
+	return mt.module, true
+}
+
+// Modules describes all mounted modules, sorted by name.
+func (a *App) Modules() []Info {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	infos := make([]Info, 0, len(a.modules))
+	for _, mt := range a.modules {
+		info := Info{
+			Name:      mt.module.Name,
+			Prefix:    mt.prefix,
+			HubPrefix: mt.module.HubPrefix(),
+			Templates: mt.module.Engine.Templates(),
+			Static:    mt.module.static != nil,
+		}
+		for pattern := range mt.module.hubHandlers {
+			info.HubPatterns = append(info.HubPatterns, pattern)
+		}
+		sort.Strings(info.HubPatterns)
+		sort.Strings(info.Templates)
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// overlaps reports whether one prefix contains the other.
+func overlaps(a, b string) bool {
+	return a == b || strings.HasPrefix(a, b+"/") || strings.HasPrefix(b, a+"/")
+}
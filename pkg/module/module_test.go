@@ -0,0 +1,98 @@
+package module
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stukennedy/irgo/pkg/router"
+	"github.com/stukennedy/irgo/pkg/websocket"
+)
+
+func newModule(t *testing.T, name, heading string) *Module {
+	t.Helper()
+	m := New(name)
+	if err := m.Engine.Parse("index", "<h1>"+heading+"</h1>"); err != nil {
+		t.Fatal(err)
+	}
+	m.Router.GET("/", func(ctx *router.Context) (string, error) {
+		return m.Engine.Render("index", nil)
+	})
+	m.HandleFunc("/live", func(s *websocket.Session, req *websocket.Request) (*websocket.Envelope, error) {
+		return websocket.NewEnvelope(name), nil
+	})
+	return m
+}
+
+func TestMountIsolation(t *testing.T) {
+	r := router.New()
+	hub := websocket.NewHub()
+	app := NewApp(r, hub)
+
+	if err := app.Mount("/help", newModule(t, "help", "Help")); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.Mount("/settings", newModule(t, "settings", "Settings")); err != nil {
+		t.Fatal(err)
+	}
+
+	for path, want := range map[string]string{"/help/": "<h1>Help</h1>", "/settings/": "<h1>Settings</h1>"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Body.String() != want {
+			t.Errorf("%s: expected %q, got %q", path, want, w.Body.String())
+		}
+	}
+
+	for _, name := range []string{"help", "settings"} {
+		session, err := hub.Connect("/ws/" + name + "/live")
+		if err != nil {
+			t.Fatalf("%s: connect failed: %v", name, err)
+		}
+		env, err := session.HandleMessage([]byte(`{"type":"request"}`))
+		if err != nil || env.Payload != name {
+			t.Errorf("%s: expected module handler, got %+v (err %v)", name, env, err)
+		}
+	}
+}
+
+func TestMountConflicts(t *testing.T) {
+	app := NewApp(router.New(), websocket.NewHub())
+	if err := app.Mount("/help", newModule(t, "help", "Help")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		prefix string
+		module *Module
+	}{
+		{"/docs", newModule(t, "help", "Dup")},
+		{"/help", newModule(t, "other", "Other")},
+		{"/help/faq", newModule(t, "faq", "FAQ")},
+		{"/", newModule(t, "root", "Root")},
+	}
+
+	for _, tt := range tests {
+		if err := app.Mount(tt.prefix, tt.module); err == nil {
+			t.Errorf("expected conflict mounting %q at %s", tt.module.Name, tt.prefix)
+		}
+	}
+
+	if len(app.Modules()) != 1 {
+		t.Errorf("expected 1 mounted module, got %d", len(app.Modules()))
+	}
+}
+
+func TestModulesInfo(t *testing.T) {
+	app := NewApp(router.New(), websocket.NewHub())
+	app.Mount("/settings", newModule(t, "settings", "Settings"))
+	app.Mount("/help", newModule(t, "help", "Help"))
+
+	infos := app.Modules()
+	if len(infos) != 2 || infos[0].Name != "help" || infos[1].Name != "settings" {
+		t.Fatalf("unexpected modules: %+v", infos)
+	}
+	if infos[0].HubPrefix != "/ws/help/" || !strings.Contains(strings.Join(infos[0].Templates, ","), "index") {
+		t.Errorf("unexpected info: %+v", infos[0])
+	}
+}
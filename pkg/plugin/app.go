@@ -0,0 +1,76 @@
+package plugin
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/stukennedy/irgo/pkg/config"
+	"github.com/stukennedy/irgo/pkg/events"
+	"github.com/stukennedy/irgo/pkg/render"
+	"github.com/stukennedy/irgo/pkg/router"
+	"github.com/stukennedy/irgo/pkg/websocket"
+)
+
+// App is a plugin's view of the application, passed to Plugin.Register.
+//
+// Routes and template functions should be added through App.Route and
+// App.Func so conflicts are detected; Router remains available for
+// middleware and anything else a plugin needs.
+type App struct {
+	Router *router.Router
+	Hub    *websocket.Hub // nil if the app has no hub
+	Engine *render.Engine
+	Events *events.Bus
+	Config *config.Config
+
+	host *Host
+	name string
+}
+
+// Name returns the name of the plugin being registered.
+func (a *App) Name() string {
+	return a.name
+}
+
+// Route registers a fragment handler, failing if another plugin or the app
+// already registered method and pattern.
+func (a *App) Route(method, pattern string, handler router.FragmentHandler) (*router.Route, error) {
+	if err := a.host.claimRoute(a.name, method, pattern); err != nil {
+		return nil, err
+	}
+	return a.Router.Fragment(method, pattern, handler), nil
+}
+
+// Func registers a template function, failing if the name is already taken.
+func (a *App) Func(name string, fn any) error {
+	if a.Engine == nil {
+		return errors.New("app has no template engine")
+	}
+	if err := a.host.claimFunc(a.name, name); err != nil {
+		return err
+	}
+	return a.Engine.AddFunc(name, fn)
+}
+
+// KV returns storage namespaced to the plugin: keys never collide with
+// those of other plugins.
+func (a *App) KV() KV {
+	return &prefixKV{kv: a.host.Store, prefix: a.name + ":"}
+}
+
+// DebugPage registers a GET page at /_debug/<plugin><path>. Debug pages are
+// only mounted when Config.Debug is set; otherwise DebugPage does nothing.
+func (a *App) DebugPage(path string, handler router.FragmentHandler) error {
+	if !a.Config.Debug {
+		return nil
+	}
+	pattern := DebugPrefix + a.name + "/" + strings.TrimPrefix(path, "/")
+	if _, err := a.Route(http.MethodGet, pattern, handler); err != nil {
+		return err
+	}
+	a.host.mu.Lock()
+	a.host.debug = append(a.host.debug, pattern)
+	a.host.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,84 @@
+// Package crashes is a plugin that lists saved crash reports on a debug
+// page at /_debug/crashes/, for desktop and mobile debug builds.
+//
+// Example:
+//
+//	if err := host.Register(crashes.New(nil)); err != nil {
+//	    log.Fatal(err)
+//	}
+package crashes
+
+import (
+	"html/template"
+	"strings"
+
+	"github.com/stukennedy/irgo/pkg/crash"
+	"github.com/stukennedy/irgo/pkg/plugin"
+	"github.com/stukennedy/irgo/pkg/router"
+)
+
+// Plugin shows crash reports from a crash.Reporter.
+type Plugin struct {
+	reporter *crash.Reporter
+}
+
+// New creates the plugin for reporter, or for crash.Default() at request
+// time if reporter is nil.
+func New(reporter *crash.Reporter) *Plugin {
+	return &Plugin{reporter: reporter}
+}
+
+// Name implements plugin.Plugin.
+func (p *Plugin) Name() string {
+	return "crashes"
+}
+
+// Register implements plugin.Plugin.
+func (p *Plugin) Register(app *plugin.App) error {
+	return app.DebugPage("/", p.page)
+}
+
+var pageTemplate = template.Must(template.New("crashes").Parse(`<h1>Crash reports</h1>
+{{- if not .Enabled}}
+<p>Crash reporting is not enabled.</p>
+{{- else}}
+{{- range .Reports}}
+<details>
+<summary>{{.Time.Format "2006-01-02 15:04:05"}} &middot; {{.Source}} &middot; {{.Panic}}</summary>
+<p>ID {{.ID}}{{with .Version}} &middot; version {{.}}{{end}}</p>
+{{- with .Request}}
+<p>{{.Method}} {{.URL}}</p>
+{{- end}}
+{{- with .Message}}
+<p>Session {{.SessionID}} on {{.URL}}{{with .Event}} ({{.}}){{end}}</p>
+{{- end}}
+<pre>{{.Stack}}</pre>
+</details>
+{{- else}}
+<p>No crash reports.</p>
+{{- end}}
+{{- end}}`))
+
+func (p *Plugin) page(ctx *router.Context) (string, error) {
+	rep := p.reporter
+	if rep == nil {
+		rep = crash.Default()
+	}
+	data := struct {
+		Enabled bool
+		Reports []crash.Report
+	}{Enabled: rep != nil}
+	if rep != nil {
+		reports, err := rep.Pending()
+		if err != nil {
+			return "", err
+		}
+		data.Reports = reports
+	}
+
+	var b strings.Builder
+	if err := pageTemplate.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,65 @@
+package crashes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stukennedy/irgo/pkg/config"
+	"github.com/stukennedy/irgo/pkg/crash"
+	"github.com/stukennedy/irgo/pkg/plugin"
+	"github.com/stukennedy/irgo/pkg/render"
+	"github.com/stukennedy/irgo/pkg/router"
+)
+
+func TestCrashesPage(t *testing.T) {
+	rep, err := crash.NewReporter(t.TempDir(), "3.1.0", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rep.Capture(crash.Report{
+		Source:  crash.SourceHTTP,
+		Panic:   "nil map <write>",
+		Stack:   "goroutine 7 [running]",
+		Request: &crash.Request{Method: "POST", URL: "/todos"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.Default()
+	cfg.Debug = true
+	r := router.New()
+	host := plugin.NewHost(r, nil, render.New(), &cfg)
+	if err := host.Register(New(rep)); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/_debug/crashes/", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	body := w.Body.String()
+	for _, want := range []string{"Crash reports", "nil map &lt;write&gt;", "version 3.1.0", "POST /todos", "goroutine 7"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("page missing %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestCrashesPageDisabled(t *testing.T) {
+	cfg := config.Default()
+	cfg.Debug = true
+	r := router.New()
+	host := plugin.NewHost(r, nil, render.New(), &cfg)
+	if err := host.Register(New(nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/_debug/crashes/", nil))
+	if !strings.Contains(w.Body.String(), "not enabled") {
+		t.Errorf("unexpected page:\n%s", w.Body.String())
+	}
+}
@@ -0,0 +1,83 @@
+package plugin
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// KV is a minimal key-value store. Implementations must be safe for
+// concurrent use.
+type KV interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte)
+	Delete(key string)
+
+	// Keys returns the stored keys with the given prefix, sorted.
+	Keys(prefix string) []string
+}
+
+// MemoryKV is an in-memory KV.
+type MemoryKV struct {
+	mu   sync.RWMutex
+	data map[string][]byte
+}
+
+// NewMemoryKV creates an empty MemoryKV.
+func NewMemoryKV() *MemoryKV {
+	return &MemoryKV{data: make(map[string][]byte)}
+}
+
+// Get returns the value stored under key.
+func (m *MemoryKV) Get(key string) ([]byte, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	v, ok := m.data[key]
+	return v, ok
+}
+
+// Set stores value under key.
+func (m *MemoryKV) Set(key string, value []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.data[key] = append([]byte(nil), value...)
+}
+
+// Delete removes key.
+func (m *MemoryKV) Delete(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.data, key)
+}
+
+// Keys returns the stored keys with the given prefix, sorted.
+func (m *MemoryKV) Keys(prefix string) []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var keys []string
+	for k := range m.data {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// prefixKV namespaces a KV under a fixed key prefix.
+type prefixKV struct {
+	kv     KV
+	prefix string
+}
+
+func (p *prefixKV) Get(key string) ([]byte, bool) { return p.kv.Get(p.prefix + key) }
+func (p *prefixKV) Set(key string, value []byte)  { p.kv.Set(p.prefix+key, value) }
+func (p *prefixKV) Delete(key string)             { p.kv.Delete(p.prefix + key) }
+
+func (p *prefixKV) Keys(prefix string) []string {
+	keys := p.kv.Keys(p.prefix + prefix)
+	for i, k := range keys {
+		keys[i] = strings.TrimPrefix(k, p.prefix)
+	}
+	return keys
+}
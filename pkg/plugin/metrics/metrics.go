@@ -0,0 +1,173 @@
+// Package metrics is a plugin that records per-route request counts,
+// errors and latency, shown on a debug dashboard at /_debug/metrics/
+// with the memory use of components registered with memory.Default.
+//
+// Example:
+//
+//	m := metrics.New()
+//	if err := host.Register(m); err != nil {
+//	    log.Fatal(err)
+//	}
+package metrics
+
+import (
+	"html/template"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stukennedy/irgo/pkg/memory"
+	"github.com/stukennedy/irgo/pkg/plugin"
+	"github.com/stukennedy/irgo/pkg/router"
+)
+
+// RouteStats summarizes the requests served by one route.
+type RouteStats struct {
+	Method   string
+	Pattern  string // "" for requests that matched no route
+	Count    int
+	Errors   int // Responses with status >= 500
+	Total    time.Duration
+	Max      time.Duration
+	LastSeen time.Time
+}
+
+// Mean returns the mean request latency.
+func (s RouteStats) Mean() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Count)
+}
+
+// Plugin records request metrics. It must be registered before the app's
+// routes, since it installs router middleware.
+type Plugin struct {
+	mu     sync.Mutex
+	routes map[string]*RouteStats
+	now    func() time.Time
+}
+
+// New creates a metrics plugin.
+func New() *Plugin {
+	return &Plugin{
+		routes: make(map[string]*RouteStats),
+		now:    time.Now,
+	}
+}
+
+// Name implements plugin.Plugin.
+func (p *Plugin) Name() string {
+	return "metrics"
+}
+
+// Register implements plugin.Plugin.
+func (p *Plugin) Register(app *plugin.App) error {
+	app.Router.Use(p.middleware)
+	return app.DebugPage("/", p.dashboard)
+}
+
+// Snapshot returns the recorded stats sorted by pattern and method.
+func (p *Plugin) Snapshot() []RouteStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := make([]RouteStats, 0, len(p.routes))
+	for _, s := range p.routes {
+		stats = append(stats, *s)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Pattern != stats[j].Pattern {
+			return stats[i].Pattern < stats[j].Pattern
+		}
+		return stats[i].Method < stats[j].Method
+	})
+	return stats
+}
+
+// Reset clears the recorded stats.
+func (p *Plugin) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.routes = make(map[string]*RouteStats)
+}
+
+func (p *Plugin) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := p.now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		pattern := ""
+		if rc := chi.RouteContext(r.Context()); rc != nil {
+			pattern = rc.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		p.record(r.Method, pattern, status, p.now().Sub(start))
+	})
+}
+
+func (p *Plugin) record(method, pattern string, status int, elapsed time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := method + " " + pattern
+	s, ok := p.routes[key]
+	if !ok {
+		s = &RouteStats{Method: method, Pattern: pattern}
+		p.routes[key] = s
+	}
+	s.Count++
+	if status >= 500 {
+		s.Errors++
+	}
+	s.Total += elapsed
+	s.Max = max(s.Max, elapsed)
+	s.LastSeen = p.now()
+}
+
+var dashboardTemplate = template.Must(template.New("metrics").Parse(`<h1>Request metrics</h1>
+<table>
+<thead><tr><th>Method</th><th>Route</th><th>Requests</th><th>Errors</th><th>Mean</th><th>Max</th></tr></thead>
+<tbody>
+{{- range .Routes}}
+<tr><td>{{.Method}}</td><td>{{if .Pattern}}{{.Pattern}}{{else}}(unmatched){{end}}</td><td>{{.Count}}</td><td>{{.Errors}}</td><td>{{.Mean}}</td><td>{{.Max}}</td></tr>
+{{- else}}
+<tr><td colspan="6">No requests recorded</td></tr>
+{{- end}}
+</tbody>
+</table>
+<h2>Memory</h2>
+<table>
+<thead><tr><th>Component</th><th>Bytes</th></tr></thead>
+<tbody>
+{{- range .Memory}}
+<tr><td>{{.Name}}</td><td>{{.Bytes}}</td></tr>
+{{- else}}
+<tr><td colspan="2">No components registered</td></tr>
+{{- end}}
+</tbody>
+</table>
+<p>Limit: {{if .Limit}}{{.Limit}} bytes{{else}}unlimited{{end}}</p>`))
+
+func (p *Plugin) dashboard(ctx *router.Context) (string, error) {
+	budget := memory.Default()
+	data := struct {
+		Routes []RouteStats
+		Memory []memory.Usage
+		Limit  int64
+	}{p.Snapshot(), budget.Usage(), budget.Limit()}
+
+	var b strings.Builder
+	if err := dashboardTemplate.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
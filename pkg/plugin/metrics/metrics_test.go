@@ -0,0 +1,92 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stukennedy/irgo/pkg/config"
+	"github.com/stukennedy/irgo/pkg/memory"
+	"github.com/stukennedy/irgo/pkg/plugin"
+	"github.com/stukennedy/irgo/pkg/render"
+	"github.com/stukennedy/irgo/pkg/router"
+)
+
+func TestMetricsPlugin(t *testing.T) {
+	cfg := config.Default()
+	cfg.Debug = true
+	r := router.New()
+	host := plugin.NewHost(r, nil, render.New(), &cfg)
+
+	m := New()
+	if err := host.Register(m); err != nil {
+		t.Fatal(err)
+	}
+
+	// App routes are added after the plugin
+	r.GET("/todos/{id}", func(ctx *router.Context) (string, error) {
+		return "todo " + ctx.Param("id"), nil
+	})
+	r.GET("/fail", func(ctx *router.Context) (string, error) {
+		return "", router.ErrInternal(errors.New("boom"))
+	})
+
+	for _, path := range []string{"/todos/1", "/todos/2", "/fail", "/missing"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	stats := map[string]RouteStats{}
+	for _, s := range m.Snapshot() {
+		stats[s.Pattern] = s
+	}
+	if s := stats["/todos/{id}"]; s.Count != 2 || s.Errors != 0 {
+		t.Errorf("expected 2 successful requests, got %+v", s)
+	}
+	if s := stats["/fail"]; s.Count != 1 || s.Errors != 1 {
+		t.Errorf("expected 1 error, got %+v", s)
+	}
+	if s := stats[""]; s.Count != 1 {
+		t.Errorf("expected 1 unmatched request, got %+v", s)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/_debug/metrics/", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	body := w.Body.String()
+	for _, want := range []string{"Request metrics", "/todos/{id}", "(unmatched)", "<td>2</td>"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected dashboard to contain %q, got %s", want, body)
+		}
+	}
+
+	m.Reset()
+	if len(m.Snapshot()) != 0 {
+		t.Error("expected stats to be cleared")
+	}
+}
+
+func TestMetricsMemory(t *testing.T) {
+	cfg := config.Default()
+	cfg.Debug = true
+	r := router.New()
+	host := plugin.NewHost(r, nil, render.New(), &cfg)
+	if err := host.Register(New()); err != nil {
+		t.Fatal(err)
+	}
+
+	unregister := memory.Default().Register("image cache", func() int64 { return 4096 }, func(int64) {})
+	defer unregister()
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/_debug/metrics/", nil))
+	body := w.Body.String()
+	for _, want := range []string{"<h2>Memory</h2>", "<td>image cache</td><td>4096</td>", "Limit: unlimited"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected dashboard to contain %q, got %s", want, body)
+		}
+	}
+}
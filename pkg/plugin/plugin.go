@@ -0,0 +1,219 @@
+// Package plugin defines the extension interface for reusable feature packs
+// (auth screens, analytics, crash reporting) that hook into an app's router,
+// hub, template engine and events without the app being forked.
+//
+// Example:
+//
+//	host := plugin.NewHost(r, hub, engine, cfg)
+//	if err := host.Register(metrics.New(), auth.New()); err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer host.Close()
+//
+// Register plugins before the app's own routes: chi requires middleware to
+// be added before routes, and plugins may install middleware.
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stukennedy/irgo/pkg/config"
+	"github.com/stukennedy/irgo/pkg/events"
+	"github.com/stukennedy/irgo/pkg/render"
+	"github.com/stukennedy/irgo/pkg/router"
+	"github.com/stukennedy/irgo/pkg/websocket"
+)
+
+// DebugPrefix is the URL prefix of plugin debug pages.
+const DebugPrefix = "/_debug/"
+
+// Plugin is a feature pack that registers itself with an app.
+type Plugin interface {
+	// Name identifies the plugin and namespaces its storage and debug pages.
+	Name() string
+
+	// Register adds the plugin's routes, middleware, template functions and
+	// event subscriptions. An error aborts Host.Register.
+	Register(app *App) error
+}
+
+// Dependent is implemented by plugins that must be registered after others.
+type Dependent interface {
+	Requires() []string
+}
+
+// Closer is implemented by plugins holding resources released by Host.Close.
+type Closer interface {
+	Close() error
+}
+
+// Host registers plugins with an app in order and detects conflicts
+// between the routes and template functions they contribute.
+type Host struct {
+	Router *router.Router
+	Hub    *websocket.Hub
+	Engine *render.Engine
+	Events *events.Bus
+	Config *config.Config
+
+	// Store backs each plugin's namespaced KV (see App.KV).
+	Store KV
+
+	mu      sync.Mutex
+	plugins []Plugin
+	routes  map[string]string // "METHOD pattern" → plugin name
+	funcs   map[string]string // Template func → plugin name
+	debug   []string
+}
+
+// NewHost creates a Host for the given app components. hub may be nil if no
+// plugin uses WebSockets, and cfg nil for config.Default().
+func NewHost(r *router.Router, hub *websocket.Hub, engine *render.Engine, cfg *config.Config) *Host {
+	if cfg == nil {
+		d := config.Default()
+		cfg = &d
+	}
+	return &Host{
+		Router: r,
+		Hub:    hub,
+		Engine: engine,
+		Events: events.NewBus(),
+		Config: cfg,
+		Store:  NewMemoryKV(),
+		routes: make(map[string]string),
+		funcs:  make(map[string]string),
+	}
+}
+
+// Register registers plugins in order. It stops at the first plugin that
+// fails: an invalid or duplicate name, a missing requirement, or an error
+// from Plugin.Register such as a route conflict.
+func (h *Host) Register(plugins ...Plugin) error {
+	for _, p := range plugins {
+		if err := h.register(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *Host) register(p Plugin) error {
+	name := p.Name()
+	if name == "" || strings.ContainsAny(name, "/:") {
+		return fmt.Errorf("plugin %q: invalid name", name)
+	}
+	if _, ok := h.Plugin(name); ok {
+		return fmt.Errorf("plugin %q: already registered", name)
+	}
+	if d, ok := p.(Dependent); ok {
+		for _, req := range d.Requires() {
+			if _, ok := h.Plugin(req); !ok {
+				return fmt.Errorf("plugin %q: requires plugin %q to be registered first", name, req)
+			}
+		}
+	}
+
+	app := &App{
+		Router: h.Router,
+		Hub:    h.Hub,
+		Engine: h.Engine,
+		Events: h.Events,
+		Config: h.Config,
+		host:   h,
+		name:   name,
+	}
+	if err := p.Register(app); err != nil {
+		return fmt.Errorf("plugin %q: %w", name, err)
+	}
+
+	h.mu.Lock()
+	h.plugins = append(h.plugins, p)
+	h.mu.Unlock()
+	return nil
+}
+
+// Plugin returns a registered plugin by name.
+func (h *Host) Plugin(name string) (Plugin, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, p := range h.plugins {
+		if p.Name() == name {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// Plugins returns the registered plugins in registration order.
+func (h *Host) Plugins() []Plugin {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]Plugin(nil), h.plugins...)
+}
+
+// DebugPages returns the URLs of mounted plugin debug pages.
+func (h *Host) DebugPages() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.debug...)
+}
+
+// Close closes plugins implementing Closer in reverse registration order.
+func (h *Host) Close() error {
+	var errs []error
+	plugins := h.Plugins()
+	slices.Reverse(plugins)
+	for _, p := range plugins {
+		if c, ok := p.(Closer); ok {
+			if err := c.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("plugin %q: %w", p.Name(), err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// claimRoute records that plugin owns method and pattern, failing if another
+// plugin or the app already registered it.
+func (h *Host) claimRoute(plugin, method, pattern string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	key := method + " " + pattern
+	if owner, ok := h.routes[key]; ok {
+		return fmt.Errorf("route %s conflicts with plugin %q", key, owner)
+	}
+	taken := false
+	chi.Walk(h.Router.Handler().(chi.Routes), func(m, p string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if m == method && p == pattern {
+			taken = true
+		}
+		return nil
+	})
+	if taken {
+		return fmt.Errorf("route %s is already registered", key)
+	}
+	h.routes[key] = plugin
+	return nil
+}
+
+// claimFunc records that plugin owns a template function name.
+func (h *Host) claimFunc(plugin, name string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if owner, ok := h.funcs[name]; ok {
+		return fmt.Errorf("template func %q conflicts with plugin %q", name, owner)
+	}
+	if h.Engine.HasFunc(name) {
+		return fmt.Errorf("template func %q is already registered", name)
+	}
+	h.funcs[name] = plugin
+	return nil
+}
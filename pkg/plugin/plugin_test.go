@@ -0,0 +1,225 @@
+package plugin
+
+import (
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stukennedy/irgo/pkg/config"
+	"github.com/stukennedy/irgo/pkg/render"
+	"github.com/stukennedy/irgo/pkg/router"
+)
+
+// testPlugin registers a GET route and a template func.
+type testPlugin struct {
+	name     string
+	route    string
+	fn       string
+	requires []string
+	closed   *[]string
+}
+
+func (p *testPlugin) Name() string       { return p.name }
+func (p *testPlugin) Requires() []string { return p.requires }
+
+func (p *testPlugin) Register(app *App) error {
+	if p.route != "" {
+		if _, err := app.Route("GET", p.route, func(ctx *router.Context) (string, error) {
+			return p.name, nil
+		}); err != nil {
+			return err
+		}
+	}
+	if p.fn != "" {
+		if err := app.Func(p.fn, func() string { return p.name }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *testPlugin) Close() error {
+	if p.closed != nil {
+		*p.closed = append(*p.closed, p.name)
+	}
+	return nil
+}
+
+func newHost() *Host {
+	return NewHost(router.New(), nil, render.New(), nil)
+}
+
+func TestRouteConflict(t *testing.T) {
+	h := newHost()
+	err := h.Register(
+		&testPlugin{name: "auth", route: "/login"},
+		&testPlugin{name: "sso", route: "/login"},
+	)
+	if err == nil || !strings.Contains(err.Error(), `plugin "sso"`) || !strings.Contains(err.Error(), `conflicts with plugin "auth"`) {
+		t.Fatalf("expected route conflict, got %v", err)
+	}
+	if len(h.Plugins()) != 1 {
+		t.Errorf("expected only the first plugin registered, got %d", len(h.Plugins()))
+	}
+
+	// The first plugin's route is served
+	w := httptest.NewRecorder()
+	h.Router.ServeHTTP(w, httptest.NewRequest("GET", "/login", nil))
+	if w.Body.String() != "auth" {
+		t.Errorf("expected auth handler, got %q", w.Body.String())
+	}
+}
+
+func TestConflictWithApp(t *testing.T) {
+	h := newHost()
+	h.Router.GET("/login", func(ctx *router.Context) (string, error) { return "", nil })
+
+	err := h.Register(&testPlugin{name: "auth", route: "/login"})
+	if err == nil || !strings.Contains(err.Error(), "already registered") {
+		t.Errorf("expected conflict with app route, got %v", err)
+	}
+
+	err = h.Register(&testPlugin{name: "dates", fn: "upper"})
+	if err == nil || !strings.Contains(err.Error(), `template func "upper"`) {
+		t.Errorf("expected conflict with default func, got %v", err)
+	}
+}
+
+func TestFuncConflict(t *testing.T) {
+	h := newHost()
+	if err := h.Register(&testPlugin{name: "a", fn: "greet"}); err != nil {
+		t.Fatal(err)
+	}
+	err := h.Register(&testPlugin{name: "b", fn: "greet"})
+	if err == nil || !strings.Contains(err.Error(), `conflicts with plugin "a"`) {
+		t.Errorf("expected func conflict, got %v", err)
+	}
+
+	if err := h.Engine.Parse("t", "{{greet}}"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := h.Engine.Render("t", nil); got != "a" {
+		t.Errorf("expected plugin func, got %q", got)
+	}
+}
+
+func TestRegisterOrder(t *testing.T) {
+	h := newHost()
+	err := h.Register(&testPlugin{name: "dashboard", requires: []string{"auth"}})
+	if err == nil || !strings.Contains(err.Error(), `requires plugin "auth"`) {
+		t.Errorf("expected missing requirement, got %v", err)
+	}
+
+	var closed []string
+	err = h.Register(
+		&testPlugin{name: "auth", closed: &closed},
+		&testPlugin{name: "dashboard", requires: []string{"auth"}, closed: &closed},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"", "a/b", "auth"} {
+		if err := h.Register(&testPlugin{name: name}); err == nil {
+			t.Errorf("expected error for name %q", name)
+		}
+	}
+
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(closed, []string{"dashboard", "auth"}) {
+		t.Errorf("expected reverse close order, got %q", closed)
+	}
+}
+
+type kvPlugin struct {
+	name string
+	app  *App
+}
+
+func (p *kvPlugin) Name() string { return p.name }
+func (p *kvPlugin) Register(app *App) error {
+	p.app = app
+	return nil
+}
+
+func TestKVNamespacing(t *testing.T) {
+	h := newHost()
+	a, b := &kvPlugin{name: "a"}, &kvPlugin{name: "b"}
+	if err := h.Register(a, b); err != nil {
+		t.Fatal(err)
+	}
+
+	a.app.KV().Set("count", []byte("1"))
+	b.app.KV().Set("count", []byte("2"))
+	b.app.KV().Set("total", []byte("3"))
+
+	if v, _ := a.app.KV().Get("count"); string(v) != "1" {
+		t.Errorf("expected a's value, got %q", v)
+	}
+	if keys := b.app.KV().Keys(""); !reflect.DeepEqual(keys, []string{"count", "total"}) {
+		t.Errorf("expected b's keys, got %q", keys)
+	}
+	a.app.KV().Delete("count")
+	if _, ok := a.app.KV().Get("count"); ok {
+		t.Error("expected key to be deleted")
+	}
+	if _, ok := b.app.KV().Get("count"); !ok {
+		t.Error("expected b's key to be kept")
+	}
+}
+
+type debugPlugin struct{}
+
+func (debugPlugin) Name() string { return "inspect" }
+func (debugPlugin) Register(app *App) error {
+	return app.DebugPage("/state", func(ctx *router.Context) (string, error) {
+		return "state", nil
+	})
+}
+
+func TestDebugPages(t *testing.T) {
+	h := newHost()
+	if err := h.Register(debugPlugin{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(h.DebugPages()) != 0 {
+		t.Errorf("expected no debug pages outside debug mode, got %q", h.DebugPages())
+	}
+
+	cfg := config.Default()
+	cfg.Debug = true
+	h = NewHost(router.New(), nil, render.New(), &cfg)
+	if err := h.Register(debugPlugin{}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(h.DebugPages(), []string{"/_debug/inspect/state"}) {
+		t.Errorf("unexpected debug pages %q", h.DebugPages())
+	}
+	w := httptest.NewRecorder()
+	h.Router.ServeHTTP(w, httptest.NewRequest("GET", "/_debug/inspect/state", nil))
+	if w.Body.String() != "state" {
+		t.Errorf("expected debug page, got %q", w.Body.String())
+	}
+}
+
+type failingPlugin struct{}
+
+func (failingPlugin) Name() string            { return "broken" }
+func (failingPlugin) Register(app *App) error { return errBroken }
+
+var errBroken = errors.New("broken")
+
+func TestRegisterError(t *testing.T) {
+	h := newHost()
+	err := h.Register(failingPlugin{}, &testPlugin{name: "after"})
+	if !errors.Is(err, errBroken) {
+		t.Errorf("expected wrapped error, got %v", err)
+	}
+	if len(h.Plugins()) != 0 {
+		t.Errorf("expected no plugins registered, got %d", len(h.Plugins()))
+	}
+}
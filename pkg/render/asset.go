@@ -0,0 +1,212 @@
+package render
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"maps"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+// AssetVersionParam is the query parameter AssetURL adds.
+const AssetVersionParam = "v"
+
+var (
+	assetMu     sync.RWMutex
+	assetHashes = map[string]string{} // URL path → content hash
+)
+
+// RegisterAssets records content hashes by URL path for AssetURL and the
+// asset template function. router.StaticFS calls it when cache busting is
+// enabled.
+func RegisterAssets(hashes map[string]string) {
+	assetMu.Lock()
+	defer assetMu.Unlock()
+	for p, h := range hashes {
+		assetHashes[p] = h
+	}
+}
+
+// AssetURL returns path with its content hash appended, e.g.
+// "/static/app.css?v=3f2a9c1e", so the URL changes whenever the file does
+// and can be cached forever. Paths with no registered hash are returned
+// unchanged. Available in templates as {{asset "/static/app.css"}}.
+func AssetURL(path string) string {
+	assetMu.RLock()
+	hash, ok := assetHashes[path]
+	assetMu.RUnlock()
+	if !ok {
+		return path
+	}
+	return path + "?" + AssetVersionParam + "=" + url.QueryEscape(hash)
+}
+
+// AssetResolver resolves paths of files in a static directory to
+// cache-busting URLs for the asset template function. Content hashes are
+// computed once, when it is created:
+//
+//	assets, err := render.NewAssetResolver("/static", os.DirFS("static"))
+//	engine.AddFuncs(assets.Funcs())
+//	r.StaticFS("/static", os.DirFS("static"), router.StaticAssets(assets))
+//
+// {{asset "css/output.css"}} then renders "/static/css/output.css?v=3f2a9c1e".
+// In manifest mode (see UseManifest) it renders the hashed file name a
+// bundler emitted instead, e.g. "/static/css/output-5d41402a.css".
+type AssetResolver struct {
+	prefix string // URL path the files are served under
+	fsys   fs.FS
+	hashes map[string]string // File path → content hash
+
+	mu       sync.RWMutex
+	manifest map[string]string // Logical path → hashed file path
+	outputs  map[string]bool   // Hashed file paths from the manifest
+	warned   map[string]bool   // Missing paths already logged
+}
+
+// NewAssetResolver hashes every file in fsys, which is served under the
+// URL path prefix. Returns an error if fsys can't be read.
+func NewAssetResolver(prefix string, fsys fs.FS) (*AssetResolver, error) {
+	hashes, err := HashFiles(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &AssetResolver{
+		prefix: strings.TrimSuffix(prefix, "/"),
+		fsys:   fsys,
+		hashes: hashes,
+		warned: make(map[string]bool),
+	}, nil
+}
+
+// NewAssetResolverDir is NewAssetResolver for a directory on disk.
+func NewAssetResolverDir(prefix, dir string) (*AssetResolver, error) {
+	return NewAssetResolver(prefix, os.DirFS(dir))
+}
+
+// UseManifest switches to manifest mode, reading the manifest file name
+// from the resolver's files. It maps the paths templates use to the
+// hashed file names a build emitted, either as plain strings or as
+// objects with a "file" field (as written by Vite):
+//
+//	{"css/output.css": "css/output-5d41402a.css"}
+//	{"src/main.ts": {"file": "assets/main-4b1e0c2d.js"}}
+//
+// Paths missing from the manifest fall back to the query string hash.
+func (a *AssetResolver) UseManifest(name string) error {
+	data, err := fs.ReadFile(a.fsys, name)
+	if err != nil {
+		return err
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("asset manifest %s: %w", name, err)
+	}
+	manifest := make(map[string]string, len(raw))
+	outputs := make(map[string]bool, len(raw))
+	for logical, value := range raw {
+		var file string
+		if err := json.Unmarshal(value, &file); err != nil {
+			var entry struct {
+				File string `json:"file"`
+			}
+			if err := json.Unmarshal(value, &entry); err != nil || entry.File == "" {
+				return fmt.Errorf("asset manifest %s: entry %q has no file", name, logical)
+			}
+			file = entry.File
+		}
+		file = strings.TrimPrefix(file, "/")
+		manifest[strings.TrimPrefix(logical, "/")] = file
+		outputs[file] = true
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.manifest, a.outputs = manifest, outputs
+	return nil
+}
+
+// URL returns the cache-busting URL for the file at p, relative to the
+// resolver's directory; a leading "/" or the URL prefix is ignored. A
+// file that doesn't exist gets its plain URL, and a warning is logged
+// the first time.
+func (a *AssetResolver) URL(p string) string {
+	name := strings.TrimPrefix(strings.TrimPrefix(p, a.prefix+"/"), "/")
+
+	a.mu.RLock()
+	hashed, inManifest := a.manifest[name]
+	a.mu.RUnlock()
+	if inManifest {
+		return a.prefix + "/" + hashed
+	}
+	if hash, ok := a.hashes[name]; ok {
+		return a.prefix + "/" + name + "?" + AssetVersionParam + "=" + url.QueryEscape(hash)
+	}
+
+	a.mu.Lock()
+	if !a.warned[name] {
+		a.warned[name] = true
+		logf("asset %q not found under %s, linking it without a version", name, a.prefix)
+	}
+	a.mu.Unlock()
+	return a.prefix + "/" + name
+}
+
+// Funcs returns the asset template function bound to the resolver, to
+// replace the default one with Engine.AddFuncs.
+func (a *AssetResolver) Funcs() template.FuncMap {
+	return template.FuncMap{"asset": a.URL}
+}
+
+// FS returns the files the resolver hashed.
+func (a *AssetResolver) FS() fs.FS {
+	return a.fsys
+}
+
+// Hashes returns a copy of the content hash of every file, keyed by
+// slash-separated path.
+func (a *AssetResolver) Hashes() map[string]string {
+	return maps.Clone(a.hashes)
+}
+
+// Immutable reports whether a request for file name with the given
+// version parameter can be cached forever: the version matches the
+// file's current hash, or the file is a hashed build output listed in the
+// manifest.
+func (a *AssetResolver) Immutable(name, version string) bool {
+	if hash, ok := a.hashes[name]; ok && version == hash {
+		return true
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.outputs[name]
+}
+
+// HashFiles returns a short content hash of every regular file in fsys,
+// keyed by slash-separated path.
+func HashFiles(fsys fs.FS) (map[string]string, error) {
+	hashes := make(map[string]string)
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		f, err := fsys.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		sum := sha256.New()
+		if _, err := io.Copy(sum, f); err != nil {
+			return err
+		}
+		hashes[name] = hex.EncodeToString(sum.Sum(nil)[:8])
+		return nil
+	})
+	return hashes, err
+}
@@ -0,0 +1,151 @@
+package render
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestAssetURL(t *testing.T) {
+	RegisterAssets(map[string]string{"/static/site.css": "abc123"})
+
+	e := New()
+	if err := e.Parse("page", `{{asset "/static/site.css"}} {{asset "/static/other.js"}}`); err != nil {
+		t.Fatal(err)
+	}
+	got, err := e.Render("page", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "/static/site.css?v=abc123 /static/other.js"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func assetFiles() fstest.MapFS {
+	return fstest.MapFS{
+		"css/output.css":          {Data: []byte("body{}")},
+		"css/output-5d41402a.css": {Data: []byte("body{}")},
+		"js/app.js":               {Data: []byte("console.log(1)")},
+		"manifest.json":           {Data: []byte(`{"css/output.css": "css/output-5d41402a.css", "/src/main.ts": {"file": "js/main-4b1e.js"}}`)},
+	}
+}
+
+func TestAssetResolver(t *testing.T) {
+	assets, err := NewAssetResolver("/static/", assetFiles())
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := assets.Hashes()["css/output.css"]
+	if len(hash) != 16 {
+		t.Fatalf("hash = %q", hash)
+	}
+
+	want := "/static/css/output.css?v=" + hash
+	for _, p := range []string{"css/output.css", "/css/output.css", "/static/css/output.css"} {
+		if got := assets.URL(p); got != want {
+			t.Errorf("URL(%q) = %q, want %q", p, got, want)
+		}
+	}
+
+	e := New()
+	e.AddFuncs(assets.Funcs())
+	if err := e.Parse("head", `<link href="{{asset "css/output.css"}}">`); err != nil {
+		t.Fatal(err)
+	}
+	html, err := e.Render("head", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if html != `<link href="`+want+`">` {
+		t.Errorf("rendered %s", html)
+	}
+
+	if !assets.Immutable("css/output.css", hash) || assets.Immutable("css/output.css", "old") {
+		t.Error("Immutable should match only the current hash")
+	}
+}
+
+func TestAssetResolverManifest(t *testing.T) {
+	assets, err := NewAssetResolver("/static", assetFiles())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := assets.UseManifest("manifest.json"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		"css/output.css": "/static/css/output-5d41402a.css",
+		"src/main.ts":    "/static/js/main-4b1e.js",
+		// Not in the manifest: the query string hash
+		"js/app.js": "/static/js/app.js?v=" + assets.Hashes()["js/app.js"],
+	}
+	for p, want := range tests {
+		if got := assets.URL(p); got != want {
+			t.Errorf("URL(%q) = %q, want %q", p, got, want)
+		}
+	}
+	if !assets.Immutable("css/output-5d41402a.css", "") {
+		t.Error("hashed manifest output should be immutable")
+	}
+	if assets.Immutable("js/app.js", "") {
+		t.Error("unversioned request should not be immutable")
+	}
+
+	for name, manifest := range map[string]string{
+		"invalid JSON": `{`,
+		"no file":      `{"a.css": {"src": "a.css"}}`,
+	} {
+		files := assetFiles()
+		files["bad.json"] = &fstest.MapFile{Data: []byte(manifest)}
+		bad, _ := NewAssetResolver("/static", files)
+		if err := bad.UseManifest("bad.json"); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+	if err := assets.UseManifest("missing.json"); err == nil {
+		t.Error("expected error for a missing manifest")
+	}
+}
+
+func TestAssetResolverMissing(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	assets, err := NewAssetResolver("/static", assetFiles())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		if got := assets.URL("img/logo.png"); got != "/static/img/logo.png" {
+			t.Errorf("URL = %q, want the plain path", got)
+		}
+	}
+	if n := strings.Count(logs.String(), `asset "img/logo.png" not found`); n != 1 {
+		t.Errorf("logged %d warnings, want 1:\n%s", n, logs.String())
+	}
+}
+
+func TestNewAssetResolverDir(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "css"), 0o755)
+	if err := os.WriteFile(filepath.Join(dir, "css", "site.css"), []byte("p{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	assets, err := NewAssetResolverDir("/assets", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := assets.URL("css/site.css"); !strings.HasPrefix(got, "/assets/css/site.css?v=") {
+		t.Errorf("URL = %q", got)
+	}
+	if _, err := NewAssetResolverDir("/assets", filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected error for a missing directory")
+	}
+}
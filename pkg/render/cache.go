@@ -0,0 +1,207 @@
+package render
+
+import (
+	"container/list"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/a-h/templ"
+)
+
+const (
+	// defaultCacheEntries and defaultCacheBytes cap the fragment cache
+	// until SetCacheLimits changes them.
+	defaultCacheEntries = 1024
+	defaultCacheBytes   = 8 << 20
+)
+
+// fragments is the cache behind Cached and TemplRenderer.RenderCached.
+var fragments = &fragmentCache{
+	maxEntries: defaultCacheEntries,
+	maxBytes:   defaultCacheBytes,
+	lru:        list.New(),
+	entries:    make(map[string]*list.Element),
+}
+
+type fragmentCache struct {
+	hits, misses atomic.Uint64
+
+	mu         sync.Mutex
+	maxEntries int
+	maxBytes   int
+	bytes      int
+	gen        uint64     // Bumped by each invalidation
+	lru        *list.List // Front = most recently used
+	entries    map[string]*list.Element
+}
+
+type fragmentEntry struct {
+	key     string
+	html    string
+	expires time.Time // Zero for no expiry
+}
+
+// CacheStats reports the fragment cache's activity, for debugging.
+type CacheStats struct {
+	Hits    uint64
+	Misses  uint64
+	Entries int
+	Bytes   int
+}
+
+// Cached returns the HTML cached under key, or renders it with fn and
+// caches it for ttl (until evicted or invalidated if ttl is 0). It suits
+// fragments that render identically many times, like country selects,
+// icon sets and static menus:
+//
+//	html, err := render.Cached(render.CacheKey("countries", selected), time.Hour, func() (string, error) {
+//		return engine.Render("fragments/countries", selected)
+//	})
+//
+// key must identify everything the HTML depends on. Errors are returned
+// and never cached. The least recently used fragments are evicted past
+// the limits set with SetCacheLimits, 1024 entries or 8MB by default.
+func Cached(key string, ttl time.Duration, fn func() (string, error)) (string, error) {
+	if html, ok := fragments.get(key); ok {
+		fragments.hits.Add(1)
+		return html, nil
+	}
+	fragments.misses.Add(1)
+
+	gen := fragments.generation()
+	html, err := fn()
+	if err != nil {
+		return "", err
+	}
+	fragments.put(key, html, ttl, gen)
+	return html, nil
+}
+
+// RenderCached renders component like Render, caching the result under
+// key until evicted or invalidated (see Cached). key must identify the
+// component and its arguments.
+func (r *TemplRenderer) RenderCached(key string, component templ.Component) (string, error) {
+	return Cached(key, 0, func() (string, error) {
+		return r.Render(component)
+	})
+}
+
+// CacheKey joins a component's name and arguments into a cache key, so
+// InvalidateCache(name) drops every variant:
+//
+//	render.CacheKey("menu", user.Role, locale) // "menu:admin:en"
+func CacheKey(name string, args ...any) string {
+	var b strings.Builder
+	b.WriteString(name)
+	for _, arg := range args {
+		b.WriteByte(':')
+		fmt.Fprint(&b, arg)
+	}
+	return b.String()
+}
+
+// InvalidateCache drops the cached fragments whose keys start with
+// prefix; "" drops them all. Renders in progress aren't cached, as they
+// may predate the change.
+func InvalidateCache(prefix string) {
+	fragments.invalidate(prefix)
+}
+
+// SetCacheLimits sets how many fragments the cache holds and their
+// total size in bytes, evicting the least recently used to fit.
+// Fragments larger than maxBytes aren't cached.
+func SetCacheLimits(maxEntries, maxBytes int) {
+	c := fragments
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.maxEntries, c.maxBytes = maxEntries, maxBytes
+	c.evict()
+}
+
+// FragmentCacheStats returns the fragment cache's hit and miss counts
+// and current size.
+func FragmentCacheStats() CacheStats {
+	c := fragments
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return CacheStats{
+		Hits:    c.hits.Load(),
+		Misses:  c.misses.Load(),
+		Entries: c.lru.Len(),
+		Bytes:   c.bytes,
+	}
+}
+
+func (c *fragmentCache) get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return "", false
+	}
+	e := el.Value.(*fragmentEntry)
+	if !e.expires.IsZero() && timeNow().After(e.expires) {
+		c.remove(el)
+		return "", false
+	}
+	c.lru.MoveToFront(el)
+	return e.html, true
+}
+
+// put stores html unless the cache was invalidated since gen, when it
+// may predate the change.
+func (c *fragmentCache) put(key, html string, ttl time.Duration, gen uint64) {
+	e := &fragmentEntry{key: key, html: html}
+	if ttl > 0 {
+		e.expires = timeNow().Add(ttl)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.gen != gen || len(html) > c.maxBytes {
+		return
+	}
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+	}
+	c.entries[key] = c.lru.PushFront(e)
+	c.bytes += len(html)
+	c.evict()
+}
+
+func (c *fragmentCache) generation() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.gen
+}
+
+func (c *fragmentCache) invalidate(prefix string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	for el := c.lru.Front(); el != nil; {
+		next := el.Next()
+		if strings.HasPrefix(el.Value.(*fragmentEntry).key, prefix) {
+			c.remove(el)
+		}
+		el = next
+	}
+}
+
+// evict drops the least recently used entries past the limits. Must be
+// called with c.mu held.
+func (c *fragmentCache) evict() {
+	for c.lru.Len() > 0 && (c.lru.Len() > c.maxEntries || c.bytes > c.maxBytes) {
+		c.remove(c.lru.Back())
+	}
+}
+
+// remove deletes an entry. Must be called with c.mu held.
+func (c *fragmentCache) remove(el *list.Element) {
+	e := c.lru.Remove(el).(*fragmentEntry)
+	delete(c.entries, e.key)
+	c.bytes -= len(e.html)
+}
@@ -0,0 +1,221 @@
+package render
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/a-h/templ"
+)
+
+// resetCache empties the fragment cache and restores its limits when the
+// test ends.
+func resetCache(t *testing.T) {
+	t.Helper()
+	InvalidateCache("")
+	t.Cleanup(func() {
+		SetCacheLimits(defaultCacheEntries, defaultCacheBytes)
+		InvalidateCache("")
+	})
+}
+
+// counter returns a render func that counts its calls.
+func counter(html string, calls *int) func() (string, error) {
+	return func() (string, error) {
+		*calls++
+		return html, nil
+	}
+}
+
+func TestCached(t *testing.T) {
+	resetCache(t)
+	before := FragmentCacheStats()
+
+	calls := 0
+	for range 3 {
+		html, err := Cached("countries", 0, counter("<select></select>", &calls))
+		if err != nil || html != "<select></select>" {
+			t.Fatalf("Cached = %q, %v", html, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("rendered %d times, want 1", calls)
+	}
+
+	stats := FragmentCacheStats()
+	if hits, misses := stats.Hits-before.Hits, stats.Misses-before.Misses; hits != 2 || misses != 1 {
+		t.Errorf("hits, misses = %d, %d, want 2, 1", hits, misses)
+	}
+	if stats.Entries != 1 || stats.Bytes != len("<select></select>") {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestCachedErrorsNotCached(t *testing.T) {
+	resetCache(t)
+
+	boom := errors.New("boom")
+	calls := 0
+	fail := func() (string, error) {
+		calls++
+		return "partial", boom
+	}
+	for range 2 {
+		if html, err := Cached("menu", time.Minute, fail); !errors.Is(err, boom) || html != "" {
+			t.Errorf("Cached = %q, %v, want the error", html, err)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("rendered %d times, want the error retried", calls)
+	}
+
+	// The next successful render is cached
+	ok := 0
+	Cached("menu", time.Minute, counter("<nav></nav>", &ok))
+	Cached("menu", time.Minute, counter("<nav></nav>", &ok))
+	if ok != 1 || FragmentCacheStats().Entries != 1 {
+		t.Errorf("rendered %d times after the error, want 1", ok)
+	}
+}
+
+func TestInvalidateCache(t *testing.T) {
+	resetCache(t)
+
+	calls := map[string]*int{}
+	renderKey := func(key string) {
+		if calls[key] == nil {
+			calls[key] = new(int)
+		}
+		Cached(key, 0, counter(key, calls[key]))
+	}
+	keys := []string{CacheKey("menu", "admin"), CacheKey("menu", "user"), CacheKey("icons"), "menus"}
+	for _, key := range keys {
+		renderKey(key)
+	}
+
+	InvalidateCache("menu:")
+	for _, key := range keys {
+		renderKey(key)
+	}
+	want := map[string]int{"menu:admin": 2, "menu:user": 2, "icons": 1, "menus": 1}
+	for key, n := range want {
+		if *calls[key] != n {
+			t.Errorf("%s rendered %d times, want %d", key, *calls[key], n)
+		}
+	}
+
+	InvalidateCache("")
+	if n := FragmentCacheStats().Entries; n != 0 {
+		t.Errorf("%d entries after invalidating everything", n)
+	}
+}
+
+func TestCachedSkipsInvalidatedRenders(t *testing.T) {
+	resetCache(t)
+
+	// Data changed while the fragment rendered, so it may be stale
+	Cached("menu", 0, func() (string, error) {
+		InvalidateCache("menu")
+		return "stale", nil
+	})
+	calls := 0
+	if html, _ := Cached("menu", 0, counter("fresh", &calls)); html != "fresh" || calls != 1 {
+		t.Errorf("got %q after invalidation, want a fresh render", html)
+	}
+}
+
+func TestCachedTTL(t *testing.T) {
+	resetCache(t)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	t.Cleanup(func() { timeNow = time.Now })
+
+	calls := 0
+	Cached("clock", time.Minute, counter("x", &calls))
+	now = now.Add(59 * time.Second)
+	Cached("clock", time.Minute, counter("x", &calls))
+	if calls != 1 {
+		t.Fatalf("rendered %d times within the ttl", calls)
+	}
+	now = now.Add(2 * time.Second)
+	Cached("clock", time.Minute, counter("x", &calls))
+	if calls != 2 {
+		t.Errorf("rendered %d times, want the expired entry re-rendered", calls)
+	}
+}
+
+func TestCacheLimits(t *testing.T) {
+	resetCache(t)
+	SetCacheLimits(2, 10)
+
+	calls := 0
+	Cached("a", 0, counter("aaaa", &calls))
+	Cached("b", 0, counter("bbbb", &calls))
+	Cached("a", 0, counter("aaaa", &calls)) // a is now most recent
+	Cached("c", 0, counter("cccc", &calls)) // evicts b
+	if stats := FragmentCacheStats(); stats.Entries != 2 || stats.Bytes != 8 {
+		t.Errorf("stats = %+v, want 2 entries of 8 bytes", stats)
+	}
+
+	calls = 0
+	Cached("a", 0, counter("aaaa", &calls))
+	Cached("b", 0, counter("bbbb", &calls))
+	if calls != 1 {
+		t.Errorf("rendered %d, want only the evicted b", calls)
+	}
+
+	// Over the byte limit evicts, and too large isn't cached at all
+	Cached("big", 0, counter("0123456789", &calls))
+	if stats := FragmentCacheStats(); stats.Entries != 1 || stats.Bytes != 10 {
+		t.Errorf("stats = %+v, want only big", stats)
+	}
+	Cached("huge", 0, counter(strings.Repeat("x", 11), &calls))
+	if stats := FragmentCacheStats(); stats.Entries != 1 || stats.Bytes != 10 {
+		t.Errorf("stats = %+v, want huge left out", stats)
+	}
+
+	SetCacheLimits(0, 10)
+	if n := FragmentCacheStats().Entries; n != 0 {
+		t.Errorf("%d entries after shrinking to 0", n)
+	}
+}
+
+func TestRenderCached(t *testing.T) {
+	resetCache(t)
+
+	calls := 0
+	icons := templ.ComponentFunc(func(_ context.Context, w io.Writer) error {
+		calls++
+		_, err := io.WriteString(w, "<svg></svg>")
+		return err
+	})
+	r := NewTemplRenderer()
+	for range 3 {
+		if html, err := r.RenderCached("icons", icons); err != nil || html != "<svg></svg>" {
+			t.Fatalf("RenderCached = %q, %v", html, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("rendered %d times, want 1", calls)
+	}
+
+	failing := templ.ComponentFunc(func(context.Context, io.Writer) error { return errors.New("boom") })
+	if _, err := r.RenderCached("broken", failing); err == nil {
+		t.Error("expected the render error")
+	}
+	if _, err := r.RenderCached("broken", textComponent("ok")); err != nil {
+		t.Errorf("error was cached: %v", err)
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	if got := CacheKey("menu", "admin", 3, true); got != "menu:admin:3:true" {
+		t.Errorf("got %q", got)
+	}
+	if got := CacheKey("icons"); got != "icons" {
+		t.Errorf("got %q", got)
+	}
+}
@@ -0,0 +1,41 @@
+package render
+
+import (
+	"context"
+	"html/template"
+)
+
+// CSRFField is the form field carrying the CSRF token.
+const CSRFField = "_csrf"
+
+type csrfKey struct{}
+
+// WithCSRFToken returns a context carrying the request's CSRF token.
+func WithCSRFToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, csrfKey{}, token)
+}
+
+// CSRFToken returns the CSRF token stored by router.CSRFMiddleware,
+// or "" if there is none. Use it in templ components:
+//
+//	<input type="hidden" name="_csrf" value={ render.CSRFToken(ctx) }/>
+func CSRFToken(ctx context.Context) string {
+	token, _ := ctx.Value(csrfKey{}).(string)
+	return token
+}
+
+// csrfToken returns the CSRF token from a request context,
+// e.g. {{csrfToken .Ctx}}
+func csrfToken(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	return CSRFToken(ctx)
+}
+
+// csrfField generates a hidden input carrying the CSRF token,
+// e.g. {{csrfField .Ctx}}
+func csrfField(ctx context.Context) template.HTML {
+	return template.HTML(`<input type="hidden" name="` + CSRFField + `" value="` +
+		template.HTMLEscapeString(csrfToken(ctx)) + `">`)
+}
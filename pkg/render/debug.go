@@ -0,0 +1,147 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SetDebug makes render errors locate the failure in the template's
+// source: TemplateError gets the source name and line, and SourceSnippet
+// returns the surrounding lines. Helpers that would otherwise drop a
+// value they can't render, such as dsSignalsJSON, write the error into
+// the page as a data-signals-error attribute. It is meant for development.
+func (e *Engine) SetDebug(on bool) {
+	e.mu.Lock()
+	e.debug = on
+	e.mu.Unlock()
+
+	if on {
+		e.AddFunc("dsSignalsJSON", dsSignalsJSONDebug)
+	} else {
+		e.AddFunc("dsSignalsJSON", dsSignalsJSON)
+	}
+}
+
+// execError wraps an error from executing template name, locating it in
+// the source in debug mode. Must be called with e.mu held.
+func (e *Engine) execError(name string, err error) *TemplateError {
+	te := &TemplateError{Name: name, Err: err}
+	if !e.debug {
+		return te
+	}
+	te.Source, te.Line = errorLocation(err)
+	if text, ok := e.source[te.Source]; ok && te.Line > 0 {
+		te.snippet = sourceSnippet(text, te.Line)
+	}
+	return te
+}
+
+// execLocation matches the position text/template puts in execution
+// errors, e.g. `template: home.html:3:14: executing "home" at <.User.Name>`.
+var execLocation = regexp.MustCompile(`template: (.*?):(\d+):\d+: executing `)
+
+// errorLocation returns the source name and line an execution or
+// escaping error reports, or "" and 0.
+func errorLocation(err error) (string, int) {
+	var escErr *template.Error
+	if errors.As(err, &escErr) && escErr.Name != "" && escErr.Line > 0 {
+		return escErr.Name, escErr.Line
+	}
+	if m := execLocation.FindStringSubmatch(err.Error()); m != nil {
+		line, _ := strconv.Atoi(m[2])
+		return m[1], line
+	}
+	return "", 0
+}
+
+// sourceSnippet returns line of text with the lines either side,
+// numbered, the failing one marked with ">":
+//
+//	  2 | <h1>Profile</h1>
+//	> 3 | <p>{{.User.Name}}</p>
+//	  4 | </div>
+func sourceSnippet(text string, line int) string {
+	lines := strings.Split(text, "\n")
+	if line > len(lines) {
+		return ""
+	}
+	first, last := max(line-1, 1), min(line+1, len(lines))
+	width := len(strconv.Itoa(last))
+	var b strings.Builder
+	for n := first; n <= last; n++ {
+		marker := " "
+		if n == line {
+			marker = ">"
+		}
+		fmt.Fprintf(&b, "%s %*d | %s\n", marker, width, n, strings.TrimRight(lines[n-1], "\r"))
+	}
+	return b.String()
+}
+
+// recordSource keeps the text parsed under name for error snippets. Must
+// be called with e.mu held for writing, from a parseFunc.
+func (e *Engine) recordSource(name, text string) {
+	if e.pending != nil {
+		e.pending[name] = text
+	}
+}
+
+// parseFS parses files matching patterns in fsys into t, like
+// template.ParseFS, recording their source.
+func (e *Engine) parseFS(t *template.Template, fsys fs.FS, patterns ...string) (*template.Template, error) {
+	var files []string
+	for _, pattern := range patterns {
+		matches, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("html/template: pattern matches no files: %#q", pattern)
+		}
+		files = append(files, matches...)
+	}
+	return e.parseFiles(t, files, path.Base, func(name string) ([]byte, error) {
+		return fs.ReadFile(fsys, name)
+	})
+}
+
+// parseGlob parses the files matching pattern into t, like
+// template.ParseGlob, recording their source.
+func (e *Engine) parseGlob(t *template.Template, pattern string) (*template.Template, error) {
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("html/template: pattern matches no files: %#q", pattern)
+	}
+	return e.parseFiles(t, files, filepath.Base, os.ReadFile)
+}
+
+// parseFiles parses each file as a template named after its base name, as
+// template.ParseFiles does, recording its source.
+func (e *Engine) parseFiles(t *template.Template, files []string, base func(string) string, read func(string) ([]byte, error)) (*template.Template, error) {
+	if len(files) == 0 {
+		return nil, fmt.Errorf("html/template: no files named in call to ParseFiles")
+	}
+	for _, file := range files {
+		b, err := read(file)
+		if err != nil {
+			return nil, err
+		}
+		name, text := base(file), string(b)
+		if _, err := t.New(name).Parse(text); err != nil {
+			return nil, err
+		}
+		e.recordSource(name, text)
+	}
+	return t, nil
+}
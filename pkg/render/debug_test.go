@@ -0,0 +1,149 @@
+package render
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+type profile struct {
+	User *struct{ Name string }
+}
+
+const profileSource = `<div>
+<h1>Profile</h1>
+<p>{{.User.Name}}</p>
+</div>`
+
+func TestTemplateErrorSnippet(t *testing.T) {
+	e := New()
+	e.SetDebug(true)
+	if err := e.Parse("pages/profile", profileSource); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := e.Render("pages/profile", profile{})
+	var te *TemplateError
+	if !errors.As(err, &te) {
+		t.Fatalf("error = %v, want TemplateError", err)
+	}
+	if te.Source != "pages/profile" || te.Line != 3 {
+		t.Errorf("location = %s:%d, want pages/profile:3", te.Source, te.Line)
+	}
+	want := "  2 | <h1>Profile</h1>\n> 3 | <p>{{.User.Name}}</p>\n  4 | </div>\n"
+	if got := te.SourceSnippet(); got != want {
+		t.Errorf("snippet:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestTemplateErrorSnippetFromFiles(t *testing.T) {
+	fsys := fstest.MapFS{
+		"pages/profile.html": {Data: []byte(profileSource)},
+		"partials/name.html": {Data: []byte(`{{define "name"}}
+<span>{{.User.Name}}</span>
+{{end}}`)},
+		"pages/card.html": {Data: []byte(`<div class="card">{{template "name" .}}</div>`)},
+	}
+	e := New()
+	e.SetDebug(true)
+	if err := e.LoadFS(fsys, "pages/*.html", "partials/*.html"); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := e.Render("profile.html", profile{})
+	var te *TemplateError
+	if !errors.As(err, &te) || !strings.Contains(te.SourceSnippet(), "> 3 | <p>{{.User.Name}}</p>") {
+		t.Errorf("snippet = %q (err %v)", te.SourceSnippet(), err)
+	}
+
+	// The failure is reported where it happens, in the called template
+	_, err = e.Render("card.html", profile{})
+	if !errors.As(err, &te) || te.Source != "name.html" || te.Line != 2 {
+		t.Fatalf("location = %s:%d (err %v)", te.Source, te.Line, err)
+	}
+	if got := te.SourceSnippet(); !strings.Contains(got, "> 2 | <span>{{.User.Name}}</span>") {
+		t.Errorf("snippet = %q", got)
+	}
+}
+
+func TestTemplateErrorSnippetFromGlob(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "profile.html"), []byte(profileSource), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for name, load := range map[string]func(e *Engine) error{
+		"glob":  func(e *Engine) error { return e.LoadGlob(filepath.Join(dir, "*.html")) },
+		"files": func(e *Engine) error { return e.LoadFiles(filepath.Join(dir, "profile.html")) },
+	} {
+		e := New()
+		e.SetDebug(true)
+		if err := load(e); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		_, err := e.Render("profile.html", profile{})
+		var te *TemplateError
+		if !errors.As(err, &te) || !strings.Contains(te.SourceSnippet(), "{{.User.Name}}") {
+			t.Errorf("%s: snippet = %q (err %v)", name, te.SourceSnippet(), err)
+		}
+	}
+}
+
+func TestTemplateErrorSnippetNeedsDebug(t *testing.T) {
+	e := New()
+	if err := e.Parse("pages/profile", profileSource); err != nil {
+		t.Fatal(err)
+	}
+	_, err := e.Render("pages/profile", profile{})
+	var te *TemplateError
+	if !errors.As(err, &te) {
+		t.Fatalf("error = %v", err)
+	}
+	if te.SourceSnippet() != "" || te.Line != 0 {
+		t.Errorf("snippet collected outside debug mode: %q", te.SourceSnippet())
+	}
+
+	e.SetDebug(true)
+	_, err = e.Render("pages/profile", profile{})
+	if !errors.As(err, &te) || te.SourceSnippet() == "" {
+		t.Errorf("no snippet after SetDebug(true): %v", err)
+	}
+}
+
+func TestTemplateErrorSnippetEdges(t *testing.T) {
+	e := New()
+	e.SetDebug(true)
+	if err := e.Parse("one", `{{.Missing.Field}}`); err != nil {
+		t.Fatal(err)
+	}
+	_, err := e.Render("one", map[string]any{"Missing": 3})
+	var te *TemplateError
+	if !errors.As(err, &te) {
+		t.Fatalf("error = %v", err)
+	}
+	if got, want := te.SourceSnippet(), "> 1 | {{.Missing.Field}}\n"; got != want {
+		t.Errorf("snippet = %q, want %q", got, want)
+	}
+
+	// A failed load leaves the recorded source alone
+	if err := e.Parse("one", `{{if}}`); err == nil {
+		t.Fatal("expected parse error")
+	}
+	_, err = e.Render("one", map[string]any{"Missing": 3})
+	if !errors.As(err, &te) || !strings.Contains(te.SourceSnippet(), ".Missing.Field") {
+		t.Errorf("snippet after failed load = %q", te.SourceSnippet())
+	}
+}
+
+func TestSourceSnippet(t *testing.T) {
+	text := strings.Repeat("line\n", 9) + "tenth\neleventh"
+	want := "   9 | line\n> 10 | tenth\n  11 | eleventh\n"
+	if got := sourceSnippet(text, 10); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	if got := sourceSnippet("a", 5); got != "" {
+		t.Errorf("out of range line = %q", got)
+	}
+}
@@ -3,18 +3,69 @@ package render
 
 import (
 	"bytes"
+	"fmt"
 	"html/template"
+	"io"
 	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
 	"sync"
+	"unicode"
 )
 
 // Engine manages template parsing and rendering.
+//
+// Functions may be added and templates loaded in any order, including
+// after rendering has started: each change is made to a master set that
+// is never executed, and a fresh clone of it is swapped in for rendering.
 type Engine struct {
-	templates *template.Template
+	// base is the master template set. html/template can't be cloned,
+	// extended or given new funcs once executed, so only clones of it
+	// are rendered.
+	base      *template.Template
+	templates *template.Template // Clone of base used by Render
 	funcs     template.FuncMap
 	mu        sync.RWMutex
+
+	// fallback is a clone of base using FallbackFuncs, rebuilt whenever
+	// base changes.
+	fallback *template.Template
+
+	// smoke lists renders checked after SwapTemplates
+	smoke []smokeTest
+
+	// sources replays the loads that built base, so it can be re-parsed
+	// from scratch by Watch and dev mode (see watch.go).
+	sources []parseFunc
+	devMode bool
+	// reloadErr is the error from the last failed reload, returned by
+	// Render until a reload succeeds.
+	reloadErr error
+
+	// source holds the text of each parsed file or Parse call by name,
+	// for the snippets in debug mode (see debug.go). pending collects it
+	// during a load, and is merged in once the load succeeds.
+	source  map[string]string
+	pending map[string]string
+	debug   bool
+
+	// defaultLayout wraps Page (see layout.go)
+	defaultLayout string
+	layoutMu      sync.Mutex
+	layoutCache   layoutSets
+
+	// funcSets caches clones for RenderWithFuncs (see funcs.go)
+	funcSetsMu sync.Mutex
+	funcSets   funcSets
 }
 
+// parseFunc parses templates into a set, recording their source on the
+// engine it is given. Clones share parseFuncs, so they must not capture
+// the engine that created them.
+type parseFunc func(*Engine, *template.Template) (*template.Template, error)
+
 // New creates a new template engine with default functions.
 func New() *Engine {
 	e := &Engine{
@@ -23,77 +74,182 @@ func New() *Engine {
 	return e
 }
 
-// AddFunc registers a custom template function.
-// Must be called before loading templates.
-func (e *Engine) AddFunc(name string, fn any) {
-	e.mu.Lock()
-	defer e.mu.Unlock()
-	e.funcs[name] = fn
+// AddFunc registers a custom template function. It may be called after
+// templates are loaded; they see the new function from the next render,
+// so funcs like url and csrfToken can be wired in late in startup. It
+// returns an error, registering nothing, if fn can't be a template
+// function.
+func (e *Engine) AddFunc(name string, fn any) error {
+	return e.AddFuncs(template.FuncMap{name: fn})
 }
 
-// AddFuncs registers multiple template functions.
-func (e *Engine) AddFuncs(funcs template.FuncMap) {
+// AddFuncs registers multiple template functions, like AddFunc. If any
+// is invalid none are registered.
+func (e *Engine) AddFuncs(funcs template.FuncMap) error {
+	for name, fn := range funcs {
+		if err := checkFunc(name, fn); err != nil {
+			return err
+		}
+	}
 	e.mu.Lock()
 	defer e.mu.Unlock()
 	for name, fn := range funcs {
 		e.funcs[name] = fn
 	}
+	if e.base != nil {
+		e.base.Funcs(funcs)
+		e.publish()
+	}
+	return nil
 }
 
-// LoadFS loads templates from an embedded filesystem.
-func (e *Engine) LoadFS(fsys fs.FS, patterns ...string) error {
-	e.mu.Lock()
-	defer e.mu.Unlock()
+// Funcs returns a copy of the registered template functions.
+func (e *Engine) Funcs() template.FuncMap {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return maps.Clone(e.funcs)
+}
 
-	tmpl, err := template.New("").Funcs(e.funcs).ParseFS(fsys, patterns...)
-	if err != nil {
-		return err
+// checkFunc reports why fn can't be registered as template function
+// name, where template.Funcs would panic.
+func checkFunc(name string, fn any) error {
+	if !isIdentifier(name) {
+		return fmt.Errorf("template func %q: not a valid identifier", name)
+	}
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return fmt.Errorf("template func %q: %T is not a function", name, fn)
+	}
+	t := v.Type()
+	switch {
+	case t.NumOut() == 1:
+	case t.NumOut() == 2 && t.Out(1) == reflect.TypeFor[error]():
+	default:
+		return fmt.Errorf("template func %q: must return one value, or a value and an error", name)
 	}
-	e.templates = tmpl
 	return nil
 }
 
-// LoadGlob loads templates matching a glob pattern.
-func (e *Engine) LoadGlob(pattern string) error {
+// isIdentifier reports whether name can be called from a template.
+func isIdentifier(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		if r != '_' && !unicode.IsLetter(r) && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return true
+}
+
+// HasFunc reports whether a template function is registered under name.
+func (e *Engine) HasFunc(name string) bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	_, ok := e.funcs[name]
+	return ok
+}
+
+// LoadFS loads templates from an embedded filesystem, adding them to any
+// already loaded. A template with the same name as an existing one
+// replaces it.
+func (e *Engine) LoadFS(fsys fs.FS, patterns ...string) error {
 	e.mu.Lock()
 	defer e.mu.Unlock()
+	return e.update(func(e *Engine, t *template.Template) (*template.Template, error) {
+		return e.parseFS(t, fsys, patterns...)
+	})
+}
 
-	tmpl, err := template.New("").Funcs(e.funcs).ParseGlob(pattern)
-	if err != nil {
-		return err
-	}
-	e.templates = tmpl
-	return nil
+// LoadGlob loads templates matching a glob pattern, adding them to any
+// already loaded.
+func (e *Engine) LoadGlob(pattern string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.update(func(e *Engine, t *template.Template) (*template.Template, error) {
+		return e.parseGlob(t, pattern)
+	})
 }
 
-// LoadFiles loads specific template files.
+// LoadFiles loads specific template files, adding them to any already
+// loaded.
 func (e *Engine) LoadFiles(filenames ...string) error {
 	e.mu.Lock()
 	defer e.mu.Unlock()
-
-	tmpl, err := template.New("").Funcs(e.funcs).ParseFiles(filenames...)
-	if err != nil {
-		return err
-	}
-	e.templates = tmpl
-	return nil
+	return e.update(func(e *Engine, t *template.Template) (*template.Template, error) {
+		return e.parseFiles(t, filenames, filepath.Base, os.ReadFile)
+	})
 }
 
 // Parse parses a template string directly.
 func (e *Engine) Parse(name, text string) error {
 	e.mu.Lock()
 	defer e.mu.Unlock()
+	return e.update(func(e *Engine, t *template.Template) (*template.Template, error) {
+		e.recordSource(name, text)
+		return t.New(name).Parse(text)
+	})
+}
 
-	if e.templates == nil {
-		e.templates = template.New("").Funcs(e.funcs)
+// Reset discards all loaded templates, keeping registered functions, so
+// the next Load replaces rather than adds to the set.
+func (e *Engine) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.base, e.templates, e.fallback = nil, nil, nil
+	e.sources, e.reloadErr = nil, nil
+	e.source = nil
+}
+
+// update parses into a copy of base and, if that succeeds, makes it the
+// new base, so a failed load leaves the set unchanged. Must be called
+// with e.mu held for writing.
+func (e *Engine) update(parse parseFunc) error {
+	next := template.New("").Funcs(e.funcs)
+	if e.base != nil {
+		clone, err := e.base.Clone()
+		if err != nil {
+			return err
+		}
+		next = clone
+	}
+	e.pending = make(map[string]string)
+	defer func() { e.pending = nil }()
+	if _, err := parse(e, next); err != nil {
+		return err
+	}
+	if e.source == nil {
+		e.source = make(map[string]string)
 	}
+	for name, text := range e.pending {
+		e.source[name] = text
+	}
+	e.base = next
+	e.sources = append(e.sources, parse)
+	e.publish()
+	return nil
+}
 
-	_, err := e.templates.New(name).Parse(text)
-	return err
+// publish replaces the rendered set and its fallback with fresh clones
+// of base. Must be called with e.mu held for writing.
+func (e *Engine) publish() {
+	tmpl, err := e.base.Clone()
+	if err != nil {
+		// Only possible if base was executed, which Engine never does
+		logf("cloning templates: %v", err)
+		return
+	}
+	e.templates = tmpl
+	e.rebuildFallback()
 }
 
 // Render executes a template and returns HTML string.
 func (e *Engine) Render(name string, data any) (string, error) {
+	if err := e.refresh(); err != nil {
+		return "", &TemplateError{Name: name, Err: err}
+	}
+
 	e.mu.RLock()
 	defer e.mu.RUnlock()
 
@@ -103,11 +259,60 @@ func (e *Engine) Render(name string, data any) (string, error) {
 
 	var buf bytes.Buffer
 	if err := e.templates.ExecuteTemplate(&buf, name, data); err != nil {
-		return "", &TemplateError{Name: name, Err: err}
+		return "", e.execError(name, err)
 	}
 	return buf.String(), nil
 }
 
+// RenderTo executes a template like Render, but writes it straight to w
+// rather than returning a string, so large pages aren't buffered. If
+// output was written before an error, the *TemplateError has Partial
+// set: the response is already under way and can't be replaced with an
+// error page.
+func (e *Engine) RenderTo(w io.Writer, name string, data any) error {
+	if err := e.refresh(); err != nil {
+		return &TemplateError{Name: name, Err: err}
+	}
+
+	e.mu.RLock()
+	tmpl := e.templates
+	e.mu.RUnlock()
+	if tmpl == nil {
+		return &TemplateError{Name: name, Err: ErrNoTemplates}
+	}
+	// Published sets are never changed, so w, which may be a slow
+	// client, is written to without holding the lock.
+	return e.executeTo(w, name, func(w io.Writer) error {
+		return tmpl.ExecuteTemplate(w, name, data)
+	})
+}
+
+// executeTo runs exec writing through to w, and wraps any error,
+// recording whether output was written before it.
+func (e *Engine) executeTo(w io.Writer, name string, exec func(io.Writer) error) error {
+	cw := &countingWriter{w: w}
+	if err := exec(cw); err != nil {
+		e.mu.RLock()
+		defer e.mu.RUnlock()
+		te := e.execError(name, err)
+		te.Partial = cw.n > 0
+		return te
+	}
+	return nil
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
 // MustRender executes a template and panics on error.
 func (e *Engine) MustRender(name string, data any) string {
 	html, err := e.Render(name, data)
@@ -123,18 +328,41 @@ func (e *Engine) Fragment(name string, data any) (string, error) {
 	return e.Render("fragments/"+name, data)
 }
 
+// FragmentTo renders a fragment to w, like Fragment and RenderTo.
+func (e *Engine) FragmentTo(w io.Writer, name string, data any) error {
+	return e.RenderTo(w, "fragments/"+name, data)
+}
+
 // Component renders a reusable UI component.
 // Prepends "components/" to the name.
 func (e *Engine) Component(name string, data any) (string, error) {
 	return e.Render("components/"+name, data)
 }
 
-// Page renders a full page template.
+// Page renders a full page template, inside the default layout if one
+// is set (see SetDefaultLayout).
 // Prepends "pages/" to the name.
 func (e *Engine) Page(name string, data any) (string, error) {
+	e.mu.RLock()
+	layout := e.defaultLayout
+	e.mu.RUnlock()
+	if layout != "" {
+		return e.RenderWithLayout(layout, "pages/"+name, data)
+	}
 	return e.Render("pages/"+name, data)
 }
 
+// PageTo renders a page to w, like Page and RenderTo.
+func (e *Engine) PageTo(w io.Writer, name string, data any) error {
+	e.mu.RLock()
+	layout := e.defaultLayout
+	e.mu.RUnlock()
+	if layout != "" {
+		return e.RenderWithLayoutTo(w, layout, "pages/"+name, data)
+	}
+	return e.RenderTo(w, "pages/"+name, data)
+}
+
 // Layout renders a layout template.
 // Prepends "layouts/" to the name.
 func (e *Engine) Layout(name string, data any) (string, error) {
@@ -171,24 +399,33 @@ func (e *Engine) Templates() []string {
 }
 
 // Clone creates a copy of the engine with the same templates and funcs.
+// Changes to either afterwards don't affect the other.
 func (e *Engine) Clone() (*Engine, error) {
 	e.mu.RLock()
 	defer e.mu.RUnlock()
 
 	clone := &Engine{
-		funcs: make(template.FuncMap),
+		funcs:         make(template.FuncMap),
+		sources:       append([]parseFunc(nil), e.sources...),
+		devMode:       e.devMode,
+		debug:         e.debug,
+		defaultLayout: e.defaultLayout,
+	}
+	if e.source != nil {
+		clone.source = maps.Clone(e.source)
 	}
 
 	for k, v := range e.funcs {
 		clone.funcs[k] = v
 	}
 
-	if e.templates != nil {
-		cloned, err := e.templates.Clone()
+	if e.base != nil {
+		base, err := e.base.Clone()
 		if err != nil {
 			return nil, err
 		}
-		clone.templates = cloned
+		clone.base = base
+		clone.publish()
 	}
 
 	return clone, nil
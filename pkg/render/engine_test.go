@@ -0,0 +1,302 @@
+package render
+
+import (
+	"errors"
+	"html/template"
+	"io"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestAddFuncAfterLoad(t *testing.T) {
+	e := New()
+	e.AddFunc("greet", func(s string) string { return "hello " + s })
+	if err := e.Parse("page", `{{greet .}}`); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.MustRender("page", "ann"); got != "hello ann" {
+		t.Fatalf("got %q", got)
+	}
+
+	// Replacing a func after rendering applies to existing templates
+	e.AddFunc("greet", func(s string) string { return "hi " + s })
+	if got := e.MustRender("page", "ann"); got != "hi ann" {
+		t.Errorf("expected replaced func, got %q", got)
+	}
+
+	// New funcs can be used by templates parsed after rendering
+	e.AddFuncs(map[string]any{"shout": strings.ToUpper})
+	if err := e.Parse("loud", `{{shout .}}`); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.MustRender("loud", "hey"); got != "HEY" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestLoadAccumulates(t *testing.T) {
+	e := New()
+	fragments := fstest.MapFS{"fragments/item.html": {Data: []byte(`{{define "fragments/item"}}<li>{{.}}</li>{{end}}`)}}
+	pages := fstest.MapFS{"pages/list.html": {Data: []byte(`{{define "pages/list"}}<ul>{{range .}}{{template "fragments/item" .}}{{end}}</ul>{{end}}`)}}
+
+	if err := e.LoadFS(fragments, "fragments/*.html"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.Fragment("item", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.LoadFS(pages, "pages/*.html"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := e.Page("list", []string{"a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "<ul><li>a</li><li>b</li></ul>" {
+		t.Errorf("got %q", got)
+	}
+
+	// A failed load leaves the set unchanged
+	broken := fstest.MapFS{"pages/bad.html": {Data: []byte(`{{define "pages/bad"}}{{.`)}}
+	if err := e.LoadFS(broken, "pages/*.html"); err == nil {
+		t.Error("expected parse error")
+	}
+	if !e.HasTemplate("pages/list") || !e.HasTemplate("fragments/item") {
+		t.Error("failed load dropped templates")
+	}
+
+	e.Reset()
+	if e.HasTemplate("pages/list") {
+		t.Error("expected Reset to drop templates")
+	}
+	if err := e.LoadFS(fragments, "fragments/*.html"); err != nil {
+		t.Fatal(err)
+	}
+	if e.HasTemplate("pages/list") {
+		t.Error("expected load after Reset to replace the set")
+	}
+}
+
+func TestCloneKeepsLateFuncs(t *testing.T) {
+	e := New()
+	if err := e.Parse("page", `{{label .}}`); err == nil {
+		t.Fatal("expected undefined func error")
+	}
+	e.AddFunc("label", func(s string) string { return "[" + s + "]" })
+	if err := e.Parse("page", `{{label .}}`); err != nil {
+		t.Fatal(err)
+	}
+	e.MustRender("page", "x")
+
+	clone, err := e.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := clone.MustRender("page", "x"); got != "[x]" {
+		t.Errorf("got %q", got)
+	}
+
+	// The engines are independent afterwards
+	clone.AddFunc("label", func(s string) string { return "<" + s + ">" })
+	if got := e.MustRender("page", "x"); got != "[x]" {
+		t.Errorf("clone's func leaked into original: %q", got)
+	}
+	if got := clone.MustRender("page", "x"); got != "&lt;x&gt;" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestAddFuncWiresPlaceholdersLate(t *testing.T) {
+	e := New()
+	if err := e.Parse("form", `<form action="{{url "todo.create"}}">{{csrfField .}}</form>`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.Render("form", nil); err == nil {
+		t.Fatal("expected an error before url is wired")
+	}
+
+	err := e.AddFuncs(template.FuncMap{
+		"url":       func(name string, params ...any) (string, error) { return "/todos", nil },
+		"csrfField": func(any) template.HTML { return `<input type="hidden" name="csrf" value="t">` },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := e.Render("form", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `<form action="/todos"><input type="hidden" name="csrf" value="t"></form>`; got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestAddFuncInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   any
+		want string
+	}{
+		{"", strings.ToUpper, "not a valid identifier"},
+		{"9lives", strings.ToUpper, "not a valid identifier"},
+		{"kebab-case", strings.ToUpper, "not a valid identifier"},
+		{"nothing", nil, "<nil> is not a function"},
+		{"value", "hello", "string is not a function"},
+		{"nilFunc", (func() string)(nil), "is not a function"},
+		{"noResult", func() {}, "must return one value"},
+		{"twoValues", func() (string, string) { return "", "" }, "must return one value"},
+	}
+	e := New()
+	if err := e.Parse("page", `{{yell .}}`); err == nil {
+		t.Fatal("expected undefined func error")
+	}
+	for _, tt := range tests {
+		err := e.AddFunc(tt.name, tt.fn)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("AddFunc(%q) = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+
+	// An invalid func registers none of its batch
+	err := e.AddFuncs(template.FuncMap{"yell": strings.ToUpper, "bad": 1})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if e.HasFunc("yell") {
+		t.Error("valid func from a failed batch was registered")
+	}
+
+	if err := e.AddFunc("yell_2", func(s string) (string, error) { return strings.ToUpper(s), nil }); err != nil {
+		t.Errorf("valid func rejected: %v", err)
+	}
+}
+
+func TestFuncs(t *testing.T) {
+	e := New()
+	if err := e.AddFunc("greet", func(s string) string { return "hi " + s }); err != nil {
+		t.Fatal(err)
+	}
+	funcs := e.Funcs()
+	for _, name := range []string{"greet", "dsGet", "hx", "url"} {
+		if funcs[name] == nil {
+			t.Errorf("Funcs missing %s", name)
+		}
+	}
+
+	// The map is a copy
+	delete(funcs, "greet")
+	funcs["shout"] = strings.ToUpper
+	if !e.HasFunc("greet") || e.HasFunc("shout") {
+		t.Error("changing the returned map changed the engine")
+	}
+}
+
+func newStreamEngine(t *testing.T) *Engine {
+	t.Helper()
+	e := New()
+	e.AddFunc("fail", func() (string, error) { return "", errors.New("db gone") })
+	templates := map[string]string{
+		"pages/report":   `<h1>{{.}}</h1>`,
+		"pages/broken":   `<h1>Report</h1>{{fail}}`,
+		"pages/early":    `{{fail}}<h1>Report</h1>`,
+		"fragments/row":  `<tr><td>{{.}}</td></tr>`,
+		"layouts/base":   `<html>{{.content}}</html>`,
+		"layouts/broken": `<html>{{.content}}{{fail}}</html>`,
+	}
+	for name, text := range templates {
+		if err := e.Parse(name, text); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return e
+}
+
+func TestRenderTo(t *testing.T) {
+	e := newStreamEngine(t)
+
+	var b strings.Builder
+	if err := e.RenderTo(&b, "pages/report", "Q3"); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "<h1>Q3</h1>" {
+		t.Errorf("RenderTo wrote %q", b.String())
+	}
+
+	b.Reset()
+	if err := e.FragmentTo(&b, "row", "a"); err != nil || b.String() != "<tr><td>a</td></tr>" {
+		t.Errorf("FragmentTo wrote %q, %v", b.String(), err)
+	}
+
+	b.Reset()
+	if err := e.PageTo(&b, "report", "Q3"); err != nil || b.String() != "<h1>Q3</h1>" {
+		t.Errorf("PageTo wrote %q, %v", b.String(), err)
+	}
+
+	e.SetDefaultLayout("layouts/base")
+	b.Reset()
+	if err := e.PageTo(&b, "report", "Q3"); err != nil || b.String() != "<html><h1>Q3</h1></html>" {
+		t.Errorf("PageTo with layout wrote %q, %v", b.String(), err)
+	}
+}
+
+func TestRenderToPartialWrite(t *testing.T) {
+	e := newStreamEngine(t)
+	tests := []struct {
+		name    string
+		render  func(io.Writer) error
+		written string
+		partial bool
+	}{
+		{"fails after output", func(w io.Writer) error { return e.RenderTo(w, "pages/broken", nil) }, "<h1>Report</h1>", true},
+		{"fails before output", func(w io.Writer) error { return e.RenderTo(w, "pages/early", nil) }, "", false},
+		{"missing template", func(w io.Writer) error { return e.RenderTo(w, "pages/missing", nil) }, "", false},
+		{"page fails under layout", func(w io.Writer) error {
+			return e.RenderWithLayoutTo(w, "layouts/base", "pages/broken", nil)
+		}, "", false},
+		{"layout fails after output", func(w io.Writer) error {
+			return e.RenderWithLayoutTo(w, "layouts/broken", "pages/report", "Q3")
+		}, "<html><h1>Q3</h1>", true},
+	}
+	for _, tt := range tests {
+		var b strings.Builder
+		err := tt.render(&b)
+		var te *TemplateError
+		if !errors.As(err, &te) {
+			t.Errorf("%s: err = %v, want a *TemplateError", tt.name, err)
+			continue
+		}
+		if te.Partial != tt.partial {
+			t.Errorf("%s: Partial = %v, want %v", tt.name, te.Partial, tt.partial)
+		}
+		if b.String() != tt.written {
+			t.Errorf("%s: wrote %q, want %q", tt.name, b.String(), tt.written)
+		}
+	}
+}
+
+// failingWriter accepts limit bytes, then fails.
+type failingWriter struct {
+	limit int
+	strings.Builder
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.Len()+len(p) > w.limit {
+		return 0, errors.New("connection reset")
+	}
+	return w.Builder.Write(p)
+}
+
+func TestRenderToWriterError(t *testing.T) {
+	e := New()
+	if err := e.Parse("rows", `{{range .}}<tr><td>{{.}}</td></tr>{{end}}`); err != nil {
+		t.Fatal(err)
+	}
+	w := &failingWriter{limit: 30}
+	err := e.RenderTo(w, "rows", []int{1, 2, 3, 4})
+	var te *TemplateError
+	if !errors.As(err, &te) || !te.Partial || !strings.Contains(err.Error(), "connection reset") {
+		t.Errorf("err = %#v, want a partial TemplateError wrapping the write error", err)
+	}
+}
@@ -0,0 +1,129 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"html/template"
+	"maps"
+	"strconv"
+)
+
+var errFallbackUnavailable = errors.New("fallback templates unavailable")
+
+const (
+	// NoJSCookie marks a client whose JavaScript bundle failed to load.
+	NoJSCookie = "irgo-nojs"
+
+	// NoJSQuery is the query parameter the detector uses to report a
+	// missing bundle when cookies can't be set from script (noscript).
+	NoJSQuery = "__nojs"
+)
+
+type noJSKey struct{}
+
+// WithNoJS returns a context flagged for no-JS fallback rendering.
+func WithNoJS(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noJSKey{}, true)
+}
+
+// IsNoJS returns true if the context is flagged for no-JS fallback rendering.
+func IsNoJS(ctx context.Context) bool {
+	v, _ := ctx.Value(noJSKey{}).(bool)
+	return v
+}
+
+// NoJSDetector returns a snippet for the document head that detects when
+// the Datastar bundle fails to load. If scripts are disabled, a noscript
+// refresh reloads with ?__nojs=1. If scripts run but the bundle hasn't
+// loaded within timeoutMs, it sets the irgo-nojs cookie and reloads once.
+// The datastarSrc module must load for the page to be considered healthy.
+func NoJSDetector(datastarSrc string, timeoutMs int) template.HTML {
+	src := template.JSEscapeString(datastarSrc)
+	return template.HTML(`<noscript><meta http-equiv="refresh" content="0;url=?` + NoJSQuery + `=1"></noscript>
+<script type="module">import '` + src + `';window.__irgoReady=true;</script>
+<script>setTimeout(function(){if(!window.__irgoReady&&document.cookie.indexOf('` + NoJSCookie + `=1')<0){document.cookie='` + NoJSCookie + `=1; path=/; max-age=3600';location.reload();}},` + strconv.Itoa(timeoutMs) + `);</script>`)
+}
+
+// FallbackFuncs returns replacements for the Datastar action helpers that
+// produce plain links and form submissions. Engine.RenderContext uses them
+// when the context is flagged with WithNoJS.
+func FallbackFuncs() template.FuncMap {
+	return template.FuncMap{
+		"dsGet":    fallbackGet,
+		"dsPost":   fallbackPost,
+		"dsPut":    fallbackPost,
+		"dsPatch":  fallbackPost,
+		"dsDelete": fallbackPost,
+	}
+}
+
+// fallbackGet generates an href so the element navigates to the full page
+func fallbackGet(url string) template.HTMLAttr {
+	return template.HTMLAttr(`href="` + template.HTMLEscapeString(url) + `"`)
+}
+
+// fallbackPost generates formaction/formmethod so a submit button posts
+// its enclosing form to the URL
+func fallbackPost(url string) template.HTMLAttr {
+	return template.HTMLAttr(`formaction="` + template.HTMLEscapeString(url) + `" formmethod="post"`)
+}
+
+// RenderContext executes a template like Render, but renders with
+// FallbackFuncs when ctx is flagged with WithNoJS, and with any funcs
+// attached with WithFuncs.
+func (e *Engine) RenderContext(ctx context.Context, name string, data any) (string, error) {
+	funcs := FuncsFromContext(ctx)
+	if !IsNoJS(ctx) {
+		return e.RenderWithFuncs(name, data, funcs)
+	}
+	if len(funcs) > 0 {
+		// Request funcs win over the fallbacks, as over the defaults
+		merged := FallbackFuncs()
+		maps.Copy(merged, funcs)
+		return e.RenderWithFuncs(name, data, merged)
+	}
+
+	tmpl, err := e.fallbackTemplates()
+	if err != nil {
+		return "", &TemplateError{Name: name, Err: err}
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, name, data); err != nil {
+		e.mu.RLock()
+		defer e.mu.RUnlock()
+		return "", e.execError(name, err)
+	}
+	return buf.String(), nil
+}
+
+// fallbackTemplates returns the templates bound to FallbackFuncs.
+func (e *Engine) fallbackTemplates() (*template.Template, error) {
+	if err := e.refresh(); err != nil {
+		return nil, err
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if e.templates == nil {
+		return nil, ErrNoTemplates
+	}
+	if e.fallback == nil {
+		return nil, errFallbackUnavailable
+	}
+	return e.fallback, nil
+}
+
+// rebuildFallback clones the master templates with FallbackFuncs.
+// Must be called with e.mu held for writing.
+func (e *Engine) rebuildFallback() {
+	e.fallback = nil
+	if e.base == nil {
+		return
+	}
+	if clone, err := e.base.Clone(); err == nil {
+		e.fallback = clone.Funcs(FallbackFuncs())
+	}
+}
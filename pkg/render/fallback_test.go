@@ -0,0 +1,57 @@
+package render
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestRenderContextFallback(t *testing.T) {
+	e := New()
+	if err := e.Parse("item", `<a {{dsGet "/todos/1"}}>View</a><button {{dsDelete "/todos/1"}}>Delete</button>`); err != nil {
+		t.Fatal(err)
+	}
+
+	html, err := e.RenderContext(context.Background(), "item", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(html, `data-on:click="@get('/todos/1')"`) {
+		t.Errorf("expected Datastar attributes, got %q", html)
+	}
+
+	// Render again to confirm fallback works after the templates executed
+	html, err = e.RenderContext(WithNoJS(context.Background()), "item", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(html, `href="/todos/1"`) || !strings.Contains(html, `formaction="/todos/1" formmethod="post"`) {
+		t.Errorf("expected fallback attributes, got %q", html)
+	}
+	if strings.Contains(html, "data-on:click") {
+		t.Errorf("expected no Datastar attributes in fallback, got %q", html)
+	}
+}
+
+func TestRenderContextFallbackAfterParse(t *testing.T) {
+	e := New()
+	e.Parse("a", `<a {{dsGet "/a"}}></a>`)
+	e.Parse("b", `<a {{dsGet "/b"}}></a>`)
+
+	html, err := e.RenderContext(WithNoJS(context.Background()), "b", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if html != `<a href="/b"></a>` {
+		t.Errorf("unexpected fallback output %q", html)
+	}
+}
+
+func TestNoJSDetector(t *testing.T) {
+	snippet := string(NoJSDetector("/static/js/datastar.js", 3000))
+	for _, want := range []string{"<noscript>", "?__nojs=1", "import '/static/js/datastar.js'", "irgo-nojs=1", "3000"} {
+		if !strings.Contains(snippet, want) {
+			t.Errorf("expected snippet to contain %q", want)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package render
+
+import (
+	"context"
+	"html/template"
+	"io"
+	"strings"
+
+	"github.com/a-h/templ"
+)
+
+// FlashMessage is a one-off message shown on the next page, such as
+// "Todo created" after a redirect. Set with router.Context.Flash.
+type FlashMessage struct {
+	Kind    string `json:"kind"` // "success", "error", "info", ...
+	Message string `json:"message"`
+}
+
+// FlashesHTML renders messages with the .error and .success classes from
+// the default stylesheet. Error messages are alerts; the rest are
+// statuses. Returns "" when there are none.
+//
+//	<div class="flashes">
+//	  <div class="flash success" role="status">Todo created</div>
+//	</div>
+func FlashesHTML(messages []FlashMessage) template.HTML {
+	if len(messages) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString(`<div class="flashes">`)
+	for _, m := range messages {
+		role := "status"
+		if m.Kind == "error" {
+			role = "alert"
+		}
+		b.WriteString(`<div class="flash ` + template.HTMLEscapeString(m.Kind) + `" role="` + role + `">`)
+		b.WriteString(template.HTMLEscapeString(m.Message))
+		b.WriteString(`</div>`)
+	}
+	b.WriteString(`</div>`)
+	return template.HTML(b.String())
+}
+
+// Flashes is FlashesHTML as a templ component:
+//
+//	@render.Flashes(flashes)
+func Flashes(messages []FlashMessage) templ.Component {
+	return templ.ComponentFunc(func(ctx context.Context, w io.Writer) error {
+		_, err := io.WriteString(w, string(FlashesHTML(messages)))
+		return err
+	})
+}
+
+// flashes renders flash messages, e.g. {{flashes .Flashes}}
+func flashes(messages []FlashMessage) template.HTML {
+	return FlashesHTML(messages)
+}
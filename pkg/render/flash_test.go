@@ -0,0 +1,39 @@
+package render
+
+import (
+	"context"
+	"html/template"
+	"strings"
+	"testing"
+)
+
+func TestFlashesHTML(t *testing.T) {
+	messages := []FlashMessage{
+		{Kind: "success", Message: "Todo created"},
+		{Kind: "error", Message: "Sync <failed>"},
+	}
+	want := `<div class="flashes">` +
+		`<div class="flash success" role="status">Todo created</div>` +
+		`<div class="flash error" role="alert">Sync &lt;failed&gt;</div>` +
+		`</div>`
+	if got := string(FlashesHTML(messages)); got != want {
+		t.Errorf("FlashesHTML =\n%s\nwant\n%s", got, want)
+	}
+	if got := FlashesHTML(nil); got != "" {
+		t.Errorf("FlashesHTML(nil) = %q, want empty", got)
+	}
+
+	tmpl := template.Must(template.New("page").Funcs(DefaultFuncs()).Parse(`{{flashes .Flashes}}`))
+	var b strings.Builder
+	if err := tmpl.Execute(&b, map[string]any{"Flashes": messages}); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != want {
+		t.Errorf("flashes template func = %s", b.String())
+	}
+
+	b.Reset()
+	if err := Flashes(messages).Render(context.Background(), &b); err != nil || b.String() != want {
+		t.Errorf("Flashes component = %s, %v", b.String(), err)
+	}
+}
@@ -0,0 +1,111 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"html/template"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// funcSets pools clones of the master templates for RenderWithFuncs,
+// keyed by the names of the funcs applied. Every render through a pool
+// sets the same names, so no request's funcs outlive it. The pools are
+// dropped when the templates change.
+type funcSets struct {
+	templates *template.Template // e.templates the pools were built for
+	pools     map[string]*sync.Pool
+}
+
+// RenderWithFuncs executes a template like Render, with funcs added to
+// or replacing the engine's for this render only. It suits
+// request-scoped funcs like csrfToken, currentUser or t:
+//
+//	html, err := engine.RenderWithFuncs("pages/home", data, template.FuncMap{
+//		"currentUser": func() *User { return user },
+//	})
+//
+// Templates are checked for funcs when parsed, so each name must also be
+// registered on the engine, if only as a placeholder. The clones used
+// are cached, so after the first render of a set of names the cost is
+// close to Render's.
+func (e *Engine) RenderWithFuncs(name string, data any, funcs template.FuncMap) (string, error) {
+	if len(funcs) == 0 {
+		return e.Render(name, data)
+	}
+	for fname, fn := range funcs {
+		if err := checkFunc(fname, fn); err != nil {
+			return "", &TemplateError{Name: name, Err: err}
+		}
+	}
+	if err := e.refresh(); err != nil {
+		return "", &TemplateError{Name: name, Err: err}
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if e.templates == nil {
+		return "", &TemplateError{Name: name, Err: ErrNoTemplates}
+	}
+
+	pool := e.funcPool(funcs)
+	set, _ := pool.Get().(*template.Template)
+	if set == nil {
+		clone, err := e.base.Clone()
+		if err != nil {
+			return "", &TemplateError{Name: name, Err: err}
+		}
+		set = clone
+	}
+	defer pool.Put(set)
+	set.Funcs(funcs)
+
+	var buf bytes.Buffer
+	if err := set.ExecuteTemplate(&buf, name, data); err != nil {
+		return "", e.execError(name, err)
+	}
+	return buf.String(), nil
+}
+
+// funcPool returns the pool of clones for the names in funcs. Must be
+// called with e.mu held.
+func (e *Engine) funcPool(funcs template.FuncMap) *sync.Pool {
+	key := strings.Join(slices.Sorted(maps.Keys(funcs)), "\x00")
+
+	e.funcSetsMu.Lock()
+	defer e.funcSetsMu.Unlock()
+	if e.funcSets.templates != e.templates {
+		e.funcSets = funcSets{templates: e.templates, pools: make(map[string]*sync.Pool)}
+	}
+	pool, ok := e.funcSets.pools[key]
+	if !ok {
+		pool = new(sync.Pool)
+		e.funcSets.pools[key] = pool
+	}
+	return pool
+}
+
+type funcsKey struct{}
+
+// WithFuncs returns a context carrying template funcs for
+// Engine.RenderContext, which renders with them as RenderWithFuncs does.
+// They are added to any funcs ctx already carries, replacing those with
+// the same names.
+func WithFuncs(ctx context.Context, funcs template.FuncMap) context.Context {
+	merged := maps.Clone(FuncsFromContext(ctx))
+	if merged == nil {
+		merged = make(template.FuncMap, len(funcs))
+	}
+	maps.Copy(merged, funcs)
+	return context.WithValue(ctx, funcsKey{}, merged)
+}
+
+// FuncsFromContext returns the template funcs attached with WithFuncs,
+// or nil.
+func FuncsFromContext(ctx context.Context) template.FuncMap {
+	funcs, _ := ctx.Value(funcsKey{}).(template.FuncMap)
+	return funcs
+}
@@ -0,0 +1,190 @@
+package render
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func newUserEngine(t testing.TB) *Engine {
+	t.Helper()
+	e := New()
+	e.AddFunc("currentUser", func() string { return "guest" })
+	if err := e.Parse("nav", `<nav>{{currentUser}} {{upper "x"}} <a {{dsGet "/me"}}>me</a></nav>`); err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func userFuncs(name string) template.FuncMap {
+	return template.FuncMap{"currentUser": func() string { return name }}
+}
+
+func TestRenderWithFuncs(t *testing.T) {
+	e := newUserEngine(t)
+
+	got, err := e.RenderWithFuncs("nav", nil, userFuncs("ada"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `<nav>ada X <a data-on:click="@get('/me')">me</a></nav>`; got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+
+	// The engine's own funcs are untouched
+	if got := e.MustRender("nav", nil); !strings.HasPrefix(got, "<nav>guest ") {
+		t.Errorf("Render = %s, want the registered func", got)
+	}
+
+	// A reused clone gets each render's funcs
+	for _, name := range []string{"bob", "cy", "ada"} {
+		got, err := e.RenderWithFuncs("nav", nil, userFuncs(name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(got, "<nav>"+name+" ") {
+			t.Errorf("got %s, want user %s", got, name)
+		}
+	}
+
+	// No funcs is a plain render
+	if got, _ := e.RenderWithFuncs("nav", nil, nil); !strings.HasPrefix(got, "<nav>guest ") {
+		t.Errorf("without funcs = %s", got)
+	}
+}
+
+func TestRenderWithFuncsErrors(t *testing.T) {
+	e := newUserEngine(t)
+
+	if _, err := e.RenderWithFuncs("nav", nil, template.FuncMap{"currentUser": "ada"}); err == nil {
+		t.Error("expected an error for an invalid func")
+	}
+	if _, err := e.RenderWithFuncs("missing", nil, userFuncs("ada")); err == nil {
+		t.Error("expected an error for a missing template")
+	}
+	wrongType := template.FuncMap{"currentUser": func(s string) string { return s }}
+	if _, err := e.RenderWithFuncs("nav", nil, wrongType); err == nil {
+		t.Error("expected an error for a func with the wrong arguments")
+	}
+	if got, err := e.RenderWithFuncs("nav", nil, userFuncs("ada")); err != nil || !strings.HasPrefix(got, "<nav>ada ") {
+		t.Errorf("after errors: %s, %v", got, err)
+	}
+
+	if _, err := New().RenderWithFuncs("nav", nil, userFuncs("ada")); err == nil {
+		t.Error("expected an error with no templates")
+	}
+}
+
+func TestRenderWithFuncsSeesChanges(t *testing.T) {
+	e := newUserEngine(t)
+	if _, err := e.RenderWithFuncs("nav", nil, userFuncs("ada")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Templates and funcs changed after the clones were cached
+	if err := e.Parse("nav", `<p>{{currentUser}} {{upper "y"}}</p>`); err != nil {
+		t.Fatal(err)
+	}
+	e.AddFunc("upper", strings.ToLower)
+	got, err := e.RenderWithFuncs("nav", nil, userFuncs("ada"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `<p>ada y</p>`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestRenderWithFuncsConcurrent(t *testing.T) {
+	e := newUserEngine(t)
+	var wg sync.WaitGroup
+	for i := range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			name := fmt.Sprintf("user%d", i)
+			for range 50 {
+				got, err := e.RenderWithFuncs("nav", nil, userFuncs(name))
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if !strings.HasPrefix(got, "<nav>"+name+" ") {
+					t.Errorf("got %s, want user %s", got, name)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestRenderContextFuncs(t *testing.T) {
+	e := newUserEngine(t)
+
+	ctx := WithFuncs(context.Background(), userFuncs("ada"))
+	ctx = WithFuncs(ctx, template.FuncMap{"upper": strings.ToLower})
+	if got := FuncsFromContext(ctx); len(got) != 2 {
+		t.Errorf("WithFuncs merged %d funcs, want 2", len(got))
+	}
+
+	got, err := e.RenderContext(ctx, "nav", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `<nav>ada x <a data-on:click="@get('/me')">me</a></nav>`; got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+
+	// No-JS rendering keeps the fallbacks alongside the request's funcs
+	got, err = e.RenderContext(WithNoJS(ctx), "nav", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `<nav>ada x <a href="/me">me</a></nav>`; got != want {
+		t.Errorf("no-JS:\n got %s\nwant %s", got, want)
+	}
+
+	if FuncsFromContext(context.Background()) != nil {
+		t.Error("expected no funcs in a bare context")
+	}
+}
+
+func BenchmarkRenderFuncs(b *testing.B) {
+	e := newUserEngine(b)
+	funcs := userFuncs("ada")
+
+	b.Run("Render", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			if _, err := e.Render("nav", nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("RenderWithFuncs", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			if _, err := e.RenderWithFuncs("nav", nil, funcs); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	// What RenderWithFuncs would cost without its clone cache
+	b.Run("CloneEachRender", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			set, err := e.base.Clone()
+			if err != nil {
+				b.Fatal(err)
+			}
+			var buf strings.Builder
+			if err := set.Funcs(funcs).ExecuteTemplate(&buf, "nav", nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
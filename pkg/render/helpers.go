@@ -5,7 +5,14 @@ import (
 	"errors"
 	"fmt"
 	"html/template"
+	"reflect"
+	"sort"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 var (
@@ -17,6 +24,18 @@ var (
 type TemplateError struct {
 	Name string
 	Err  error
+
+	// Source and Line locate an execution error in the template source
+	// when the engine is in debug mode (see Engine.SetDebug). Source is
+	// the file's base name, or the name given to Parse.
+	Source string
+	Line   int
+
+	// Partial is set when a render to a writer (see Engine.RenderTo)
+	// wrote output before failing.
+	Partial bool
+
+	snippet string
 }
 
 func (e *TemplateError) Error() string {
@@ -27,17 +46,32 @@ func (e *TemplateError) Unwrap() error {
 	return e.Err
 }
 
+// SourceSnippet returns the failing line of the template source and the
+// lines either side, numbered, or "" outside debug mode.
+func (e *TemplateError) SourceSnippet() string {
+	return e.snippet
+}
+
 // DefaultFuncs returns the standard template functions for Datastar.
 func DefaultFuncs() template.FuncMap {
-	return template.FuncMap{
+	funcs := template.FuncMap{
 		// Datastar action helpers (data-on:event="@method('/url')")
 		"dsGet":    dsGet,
 		"dsPost":   dsPost,
 		"dsPut":    dsPut,
 		"dsPatch":  dsPatch,
 		"dsDelete": dsDelete,
+		"dsGetOn":    dsGetOn,
+		"dsPostOn":   dsPostOn,
+		"dsPutOn":    dsPutOn,
+		"dsPatchOn":  dsPatchOn,
+		"dsDeleteOn": dsDeleteOn,
 
 		// Datastar event handlers
+		"dsOn":         dsOn,
+		"dsOnDebounce": dsOnDebounce,
+		"dsOnThrottle": dsOnThrottle,
+		"dsOnKeydown":  dsOnKeydown,
 		"dsOnClick":   dsOnClick,
 		"dsOnSubmit":  dsOnSubmit,
 		"dsOnChange":  dsOnChange,
@@ -45,11 +79,15 @@ func DefaultFuncs() template.FuncMap {
 		"dsOnKeyup":   dsOnKeyup,
 		"dsOnLoad":    dsOnLoad,
 		"dsOnIntersect": dsOnIntersect,
+		"dsPoll":        dsPoll,
+		"dsOptimistic":  dsOptimistic,
 
 		// Datastar binding and signals
 		"dsBind":     dsBind,
 		"dsSignals":  dsSignals,
 		"dsSignalsJSON": dsSignalsJSON,
+		"dsSignalsJSONStrict": dsSignalsJSONStrict,
+		"dsValidationSignals": dsValidationSignals,
 
 		// Datastar display helpers
 		"dsText":  dsText,
@@ -67,6 +105,31 @@ func DefaultFuncs() template.FuncMap {
 		"safeURL": safeURL,
 		"attr":    attr,
 		"class":   class,
+		"classIf":  classIf,
+		"classMap": classMap,
+
+		// Form helpers
+		"methodField": methodField,
+		"csrfToken":   csrfToken,
+		"csrfField":   csrfField,
+		"flashes":     flashes, // See router.Context.Flash
+
+		// Routing helpers (see Engine.SetURLResolver)
+		"url": unresolvedURL,
+		"asset": AssetURL, // See RegisterAssets
+
+		// Icon helpers (see IconSet)
+		"icon":       noIconSet,
+		"iconUse":    noIconSet,
+		"iconSprite": noIconSprite,
+
+		// Viewport helpers (see router.ViewportMiddleware)
+		"isCompact":       isCompact,
+		"viewportWidth":   viewportWidth,
+		"viewportSignals": ViewportSignals,
+
+		// Translation helpers (see router.I18nMiddleware)
+		"t": translate,
 
 		// Utility helpers
 		"join":      strings.Join,
@@ -78,11 +141,26 @@ func DefaultFuncs() template.FuncMap {
 		"trim":      strings.TrimSpace,
 		"replace":   strings.ReplaceAll,
 
+		// Formatting helpers
+		"formatDate": formatDate,
+		"timeAgo":    timeAgo,
+		"truncate":   truncate,
+		"pluralize":  pluralize,
+		"number":     number,
+		"currency":   currency,
+		"titleCase":  titleCase,
+
 		// Conditional helpers
 		"if_":     ifFunc,
 		"default": defaultFunc,
 		"coalesce": coalesce,
 	}
+
+	// HTMX attribute helpers
+	for name, fn := range HTMXFuncs() {
+		funcs[name] = fn
+	}
+	return funcs
 }
 
 // --- Datastar Action Helpers ---
@@ -90,124 +168,255 @@ func DefaultFuncs() template.FuncMap {
 
 // dsGet generates a data-on:click attribute with @get action
 func dsGet(url string) template.HTMLAttr {
-	return template.HTMLAttr(`data-on:click="@get('` + url + `')"`)
+	return dsAction("get", "click", url)
 }
 
 // dsPost generates a data-on:click attribute with @post action
 func dsPost(url string) template.HTMLAttr {
-	return template.HTMLAttr(`data-on:click="@post('` + url + `')"`)
+	return dsAction("post", "click", url)
 }
 
 // dsPut generates a data-on:click attribute with @put action
 func dsPut(url string) template.HTMLAttr {
-	return template.HTMLAttr(`data-on:click="@put('` + url + `')"`)
+	return dsAction("put", "click", url)
 }
 
 // dsPatch generates a data-on:click attribute with @patch action
 func dsPatch(url string) template.HTMLAttr {
-	return template.HTMLAttr(`data-on:click="@patch('` + url + `')"`)
+	return dsAction("patch", "click", url)
 }
 
 // dsDelete generates a data-on:click attribute with @delete action
 func dsDelete(url string) template.HTMLAttr {
-	return template.HTMLAttr(`data-on:click="@delete('` + url + `')"`)
+	return dsAction("delete", "click", url)
+}
+
+// dsGetOn generates a data-on:event attribute with @get action,
+// e.g. {{dsGetOn "input__debounce.300ms" "/search"}}
+func dsGetOn(event, url string) template.HTMLAttr {
+	return dsAction("get", event, url)
+}
+
+// dsPostOn generates a data-on:event attribute with @post action
+func dsPostOn(event, url string) template.HTMLAttr {
+	return dsAction("post", event, url)
+}
+
+// dsPutOn generates a data-on:event attribute with @put action
+func dsPutOn(event, url string) template.HTMLAttr {
+	return dsAction("put", event, url)
+}
+
+// dsPatchOn generates a data-on:event attribute with @patch action
+func dsPatchOn(event, url string) template.HTMLAttr {
+	return dsAction("patch", event, url)
+}
+
+// dsDeleteOn generates a data-on:event attribute with @delete action
+func dsDeleteOn(event, url string) template.HTMLAttr {
+	return dsAction("delete", event, url)
+}
+
+// dsAction generates data-on:event="@method('url')"
+func dsAction(method, event, url string) template.HTMLAttr {
+	return dsOn(event, `@`+method+`('`+jsQuote(url)+`')`)
 }
 
 // --- Datastar Event Handlers ---
 
+// dsOn generates a data-on:event attribute for any event. The event may
+// carry modifiers, e.g. {{dsOn "submit__prevent" "@post('/todos')"}}
+func dsOn(event, expression string) template.HTMLAttr {
+	name := "data-on:" + attrName(event)
+	checkExpression(name, expression)
+	return template.HTMLAttr(name + `="` + escapeAttr(expression) + `"`)
+}
+
+// dsOnDebounce generates a debounced data-on:event attribute,
+// e.g. {{dsOnDebounce "input" "300ms" "@get('/search')"}} gives
+// data-on:input__debounce.300ms
+func dsOnDebounce(event, duration, expression string) template.HTMLAttr {
+	return dsOn(event+"__debounce."+duration, expression)
+}
+
+// dsOnThrottle generates a throttled data-on:event attribute,
+// e.g. data-on:scroll__throttle.100ms
+func dsOnThrottle(event, duration, expression string) template.HTMLAttr {
+	return dsOn(event+"__throttle."+duration, expression)
+}
+
+// dsOnKeydown generates a data-on:keydown attribute running expression
+// only for key (a KeyboardEvent.key value such as "Enter" or "Escape")
+func dsOnKeydown(key, expression string) template.HTMLAttr {
+	return dsOn("keydown", `if (evt.key === '`+jsQuote(key)+`') { `+expression+` }`)
+}
+
 // dsOnClick generates a data-on:click attribute with custom expression
 func dsOnClick(expression string) template.HTMLAttr {
-	return template.HTMLAttr(`data-on:click="` + expression + `"`)
+	checkExpression("data-on:click", expression)
+	return template.HTMLAttr(`data-on:click="` + escapeAttr(expression) + `"`)
 }
 
 // dsOnSubmit generates a data-on:submit attribute
 func dsOnSubmit(expression string) template.HTMLAttr {
-	return template.HTMLAttr(`data-on:submit="` + expression + `"`)
+	checkExpression("data-on:submit", expression)
+	return template.HTMLAttr(`data-on:submit="` + escapeAttr(expression) + `"`)
 }
 
 // dsOnChange generates a data-on:change attribute
 func dsOnChange(expression string) template.HTMLAttr {
-	return template.HTMLAttr(`data-on:change="` + expression + `"`)
+	checkExpression("data-on:change", expression)
+	return template.HTMLAttr(`data-on:change="` + escapeAttr(expression) + `"`)
 }
 
 // dsOnInput generates a data-on:input attribute
 func dsOnInput(expression string) template.HTMLAttr {
-	return template.HTMLAttr(`data-on:input="` + expression + `"`)
+	checkExpression("data-on:input", expression)
+	return template.HTMLAttr(`data-on:input="` + escapeAttr(expression) + `"`)
 }
 
 // dsOnKeyup generates a data-on:keyup attribute
 func dsOnKeyup(expression string) template.HTMLAttr {
-	return template.HTMLAttr(`data-on:keyup="` + expression + `"`)
+	checkExpression("data-on:keyup", expression)
+	return template.HTMLAttr(`data-on:keyup="` + escapeAttr(expression) + `"`)
 }
 
 // dsOnLoad generates a data-on:load attribute (triggers when element loads)
 func dsOnLoad(expression string) template.HTMLAttr {
-	return template.HTMLAttr(`data-on:load="` + expression + `"`)
+	checkExpression("data-on:load", expression)
+	return template.HTMLAttr(`data-on:load="` + escapeAttr(expression) + `"`)
 }
 
 // dsOnIntersect generates a data-on-intersect attribute (triggers when visible)
 func dsOnIntersect(expression string) template.HTMLAttr {
-	return template.HTMLAttr(`data-on-intersect="` + expression + `"`)
+	checkExpression("data-on-intersect", expression)
+	return template.HTMLAttr(`data-on-intersect="` + escapeAttr(expression) + `"`)
 }
 
 // --- Datastar Binding and Signals ---
 
 // dsBind generates a data-bind:signal attribute for two-way binding
 func dsBind(signal string) template.HTMLAttr {
-	return template.HTMLAttr(`data-bind:` + signal)
+	return template.HTMLAttr(`data-bind:` + attrName(signal))
 }
 
 // dsSignals generates a data-signals attribute with raw JSON
 func dsSignals(json string) template.HTMLAttr {
-	return template.HTMLAttr(`data-signals="` + json + `"`)
+	return template.HTMLAttr(`data-signals="` + escapeAttr(json) + `"`)
 }
 
-// dsSignalsJSON generates a data-signals attribute from a Go map
+// dsSignalsJSON generates a data-signals attribute from a Go map.
+// encoding/json sorts map keys, so the output is stable across renders.
+// Values JSON can't encode, such as funcs, channels and NaN, are logged
+// and the attribute is left out; see also dsSignalsJSONStrict.
 func dsSignalsJSON(data any) template.HTMLAttr {
+	attr, err := signalsAttr(data)
+	if err != nil {
+		logf("dsSignalsJSON: %v", err)
+		return ""
+	}
+	return attr
+}
+
+// dsSignalsJSONDebug replaces dsSignalsJSON in debug mode (see SetDebug),
+// writing the error into the element as a data-signals-error attribute.
+func dsSignalsJSONDebug(data any) template.HTMLAttr {
+	attr, err := signalsAttr(data)
+	if err != nil {
+		logf("dsSignalsJSON: %v", err)
+		return template.HTMLAttr(`data-signals-error="` + template.HTMLEscapeString("dsSignalsJSON: "+err.Error()) + `"`)
+	}
+	return attr
+}
+
+// dsSignalsJSONStrict is dsSignalsJSON, but panics when data can't be
+// encoded, failing the render, so tests catch it.
+func dsSignalsJSONStrict(data any) template.HTMLAttr {
+	attr, err := signalsAttr(data)
+	if err != nil {
+		panic("dsSignalsJSON: " + err.Error())
+	}
+	return attr
+}
+
+// signalsAttr encodes data as a data-signals attribute.
+func signalsAttr(data any) (template.HTMLAttr, error) {
 	jsonBytes, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	return template.HTMLAttr(`data-signals="` + escapeJSONAttr(string(jsonBytes)) + `"`), nil
+}
+
+// ValidationSignal is the signal dsValidationSignals sets.
+const ValidationSignal = "errors"
+
+// dsValidationSignals generates a data-signals attribute setting the
+// "errors" signal to a field → message map, such as router.ValidationErrors,
+// so each input can show its own message:
+//
+//	<form {{dsValidationSignals .Errors "title" "email"}}>
+//	  <input {{dsBind "email"}}>
+//	  <span class="error" {{dsText "$errors.email"}}></span>
+//
+// Named fields without an error are set to "", clearing earlier messages.
+func dsValidationSignals(errs map[string]string, fields ...string) template.HTMLAttr {
+	messages := make(map[string]string, len(errs)+len(fields))
+	for _, field := range fields {
+		messages[field] = ""
+	}
+	for field, msg := range errs {
+		messages[field] = msg
+	}
+	jsonBytes, err := json.Marshal(map[string]any{ValidationSignal: messages})
 	if err != nil {
 		return ""
 	}
-	return template.HTMLAttr(`data-signals="` + string(jsonBytes) + `"`)
+	return template.HTMLAttr(`data-signals="` + escapeAttr(string(jsonBytes)) + `"`)
 }
 
 // --- Datastar Display Helpers ---
 
 // dsText generates a data-text attribute for reactive text content
 func dsText(expression string) template.HTMLAttr {
-	return template.HTMLAttr(`data-text="` + expression + `"`)
+	checkExpression("data-text", expression)
+	return template.HTMLAttr(`data-text="` + escapeAttr(expression) + `"`)
 }
 
 // dsShow generates a data-show attribute for conditional visibility
 func dsShow(expression string) template.HTMLAttr {
-	return template.HTMLAttr(`data-show="` + expression + `"`)
+	checkExpression("data-show", expression)
+	return template.HTMLAttr(`data-show="` + escapeAttr(expression) + `"`)
 }
 
 // dsClass generates a data-class:classname attribute for conditional classes
 func dsClass(className, expression string) template.HTMLAttr {
-	return template.HTMLAttr(`data-class:` + className + `="` + expression + `"`)
+	checkExpression("data-class:"+className, expression)
+	return template.HTMLAttr(`data-class:` + attrName(className) + `="` + escapeAttr(expression) + `"`)
 }
 
 // dsAttr generates a data-attr:attrname attribute for reactive attributes
-func dsAttr(attrName, expression string) template.HTMLAttr {
-	return template.HTMLAttr(`data-attr:` + attrName + `="` + expression + `"`)
+func dsAttr(name, expression string) template.HTMLAttr {
+	checkExpression("data-attr:"+name, expression)
+	return template.HTMLAttr(`data-attr:` + attrName(name) + `="` + escapeAttr(expression) + `"`)
 }
 
 // dsStyle generates a data-style:property attribute for reactive inline styles
 func dsStyle(property, expression string) template.HTMLAttr {
-	return template.HTMLAttr(`data-style:` + property + `="` + expression + `"`)
+	checkExpression("data-style:"+property, expression)
+	return template.HTMLAttr(`data-style:` + attrName(property) + `="` + escapeAttr(expression) + `"`)
 }
 
 // --- Datastar Indicators and Refs ---
 
 // dsIndicator generates a data-indicator:signal attribute for loading states
 func dsIndicator(signal string) template.HTMLAttr {
-	return template.HTMLAttr(`data-indicator:` + signal)
+	return template.HTMLAttr(`data-indicator:` + attrName(signal))
 }
 
 // dsRef generates a data-ref:name attribute for element references
 func dsRef(name string) template.HTMLAttr {
-	return template.HTMLAttr(`data-ref:` + name)
+	return template.HTMLAttr(`data-ref:` + attrName(name))
 }
 
 // --- HTML Helpers ---
@@ -221,20 +430,135 @@ func safeURL(s string) template.URL {
 }
 
 func attr(key, value string) template.HTMLAttr {
-	return template.HTMLAttr(key + `="` + value + `"`)
-}
-
-func class(classes ...string) template.HTMLAttr {
-	var nonEmpty []string
+	return template.HTMLAttr(attrName(key) + `="` + escapeAttr(value) + `"`)
+}
+
+// class generates a class attribute from strings, string slices and
+// map[string]bool (the true keys, sorted), skipping empty and repeated
+// names: {{class "btn" (classIf .Active "active") .Extra}}
+func class(classes ...any) template.HTMLAttr {
+	var names []string
+	seen := make(map[string]bool)
+	add := func(s string) {
+		for _, name := range strings.Fields(s) {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
 	for _, c := range classes {
-		if c = strings.TrimSpace(c); c != "" {
-			nonEmpty = append(nonEmpty, c)
+		switch v := c.(type) {
+		case nil:
+		case string:
+			add(v)
+		case []string:
+			for _, s := range v {
+				add(s)
+			}
+		case map[string]bool:
+			for _, s := range enabledClasses(v) {
+				add(s)
+			}
+		default:
+			add(fmt.Sprint(v))
 		}
 	}
-	if len(nonEmpty) == 0 {
+	if len(names) == 0 {
 		return ""
 	}
-	return template.HTMLAttr(`class="` + strings.Join(nonEmpty, " ") + `"`)
+	return template.HTMLAttr(`class="` + escapeAttr(strings.Join(names, " ")) + `"`)
+}
+
+// classIf returns name when cond is true, for class:
+// {{class "tab" (classIf .Selected "active")}}
+func classIf(cond bool, name string) string {
+	if cond {
+		return name
+	}
+	return ""
+}
+
+// classMap generates a class attribute from the true keys of classes, in
+// sorted order so the output is stable.
+func classMap(classes map[string]bool) template.HTMLAttr {
+	return class(classes)
+}
+
+// enabledClasses returns the true keys of classes, sorted.
+func enabledClasses(classes map[string]bool) []string {
+	var names []string
+	for name, on := range classes {
+		if on {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// --- Form Helpers ---
+
+// methodField generates the hidden _method input honored by
+// router.MethodOverrideMiddleware, e.g. {{methodField "DELETE"}}
+func methodField(method string) template.HTML {
+	return template.HTML(`<input type="hidden" name="_method" value="` +
+		template.HTMLEscapeString(strings.ToUpper(method)) + `">`)
+}
+
+// --- Escaping ---
+
+// attrEscaper escapes the characters that can end a double-quoted
+// attribute value or open markup. Single quotes and other characters
+// Datastar expressions commonly use are left alone, so safe values are
+// unchanged.
+var attrEscaper = strings.NewReplacer(
+	`&`, "&amp;",
+	`"`, "&#34;",
+	`<`, "&lt;",
+	`>`, "&gt;",
+)
+
+// escapeAttr escapes s for use inside a double-quoted attribute value.
+// Browsers decode it before Datastar reads the expression.
+func escapeAttr(s string) string {
+	return attrEscaper.Replace(s)
+}
+
+// jsonAttrEscaper also escapes single quotes, which only appear inside
+// JSON strings, so the value is safe in either kind of quotes.
+var jsonAttrEscaper = strings.NewReplacer(
+	`&`, "&amp;",
+	`"`, "&#34;",
+	`'`, "&#39;",
+	`<`, "&lt;",
+	`>`, "&gt;",
+)
+
+// escapeJSONAttr escapes JSON for use inside a quoted attribute value.
+func escapeJSONAttr(s string) string {
+	return jsonAttrEscaper.Replace(s)
+}
+
+// jsEscaper escapes a single-quoted JavaScript string.
+var jsEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\n", `\n`, "\r", `\r`)
+
+// jsQuote escapes s for use inside a single-quoted JavaScript string.
+func jsQuote(s string) string {
+	return jsEscaper.Replace(s)
+}
+
+// attrName drops characters that aren't allowed in attribute names, so a
+// name can't end the attribute or add another.
+func attrName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9',
+			r == '-', r == '_', r == '.', r == ':':
+			return r
+		}
+		return -1
+	}, s)
 }
 
 // --- Conditional Helpers ---
@@ -246,18 +570,267 @@ func ifFunc(condition bool, trueVal, falseVal any) any {
 	return falseVal
 }
 
+// defaultFunc returns def when val is empty (see isEmpty):
+// {{default .Title "Untitled"}}
 func defaultFunc(val, def any) any {
-	if val == nil || val == "" || val == 0 || val == false {
+	if isEmpty(val) {
 		return def
 	}
 	return val
 }
 
+// coalesce returns the first value that isn't empty, or nil.
 func coalesce(vals ...any) any {
 	for _, v := range vals {
-		if v != nil && v != "" && v != 0 && v != false {
+		if !isEmpty(v) {
 			return v
 		}
 	}
 	return nil
 }
+
+// emptyCollectionsKept is set by EmptyCollectionsAsZero(false).
+var emptyCollectionsKept atomic.Bool
+
+// EmptyCollectionsAsZero sets whether default and coalesce treat empty,
+// non-nil slices, maps and channels as empty (the default). When false,
+// only nil ones are.
+func EmptyCollectionsAsZero(on bool) {
+	emptyCollectionsKept.Store(!on)
+}
+
+// isEmpty reports whether v is nil or the zero value of its type: 0 of
+// any numeric type, "", false, a nil pointer or interface, a zero struct
+// such as time.Time{}, or an empty slice or map. A non-nil pointer is
+// never empty, even to a zero value.
+func isEmpty(v any) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.Func:
+		return rv.IsNil()
+	case reflect.Slice, reflect.Map, reflect.Chan:
+		if emptyCollectionsKept.Load() {
+			return rv.IsNil()
+		}
+		return rv.Len() == 0
+	}
+	return rv.IsZero()
+}
+
+// --- Formatting Helpers ---
+
+// timeNow is the clock used by timeAgo, replaced in tests.
+var timeNow = time.Now
+
+// formatDate formats t with a time layout, e.g.
+// {{formatDate .CreatedAt "2 Jan 2006"}}. t may be a time.Time, a
+// *time.Time or unix seconds; nil and zero times render "".
+func formatDate(t any, layout string) string {
+	tm, ok := toTime(t)
+	if !ok {
+		return ""
+	}
+	return tm.Format(layout)
+}
+
+// timeAgo describes t relative to now, e.g. "3 minutes ago" or
+// "in 2 days". It takes the same values as formatDate.
+func timeAgo(t any) string {
+	tm, ok := toTime(t)
+	if !ok {
+		return ""
+	}
+	d := timeNow().Sub(tm)
+	future := d < 0
+	if future {
+		d = -d
+	}
+	if d < time.Minute {
+		return "just now"
+	}
+
+	var n int
+	var unit string
+	switch {
+	case d < time.Hour:
+		n, unit = int(d/time.Minute), "minute"
+	case d < 24*time.Hour:
+		n, unit = int(d/time.Hour), "hour"
+	case d < 30*24*time.Hour:
+		n, unit = int(d/(24*time.Hour)), "day"
+	case d < 365*24*time.Hour:
+		n, unit = int(d/(30*24*time.Hour)), "month"
+	default:
+		n, unit = int(d/(365*24*time.Hour)), "year"
+	}
+	if n != 1 {
+		unit += "s"
+	}
+	if future {
+		return fmt.Sprintf("in %d %s", n, unit)
+	}
+	return fmt.Sprintf("%d %s ago", n, unit)
+}
+
+// toTime converts a time.Time, *time.Time or unix seconds to a time,
+// reporting false for nil and zero values.
+func toTime(t any) (time.Time, bool) {
+	var tm time.Time
+	switch v := t.(type) {
+	case time.Time:
+		tm = v
+	case *time.Time:
+		if v == nil {
+			return time.Time{}, false
+		}
+		tm = *v
+	case int64:
+		if v == 0 {
+			return time.Time{}, false
+		}
+		tm = time.Unix(v, 0)
+	case int:
+		if v == 0 {
+			return time.Time{}, false
+		}
+		tm = time.Unix(int64(v), 0)
+	default:
+		return time.Time{}, false
+	}
+	return tm, !tm.IsZero()
+}
+
+// truncate shortens s to at most n characters (runes, not bytes),
+// appending suffix when anything was cut: {{truncate .Body 80 "…"}}
+func truncate(s string, n int, suffix string) string {
+	if n < 0 {
+		n = 0
+	}
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	i := 0
+	for pos := range s {
+		if i == n {
+			return s[:pos] + suffix
+		}
+		i++
+	}
+	return s
+}
+
+// pluralize returns singular when n is 1 and plural otherwise:
+// {{len .Todos}} {{pluralize (len .Todos) "item" "items"}}
+func pluralize(n any, singular, plural string) string {
+	if f, ok := toFloat(n); ok && f == 1 {
+		return singular
+	}
+	return plural
+}
+
+// number formats an integer or float with thousands separators, e.g.
+// 1234567.5 as "1,234,567.5". nil and non-numeric values render "".
+func number(v any) string {
+	rv, ok := numberValue(v)
+	if !ok {
+		return ""
+	}
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return groupThousands(strconv.FormatInt(rv.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return groupThousands(strconv.FormatUint(rv.Uint(), 10))
+	default:
+		return groupThousands(strconv.FormatFloat(rv.Float(), 'f', -1, 64))
+	}
+}
+
+// currency formats v with two decimal places and a symbol, e.g.
+// {{currency .Total "$"}} renders "$1,234.50" or "-$3.00". nil and
+// non-numeric values render "".
+func currency(v any, symbol string) string {
+	f, ok := toFloat(v)
+	if !ok {
+		return ""
+	}
+	sign := ""
+	if f < 0 {
+		sign, f = "-", -f
+	}
+	return sign + symbol + groupThousands(strconv.FormatFloat(f, 'f', 2, 64))
+}
+
+// titleCase upper-cases the first letter of each space-separated word.
+func titleCase(s string) string {
+	start := true
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			start = true
+			return r
+		}
+		if start {
+			start = false
+			return unicode.ToUpper(r)
+		}
+		return r
+	}, s)
+}
+
+// numberValue returns the numeric value v holds, following pointers.
+func numberValue(v any) (reflect.Value, bool) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return reflect.Value{}, false
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return rv, true
+	}
+	return reflect.Value{}, false
+}
+
+// toFloat converts a numeric value to float64.
+func toFloat(v any) (float64, bool) {
+	rv, ok := numberValue(v)
+	if !ok {
+		return 0, false
+	}
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(rv.Uint()), true
+	default:
+		return rv.Float(), true
+	}
+}
+
+// groupThousands inserts commas into the integer part of a formatted
+// number, e.g. "-1234567.25" becomes "-1,234,567.25".
+func groupThousands(s string) string {
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	whole, frac, hasFrac := strings.Cut(s, ".")
+	var b strings.Builder
+	for i, c := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(c)
+	}
+	if hasFrac {
+		b.WriteByte('.')
+		b.WriteString(frac)
+	}
+	return sign + b.String()
+}
@@ -0,0 +1,543 @@
+package render
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHelperEscaping(t *testing.T) {
+	tests := []struct {
+		name string
+		got  template.HTMLAttr
+		want string
+	}{
+		// Safe values are unchanged
+		{"dsGet", dsGet("/todos/1"), `data-on:click="@get('/todos/1')"`},
+		{"dsOnClick", dsOnClick("$open = !$open"), `data-on:click="$open = !$open"`},
+		{"dsText", dsText("$count + ' items'"), `data-text="$count + ' items'"`},
+		{"dsClass", dsClass("text-red-500", "$error"), `data-class:text-red-500="$error"`},
+		{"dsBind", dsBind("user.name"), `data-bind:user.name`},
+		{"attr", attr("aria-label", "Close menu"), `aria-label="Close menu"`},
+		{"class", class("btn", " primary "), `class="btn primary"`},
+		{"unicode", attr("title", "Café – 日本語 ✓"), `title="Café – 日本語 ✓"`},
+
+		// Quotes and markup can't end the attribute
+		{"dsGet quote", dsGet(`/x')" onmouseover="alert(1)`),
+			`data-on:click="@get('/x\')&#34; onmouseover=&#34;alert(1)')"`},
+		{"dsPost backslash", dsPost(`/x\'`), `data-on:click="@post('/x\\\'')"`},
+		{"dsOnClick quote", dsOnClick(`$a" onclick="evil()`), `data-on:click="$a&#34; onclick=&#34;evil()"`},
+		{"dsShow comparison", dsShow(`$count > 0 && $open`), `data-show="$count &gt; 0 &amp;&amp; $open"`},
+		{"dsText script", dsText(`'</script><script>alert(1)</script>'`),
+			`data-text="'&lt;/script&gt;&lt;script&gt;alert(1)&lt;/script&gt;'"`},
+		{"dsSignals", dsSignals(`{"name": "Ada"}`), `data-signals="{&#34;name&#34;: &#34;Ada&#34;}"`},
+		{"dsSignalsJSON", dsSignalsJSON(map[string]string{"q": `"><img src=x>`}),
+			`data-signals="{&#34;q&#34;:&#34;\&#34;\u003e\u003cimg src=x\u003e&#34;}"`},
+		{"attr value", attr("title", `a "quoted" <b>title</b>`), `title="a &#34;quoted&#34; &lt;b&gt;title&lt;/b&gt;"`},
+		{"attr newline", attr("title", "line one\nline two"), "title=\"line one\nline two\""},
+		{"class quote", class(`btn" onclick="x`), `class="btn&#34; onclick=&#34;x"`},
+
+		// Names lose characters that would end them
+		{"attr name", attr(`title onclick="x"`, "y"), `titleonclickx="y"`},
+		{"dsBind name", dsBind(`name" autofocus onfocus="x`), `data-bind:nameautofocusonfocusx`},
+		{"dsAttr name", dsAttr("disabled>", "$busy"), `data-attr:disabled="$busy"`},
+	}
+	for _, tt := range tests {
+		if string(tt.got) != tt.want {
+			t.Errorf("%s:\n got %s\nwant %s", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+// TestHelperEscapingInTemplate checks escaped helpers survive html/template
+// and leave exactly the attributes they generate.
+func TestHelperEscapingInTemplate(t *testing.T) {
+	e := New()
+	if err := e.Parse("btn", `<button {{dsOnClick .}}>Go</button>`); err != nil {
+		t.Fatal(err)
+	}
+	html, err := e.Render("btn", `alert("hi")</button><script>x()</script>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(html, "<") != 2 || strings.Count(html, `"`) != 2 {
+		t.Errorf("value escaped its attribute: %s", html)
+	}
+}
+
+func TestDsOnHelpers(t *testing.T) {
+	StrictExpressions()
+	t.Cleanup(func() { CheckExpressions(false) })
+
+	tests := []struct {
+		name string
+		got  template.HTMLAttr
+		want string
+	}{
+		{"dsOn", dsOn("dblclick", "$editing = true"), `data-on:dblclick="$editing = true"`},
+		{"dsOn modifiers", dsOn("submit__prevent", "@post('/todos')"), `data-on:submit__prevent="@post('/todos')"`},
+		{"dsGetOn", dsGetOn("load", "/feed"), `data-on:load="@get('/feed')"`},
+		{"dsPostOn", dsPostOn("submit__prevent", "/todos"), `data-on:submit__prevent="@post('/todos')"`},
+		{"dsPutOn", dsPutOn("change", "/todos/1"), `data-on:change="@put('/todos/1')"`},
+		{"dsPatchOn", dsPatchOn("blur", "/todos/1"), `data-on:blur="@patch('/todos/1')"`},
+		{"dsDeleteOn", dsDeleteOn("dblclick", "/todos/1"), `data-on:dblclick="@delete('/todos/1')"`},
+		{"dsGet unchanged", dsGet("/todos"), `data-on:click="@get('/todos')"`},
+		{"dsOnDebounce", dsOnDebounce("input", "300ms", "@get('/search')"),
+			`data-on:input__debounce.300ms="@get('/search')"`},
+		{"dsOnThrottle", dsOnThrottle("scroll", "100ms", "@get('/more')"),
+			`data-on:scroll__throttle.100ms="@get('/more')"`},
+		{"dsOnKeydown", dsOnKeydown("Enter", "@post('/todos')"),
+			`data-on:keydown="if (evt.key === 'Enter') { @post('/todos') }"`},
+		{"dsOnKeydown escape", dsOnKeydown("Escape", "$editing = false"),
+			`data-on:keydown="if (evt.key === 'Escape') { $editing = false }"`},
+		{"dsOnKeydown quoted key", dsOnKeydown(`'`, "$n++"),
+			`data-on:keydown="if (evt.key === '\'') { $n++ }"`},
+	}
+	for _, tt := range tests {
+		if string(tt.got) != tt.want {
+			t.Errorf("%s:\n got %s\nwant %s", tt.name, tt.got, tt.want)
+		}
+	}
+
+	// Registered for templates
+	e := New()
+	if err := e.Parse("search", `<input {{dsGetOn "input__debounce.300ms" "/search"}} {{dsOnKeydown "Enter" "@post('/todos')"}}>`); err != nil {
+		t.Fatal(err)
+	}
+	html, err := e.Render("search", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `<input data-on:input__debounce.300ms="@get('/search')" data-on:keydown="if (evt.key === 'Enter') { @post('/todos') }">`; html != want {
+		t.Errorf("got %s\nwant %s", html, want)
+	}
+}
+
+func TestFormatDate(t *testing.T) {
+	tm := time.Date(2024, 3, 9, 14, 5, 0, 0, time.UTC)
+	var nilTime *time.Time
+	tests := []struct {
+		name string
+		in   any
+		want string
+	}{
+		{"time", tm, "2024-03-09 14:05"},
+		{"pointer", &tm, "2024-03-09 14:05"},
+		{"unix int64", tm.Unix(), "2024-03-09 14:05"},
+		{"unix int", int(tm.Unix()), "2024-03-09 14:05"},
+		{"nil", nil, ""},
+		{"nil pointer", nilTime, ""},
+		{"zero time", time.Time{}, ""},
+		{"zero pointer", &time.Time{}, ""},
+		{"zero unix", int64(0), ""},
+		{"string", "2024-03-09", ""},
+	}
+	for _, tt := range tests {
+		if got := formatDate(tt.in, "2006-01-02 15:04"); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTimeAgo(t *testing.T) {
+	now := time.Date(2024, 3, 9, 12, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	t.Cleanup(func() { timeNow = time.Now })
+
+	ago := func(d time.Duration) time.Time { return now.Add(-d) }
+	var nilTime *time.Time
+	tests := []struct {
+		name string
+		in   any
+		want string
+	}{
+		{"seconds", ago(30 * time.Second), "just now"},
+		{"one minute", ago(time.Minute), "1 minute ago"},
+		{"minutes", ago(3*time.Minute + 20*time.Second), "3 minutes ago"},
+		{"hours", ago(5 * time.Hour), "5 hours ago"},
+		{"one day", ago(25 * time.Hour), "1 day ago"},
+		{"months", ago(65 * 24 * time.Hour), "2 months ago"},
+		{"years", ago(800 * 24 * time.Hour), "2 years ago"},
+		{"future", now.Add(2*24*time.Hour + time.Hour), "in 2 days"},
+		{"pointer", func() *time.Time { t := ago(time.Hour); return &t }(), "1 hour ago"},
+		{"unix", ago(10 * time.Minute).Unix(), "10 minutes ago"},
+		{"nil", nil, ""},
+		{"nil pointer", nilTime, ""},
+		{"zero", time.Time{}, ""},
+		{"zero unix", 0, ""},
+	}
+	for _, tt := range tests {
+		if got := timeAgo(tt.in); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTextFormatting(t *testing.T) {
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"truncate short", truncate("hello", 10, "…"), "hello"},
+		{"truncate exact", truncate("hello", 5, "…"), "hello"},
+		{"truncate cut", truncate("hello world", 5, "…"), "hello…"},
+		{"truncate runes", truncate("日本語テキスト", 3, "..."), "日本語..."},
+		{"truncate zero", truncate("hello", 0, "…"), "…"},
+		{"truncate negative", truncate("hello", -1, ""), ""},
+		{"truncate empty", truncate("", 3, "…"), ""},
+
+		{"pluralize one", pluralize(1, "item", "items"), "item"},
+		{"pluralize zero", pluralize(0, "item", "items"), "items"},
+		{"pluralize many", pluralize(int64(5), "item", "items"), "items"},
+		{"pluralize float", pluralize(1.0, "item", "items"), "item"},
+		{"pluralize nil", pluralize(nil, "item", "items"), "items"},
+
+		{"titleCase", titleCase("hello big world"), "Hello Big World"},
+		{"titleCase mixed", titleCase("éclair and iPhone"), "Éclair And IPhone"},
+		{"titleCase empty", titleCase(""), ""},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestNumberFormatting(t *testing.T) {
+	n := 1234567
+	var nilInt *int
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"number small", number(999), "999"},
+		{"number zero", number(0), "0"},
+		{"number int", number(1234567), "1,234,567"},
+		{"number int64", number(int64(-1234567)), "-1,234,567"},
+		{"number uint", number(uint32(1000)), "1,000"},
+		{"number float", number(1234567.25), "1,234,567.25"},
+		{"number float32", number(float32(1500.5)), "1,500.5"},
+		{"number pointer", number(&n), "1,234,567"},
+		{"number nil", number(nil), ""},
+		{"number nil pointer", number(nilInt), ""},
+		{"number string", number("1234"), ""},
+
+		{"currency", currency(1234.5, "$"), "$1,234.50"},
+		{"currency int", currency(3, "£"), "£3.00"},
+		{"currency negative", currency(-1234567.891, "€"), "-€1,234,567.89"},
+		{"currency zero", currency(0, "$"), "$0.00"},
+		{"currency nil", currency(nil, "$"), ""},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestFormattingInTemplate(t *testing.T) {
+	e := New()
+	tmpl := `{{number .Count}} {{pluralize .Count "todo" "todos"}}, due {{formatDate .Due "2 Jan"}}{{formatDate .Done "2 Jan"}}`
+	if err := e.Parse("summary", tmpl); err != nil {
+		t.Fatal(err)
+	}
+	html, err := e.Render("summary", map[string]any{
+		"Count": 1200,
+		"Due":   time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC),
+		"Done":  (*time.Time)(nil),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "1,200 todos, due 9 Mar"; html != want {
+		t.Errorf("got %q, want %q", html, want)
+	}
+}
+
+func TestClassHelpers(t *testing.T) {
+	tests := []struct {
+		name string
+		got  template.HTMLAttr
+		want string
+	}{
+		{"strings", class("btn", "", "  primary "), `class="btn primary"`},
+		{"empty", class("", nil, classIf(false, "x")), ``},
+		{"classIf", class("tab", classIf(true, "active"), classIf(false, "disabled")), `class="tab active"`},
+		{"map sorted", class(map[string]bool{"zeta": true, "alpha": true, "mid": true, "off": false}),
+			`class="alpha mid zeta"`},
+		{"mixed", class("btn", map[string]bool{"loading": true, "error": false}, []string{"w-full", ""}, classIf(true, "active")),
+			`class="btn loading w-full active"`},
+		{"duplicates", class("btn btn-lg", "btn", map[string]bool{"btn-lg": true}), `class="btn btn-lg"`},
+		{"other types", class("col", 3), `class="col 3"`},
+		{"classMap", classMap(map[string]bool{"b": true, "a": true, "c": false}), `class="a b"`},
+		{"classMap empty", classMap(nil), ``},
+		{"escaped name", class(`x" onclick="evil()`), `class="x&#34; onclick=&#34;evil()"`},
+		{"escaped map key", classMap(map[string]bool{`"><script>`: true}), `class="&#34;&gt;&lt;script&gt;"`},
+	}
+	for _, tt := range tests {
+		if string(tt.got) != tt.want {
+			t.Errorf("%s:\n got %s\nwant %s", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestClassMapStableOrder(t *testing.T) {
+	classes := map[string]bool{}
+	for _, c := range strings.Fields("p-4 flex rounded shadow bg-white text-sm gap-2 items-center") {
+		classes[c] = true
+	}
+	want := `class="bg-white flex gap-2 items-center p-4 rounded shadow text-sm"`
+	for range 20 {
+		if got := string(classMap(classes)); got != want {
+			t.Fatalf("got %s, want %s", got, want)
+		}
+	}
+}
+
+func TestClassHelpersInTemplate(t *testing.T) {
+	e := New()
+	if err := e.Parse("tab", `<a {{class "tab" (classIf .Selected "active") .Extra}}>Tab</a>`); err != nil {
+		t.Fatal(err)
+	}
+	html, err := e.Render("tab", map[string]any{
+		"Selected": true,
+		"Extra":    map[string]bool{"bold": true, "muted": false},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `<a class="tab active bold">Tab</a>`; html != want {
+		t.Errorf("got %s, want %s", html, want)
+	}
+}
+
+func TestDefaultAndCoalesce(t *testing.T) {
+	type point struct{ X, Y int }
+	n, zero := 5, 0
+	var nilInt *int
+	var nilErr error
+	var nilSlice []string
+	var nilMap map[string]int
+	var nilFunc func()
+
+	tests := []struct {
+		name  string
+		val   any
+		empty bool
+	}{
+		{"nil", nil, true},
+		{"int 0", 0, true},
+		{"int8 0", int8(0), true},
+		{"int16 0", int16(0), true},
+		{"int32 0", int32(0), true},
+		{"int64 0", int64(0), true},
+		{"uint 0", uint(0), true},
+		{"uint8 0", uint8(0), true},
+		{"uint64 0", uint64(0), true},
+		{"float32 0", float32(0), true},
+		{"float64 0", float64(0), true},
+		{"complex 0", complex128(0), true},
+		{"empty string", "", true},
+		{"false", false, true},
+		{"nil pointer", nilInt, true},
+		{"nil interface", nilErr, true},
+		{"nil slice", nilSlice, true},
+		{"empty slice", []string{}, true},
+		{"nil map", nilMap, true},
+		{"empty map", map[string]int{}, true},
+		{"nil func", nilFunc, true},
+		{"zero time", time.Time{}, true},
+		{"zero struct", point{}, true},
+		{"zero array", [2]int{}, true},
+
+		{"int", 7, false},
+		{"negative int64", int64(-1), false},
+		{"uint8", uint8(1), false},
+		{"float", 0.5, false},
+		{"string", "x", false},
+		{"space", " ", false},
+		{"true", true, false},
+		{"pointer", &n, false},
+		{"pointer to zero", &zero, false},
+		{"slice", []string{""}, false},
+		{"map", map[string]int{"a": 0}, false},
+		{"time", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), false},
+		{"struct", point{X: 1}, false},
+		{"array", [2]int{0, 1}, false},
+		{"func", func() {}, false},
+	}
+	for _, tt := range tests {
+		want := tt.val
+		if tt.empty {
+			want = "fallback"
+		}
+		got := defaultFunc(tt.val, "fallback")
+		if tt.empty && got != "fallback" {
+			t.Errorf("default(%s) = %v, want the default", tt.name, got)
+		}
+		if !tt.empty && !sameValue(got, want) {
+			t.Errorf("default(%s) = %v, want the value", tt.name, got)
+		}
+
+		if got := coalesce(tt.val, "fallback"); tt.empty != (got == "fallback") {
+			t.Errorf("coalesce(%s) = %v", tt.name, got)
+		}
+	}
+
+	if got := coalesce(int64(0), "", nilInt, float64(2.5), 3); got != 2.5 {
+		t.Errorf("coalesce picked %v, want 2.5", got)
+	}
+	if got := coalesce(0, "", nil); got != nil {
+		t.Errorf("coalesce of empty values = %v, want nil", got)
+	}
+}
+
+// sameValue compares values that may not be comparable with ==.
+func sameValue(a, b any) bool {
+	return fmt.Sprintf("%#v", a) == fmt.Sprintf("%#v", b)
+}
+
+func TestEmptyCollectionsAsZero(t *testing.T) {
+	EmptyCollectionsAsZero(false)
+	t.Cleanup(func() { EmptyCollectionsAsZero(true) })
+
+	var nilSlice []int
+	if got := defaultFunc([]int{}, "fallback"); sameValue(got, "fallback") {
+		t.Error("empty slice treated as empty")
+	}
+	if got := defaultFunc(map[string]int{}, "fallback"); sameValue(got, "fallback") {
+		t.Error("empty map treated as empty")
+	}
+	if got := defaultFunc(nilSlice, "fallback"); got != "fallback" {
+		t.Errorf("nil slice = %v, want the default", got)
+	}
+}
+
+func TestDefaultInTemplate(t *testing.T) {
+	e := New()
+	if err := e.Parse("count", `{{default .Count "none"}} {{default .Name "anon"}} {{coalesce .Nick .Name "?"}}`); err != nil {
+		t.Fatal(err)
+	}
+	html, err := e.Render("count", map[string]any{"Count": int64(0), "Name": "Ada", "Nick": (*string)(nil)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "none Ada Ada"; html != want {
+		t.Errorf("got %q, want %q", html, want)
+	}
+}
+
+func TestDsSignalsJSONErrors(t *testing.T) {
+	var logs bytes.Buffer
+	SetLogger(log.New(&logs, "", 0))
+	t.Cleanup(func() { SetLogger(nil) })
+
+	tests := []struct {
+		name string
+		data any
+		want string
+	}{
+		{"NaN", map[string]float64{"x": math.NaN()}, "unsupported value: NaN"},
+		{"Inf", []float64{math.Inf(1)}, "unsupported value: +Inf"},
+		{"func", map[string]any{"f": func() {}}, "unsupported type: func()"},
+		{"chan", make(chan int), "unsupported type: chan int"},
+	}
+	for _, tt := range tests {
+		logs.Reset()
+		if got := dsSignalsJSON(tt.data); got != "" {
+			t.Errorf("%s: dsSignalsJSON = %s, want nothing", tt.name, got)
+		}
+		if !strings.Contains(logs.String(), "render: dsSignalsJSON: ") || !strings.Contains(logs.String(), tt.want) {
+			t.Errorf("%s: logged %q, want %q", tt.name, logs.String(), tt.want)
+		}
+
+		got := string(dsSignalsJSONDebug(tt.data))
+		if !strings.HasPrefix(got, `data-signals-error="dsSignalsJSON: `) || !strings.Contains(got, tt.want) {
+			t.Errorf("%s: debug = %s, want an error attribute with %q", tt.name, got, tt.want)
+		}
+
+		func() {
+			defer func() {
+				r := recover()
+				if r == nil || !strings.Contains(fmt.Sprint(r), tt.want) {
+					t.Errorf("%s: strict panicked with %v, want %q", tt.name, r, tt.want)
+				}
+			}()
+			dsSignalsJSONStrict(tt.data)
+		}()
+	}
+}
+
+func TestDsSignalsJSONQuotes(t *testing.T) {
+	data := map[string]string{"name": `O'Brien "Bob"`}
+	want := `data-signals="{&#34;name&#34;:&#34;O&#39;Brien \&#34;Bob\&#34;&#34;}"`
+	if got := dsSignalsJSON(data); string(got) != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+	if got := dsSignalsJSONStrict(data); string(got) != want {
+		t.Errorf("strict:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestDsSignalsJSONInTemplate(t *testing.T) {
+	SetLogger(log.New(io.Discard, "", 0))
+	t.Cleanup(func() { SetLogger(nil) })
+	bad := map[string]any{"Signals": map[string]float64{"x": math.NaN()}}
+
+	e := New()
+	if err := e.Parse("lax", `<div {{dsSignalsJSON .Signals}}></div>`); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Parse("strict", `<div {{dsSignalsJSONStrict .Signals}}></div>`); err != nil {
+		t.Fatal(err)
+	}
+	if html, err := e.Render("lax", bad); err != nil || html != `<div ></div>` {
+		t.Errorf("lax: got %q, %v", html, err)
+	}
+	if _, err := e.Render("strict", bad); err == nil || !strings.Contains(err.Error(), "unsupported value: NaN") {
+		t.Errorf("strict: err = %v, want the marshal error", err)
+	}
+
+	e.SetDebug(true)
+	html, err := e.Render("lax", bad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `<div data-signals-error="dsSignalsJSON: json: unsupported value: NaN"></div>`; html != want {
+		t.Errorf("debug:\n got %s\nwant %s", html, want)
+	}
+
+	e.SetDebug(false)
+	if html, _ := e.Render("lax", bad); html != `<div ></div>` {
+		t.Errorf("debug off: got %q", html)
+	}
+}
+
+// failingMarshaler fails to encode with an error holding markup.
+type failingMarshaler struct{}
+
+func (failingMarshaler) MarshalJSON() ([]byte, error) {
+	return nil, errors.New(`bad "value"><b>`)
+}
+
+func TestDsSignalsJSONDebugEscapes(t *testing.T) {
+	SetLogger(log.New(io.Discard, "", 0))
+	t.Cleanup(func() { SetLogger(nil) })
+
+	got := string(dsSignalsJSONDebug(failingMarshaler{}))
+	if strings.ContainsAny(strings.TrimSuffix(strings.TrimPrefix(got, `data-signals-error="`), `"`), `"<>`) {
+		t.Errorf("debug = %s, want the error escaped inside the attribute", got)
+	}
+	if !strings.Contains(got, "bad &#34;value&#34;&gt;&lt;b&gt;") {
+		t.Errorf("debug = %s, want the escaped error", got)
+	}
+}
@@ -0,0 +1,143 @@
+package render
+
+import (
+	"encoding/json"
+	"html/template"
+	"strings"
+)
+
+// HTMXFuncs returns the HTMX attribute helpers, also included in
+// DefaultFuncs. Each returns one escaped attribute; hx merges several:
+//
+//	<button {{hx (hxDelete "/todos/1") (hxTarget "closest li") (hxSwap "outerHTML") (hxConfirm "Delete?")}}>
+func HTMXFuncs() template.FuncMap {
+	return template.FuncMap{
+		"hxGet":       hxGet,
+		"hxPost":      hxPost,
+		"hxPut":       hxPut,
+		"hxPatch":     hxPatch,
+		"hxDelete":    hxDelete,
+		"hxTarget":    hxTarget,
+		"hxSwap":      hxSwap,
+		"hxTrigger":   hxTrigger,
+		"hxVals":      hxVals,
+		"hxIndicator": hxIndicator,
+		"hxConfirm":   hxConfirm,
+		"hx":          hx,
+	}
+}
+
+// hxGet generates an hx-get attribute
+func hxGet(url string) template.HTMLAttr {
+	return hxAttr("hx-get", url)
+}
+
+// hxPost generates an hx-post attribute
+func hxPost(url string) template.HTMLAttr {
+	return hxAttr("hx-post", url)
+}
+
+// hxPut generates an hx-put attribute
+func hxPut(url string) template.HTMLAttr {
+	return hxAttr("hx-put", url)
+}
+
+// hxPatch generates an hx-patch attribute
+func hxPatch(url string) template.HTMLAttr {
+	return hxAttr("hx-patch", url)
+}
+
+// hxDelete generates an hx-delete attribute
+func hxDelete(url string) template.HTMLAttr {
+	return hxAttr("hx-delete", url)
+}
+
+// hxTarget generates an hx-target attribute, e.g. {{hxTarget "#list"}}
+func hxTarget(selector string) template.HTMLAttr {
+	return hxAttr("hx-target", selector)
+}
+
+// hxSwap generates an hx-swap attribute, e.g. {{hxSwap "outerHTML"}}
+func hxSwap(strategy string) template.HTMLAttr {
+	return hxAttr("hx-swap", strategy)
+}
+
+// hxTrigger generates an hx-trigger attribute, e.g.
+// {{hxTrigger "keyup changed delay:300ms"}}
+func hxTrigger(spec string) template.HTMLAttr {
+	checkTrigger(spec)
+	return hxAttr("hx-trigger", spec)
+}
+
+// hxVals generates an hx-vals attribute with values JSON-encoded, or
+// logs and generates nothing if values can't be encoded
+func hxVals(values any) template.HTMLAttr {
+	data, err := json.Marshal(values)
+	if err != nil {
+		logf("hxVals: %v", err)
+		return ""
+	}
+	return hxAttr("hx-vals", string(data))
+}
+
+// hxIndicator generates an hx-indicator attribute
+func hxIndicator(selector string) template.HTMLAttr {
+	return hxAttr("hx-indicator", selector)
+}
+
+// hxConfirm generates an hx-confirm attribute
+func hxConfirm(message string) template.HTMLAttr {
+	return hxAttr("hx-confirm", message)
+}
+
+func hxAttr(name, value string) template.HTMLAttr {
+	return template.HTMLAttr(name + `="` + escapeAttr(value) + `"`)
+}
+
+// hx merges attributes into one list. Empty ones are skipped, and when
+// an attribute appears more than once the last value wins, so defaults
+// can be overridden: {{hx .Defaults (hxSwap "outerHTML")}}
+func hx(attrs ...template.HTMLAttr) template.HTMLAttr {
+	var order []string
+	values := make(map[string]string)
+	for _, a := range attrs {
+		for _, attr := range splitAttrs(string(a)) {
+			name, _, _ := strings.Cut(attr, "=")
+			if _, ok := values[name]; !ok {
+				order = append(order, name)
+			}
+			values[name] = attr
+		}
+	}
+	merged := make([]string, len(order))
+	for i, name := range order {
+		merged[i] = values[name]
+	}
+	return template.HTMLAttr(strings.Join(merged, " "))
+}
+
+// splitAttrs splits an attribute list like `a="x y" b` into attributes.
+// Values are double-quoted, as the helpers produce them.
+func splitAttrs(s string) []string {
+	var attrs []string
+	start, quoted := -1, false
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"':
+			quoted = !quoted
+		case (c == ' ' || c == '\t' || c == '\n') && !quoted:
+			if start >= 0 {
+				attrs = append(attrs, s[start:i])
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		attrs = append(attrs, s[start:])
+	}
+	return attrs
+}
@@ -0,0 +1,114 @@
+package render
+
+import (
+	"bytes"
+	"html/template"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestHxValsLogsErrors(t *testing.T) {
+	var logs bytes.Buffer
+	SetLogger(log.New(&logs, "", 0))
+	t.Cleanup(func() { SetLogger(nil) })
+
+	if got := hxVals(map[string]any{"f": func() {}}); got != "" {
+		t.Errorf("hxVals = %s, want nothing", got)
+	}
+	if want := "render: hxVals: json: unsupported type: func()"; !strings.Contains(logs.String(), want) {
+		t.Errorf("logged %q, want %q", logs.String(), want)
+	}
+}
+
+func TestHTMXHelpers(t *testing.T) {
+	tests := []struct {
+		name string
+		got  template.HTMLAttr
+		want string
+	}{
+		{"hxGet", hxGet("/todos"), `hx-get="/todos"`},
+		{"hxPost", hxPost("/todos"), `hx-post="/todos"`},
+		{"hxPut", hxPut("/todos/1"), `hx-put="/todos/1"`},
+		{"hxPatch", hxPatch("/todos/1"), `hx-patch="/todos/1"`},
+		{"hxDelete", hxDelete("/todos/1"), `hx-delete="/todos/1"`},
+		{"hxTarget", hxTarget("closest li"), `hx-target="closest li"`},
+		{"hxSwap", hxSwap("outerHTML swap:200ms"), `hx-swap="outerHTML swap:200ms"`},
+		{"hxTrigger", hxTrigger("keyup changed delay:300ms"), `hx-trigger="keyup changed delay:300ms"`},
+		{"hxIndicator", hxIndicator("#spinner"), `hx-indicator="#spinner"`},
+		{"hxConfirm", hxConfirm("Delete this todo?"), `hx-confirm="Delete this todo?"`},
+		{"hxVals", hxVals(map[string]any{"id": 1, "done": true}), `hx-vals="{&#34;done&#34;:true,&#34;id&#34;:1}"`},
+
+		// Quotes and markup can't end the attribute
+		{"hxGet quote", hxGet(`/x" onclick="evil()`), `hx-get="/x&#34; onclick=&#34;evil()"`},
+		{"hxConfirm markup", hxConfirm(`Delete "<b>all</b>" & go?`),
+			`hx-confirm="Delete &#34;&lt;b&gt;all&lt;/b&gt;&#34; &amp; go?"`},
+		{"hxVals value", hxVals(map[string]string{"q": `"><img src=x>`}),
+			`hx-vals="{&#34;q&#34;:&#34;\&#34;\u003e\u003cimg src=x\u003e&#34;}"`},
+		{"hxVals unencodable", hxVals(func() {}), ``},
+	}
+	for _, tt := range tests {
+		if string(tt.got) != tt.want {
+			t.Errorf("%s:\n got %s\nwant %s", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestHX(t *testing.T) {
+	tests := []struct {
+		name string
+		got  template.HTMLAttr
+		want string
+	}{
+		{"empty", hx(), ``},
+		{"merge", hx(hxDelete("/todos/1"), hxTarget("closest li"), hxSwap("outerHTML")),
+			`hx-delete="/todos/1" hx-target="closest li" hx-swap="outerHTML"`},
+		{"skips empty", hx(hxGet("/feed"), "", hxVals(func() {})), `hx-get="/feed"`},
+		{"last wins", hx(hxSwap("innerHTML"), hxTarget("#a"), hxSwap("outerHTML")),
+			`hx-swap="outerHTML" hx-target="#a"`},
+		{"nested lists", hx(hx(hxGet("/a"), hxTarget("#list")), hxTarget("#other")),
+			`hx-get="/a" hx-target="#other"`},
+		{"bare attribute", hx(attr("hx-boost", "true"), "disabled", hxGet("/a")),
+			`hx-boost="true" disabled hx-get="/a"`},
+		{"quoted spaces", hx(hxConfirm("Are you sure?"), hxConfirm("Really delete it?")),
+			`hx-confirm="Really delete it?"`},
+		{"mixed with datastar", hx(hxPost("/todos"), dsShow("$open")),
+			`hx-post="/todos" data-show="$open"`},
+	}
+	for _, tt := range tests {
+		if string(tt.got) != tt.want {
+			t.Errorf("%s:\n got %s\nwant %s", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestHTMXHelpersInTemplate(t *testing.T) {
+	e := New()
+	tmpl := `<button {{hx (hxDelete .URL) (hxTarget "closest li") (hxConfirm .Msg)}}>x</button>`
+	if err := e.Parse("btn", tmpl); err != nil {
+		t.Fatal(err)
+	}
+	html, err := e.Render("btn", map[string]string{
+		"URL": "/todos/1",
+		"Msg": `Delete "milk"?</button><script>x()</script>`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<button hx-delete="/todos/1" hx-target="closest li" hx-confirm="Delete &#34;milk&#34;?&lt;/button&gt;&lt;script&gt;x()&lt;/script&gt;">x</button>`
+	if html != want {
+		t.Errorf("got  %s\nwant %s", html, want)
+	}
+	if strings.Count(html, "<") != 2 {
+		t.Errorf("value escaped its attribute: %s", html)
+	}
+}
+
+func TestHTMXFuncsInDefaults(t *testing.T) {
+	defaults := DefaultFuncs()
+	for name := range HTMXFuncs() {
+		if defaults[name] == nil {
+			t.Errorf("DefaultFuncs missing %s", name)
+		}
+	}
+}
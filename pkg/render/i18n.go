@@ -0,0 +1,14 @@
+package render
+
+import (
+	"context"
+
+	"github.com/stukennedy/irgo/pkg/i18n"
+)
+
+// translate translates key into the request's locale, e.g.
+// {{t .Ctx "todos" (len .Todos)}}. Without router.I18nMiddleware it
+// returns the key.
+func translate(ctx context.Context, key string, args ...any) string {
+	return i18n.T(ctx, key, args...)
+}
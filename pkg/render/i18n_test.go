@@ -0,0 +1,35 @@
+package render
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stukennedy/irgo/pkg/i18n"
+)
+
+func TestTranslateFunc(t *testing.T) {
+	b := i18n.NewBundle("en")
+	b.Add("en", map[string]string{"todos.one": "%d todo", "todos.other": "%d todos"})
+	b.Add("fr", map[string]string{"todos.one": "%d tâche", "todos.other": "%d tâches"})
+
+	e := New()
+	if err := e.Parse("count", `{{t . "todos" 1}}, {{t . "todos" 3}}`); err != nil {
+		t.Fatal(err)
+	}
+
+	html, err := e.Render("count", i18n.WithContext(context.Background(), b.Localizer("fr")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if html != "1 tâche, 3 tâches" {
+		t.Errorf("got %q", html)
+	}
+
+	html, err = e.Render("count", context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if html != "todos, todos" {
+		t.Errorf("without localizer got %q, want the key", html)
+	}
+}
@@ -0,0 +1,291 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// IconOptions controls how NewIconSet prepares SVG files.
+type IconOptions struct {
+	// StripSize removes width and height from each icon's root <svg>,
+	// so icons are sized with CSS classes.
+	StripSize bool
+	// CurrentColor sets fill="currentColor" on each icon's root <svg>,
+	// so icons take the surrounding text colour.
+	CurrentColor bool
+	// Debug renders unknown icon names as a visible placeholder rather
+	// than nothing, for development.
+	Debug bool
+}
+
+// IconSet holds SVG icons loaded at startup for the icon template
+// functions:
+//
+//	icons, err := render.NewIconSet(os.DirFS("static/icons"), render.IconOptions{StripSize: true, CurrentColor: true})
+//	engine.AddFuncs(icons.Funcs())
+//
+// {{icon "arrows/left" "w-5 h-5"}} inlines static/icons/arrows/left.svg
+// with the classes added. For icons repeated many times on a page, such
+// as in list rows, render the sprite once with {{iconSprite}} and
+// reference it with {{iconUse "trash" "w-4 h-4"}}.
+type IconSet struct {
+	icons map[string]*svgIcon // By path without ".svg"
+	debug bool
+
+	mu     sync.Mutex
+	warned map[string]bool // Missing names already logged
+}
+
+// svgIcon is an SVG file split into its root element's attributes and
+// its content.
+type svgIcon struct {
+	attrs []svgAttr
+	inner string
+}
+
+type svgAttr struct {
+	name, value string // value as written in the file, unquoted
+}
+
+// NewIconSet loads every .svg file in fsys, naming each by its path
+// without the extension. Returns an error if fsys can't be read or a
+// file has no <svg> element.
+func NewIconSet(fsys fs.FS, opts IconOptions) (*IconSet, error) {
+	s := &IconSet{
+		icons:  make(map[string]*svgIcon),
+		debug:  opts.Debug,
+		warned: make(map[string]bool),
+	}
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || path.Ext(name) != ".svg" {
+			return err
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		icon, err := parseSVG(string(data))
+		if err != nil {
+			return fmt.Errorf("icon %s: %w", name, err)
+		}
+		if opts.StripSize {
+			icon.remove("width", "height")
+		}
+		if opts.CurrentColor {
+			icon.set("fill", "currentColor")
+		}
+		s.icons[strings.TrimSuffix(name, ".svg")] = icon
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// NewIconSetDir is NewIconSet for a directory on disk.
+func NewIconSetDir(dir string, opts IconOptions) (*IconSet, error) {
+	return NewIconSet(os.DirFS(dir), opts)
+}
+
+// Funcs returns the icon, iconUse and iconSprite template functions
+// bound to the set, to replace the defaults with Engine.AddFuncs.
+func (s *IconSet) Funcs() template.FuncMap {
+	return template.FuncMap{
+		"icon":       s.Icon,
+		"iconUse":    s.Use,
+		"iconSprite": s.Sprite,
+	}
+}
+
+// Names returns the names of the loaded icons, sorted.
+func (s *IconSet) Names() []string {
+	names := make([]string, 0, len(s.icons))
+	for name := range s.icons {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Icon returns the named icon's SVG with classes added to its root
+// element. Unknown names render nothing, or a placeholder in debug mode.
+func (s *IconSet) Icon(name string, classes ...string) template.HTML {
+	icon, ok := s.icons[name]
+	if !ok {
+		return s.missing(name)
+	}
+	var b strings.Builder
+	b.WriteString("<svg")
+	writeSVGAttrs(&b, icon.attrs, classes)
+	b.WriteString(">")
+	b.WriteString(icon.inner)
+	b.WriteString("</svg>")
+	return template.HTML(b.String())
+}
+
+// Use returns an <svg> referencing the named icon's symbol in the
+// sprite (see Sprite), which is cheaper than Icon for icons repeated
+// many times on a page.
+func (s *IconSet) Use(name string, classes ...string) template.HTML {
+	icon, ok := s.icons[name]
+	if !ok {
+		return s.missing(name)
+	}
+	var attrs []svgAttr
+	if viewBox, ok := icon.get("viewBox"); ok {
+		attrs = append(attrs, svgAttr{"viewBox", viewBox})
+	}
+	if fill, ok := icon.get("fill"); ok {
+		attrs = append(attrs, svgAttr{"fill", fill})
+	}
+	var b strings.Builder
+	b.WriteString("<svg")
+	writeSVGAttrs(&b, attrs, classes)
+	b.WriteString(`><use href="#` + iconID(name) + `"></use></svg>`)
+	return template.HTML(b.String())
+}
+
+// Sprite returns a hidden <svg> holding each named icon once as a
+// <symbol>, for Use to reference; with no names it holds every icon.
+// Render it once per page, e.g. at the start of the body. Unknown names
+// are skipped.
+func (s *IconSet) Sprite(names ...string) template.HTML {
+	if len(names) == 0 {
+		names = s.Names()
+	}
+	var b strings.Builder
+	b.WriteString(`<svg xmlns="http://www.w3.org/2000/svg" style="display:none">`)
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		icon, ok := s.icons[name]
+		if !ok || seen[name] {
+			continue
+		}
+		seen[name] = true
+		attrs := []svgAttr{{"id", iconID(name)}}
+		if viewBox, ok := icon.get("viewBox"); ok {
+			attrs = append(attrs, svgAttr{"viewBox", viewBox})
+		}
+		b.WriteString("<symbol")
+		writeSVGAttrs(&b, attrs, nil)
+		b.WriteString(">")
+		b.WriteString(icon.inner)
+		b.WriteString("</symbol>")
+	}
+	b.WriteString("</svg>")
+	return template.HTML(b.String())
+}
+
+// missing logs an unknown icon name once, and renders a placeholder for
+// it in debug mode.
+func (s *IconSet) missing(name string) template.HTML {
+	s.mu.Lock()
+	if !s.warned[name] {
+		s.warned[name] = true
+		logf("icon %q not found", name)
+	}
+	s.mu.Unlock()
+	if !s.debug {
+		return ""
+	}
+	return template.HTML(`<span class="icon-missing" title="icon not found" style="color:red;font-weight:bold">[` +
+		template.HTMLEscapeString(name) + `]</span>`)
+}
+
+// iconID is the sprite symbol id for an icon name.
+func iconID(name string) string {
+	return "icon-" + attrName(strings.ReplaceAll(name, "/", "-"))
+}
+
+// svgRoot matches the opening tag of an SVG file's root element.
+var svgRoot = regexp.MustCompile(`(?s)<svg\b([^>]*?)/?>`)
+
+// parseSVG splits an SVG document into its root element's attributes and
+// content, dropping any XML declaration, doctype or comments before it.
+func parseSVG(doc string) (*svgIcon, error) {
+	loc := svgRoot.FindStringSubmatchIndex(doc)
+	if loc == nil {
+		return nil, errors.New("no <svg> element")
+	}
+	icon := &svgIcon{}
+	for _, m := range attrPattern.FindAllStringSubmatch(doc[loc[2]:loc[3]], -1) {
+		value := m[2]
+		if value == "" {
+			value = m[3]
+		}
+		icon.attrs = append(icon.attrs, svgAttr{m[1], value})
+	}
+	if !strings.HasSuffix(doc[loc[0]:loc[1]], "/>") {
+		end := strings.LastIndex(doc, "</svg>")
+		if end < loc[1] {
+			return nil, errors.New("unclosed <svg> element")
+		}
+		icon.inner = strings.TrimSpace(doc[loc[1]:end])
+	}
+	return icon, nil
+}
+
+func (i *svgIcon) get(name string) (string, bool) {
+	for _, a := range i.attrs {
+		if a.name == name {
+			return a.value, true
+		}
+	}
+	return "", false
+}
+
+func (i *svgIcon) set(name, value string) {
+	for j := range i.attrs {
+		if i.attrs[j].name == name {
+			i.attrs[j].value = value
+			return
+		}
+	}
+	i.attrs = append(i.attrs, svgAttr{name, value})
+}
+
+func (i *svgIcon) remove(names ...string) {
+	i.attrs = slices.DeleteFunc(i.attrs, func(a svgAttr) bool {
+		return slices.Contains(names, a.name)
+	})
+}
+
+// writeSVGAttrs writes attrs, adding classes to any class attribute.
+func writeSVGAttrs(b *strings.Builder, attrs []svgAttr, classes []string) {
+	var class []string
+	for _, a := range attrs {
+		if a.name == "class" {
+			class = append(class, a.value)
+			continue
+		}
+		b.WriteString(" " + a.name + `="` + strings.ReplaceAll(a.value, `"`, "&#34;") + `"`)
+	}
+	for _, c := range classes {
+		if c = strings.TrimSpace(c); c != "" {
+			class = append(class, escapeAttr(c))
+		}
+	}
+	if len(class) > 0 {
+		b.WriteString(` class="` + strings.Join(class, " ") + `"`)
+	}
+}
+
+// noIconSet is the default icon function, used until an IconSet's Funcs
+// are added.
+func noIconSet(name string, classes ...string) (template.HTML, error) {
+	return "", fmt.Errorf("icon %q: no icon set; add IconSet.Funcs to the engine", name)
+}
+
+// noIconSprite is the default iconSprite function.
+func noIconSprite(names ...string) (template.HTML, error) {
+	return "", errors.New("iconSprite: no icon set; add IconSet.Funcs to the engine")
+}
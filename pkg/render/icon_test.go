@@ -0,0 +1,138 @@
+package render
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+var testIcons = fstest.MapFS{
+	"trash.svg": {Data: []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<!-- trash can -->
+<svg xmlns="http://www.w3.org/2000/svg" width="24" height="24" viewBox="0 0 24 24" fill="none" class="icon">
+  <path d="M3 6h18"/>
+</svg>
+`)},
+	"arrows/left.svg": {Data: []byte(`<svg viewBox='0 0 16 16' width='16'><path d='M10 4 6 8l4 4'/></svg>`)},
+	"dot.svg":         {Data: []byte(`<svg viewBox="0 0 2 2"/>`)},
+	"README.md":       {Data: []byte(`not an icon`)},
+}
+
+func newTestIcons(t *testing.T, opts IconOptions) *IconSet {
+	t.Helper()
+	icons, err := NewIconSet(testIcons, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return icons
+}
+
+func TestIcon(t *testing.T) {
+	raw := newTestIcons(t, IconOptions{})
+	styled := newTestIcons(t, IconOptions{StripSize: true, CurrentColor: true})
+
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"as loaded", string(raw.Icon("trash")),
+			`<svg xmlns="http://www.w3.org/2000/svg" width="24" height="24" viewBox="0 0 24 24" fill="none" class="icon"><path d="M3 6h18"/></svg>`},
+		{"classes added", string(raw.Icon("trash", "w-5 h-5", "text-red-600")),
+			`<svg xmlns="http://www.w3.org/2000/svg" width="24" height="24" viewBox="0 0 24 24" fill="none" class="icon w-5 h-5 text-red-600"><path d="M3 6h18"/></svg>`},
+		{"stripped and recoloured", string(styled.Icon("trash", "w-5")),
+			`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 24 24" fill="currentColor" class="icon w-5"><path d="M3 6h18"/></svg>`},
+		{"fill added", string(styled.Icon("arrows/left")),
+			`<svg viewBox="0 0 16 16" fill="currentColor"><path d='M10 4 6 8l4 4'/></svg>`},
+		{"self-closing", string(raw.Icon("dot", "a")), `<svg viewBox="0 0 2 2" class="a"></svg>`},
+		{"class escaped", string(raw.Icon("dot", `x" onload="evil()`)),
+			`<svg viewBox="0 0 2 2" class="x&#34; onload=&#34;evil()"></svg>`},
+		{"use", string(styled.Use("arrows/left", "w-4")),
+			`<svg viewBox="0 0 16 16" fill="currentColor" class="w-4"><use href="#icon-arrows-left"></use></svg>`},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s:\n got %s\nwant %s", tt.name, tt.got, tt.want)
+		}
+	}
+
+	if got := raw.Names(); strings.Join(got, ",") != "arrows/left,dot,trash" {
+		t.Errorf("Names = %v", got)
+	}
+}
+
+func TestIconSprite(t *testing.T) {
+	icons := newTestIcons(t, IconOptions{})
+
+	got := string(icons.Sprite("trash", "arrows/left", "trash", "nope", "trash"))
+	want := `<svg xmlns="http://www.w3.org/2000/svg" style="display:none">` +
+		`<symbol id="icon-trash" viewBox="0 0 24 24"><path d="M3 6h18"/></symbol>` +
+		`<symbol id="icon-arrows-left" viewBox="0 0 16 16"><path d='M10 4 6 8l4 4'/></symbol>` +
+		`</svg>`
+	if got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+
+	all := string(icons.Sprite())
+	for _, id := range []string{"icon-trash", "icon-arrows-left", "icon-dot"} {
+		if strings.Count(all, `id="`+id+`"`) != 1 {
+			t.Errorf("sprite of every icon has %s %d times", id, strings.Count(all, id))
+		}
+	}
+}
+
+func TestIconMissing(t *testing.T) {
+	var logs bytes.Buffer
+	SetLogger(log.New(&logs, "", 0))
+	t.Cleanup(func() { SetLogger(nil) })
+
+	prod := newTestIcons(t, IconOptions{})
+	if got := prod.Icon("nope"); got != "" {
+		t.Errorf("production = %q, want nothing", got)
+	}
+	prod.Use("nope")
+	if n := strings.Count(logs.String(), `icon "nope" not found`); n != 1 {
+		t.Errorf("logged %d times, want once:\n%s", n, logs.String())
+	}
+
+	debug := newTestIcons(t, IconOptions{Debug: true})
+	got := string(debug.Icon("<nope>"))
+	if !strings.Contains(got, `class="icon-missing"`) || !strings.Contains(got, "[&lt;nope&gt;]") {
+		t.Errorf("debug = %s, want an escaped placeholder", got)
+	}
+}
+
+func TestIconFuncs(t *testing.T) {
+	e := New()
+	if err := e.Parse("row", `{{iconSprite}}<button>{{iconUse "trash" "w-4"}}</button>{{icon "dot"}}`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.Render("row", nil); err == nil || !strings.Contains(err.Error(), "no icon set") {
+		t.Errorf("err = %v, want an error before icons are added", err)
+	}
+
+	icons := newTestIcons(t, IconOptions{})
+	e.AddFuncs(icons.Funcs())
+	html, err := e.Render("row", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`<symbol id="icon-trash"`, `<use href="#icon-trash"></use>`, `<svg viewBox="0 0 2 2"></svg>`} {
+		if !strings.Contains(html, want) {
+			t.Errorf("missing %s in %s", want, html)
+		}
+	}
+}
+
+func TestNewIconSetErrors(t *testing.T) {
+	broken := fstest.MapFS{"bad.svg": {Data: []byte(`<div>not svg</div>`)}}
+	if _, err := NewIconSet(broken, IconOptions{}); err == nil || !strings.Contains(err.Error(), "bad.svg") {
+		t.Errorf("err = %v, want the bad file named", err)
+	}
+	unclosed := fstest.MapFS{"open.svg": {Data: []byte(`<svg viewBox="0 0 1 1"><path/>`)}}
+	if _, err := NewIconSet(unclosed, IconOptions{}); err == nil {
+		t.Error("expected an error for an unclosed svg")
+	}
+}
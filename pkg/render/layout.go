@@ -0,0 +1,177 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+)
+
+// maxLayoutDepth bounds layout nesting, catching cycles.
+const maxLayoutDepth = 8
+
+// layoutSets caches the template sets built by RenderWithLayout for one
+// published set.
+type layoutSets struct {
+	templates *template.Template // The set the cache was built from
+	sets      map[string]*template.Template
+}
+
+// SetDefaultLayout sets the layout Page renders pages inside, e.g.
+// "layouts/base". An empty name renders pages on their own.
+func (e *Engine) SetDefaultLayout(name string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.defaultLayout = name
+}
+
+// RenderWithLayout renders page with data, then layout with a map
+// holding the result under "content" and the page's data under "data":
+//
+//	{{define "layouts/base"}}
+//	<title>{{block "title" .}}My App{{end}}</title>
+//	<main>{{.content}}</main>
+//	{{end}}
+//
+// Templates named "<page>:<block>" override the layout's blocks for
+// that page only; blocks the page doesn't define keep the layout's
+// default. Block names are scoped this way because every template shares
+// one set, so a bare {{define "title"}} would apply to every page:
+//
+//	{{define "pages/home"}}<h1>Welcome</h1>{{end}}
+//	{{define "pages/home:title"}}Home{{end}}
+//
+// A layout is nested inside another by naming the parent in a
+// "<layout>:parent" template, e.g. {{define "layouts/admin:parent"}}layouts/base{{end}}.
+// Layouts can override their parent's blocks the same way pages do; the
+// page's overrides win.
+func (e *Engine) RenderWithLayout(layout, page string, data any) (string, error) {
+	var buf bytes.Buffer
+	if err := e.RenderWithLayoutTo(&buf, layout, page, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// RenderWithLayoutTo renders like RenderWithLayout, writing to w. The
+// page and any inner layouts are buffered, as each is the content of the
+// next; the outermost layout is written straight to w (see RenderTo).
+func (e *Engine) RenderWithLayoutTo(w io.Writer, layout, page string, data any) error {
+	set, name, outerData, err := e.layoutOuter(layout, page, data)
+	if err != nil {
+		return err
+	}
+	return e.executeTo(w, name, func(w io.Writer) error {
+		return set.ExecuteTemplate(w, name, outerData)
+	})
+}
+
+// layoutOuter renders page and all but the outermost of its layouts,
+// returning the set to render the outermost with, its name and its data.
+func (e *Engine) layoutOuter(layout, page string, data any) (*template.Template, string, any, error) {
+	if err := e.refresh(); err != nil {
+		return nil, "", nil, &TemplateError{Name: page, Err: err}
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.templates == nil {
+		return nil, "", nil, &TemplateError{Name: page, Err: ErrNoTemplates}
+	}
+
+	chain, err := e.layoutChain(layout)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	set, err := e.layoutSet(chain, page)
+	if err != nil {
+		return nil, "", nil, &TemplateError{Name: page, Err: err}
+	}
+	if len(chain) == 0 {
+		return set, page, data, nil
+	}
+
+	var buf bytes.Buffer
+	if err := set.ExecuteTemplate(&buf, page, data); err != nil {
+		return nil, "", nil, e.execError(page, err)
+	}
+	inner, outer := chain[:len(chain)-1], chain[len(chain)-1]
+	for _, name := range inner {
+		content := template.HTML(buf.String())
+		buf.Reset()
+		if err := set.ExecuteTemplate(&buf, name, map[string]any{"content": content, "data": data}); err != nil {
+			return nil, "", nil, e.execError(name, err)
+		}
+	}
+	return set, outer, map[string]any{"content": template.HTML(buf.String()), "data": data}, nil
+}
+
+// layoutChain returns layout followed by its parents, innermost first.
+// Must be called with e.mu held.
+func (e *Engine) layoutChain(layout string) ([]string, error) {
+	var chain []string
+	for name := layout; name != ""; {
+		if e.templates.Lookup(name) == nil {
+			return nil, &TemplateError{Name: name, Err: fmt.Errorf("layout not defined")}
+		}
+		if len(chain) == maxLayoutDepth {
+			return nil, &TemplateError{Name: layout, Err: fmt.Errorf("layouts nested more than %d deep", maxLayoutDepth)}
+		}
+		chain = append(chain, name)
+
+		parent := e.templates.Lookup(name + ":parent")
+		if parent == nil {
+			break
+		}
+		var buf bytes.Buffer
+		if err := parent.Execute(&buf, nil); err != nil {
+			return nil, &TemplateError{Name: name + ":parent", Err: err}
+		}
+		name = strings.TrimSpace(buf.String())
+	}
+	return chain, nil
+}
+
+// layoutSet returns a clone of the templates with the blocks of chain
+// and page overridden, building and caching it on first use. Must be
+// called with e.mu held.
+func (e *Engine) layoutSet(chain []string, page string) (*template.Template, error) {
+	key := page + "\x00" + strings.Join(chain, "\x00")
+
+	e.layoutMu.Lock()
+	defer e.layoutMu.Unlock()
+	if e.layoutCache.templates != e.templates {
+		e.layoutCache = layoutSets{templates: e.templates, sets: make(map[string]*template.Template)}
+	}
+	if set, ok := e.layoutCache.sets[key]; ok {
+		return set, nil
+	}
+
+	if e.templates.Lookup(page) == nil {
+		return nil, fmt.Errorf("page not defined")
+	}
+	set, err := e.base.Clone()
+	if err != nil {
+		return nil, err
+	}
+	// Outermost layout first, so inner layouts and then the page win
+	owners := []string{page}
+	for _, name := range chain {
+		owners = append([]string{name}, owners...)
+	}
+	for _, owner := range owners {
+		prefix := owner + ":"
+		for _, t := range e.base.Templates() {
+			block, ok := strings.CutPrefix(t.Name(), prefix)
+			if !ok || block == "" || block == "parent" || t.Tree == nil {
+				continue
+			}
+			if _, err := set.AddParseTree(block, t.Tree.Copy()); err != nil {
+				return nil, err
+			}
+		}
+	}
+	e.layoutCache.sets[key] = set
+	return set, nil
+}
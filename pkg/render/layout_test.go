@@ -0,0 +1,112 @@
+package render
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func layoutEngine(t *testing.T) *Engine {
+	t.Helper()
+	e := New()
+	err := e.LoadFS(fstest.MapFS{
+		"layouts/base.html": {Data: []byte(`{{define "layouts/base"}}<title>{{block "title" .}}My App{{end}}</title>` +
+			`<nav>{{block "nav" .}}home{{end}}</nav><main>{{.content}}</main>{{end}}`)},
+		"layouts/admin.html": {Data: []byte(`{{define "layouts/admin:parent"}} layouts/base {{end}}` +
+			`{{define "layouts/admin:nav"}}admin{{end}}` +
+			`{{define "layouts/admin"}}<section class="admin">{{.content}}</section>{{end}}`)},
+		"pages/home.html": {Data: []byte(`{{define "pages/home"}}<h1>Hello {{.}}</h1>{{end}}` +
+			`{{define "pages/home:title"}}Home – {{.data}}{{end}}`)},
+		"pages/about.html": {Data: []byte(`{{define "pages/about"}}<p>About</p>{{end}}`)},
+		"pages/users.html": {Data: []byte(`{{define "pages/users"}}<ul></ul>{{end}}` +
+			`{{define "pages/users:title"}}Users{{end}}`)},
+		"pages/settings.html": {Data: []byte(`{{define "pages/settings"}}<form></form>{{end}}` +
+			`{{define "pages/settings:nav"}}settings{{end}}`)},
+	}, "layouts/*.html", "pages/*.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestRenderWithLayout(t *testing.T) {
+	e := layoutEngine(t)
+	tests := []struct {
+		layout, page string
+		want         string
+	}{
+		{"layouts/base", "pages/home",
+			`<title>Home – Ada</title><nav>home</nav><main><h1>Hello Ada</h1></main>`},
+		// Missing blocks keep the layout's defaults, even after another
+		// page overrode them
+		{"layouts/base", "pages/about",
+			`<title>My App</title><nav>home</nav><main><p>About</p></main>`},
+		// Nested layouts, with the inner layout overriding an outer block
+		{"layouts/admin", "pages/users",
+			`<title>Users</title><nav>admin</nav><main><section class="admin"><ul></ul></section></main>`},
+		// The page's overrides beat the inner layout's
+		{"layouts/admin", "pages/settings",
+			`<title>My App</title><nav>settings</nav><main><section class="admin"><form></form></section></main>`},
+	}
+	for _, tt := range tests {
+		// Twice, to exercise the cached set
+		for i := 0; i < 2; i++ {
+			got, err := e.RenderWithLayout(tt.layout, tt.page, "Ada")
+			if err != nil {
+				t.Fatalf("%s in %s: %v", tt.page, tt.layout, err)
+			}
+			if got != tt.want {
+				t.Errorf("%s in %s:\n got %s\nwant %s", tt.page, tt.layout, got, tt.want)
+			}
+		}
+	}
+
+	// Plain renders are unaffected by page blocks
+	if got := e.MustRender("pages/about", nil); got != "<p>About</p>" {
+		t.Errorf("Render = %q", got)
+	}
+}
+
+func TestRenderWithLayoutErrors(t *testing.T) {
+	e := layoutEngine(t)
+	if _, err := e.RenderWithLayout("layouts/missing", "pages/home", nil); err == nil || !strings.Contains(err.Error(), "layouts/missing") {
+		t.Errorf("missing layout: err = %v", err)
+	}
+	if _, err := e.RenderWithLayout("layouts/base", "pages/missing", nil); err == nil || !strings.Contains(err.Error(), "pages/missing") {
+		t.Errorf("missing page: err = %v", err)
+	}
+
+	if err := e.Parse("layouts/loop:parent", `layouts/loop`); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Parse("layouts/loop", `{{.content}}`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.RenderWithLayout("layouts/loop", "pages/home", nil); err == nil {
+		t.Error("layout cycle: expected error")
+	}
+}
+
+func TestDefaultLayout(t *testing.T) {
+	e := layoutEngine(t)
+	if got, _ := e.Page("about", nil); got != "<p>About</p>" {
+		t.Errorf("Page without default layout = %q", got)
+	}
+
+	e.SetDefaultLayout("layouts/base")
+	got, err := e.Page("about", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `<title>My App</title><nav>home</nav><main><p>About</p></main>`; got != want {
+		t.Errorf("Page = %q, want %q", got, want)
+	}
+
+	// Templates loaded later are picked up
+	if err := e.Parse("pages/new", `new`); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := e.Page("new", nil); err != nil || !strings.Contains(got, "<main>new</main>") {
+		t.Errorf("Page after load = %q, %v", got, err)
+	}
+}
@@ -0,0 +1,459 @@
+package render
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+// Issue is a problem found in a Datastar expression or hx-trigger spec.
+type Issue struct {
+	Attr    string // Attribute name, e.g. "data-on:click"
+	Value   string // Attribute value
+	Pos     int    // Byte offset of the problem in Value
+	Message string
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s: col %d: %s in %q", i.Attr, i.Pos+1, i.Message, i.Value)
+}
+
+// Expression check modes; see CheckExpressions.
+const (
+	checksOff int32 = iota
+	checksLog
+	checksPanic
+)
+
+var exprChecks atomic.Int32
+
+// CheckExpressions turns on checking of the expressions passed to the ds*
+// helpers, logging any issues. It is off by default, leaving a single
+// atomic load per helper call in production.
+func CheckExpressions(enabled bool) {
+	if enabled {
+		exprChecks.Store(checksLog)
+	} else {
+		exprChecks.Store(checksOff)
+	}
+}
+
+// StrictExpressions makes the ds* helpers panic on expression issues, which
+// template execution reports as an error. Intended for tests; undo with
+// CheckExpressions.
+func StrictExpressions() {
+	exprChecks.Store(checksPanic)
+}
+
+// checkExpression lints a helper's expression when checks are enabled.
+func checkExpression(attr, expr string) {
+	mode := exprChecks.Load()
+	if mode == checksOff {
+		return
+	}
+	reportIssues(mode, lintExpression(attr, expr))
+}
+
+// checkTrigger lints a helper's hx-trigger spec when checks are enabled.
+func checkTrigger(spec string) {
+	mode := exprChecks.Load()
+	if mode == checksOff {
+		return
+	}
+	reportIssues(mode, lintTrigger("hx-trigger", spec))
+}
+
+// reportIssues logs issues, or panics with the first in strict mode.
+func reportIssues(mode int32, issues []Issue) {
+	if len(issues) == 0 {
+		return
+	}
+	if mode == checksPanic {
+		panic(issues[0].String())
+	}
+	for _, issue := range issues {
+		logf("%s", issue)
+	}
+}
+
+// attrPattern matches quoted HTML attributes.
+var attrPattern = regexp.MustCompile(`([a-zA-Z_:][-a-zA-Z0-9_:.]*)\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+
+// expressionAttrs are the Datastar attributes whose values are expressions.
+var expressionAttrs = []string{"data-on", "data-text", "data-show", "data-class", "data-attr", "data-style", "data-computed", "data-effect"}
+
+// LintAttributes checks the Datastar expressions and hx-trigger specs in an
+// HTML fragment. Positions are relative to each attribute value.
+func LintAttributes(fragment string) []Issue {
+	var issues []Issue
+	for _, m := range attrPattern.FindAllStringSubmatch(fragment, -1) {
+		name, value := strings.ToLower(m[1]), m[2]
+		if value == "" {
+			value = m[3]
+		}
+		value = html.UnescapeString(value)
+
+		switch {
+		case name == "hx-trigger" || name == "data-hx-trigger":
+			issues = append(issues, lintTrigger(name, value)...)
+		case isExpressionAttr(name):
+			issues = append(issues, lintExpression(name, value)...)
+		}
+	}
+	return issues
+}
+
+func isExpressionAttr(name string) bool {
+	for _, prefix := range expressionAttrs {
+		if name == prefix || strings.HasPrefix(name, prefix+":") || strings.HasPrefix(name, prefix+"-") {
+			return true
+		}
+	}
+	return false
+}
+
+// --- Datastar expressions ---
+
+type tokenKind int
+
+const (
+	tokNone tokenKind = iota
+	tokOperand
+	tokKeyword
+	tokAction
+	tokOp
+	tokOpen
+	tokClose
+	tokSemi
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+var (
+	// Longest first
+	operators = []string{
+		">>>=", "===", "!==", "**=", "...", "??=", "&&=", "||=", ">>>", "<<=", ">>=",
+		"==", "!=", "<=", ">=", "&&", "||", "??", "?.", "=>", "++", "--", "+=", "-=",
+		"*=", "/=", "%=", "&=", "|=", "^=", "**", "<<", ">>",
+		"+", "-", "*", "/", "%", "<", ">", "=", "!", "~", "?", ":", ",", ".", "&", "|", "^",
+	}
+	prefixOps  = map[string]bool{"+": true, "-": true, "!": true, "~": true, "++": true, "--": true, "...": true}
+	keywords   = map[string]bool{"typeof": true, "new": true, "void": true, "delete": true, "await": true, "in": true, "instanceof": true, "of": true, "return": true, "let": true, "const": true, "var": true, "if": true, "else": true, "async": true, "function": true, "throw": true}
+	brackets   = map[byte]byte{')': '(', ']': '[', '}': '{'}
+	identStart = func(c byte) bool { return c == '_' || c >= 0x80 || (c|0x20 >= 'a' && c|0x20 <= 'z') }
+	identChar  = func(c byte) bool { return identStart(c) || (c >= '0' && c <= '9') }
+)
+
+// lintExpression checks a Datastar expression: balanced brackets and
+// strings, well-formed $signals and @actions, and operators with operands
+// on both sides. It is a sanity check, not a JavaScript parser.
+func lintExpression(attr, expr string) []Issue {
+	var issues []Issue
+	report := func(pos int, format string, args ...any) {
+		issues = append(issues, Issue{Attr: attr, Value: expr, Pos: pos, Message: fmt.Sprintf(format, args...)})
+	}
+	if strings.TrimSpace(expr) == "" {
+		report(0, "empty expression")
+		return issues
+	}
+
+	var stack []token
+	prev := token{kind: tokNone}
+	expectOperand := func() bool {
+		return prev.kind == tokNone || prev.kind == tokOp || prev.kind == tokOpen || prev.kind == tokSemi || prev.kind == tokKeyword
+	}
+
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		var tok token
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+			continue
+
+		case c == '\'' || c == '"' || c == '`':
+			end := scanString(expr, i)
+			if end < 0 {
+				report(i, "unterminated string")
+				return issues
+			}
+			tok = token{tokOperand, expr[i:end], i}
+
+		case c >= '0' && c <= '9' || (c == '.' && i+1 < len(expr) && expr[i+1] >= '0' && expr[i+1] <= '9'):
+			end := i + 1
+			for end < len(expr) && (identChar(expr[end]) || expr[end] == '.') {
+				end++
+			}
+			tok = token{tokOperand, expr[i:end], i}
+
+		case identStart(c):
+			end := scanIdent(expr, i)
+			kind := tokOperand
+			if keywords[expr[i:end]] {
+				kind = tokKeyword
+			}
+			tok = token{kind, expr[i:end], i}
+
+		case c == '$' || c == '@':
+			end := scanIdent(expr, i+1)
+			if end == i+1 {
+				if c == '$' {
+					report(i, "signal name expected after $")
+				} else {
+					report(i, "action name expected after @")
+				}
+			}
+			kind := tokOperand
+			if c == '@' {
+				kind = tokAction
+			}
+			tok = token{kind, expr[i:end], i}
+
+		case c == '(' || c == '[' || c == '{':
+			tok = token{tokOpen, string(c), i}
+			stack = append(stack, tok)
+
+		case c == ')' || c == ']' || c == '}':
+			tok = token{tokClose, string(c), i}
+			if len(stack) == 0 || stack[len(stack)-1].text[0] != brackets[c] {
+				report(i, "unexpected %q", c)
+			} else {
+				stack = stack[:len(stack)-1]
+			}
+			if prev.kind == tokOp && prev.text != "," {
+				report(prev.pos, "operator %q has no right operand", prev.text)
+			}
+
+		case c == ';':
+			tok = token{tokSemi, ";", i}
+			if prev.kind == tokOp {
+				report(prev.pos, "operator %q has no right operand", prev.text)
+			}
+
+		case c == '/' && expectOperand():
+			// Regular expression literal
+			end := scanString(expr, i)
+			if end < 0 {
+				report(i, "unterminated regular expression")
+				return issues
+			}
+			for end < len(expr) && identChar(expr[end]) {
+				end++
+			}
+			tok = token{tokOperand, expr[i:end], i}
+
+		default:
+			op := matchOperator(expr[i:])
+			if op == "" {
+				report(i, "unexpected character %q", c)
+				i++
+				continue
+			}
+			tok = token{tokOp, op, i}
+			switch {
+			case (op == "++" || op == "--") && (prev.kind == tokOperand || prev.kind == tokClose):
+				tok.kind = tokOperand // Postfix
+			case expectOperand() && !prefixOps[op]:
+				report(i, "unexpected operator %q", op)
+			}
+		}
+
+		if prev.kind == tokAction && tok.text != "(" {
+			report(prev.pos, "action %s must be called, e.g. %s(...)", prev.text, prev.text)
+		}
+		postfix := tok.kind == tokOperand && (tok.text == "++" || tok.text == "--")
+		if prev.kind == tokOperand && (tok.kind == tokOperand || tok.kind == tokAction) && !postfix && !strings.HasPrefix(tok.text, "`") {
+			report(tok.pos, "missing operator before %q", tok.text)
+		}
+		prev = tok
+		i = tok.pos + len(tok.text)
+	}
+
+	switch prev.kind {
+	case tokOp:
+		report(prev.pos, "expression ends with operator %q", prev.text)
+	case tokAction:
+		report(prev.pos, "action %s must be called, e.g. %s(...)", prev.text, prev.text)
+	}
+	for _, open := range stack {
+		report(open.pos, "unclosed %q", open.text)
+	}
+	return issues
+}
+
+// scanString returns the offset after the literal starting at expr[i],
+// or -1 if it is unterminated.
+func scanString(expr string, i int) int {
+	quote := expr[i]
+	for j := i + 1; j < len(expr); j++ {
+		switch expr[j] {
+		case '\\':
+			j++
+		case quote:
+			return j + 1
+		}
+	}
+	return -1
+}
+
+func scanIdent(expr string, i int) int {
+	if i >= len(expr) || !identStart(expr[i]) {
+		return i
+	}
+	for i < len(expr) && identChar(expr[i]) {
+		i++
+	}
+	return i
+}
+
+func matchOperator(s string) string {
+	for _, op := range operators {
+		if strings.HasPrefix(s, op) {
+			return op
+		}
+	}
+	return ""
+}
+
+// --- hx-trigger ---
+
+var (
+	intervalPattern  = regexp.MustCompile(`^\d+(ms|s|m)?$`)
+	eventNamePattern = regexp.MustCompile(`^[A-Za-z_][-A-Za-z0-9_:.]*$`)
+	extendedFrom     = map[string]bool{"closest": true, "find": true, "next": true, "previous": true}
+	queueOptions     = map[string]bool{"first": true, "last": true, "all": true, "none": true}
+)
+
+// lintTrigger checks an hx-trigger spec: comma-separated triggers, each an
+// event name (or "every <interval>") with an optional [filter] and
+// modifiers.
+func lintTrigger(attr, spec string) []Issue {
+	var issues []Issue
+	report := func(pos int, format string, args ...any) {
+		issues = append(issues, Issue{Attr: attr, Value: spec, Pos: pos, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for _, part := range splitOutside(spec, ',', 0) {
+		words := splitOutside(part.text, ' ', part.pos)
+		if len(words) == 0 {
+			report(part.pos, "empty trigger")
+			continue
+		}
+
+		first := words[0]
+		rest := words[1:]
+		event, filter, hasFilter := strings.Cut(first.text, "[")
+		switch {
+		case event == "every":
+			if len(rest) == 0 {
+				report(first.pos, "every needs an interval, e.g. every 2s")
+				continue
+			}
+			interval, filter2, hasFilter2 := strings.Cut(rest[0].text, "[")
+			if !intervalPattern.MatchString(interval) {
+				report(rest[0].pos, "invalid interval %q", interval)
+			}
+			if hasFilter2 {
+				issues = append(issues, lintFilter(attr, spec, filter2, rest[0].pos+len(interval)+1)...)
+			}
+			rest = rest[1:]
+		case !eventNamePattern.MatchString(event):
+			report(first.pos, "invalid event name %q", event)
+		}
+		if hasFilter {
+			issues = append(issues, lintFilter(attr, spec, filter, first.pos+len(event)+1)...)
+		} else if len(rest) > 0 && strings.HasPrefix(rest[0].text, "[") {
+			issues = append(issues, lintFilter(attr, spec, rest[0].text[1:], rest[0].pos+1)...)
+			rest = rest[1:]
+		}
+
+		for j := 0; j < len(rest); j++ {
+			w := rest[j]
+			name, value, hasValue := strings.Cut(w.text, ":")
+			switch name {
+			case "once", "changed", "consume":
+				if hasValue {
+					report(w.pos, "modifier %q takes no value", name)
+				}
+			case "delay", "throttle":
+				if !intervalPattern.MatchString(value) {
+					report(w.pos, "%s needs an interval, e.g. %s:500ms", name, name)
+				}
+			case "from", "target":
+				switch {
+				case value == "":
+					report(w.pos, "%s needs a selector", name)
+				case name == "from" && extendedFrom[value]:
+					if j+1 >= len(rest) {
+						report(w.pos, "from:%s needs a selector", value)
+					}
+					j++
+				}
+			case "queue":
+				if !queueOptions[value] {
+					report(w.pos, "queue must be first, last, all or none")
+				}
+			default:
+				report(w.pos, "unknown modifier %q", w.text)
+			}
+		}
+	}
+	return issues
+}
+
+// lintFilter checks a trigger filter "expr]" starting at offset pos.
+func lintFilter(attr, spec, filter string, pos int) []Issue {
+	expr, ok := strings.CutSuffix(filter, "]")
+	if !ok {
+		return []Issue{{Attr: attr, Value: spec, Pos: pos - 1, Message: `unclosed "["`}}
+	}
+	issues := lintExpression(attr, expr)
+	for i := range issues {
+		issues[i].Value = spec
+		issues[i].Pos += pos
+	}
+	return issues
+}
+
+type span struct {
+	text string
+	pos  int
+}
+
+// splitOutside splits s on sep outside square brackets, trimming spaces
+// and dropping empty fields for space separators. Positions are offset
+// by base.
+func splitOutside(s string, sep byte, base int) []span {
+	var spans []span
+	depth, start := 0, 0
+	add := func(end int) {
+		field := s[start:end]
+		trimmed := strings.TrimLeft(field, " ")
+		pos := base + start + len(field) - len(trimmed)
+		trimmed = strings.TrimRight(trimmed, " ")
+		if trimmed != "" || sep != ' ' {
+			spans = append(spans, span{trimmed, pos})
+		}
+	}
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case sep:
+			if depth <= 0 {
+				add(i)
+				start = i + 1
+			}
+		}
+	}
+	add(len(s))
+	return spans
+}
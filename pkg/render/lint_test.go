@@ -0,0 +1,160 @@
+package render
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLintExpression(t *testing.T) {
+	valid := []string{
+		"$count++",
+		"$count = $count + 1",
+		"@post('/todos')",
+		"@get('/search?q=' + encodeURIComponent($query))",
+		"evt.key === 'Enter' && @post('/todos', {filterSignals: {include: /^todo/}})",
+		"$open ? 'Close' : 'Open'",
+		"$items.length > 0 && !$loading",
+		"$a = -1; $b = typeof $c",
+		"() => $n--",
+		"$todos.filter(t => t.done).length",
+		"`Hello ${$name}`",
+		`el.classList.toggle("active")`,
+	}
+	for _, expr := range valid {
+		if issues := lintExpression("data-on:click", expr); len(issues) != 0 {
+			t.Errorf("%q: unexpected issues %v", expr, issues)
+		}
+	}
+
+	broken := []struct {
+		expr string
+		pos  int
+		msg  string
+	}{
+		{"$count +", 7, `expression ends with operator "+"`},
+		{"", 0, "empty expression"},
+		{"@post('/todos)", 6, "unterminated string"},
+		{"@post('/todos'", 5, `unclosed "("`},
+		{"@post", 0, "action @post must be called"},
+		{"@post '/x'", 0, "action @post must be called"},
+		{"@('/x')", 0, "action name expected after @"},
+		{"$ = 1", 0, "signal name expected after $"},
+		{"$a $b", 3, `missing operator before "$b"`},
+		{"$a = * 2", 5, `unexpected operator "*"`},
+		{"($a + )", 4, `operator "+" has no right operand`},
+		{"$a)", 2, `unexpected ')'`},
+		{"$a # 1", 3, `unexpected character '#'`},
+	}
+	for _, tt := range broken {
+		issues := lintExpression("data-on:click", tt.expr)
+		if len(issues) == 0 {
+			t.Errorf("%q: expected an issue", tt.expr)
+			continue
+		}
+		if issues[0].Pos != tt.pos || !strings.Contains(issues[0].Message, tt.msg) {
+			t.Errorf("%q: got %d %q, want %d %q", tt.expr, issues[0].Pos, issues[0].Message, tt.pos, tt.msg)
+		}
+	}
+}
+
+func TestLintTrigger(t *testing.T) {
+	valid := []string{
+		"click",
+		"keyup changed delay:500ms",
+		"every 2s",
+		"load, every 10s [document.visibilityState === 'visible']",
+		"click[ctrlKey && shiftKey] once from:closest form",
+		"sse:message queue:last throttle:1s target:#list",
+	}
+	for _, spec := range valid {
+		if issues := lintTrigger("hx-trigger", spec); len(issues) != 0 {
+			t.Errorf("%q: unexpected issues %v", spec, issues)
+		}
+	}
+
+	broken := []struct {
+		spec string
+		pos  int
+		msg  string
+	}{
+		{"keyup delay:fast", 6, "delay needs an interval"},
+		{"every", 0, "every needs an interval"},
+		{"every soon", 6, `invalid interval "soon"`},
+		{"click, ", 7, "empty trigger"},
+		{"click oncee", 6, `unknown modifier "oncee"`},
+		{"click queue:sometimes", 6, "queue must be"},
+		{"click from:closest", 6, "from:closest needs a selector"},
+		{"click[ctrlKey &&]", 14, `expression ends with operator "&&"`},
+		{"click[ctrlKey", 5, `unclosed "["`},
+		{"9click", 0, "invalid event name"},
+	}
+	for _, tt := range broken {
+		issues := lintTrigger("hx-trigger", tt.spec)
+		if len(issues) == 0 {
+			t.Errorf("%q: expected an issue", tt.spec)
+			continue
+		}
+		if issues[0].Pos != tt.pos || !strings.Contains(issues[0].Message, tt.msg) {
+			t.Errorf("%q: got %d %q, want %d %q", tt.spec, issues[0].Pos, issues[0].Message, tt.pos, tt.msg)
+		}
+	}
+}
+
+func TestLintAttributes(t *testing.T) {
+	fragment := `<div data-show="$open" data-signals="{open: false}">
+<button data-on:click="$count +">Add</button>
+<input data-bind:query hx-trigger="keyup delay:soon">
+<span data-class:active='$tab == &#39;home'>Home</span>
+</div>`
+
+	issues := LintAttributes(fragment)
+	if len(issues) != 3 {
+		t.Fatalf("expected 3 issues, got %v", issues)
+	}
+	want := []string{
+		`data-on:click: col 8: expression ends with operator "+" in "$count +"`,
+		"hx-trigger: col 7: delay needs an interval",
+		`data-class:active: col 9: unterminated string`,
+	}
+	for i, w := range want {
+		if !strings.HasPrefix(issues[i].String(), w) {
+			t.Errorf("issue %d: got %q, want prefix %q", i, issues[i], w)
+		}
+	}
+}
+
+func TestHelperChecks(t *testing.T) {
+	e := New()
+	if err := e.Parse("button", `<button {{dsOnClick .}}>Go</button>`); err != nil {
+		t.Fatal(err)
+	}
+
+	// Off by default
+	if _, err := e.Render("button", "$count +"); err != nil {
+		t.Fatalf("expected no checks by default, got %v", err)
+	}
+
+	StrictExpressions()
+	t.Cleanup(func() { CheckExpressions(false) })
+
+	if _, err := e.Render("button", "$count++"); err != nil {
+		t.Errorf("expected valid expression to render, got %v", err)
+	}
+	_, err := e.Render("button", "$count +")
+	if err == nil || !strings.Contains(err.Error(), `expression ends with operator "+"`) {
+		t.Errorf("expected strict mode error, got %v", err)
+	}
+
+	e = New()
+	if err := e.Parse("delete", `<button {{dsDelete .}}>Delete</button>`); err != nil {
+		t.Fatal(err)
+	}
+	// Quotes in URLs are escaped rather than breaking the expression
+	html, err := e.Render("delete", "/todos/it's")
+	if err != nil {
+		t.Errorf("expected escaped quote in URL to render, got %v", err)
+	}
+	if want := `@delete('/todos/it\'s')`; !strings.Contains(html, want) {
+		t.Errorf("got %s, want it to contain %s", html, want)
+	}
+}
@@ -0,0 +1,25 @@
+package render
+
+import (
+	"log"
+	"sync/atomic"
+)
+
+// logger receives the package's warnings; nil means log.Default().
+var logger atomic.Pointer[log.Logger]
+
+// SetLogger sets the logger for render's warnings, such as failed
+// reloads and helpers given values they can't render. nil restores the
+// standard logger.
+func SetLogger(l *log.Logger) {
+	logger.Store(l)
+}
+
+// logf logs a warning to the configured logger.
+func logf(format string, args ...any) {
+	l := logger.Load()
+	if l == nil {
+		l = log.Default()
+	}
+	l.Printf("render: "+format, args...)
+}
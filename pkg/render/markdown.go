@@ -0,0 +1,815 @@
+package render
+
+import (
+	"html"
+	"html/template"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MarkdownPolicy controls how Markdown sanitises its output. The zero
+// value is the default policy.
+type MarkdownPolicy struct {
+	// EscapeHTML shows HTML written in the markdown as text. By default it
+	// is kept, subject to the rest of the policy.
+	EscapeHTML bool
+
+	// Tags are allowed in addition to the default formatting tags (those
+	// allowed by SandboxFragment). Script-like elements (script, style,
+	// iframe, svg and the like) can't be allowed.
+	Tags []string
+
+	// Attrs are allowed on every tag in addition to the defaults (id,
+	// class, title and the like, plus href on links and src and alt on
+	// images). An entry ending in "*" matches by prefix, e.g. "data-*".
+	// Event handlers and style can't be allowed.
+	Attrs []string
+
+	// URLSchemes are the schemes allowed in href and src, replacing the
+	// default http, https, mailto and tel. Relative URLs are always
+	// allowed; javascript: URLs never are.
+	URLSchemes []string
+}
+
+func (p MarkdownPolicy) allowsTag(name string) bool {
+	return SandboxPolicy{Tags: p.Tags}.allowsTag(name)
+}
+
+func (p MarkdownPolicy) attr(tagName, name, value string) (string, bool) {
+	if strings.HasPrefix(name, "on") || name == "style" {
+		return "", false
+	}
+	if !sandboxAttrs[name] && !sandboxTagAttrs[tagName][name] && !(SandboxPolicy{Attrs: p.Attrs}).allowsAttr(name) {
+		return "", false
+	}
+	if name == "href" || name == "src" {
+		value = strings.TrimSpace(value)
+		schemes := p.URLSchemes
+		if len(schemes) == 0 {
+			schemes = defaultURLSchemes
+		}
+		return value, allowedURL(value, schemes)
+	}
+	return value, true
+}
+
+var markdownPolicy atomic.Pointer[MarkdownPolicy]
+
+// SetMarkdownPolicy sets the policy used by Markdown and the markdown
+// template function.
+func SetMarkdownPolicy(p MarkdownPolicy) {
+	markdownPolicy.Store(&p)
+}
+
+func currentMarkdownPolicy() MarkdownPolicy {
+	if p := markdownPolicy.Load(); p != nil {
+		return *p
+	}
+	return MarkdownPolicy{}
+}
+
+// EnableMarkdown registers the markdown template function, which renders
+// markdown with Markdown:
+//
+//	<article>{{markdown .ReleaseNotes}}</article>
+func (e *Engine) EnableMarkdown() {
+	e.AddFunc("markdown", Markdown)
+}
+
+// Markdown renders CommonMark to HTML, sanitised by the policy set with
+// SetMarkdownPolicy: scripts and styles are dropped, as are event
+// handlers and javascript: URLs, so it is safe for untrusted content.
+//
+// Headings, paragraphs, emphasis, code spans, fenced and indented code,
+// block quotes, lists, thematic breaks, links, images, autolinks and hard
+// line breaks are supported. Link reference definitions are not.
+func Markdown(src string) template.HTML {
+	policy := currentMarkdownPolicy()
+	md := mdRenderer{rawHTML: !policy.EscapeHTML}
+	var b strings.Builder
+	md.blocks(&b, parseMarkdownBlocks(markdownLines(src)), false)
+	return template.HTML(sanitize(b.String(), policy))
+}
+
+// --- Blocks ---
+
+type mdKind int
+
+const (
+	mdParagraph mdKind = iota
+	mdHeading
+	mdCode
+	mdQuote
+	mdList
+	mdRule
+	mdHTML
+)
+
+type mdBlock struct {
+	kind     mdKind
+	text     string // Inline text, code or HTML
+	level    int    // Heading level
+	lang     string // Code language
+	children []*mdBlock
+	items    [][]*mdBlock // List items
+	ordered  bool
+	start    int
+	tight    bool
+}
+
+var (
+	mdFence      = regexp.MustCompile("^( {0,3})(`{3,}|~{3,})\\s*([^`\\s]*)[^`]*$")
+	mdATXHeading = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	mdRuleLine   = regexp.MustCompile(`^ {0,3}(?:(?:\*[ \t]*){3,}|(?:-[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	mdSetext     = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+	mdQuoteLine  = regexp.MustCompile(`^ {0,3}> ?`)
+	mdHTMLStart  = regexp.MustCompile(`^ {0,3}<(?:/?[A-Za-z][A-Za-z0-9-]*(?:[\s/>]|$)|!|\?)`)
+	mdListMarker = regexp.MustCompile(`^( {0,3})([-+*]|[0-9]{1,9}[.)])( +|$)`)
+	mdEntity     = regexp.MustCompile(`^&(?:#[0-9]{1,7}|#[xX][0-9a-fA-F]{1,6}|[A-Za-z][A-Za-z0-9]{1,31});`)
+	mdAutolink   = regexp.MustCompile(`^<([A-Za-z][A-Za-z0-9.+-]{1,31}:[^\s<>]*)>`)
+	mdEmailLink  = regexp.MustCompile(`^<([A-Za-z0-9.!#$%&'*+/=?^_` + "`" + `{|}~-]+@[A-Za-z0-9](?:[A-Za-z0-9-]{0,61}[A-Za-z0-9])?(?:\.[A-Za-z0-9](?:[A-Za-z0-9-]{0,61}[A-Za-z0-9])?)*)>`)
+	mdNewlines   = strings.NewReplacer("\r\n", "\n", "\r", "\n")
+)
+
+// markdownLines splits src into lines, expanding tabs in indentation.
+func markdownLines(src string) []string {
+	lines := strings.Split(mdNewlines.Replace(src), "\n")
+	for i, line := range lines {
+		if !strings.Contains(line, "\t") {
+			continue
+		}
+		col, j := 0, 0
+		for ; j < len(line) && (line[j] == ' ' || line[j] == '\t'); j++ {
+			if line[j] == '\t' {
+				col += 4 - col%4
+			} else {
+				col++
+			}
+		}
+		lines[i] = strings.Repeat(" ", col) + line[j:]
+	}
+	return lines
+}
+
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// listItem describes a list marker at the start of a line.
+type listItem struct {
+	ordered bool
+	marker  byte // Bullet character, or '.' or ')' after a number
+	start   int
+	indent  int // Column of the item's content
+	rest    string
+}
+
+func parseListMarker(line string) (listItem, bool) {
+	m := mdListMarker.FindStringSubmatch(line)
+	if m == nil {
+		return listItem{}, false
+	}
+	item := listItem{marker: m[2][len(m[2])-1]}
+	if len(m[2]) > 1 || (m[2][0] >= '0' && m[2][0] <= '9') {
+		item.ordered = true
+		item.start, _ = strconv.Atoi(m[2][:len(m[2])-1])
+	}
+	spaces := len(m[3])
+	if spaces == 0 || spaces > 4 {
+		// An empty item, or one starting with indented code
+		spaces = 1
+	}
+	item.indent = len(m[1]) + len(m[2]) + spaces
+	if len(line) > item.indent {
+		item.rest = line[item.indent:]
+	}
+	return item, true
+}
+
+func (a listItem) sameList(b listItem) bool {
+	return a.ordered == b.ordered && a.marker == b.marker
+}
+
+// startsBlock reports whether line starts a block that ends a paragraph.
+func startsBlock(line string) bool {
+	if mdFence.MatchString(line) || mdATXHeading.MatchString(line) || mdRuleLine.MatchString(line) || mdQuoteLine.MatchString(line) {
+		return true
+	}
+	if item, ok := parseListMarker(line); ok {
+		// Only non-empty bullets and lists starting at 1 interrupt a paragraph
+		return !isBlank(item.rest) && (!item.ordered || item.start == 1)
+	}
+	return false
+}
+
+func parseMarkdownBlocks(lines []string) []*mdBlock {
+	var blocks []*mdBlock
+	var para []string
+	endPara := func() {
+		if para != nil {
+			blocks = append(blocks, &mdBlock{kind: mdParagraph, text: strings.Join(para, "\n")})
+			para = nil
+		}
+	}
+
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		switch {
+		case isBlank(line):
+			endPara()
+			i++
+			continue
+
+		case para != nil && mdSetext.MatchString(line):
+			level := 2
+			if strings.TrimSpace(line)[0] == '=' {
+				level = 1
+			}
+			blocks = append(blocks, &mdBlock{kind: mdHeading, level: level, text: strings.Join(para, "\n")})
+			para = nil
+			i++
+			continue
+
+		case para != nil && !startsBlock(line):
+			para = append(para, strings.TrimLeft(line, " "))
+			i++
+			continue
+		}
+		endPara()
+
+		if m := mdFence.FindStringSubmatch(line); m != nil {
+			indent, fence := len(m[1]), m[2]
+			var code strings.Builder
+			i++
+			for ; i < len(lines); i++ {
+				l := lines[i]
+				if t := strings.TrimSpace(l); indentOf(l) < 4 && strings.HasPrefix(t, fence) && strings.Trim(t, fence[:1]) == "" {
+					i++
+					break
+				}
+				code.WriteString(l[min(indent, indentOf(l)):] + "\n")
+			}
+			blocks = append(blocks, &mdBlock{kind: mdCode, text: code.String(), lang: html.UnescapeString(m[3])})
+			continue
+		}
+
+		if m := mdATXHeading.FindStringSubmatch(line); m != nil {
+			blocks = append(blocks, &mdBlock{kind: mdHeading, level: len(m[1]), text: m[2]})
+			i++
+			continue
+		}
+
+		if mdRuleLine.MatchString(line) {
+			blocks = append(blocks, &mdBlock{kind: mdRule})
+			i++
+			continue
+		}
+
+		if mdQuoteLine.MatchString(line) {
+			var inner []string
+			for ; i < len(lines); i++ {
+				l := lines[i]
+				if loc := mdQuoteLine.FindStringIndex(l); loc != nil {
+					inner = append(inner, l[loc[1]:])
+				} else if !isBlank(l) && len(inner) > 0 && !isBlank(inner[len(inner)-1]) && !startsBlock(l) {
+					inner = append(inner, l) // Lazy continuation
+				} else {
+					break
+				}
+			}
+			blocks = append(blocks, &mdBlock{kind: mdQuote, children: parseMarkdownBlocks(inner)})
+			continue
+		}
+
+		if item, ok := parseListMarker(line); ok {
+			var list *mdBlock
+			list, i = parseList(lines, i, item)
+			blocks = append(blocks, list)
+			continue
+		}
+
+		if mdHTMLStart.MatchString(line) {
+			var raw []string
+			for ; i < len(lines) && !isBlank(lines[i]); i++ {
+				raw = append(raw, lines[i])
+			}
+			blocks = append(blocks, &mdBlock{kind: mdHTML, text: strings.Join(raw, "\n")})
+			continue
+		}
+
+		if indentOf(line) >= 4 {
+			var code []string
+			for ; i < len(lines) && (isBlank(lines[i]) || indentOf(lines[i]) >= 4); i++ {
+				l := lines[i]
+				code = append(code, l[min(4, len(l)):])
+			}
+			for len(code) > 0 && isBlank(code[len(code)-1]) {
+				code = code[:len(code)-1]
+			}
+			blocks = append(blocks, &mdBlock{kind: mdCode, text: strings.Join(code, "\n") + "\n"})
+			continue
+		}
+
+		para = []string{strings.TrimLeft(line, " ")}
+		i++
+	}
+	endPara()
+	return blocks
+}
+
+// parseList parses the list starting at lines[i], returning it and the
+// index of the first line after it.
+func parseList(lines []string, i int, first listItem) (*mdBlock, int) {
+	list := &mdBlock{kind: mdList, ordered: first.ordered, start: first.start, tight: true}
+	item := first
+	for {
+		content := []string{item.rest}
+		i++
+		for i < len(lines) {
+			l := lines[i]
+			if isBlank(l) {
+				content = append(content, "")
+			} else if indentOf(l) >= item.indent {
+				content = append(content, l[item.indent:])
+			} else if next, ok := parseListMarker(l); ok && next.sameList(first) {
+				break
+			} else if !isBlank(content[len(content)-1]) && !startsBlock(l) {
+				content = append(content, strings.TrimLeft(l, " ")) // Lazy continuation
+			} else {
+				break
+			}
+			i++
+		}
+
+		trailing := 0
+		for len(content) > 1 && isBlank(content[len(content)-1]) {
+			content = content[:len(content)-1]
+			trailing++
+		}
+		for _, l := range content[1:] {
+			if isBlank(l) {
+				list.tight = false
+			}
+		}
+		list.items = append(list.items, parseMarkdownBlocks(content))
+
+		next, ok := listItem{}, false
+		if i < len(lines) {
+			next, ok = parseListMarker(lines[i])
+		}
+		if !ok || !next.sameList(first) {
+			return list, i
+		}
+		if trailing > 0 {
+			list.tight = false
+		}
+		item = next
+	}
+}
+
+// --- Rendering ---
+
+type mdRenderer struct {
+	rawHTML bool
+}
+
+func (md mdRenderer) blocks(b *strings.Builder, blocks []*mdBlock, tight bool) {
+	for i, blk := range blocks {
+		switch blk.kind {
+		case mdParagraph:
+			if tight {
+				// Tight list items hold their text without a paragraph
+				b.WriteString(md.inline(blk.text))
+				if i < len(blocks)-1 {
+					b.WriteString("\n")
+				}
+			} else {
+				b.WriteString("<p>" + md.inline(blk.text) + "</p>\n")
+			}
+		case mdHeading:
+			h := strconv.Itoa(blk.level)
+			b.WriteString("<h" + h + ">" + md.inline(blk.text) + "</h" + h + ">\n")
+		case mdCode:
+			b.WriteString("<pre><code")
+			if blk.lang != "" {
+				b.WriteString(` class="language-` + html.EscapeString(blk.lang) + `"`)
+			}
+			b.WriteString(">" + html.EscapeString(blk.text) + "</code></pre>\n")
+		case mdQuote:
+			b.WriteString("<blockquote>\n")
+			md.blocks(b, blk.children, false)
+			b.WriteString("</blockquote>\n")
+		case mdList:
+			tag := "ul"
+			if blk.ordered {
+				tag = "ol"
+			}
+			b.WriteString("<" + tag)
+			if blk.ordered && blk.start != 1 {
+				b.WriteString(` start="` + strconv.Itoa(blk.start) + `"`)
+			}
+			b.WriteString(">\n")
+			for _, item := range blk.items {
+				b.WriteString("<li>")
+				if !blk.tight || (len(item) > 0 && item[0].kind != mdParagraph) {
+					b.WriteString("\n")
+				}
+				md.blocks(b, item, blk.tight)
+				b.WriteString("</li>\n")
+			}
+			b.WriteString("</" + tag + ">\n")
+		case mdRule:
+			b.WriteString("<hr>\n")
+		case mdHTML:
+			if md.rawHTML {
+				b.WriteString(blk.text + "\n")
+			} else {
+				b.WriteString("<p>" + html.EscapeString(blk.text) + "</p>\n")
+			}
+		}
+	}
+}
+
+// inlineNode is a run of rendered HTML or of emphasis delimiters.
+type inlineNode struct {
+	html     string
+	delim    byte // '*' or '_' for a delimiter run
+	count    int  // Delimiters not yet matched
+	orig     int
+	canOpen  bool
+	canClose bool
+	opens    string // Tags opened after the remaining delimiters
+	closes   string // Tags closed before them
+}
+
+// inline renders inline markdown to HTML.
+func (md mdRenderer) inline(s string) string {
+	var nodes []*inlineNode
+	var buf []byte
+	flush := func() {
+		if len(buf) > 0 {
+			nodes = append(nodes, &inlineNode{html: string(buf)})
+			buf = nil
+		}
+	}
+
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch c {
+		case '\\':
+			if i+1 < len(s) && isASCIIPunct(s[i+1]) {
+				buf = append(buf, html.EscapeString(s[i+1:i+2])...)
+				i += 2
+				continue
+			}
+			if i+1 < len(s) && s[i+1] == '\n' {
+				buf = append(buf, "<br>\n"...)
+				i += 2
+				continue
+			}
+
+		case '`':
+			n := runLength(s, i, '`')
+			if end, code, ok := codeSpan(s, i, n); ok {
+				buf = append(buf, "<code>"+html.EscapeString(code)+"</code>"...)
+				i = end
+			} else {
+				buf = append(buf, s[i:i+n]...)
+				i += n
+			}
+			continue
+
+		case '*', '_':
+			n := runLength(s, i, c)
+			prev, _ := utf8.DecodeLastRuneInString(s[:i])
+			if i == 0 {
+				prev = ' '
+			}
+			next := ' '
+			if i+n < len(s) {
+				next, _ = utf8.DecodeRuneInString(s[i+n:])
+			}
+			left := !unicode.IsSpace(next) && (!isPunctRune(next) || unicode.IsSpace(prev) || isPunctRune(prev))
+			right := !unicode.IsSpace(prev) && (!isPunctRune(prev) || unicode.IsSpace(next) || isPunctRune(next))
+			node := &inlineNode{delim: c, count: n, orig: n, canOpen: left, canClose: right}
+			if c == '_' {
+				node.canOpen = left && (!right || isPunctRune(prev))
+				node.canClose = right && (!left || isPunctRune(next))
+			}
+			flush()
+			nodes = append(nodes, node)
+			i += n
+			continue
+
+		case '!', '[':
+			image := c == '!'
+			open := i
+			if image {
+				if i+1 >= len(s) || s[i+1] != '[' {
+					break
+				}
+				open++
+			}
+			if text, dest, title, end, ok := parseLink(s, open); ok {
+				inner := md.inline(text)
+				if image {
+					buf = append(buf, `<img src="`+html.EscapeString(dest)+`" alt="`+stripTags(inner)+`"`...)
+				} else {
+					buf = append(buf, `<a href="`+html.EscapeString(dest)+`"`...)
+				}
+				if title != "" {
+					buf = append(buf, ` title="`+html.EscapeString(title)+`"`...)
+				}
+				if image {
+					buf = append(buf, '>')
+				} else {
+					buf = append(buf, ">"+inner+"</a>"...)
+				}
+				i = end
+				continue
+			}
+
+		case '<':
+			if m := mdAutolink.FindStringSubmatch(s[i:]); m != nil {
+				buf = append(buf, `<a href="`+html.EscapeString(m[1])+`">`+html.EscapeString(m[1])+"</a>"...)
+				i += len(m[0])
+				continue
+			}
+			if m := mdEmailLink.FindStringSubmatch(s[i:]); m != nil {
+				buf = append(buf, `<a href="mailto:`+html.EscapeString(m[1])+`">`+html.EscapeString(m[1])+"</a>"...)
+				i += len(m[0])
+				continue
+			}
+			if md.rawHTML {
+				if strings.HasPrefix(s[i:], "<!--") {
+					if end := strings.Index(s[i+4:], "-->"); end >= 0 {
+						i += 4 + end + 3 // Comments are dropped when sanitising anyway
+						continue
+					}
+				}
+				if _, rest, ok := parseTag(s[i:]); ok {
+					n := len(s[i:]) - len(rest)
+					buf = append(buf, s[i:i+n]...)
+					i += n
+					continue
+				}
+			}
+			buf = append(buf, "&lt;"...)
+			i++
+			continue
+
+		case '&':
+			if m := mdEntity.FindString(s[i:]); m != "" {
+				buf = append(buf, m...)
+				i += len(m)
+				continue
+			}
+			buf = append(buf, "&amp;"...)
+			i++
+			continue
+
+		case '>', '"':
+			buf = append(buf, html.EscapeString(s[i:i+1])...)
+			i++
+			continue
+
+		case '\n':
+			trimmed := strings.TrimRight(string(buf), " ")
+			hard := len(buf)-len(trimmed) >= 2
+			buf = []byte(trimmed)
+			if hard {
+				buf = append(buf, "<br>"...)
+			}
+			buf = append(buf, '\n')
+			i++
+			for i < len(s) && s[i] == ' ' {
+				i++
+			}
+			continue
+		}
+		buf = append(buf, c)
+		i++
+	}
+	flush()
+
+	matchEmphasis(nodes)
+	var b strings.Builder
+	for _, n := range nodes {
+		if n.delim == 0 {
+			b.WriteString(n.html)
+			continue
+		}
+		b.WriteString(n.closes)
+		b.WriteString(strings.Repeat(string(n.delim), n.count))
+		b.WriteString(n.opens)
+	}
+	return strings.TrimRight(b.String(), " ")
+}
+
+// matchEmphasis pairs delimiter runs into em and strong, following the
+// CommonMark rules.
+func matchEmphasis(nodes []*inlineNode) {
+	for c, closer := range nodes {
+		if closer.delim == 0 || !closer.canClose {
+			continue
+		}
+		for closer.count > 0 {
+			o := c - 1
+			for ; o >= 0; o-- {
+				opener := nodes[o]
+				if opener.delim != closer.delim || !opener.canOpen || opener.count == 0 {
+					continue
+				}
+				// Runs that could both open and close can't pair if their
+				// lengths sum to a multiple of three, e.g. *foo**bar*
+				if (opener.canClose || closer.canOpen) && (opener.orig+closer.orig)%3 == 0 &&
+					!(opener.orig%3 == 0 && closer.orig%3 == 0) {
+					continue
+				}
+				break
+			}
+			if o < 0 {
+				break
+			}
+			opener := nodes[o]
+			use, tag := 1, "em"
+			if opener.count >= 2 && closer.count >= 2 {
+				use, tag = 2, "strong"
+			}
+			opener.count -= use
+			closer.count -= use
+			opener.opens = "<" + tag + ">" + opener.opens
+			closer.closes += "</" + tag + ">"
+			// Delimiters inside the pair can no longer match outside it
+			for _, n := range nodes[o+1 : c] {
+				n.canOpen, n.canClose = false, false
+			}
+		}
+	}
+}
+
+// codeSpan returns the end and content of the code span opened by the n
+// backticks at s[i].
+func codeSpan(s string, i, n int) (end int, code string, ok bool) {
+	for j := i + n; j < len(s); {
+		if s[j] != '`' {
+			j++
+			continue
+		}
+		m := runLength(s, j, '`')
+		if m == n {
+			code = strings.ReplaceAll(s[i+n:j], "\n", " ")
+			if len(code) >= 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.Trim(code, " ") != "" {
+				code = code[1 : len(code)-1]
+			}
+			return j + m, code, true
+		}
+		j += m
+	}
+	return 0, "", false
+}
+
+// parseLink parses an inline link, [text](dest "title"), starting at the
+// '[' at s[i].
+func parseLink(s string, i int) (text, dest, title string, end int, ok bool) {
+	// Find the matching ']'
+	depth, j := 0, i
+	for ; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '`':
+			n := runLength(s, j, '`')
+			if e, _, ok := codeSpan(s, j, n); ok {
+				j = e - 1
+			} else {
+				j += n - 1
+			}
+		case '[':
+			depth++
+		case ']':
+			depth--
+		}
+		if depth == 0 {
+			break
+		}
+	}
+	if j >= len(s) || j+1 >= len(s) || s[j+1] != '(' {
+		return "", "", "", 0, false
+	}
+	text = s[i+1 : j]
+
+	k := skipLinkSpace(s, j+2)
+	if k < len(s) && s[k] == '<' {
+		close := strings.IndexAny(s[k+1:], ">\n")
+		if close < 0 || s[k+1+close] != '>' {
+			return "", "", "", 0, false
+		}
+		dest = s[k+1 : k+1+close]
+		k += close + 2
+	} else {
+		start, parens := k, 0
+		for ; k < len(s) && s[k] > ' '; k++ {
+			if s[k] == '\\' && k+1 < len(s) {
+				k++
+			} else if s[k] == '(' {
+				parens++
+			} else if s[k] == ')' {
+				if parens == 0 {
+					break
+				}
+				parens--
+			}
+		}
+		dest = s[start:k]
+	}
+
+	t := skipLinkSpace(s, k)
+	if t < len(s) && t > k && (s[t] == '"' || s[t] == '\'' || s[t] == '(') {
+		closer := s[t]
+		if closer == '(' {
+			closer = ')'
+		}
+		e := t + 1
+		for ; e < len(s) && s[e] != closer; e++ {
+			if s[e] == '\\' {
+				e++
+			}
+		}
+		if e >= len(s) {
+			return "", "", "", 0, false
+		}
+		title = s[t+1 : e]
+		k = skipLinkSpace(s, e+1)
+	} else {
+		k = t
+	}
+	if k >= len(s) || s[k] != ')' {
+		return "", "", "", 0, false
+	}
+	return text, html.UnescapeString(unescapePunct(dest)), html.UnescapeString(unescapePunct(title)), k + 1, true
+}
+
+func skipLinkSpace(s string, i int) int {
+	for i < len(s) && (s[i] == ' ' || s[i] == '\t' || s[i] == '\n') {
+		i++
+	}
+	return i
+}
+
+// unescapePunct removes backslashes escaping ASCII punctuation.
+func unescapePunct(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && isASCIIPunct(s[i+1]) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// stripTags removes tags from rendered HTML, keeping its escaped text.
+func stripTags(s string) string {
+	var b strings.Builder
+	for {
+		i := strings.IndexByte(s, '<')
+		if i < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+		b.WriteString(s[:i])
+		end := strings.IndexByte(s[i:], '>')
+		if end < 0 {
+			return b.String()
+		}
+		s = s[i+end+1:]
+	}
+}
+
+func runLength(s string, i int, c byte) int {
+	n := 0
+	for i+n < len(s) && s[i+n] == c {
+		n++
+	}
+	return n
+}
+
+func isASCIIPunct(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
+
+func isPunctRune(r rune) bool {
+	return unicode.IsPunct(r) || unicode.IsSymbol(r)
+}
@@ -0,0 +1,142 @@
+package render
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMarkdown(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"heading", "# Release notes #", "<h1>Release notes</h1>\n"},
+		{"setext", "Help\n====", "<h1>Help</h1>\n"},
+		{"paragraph", "one\ntwo", "<p>one\ntwo</p>\n"},
+		{"hard break", "one  \ntwo", "<p>one<br>\ntwo</p>\n"},
+		{"emphasis", "*em*, **strong**, ***both*** and snake_case_name",
+			"<p><em>em</em>, <strong>strong</strong>, <em><strong>both</strong></em> and snake_case_name</p>\n"},
+		{"code span", "run `a < b && c`", "<p>run <code>a &lt; b &amp;&amp; c</code></p>\n"},
+		{"escapes", `\*not em\* & 5 > 3 &copy;`, "<p>*not em* &amp; 5 &gt; 3 ©</p>\n"},
+		{"link", `[Help](/help "Get help")`, `<p><a href="/help" title="Get help">Help</a></p>` + "\n"},
+		{"image", "![the *logo*](/logo.png)", `<p><img src="/logo.png" alt="the logo"></p>` + "\n"},
+		{"autolink", "<https://example.com> <ada@example.com>",
+			`<p><a href="https://example.com">https://example.com</a> <a href="mailto:ada@example.com">ada@example.com</a></p>` + "\n"},
+		{"rule", "a\n\n***\n\nb", "<p>a</p>\n<hr>\n<p>b</p>\n"},
+		{"quote", "> quoted\nlazily", "<blockquote>\n<p>quoted\nlazily</p>\n</blockquote>\n"},
+		{"tight list", "- one\n- two\n  - nested",
+			"<ul>\n<li>one</li>\n<li>two\n<ul>\n<li>nested</li>\n</ul>\n</li>\n</ul>\n"},
+		{"loose list", "1. one\n\n2. two", "<ol>\n<li>\n<p>one</p>\n</li>\n<li>\n<p>two</p>\n</li>\n</ol>\n"},
+		{"ordered start", "3) three", "<ol start=\"3\">\n<li>three</li>\n</ol>\n"},
+		{"indented code", "    x := 1", "<pre><code>x := 1\n</code></pre>\n"},
+		{"raw html kept", `<div class="note">Note</div>`, `<div class="note">Note</div>` + "\n"},
+	}
+	for _, tt := range tests {
+		if got := string(Markdown(tt.src)); got != tt.want {
+			t.Errorf("%s:\n got %q\nwant %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMarkdownCodeFences(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"language", "```go\nif a < b {\n}\n```",
+			"<pre><code class=\"language-go\">if a &lt; b {\n}\n</code></pre>\n"},
+		{"tildes", "~~~\n<script>alert(1)</script>\n~~~",
+			"<pre><code>&lt;script&gt;alert(1)&lt;/script&gt;\n</code></pre>\n"},
+		{"markdown inside", "```\n# not a heading\n*not em*\n```",
+			"<pre><code># not a heading\n*not em*\n</code></pre>\n"},
+		{"longer close", "````\n```\n````", "<pre><code>```\n</code></pre>\n"},
+		{"unclosed", "```\ncode", "<pre><code>code\n</code></pre>\n"},
+		{"in list", "- step:\n\n  ```sh\n  make\n  ```",
+			"<ul>\n<li>\n<p>step:</p>\n<pre><code class=\"language-sh\">make\n</code></pre>\n</li>\n</ul>\n"},
+	}
+	for _, tt := range tests {
+		if got := string(Markdown(tt.src)); got != tt.want {
+			t.Errorf("%s:\n got %q\nwant %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMarkdownSanitizes(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{"script block", "<script>\nalert(1)\n</script>"},
+		{"inline script", "Hello <script>alert(1)</script> world"},
+		{"script in list", "- <script>alert(1)</script>"},
+		{"script in quote", "> <script>alert(1)</script>"},
+		{"split script", "<scr<script>ipt>alert(1)</script>"},
+		{"uppercase script", "<SCRIPT>alert(1)</SCRIPT>"},
+		{"style", "<style>body{display:none}</style>"},
+		{"event handler", `<img src="x.png" onerror="alert(1)">`},
+		{"event handler inline", `text <b onclick="alert(1)">bold</b>`},
+		{"style attr", `<p style="background:url(javascript:alert(1))">x</p>`},
+		{"javascript link", "[click](javascript:alert(1))"},
+		{"javascript mixed case", "[click](JaVaScRiPt:alert(1))"},
+		{"javascript entity", "[click](java&#x09;script:alert(1))"},
+		{"javascript autolink", "<javascript:alert(1)>"},
+		{"javascript image", "![x](javascript:alert(1))"},
+		{"javascript raw link", `<a href="  javascript:alert(1)">x</a>`},
+		{"iframe", `<iframe src="https://evil.example"></iframe>`},
+	}
+	for _, tt := range tests {
+		got := strings.ToLower(string(Markdown(tt.src)))
+		for _, bad := range []string{"<script", "<style", "<iframe", "onerror", "onclick", "style=", `href="java`, `src="java`} {
+			if strings.Contains(got, bad) {
+				t.Errorf("%s: output contains %q: %s", tt.name, bad, got)
+			}
+		}
+	}
+}
+
+func TestMarkdownPolicy(t *testing.T) {
+	t.Cleanup(func() { SetMarkdownPolicy(MarkdownPolicy{}) })
+
+	SetMarkdownPolicy(MarkdownPolicy{EscapeHTML: true})
+	if got, want := string(Markdown("a <b>bold</b> move")), "<p>a &lt;b&gt;bold&lt;/b&gt; move</p>\n"; got != want {
+		t.Errorf("EscapeHTML:\n got %q\nwant %q", got, want)
+	}
+
+	SetMarkdownPolicy(MarkdownPolicy{URLSchemes: []string{"https", "myapp"}})
+	if got, want := string(Markdown("[open](myapp://todos) [web](http://x)")),
+		`<p><a href="myapp://todos">open</a> <a>web</a></p>`+"\n"; got != want {
+		t.Errorf("URLSchemes:\n got %q\nwant %q", got, want)
+	}
+
+	SetMarkdownPolicy(MarkdownPolicy{URLSchemes: []string{"javascript"}})
+	if got := string(Markdown("[x](javascript:alert(1))")); strings.Contains(got, "javascript") {
+		t.Errorf("javascript: allowed by policy: %s", got)
+	}
+
+	SetMarkdownPolicy(MarkdownPolicy{Tags: []string{"script", "video"}, Attrs: []string{"data-*", "onclick", "style"}})
+	got := string(Markdown(`<video data-id="1" onclick="x()" style="color:red">v</video><script>x()</script>`))
+	if want := `<video data-id="1">v</video>` + "\n"; got != want {
+		t.Errorf("Tags and Attrs:\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestEnableMarkdown(t *testing.T) {
+	e := New()
+	if e.HasFunc("markdown") {
+		t.Fatal("markdown registered without EnableMarkdown")
+	}
+	e.EnableMarkdown()
+	if err := e.Parse("notes", `<article>{{markdown .}}</article>`); err != nil {
+		t.Fatal(err)
+	}
+	html, err := e.Render("notes", "## New\n\n- **Faster** <script>x()</script>sync")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "<article><h2>New</h2>\n<ul>\n<li><strong>Faster</strong> sync</li>\n</ul>\n</article>"
+	if html != want {
+		t.Errorf("got  %q\nwant %q", html, want)
+	}
+}
@@ -0,0 +1,41 @@
+package render
+
+import "html/template"
+
+// Optimistic UI naming, shared by dsOptimistic and the router's
+// Context.OptimisticConfirm and Context.OptimisticRollback.
+const (
+	// OptimisticSignal holds a pending flag per element ID, set when an
+	// optimistic action starts, e.g. $_optimistic['todo-1'].
+	OptimisticSignal = "_optimistic"
+
+	// OptimisticErrorSignal holds the message from the last rollback,
+	// for an error flash, e.g. <p {{dsText "$_optimisticError"}}></p>.
+	OptimisticErrorSignal = "_optimisticError"
+
+	// OptimisticConfirmEvent is dispatched on window when the server
+	// accepts a mutation. Its detail is {"target": "#id"}.
+	OptimisticConfirmEvent = "irgo-optimistic-confirm"
+
+	// OptimisticRollbackEvent is dispatched on window when the server
+	// rejects a mutation. Its detail is {"target": "#id", "message": "..."}.
+	OptimisticRollbackEvent = "irgo-optimistic-rollback"
+)
+
+// dsOptimistic generates attributes that mark the element pending and
+// run action on click, adding pendingClass until the server confirms or
+// rolls back the change for the element's ID, e.g.
+// <li id="todo-1" {{dsOptimistic "@post('/todos/1/toggle')" "opacity-50"}}>
+func dsOptimistic(action, pendingClass string) template.HTMLAttr {
+	pending := "$" + OptimisticSignal + "[el.id]"
+	click := pending + " = true; " + action
+	clear := "evt.detail.target === '#' + el.id && (" + pending + " = false)"
+	checkExpression("data-on:click", click)
+	checkExpression("data-class:"+pendingClass, pending)
+	checkExpression("data-on:"+OptimisticConfirmEvent, clear)
+	return template.HTMLAttr(`data-signals:` + OptimisticSignal + `__ifmissing="{}" ` +
+		`data-on:click="` + click + `" ` +
+		`data-class:` + pendingClass + `="` + pending + `" ` +
+		`data-on:` + OptimisticConfirmEvent + `__window="` + clear + `" ` +
+		`data-on:` + OptimisticRollbackEvent + `__window="` + clear + `"`)
+}
@@ -0,0 +1,24 @@
+package render
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDsOptimistic(t *testing.T) {
+	got := string(dsOptimistic("@post('/todos/1/toggle')", "opacity-50"))
+	for _, want := range []string{
+		`data-signals:_optimistic__ifmissing="{}"`,
+		`data-on:click="$_optimistic[el.id] = true; @post('/todos/1/toggle')"`,
+		`data-class:opacity-50="$_optimistic[el.id]"`,
+		`data-on:irgo-optimistic-confirm__window="evt.detail.target === '#' + el.id && ($_optimistic[el.id] = false)"`,
+		`data-on:irgo-optimistic-rollback__window="evt.detail.target === '#' + el.id && ($_optimistic[el.id] = false)"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("dsOptimistic output missing %q:\n%s", want, got)
+		}
+	}
+	if issues := LintAttributes(got); len(issues) > 0 {
+		t.Errorf("lint issues: %v", issues)
+	}
+}
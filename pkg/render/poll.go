@@ -0,0 +1,42 @@
+package render
+
+import (
+	"html/template"
+	"strings"
+
+	"github.com/stukennedy/irgo/pkg/core"
+)
+
+// PollKey returns the key under the "_poll" Datastar signal that
+// router.Route.Polling updates for a path, e.g. "todos_stats" for
+// "/todos/stats". Any query string is ignored.
+func PollKey(path string) string {
+	path, _, _ = strings.Cut(path, "?")
+	var b strings.Builder
+	for _, r := range strings.Trim(path, "/") {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	if b.Len() == 0 {
+		return "root"
+	}
+	return b.String()
+}
+
+// dsPoll generates attributes that poll url at the interval the server
+// sends in the "_poll" signal (see router.Route.Polling), checking once a
+// second, e.g. <div id="stats" {{dsPoll "/stats"}}. Polls are sent with
+// background priority, so on a busy bridge they wait behind user actions.
+func dsPoll(url string) template.HTMLAttr {
+	sig := "$_poll." + PollKey(url)
+	expr := `if (Date.now() >= ` + sig + `.at) { ` +
+		sig + `.at = Date.now() + (` + sig + `.every || 1000); ` +
+		`@get('` + url + `', {headers: {'` + core.PriorityHeader + `': 'background'}}) }`
+	checkExpression("data-on-interval", expr)
+	return template.HTMLAttr(`data-signals:_poll.` + PollKey(url) + `__ifmissing="{every: 0, at: 0}" ` +
+		`data-on-interval__duration.1s="` + expr + `"`)
+}
@@ -0,0 +1,38 @@
+package render
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPollKey(t *testing.T) {
+	tests := map[string]string{
+		"/stats":          "stats",
+		"/todos/stats":    "todos_stats",
+		"/feed/42?page=2": "feed_42",
+		"/":               "root",
+		"/a-b.c/":         "a_b_c",
+	}
+	for path, want := range tests {
+		if got := PollKey(path); got != want {
+			t.Errorf("PollKey(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestDsPoll(t *testing.T) {
+	got := string(dsPoll("/todos/stats"))
+	for _, want := range []string{
+		`data-signals:_poll.todos_stats__ifmissing="{every: 0, at: 0}"`,
+		`data-on-interval__duration.1s="`,
+		`$_poll.todos_stats.every || 1000`,
+		`@get('/todos/stats', {headers: {'X-Irgo-Priority': 'background'}})`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("dsPoll output missing %q:\n%s", want, got)
+		}
+	}
+	if issues := LintAttributes(got); len(issues) > 0 {
+		t.Errorf("lint issues: %v", issues)
+	}
+}
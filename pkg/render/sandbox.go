@@ -0,0 +1,404 @@
+package render
+
+import (
+	"html"
+	"strings"
+)
+
+// DefaultSandboxPrefix namespaces ids and classes when SandboxPolicy.Prefix
+// is empty.
+const DefaultSandboxPrefix = "sandbox"
+
+// SandboxPolicy controls what SandboxFragment lets through.
+type SandboxPolicy struct {
+	// Prefix namespaces ids and classes: with "weather", id="app" becomes
+	// id="weather-app", so a fragment can't target the host page's
+	// elements. Defaults to DefaultSandboxPrefix.
+	Prefix string
+
+	// Tags are allowed in addition to the default formatting tags. They
+	// get the global attributes only. Script-like elements (script,
+	// style, iframe, svg and the like) can't be allowed.
+	Tags []string
+
+	// Attrs lists the data-* and hx-* attributes to keep, verbatim; an
+	// entry ending in "*" matches by prefix, e.g. "data-on:*". All other
+	// data-* and hx-* attributes are stripped.
+	Attrs []string
+}
+
+func (p SandboxPolicy) prefix() string {
+	if p.Prefix == "" {
+		return DefaultSandboxPrefix
+	}
+	return p.Prefix
+}
+
+func (p SandboxPolicy) allowsTag(name string) bool {
+	if sandboxTags[name] {
+		return true
+	}
+	for _, t := range p.Tags {
+		if strings.EqualFold(t, name) {
+			return !sandboxDropped[name]
+		}
+	}
+	return false
+}
+
+func (p SandboxPolicy) allowsAttr(name string) bool {
+	for _, a := range p.Attrs {
+		if a == name || (strings.HasSuffix(a, "*") && strings.HasPrefix(name, a[:len(a)-1])) {
+			return true
+		}
+	}
+	return false
+}
+
+// sandboxTags are the formatting and structural tags allowed by default.
+var sandboxTags = map[string]bool{
+	"a": true, "abbr": true, "article": true, "aside": true, "b": true,
+	"blockquote": true, "br": true, "caption": true, "cite": true,
+	"code": true, "dd": true, "del": true, "details": true, "dfn": true,
+	"div": true, "dl": true, "dt": true, "em": true, "figcaption": true,
+	"figure": true, "footer": true, "h1": true, "h2": true, "h3": true,
+	"h4": true, "h5": true, "h6": true, "header": true, "hr": true,
+	"i": true, "img": true, "ins": true, "kbd": true, "li": true,
+	"mark": true, "ol": true, "p": true, "pre": true, "q": true, "s": true,
+	"section": true, "small": true, "span": true, "strong": true,
+	"sub": true, "summary": true, "sup": true, "table": true, "tbody": true,
+	"td": true, "tfoot": true, "th": true, "thead": true, "time": true,
+	"tr": true, "u": true, "ul": true,
+}
+
+// sandboxDropped elements are removed along with their content.
+var sandboxDropped = map[string]bool{
+	"script": true, "style": true, "iframe": true, "object": true,
+	"svg": true, "math": true, "template": true, "noscript": true,
+	"textarea": true, "title": true, "xmp": true, "noembed": true,
+	"noframes": true,
+}
+
+var voidTags = map[string]bool{"br": true, "hr": true, "img": true, "wbr": true}
+
+// impliedEnd tags are closed by a sibling of the same kind.
+var impliedEnd = map[string]bool{"li": true, "p": true, "dt": true, "dd": true, "tr": true, "td": true, "th": true}
+
+// sandboxAttrs are the attributes allowed on any tag; sandboxTagAttrs add
+// per-tag ones.
+var sandboxAttrs = map[string]bool{
+	"id": true, "class": true, "title": true, "lang": true, "dir": true, "role": true,
+}
+
+var sandboxTagAttrs = map[string]map[string]bool{
+	"a":    {"href": true},
+	"img":  {"src": true, "alt": true, "width": true, "height": true},
+	"td":   {"colspan": true, "rowspan": true},
+	"th":   {"colspan": true, "rowspan": true, "scope": true},
+	"ol":   {"start": true},
+	"time": {"datetime": true},
+}
+
+// SandboxFragment sanitises HTML from an untrusted source, such as a
+// plugin, for inclusion in the page:
+//
+//   - only formatting tags are kept (plus policy.Tags); script-like
+//     elements are dropped with their content, others are unwrapped
+//   - event handlers, styles and unsafe URLs are removed
+//   - ids, classes and "#fragment" links are prefixed with policy.Prefix,
+//     so out-of-band swaps and Datastar patches can't reach host elements
+//     like #app
+//   - data-* and hx-* attributes are stripped unless listed in policy.Attrs
+//
+// The result is wrapped in <div data-sandbox="prefix">, with any unclosed
+// tags closed.
+func SandboxFragment(fragment string, policy SandboxPolicy) string {
+	rules := sandboxRules{policy: policy, prefix: policy.prefix()}
+	return `<div data-sandbox="` + html.EscapeString(rules.prefix) + `">` + sanitize(fragment, rules) + "</div>"
+}
+
+// sanitizeRules decides which tags and attributes sanitize keeps.
+type sanitizeRules interface {
+	allowsTag(name string) bool
+	// attr reports whether an attribute is kept, and its rewritten value.
+	attr(tagName, name, value string) (string, bool)
+}
+
+// sanitize keeps the tags and attributes of src allowed by rules, closing
+// any left open. Script-like elements are always dropped.
+func sanitize(src string, rules sanitizeRules) string {
+	s := sandboxer{rules: rules}
+	s.run(src)
+	for i := len(s.open) - 1; i >= 0; i-- {
+		s.b.WriteString("</" + s.open[i] + ">")
+	}
+	return s.b.String()
+}
+
+type sandboxer struct {
+	rules sanitizeRules
+	open  []string // Allowed elements not yet closed
+	b     strings.Builder
+}
+
+func (s *sandboxer) run(src string) {
+	for src != "" {
+		i := strings.IndexByte(src, '<')
+		if i < 0 {
+			s.text(src)
+			return
+		}
+		s.text(src[:i])
+		src = src[i:]
+
+		// Comments, doctypes, CDATA and processing instructions are dropped
+		if strings.HasPrefix(src, "<!--") {
+			end := strings.Index(src[4:], "-->")
+			if end < 0 {
+				return
+			}
+			src = src[4+end+3:]
+			continue
+		}
+		if strings.HasPrefix(src, "<!") || strings.HasPrefix(src, "<?") {
+			end := strings.IndexByte(src, '>')
+			if end < 0 {
+				return
+			}
+			src = src[end+1:]
+			continue
+		}
+
+		t, rest, ok := parseTag(src)
+		if !ok {
+			s.text("<")
+			src = src[1:]
+			continue
+		}
+		src = rest
+		if t.end {
+			s.endTag(t.name)
+			continue
+		}
+		if sandboxDropped[t.name] {
+			src = skipElement(src, t.name)
+			continue
+		}
+		s.startTag(t)
+	}
+}
+
+// text writes character data, re-escaping it so stray '<' and '&' can't
+// start markup.
+func (s *sandboxer) text(t string) {
+	if t != "" {
+		s.b.WriteString(html.EscapeString(html.UnescapeString(t)))
+	}
+}
+
+func (s *sandboxer) startTag(t tag) {
+	if !s.rules.allowsTag(t.name) {
+		return
+	}
+	// <li>one<li>two: the second item implicitly ends the first
+	if n := len(s.open); n > 0 && s.open[n-1] == t.name && impliedEnd[t.name] {
+		s.endTag(t.name)
+	}
+	s.b.WriteString("<" + t.name)
+	for _, a := range t.attrs {
+		value, ok := s.rules.attr(t.name, a.name, a.value)
+		if ok {
+			s.b.WriteString(" " + a.name + `="` + html.EscapeString(value) + `"`)
+		}
+	}
+	s.b.WriteString(">")
+	if !voidTags[t.name] {
+		s.open = append(s.open, t.name)
+	}
+}
+
+// endTag closes name and anything left open inside it; end tags with no
+// matching open element are ignored.
+func (s *sandboxer) endTag(name string) {
+	for i := len(s.open) - 1; i >= 0; i-- {
+		if s.open[i] != name {
+			continue
+		}
+		for j := len(s.open) - 1; j >= i; j-- {
+			s.b.WriteString("</" + s.open[j] + ">")
+		}
+		s.open = s.open[:i]
+		return
+	}
+}
+
+// sandboxRules are the sanitizeRules of SandboxFragment.
+type sandboxRules struct {
+	policy SandboxPolicy
+	prefix string
+}
+
+func (s sandboxRules) allowsTag(name string) bool {
+	return s.policy.allowsTag(name)
+}
+
+func (s sandboxRules) attr(tagName, name, value string) (string, bool) {
+	if strings.HasPrefix(name, "data-") || strings.HasPrefix(name, "hx-") {
+		return value, s.policy.allowsAttr(name)
+	}
+	if !sandboxAttrs[name] && !sandboxTagAttrs[tagName][name] {
+		return "", false
+	}
+	switch name {
+	case "id":
+		if value = strings.TrimSpace(value); value == "" {
+			return "", false
+		}
+		return s.prefix + "-" + value, true
+	case "class":
+		classes := strings.Fields(value)
+		for i, c := range classes {
+			classes[i] = s.prefix + "-" + c
+		}
+		return strings.Join(classes, " "), len(classes) > 0
+	case "href", "src":
+		value = strings.TrimSpace(value)
+		if id, ok := strings.CutPrefix(value, "#"); ok && id != "" {
+			return "#" + s.prefix + "-" + id, true
+		}
+		return value, sandboxURL(value)
+	}
+	return value, true
+}
+
+// sandboxURL allows relative URLs and the http, https, mailto and tel
+// schemes. Whitespace and control characters are ignored when finding the
+// scheme, as browsers do.
+func sandboxURL(u string) bool {
+	return allowedURL(u, defaultURLSchemes)
+}
+
+var defaultURLSchemes = []string{"http", "https", "mailto", "tel"}
+
+// allowedURL reports whether u is relative or uses one of schemes. Script
+// URLs are never allowed.
+func allowedURL(u string, schemes []string) bool {
+	u = strings.Map(func(r rune) rune {
+		if r <= ' ' {
+			return -1
+		}
+		return r
+	}, u)
+	colon := strings.IndexByte(u, ':')
+	if colon < 0 || strings.ContainsAny(u[:colon], "/?#") {
+		return true
+	}
+	scheme := u[:colon]
+	if strings.EqualFold(scheme, "javascript") || strings.EqualFold(scheme, "vbscript") {
+		return false
+	}
+	for _, s := range schemes {
+		if strings.EqualFold(s, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// skipElement returns src after the end tag closing name, or "" if there
+// is none.
+func skipElement(src, name string) string {
+	end := strings.Index(strings.ToLower(src), "</"+name)
+	if end < 0 {
+		return ""
+	}
+	src = src[end:]
+	if gt := strings.IndexByte(src, '>'); gt >= 0 {
+		return src[gt+1:]
+	}
+	return ""
+}
+
+type tag struct {
+	name  string // Lower case
+	end   bool
+	attrs []tagAttr
+}
+
+type tagAttr struct {
+	name  string // Lower case
+	value string // Unescaped
+}
+
+// parseTag parses the start or end tag at the beginning of src, returning
+// the rest of src. ok is false if src doesn't start with a complete tag.
+func parseTag(src string) (t tag, rest string, ok bool) {
+	i := 1
+	if i < len(src) && src[i] == '/' {
+		t.end = true
+		i++
+	}
+	if i >= len(src) || !isASCIILetter(src[i]) {
+		return t, src, false
+	}
+	start := i
+	for i < len(src) && !isTagSpace(src[i]) && src[i] != '/' && src[i] != '>' {
+		i++
+	}
+	t.name = strings.ToLower(src[start:i])
+
+	for {
+		for i < len(src) && (isTagSpace(src[i]) || src[i] == '/') {
+			i++
+		}
+		if i >= len(src) {
+			return t, src, false
+		}
+		if src[i] == '>' {
+			return t, src[i+1:], true
+		}
+
+		start = i
+		i++ // A name may start with '='
+		for i < len(src) && !isTagSpace(src[i]) && src[i] != '/' && src[i] != '>' && src[i] != '=' {
+			i++
+		}
+		a := tagAttr{name: strings.ToLower(src[start:i])}
+		for i < len(src) && isTagSpace(src[i]) {
+			i++
+		}
+		if i < len(src) && src[i] == '=' {
+			i++
+			for i < len(src) && isTagSpace(src[i]) {
+				i++
+			}
+			if i < len(src) && (src[i] == '"' || src[i] == '\'') {
+				q := src[i]
+				end := strings.IndexByte(src[i+1:], q)
+				if end < 0 {
+					return t, src, false
+				}
+				a.value = src[i+1 : i+1+end]
+				i += end + 2
+			} else {
+				start = i
+				for i < len(src) && !isTagSpace(src[i]) && src[i] != '>' {
+					i++
+				}
+				a.value = src[start:i]
+			}
+			a.value = html.UnescapeString(a.value)
+		}
+		if !t.end {
+			t.attrs = append(t.attrs, a)
+		}
+	}
+}
+
+func isASCIILetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func isTagSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
@@ -0,0 +1,61 @@
+package render
+
+import "testing"
+
+func TestSandboxFragment(t *testing.T) {
+	policy := SandboxPolicy{Prefix: "wx"}
+	tests := []struct {
+		name, in, want string
+	}{
+		{
+			"oob hijack",
+			`<div id="app" hx-swap-oob="true" data-on:load="@get('/steal')">owned</div>`,
+			`<div id="wx-app">owned</div>`,
+		},
+		{
+			"script",
+			`<p>hi<script>alert(1)</script><SCRIPT src=x></script ></p>`,
+			`<p>hi</p>`,
+		},
+		{
+			"handlers and urls",
+			`<a href="jav&#x09;ascript:alert(1)" onclick="x()">a</a><img src="https://x/y.png" onerror="x()" style="color:red"><a href="#top">t</a>`,
+			`<a>a</a><img src="https://x/y.png"><a href="#wx-top">t</a>`,
+		},
+		{
+			"formatting",
+			`<h2 class="title big">Hi</h2><p><strong>bold</strong>, <em>em</em> &amp; <code>x &lt; y</code></p><ul><li>one<li>two</ul>`,
+			`<h2 class="wx-title wx-big">Hi</h2><p><strong>bold</strong>, <em>em</em> &amp; <code>x &lt; y</code></p><ul><li>one</li><li>two</li></ul>`,
+		},
+		{
+			"unknown tags unwrapped",
+			`<form action="/x"><input name="a"><button>go</button></form><!-- note --><b>ok`,
+			`go<b>ok</b>`,
+		},
+		{
+			"stray markup",
+			`a < b <!doctype html> c > d </div></b>`,
+			`a &lt; b  c &gt; d `,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := `<div data-sandbox="wx">` + tt.want + `</div>`
+			if got := SandboxFragment(tt.in, policy); got != want {
+				t.Errorf("got  %s\nwant %s", got, want)
+			}
+		})
+	}
+}
+
+func TestSandboxFragmentPolicy(t *testing.T) {
+	policy := SandboxPolicy{
+		Tags:  []string{"button", "script"},
+		Attrs: []string{"data-on:*", "data-show"},
+	}
+	got := SandboxFragment(`<button data-on:click="@post('/x')" data-show="$a" data-text="$b" hx-get="/y">go</button><script>x</script>`, policy)
+	want := `<div data-sandbox="sandbox"><button data-on:click="@post(&#39;/x&#39;)" data-show="$a">go</button></div>`
+	if got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
@@ -0,0 +1,39 @@
+package render
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestStableMapOutput guards the byte-identical output that fragment caching
+// and ETags rely on: templates range over maps in key order and
+// dsSignalsJSON marshals map keys sorted.
+func TestStableMapOutput(t *testing.T) {
+	e := New()
+	err := e.Parse("map", `<ul {{dsSignalsJSON .Signals}}>{{range $k, $v := .Items}}<li {{class $k $v}}>{{$k}}={{$v}}</li>{{end}}</ul>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items := map[string]string{}
+	signals := map[string]any{}
+	for i := 0; i < 40; i++ {
+		items[fmt.Sprintf("item%02d", i)] = fmt.Sprintf("v%d", i)
+		signals[fmt.Sprintf("s%d", i)] = map[string]int{"b": i, "a": -i}
+	}
+	data := map[string]any{"Items": items, "Signals": signals}
+
+	first, err := e.Render("map", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 50; i++ {
+		got, err := e.Render("map", data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != first {
+			t.Fatalf("render %d differs:\n%s\n%s", i, first, got)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package render
+
+import (
+	"fmt"
+	"html/template"
+	"io/fs"
+	"path"
+)
+
+// templateExts are the file extensions SwapTemplates loads.
+var templateExts = map[string]bool{".html": true, ".tmpl": true, ".gohtml": true}
+
+type smokeTest struct {
+	name string
+	data any
+}
+
+// SmokeTest registers a template render that must succeed after
+// SwapTemplates, or the swap is rolled back.
+func (e *Engine) SmokeTest(name string, data any) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.smoke = append(e.smoke, smokeTest{name: name, data: data})
+}
+
+// SwapTemplates replaces the engine's templates with every .html, .tmpl
+// and .gohtml file in fsys, for over-the-air content updates. The new set
+// must parse; it is then swapped in atomically and the SmokeTest renders
+// run. If any fails, the previous set is restored and the error returned.
+func (e *Engine) SwapTemplates(fsys fs.FS) error {
+	var files []string
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && templateExts[path.Ext(p)] {
+			files = append(files, p)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return ErrNoTemplates
+	}
+
+	e.mu.Lock()
+	e.pending = make(map[string]string)
+	tmpl, err := e.parseFS(template.New("").Funcs(e.funcs), fsys, files...)
+	source := e.pending
+	e.pending = nil
+	if err != nil {
+		e.mu.Unlock()
+		logf("template swap rejected: %v", err)
+		return err
+	}
+	prevBase, prevTemplates, prevFallback, prevSources, prevSource := e.base, e.templates, e.fallback, e.sources, e.source
+	e.base, e.source = tmpl, source
+	e.sources = []parseFunc{func(e *Engine, t *template.Template) (*template.Template, error) {
+		return e.parseFS(t, fsys, files...)
+	}}
+	e.publish()
+	smoke := append([]smokeTest(nil), e.smoke...)
+	e.mu.Unlock()
+
+	for _, t := range smoke {
+		if _, err := e.Render(t.name, t.data); err != nil {
+			e.mu.Lock()
+			e.base, e.templates, e.fallback, e.sources = prevBase, prevTemplates, prevFallback, prevSources
+			e.source = prevSource
+			e.mu.Unlock()
+			logf("template swap rolled back: %v", err)
+			return fmt.Errorf("smoke render failed, rolled back: %w", err)
+		}
+	}
+
+	logf("swapped in %d template files", len(files))
+	return nil
+}
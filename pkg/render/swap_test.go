@@ -0,0 +1,69 @@
+package render
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestSwapTemplates(t *testing.T) {
+	e := New()
+	if err := e.Parse("home", `{{define "home"}}old {{.}}{{end}}`); err != nil {
+		t.Fatal(err)
+	}
+	e.SmokeTest("home", "smoke")
+
+	err := e.SwapTemplates(fstest.MapFS{
+		"pages/home.html": {Data: []byte(`{{define "home"}}new {{upper .}}{{end}}`)},
+		"README.md":       {Data: []byte(`{{broken`)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := e.Render("home", "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "new X" {
+		t.Errorf("got %q, want %q", got, "new X")
+	}
+}
+
+func TestSwapTemplatesRejectsParseError(t *testing.T) {
+	e := New()
+	if err := e.Parse("home", `{{define "home"}}old{{end}}`); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := e.SwapTemplates(fstest.MapFS{"home.html": {Data: []byte(`{{define "home"}}{{.`)}}); err == nil {
+		t.Fatal("expected parse error")
+	}
+	if got, _ := e.Render("home", nil); got != "old" {
+		t.Errorf("got %q, want old templates kept", got)
+	}
+	if err := e.SwapTemplates(fstest.MapFS{"notes.txt": {Data: []byte("hi")}}); err != ErrNoTemplates {
+		t.Errorf("err = %v, want ErrNoTemplates", err)
+	}
+}
+
+func TestSwapTemplatesRollsBackOnSmokeFailure(t *testing.T) {
+	e := New()
+	if err := e.Parse("home", `{{define "home"}}old{{end}}`); err != nil {
+		t.Fatal(err)
+	}
+	e.SmokeTest("home", nil)
+
+	err := e.SwapTemplates(fstest.MapFS{
+		"home.html": {Data: []byte(`{{define "home"}}{{template "missing"}}{{end}}`)},
+	})
+	if err == nil || !strings.Contains(err.Error(), "rolled back") {
+		t.Fatalf("err = %v, want rollback error", err)
+	}
+	got, err := e.Render("home", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "old" {
+		t.Errorf("got %q, want previous templates restored", got)
+	}
+}
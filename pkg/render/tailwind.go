@@ -1,54 +1,190 @@
 package render
 
-// TailwindConfig provides default Tailwind CSS configuration for irgo apps.
-const TailwindConfig = `/** @type {import('tailwindcss').Config} */
+import (
+	"encoding/json"
+	"html"
+	"html/template"
+	"strings"
+	texttemplate "text/template"
+)
+
+// TailwindOptions customizes TailwindConfig.
+type TailwindOptions struct {
+	// Content adds globs to those scanned for class names, which are
+	// "./**/*.templ" and "./**/*.go".
+	Content []string
+	// Colors adds theme colors by name, e.g. {"brand": "#0ea5e9"}.
+	Colors map[string]string
+}
+
+// TailwindConfig returns a tailwind.config.js for irgo apps, with the
+// animations Datastar patches use.
+func TailwindConfig(opts TailwindOptions) string {
+	content := append([]string{"./**/*.templ", "./**/*.go"}, opts.Content...)
+	return scaffold(tailwindConfigTemplate, map[string]any{
+		"Content": content,
+		"Colors":  opts.Colors,
+	})
+}
+
+var tailwindConfigTemplate = texttemplate.Must(texttemplate.New("tailwind.config.js").Parse(`/** @type {import('tailwindcss').Config} */
 module.exports = {
   content: [
-    "./**/*.templ",
-    "./**/*.go",
+{{- range .Content}}
+    "{{js .}}",
+{{- end}}
   ],
   theme: {
     extend: {
-      // Datastar and general animations
-      animation: {
-        'morph-in': 'morphIn 0.3s ease-out',
-        'morph-out': 'morphOut 0.3s ease-out',
-        'fade-in': 'fadeIn 0.3s ease-out',
-        'fade-out': 'fadeOut 0.3s ease-out',
-        'slide-in': 'slideIn 0.3s ease-out',
-        'slide-out': 'slideOut 0.3s ease-out',
+{{- with .Colors}}
+      colors: {
+{{- range $name, $value := .}}
+        '{{js $name}}': '{{js $value}}',
+{{- end}}
       },
-      keyframes: {
-        'morphIn': {
-          '0%': { opacity: '0', transform: 'translateY(-10px)' },
-          '100%': { opacity: '1', transform: 'translateY(0)' },
-        },
-        'morphOut': {
-          '0%': { opacity: '1' },
-          '100%': { opacity: '0' },
-        },
-        'fadeIn': {
-          '0%': { opacity: '0' },
-          '100%': { opacity: '1' },
-        },
-        'fadeOut': {
-          '0%': { opacity: '1' },
-          '100%': { opacity: '0' },
-        },
-        'slideIn': {
-          '0%': { opacity: '0', transform: 'translateX(-10px)' },
-          '100%': { opacity: '1', transform: 'translateX(0)' },
-        },
-        'slideOut': {
-          '0%': { opacity: '1', transform: 'translateX(0)' },
-          '100%': { opacity: '0', transform: 'translateX(10px)' },
-        },
-      },
-    },
+{{- end}}
+` + tailwindAnimations))
+
+// PackageJSONOptions customizes PackageJSON. Empty fields take the
+// defaults shown.
+type PackageJSONOptions struct {
+	Name    string // "irgo-app"
+	Version string // "1.0.0"
+	Private bool
+
+	InputCSS  string // "./assets/css/input.css"
+	OutputCSS string // "./assets/css/output.css"
+
+	// BuildScript and WatchScript name the npm scripts that build and
+	// watch the CSS.
+	BuildScript string // "build:css"
+	WatchScript string // "watch:css"
+
+	// TailwindPackage and TailwindVersion set the Tailwind dev
+	// dependency, e.g. "@tailwindcss/cli" and "^4.0.0" for Tailwind 4.
+	TailwindPackage string // "tailwindcss"
+	TailwindVersion string // "^3.4.0"
+}
+
+// PackageJSON returns a package.json with scripts building the app's
+// Tailwind CSS.
+func PackageJSON(opts PackageJSONOptions) string {
+	return scaffold(packageJSONTemplate, map[string]any{
+		"Name":            orDefault(opts.Name, "irgo-app"),
+		"Version":         orDefault(opts.Version, "1.0.0"),
+		"Private":         opts.Private,
+		"InputCSS":        orDefault(opts.InputCSS, "./assets/css/input.css"),
+		"OutputCSS":       orDefault(opts.OutputCSS, "./assets/css/output.css"),
+		"BuildScript":     orDefault(opts.BuildScript, "build:css"),
+		"WatchScript":     orDefault(opts.WatchScript, "watch:css"),
+		"TailwindPackage": orDefault(opts.TailwindPackage, "tailwindcss"),
+		"TailwindVersion": orDefault(opts.TailwindVersion, "^3.4.0"),
+	})
+}
+
+var packageJSONTemplate = texttemplate.Must(texttemplate.New("package.json").Funcs(texttemplate.FuncMap{
+	"json": jsonString,
+}).Parse(`{
+  "name": {{json .Name}},
+  "version": {{json .Version}},
+{{- if .Private}}
+  "private": true,
+{{- end}}
+  "scripts": {
+    {{json .BuildScript}}: {{json (print "tailwindcss -i " .InputCSS " -o " .OutputCSS " --minify")}},
+    {{json .WatchScript}}: {{json (print "tailwindcss -i " .InputCSS " -o " .OutputCSS " --watch")}}
   },
-  plugins: [],
+  "devDependencies": {
+    {{json .TailwindPackage}}: {{json .TailwindVersion}}
+  }
+}
+`))
+
+// BaseHTMLOptions customizes BaseHTML.
+type BaseHTMLOptions struct {
+	// Title is the page title. Empty leaves a {{.Title}} action in its
+	// place.
+	Title string
+	// ExtraHead is added to the end of the head, e.g. meta tags or
+	// render.NoJSDetector.
+	ExtraHead template.HTML
+	// BundledDatastar loads Datastar from the app's own assets, as
+	// mobile apps must, rather than the CDN.
+	BundledDatastar bool
+	// DatastarPath is the bundled Datastar's URL,
+	// "/assets/js/datastar.js" by default.
+	DatastarPath string
+	// Stylesheet is the built CSS's URL, "/assets/css/output.css" by
+	// default.
+	Stylesheet string
+}
+
+// BaseHTML returns a minimal page template with Datastar and Tailwind,
+// rendering its Content field in the #app div.
+func BaseHTML(opts BaseHTMLOptions) string {
+	title := "{{.Title}}"
+	if opts.Title != "" {
+		// Escaped, and kept from opening an action in the page template
+		title = strings.ReplaceAll(html.EscapeString(opts.Title), "{", "&#123;")
+	}
+	datastar := datastarCDN
+	if opts.BundledDatastar {
+		datastar = orDefault(opts.DatastarPath, "/assets/js/datastar.js")
+	}
+	return scaffold(baseHTMLTemplate, map[string]any{
+		"Title":      title,
+		"ExtraHead":  string(opts.ExtraHead),
+		"Datastar":   datastar,
+		"Stylesheet": orDefault(opts.Stylesheet, "/assets/css/output.css"),
+	})
+}
+
+var baseHTMLTemplate = texttemplate.Must(texttemplate.New("base.html").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0, user-scalable=no, viewport-fit=cover">
+    <meta name="mobile-web-app-capable" content="yes">
+    <meta name="apple-mobile-web-app-capable" content="yes">
+    <meta name="apple-mobile-web-app-status-bar-style" content="default">
+    <title>{{.Title}}</title>
+    <link rel="stylesheet" href="{{html .Stylesheet}}">
+    <script type="module" src="{{html .Datastar}}"></script>
+{{- with .ExtraHead}}
+    {{.}}
+{{- end}}
+</head>
+<body class="bg-gray-50 text-gray-900 safe-area">
+    <div id="app">
+        {{"{{.Content}}"}}
+    </div>
+</body>
+</html>
+`))
+
+// scaffold renders one of the scaffolding templates, which can't fail on
+// the data they are given.
+func scaffold(t *texttemplate.Template, data any) string {
+	var b strings.Builder
+	if err := t.Execute(&b, data); err != nil {
+		panic(err)
+	}
+	return b.String()
+}
+
+// orDefault returns s, or def if s is empty.
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+// jsonString quotes s as a JSON string.
+func jsonString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
 }
-`
 
 // TailwindCSS provides base CSS including Datastar integration styles.
 const TailwindCSS = `@tailwind base;
@@ -129,8 +265,73 @@ const TailwindCSS = `@tailwind base;
 }
 `
 
-// PackageJSON provides the default package.json for Tailwind setup.
-const PackageJSON = `{
+// DatastarScript returns the script tag for Datastar.
+// For mobile apps, this would be bundled locally.
+const DatastarScript = `<script type="module" src="` + datastarCDN + `"></script>`
+
+// datastarCDN is where DatastarScript loads Datastar from.
+const datastarCDN = "https://cdn.jsdelivr.net/gh/starfederation/datastar/bundles/datastar.js"
+
+// DefaultTailwindConfig is TailwindConfig with no options.
+//
+// Deprecated: use TailwindConfig, which can add content globs and colors.
+const DefaultTailwindConfig = `/** @type {import('tailwindcss').Config} */
+module.exports = {
+  content: [
+    "./**/*.templ",
+    "./**/*.go",
+  ],
+  theme: {
+    extend: {
+` + tailwindAnimations
+
+// tailwindAnimations closes TailwindConfig's theme.extend with the
+// animations Datastar patches use.
+const tailwindAnimations = `      // Datastar and general animations
+      animation: {
+        'morph-in': 'morphIn 0.3s ease-out',
+        'morph-out': 'morphOut 0.3s ease-out',
+        'fade-in': 'fadeIn 0.3s ease-out',
+        'fade-out': 'fadeOut 0.3s ease-out',
+        'slide-in': 'slideIn 0.3s ease-out',
+        'slide-out': 'slideOut 0.3s ease-out',
+      },
+      keyframes: {
+        'morphIn': {
+          '0%': { opacity: '0', transform: 'translateY(-10px)' },
+          '100%': { opacity: '1', transform: 'translateY(0)' },
+        },
+        'morphOut': {
+          '0%': { opacity: '1' },
+          '100%': { opacity: '0' },
+        },
+        'fadeIn': {
+          '0%': { opacity: '0' },
+          '100%': { opacity: '1' },
+        },
+        'fadeOut': {
+          '0%': { opacity: '1' },
+          '100%': { opacity: '0' },
+        },
+        'slideIn': {
+          '0%': { opacity: '0', transform: 'translateX(-10px)' },
+          '100%': { opacity: '1', transform: 'translateX(0)' },
+        },
+        'slideOut': {
+          '0%': { opacity: '1', transform: 'translateX(0)' },
+          '100%': { opacity: '0', transform: 'translateX(10px)' },
+        },
+      },
+    },
+  },
+  plugins: [],
+}
+`
+
+// DefaultPackageJSON is PackageJSON with no options.
+//
+// Deprecated: use PackageJSON, which can set the app's name.
+const DefaultPackageJSON = `{
   "name": "irgo-app",
   "version": "1.0.0",
   "scripts": {
@@ -143,12 +344,10 @@ const PackageJSON = `{
 }
 `
 
-// DatastarScript returns the script tag for Datastar.
-// For mobile apps, this would be bundled locally.
-const DatastarScript = `<script type="module" src="https://cdn.jsdelivr.net/gh/starfederation/datastar/bundles/datastar.js"></script>`
-
-// BaseHTML provides a minimal HTML template with Datastar and Tailwind.
-const BaseHTML = `<!DOCTYPE html>
+// DefaultBaseHTML is BaseHTML with BundledDatastar set.
+//
+// Deprecated: use BaseHTML, which can set the title and extra head tags.
+const DefaultBaseHTML = `<!DOCTYPE html>
 <html lang="en">
 <head>
     <meta charset="UTF-8">
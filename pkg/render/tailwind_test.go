@@ -0,0 +1,128 @@
+package render
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestScaffoldingDefaults(t *testing.T) {
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"TailwindConfig", TailwindConfig(TailwindOptions{}), DefaultTailwindConfig},
+		{"PackageJSON", PackageJSON(PackageJSONOptions{}), DefaultPackageJSON},
+		{"BaseHTML", BaseHTML(BaseHTMLOptions{BundledDatastar: true}), DefaultBaseHTML},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s:\n got %s\nwant %s", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestTailwindConfigOptions(t *testing.T) {
+	got := TailwindConfig(TailwindOptions{
+		Content: []string{"./templates/**/*.html", "./static/js/*.js"},
+		Colors:  map[string]string{"brand": "#0ea5e9", "accent": "#f43f5e"},
+	})
+	for _, want := range []string{
+		`    "./**/*.templ",
+    "./**/*.go",
+    "./templates/**/*.html",
+    "./static/js/*.js",
+  ],`,
+		`    extend: {
+      colors: {
+        'accent': '#f43f5e',
+        'brand': '#0ea5e9',
+      },
+      // Datastar and general animations`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing:\n%s\nin:\n%s", want, got)
+		}
+	}
+
+	// Values can't break out of their strings
+	got = TailwindConfig(TailwindOptions{Colors: map[string]string{"x": `red', evil: '1`}})
+	if strings.Contains(got, `'red',`) {
+		t.Errorf("color escaped its string:\n%s", got)
+	}
+}
+
+func TestPackageJSONOptions(t *testing.T) {
+	got := PackageJSON(PackageJSONOptions{
+		Name:            `my "app"`,
+		Version:         "0.1.0",
+		Private:         true,
+		InputCSS:        "./static/css/input.css",
+		OutputCSS:       "./static/css/output.css",
+		BuildScript:     "css",
+		WatchScript:     "css:watch",
+		TailwindPackage: "@tailwindcss/cli",
+		TailwindVersion: "^4.0.0",
+	})
+	var pkg struct {
+		Name            string
+		Version         string
+		Private         bool
+		Scripts         map[string]string
+		DevDependencies map[string]string
+	}
+	if err := json.Unmarshal([]byte(got), &pkg); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, got)
+	}
+	if pkg.Name != `my "app"` || pkg.Version != "0.1.0" || !pkg.Private {
+		t.Errorf("got %+v", pkg)
+	}
+	if want := "tailwindcss -i ./static/css/input.css -o ./static/css/output.css --watch"; pkg.Scripts["css:watch"] != want {
+		t.Errorf("css:watch = %q, want %q", pkg.Scripts["css:watch"], want)
+	}
+	if pkg.DevDependencies["@tailwindcss/cli"] != "^4.0.0" || len(pkg.DevDependencies) != 1 {
+		t.Errorf("devDependencies = %v", pkg.DevDependencies)
+	}
+}
+
+func TestBaseHTMLOptions(t *testing.T) {
+	got := BaseHTML(BaseHTMLOptions{
+		Title:           "Todos & more",
+		ExtraHead:       `<meta name="theme-color" content="#0ea5e9">`,
+		BundledDatastar: true,
+		DatastarPath:    "/static/js/datastar.js",
+		Stylesheet:      "/static/css/output.css",
+	})
+	for _, want := range []string{
+		`<title>Todos &amp; more</title>`,
+		`<link rel="stylesheet" href="/static/css/output.css">`,
+		`<script type="module" src="/static/js/datastar.js"></script>
+    <meta name="theme-color" content="#0ea5e9">
+</head>`,
+		`{{.Content}}`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %s in:\n%s", want, got)
+		}
+	}
+
+	// The CDN is used unless Datastar is bundled
+	if got := BaseHTML(BaseHTMLOptions{DatastarPath: "/static/js/datastar.js"}); !strings.Contains(got, `src="`+datastarCDN+`"`) {
+		t.Errorf("expected the CDN script:\n%s", got)
+	}
+
+	// The result is a template; the title can't add actions to it
+	e := New()
+	page := BaseHTML(BaseHTMLOptions{Title: "{{.Secret}}"})
+	if err := e.Parse("base", page); err != nil {
+		t.Fatal(err)
+	}
+	html, err := e.Render("base", map[string]any{"Content": "hi", "Secret": "s3cret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(html, "s3cret") || !strings.Contains(html, "hi") {
+		t.Errorf("got %s", html)
+	}
+}
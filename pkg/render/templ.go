@@ -4,10 +4,34 @@ import (
 	"bytes"
 	"context"
 	"io"
+	"net/http"
+	"sync"
 
 	"github.com/a-h/templ"
 )
 
+// maxPooledBuffer is the largest buffer returned to templBuffers, so one
+// huge render doesn't pin its memory for the life of the process.
+const maxPooledBuffer = 1 << 20
+
+// templBuffers holds render buffers reused across TemplRenderer calls.
+var templBuffers = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+func getBuffer() *bytes.Buffer {
+	return templBuffers.Get().(*bytes.Buffer)
+}
+
+// putBuffer returns buf to the pool unless it has grown too large.
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBuffer {
+		return
+	}
+	buf.Reset()
+	templBuffers.Put(buf)
+}
+
 // TemplRenderer wraps templ components for use with the router.
 type TemplRenderer struct {
 	ctx context.Context
@@ -27,13 +51,44 @@ func (r *TemplRenderer) WithContext(ctx context.Context) *TemplRenderer {
 
 // Render renders a templ component to a string.
 func (r *TemplRenderer) Render(component templ.Component) (string, error) {
-	var buf bytes.Buffer
-	if err := component.Render(r.ctx, &buf); err != nil {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if err := component.Render(r.ctx, buf); err != nil {
 		return "", err
 	}
 	return buf.String(), nil
 }
 
+// RenderBytes renders a templ component to a byte slice owned by the
+// caller. The output is copied out of a pooled buffer, except for renders
+// too large to pool, whose buffer is returned as is.
+func (r *TemplRenderer) RenderBytes(component templ.Component) ([]byte, error) {
+	buf := getBuffer()
+	if err := component.Render(r.ctx, buf); err != nil {
+		putBuffer(buf)
+		return nil, err
+	}
+	if buf.Cap() > maxPooledBuffer {
+		return buf.Bytes(), nil
+	}
+	out := bytes.Clone(buf.Bytes())
+	putBuffer(buf)
+	return out, nil
+}
+
+// RenderWithLayout renders content wrapped in layout, e.g.
+//
+//	html, err := renderer.RenderWithLayout(layouts.Base, pages.Home(todos))
+//
+// A nil layout renders content alone. See router.Router.SetLayout to wrap
+// a router's component handlers.
+func (r *TemplRenderer) RenderWithLayout(layout func(templ.Component) templ.Component, content templ.Component) (string, error) {
+	if layout == nil {
+		return r.Render(content)
+	}
+	return r.Render(layout(content))
+}
+
 // MustRender renders a templ component, panics on error.
 func (r *TemplRenderer) MustRender(component templ.Component) string {
 	html, err := r.Render(component)
@@ -48,11 +103,28 @@ func (r *TemplRenderer) RenderTo(w io.Writer, component templ.Component) error {
 	return component.Render(r.ctx, w)
 }
 
+// RenderToResponse streams a templ component straight into w as HTML,
+// without buffering it. The response is committed once the component
+// starts writing, so an error part way through can't become an error
+// response; router.ComponentHandler buffers for that reason.
+func (r *TemplRenderer) RenderToResponse(w http.ResponseWriter, component templ.Component) error {
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	}
+	return component.Render(r.ctx, w)
+}
+
 // RenderComponent is a convenience function to render a templ component.
 func RenderComponent(component templ.Component) (string, error) {
 	return NewTemplRenderer().Render(component)
 }
 
+// RenderToResponse streams a templ component into w, see
+// TemplRenderer.RenderToResponse.
+func RenderToResponse(w http.ResponseWriter, component templ.Component) error {
+	return NewTemplRenderer().RenderToResponse(w, component)
+}
+
 // MustRenderComponent renders a templ component, panics on error.
 func MustRenderComponent(component templ.Component) string {
 	return NewTemplRenderer().MustRender(component)
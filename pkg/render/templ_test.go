@@ -0,0 +1,150 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/a-h/templ"
+)
+
+func textComponent(s string) templ.Component {
+	return templ.ComponentFunc(func(_ context.Context, w io.Writer) error {
+		_, err := io.WriteString(w, s)
+		return err
+	})
+}
+
+func TestTemplRendererReusesBuffers(t *testing.T) {
+	r := NewTemplRenderer()
+	long, err := r.Render(textComponent(strings.Repeat("x", 4096)))
+	if err != nil || len(long) != 4096 {
+		t.Fatalf("Render = %d bytes, %v", len(long), err)
+	}
+	// A pooled buffer must not leak the previous render
+	if got, _ := r.Render(textComponent("short")); got != "short" {
+		t.Errorf("Render = %q", got)
+	}
+
+	b, err := r.RenderBytes(textComponent("<p>one</p>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.RenderBytes(textComponent("<p>two</p>"))
+	if string(b) != "<p>one</p>" {
+		t.Errorf("RenderBytes result changed by a later render: %q", b)
+	}
+
+	big, err := r.RenderBytes(textComponent(strings.Repeat("y", maxPooledBuffer+1)))
+	if err != nil || len(big) != maxPooledBuffer+1 {
+		t.Errorf("RenderBytes = %d bytes, %v", len(big), err)
+	}
+}
+
+func TestTemplRendererErrors(t *testing.T) {
+	boom := errors.New("boom")
+	failing := templ.ComponentFunc(func(_ context.Context, w io.Writer) error {
+		io.WriteString(w, "partial")
+		return boom
+	})
+	r := NewTemplRenderer()
+	if _, err := r.Render(failing); !errors.Is(err, boom) {
+		t.Errorf("Render error = %v", err)
+	}
+	if b, err := r.RenderBytes(failing); !errors.Is(err, boom) || b != nil {
+		t.Errorf("RenderBytes = %q, %v", b, err)
+	}
+	if got, _ := r.Render(textComponent("ok")); got != "ok" {
+		t.Errorf("Render after error = %q", got)
+	}
+}
+
+func TestTemplRenderWithLayout(t *testing.T) {
+	layout := func(content templ.Component) templ.Component {
+		return templ.ComponentFunc(func(ctx context.Context, w io.Writer) error {
+			io.WriteString(w, "<main>")
+			content.Render(ctx, w)
+			_, err := io.WriteString(w, "</main>")
+			return err
+		})
+	}
+	r := NewTemplRenderer()
+	if got, err := r.RenderWithLayout(layout, textComponent("<p>hi</p>")); err != nil || got != "<main><p>hi</p></main>" {
+		t.Errorf("RenderWithLayout = %q, %v", got, err)
+	}
+	if got, _ := r.RenderWithLayout(nil, textComponent("<p>hi</p>")); got != "<p>hi</p>" {
+		t.Errorf("RenderWithLayout(nil) = %q", got)
+	}
+}
+
+func TestRenderToResponse(t *testing.T) {
+	w := httptest.NewRecorder()
+	if err := RenderToResponse(w, textComponent("<li>item</li>")); err != nil {
+		t.Fatal(err)
+	}
+	if w.Body.String() != "<li>item</li>" {
+		t.Errorf("body = %q", w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	w = httptest.NewRecorder()
+	w.Header().Set("Content-Type", "image/svg+xml")
+	RenderToResponse(w, textComponent("<svg></svg>"))
+	if ct := w.Header().Get("Content-Type"); ct != "image/svg+xml" {
+		t.Errorf("Content-Type overwritten: %q", ct)
+	}
+}
+
+// fragment10KB is a chat-sized component of about 10KB.
+var fragment10KB = textComponent(strings.Repeat(`<li class="msg">hello there</li>`, 320))
+
+// BenchmarkTemplRenderUnpooled is the baseline: a fresh buffer per render.
+func BenchmarkTemplRenderUnpooled(b *testing.B) {
+	ctx := context.Background()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var buf bytes.Buffer
+		if err := fragment10KB.Render(ctx, &buf); err != nil {
+			b.Fatal(err)
+		}
+		_ = buf.String()
+	}
+}
+
+func BenchmarkTemplRender(b *testing.B) {
+	r := NewTemplRenderer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := r.Render(fragment10KB); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkTemplRenderBytes(b *testing.B) {
+	r := NewTemplRenderer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := r.RenderBytes(fragment10KB); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkTemplRenderToResponse(b *testing.B) {
+	r := NewTemplRenderer()
+	w := httptest.NewRecorder()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		w.Body.Reset()
+		if err := r.RenderToResponse(w, fragment10KB); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package render
+
+import "fmt"
+
+// URLResolver builds URLs for named routes. *router.Router implements it.
+type URLResolver interface {
+	URL(name string, params ...any) (string, error)
+}
+
+// SetURLResolver wires the url template function to a router so templates
+// can call {{url "todo.toggle" .ID}}.
+func (e *Engine) SetURLResolver(r URLResolver) {
+	e.AddFunc("url", r.URL)
+}
+
+// unresolvedURL is the default url function, used until SetURLResolver is
+// called.
+func unresolvedURL(name string, params ...any) (string, error) {
+	return "", fmt.Errorf("url %q: no router set; call Engine.SetURLResolver", name)
+}
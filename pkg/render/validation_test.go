@@ -0,0 +1,48 @@
+package render
+
+import (
+	"encoding/json"
+	"html"
+	"html/template"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fieldErrors stands in for router.ValidationErrors.
+type fieldErrors map[string]string
+
+func TestDsValidationSignals(t *testing.T) {
+	tmpl := template.Must(template.New("form").Funcs(DefaultFuncs()).Parse(
+		`<form {{dsValidationSignals .Errors "title" "email"}}></form>`))
+	var b strings.Builder
+	errs := fieldErrors{"email": `email must be a valid "email" address`}
+	if err := tmpl.Execute(&b, map[string]any{"Errors": errs}); err != nil {
+		t.Fatal(err)
+	}
+
+	out := b.String()
+	const prefix, suffix = `<form data-signals="`, `"></form>`
+	if !strings.HasPrefix(out, prefix) || !strings.HasSuffix(out, suffix) {
+		t.Fatalf("output = %s", out)
+	}
+	raw := strings.TrimSuffix(strings.TrimPrefix(out, prefix), suffix)
+	if strings.Contains(raw, `"`) {
+		t.Errorf("unescaped quote in attribute: %s", raw)
+	}
+
+	var signals map[string]map[string]string
+	if err := json.Unmarshal([]byte(html.UnescapeString(raw)), &signals); err != nil {
+		t.Fatalf("signals %s: %v", raw, err)
+	}
+	want := map[string]string{"title": "", "email": errs["email"]}
+	if got := signals[ValidationSignal]; !reflect.DeepEqual(got, want) {
+		t.Errorf("errors signal = %q, want %q", got, want)
+	}
+}
+
+func TestDsValidationSignalsNil(t *testing.T) {
+	if got, want := string(dsValidationSignals(nil)), `data-signals="{&#34;errors&#34;:{}}"`; got != want {
+		t.Errorf("dsValidationSignals(nil) = %s, want %s", got, want)
+	}
+}
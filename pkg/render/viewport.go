@@ -0,0 +1,33 @@
+package render
+
+import (
+	"context"
+	"html/template"
+
+	"github.com/stukennedy/irgo/pkg/viewport"
+)
+
+// ViewportSignals returns attributes for the <body> element that keep the
+// "viewport" Datastar signal in sync with the window size, so
+// router.ViewportMiddleware sees it on every Datastar request.
+func ViewportSignals() template.HTMLAttr {
+	const update = `$viewport.width = window.innerWidth; ` +
+		`$viewport.height = window.innerHeight; ` +
+		`$viewport.dpr = window.devicePixelRatio`
+	return template.HTMLAttr(`data-signals:viewport="{width: window.innerWidth, height: window.innerHeight, dpr: window.devicePixelRatio}" ` +
+		`data-on:resize__window__debounce.250ms="` + update + `"`)
+}
+
+// isCompact reports whether the request's viewport is phone-sized,
+// e.g. {{if isCompact .Ctx}}
+func isCompact(ctx context.Context) bool {
+	v, _ := viewport.FromContext(ctx)
+	return v.IsCompact()
+}
+
+// viewportWidth returns the request's viewport width in CSS pixels,
+// e.g. {{viewportWidth .Ctx}}
+func viewportWidth(ctx context.Context) int {
+	v, _ := viewport.FromContext(ctx)
+	return v.Width
+}
@@ -0,0 +1,46 @@
+package render
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stukennedy/irgo/pkg/viewport"
+)
+
+func TestViewportFuncs(t *testing.T) {
+	e := New()
+	if err := e.Parse("nav", `{{if isCompact .}}<button>Menu</button>{{else}}<nav>Links</nav>{{end}} {{viewportWidth .}}`); err != nil {
+		t.Fatal(err)
+	}
+
+	html, err := e.Render("nav", viewport.WithContext(context.Background(), viewport.Phone))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if html != "<button>Menu</button> 390" {
+		t.Errorf("expected compact layout, got %q", html)
+	}
+
+	html, err = e.Render("nav", context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if html != "<nav>Links</nav> 1280" {
+		t.Errorf("expected desktop layout, got %q", html)
+	}
+}
+
+func TestViewportSignals(t *testing.T) {
+	e := New()
+	if err := e.Parse("body", `<body {{viewportSignals}}></body>`); err != nil {
+		t.Fatal(err)
+	}
+	html, err := e.Render("body", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(html, `data-signals:viewport="{width: window.innerWidth`) || !strings.Contains(html, `data-on:resize__window__debounce.250ms=`) {
+		t.Errorf("expected viewport signal attributes, got %q", html)
+	}
+}
@@ -0,0 +1,186 @@
+package render
+
+import (
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// watchInterval is how often Watch checks for changed files.
+var watchInterval = 250 * time.Millisecond
+
+// Watch replaces the engine's templates with those in dir and reloads
+// them whenever a file is added, changed or removed, for development:
+//
+//	stop, err := engine.Watch("templates", "*.html", "pages/*.html")
+//	defer stop()
+//
+// Patterns are fs.Glob patterns relative to dir; without any, every
+// .html, .tmpl and .gohtml file under dir is loaded, as by SwapTemplates.
+// Templates loaded with Parse or Load* after Watch are kept on reload.
+//
+// Changes are found by polling file sizes and modification times. Each
+// reload parses a fresh set and swaps it in atomically; if parsing fails
+// the previous set stays in place and Render returns the error until the
+// files are fixed. Call stop to end watching.
+func (e *Engine) Watch(dir string, patterns ...string) (stop func(), err error) {
+	fsys := os.DirFS(dir)
+	load := func(e *Engine, t *template.Template) (*template.Template, error) {
+		files, err := watchedFiles(fsys, patterns)
+		if err != nil {
+			return nil, err
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("%s: %w", dir, ErrNoTemplates)
+		}
+		return e.parseFS(t, fsys, files...)
+	}
+
+	e.mu.Lock()
+	last := fileSnapshot(fsys, patterns)
+	prevBase, prevSources := e.base, e.sources
+	e.base, e.sources = nil, nil
+	if err := e.update(load); err != nil {
+		e.base, e.sources = prevBase, prevSources
+		e.mu.Unlock()
+		return nil, err
+	}
+	e.reloadErr = nil
+	e.mu.Unlock()
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(watchInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			if now := fileSnapshot(fsys, patterns); now != last {
+				last = now
+				e.reload()
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			wg.Wait()
+		})
+	}, nil
+}
+
+// SetDevMode makes every Render re-parse the engine's templates from
+// their sources first, picking up edits without Watch. It is slow and
+// meant only for development. Parse errors are returned by Render.
+func (e *Engine) SetDevMode(on bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.devMode = on
+}
+
+// reload re-parses the templates, keeping the previous set and recording
+// the error if that fails.
+func (e *Engine) reload() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.reloadErr = e.rebuild()
+	if e.reloadErr != nil {
+		logf("template reload failed: %v", e.reloadErr)
+	}
+}
+
+// refresh reloads the templates in dev mode and returns the error from
+// the last failed reload, if any.
+func (e *Engine) refresh() error {
+	e.mu.RLock()
+	dev, err := e.devMode, e.reloadErr
+	e.mu.RUnlock()
+	if !dev {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.sources) > 0 {
+		e.reloadErr = e.rebuild()
+	}
+	return e.reloadErr
+}
+
+// rebuild replays sources into a fresh set and publishes it. Must be
+// called with e.mu held for writing.
+func (e *Engine) rebuild() error {
+	next := template.New("").Funcs(e.funcs)
+	e.pending = make(map[string]string)
+	defer func() { e.pending = nil }()
+	for _, parse := range e.sources {
+		if _, err := parse(e, next); err != nil {
+			return err
+		}
+	}
+	e.base, e.source = next, e.pending
+	e.publish()
+	return nil
+}
+
+// watchedFiles lists the template files in fsys matching patterns, or
+// every template file if there are none.
+func watchedFiles(fsys fs.FS, patterns []string) ([]string, error) {
+	if len(patterns) == 0 {
+		var files []string
+		err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+			if err == nil && !d.IsDir() && templateExts[path.Ext(p)] {
+				files = append(files, p)
+			}
+			return err
+		})
+		return files, err
+	}
+
+	seen := make(map[string]bool)
+	var files []string
+	for _, pattern := range patterns {
+		matches, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range matches {
+			if !seen[m] {
+				seen[m] = true
+				files = append(files, m)
+			}
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// fileSnapshot summarises the watched files' names, sizes and
+// modification times.
+func fileSnapshot(fsys fs.FS, patterns []string) string {
+	files, err := watchedFiles(fsys, patterns)
+	if err != nil {
+		return err.Error()
+	}
+	var b strings.Builder
+	for _, f := range files {
+		if info, err := fs.Stat(fsys, f); err == nil {
+			fmt.Fprintf(&b, "%s %d %d\n", f, info.Size(), info.ModTime().UnixNano())
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,174 @@
+package render
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mtime advances with each write, so changes are seen even on
+// filesystems with coarse modification times.
+var mtime = time.Now()
+
+func writeTemplate(t *testing.T, path, text string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	mtime = mtime.Add(time.Second)
+	os.Chtimes(path, mtime, mtime)
+}
+
+// waitFor polls render until check accepts its result.
+func waitFor(t *testing.T, render func() (string, error), check func(string, error) bool) (string, error) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		html, err := render()
+		if check(html, err) || time.Now().After(deadline) {
+			return html, err
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	watchInterval = 5 * time.Millisecond
+	dir := t.TempDir()
+	file := filepath.Join(dir, "greeting.html")
+	writeTemplate(t, file, `{{define "greeting"}}Hello {{.}}{{end}}`)
+
+	e := New()
+	stop, err := e.Watch(dir, "*.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+	render := func() (string, error) { return e.Render("greeting", "Ada") }
+
+	if html, err := render(); err != nil || html != "Hello Ada" {
+		t.Fatalf("initial render = %q, %v", html, err)
+	}
+
+	writeTemplate(t, file, `{{define "greeting"}}Hi there {{.}}{{end}}`)
+	html, err := waitFor(t, render, func(html string, err error) bool { return html == "Hi there Ada" })
+	if err != nil || html != "Hi there Ada" {
+		t.Fatalf("after edit = %q, %v", html, err)
+	}
+
+	// A broken edit keeps the old set and surfaces the error
+	writeTemplate(t, file, `{{define "greeting"}}Hi {{.}`)
+	_, err = waitFor(t, render, func(_ string, err error) bool { return err != nil })
+	if err == nil || !strings.Contains(err.Error(), "greeting.html") {
+		t.Fatalf("after broken edit: err = %v, want parse error", err)
+	}
+	if !e.HasTemplate("greeting") {
+		t.Error("previous templates dropped after a failed reload")
+	}
+
+	// Fixing the file clears the error; new files are picked up
+	writeTemplate(t, file, `{{define "greeting"}}Bonjour {{.}}{{end}}`)
+	writeTemplate(t, filepath.Join(dir, "farewell.html"), `{{define "farewell"}}Bye{{end}}`)
+	html, err = waitFor(t, render, func(html string, err error) bool { return err == nil && html == "Bonjour Ada" })
+	if err != nil || html != "Bonjour Ada" {
+		t.Fatalf("after fix = %q, %v", html, err)
+	}
+	if _, err := waitFor(t, func() (string, error) { return e.Render("farewell", nil) },
+		func(_ string, err error) bool { return err == nil }); err != nil {
+		t.Errorf("new file not loaded: %v", err)
+	}
+
+	// No reloads after stop
+	stop()
+	writeTemplate(t, file, `{{define "greeting"}}Stopped{{end}}`)
+	time.Sleep(50 * time.Millisecond)
+	if html, _ := render(); html != "Bonjour Ada" {
+		t.Errorf("reloaded after stop: %q", html)
+	}
+}
+
+func TestWatchInitialError(t *testing.T) {
+	dir := t.TempDir()
+	e := New()
+	if _, err := e.Watch(dir); err == nil {
+		t.Error("expected error watching a directory without templates")
+	}
+
+	writeTemplate(t, filepath.Join(dir, "bad.html"), `{{if}}`)
+	if _, err := e.Watch(dir); err == nil {
+		t.Error("expected parse error")
+	}
+}
+
+func TestDevMode(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "greeting.html")
+	writeTemplate(t, file, `{{define "greeting"}}Hello{{end}}`)
+
+	e := New()
+	if err := e.LoadGlob(filepath.Join(dir, "*.html")); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Parse("inline", `inline {{template "greeting"}}`); err != nil {
+		t.Fatal(err)
+	}
+
+	writeTemplate(t, file, `{{define "greeting"}}Hi{{end}}`)
+	if html, _ := e.Render("inline", nil); html != "inline Hello" {
+		t.Errorf("without dev mode = %q, want the parsed set", html)
+	}
+
+	e.SetDevMode(true)
+	if html, err := e.Render("inline", nil); err != nil || html != "inline Hi" {
+		t.Errorf("dev mode = %q, %v", html, err)
+	}
+
+	writeTemplate(t, file, `{{define "greeting"}}{{end`)
+	if _, err := e.Render("inline", nil); err == nil {
+		t.Error("dev mode: expected parse error from Render")
+	}
+
+	writeTemplate(t, file, `{{define "greeting"}}Hey{{end}}`)
+	if html, err := e.Render("inline", nil); err != nil || html != "inline Hey" {
+		t.Errorf("dev mode after fix = %q, %v", html, err)
+	}
+}
+
+func TestDevModeClone(t *testing.T) {
+	e := New()
+	e.SetDebug(true)
+	if err := e.Parse("pages/profile", profileSource); err != nil {
+		t.Fatal(err)
+	}
+	e.SetDevMode(true)
+	clone, err := e.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each rebuild records sources on its own engine, under its own lock
+	var wg sync.WaitGroup
+	for _, eng := range []*Engine{e, clone, e, clone} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				eng.Render("pages/profile", profile{User: &struct{ Name string }{"Ada"}})
+			}
+		}()
+	}
+	wg.Wait()
+
+	_, err = clone.Render("pages/profile", profile{})
+	var te *TemplateError
+	if !errors.As(err, &te) {
+		t.Fatalf("error = %v, want TemplateError", err)
+	}
+	if te.SourceSnippet() == "" {
+		t.Error("expected a source snippet from the clone after rebuilding")
+	}
+}
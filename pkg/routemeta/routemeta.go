@@ -0,0 +1,120 @@
+// Package routemeta describes documented routes for code generation and
+// conformance testing. Route metadata is attached with router.Route.Describe
+// and exported as JSON for the CLI's client generator.
+package routemeta
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Doc describes a single route.
+type Doc struct {
+	Name     string   `json:"name"`               // Identifier used for generated functions
+	Method   string   `json:"method"`             // HTTP method
+	Pattern  string   `json:"pattern"`            // Route pattern, e.g. "/todos/{id}"
+	Summary  string   `json:"summary,omitempty"`  // One-line description
+	Fields   []Field  `json:"fields,omitempty"`   // Form fields accepted by the route
+	Triggers []string `json:"triggers,omitempty"` // Client events the response dispatches
+}
+
+// Field describes a form field.
+type Field struct {
+	Name     string `json:"name"`
+	Type     string `json:"type,omitempty"` // "string" (default), "int", "bool"
+	Required bool   `json:"required,omitempty"`
+}
+
+var paramRe = regexp.MustCompile(`\{([^}:]+)(?::[^}]*)?\}`)
+
+// PathParams returns the names of the path parameters in the pattern.
+func (d Doc) PathParams() []string {
+	var params []string
+	for _, m := range paramRe.FindAllStringSubmatch(d.Pattern, -1) {
+		params = append(params, m[1])
+	}
+	return params
+}
+
+// Path substitutes params into the pattern, escaping each value.
+func (d Doc) Path(params map[string]string) string {
+	return paramRe.ReplaceAllStringFunc(d.Pattern, func(m string) string {
+		name := paramRe.FindStringSubmatch(m)[1]
+		return url.PathEscape(params[name])
+	})
+}
+
+// Identifier returns the route's name, or one derived from its method and
+// pattern (e.g. "postTodosId") if unnamed.
+func (d Doc) Identifier() string {
+	if d.Name != "" {
+		return d.Name
+	}
+	var b strings.Builder
+	b.WriteString(strings.ToLower(d.Method))
+	upper := true
+	for _, r := range d.Pattern {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Validate checks the doc for internal consistency.
+func (d Doc) Validate() error {
+	if d.Method == "" || d.Pattern == "" {
+		return fmt.Errorf("route %q: method and pattern are required", d.Identifier())
+	}
+	seen := make(map[string]bool)
+	for _, p := range d.PathParams() {
+		seen[p] = true
+	}
+	for _, f := range d.Fields {
+		if f.Name == "" {
+			return fmt.Errorf("route %s %s: field with empty name", d.Method, d.Pattern)
+		}
+		if seen[f.Name] {
+			return fmt.Errorf("route %s %s: field %q duplicates a path param or field", d.Method, d.Pattern, f.Name)
+		}
+		seen[f.Name] = true
+		switch f.Type {
+		case "", "string", "int", "bool":
+		default:
+			return fmt.Errorf("route %s %s: field %q has unknown type %q", d.Method, d.Pattern, f.Name, f.Type)
+		}
+	}
+	return nil
+}
+
+// Write encodes docs as indented JSON.
+func Write(w io.Writer, docs []Doc) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(docs)
+}
+
+// Read decodes docs from JSON and validates them.
+func Read(r io.Reader) ([]Doc, error) {
+	var docs []Doc
+	if err := json.NewDecoder(r).Decode(&docs); err != nil {
+		return nil, err
+	}
+	for _, d := range docs {
+		if err := d.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return docs, nil
+}
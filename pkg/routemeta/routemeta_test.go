@@ -0,0 +1,66 @@
+package routemeta
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestPathParams(t *testing.T) {
+	d := Doc{Method: "GET", Pattern: "/users/{userID}/posts/{id:[0-9]+}"}
+	if got := d.PathParams(); !reflect.DeepEqual(got, []string{"userID", "id"}) {
+		t.Errorf("unexpected params: %v", got)
+	}
+	if got := d.Path(map[string]string{"userID": "a b", "id": "7"}); got != "/users/a%20b/posts/7" {
+		t.Errorf("unexpected path: %q", got)
+	}
+}
+
+func TestIdentifier(t *testing.T) {
+	if got := (Doc{Method: "PATCH", Pattern: "/todos/{id}"}).Identifier(); got != "patchTodosId" {
+		t.Errorf("expected patchTodosId, got %q", got)
+	}
+	if got := (Doc{Name: "toggle", Method: "PATCH", Pattern: "/todos/{id}"}).Identifier(); got != "toggle" {
+		t.Errorf("expected toggle, got %q", got)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  Doc
+		ok   bool
+	}{
+		{"valid", Doc{Method: "POST", Pattern: "/todos", Fields: []Field{{Name: "title", Type: "string"}}}, true},
+		{"missing method", Doc{Pattern: "/todos"}, false},
+		{"unknown type", Doc{Method: "POST", Pattern: "/todos", Fields: []Field{{Name: "due", Type: "date"}}}, false},
+		{"field shadows param", Doc{Method: "POST", Pattern: "/todos/{id}", Fields: []Field{{Name: "id"}}}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.doc.Validate(); (err == nil) != tt.ok {
+			t.Errorf("%s: unexpected result %v", tt.name, err)
+		}
+	}
+}
+
+func TestWriteRead(t *testing.T) {
+	docs := []Doc{{
+		Name:     "createTodo",
+		Method:   "POST",
+		Pattern:  "/todos",
+		Fields:   []Field{{Name: "title", Required: true}},
+		Triggers: []string{"todo-created"},
+	}}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, docs); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, docs) {
+		t.Errorf("round trip mismatch: %+v", got)
+	}
+}
@@ -0,0 +1,132 @@
+package router
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// DefaultLoginPath is where HTMX requests failing BasicAuth or BearerAuth
+// are redirected.
+const DefaultLoginPath = "/login"
+
+// AuthOption configures BasicAuth and BearerAuth.
+type AuthOption func(*authConfig)
+
+type authConfig struct {
+	loginPath string
+}
+
+// AuthLoginPath sets where HTMX requests are sent with HX-Redirect when
+// unauthorized, instead of DefaultLoginPath. An empty path disables the
+// redirect.
+func AuthLoginPath(path string) AuthOption {
+	return func(c *authConfig) {
+		c.loginPath = path
+	}
+}
+
+type principalKey struct{}
+
+// BasicAuth returns middleware requiring HTTP Basic credentials accepted
+// by validate. The username becomes the request's principal (see
+// Context.Principal). Compare secrets with SecureCompare, or use
+// BasicAuthUsers:
+//
+//	r.Use(router.BasicAuth("dev", router.BasicAuthUsers(map[string]string{"admin": pass})))
+//
+// Browsers get a 401 challenge and their login dialog. HTMX and Datastar
+// requests get a 401 without the challenge, which would pop the dialog
+// mid-page; HTMX requests are also redirected to the login path.
+func BasicAuth(realm string, validate func(user, pass string) bool, opts ...AuthOption) func(http.Handler) http.Handler {
+	challenge := `Basic realm=` + strconv.Quote(realm) + `, charset="UTF-8"`
+	return authMiddleware(challenge, opts, func(r *http.Request) (any, bool) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || !validate(user, pass) {
+			return nil, false
+		}
+		return user, true
+	})
+}
+
+// BearerAuth returns middleware requiring an "Authorization: Bearer"
+// token accepted by validate, which returns the principal the token
+// belongs to (see Context.Principal). Unauthorized requests are handled
+// as by BasicAuth.
+func BearerAuth(validate func(token string) (any, bool), opts ...AuthOption) func(http.Handler) http.Handler {
+	return authMiddleware("Bearer", opts, func(r *http.Request) (any, bool) {
+		scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+			return nil, false
+		}
+		return validate(strings.TrimSpace(token))
+	})
+}
+
+func authMiddleware(challenge string, opts []AuthOption, authenticate func(*http.Request) (any, bool)) func(http.Handler) http.Handler {
+	cfg := authConfig{loginPath: DefaultLoginPath}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := authenticate(r)
+			if !ok {
+				unauthorized(w, r, challenge, cfg.loginPath)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, principal)))
+		})
+	}
+}
+
+// unauthorized writes a 401 error fragment. Only full page requests get
+// the WWW-Authenticate challenge.
+func unauthorized(w http.ResponseWriter, r *http.Request, challenge, loginPath string) {
+	htmx := r.Header.Get("HX-Request") == "true"
+	switch {
+	case htmx && loginPath != "":
+		w.Header().Set("HX-Redirect", loginPath)
+	case !htmx && !IsDatastarRequest(r):
+		w.Header().Set("WWW-Authenticate", challenge)
+	}
+	NewContext(w, r).ErrorStatus(http.StatusUnauthorized, "Unauthorized")
+}
+
+// GetPrincipal returns the principal set by BasicAuth or BearerAuth, or
+// nil if the request wasn't authenticated by them.
+func GetPrincipal(r *http.Request) any {
+	return r.Context().Value(principalKey{})
+}
+
+// Principal returns the authenticated principal: the username for
+// BasicAuth, or the value returned by BearerAuth's validate. It is nil
+// on routes without either middleware.
+func (c *Context) Principal() any {
+	return GetPrincipal(c.Request)
+}
+
+// SecureCompare reports whether given equals want in constant time,
+// revealing neither where they differ nor want's length.
+func SecureCompare(given, want string) bool {
+	g := sha256.Sum256([]byte(given))
+	w := sha256.Sum256([]byte(want))
+	return subtle.ConstantTimeCompare(g[:], w[:]) == 1
+}
+
+// BasicAuthUsers returns a BasicAuth validator accepting the given
+// username → password pairs, compared in constant time.
+func BasicAuthUsers(users map[string]string) func(user, pass string) bool {
+	return func(user, pass string) bool {
+		want, ok := users[user]
+		if !ok {
+			// Same work for unknown users
+			SecureCompare(pass, pass)
+			return false
+		}
+		return SecureCompare(pass, want)
+	}
+}
@@ -0,0 +1,152 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func authRouter(mw func(http.Handler) http.Handler) *Router {
+	r := New()
+	r.Use(mw)
+	r.GET("/secret", func(ctx *Context) (string, error) {
+		return fmt.Sprintf("hello %v", ctx.Principal()), nil
+	})
+	return r
+}
+
+func TestBasicAuth(t *testing.T) {
+	r := authRouter(BasicAuth("dev", BasicAuthUsers(map[string]string{"admin": "s3cret"})))
+
+	tests := []struct {
+		name       string
+		user, pass string
+		status     int
+	}{
+		{"valid", "admin", "s3cret", http.StatusOK},
+		{"wrong password", "admin", "nope", http.StatusUnauthorized},
+		{"unknown user", "eve", "s3cret", http.StatusUnauthorized},
+		{"no credentials", "", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/secret", nil)
+		if tt.user != "" {
+			req.SetBasicAuth(tt.user, tt.pass)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.status)
+			continue
+		}
+		if tt.status == http.StatusOK {
+			if got := w.Body.String(); got != "hello admin" {
+				t.Errorf("%s: body = %q", tt.name, got)
+			}
+			continue
+		}
+		if got := w.Header().Get("WWW-Authenticate"); got != `Basic realm="dev", charset="UTF-8"` {
+			t.Errorf("%s: WWW-Authenticate = %q", tt.name, got)
+		}
+		if !strings.Contains(w.Body.String(), `class="error"`) {
+			t.Errorf("%s: body = %q, want error fragment", tt.name, w.Body.String())
+		}
+		if w.Header().Get("HX-Redirect") != "" {
+			t.Errorf("%s: HX-Redirect set for a plain request", tt.name)
+		}
+	}
+}
+
+func TestBearerAuth(t *testing.T) {
+	type user struct{ Name string }
+	r := authRouter(BearerAuth(func(token string) (any, bool) {
+		if SecureCompare(token, "tok-1") {
+			return user{"ada"}, true
+		}
+		return nil, false
+	}))
+
+	for header, want := range map[string]int{
+		"Bearer tok-1": http.StatusOK,
+		"bearer tok-1": http.StatusOK,
+		"Bearer tok-2": http.StatusUnauthorized,
+		"Basic tok-1":  http.StatusUnauthorized,
+		"Bearer ":      http.StatusUnauthorized,
+		"":             http.StatusUnauthorized,
+	} {
+		req := httptest.NewRequest("GET", "/secret", nil)
+		req.Header.Set("Authorization", header)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Errorf("%q: status = %d, want %d", header, w.Code, want)
+		}
+		if want == http.StatusOK && w.Body.String() != "hello {ada}" {
+			t.Errorf("%q: body = %q", header, w.Body.String())
+		}
+		if want == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") != "Bearer" {
+			t.Errorf("%q: WWW-Authenticate = %q", header, w.Header().Get("WWW-Authenticate"))
+		}
+	}
+}
+
+func TestAuthHTMXRedirect(t *testing.T) {
+	deny := func(string, string) bool { return false }
+	tests := []struct {
+		name     string
+		opts     []AuthOption
+		header   string
+		redirect string
+	}{
+		{"default login path", nil, "HX-Request", DefaultLoginPath},
+		{"custom login path", []AuthOption{AuthLoginPath("/signin")}, "HX-Request", "/signin"},
+		{"redirect disabled", []AuthOption{AuthLoginPath("")}, "HX-Request", ""},
+		{"datastar", nil, "Datastar", ""},
+	}
+	for _, tt := range tests {
+		r := authRouter(BasicAuth("dev", deny, tt.opts...))
+		req := httptest.NewRequest("GET", "/secret", nil)
+		if tt.header == "HX-Request" {
+			req.Header.Set("HX-Request", "true")
+		} else {
+			req.Header.Set("Accept", "text/event-stream")
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("%s: status = %d, want 401", tt.name, w.Code)
+		}
+		if got := w.Header().Get("HX-Redirect"); got != tt.redirect {
+			t.Errorf("%s: HX-Redirect = %q, want %q", tt.name, got, tt.redirect)
+		}
+		if got := w.Header().Get("WWW-Authenticate"); got != "" {
+			t.Errorf("%s: WWW-Authenticate = %q, want none to avoid the browser dialog", tt.name, got)
+		}
+	}
+}
+
+func TestPrincipalUnauthenticated(t *testing.T) {
+	ctx := NewContext(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if p := ctx.Principal(); p != nil {
+		t.Errorf("Principal() = %v, want nil", p)
+	}
+}
+
+func TestSecureCompare(t *testing.T) {
+	for _, tt := range []struct {
+		a, b string
+		want bool
+	}{
+		{"abc", "abc", true},
+		{"abc", "abd", false},
+		{"abc", "abcd", false},
+		{"", "", true},
+	} {
+		if got := SecureCompare(tt.a, tt.b); got != tt.want {
+			t.Errorf("SecureCompare(%q, %q) = %v", tt.a, tt.b, got)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package router
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// errUnsupportedField reports a struct field the binders can't set; it
+// is a programming error rather than a bad request.
+var errUnsupportedField = errors.New("router: unsupported bind field type")
+
+// BindQuery sets the fields of the struct v points to from query
+// parameters named by their `query` tags:
+//
+//	type ListParams struct {
+//	    Page  int        `query:"page"`
+//	    Tags  []string   `query:"tag"`   // ?tag=a&tag=b
+//	    Since *time.Time `query:"since"` // nil if absent
+//	}
+//
+// Fields of embedded structs are bound too. Supported types are strings,
+// bools (as QueryBool), ints, uints, floats, time.Time (RFC 3339 or
+// 2006-01-02), encoding.TextUnmarshaler, and slices of or pointers to
+// them. Absent or empty parameters leave fields unchanged, so preset
+// defaults survive, and parameters with no matching field are ignored.
+// An invalid value is a 400 HTTPError.
+func (c *Context) BindQuery(v any) error {
+	q := c.Request.URL.Query()
+	return bindValues(v, "query", "query parameter", func(name string) []string {
+		return q[name]
+	})
+}
+
+// BindPath sets fields from URL path parameters named by their `path`
+// tags, e.g. `path:"id"` for a route "/todos/{id}", as BindQuery does.
+func (c *Context) BindPath(v any) error {
+	return bindValues(v, "path", "path parameter", func(name string) []string {
+		rctx := chi.RouteContext(c.Request.Context())
+		if rctx == nil {
+			return nil
+		}
+		for i, key := range rctx.URLParams.Keys {
+			if key != name {
+				continue
+			}
+			value := rctx.URLParams.Values[i]
+			if c.Request.URL.RawPath != "" {
+				if u, err := url.PathUnescape(value); err == nil {
+					value = u
+				}
+			}
+			return []string{value}
+		}
+		return nil
+	})
+}
+
+// BindAll binds path parameters, then query parameters, then the JSON
+// body if there is one, so later sources win.
+func (c *Context) BindAll(v any) error {
+	if err := c.BindPath(v); err != nil {
+		return err
+	}
+	if err := c.BindQuery(v); err != nil {
+		return err
+	}
+	if c.Request.Body == nil || c.Request.ContentLength == 0 {
+		return nil
+	}
+	if err := c.Bind(v); err != nil && !errors.Is(err, io.EOF) {
+		return ErrBadRequest("Invalid request body").WithInternal(err)
+	}
+	return nil
+}
+
+// bindValues sets the fields of the struct v points to that have the
+// given tag, from lookup. source names the values in errors. Shared by
+// the binders so conversions behave the same for every source.
+func bindValues(v any, tag, source string, lookup func(name string) []string) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("router: bind target must be a non-nil struct pointer, got %T", v)
+	}
+	_, err := bindStruct(rv.Elem(), tag, source, lookup)
+	return err
+}
+
+// bindStruct binds sv's fields, reporting whether any was set.
+func bindStruct(sv reflect.Value, tag, source string, lookup func(string) []string) (bool, error) {
+	st := sv.Type()
+	set := false
+	for i := 0; i < st.NumField(); i++ {
+		f := st.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		fv := sv.Field(i)
+		name, tagged := f.Tag.Lookup(tag)
+
+		if f.Anonymous && !tagged {
+			ok, err := bindEmbedded(fv, tag, source, lookup)
+			if err != nil {
+				return set, err
+			}
+			set = set || ok
+			continue
+		}
+		if !tagged || name == "-" {
+			continue
+		}
+
+		values := lookup(name)
+		if allEmpty(values) {
+			continue
+		}
+		if err := setField(fv, values); err != nil {
+			if errors.Is(err, errUnsupportedField) {
+				return set, fmt.Errorf("%w: %s.%s is %s", errUnsupportedField, st.Name(), f.Name, f.Type)
+			}
+			return set, ErrBadRequest("Invalid " + source + ": " + name).WithInternal(err)
+		}
+		set = true
+	}
+	return set, nil
+}
+
+// bindEmbedded binds an embedded struct or struct pointer, allocating the
+// pointer only if a field is set.
+func bindEmbedded(fv reflect.Value, tag, source string, lookup func(string) []string) (bool, error) {
+	switch {
+	case fv.Kind() == reflect.Struct:
+		return bindStruct(fv, tag, source, lookup)
+	case fv.Kind() == reflect.Pointer && fv.Type().Elem().Kind() == reflect.Struct:
+		target := fv
+		if fv.IsNil() {
+			target = reflect.New(fv.Type().Elem())
+		}
+		ok, err := bindStruct(target.Elem(), tag, source, lookup)
+		if ok && fv.IsNil() {
+			fv.Set(target)
+		}
+		return ok, err
+	}
+	return false, nil
+}
+
+func allEmpty(values []string) bool {
+	for _, v := range values {
+		if v != "" {
+			return false
+		}
+	}
+	return true
+}
+
+var (
+	timeType            = reflect.TypeFor[time.Time]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// setField converts values into fv. Only slices use more than the first
+// value.
+func setField(fv reflect.Value, values []string) error {
+	switch {
+	case fv.Kind() == reflect.Pointer:
+		elem := reflect.New(fv.Type().Elem())
+		if err := setField(elem.Elem(), values); err != nil {
+			return err
+		}
+		fv.Set(elem)
+		return nil
+	case fv.Type() == timeType:
+		t, err := parseTime(values[0])
+		if err != nil {
+			return err
+		}
+		fv.Set(reflect.ValueOf(t))
+		return nil
+	case reflect.PointerTo(fv.Type()).Implements(textUnmarshalerType):
+		return fv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(values[0]))
+	case fv.Kind() == reflect.Slice:
+		slice := reflect.MakeSlice(fv.Type(), len(values), len(values))
+		for i, v := range values {
+			if err := setField(slice.Index(i), []string{v}); err != nil {
+				return err
+			}
+		}
+		fv.Set(slice)
+		return nil
+	}
+	return setScalar(fv, values[0])
+}
+
+func setScalar(fv reflect.Value, s string) error {
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(s)
+	case reflect.Bool:
+		b, err := parseBool(s)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(n)
+	default:
+		return errUnsupportedField
+	}
+	return nil
+}
+
+// parseTime accepts RFC 3339 timestamps and plain dates.
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, s)
+}
@@ -0,0 +1,181 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type Paging struct {
+	Page  int `query:"page"`
+	Limit int `query:"limit"`
+}
+
+type Filter struct {
+	Status string `query:"status"`
+}
+
+type listParams struct {
+	Paging
+	*Filter
+	Tags   []string   `query:"tag"`
+	Since  *time.Time `query:"since"`
+	Done   *bool      `query:"done"`
+	Score  float32    `query:"score"`
+	Level  uint8      `query:"level"`
+	Ignore string     `query:"-"`
+	Plain  string
+	hidden string `query:"hidden"`
+}
+
+func TestBindQuery(t *testing.T) {
+	ctx := queryContext("page=3&tag=a&tag=b&since=2024-05-01&done=on&score=2.5&level=7&Plain=x&hidden=y&unknown=z&limit=")
+	p := listParams{Paging: Paging{Limit: 20}}
+	if err := ctx.BindQuery(&p); err != nil {
+		t.Fatalf("BindQuery: %v", err)
+	}
+
+	if p.Page != 3 || p.Limit != 20 {
+		t.Errorf("Paging = %+v, want page 3 and default limit 20", p.Paging)
+	}
+	if p.Filter != nil {
+		t.Errorf("Filter = %+v, want nil with no status", p.Filter)
+	}
+	if !reflect.DeepEqual(p.Tags, []string{"a", "b"}) {
+		t.Errorf("Tags = %q", p.Tags)
+	}
+	if p.Since == nil || !p.Since.Equal(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Since = %v", p.Since)
+	}
+	if p.Done == nil || !*p.Done {
+		t.Errorf("Done = %v, want true", p.Done)
+	}
+	if p.Score != 2.5 || p.Level != 7 {
+		t.Errorf("Score, Level = %v, %v", p.Score, p.Level)
+	}
+	if p.Ignore != "" || p.Plain != "" || p.hidden != "" {
+		t.Errorf("bound untagged or unexported fields: %+v", p)
+	}
+}
+
+func TestBindQueryOptional(t *testing.T) {
+	var p listParams
+	if err := queryContext("status=open").BindQuery(&p); err != nil {
+		t.Fatalf("BindQuery: %v", err)
+	}
+	if p.Filter == nil || p.Status != "open" {
+		t.Errorf("Filter = %+v, want allocated with status open", p.Filter)
+	}
+	if p.Since != nil || p.Done != nil {
+		t.Errorf("Since, Done = %v, %v, want nil when absent", p.Since, p.Done)
+	}
+}
+
+func TestBindQueryInvalid(t *testing.T) {
+	tests := []struct {
+		query string
+		param string
+	}{
+		{"page=two", "page"},
+		{"level=300", "level"},
+		{"since=yesterday", "since"},
+		{"done=maybe", "done"},
+		{"tag=a&score=x", "score"},
+	}
+	for _, tt := range tests {
+		var p listParams
+		err := queryContext(tt.query).BindQuery(&p)
+		var httpErr *HTTPError
+		if !errors.As(err, &httpErr) || httpErr.Status != http.StatusBadRequest {
+			t.Errorf("%s: err = %v, want 400", tt.query, err)
+			continue
+		}
+		if want := "Invalid query parameter: " + tt.param; httpErr.Message != want {
+			t.Errorf("%s: message = %q, want %q", tt.query, httpErr.Message, want)
+		}
+	}
+}
+
+func TestBindTarget(t *testing.T) {
+	ctx := queryContext("page=1")
+	var p Paging
+	for _, v := range []any{p, nil, (*Paging)(nil), new(int)} {
+		if err := ctx.BindQuery(v); err == nil {
+			t.Errorf("BindQuery(%T) succeeded", v)
+		}
+	}
+
+	var bad struct {
+		Ch chan int `query:"page"`
+	}
+	err := ctx.BindQuery(&bad)
+	var httpErr *HTTPError
+	if !errors.Is(err, errUnsupportedField) || errors.As(err, &httpErr) {
+		t.Errorf("unsupported field: err = %v, want errUnsupportedField", err)
+	}
+}
+
+type todoParams struct {
+	ID    int64  `path:"id" json:"id"`
+	Slug  string `path:"slug"`
+	Page  int    `query:"page" json:"page"`
+	Title string `query:"title" json:"title"`
+}
+
+func TestBindPath(t *testing.T) {
+	r := New()
+	r.GET("/todos/{id}/{slug}", func(ctx *Context) (string, error) {
+		var p todoParams
+		if err := ctx.BindPath(&p); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d %s %d", p.ID, p.Slug, p.Page), nil
+	})
+
+	if w := serve(r, "GET", "/todos/42/a%2Fb?page=2"); w.Body.String() != "42 a/b 0" {
+		t.Errorf("body = %q", w.Body.String())
+	}
+	w := serve(r, "GET", "/todos/x/y")
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "Invalid path parameter: id") {
+		t.Errorf("bad id: %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestBindAll(t *testing.T) {
+	r := New()
+	r.POST("/todos/{id}", func(ctx *Context) (string, error) {
+		var p todoParams
+		if err := ctx.BindAll(&p); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d %d %s", p.ID, p.Page, p.Title), nil
+	})
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"no body", "", "7 2 query"},
+		{"body wins", `{"id": 9, "title": "body"}`, "9 2 body"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/todos/7?page=2&title=query", strings.NewReader(tt.body))
+		r.ServeHTTP(w, req)
+		if w.Body.String() != tt.want {
+			t.Errorf("%s: body = %q, want %q", tt.name, w.Body.String(), tt.want)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/todos/7", strings.NewReader("{")))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("malformed body: status = %d, want 400", w.Code)
+	}
+}
@@ -0,0 +1,160 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CacheConfig describes the caching headers for a response.
+type CacheConfig struct {
+	MaxAge         time.Duration // max-age directive and Expires header
+	Private        bool          // Response is specific to one user
+	Immutable      bool          // Response never changes while fresh
+	NoCache        bool          // Client must revalidate before reuse
+	NoStore        bool          // Response must not be stored at all
+	MustRevalidate bool          // Stale responses must not be used
+}
+
+// defaultCacheProfiles returns the built-in cache profiles.
+func defaultCacheProfiles() map[string]CacheConfig {
+	return map[string]CacheConfig{
+		"static":        {MaxAge: 365 * 24 * time.Hour, Immutable: true},
+		"private-short": {MaxAge: time.Minute, Private: true, MustRevalidate: true},
+		"no-store":      {NoStore: true},
+	}
+}
+
+// CacheControl returns the Cache-Control header value for the config.
+func (c CacheConfig) CacheControl() string {
+	if c.NoStore {
+		return "no-store"
+	}
+
+	var parts []string
+	if c.Private {
+		parts = append(parts, "private")
+	} else {
+		parts = append(parts, "public")
+	}
+	if c.NoCache {
+		parts = append(parts, "no-cache")
+	}
+	parts = append(parts, "max-age="+strconv.Itoa(int(c.MaxAge/time.Second)))
+	if c.Immutable {
+		parts = append(parts, "immutable")
+	}
+	if c.MustRevalidate {
+		parts = append(parts, "must-revalidate")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Apply writes the caching headers to h, replacing any set earlier
+// (for example by NoCacheMiddleware).
+func (c CacheConfig) Apply(h http.Header) {
+	h.Set("Cache-Control", c.CacheControl())
+	if c.NoStore {
+		h.Set("Pragma", "no-cache")
+		h.Set("Expires", "0")
+		return
+	}
+	h.Del("Pragma")
+	if c.NoCache || c.MaxAge <= 0 {
+		h.Set("Expires", "0")
+	} else {
+		h.Set("Expires", time.Now().Add(c.MaxAge).UTC().Format(http.TimeFormat))
+	}
+}
+
+// CacheProfile registers a named cache profile, replacing any existing
+// profile with the same name. Built-in profiles are "static",
+// "private-short" and "no-store".
+func (r *Router) CacheProfile(name string, cfg CacheConfig) {
+	r.config.cacheProfiles[name] = cfg
+}
+
+// HasCacheProfile reports whether a cache profile is registered under
+// name.
+func (r *Router) HasCacheProfile(name string) bool {
+	_, ok := r.config.cacheProfiles[name]
+	return ok
+}
+
+// WithCache returns a router whose routes use the named cache profile.
+// A profile set on an individual Route takes precedence.
+// Panics if the profile has not been registered with Router.CacheProfile
+// (see HasCacheProfile).
+func (r *Router) WithCache(profile string) *Router {
+	return r.With(CacheMiddleware(r.cacheProfile(profile)))
+}
+
+func (r *Router) cacheProfile(name string) CacheConfig {
+	cfg, ok := r.config.cacheProfiles[name]
+	if !ok {
+		panic(fmt.Sprintf("router: unknown cache profile %q", name))
+	}
+	return cfg
+}
+
+// CacheMiddleware sets caching headers from cfg on successful (2xx and
+// 304) responses. Other responses get no-store instead, so caches don't
+// keep an error for the profile's lifetime.
+func CacheMiddleware(cfg CacheConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(cfg.writer(w), r)
+		})
+	}
+}
+
+// writer applies c's headers to w up front, so handlers can still
+// override them, and returns a writer that swaps them for no-store if the
+// response turns out not to be successful.
+func (c CacheConfig) writer(w http.ResponseWriter) http.ResponseWriter {
+	c.Apply(w.Header())
+	return &cacheWriter{ResponseWriter: w, cacheControl: c.CacheControl()}
+}
+
+// cacheWriter drops a cache profile's headers from error responses,
+// unless the handler replaced them with its own.
+type cacheWriter struct {
+	http.ResponseWriter
+	cacheControl string // Cache-Control set by the profile
+	wroteHeader  bool
+}
+
+func (w *cacheWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		ok := code >= 200 && code < 300 || code == http.StatusNotModified
+		if h := w.Header(); !ok && h.Get("Cache-Control") == w.cacheControl {
+			CacheConfig{NoStore: true}.Apply(h)
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *cacheWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher so SSE handlers keep streaming.
+func (w *cacheWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController.
+func (w *cacheWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,161 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCacheProfiles(t *testing.T) {
+	tests := []struct {
+		profile      string
+		cacheControl string
+		pragma       string
+	}{
+		{"static", "public, max-age=31536000, immutable", ""},
+		{"private-short", "private, max-age=60, must-revalidate", ""},
+		{"no-store", "no-store", "no-cache"},
+	}
+
+	for _, tt := range tests {
+		r := New()
+		r.GET("/page", func(ctx *Context) (string, error) {
+			return "<div>ok</div>", nil
+		}).Cache(tt.profile)
+
+		req := httptest.NewRequest("GET", "/page", nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if cc := w.Header().Get("Cache-Control"); cc != tt.cacheControl {
+			t.Errorf("%s: expected Cache-Control %q, got %q", tt.profile, tt.cacheControl, cc)
+		}
+		if p := w.Header().Get("Pragma"); p != tt.pragma {
+			t.Errorf("%s: expected Pragma %q, got %q", tt.profile, tt.pragma, p)
+		}
+		if w.Header().Get("Expires") == "" {
+			t.Errorf("%s: expected Expires header", tt.profile)
+		}
+	}
+}
+
+func TestCustomCacheProfile(t *testing.T) {
+	r := New()
+	r.CacheProfile("feed", CacheConfig{MaxAge: 5 * time.Minute, NoCache: true})
+	r.GET("/feed", func(ctx *Context) (string, error) {
+		return "", nil
+	}).Cache("feed")
+
+	req := httptest.NewRequest("GET", "/feed", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if cc := w.Header().Get("Cache-Control"); cc != "public, no-cache, max-age=300" {
+		t.Errorf("expected custom Cache-Control, got %q", cc)
+	}
+	if exp := w.Header().Get("Expires"); exp != "0" {
+		t.Errorf("expected Expires 0 for no-cache, got %q", exp)
+	}
+}
+
+func TestCacheProfilePrecedence(t *testing.T) {
+	r := New()
+	r.Use(NoCacheMiddleware)
+
+	r.GET("/default", func(ctx *Context) (string, error) {
+		return "", nil
+	})
+	r.GET("/explicit", func(ctx *Context) (string, error) {
+		return "", nil
+	}).Cache("static")
+
+	r.Route("/group", func(r *Router) {
+		g := r.WithCache("private-short")
+		g.GET("/inherited", func(ctx *Context) (string, error) {
+			return "", nil
+		})
+		g.GET("/override", func(ctx *Context) (string, error) {
+			return "", nil
+		}).Cache("no-store")
+	})
+
+	tests := []struct {
+		path         string
+		cacheControl string
+	}{
+		{"/default", "no-cache, no-store, must-revalidate"},
+		{"/explicit", "public, max-age=31536000, immutable"},
+		{"/group/inherited", "private, max-age=60, must-revalidate"},
+		{"/group/override", "no-store"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: expected status 200, got %d", tt.path, w.Code)
+		}
+		if cc := w.Header().Get("Cache-Control"); cc != tt.cacheControl {
+			t.Errorf("%s: expected Cache-Control %q, got %q", tt.path, tt.cacheControl, cc)
+		}
+	}
+}
+
+func TestCacheProfileSkipsErrors(t *testing.T) {
+	r := New()
+	r.GET("/missing", func(ctx *Context) (string, error) {
+		return "", ErrNotFound("nope")
+	}).Cache("static")
+	r.Route("/group", func(r *Router) {
+		g := r.WithCache("static")
+		g.GET("/missing", func(ctx *Context) (string, error) {
+			return "", ErrNotFound("nope")
+		})
+		g.GET("/ok", func(ctx *Context) (string, error) {
+			return "ok", nil
+		})
+	})
+
+	tests := []struct {
+		path         string
+		status       int
+		cacheControl string
+	}{
+		{"/missing", http.StatusNotFound, "no-store"},
+		{"/group/missing", http.StatusNotFound, "no-store"},
+		{"/group/ok", http.StatusOK, "public, max-age=31536000, immutable"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+		if w.Code != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.path, tt.status, w.Code)
+		}
+		if cc := w.Header().Get("Cache-Control"); cc != tt.cacheControl {
+			t.Errorf("%s: expected Cache-Control %q, got %q", tt.path, tt.cacheControl, cc)
+		}
+		if tt.status != http.StatusOK && w.Header().Get("Expires") != "0" {
+			t.Errorf("%s: expected Expires 0, got %q", tt.path, w.Header().Get("Expires"))
+		}
+	}
+
+	if !r.HasCacheProfile("static") || r.HasCacheProfile("missing") {
+		t.Error("HasCacheProfile: expected static registered and missing not")
+	}
+}
+
+func TestUnknownCacheProfilePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for unknown cache profile")
+		}
+	}()
+
+	r := New()
+	r.GET("/x", func(ctx *Context) (string, error) {
+		return "", nil
+	}).Cache("missing")
+}
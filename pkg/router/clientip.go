@@ -0,0 +1,92 @@
+package router
+
+import (
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// WithTrustedProxies lets Context.ClientIP honour X-Forwarded-For and
+// X-Real-IP from peers in the given CIDRs (or single addresses), e.g.
+//
+//	router.New(router.WithTrustedProxies("10.0.0.0/8", "::1"))
+//
+// Panics on an invalid CIDR or address.
+func WithTrustedProxies(cidrs ...string) Option {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		p, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(cidr)
+			if addrErr != nil {
+				panic("router: invalid trusted proxy " + cidr)
+			}
+			p = netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen())
+		}
+		prefixes = append(prefixes, p.Masked())
+	}
+	return func(c *routerConfig) {
+		c.proxies = append(c.proxies, prefixes...)
+	}
+}
+
+// ClientIP returns the client's IP address without the port. Forwarding
+// headers are only used when the connecting peer is a trusted proxy (see
+// WithTrustedProxies); X-Forwarded-For is read right to left, skipping
+// trusted hops, so clients can't spoof it by prepending entries.
+func (c *Context) ClientIP() string {
+	peer, ok := parseIP(c.Request.RemoteAddr)
+	if !ok {
+		return c.Request.RemoteAddr
+	}
+	if c.config == nil || !c.trusted(peer) {
+		return peer.String()
+	}
+
+	var hops []string
+	for _, h := range c.Request.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(h, ",")...)
+	}
+	if len(hops) > 0 {
+		client := peer
+		for i := len(hops) - 1; i >= 0; i-- {
+			addr, ok := parseIP(hops[i])
+			if !ok {
+				break
+			}
+			client = addr
+			if !c.trusted(addr) {
+				break
+			}
+		}
+		return client.String()
+	}
+
+	if addr, ok := parseIP(c.Request.Header.Get("X-Real-IP")); ok {
+		return addr.String()
+	}
+	return peer.String()
+}
+
+func (c *Context) trusted(addr netip.Addr) bool {
+	for _, p := range c.config.proxies {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseIP parses an address with an optional port, e.g. "10.0.0.1:443",
+// "[::1]:80" or "2001:db8::1".
+func parseIP(s string) (netip.Addr, bool) {
+	s = strings.TrimSpace(s)
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	addr, err := netip.ParseAddr(strings.Trim(s, "[]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap().WithZone(""), true
+}
@@ -0,0 +1,66 @@
+package router
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name    string
+		proxies []string
+		remote  string
+		xff     []string
+		realIP  string
+		want    string
+	}{
+		{name: "strips port", remote: "203.0.113.5:52314", want: "203.0.113.5"},
+		{name: "ipv6", remote: "[2001:db8::1]:443", want: "2001:db8::1"},
+		{name: "ipv4-mapped", remote: "[::ffff:203.0.113.5]:80", want: "203.0.113.5"},
+		{name: "headers ignored without proxies", remote: "203.0.113.5:1", xff: []string{"198.51.100.7"}, realIP: "198.51.100.8", want: "203.0.113.5"},
+		{name: "untrusted peer", proxies: []string{"10.0.0.0/8"}, remote: "192.0.2.1:1", xff: []string{"198.51.100.7"}, want: "192.0.2.1"},
+		{name: "one hop", proxies: []string{"10.0.0.0/8"}, remote: "10.0.0.2:1", xff: []string{"198.51.100.7"}, want: "198.51.100.7"},
+		{name: "multiple hops", proxies: []string{"10.0.0.0/8"}, remote: "10.0.0.2:1", xff: []string{"198.51.100.7, 10.1.1.1, 10.0.0.5"}, want: "198.51.100.7"},
+		{name: "spoofed prefix", proxies: []string{"10.0.0.0/8"}, remote: "10.0.0.2:1", xff: []string{"6.6.6.6, 198.51.100.7, 10.0.0.5"}, want: "198.51.100.7"},
+		{name: "multiple headers", proxies: []string{"10.0.0.0/8"}, remote: "10.0.0.2:1", xff: []string{"6.6.6.6", "198.51.100.7", "10.0.0.5"}, want: "198.51.100.7"},
+		{name: "all trusted", proxies: []string{"10.0.0.0/8"}, remote: "10.0.0.2:1", xff: []string{"10.9.9.9, 10.0.0.5"}, want: "10.9.9.9"},
+		{name: "invalid hop", proxies: []string{"10.0.0.0/8"}, remote: "10.0.0.2:1", xff: []string{"198.51.100.7, garbage, 10.0.0.5"}, want: "10.0.0.5"},
+		{name: "hop with port", proxies: []string{"10.0.0.0/8"}, remote: "10.0.0.2:1", xff: []string{"198.51.100.7:8080"}, want: "198.51.100.7"},
+		{name: "ipv6 proxy", proxies: []string{"fd00::/8"}, remote: "[fd00::2]:80", xff: []string{"2001:db8::7, fd00::3"}, want: "2001:db8::7"},
+		{name: "single address proxy", proxies: []string{"::1"}, remote: "[::1]:80", xff: []string{"[2001:db8::7]:443"}, want: "2001:db8::7"},
+		{name: "x-real-ip", proxies: []string{"10.0.0.0/8"}, remote: "10.0.0.2:1", realIP: "198.51.100.9", want: "198.51.100.9"},
+		{name: "invalid x-real-ip", proxies: []string{"10.0.0.0/8"}, remote: "10.0.0.2:1", realIP: "nope", want: "10.0.0.2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := New(WithTrustedProxies(tt.proxies...))
+			var got string
+			r.GET("/", func(ctx *Context) (string, error) {
+				got = ctx.ClientIP()
+				return "", nil
+			})
+
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remote
+			for _, h := range tt.xff {
+				req.Header.Add("X-Forwarded-For", h)
+			}
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+			r.ServeHTTP(httptest.NewRecorder(), req)
+			if got != tt.want {
+				t.Errorf("ClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithTrustedProxiesInvalid(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for invalid CIDR")
+		}
+	}()
+	WithTrustedProxies("10.0.0.0/33")
+}
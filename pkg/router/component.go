@@ -0,0 +1,155 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/a-h/templ"
+)
+
+// ComponentHandler is a handler function that returns a templ component.
+// The component is rendered straight to the response without first being
+// converted to a string. Returning a nil component after writing the
+// response (e.g. ctx.Redirect) is a no-op.
+type ComponentHandler func(ctx *Context) (templ.Component, error)
+
+// bufferPool holds render buffers for component handlers. Components are
+// rendered into a pooled buffer rather than the ResponseWriter so a render
+// error can still produce an error response.
+var bufferPool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+// Component registers a handler that returns a templ component.
+func (r *Router) Component(method, pattern string, handler ComponentHandler) *Route {
+	route := r.newRoute(method, pattern)
+	r.handle(method, pattern, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w = route.apply(w)
+		layout, state := r.layout.get(), (*layoutState)(nil)
+		if layout != nil {
+			layout, state, req = r.layoutRequest(w, req, layout)
+		}
+		ctx := r.newContext(w, req)
+		component, err := handler(ctx)
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+		if ctx.Written() {
+			return
+		}
+		if component == nil {
+			ctx.HTML("")
+			return
+		}
+		if layout != nil && !state.skip.Load() {
+			component = layout(component)
+		}
+
+		buf := bufferPool.Get().(*bytes.Buffer)
+		defer func() {
+			buf.Reset()
+			bufferPool.Put(buf)
+		}()
+		if err := component.Render(ctx.Context(), buf); err != nil {
+			ctx.Error(err)
+			return
+		}
+
+		ctx.written = true
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write(buf.Bytes())
+	}))
+	return route
+}
+
+// templLayout holds the layout set with SetLayout. A router without one
+// uses its parent's.
+type templLayout struct {
+	layout atomic.Pointer[func(templ.Component) templ.Component]
+	parent *templLayout
+}
+
+func (l *templLayout) get() func(templ.Component) templ.Component {
+	for ; l != nil; l = l.parent {
+		if fn := l.layout.Load(); fn != nil {
+			return *fn
+		}
+	}
+	return nil
+}
+
+// SetLayout wraps the components returned by the router's component
+// handlers (GETC and friends) in layout when the request is for a full
+// page, such as direct navigation, an hx-boost link or an HTMX history
+// restore. Other HTMX and Datastar requests get the fragment alone:
+//
+//	r.SetLayout(func(content templ.Component) templ.Component {
+//		return layouts.Base("My App", content)
+//	})
+//
+// Unlike LayoutWrapper the response isn't buffered and re-written, and
+// LayoutWrapper won't wrap a page that already has this layout. It applies
+// to routes in groups too, which may set their own layout; nil reverts a
+// group to its parent's. Routes opt out with the SkipLayout middleware or
+// Context.SkipLayout.
+func (r *Router) SetLayout(layout func(content templ.Component) templ.Component) {
+	if layout == nil {
+		r.layout.layout.Store(nil)
+		return
+	}
+	r.layout.layout.Store(&layout)
+}
+
+// layoutRequest decides whether a component handler's response is wrapped
+// in layout, returning nil if not. When it is, the returned request
+// carries the state Context.SkipLayout sets.
+func (r *Router) layoutRequest(w http.ResponseWriter, req *http.Request, layout func(templ.Component) templ.Component) (func(templ.Component) templ.Component, *layoutState, *http.Request) {
+	AddVary(w.Header(), "Accept", "HX-Request", "HX-Boosted", "HX-History-Restore-Request")
+	if IsDatastarRequest(req) && !IsNoJSRequest(req) {
+		return nil, nil, req
+	}
+	if req.Header.Get("HX-Request") == "true" && !IsBoostedRequest(req) && !IsHistoryRestoreRequest(req) {
+		return nil, nil, req
+	}
+
+	outer, _ := req.Context().Value(layoutStateKey{}).(*layoutState)
+	if outer != nil {
+		if outer.skip.Load() {
+			return nil, nil, req
+		}
+		// The page gets this layout or none, never LayoutWrapper's as well
+		outer.skip.Store(true)
+	}
+	state := &layoutState{}
+	return layout, state, req.WithContext(context.WithValue(req.Context(), layoutStateKey{}, state))
+}
+
+// GETC registers a GET handler that returns a templ component.
+func (r *Router) GETC(pattern string, handler ComponentHandler) *Route {
+	return r.Component(http.MethodGet, pattern, handler)
+}
+
+// POSTC registers a POST handler that returns a templ component.
+func (r *Router) POSTC(pattern string, handler ComponentHandler) *Route {
+	return r.Component(http.MethodPost, pattern, handler)
+}
+
+// PUTC registers a PUT handler that returns a templ component.
+func (r *Router) PUTC(pattern string, handler ComponentHandler) *Route {
+	return r.Component(http.MethodPut, pattern, handler)
+}
+
+// PATCHC registers a PATCH handler that returns a templ component.
+func (r *Router) PATCHC(pattern string, handler ComponentHandler) *Route {
+	return r.Component(http.MethodPatch, pattern, handler)
+}
+
+// DELETEC registers a DELETE handler that returns a templ component.
+func (r *Router) DELETEC(pattern string, handler ComponentHandler) *Route {
+	return r.Component(http.MethodDelete, pattern, handler)
+}
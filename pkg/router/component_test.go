@@ -0,0 +1,239 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/a-h/templ"
+	"github.com/stukennedy/irgo/pkg/render"
+	irgotest "github.com/stukennedy/irgo/pkg/testing"
+)
+
+func textComponent(s string) templ.Component {
+	return templ.ComponentFunc(func(_ context.Context, w io.Writer) error {
+		_, err := io.WriteString(w, s)
+		return err
+	})
+}
+
+func TestComponentHandler(t *testing.T) {
+	r := New()
+	r.GETC("/hello/{name}", func(ctx *Context) (templ.Component, error) {
+		return textComponent("<p>Hello " + ctx.Param("name") + "</p>"), nil
+	})
+
+	w := httptest.NewRecorder()
+	r.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/hello/ada", nil))
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+	if w.Body.String() != "<p>Hello ada</p>" {
+		t.Errorf("unexpected body %q", w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" {
+		t.Errorf("unexpected content type %q", ct)
+	}
+}
+
+func TestComponentHandlerErrors(t *testing.T) {
+	r := New()
+	r.GETC("/handler-error", func(ctx *Context) (templ.Component, error) {
+		return nil, ErrNotFound("no such todo")
+	})
+	r.GETC("/render-error", func(ctx *Context) (templ.Component, error) {
+		return templ.ComponentFunc(func(_ context.Context, w io.Writer) error {
+			io.WriteString(w, "<p>partial")
+			return errors.New("boom")
+		}), nil
+	})
+
+	w := httptest.NewRecorder()
+	r.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/handler-error", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/render-error", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500, got %d", w.Code)
+	}
+	if strings.Contains(w.Body.String(), "partial") {
+		t.Errorf("partial render should not be written, got %q", w.Body.String())
+	}
+}
+
+func TestComponentHandlerNilAfterWrite(t *testing.T) {
+	r := New()
+	r.POSTC("/todos", func(ctx *Context) (templ.Component, error) {
+		ctx.Redirect("/todos")
+		return nil, nil
+	})
+
+	w := httptest.NewRecorder()
+	r.Handler().ServeHTTP(w, httptest.NewRequest("POST", "/todos", nil))
+	if w.Code != http.StatusSeeOther {
+		t.Errorf("expected status 303, got %d", w.Code)
+	}
+	if w.Header().Get("Location") != "/todos" {
+		t.Errorf("expected redirect to /todos, got %q", w.Header().Get("Location"))
+	}
+}
+
+// pageLayout wraps content in a named page shell.
+func pageLayout(name string) func(templ.Component) templ.Component {
+	return func(content templ.Component) templ.Component {
+		return templ.ComponentFunc(func(ctx context.Context, w io.Writer) error {
+			io.WriteString(w, "<html data-layout=\""+name+"\"><main>")
+			if err := content.Render(ctx, w); err != nil {
+				return err
+			}
+			_, err := io.WriteString(w, "</main></html>")
+			return err
+		})
+	}
+}
+
+func TestSetLayout(t *testing.T) {
+	r := New()
+	r.SetLayout(pageLayout("base"))
+	r.GETC("/todos", func(ctx *Context) (templ.Component, error) {
+		return textComponent("<ul>todos</ul>"), nil
+	})
+	client := irgotest.NewClient(r)
+
+	page := client.Get("/todos")
+	page.AssertOK(t)
+	page.AssertBodyEquals(t, `<html data-layout="base"><main><ul>todos</ul></main></html>`)
+	page.AssertHTML(t)
+	if vary := page.Header("Vary"); !strings.Contains(vary, "HX-Request") || !strings.Contains(vary, "HX-Boosted") {
+		t.Errorf("Vary = %q", vary)
+	}
+
+	client.HTMX().Get("/todos").AssertBodyEquals(t, "<ul>todos</ul>")
+	client.Datastar().Get("/todos").AssertBodyEquals(t, "<ul>todos</ul>")
+
+	// hx-boost and history restores swap in a whole page
+	client.HTMX().WithHeader("HX-Boosted", "true").Get("/todos").AssertContains(t, `data-layout="base"`)
+	client.HTMX().WithHeader("HX-History-Restore-Request", "true").Get("/todos").AssertContains(t, `data-layout="base"`)
+}
+
+func TestSetLayoutGroups(t *testing.T) {
+	r := New()
+	r.GETC("/", func(ctx *Context) (templ.Component, error) {
+		return textComponent("home"), nil
+	})
+	r.Route("/admin", func(r *Router) {
+		r.SetLayout(pageLayout("admin"))
+		r.GETC("/", func(ctx *Context) (templ.Component, error) {
+			return textComponent("dashboard"), nil
+		})
+		r.Group(func(r *Router) {
+			r.SetLayout(nil) // Falls back to the admin layout
+			r.GETC("/users", func(ctx *Context) (templ.Component, error) {
+				return textComponent("users"), nil
+			})
+		})
+		r.With(SkipLayout).GETC("/raw", func(ctx *Context) (templ.Component, error) {
+			return textComponent("raw"), nil
+		})
+		r.GETC("/print", func(ctx *Context) (templ.Component, error) {
+			ctx.SkipLayout()
+			return textComponent("print"), nil
+		})
+	})
+	// Set after the routes above; they still get it
+	r.SetLayout(pageLayout("base"))
+	client := irgotest.NewClient(r)
+
+	client.Get("/").AssertBodyEquals(t, `<html data-layout="base"><main>home</main></html>`)
+	client.Get("/admin/").AssertBodyEquals(t, `<html data-layout="admin"><main>dashboard</main></html>`)
+	client.Get("/admin/users").AssertBodyEquals(t, `<html data-layout="admin"><main>users</main></html>`)
+	client.Get("/admin/raw").AssertBodyEquals(t, "raw")
+	client.Get("/admin/print").AssertBodyEquals(t, "print")
+}
+
+func TestSetLayoutWithLayoutWrapper(t *testing.T) {
+	r := New()
+	wrapper := &LayoutWrapper{Layout: func(content string) string {
+		return "<wrapper>" + content + "</wrapper>"
+	}}
+	r.Use(wrapper.Wrap)
+	r.GET("/plain", func(ctx *Context) (string, error) {
+		return "plain", nil
+	})
+	r.Group(func(r *Router) {
+		r.SetLayout(pageLayout("templ"))
+		r.GETC("/templ", func(ctx *Context) (templ.Component, error) {
+			return textComponent("templ"), nil
+		})
+		r.GETC("/skipped", func(ctx *Context) (templ.Component, error) {
+			ctx.SkipLayout()
+			return textComponent("skipped"), nil
+		})
+	})
+	client := irgotest.NewClient(r)
+
+	client.Get("/plain").AssertBodyEquals(t, "<wrapper>plain</wrapper>")
+	client.Get("/templ").AssertBodyEquals(t, `<html data-layout="templ"><main>templ</main></html>`)
+	client.Get("/skipped").AssertBodyEquals(t, "skipped")
+	client.HTMX().Get("/templ").AssertBodyEquals(t, "templ")
+}
+
+var largePage = textComponent(strings.Repeat("<li>item</li>", 10000))
+
+func BenchmarkFragmentTemplString(b *testing.B) {
+	r := New()
+	r.GET("/", func(ctx *Context) (string, error) {
+		return render.RenderComponent(largePage)
+	})
+	benchmarkRoute(b, r)
+}
+
+func BenchmarkComponentHandler(b *testing.B) {
+	r := New()
+	r.GETC("/", func(ctx *Context) (templ.Component, error) {
+		return largePage, nil
+	})
+	benchmarkRoute(b, r)
+}
+
+func benchmarkRoute(b *testing.B, r *Router) {
+	h := r.Handler()
+	req := httptest.NewRequest("GET", "/", nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+	}
+}
+
+// The render step alone: a fresh buffer grows to the page size on every
+// render, while Component reuses a pooled one.
+func BenchmarkComponentRenderFreshBuffer(b *testing.B) {
+	ctx := context.Background()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var buf bytes.Buffer
+		largePage.Render(ctx, &buf)
+	}
+}
+
+func BenchmarkComponentRenderPooledBuffer(b *testing.B) {
+	ctx := context.Background()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf := bufferPool.Get().(*bytes.Buffer)
+		largePage.Render(ctx, buf)
+		buf.Reset()
+		bufferPool.Put(buf)
+	}
+}
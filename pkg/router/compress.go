@@ -0,0 +1,222 @@
+package router
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// DefaultCompressMinSize is the smallest body CompressMiddleware
+// compresses; shorter responses are sent as-is.
+const DefaultCompressMinSize = 1024
+
+// defaultCompressTypes are compressed when no types are given.
+var defaultCompressTypes = []string{"text/html", "application/json"}
+
+// Compressor gzips or deflates responses for clients that accept it.
+// Server-sent event streams are never compressed, so Datastar updates
+// are not held back in a compression buffer.
+type Compressor struct {
+	// Level is a compress/flate level, e.g. flate.DefaultCompression.
+	Level int
+
+	// Types lists the Content-Types to compress. A trailing "/*" matches
+	// a whole family, e.g. "text/*".
+	Types []string
+
+	// MinSize is the smallest body compressed, in bytes.
+	MinSize int
+}
+
+// CompressMiddleware returns middleware that compresses responses of the
+// given Content-Types (default text/html and application/json) at level,
+// skipping bodies under DefaultCompressMinSize. Use a Compressor directly
+// to change the threshold. Panics on an invalid level.
+func CompressMiddleware(level int, types ...string) func(http.Handler) http.Handler {
+	if len(types) == 0 {
+		types = defaultCompressTypes
+	}
+	c := &Compressor{Level: level, Types: types, MinSize: DefaultCompressMinSize}
+	return c.Wrap
+}
+
+// Wrap returns middleware that compresses matching responses.
+func (c *Compressor) Wrap(next http.Handler) http.Handler {
+	if c.Level < flate.HuffmanOnly || c.Level > flate.BestCompression {
+		panic("router: invalid compression level " + strconv.Itoa(c.Level))
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding := acceptedEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, c: c, encoding: encoding, status: http.StatusOK}
+		defer cw.close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// compressible reports whether a Content-Type is in c.Types.
+func (c *Compressor) compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, t := range c.Types {
+		if family, ok := strings.CutSuffix(t, "/*"); ok {
+			if strings.HasPrefix(mediaType, family+"/") {
+				return true
+			}
+		} else if mediaType == t {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptedEncoding picks gzip, then deflate, from an Accept-Encoding
+// header; "" if neither is acceptable.
+func acceptedEncoding(header string) string {
+	accepted := map[string]bool{}
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			q, _ = strconv.ParseFloat(v, 64)
+		}
+		accepted[strings.ToLower(strings.TrimSpace(name))] = q > 0
+	}
+	for _, enc := range []string{"gzip", "deflate"} {
+		if accepted[enc] {
+			return enc
+		}
+	}
+	return ""
+}
+
+// compressWriter buffers the start of a response until it knows whether
+// to compress it: the body reached MinSize, the handler flushed, or the
+// handler returned.
+type compressWriter struct {
+	http.ResponseWriter
+	c        *Compressor
+	encoding string
+
+	status      int
+	wroteHeader bool // WriteHeader called by the handler
+	decided     bool // Headers sent downstream
+	buf         bytes.Buffer
+	enc         io.WriteCloser // nil when passing through
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if w.decided || w.wroteHeader {
+		return
+	}
+	w.status = code
+	w.wroteHeader = true
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if !w.decided {
+		if w.buf.Len()+len(b) < w.c.MinSize && w.mayCompress() {
+			return w.buf.Write(b)
+		}
+		w.buf.Write(b)
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	if w.enc != nil {
+		return w.enc.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// mayCompress reports whether the response could still be compressed,
+// going by the headers set so far.
+func (w *compressWriter) mayCompress() bool {
+	h := w.Header()
+	if h.Get("Content-Encoding") != "" || w.status < 200 ||
+		w.status == http.StatusNoContent || w.status == http.StatusNotModified {
+		return false
+	}
+	ct := h.Get("Content-Type")
+	return ct == "" || w.c.compressible(ct)
+}
+
+// decide sends the headers and any buffered body, compressing if the
+// response qualifies and, when sized is false, regardless of MinSize.
+func (w *compressWriter) decide(sized bool) error {
+	w.decided = true
+	h := w.Header()
+	if h.Get("Content-Type") == "" && w.buf.Len() > 0 && w.status != http.StatusNoContent {
+		h.Set("Content-Type", http.DetectContentType(w.buf.Bytes()))
+	}
+
+	if w.mayCompress() && h.Get("Content-Type") != "" {
+		AddVary(h, "Accept-Encoding")
+		if sized && w.buf.Len() >= w.c.MinSize {
+			h.Set("Content-Encoding", w.encoding)
+			h.Del("Content-Length")
+			if w.encoding == "gzip" {
+				w.enc, _ = gzip.NewWriterLevel(w.ResponseWriter, w.c.Level)
+			} else {
+				w.enc, _ = flate.NewWriter(w.ResponseWriter, w.c.Level)
+			}
+		}
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	var err error
+	if w.enc != nil {
+		_, err = w.enc.Write(w.buf.Bytes())
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf.Bytes())
+	}
+	w.buf.Reset()
+	return err
+}
+
+// Flush sends what is buffered so far. A response flushed before
+// reaching MinSize, such as an event stream, is not compressed.
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.decide(false)
+	}
+	if f, ok := w.enc.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// close finishes the response once the handler returns.
+func (w *compressWriter) close() {
+	if !w.decided {
+		if !w.wroteHeader && w.buf.Len() == 0 {
+			return // Nothing written; leave the response to the server
+		}
+		w.decide(true)
+	}
+	if w.enc != nil {
+		w.enc.Close()
+	}
+}
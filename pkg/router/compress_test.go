@@ -0,0 +1,159 @@
+package router
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func compressRouter(mw func(http.Handler) http.Handler) *Router {
+	r := New()
+	r.Use(mw)
+	r.GET("/page", func(ctx *Context) (string, error) {
+		return "<ul>" + strings.Repeat("<li>item</li>", 200) + "</ul>", nil
+	})
+	r.GET("/small", func(ctx *Context) (string, error) {
+		return "<p>hi</p>", nil
+	})
+	r.GET("/data", func(ctx *Context) (string, error) {
+		ctx.JSON(map[string]string{"payload": strings.Repeat("x", 2000)})
+		return "", nil
+	})
+	r.GET("/encoded", func(ctx *Context) (string, error) {
+		ctx.Response.Header().Set("Content-Encoding", "br")
+		return strings.Repeat("b", 2000), nil
+	})
+	r.DSGet("/stream", func(ctx *Context) error {
+		sse := ctx.SSE()
+		for i := 0; i < 50; i++ {
+			if err := sse.PatchHTML(`<div id="feed">` + strings.Repeat("update ", 20) + `</div>`); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return r
+}
+
+func compressGet(r *Router, path, acceptEncoding string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", path, nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCompressGzip(t *testing.T) {
+	r := compressRouter(CompressMiddleware(gzip.BestSpeed))
+	w := compressGet(r, "/page", "gzip, deflate")
+
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Content-Encoding = %q", w.Header().Get("Content-Encoding"))
+	}
+	if !strings.Contains(w.Header().Get("Vary"), "Accept-Encoding") {
+		t.Errorf("Vary = %q", w.Header().Get("Vary"))
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "<ul>" + strings.Repeat("<li>item</li>", 200) + "</ul>"; string(body) != want {
+		t.Errorf("decompressed body mismatch: %d bytes", len(body))
+	}
+}
+
+func TestCompressDeflateJSON(t *testing.T) {
+	r := compressRouter(CompressMiddleware(flate.DefaultCompression))
+	w := compressGet(r, "/data", "deflate;q=0.5, gzip;q=0")
+
+	if w.Header().Get("Content-Encoding") != "deflate" {
+		t.Fatalf("Content-Encoding = %q", w.Header().Get("Content-Encoding"))
+	}
+	body, err := io.ReadAll(flate.NewReader(w.Body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(body), `{"payload":"xxx`) {
+		t.Errorf("body = %.40q", body)
+	}
+}
+
+func TestCompressSkips(t *testing.T) {
+	r := compressRouter(CompressMiddleware(gzip.DefaultCompression))
+
+	tests := []struct {
+		name, path, accept, want string
+	}{
+		{"no accept-encoding", "/page", "", "<ul><li>item"},
+		{"identity only", "/page", "identity", "<ul><li>item"},
+		{"below threshold", "/small", "gzip", "<p>hi</p>"},
+		{"already encoded", "/encoded", "gzip", "bbbb"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := compressGet(r, tt.path, tt.accept)
+			if enc := w.Header().Get("Content-Encoding"); enc == "gzip" {
+				t.Errorf("response compressed")
+			}
+			if !strings.HasPrefix(w.Body.String(), tt.want) {
+				t.Errorf("body = %.40q", w.Body.String())
+			}
+		})
+	}
+
+	c := &Compressor{Level: gzip.DefaultCompression, Types: []string{"text/*"}, MinSize: 4}
+	r2 := compressRouter(c.Wrap)
+	if w := compressGet(r2, "/small", "gzip"); w.Header().Get("Content-Encoding") != "gzip" {
+		t.Error("custom MinSize not applied")
+	}
+	if w := compressGet(r2, "/data", "gzip"); w.Header().Get("Content-Encoding") != "" {
+		t.Error("JSON compressed though not in Types")
+	}
+}
+
+func TestCompressSSEPassesThrough(t *testing.T) {
+	plain := compressRouter(func(next http.Handler) http.Handler { return next })
+	compressed := compressRouter(CompressMiddleware(gzip.BestCompression))
+
+	req := func(r *Router) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/stream", nil)
+		req.Header.Set("Accept", "text/event-stream")
+		req.Header.Set("Accept-Encoding", "gzip")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	want, got := req(plain), req(compressed)
+
+	if got.Header().Get("Content-Encoding") != "" {
+		t.Errorf("SSE compressed: %q", got.Header().Get("Content-Encoding"))
+	}
+	if got.Header().Get("Content-Type") != want.Header().Get("Content-Type") {
+		t.Errorf("Content-Type = %q", got.Header().Get("Content-Type"))
+	}
+	if got.Body.String() != want.Body.String() {
+		t.Error("SSE body changed by compression middleware")
+	}
+	if !got.Flushed {
+		t.Error("SSE response not flushed")
+	}
+}
+
+func TestCompressInvalidLevel(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic")
+		}
+	}()
+	CompressMiddleware(42)(http.NotFoundHandler())
+}
@@ -1,11 +1,19 @@
 package router
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/stukennedy/irgo/pkg/datastar"
+	"github.com/stukennedy/irgo/pkg/render"
 )
 
 // Context provides request data and response helpers for handlers.
@@ -13,6 +21,12 @@ type Context struct {
 	Request  *http.Request
 	Response http.ResponseWriter
 	written  bool
+	config   *routerConfig
+	nextPoll time.Duration // Set by NextPoll
+
+	flashes     []FlashMessage // Queued by Flash this request
+	readFlashes []FlashMessage // Returned by Flashes
+	flashesRead bool
 }
 
 // NewContext creates a new Context from the standard http types.
@@ -28,6 +42,41 @@ func (c *Context) Param(key string) string {
 	return chi.URLParam(c.Request, key)
 }
 
+// Wildcard returns the URL-decoded remainder matched by a catch-all route,
+// e.g. "docs/read me.txt" for /files/docs/read%20me.txt on "/files/*".
+// The value is not cleaned; use WildcardParts when building file paths.
+func (c *Context) Wildcard() string {
+	return wildcard(c.Request)
+}
+
+// WildcardParts returns the cleaned segments of the wildcard remainder.
+// Dot segments are resolved without escaping the route prefix, so
+// "a/../../b" yields ["b"].
+func (c *Context) WildcardParts() []string {
+	p := cleanWildcard(c.Request)
+	if p == "/" {
+		return nil
+	}
+	return strings.Split(p[1:], "/")
+}
+
+// wildcard returns the decoded "*" URL param. chi matches against RawPath
+// when the request has one, in which case the param is still escaped.
+func wildcard(req *http.Request) string {
+	v := chi.URLParam(req, "*")
+	if req.URL.RawPath != "" {
+		if u, err := url.PathUnescape(v); err == nil {
+			v = u
+		}
+	}
+	return v
+}
+
+// cleanWildcard returns the wildcard remainder as a rooted, cleaned path.
+func cleanWildcard(req *http.Request) string {
+	return path.Clean("/" + wildcard(req))
+}
+
 // Query returns a query string parameter.
 func (c *Context) Query(key string) string {
 	return c.Request.URL.Query().Get(key)
@@ -47,8 +96,10 @@ func (c *Context) FormValue(key string) string {
 	return c.Request.FormValue(key)
 }
 
-// Header returns a request header value.
+// Header returns a request header value. Reading HX-Request or HX-Target
+// adds it to the response's Vary header (see VaryHTMX).
 func (c *Context) Header(key string) string {
+	c.varyOn(key)
 	return c.Request.Header.Get(key)
 }
 
@@ -60,15 +111,31 @@ func (c *Context) SetHeader(key, value string) {
 // --- Datastar Integration ---
 
 // IsDatastar returns true if this is a Datastar request.
-// Datastar sends an Accept header with text/event-stream for SSE requests.
+// Datastar sends an Accept header with text/event-stream for SSE requests,
+// so the response varies on Accept.
 func (c *Context) IsDatastar() bool {
+	AddVary(c.Response.Header(), "Accept")
 	accept := c.Request.Header.Get("Accept")
 	return accept == "text/event-stream"
 }
 
+// IsNoJS returns true if the client's JavaScript bundle failed to load
+// (see NoJSMiddleware). Handlers should render full pages with plain
+// links and forms, e.g. via render.Engine.RenderContext(ctx.Context(), ...).
+func (c *Context) IsNoJS() bool {
+	return IsNoJSRequest(c.Request)
+}
+
+// Context returns the request's context.Context.
+func (c *Context) Context() context.Context {
+	return c.Request.Context()
+}
+
 // SSE creates a new SSE writer for streaming Datastar responses.
 // Use this to send DOM patches, signal updates, and other SSE events.
+// Opening the stream writes the response headers.
 func (c *Context) SSE() *datastar.SSE {
+	c.written = true
 	return datastar.NewSSE(c.Response, c.Request)
 }
 
@@ -94,6 +161,32 @@ func (c *Context) HTMLStatus(status int, html string) {
 	c.Response.Write([]byte(html))
 }
 
+// StreamTemplate renders a template from e straight to the response,
+// rather than returning it as a string, so large pages aren't buffered
+// twice:
+//
+//	r.GET("/reports/{id}", func(ctx *router.Context) (string, error) {
+//		return "", ctx.StreamTemplate(engine, "pages/report", report)
+//	})
+//
+// The status and headers are sent with the first output. An error before
+// that leaves the response unwritten, so the handler's error response is
+// sent as usual; after it the response can only be cut short, and
+// render.TemplateError.Partial is set.
+func (c *Context) StreamTemplate(e *render.Engine, name string, data any) error {
+	c.Response.Header().Set("Content-Type", "text/html; charset=utf-8")
+	return e.RenderTo(streamWriter{c}, name, data)
+}
+
+// streamWriter marks the context written once output reaches the
+// response.
+type streamWriter struct{ c *Context }
+
+func (w streamWriter) Write(p []byte) (int, error) {
+	w.c.written = true
+	return w.c.Response.Write(p)
+}
+
 // JSON writes a JSON response with 200 status.
 func (c *Context) JSON(data any) {
 	c.JSONStatus(http.StatusOK, data)
@@ -108,7 +201,35 @@ func (c *Context) JSONStatus(status int, data any) {
 }
 
 // Error writes an error response.
+// If err is or wraps an *HTTPError, its status and message are used and any
+// internal cause is logged. ValidationErrors produce a 422 listing the
+// messages. Other errors produce a 500 and are logged.
 func (c *Context) Error(err error) {
+	// Body over a MaxBodySize limit
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		err = ErrPayloadTooLarge("Request body too large")
+	}
+
+	var validationErrs ValidationErrors
+	if errors.As(err, &validationErrs) {
+		c.writeValidationErrors(validationErrs)
+		return
+	}
+
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		if httpErr.Internal != nil || httpErr.Status >= 500 {
+			log.Printf("router: %s %s: %v", c.Request.Method, c.Request.URL.Path, err)
+		}
+		c.ErrorStatus(httpErr.Status, httpErr.Message)
+		return
+	}
+	log.Printf("router: %s %s: %v", c.Request.Method, c.Request.URL.Path, err)
+	if errors.Is(err, context.DeadlineExceeded) {
+		c.ErrorStatus(http.StatusGatewayTimeout, timeoutMessage)
+		return
+	}
 	c.ErrorStatus(http.StatusInternalServerError, err.Error())
 }
 
@@ -136,10 +257,15 @@ func (c *Context) BadRequest(message string) {
 	c.ErrorStatus(http.StatusBadRequest, message)
 }
 
-// Redirect sends a standard HTTP redirect response.
-func (c *Context) Redirect(url string) {
+// Redirect sends a standard HTTP redirect response. The status defaults
+// to 303 See Other; pass e.g. http.StatusMovedPermanently to override it.
+func (c *Context) Redirect(url string, status ...int) {
+	code := http.StatusSeeOther
+	if len(status) > 0 {
+		code = status[0]
+	}
 	c.written = true
-	http.Redirect(c.Response, c.Request, url, http.StatusSeeOther)
+	http.Redirect(c.Response, c.Request, url, code)
 }
 
 // NoContent writes a 204 No Content response.
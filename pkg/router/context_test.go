@@ -1,10 +1,13 @@
 package router
 
 import (
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+
+	"github.com/stukennedy/irgo/pkg/render"
 )
 
 func TestContextParam(t *testing.T) {
@@ -379,3 +382,46 @@ func TestContextSSE(t *testing.T) {
 		t.Error("expected SSE() to return non-nil")
 	}
 }
+
+func TestStreamTemplate(t *testing.T) {
+	engine := render.New()
+	engine.AddFunc("fail", func() (string, error) { return "", errors.New("db gone") })
+	engine.Parse("report", `<h1>{{.}}</h1>`)
+	engine.Parse("broken", `<h1>Report</h1>{{fail}}`)
+	engine.Parse("early", `{{fail}}<h1>Report</h1>`)
+
+	r := New()
+	r.GET("/{name}", func(ctx *Context) (string, error) {
+		return "", ctx.StreamTemplate(engine, ctx.Param("name"), "Q3")
+	})
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/report", http.StatusOK, "<h1>Q3</h1>"},
+		// Nothing was sent, so the usual error response is
+		{"/early", http.StatusInternalServerError, `role="alert"`},
+		// Output was under way, so the response is cut short as is
+		{"/broken", http.StatusOK, "<h1>Report</h1>"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.status || !strings.Contains(w.Body.String(), tt.body) {
+			t.Errorf("%s: got %d %q, want %d %q", tt.path, w.Code, w.Body.String(), tt.status, tt.body)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" {
+			t.Errorf("%s: Content-Type = %q", tt.path, ct)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/broken", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Body.String() != "<h1>Report</h1>" {
+		t.Errorf("error page appended to a partial response: %q", w.Body.String())
+	}
+}
@@ -0,0 +1,194 @@
+package router
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNoCookieSecret is returned by signed cookie helpers when the router
+	// was created without WithCookieSecret.
+	ErrNoCookieSecret = errors.New("router: no cookie secret configured")
+
+	// ErrInvalidCookie is returned when a signed cookie fails verification.
+	ErrInvalidCookie = errors.New("router: invalid cookie signature")
+
+	// ErrCookieExpired is returned when a signed cookie is past its expiry.
+	ErrCookieExpired = errors.New("router: cookie expired")
+)
+
+// CookieOption configures a cookie set with SetCookieValue.
+type CookieOption func(*http.Cookie)
+
+// CookieMaxAge sets the cookie lifetime. Zero or negative deletes the cookie.
+func CookieMaxAge(d time.Duration) CookieOption {
+	return func(c *http.Cookie) {
+		if d <= 0 {
+			c.MaxAge = -1
+			c.Expires = time.Unix(0, 0)
+			return
+		}
+		c.MaxAge = int(d / time.Second)
+		c.Expires = time.Now().Add(d)
+	}
+}
+
+// CookiePath sets the cookie path (default: "/").
+func CookiePath(path string) CookieOption {
+	return func(c *http.Cookie) {
+		c.Path = path
+	}
+}
+
+// CookieHTTPOnly controls whether the cookie is hidden from JavaScript (default: true).
+func CookieHTTPOnly(httpOnly bool) CookieOption {
+	return func(c *http.Cookie) {
+		c.HttpOnly = httpOnly
+	}
+}
+
+// CookieSameSite sets the SameSite mode (default: Lax).
+func CookieSameSite(mode http.SameSite) CookieOption {
+	return func(c *http.Cookie) {
+		c.SameSite = mode
+	}
+}
+
+// CookieSecure controls whether the cookie is only sent over HTTPS.
+func CookieSecure(secure bool) CookieOption {
+	return func(c *http.Cookie) {
+		c.Secure = secure
+	}
+}
+
+// Cookie returns the value of the named request cookie, or "" if absent.
+func (c *Context) Cookie(name string) string {
+	cookie, err := c.Request.Cookie(name)
+	if err != nil {
+		return ""
+	}
+	return cookie.Value
+}
+
+// SetCookie adds a Set-Cookie header to the response.
+func (c *Context) SetCookie(cookie *http.Cookie) {
+	http.SetCookie(c.Response, cookie)
+}
+
+// SetCookieValue sets a cookie with sensible defaults
+// (Path "/", HttpOnly, SameSite=Lax), adjusted by opts.
+func (c *Context) SetCookieValue(name, value string, opts ...CookieOption) {
+	c.SetCookie(newCookie(name, value, opts))
+}
+
+// DeleteCookie expires the named cookie on the client.
+func (c *Context) DeleteCookie(name string) {
+	c.SetCookieValue(name, "", CookieMaxAge(0))
+}
+
+// SetSignedCookie sets a cookie whose value is HMAC-signed with the router's
+// cookie secret. If a max age is given, the expiry is signed too so the
+// cookie is rejected after it lapses even if the client keeps sending it.
+func (c *Context) SetSignedCookie(name, value string, opts ...CookieOption) error {
+	secret := c.cookieSecret()
+	if secret == nil {
+		return ErrNoCookieSecret
+	}
+	cookie := newCookie(name, value, opts)
+	var expires int64
+	if cookie.MaxAge > 0 {
+		expires = cookie.Expires.Unix()
+	}
+	cookie.Value = signCookie(secret, name, value, expires)
+	c.SetCookie(cookie)
+	return nil
+}
+
+// SignedCookie returns the verified value of a cookie set with SetSignedCookie.
+// Returns http.ErrNoCookie if absent, ErrInvalidCookie if tampered with,
+// and ErrCookieExpired if past its signed expiry.
+func (c *Context) SignedCookie(name string) (string, error) {
+	secret := c.cookieSecret()
+	if secret == nil {
+		return "", ErrNoCookieSecret
+	}
+	cookie, err := c.Request.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	return verifyCookie(secret, name, cookie.Value, time.Now())
+}
+
+func (c *Context) cookieSecret() []byte {
+	if c.config == nil || len(c.config.cookieSecret) == 0 {
+		return nil
+	}
+	return c.config.cookieSecret
+}
+
+func newCookie(name, value string, opts []CookieOption) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+	for _, opt := range opts {
+		opt(cookie)
+	}
+	return cookie
+}
+
+// signCookie encodes value as "base64(value).expires.base64(mac)".
+// The cookie name is included in the MAC so values can't be swapped
+// between cookies. An expires of 0 means no signed expiry.
+func signCookie(secret []byte, name, value string, expires int64) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(value)) + "." + strconv.FormatInt(expires, 10)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(cookieMAC(secret, name, payload))
+}
+
+func verifyCookie(secret []byte, name, signed string, now time.Time) (string, error) {
+	i := strings.LastIndexByte(signed, '.')
+	if i < 0 {
+		return "", ErrInvalidCookie
+	}
+	payload, sig := signed[:i], signed[i+1:]
+
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, cookieMAC(secret, name, payload)) {
+		return "", ErrInvalidCookie
+	}
+
+	encoded, expStr, ok := strings.Cut(payload, ".")
+	if !ok {
+		return "", ErrInvalidCookie
+	}
+	expires, err := strconv.ParseInt(expStr, 10, 64)
+	if err != nil {
+		return "", ErrInvalidCookie
+	}
+	if expires > 0 && now.Unix() >= expires {
+		return "", ErrCookieExpired
+	}
+
+	value, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", ErrInvalidCookie
+	}
+	return string(value), nil
+}
+
+func cookieMAC(secret []byte, name, payload string) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(name))
+	h.Write([]byte{'='})
+	h.Write([]byte(payload))
+	return h.Sum(nil)
+}
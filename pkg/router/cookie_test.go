@@ -0,0 +1,119 @@
+package router
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCookieHelpers(t *testing.T) {
+	r := New()
+	var got string
+	r.GET("/prefs", func(ctx *Context) (string, error) {
+		got = ctx.Cookie("theme")
+		ctx.SetCookieValue("theme", "light", CookieMaxAge(time.Hour), CookieSameSite(http.SameSiteStrictMode))
+		return "", nil
+	})
+
+	req := httptest.NewRequest("GET", "/prefs", nil)
+	req.AddCookie(&http.Cookie{Name: "theme", Value: "dark"})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if got != "dark" {
+		t.Errorf("expected cookie 'dark', got %q", got)
+	}
+
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("expected 1 cookie, got %d", len(cookies))
+	}
+	c := cookies[0]
+	if c.Value != "light" || c.Path != "/" || !c.HttpOnly || c.MaxAge != 3600 || c.SameSite != http.SameSiteStrictMode {
+		t.Errorf("unexpected cookie attributes: %+v", c)
+	}
+}
+
+func signedRouter(secret string, handler FragmentHandler) *Router {
+	r := New(WithCookieSecret([]byte(secret)))
+	r.GET("/x", handler)
+	return r
+}
+
+func TestSignedCookieRoundTrip(t *testing.T) {
+	r := signedRouter("key", func(ctx *Context) (string, error) {
+		return "", ctx.SetSignedCookie("user", "42")
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/x", nil))
+	cookie := w.Result().Cookies()[0]
+
+	if cookie.Value == "42" {
+		t.Fatal("expected signed value, got plain value")
+	}
+
+	var got string
+	var gotErr error
+	r = signedRouter("key", func(ctx *Context) (string, error) {
+		got, gotErr = ctx.SignedCookie("user")
+		return "", nil
+	})
+	req := httptest.NewRequest("GET", "/x", nil)
+	req.AddCookie(cookie)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	if gotErr != nil || got != "42" {
+		t.Errorf("expected '42', got %q (err %v)", got, gotErr)
+	}
+}
+
+func TestSignedCookieTampered(t *testing.T) {
+	secret := []byte("key")
+	valid := signCookie(secret, "user", "42", 0)
+
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"wrong secret", signCookie([]byte("other"), "user", "42", 0)},
+		{"other cookie name", signCookie(secret, "admin", "42", 0)},
+		{"modified value", strings.Replace(valid, valid[:2], "XX", 1)},
+		{"no signature", "42"},
+	}
+
+	for _, tt := range tests {
+		if _, err := verifyCookie(secret, "user", tt.value, time.Now()); !errors.Is(err, ErrInvalidCookie) {
+			t.Errorf("%s: expected ErrInvalidCookie, got %v", tt.name, err)
+		}
+	}
+}
+
+func TestSignedCookieExpiry(t *testing.T) {
+	secret := []byte("key")
+	now := time.Now()
+	signed := signCookie(secret, "user", "42", now.Add(time.Minute).Unix())
+
+	if v, err := verifyCookie(secret, "user", signed, now); err != nil || v != "42" {
+		t.Errorf("expected valid cookie before expiry, got %q (err %v)", v, err)
+	}
+	if _, err := verifyCookie(secret, "user", signed, now.Add(2*time.Minute)); !errors.Is(err, ErrCookieExpired) {
+		t.Errorf("expected ErrCookieExpired, got %v", err)
+	}
+}
+
+func TestSignedCookieNoSecret(t *testing.T) {
+	r := New()
+	var err error
+	r.GET("/x", func(ctx *Context) (string, error) {
+		err = ctx.SetSignedCookie("user", "42")
+		return "", nil
+	})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/x", nil))
+
+	if !errors.Is(err, ErrNoCookieSecret) {
+		t.Errorf("expected ErrNoCookieSecret, got %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+package router
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stukennedy/irgo/pkg/render"
+	irgotest "github.com/stukennedy/irgo/pkg/testing"
+)
+
+func csrfRouter() *Router {
+	r := New()
+	r.Use(MethodOverrideMiddleware())
+	r.Use(CSRFMiddleware(CSRFExcludePaths("/webhooks/")))
+	r.GET("/form", func(ctx *Context) (string, error) {
+		return ctx.CSRFToken(), nil
+	})
+	r.POST("/todos", func(ctx *Context) (string, error) {
+		return "created", nil
+	})
+	r.DELETE("/todos/{id}", func(ctx *Context) (string, error) {
+		return "deleted " + ctx.Param("id"), nil
+	})
+	r.POST("/webhooks/stripe", func(ctx *Context) (string, error) {
+		return "hook", nil
+	})
+	return r
+}
+
+func TestCSRFHeader(t *testing.T) {
+	client := irgotest.NewClient(csrfRouter())
+	token := client.Get("/form").BodyString()
+	if token == "" || client.Cookie("irgo_csrf") != token {
+		t.Fatalf("expected token cookie to match context token, got %q", token)
+	}
+
+	client.WithHeader("X-CSRF-Token", token).Post("/todos", nil).AssertBodyEquals(t, "created")
+}
+
+func TestCSRFRejects(t *testing.T) {
+	client := irgotest.NewClient(csrfRouter())
+	client.Get("/form")
+
+	resp := client.Post("/todos", nil)
+	resp.AssertStatus(t, http.StatusForbidden)
+	resp.HTML(t).ContainsClass("error")
+
+	client.WithHeader("X-CSRF-Token", "forged").Post("/todos", nil).AssertStatus(t, http.StatusForbidden)
+
+	// Fresh client without a cookie
+	irgotest.NewClient(csrfRouter()).WithHeader("X-CSRF-Token", "x").Post("/todos", nil).AssertStatus(t, http.StatusForbidden)
+}
+
+func TestCSRFExcludePaths(t *testing.T) {
+	client := irgotest.NewClient(csrfRouter())
+	client.Post("/webhooks/stripe", nil).AssertBodyEquals(t, "hook")
+}
+
+func TestCSRFWithMethodOverride(t *testing.T) {
+	client := irgotest.NewClient(csrfRouter())
+	token := client.Get("/form").BodyString()
+
+	form := url.Values{"_method": {"DELETE"}, render.CSRFField: {token}}
+	client.WithHeader("Content-Type", "application/x-www-form-urlencoded").
+		Post("/todos/3", strings.NewReader(form.Encode())).
+		AssertBodyEquals(t, "deleted 3")
+
+	form.Set(render.CSRFField, "forged")
+	client.WithHeader("Content-Type", "application/x-www-form-urlencoded").
+		Post("/todos/3", strings.NewReader(form.Encode())).
+		AssertStatus(t, http.StatusForbidden)
+}
+
+func TestCSRFTemplateFuncs(t *testing.T) {
+	r := New()
+	r.Use(CSRFMiddleware())
+	engine := render.New()
+	engine.Parse("form", `<form>{{csrfField .}}</form>`)
+	r.GET("/form", func(ctx *Context) (string, error) {
+		return engine.Render("form", ctx.Context())
+	})
+
+	client := irgotest.NewClient(r)
+	body := client.Get("/form").BodyString()
+	if !strings.Contains(body, `name="_csrf" value="`+client.Cookie("irgo_csrf")+`"`) {
+		t.Errorf("expected hidden CSRF field, got %q", body)
+	}
+}
@@ -0,0 +1,28 @@
+package router
+
+import "log"
+
+// SwapHandlerData atomically replaces the content table stored under key,
+// e.g. a price list or copy deck delivered over the air. Handlers read it
+// with Context.HandlerData; requests already running keep the value they
+// read.
+func (r *Router) SwapHandlerData(key string, data any) {
+	r.config.data.Store(key, data)
+	log.Printf("router: swapped handler data %q", key)
+}
+
+// HandlerData returns the content table stored under key, or nil.
+func (r *Router) HandlerData(key string) any {
+	v, _ := r.config.data.Load(key)
+	return v
+}
+
+// HandlerData returns the content table the router stores under key, or
+// nil if none was set with Router.SwapHandlerData.
+func (c *Context) HandlerData(key string) any {
+	if c.config == nil {
+		return nil
+	}
+	v, _ := c.config.data.Load(key)
+	return v
+}
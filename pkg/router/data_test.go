@@ -0,0 +1,39 @@
+package router
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSwapHandlerData(t *testing.T) {
+	r := New()
+	r.GET("/prices", func(ctx *Context) (string, error) {
+		prices, _ := ctx.HandlerData("prices").(map[string]int)
+		return fmt.Sprint(prices["tea"]), nil
+	})
+
+	get := func() string {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("GET", "/prices", nil))
+		return rec.Body.String()
+	}
+
+	if got := get(); got != "0" {
+		t.Errorf("before swap got %q, want 0", got)
+	}
+	r.SwapHandlerData("prices", map[string]int{"tea": 3})
+	if got := get(); got != "3" {
+		t.Errorf("after swap got %q, want 3", got)
+	}
+	r.SwapHandlerData("prices", map[string]int{"tea": 4})
+	if got := get(); got != "4" {
+		t.Errorf("after second swap got %q, want 4", got)
+	}
+	if r.HandlerData("missing") != nil {
+		t.Error("expected nil for unknown key")
+	}
+	if NewContext(nil, nil).HandlerData("prices") != nil {
+		t.Error("expected nil without a router")
+	}
+}
@@ -0,0 +1,82 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// HTTPError is an error that carries an HTTP status code.
+// Return it (or an error wrapping it) from a handler to control the
+// status and message of the error fragment.
+type HTTPError struct {
+	Status   int    // HTTP status code
+	Message  string // Message shown to the user
+	Internal error  // Underlying cause, logged but never shown
+}
+
+// NewHTTPError creates an HTTPError with the given status and message.
+// An empty message defaults to the status text.
+func NewHTTPError(status int, message string) *HTTPError {
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	return &HTTPError{Status: status, Message: message}
+}
+
+func (e *HTTPError) Error() string {
+	if e.Internal != nil {
+		return fmt.Sprintf("%d %s: %v", e.Status, e.Message, e.Internal)
+	}
+	return fmt.Sprintf("%d %s", e.Status, e.Message)
+}
+
+func (e *HTTPError) Unwrap() error {
+	return e.Internal
+}
+
+// WithInternal attaches an underlying cause to the error.
+func (e *HTTPError) WithInternal(err error) *HTTPError {
+	e.Internal = err
+	return e
+}
+
+// ErrBadRequest returns a 400 HTTPError.
+func ErrBadRequest(message string) *HTTPError {
+	return NewHTTPError(http.StatusBadRequest, message)
+}
+
+// ErrUnauthorized returns a 401 HTTPError.
+func ErrUnauthorized(message string) *HTTPError {
+	return NewHTTPError(http.StatusUnauthorized, message)
+}
+
+// ErrForbidden returns a 403 HTTPError.
+func ErrForbidden(message string) *HTTPError {
+	return NewHTTPError(http.StatusForbidden, message)
+}
+
+// ErrNotFound returns a 404 HTTPError.
+func ErrNotFound(message string) *HTTPError {
+	return NewHTTPError(http.StatusNotFound, message)
+}
+
+// ErrConflict returns a 409 HTTPError.
+func ErrConflict(message string) *HTTPError {
+	return NewHTTPError(http.StatusConflict, message)
+}
+
+// ErrPayloadTooLarge returns a 413 HTTPError.
+func ErrPayloadTooLarge(message string) *HTTPError {
+	return NewHTTPError(http.StatusRequestEntityTooLarge, message)
+}
+
+// ErrUnprocessable returns a 422 HTTPError.
+func ErrUnprocessable(message string) *HTTPError {
+	return NewHTTPError(http.StatusUnprocessableEntity, message)
+}
+
+// ErrInternal returns a 500 HTTPError wrapping err.
+// The error is logged; the user sees a generic message.
+func ErrInternal(err error) *HTTPError {
+	return NewHTTPError(http.StatusInternalServerError, "").WithInternal(err)
+}
@@ -0,0 +1,82 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPErrorStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		status  int
+		message string
+	}{
+		{"not found", ErrNotFound("Todo not found"), http.StatusNotFound, "Todo not found"},
+		{"bad request", ErrBadRequest(""), http.StatusBadRequest, "Bad Request"},
+		{"wrapped", fmt.Errorf("loading todo: %w", ErrNotFound("Todo not found")), http.StatusNotFound, "Todo not found"},
+		{"internal", ErrInternal(errors.New("db down")), http.StatusInternalServerError, "Internal Server Error"},
+		{"plain", errors.New("boom"), http.StatusInternalServerError, "boom"},
+	}
+
+	for _, tt := range tests {
+		r := New()
+		r.GET("/x", func(ctx *Context) (string, error) {
+			return "", tt.err
+		})
+
+		req := httptest.NewRequest("GET", "/x", nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.status, w.Code)
+		}
+		if !strings.Contains(w.Body.String(), tt.message) {
+			t.Errorf("%s: expected body to contain %q, got %q", tt.name, tt.message, w.Body.String())
+		}
+	}
+}
+
+func TestHTTPErrorHidesInternal(t *testing.T) {
+	r := New()
+	r.GET("/x", func(ctx *Context) (string, error) {
+		return "", ErrInternal(errors.New("secret dsn"))
+	})
+
+	req := httptest.NewRequest("GET", "/x", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if strings.Contains(w.Body.String(), "secret dsn") {
+		t.Errorf("internal error leaked into response: %q", w.Body.String())
+	}
+}
+
+func TestHTTPErrorUnwrap(t *testing.T) {
+	cause := errors.New("cause")
+	err := ErrConflict("Already exists").WithInternal(cause)
+
+	if !errors.Is(err, cause) {
+		t.Error("expected errors.Is to find internal cause")
+	}
+}
+
+func TestSSEHandlerHTTPError(t *testing.T) {
+	r := New()
+	r.DSGet("/x", func(ctx *Context) error {
+		return ErrForbidden("")
+	})
+
+	req := httptest.NewRequest("GET", "/x", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected status 403, got %d", w.Code)
+	}
+}
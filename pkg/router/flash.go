@@ -0,0 +1,108 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/stukennedy/irgo/pkg/render"
+)
+
+// FlashMessage is a message set with Context.Flash.
+type FlashMessage = render.FlashMessage
+
+const (
+	// FlashCookieName is the signed cookie holding flash messages when
+	// SessionMiddleware isn't installed.
+	FlashCookieName = "irgo_flash"
+
+	// flashTTL is how long an unread flash cookie lives.
+	flashTTL = 5 * time.Minute
+
+	// flashSessionKey holds flash messages in the session.
+	flashSessionKey = "_flashes"
+)
+
+// Flash queues a message for the next request to show with Flashes,
+// typically before redirecting:
+//
+//	ctx.Flash("success", "Todo created")
+//	ctx.Redirect("/todos")
+//
+// Messages go in the session if SessionMiddleware is installed, otherwise
+// in a signed cookie that expires after five minutes, which needs
+// WithCookieSecret (ErrNoCookieSecret otherwise). Like other cookies, call
+// it before the handler starts writing.
+func (c *Context) Flash(kind, message string) error {
+	if c.flashes == nil && !c.flashesRead {
+		// Keep messages queued earlier and not yet shown
+		c.flashes = c.storedFlashes()
+	}
+	c.flashes = append(c.flashes, FlashMessage{Kind: kind, Message: message})
+	data, err := json.Marshal(c.flashes)
+	if err != nil {
+		return err
+	}
+
+	if s := c.Session(); s != nil {
+		s.Set(flashSessionKey, string(data))
+		return nil
+	}
+	if c.cookieSecret() == nil {
+		return ErrNoCookieSecret
+	}
+	removeSetCookie(c.Response.Header(), FlashCookieName)
+	return c.SetSignedCookie(FlashCookieName, string(data), CookieMaxAge(flashTTL))
+}
+
+// Flashes returns the messages queued by Flash on an earlier request and
+// clears them, so each is shown once. Repeated calls within a request
+// return the same messages. Render them with the flashes template
+// function or render.Flashes:
+//
+//	{{flashes .Flashes}}
+func (c *Context) Flashes() []FlashMessage {
+	if c.flashesRead {
+		return c.readFlashes
+	}
+	c.flashesRead = true
+	c.readFlashes = c.storedFlashes()
+
+	if s := c.Session(); s != nil {
+		if s.Get(flashSessionKey) != nil {
+			s.Delete(flashSessionKey)
+		}
+	} else if _, err := c.Request.Cookie(FlashCookieName); err == nil {
+		c.DeleteCookie(FlashCookieName)
+	}
+	return c.readFlashes
+}
+
+// storedFlashes returns the messages queued for this request, ignoring a
+// tampered or expired cookie.
+func (c *Context) storedFlashes() []FlashMessage {
+	var data string
+	if s := c.Session(); s != nil {
+		data = s.GetString(flashSessionKey)
+	} else if c.cookieSecret() != nil {
+		data, _ = c.SignedCookie(FlashCookieName)
+	}
+	var messages []FlashMessage
+	if data != "" {
+		json.Unmarshal([]byte(data), &messages)
+	}
+	return messages
+}
+
+// removeSetCookie drops Set-Cookie headers for name, so a cookie set
+// again in the same response isn't sent twice.
+func removeSetCookie(h http.Header, name string) {
+	cookies := h.Values("Set-Cookie")
+	h.Del("Set-Cookie")
+	for _, c := range cookies {
+		if !strings.HasPrefix(c, name+"=") {
+			h.Add("Set-Cookie", c)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package router
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// flashApp creates a todo with a POST that flashes and redirects to a GET
+// listing the flashes.
+func flashApp(r *Router) {
+	r.POST("/todos", func(ctx *Context) (string, error) {
+		if err := ctx.Flash("success", "Todo created"); err != nil {
+			return "", err
+		}
+		if err := ctx.Flash("error", "Sync <delayed>"); err != nil {
+			return "", err
+		}
+		ctx.Redirect("/todos")
+		return "", nil
+	})
+	r.GET("/todos", func(ctx *Context) (string, error) {
+		var kinds []string
+		for _, f := range ctx.Flashes() {
+			kinds = append(kinds, f.Kind+":"+f.Message)
+		}
+		// Repeated reads in one request see the same messages
+		if len(ctx.Flashes()) != len(kinds) {
+			return "", errors.New("second Flashes call differs")
+		}
+		return "[" + strings.Join(kinds, ",") + "]", nil
+	})
+}
+
+func flashClient(t *testing.T, h http.Handler) (*httptest.Server, *http.Client) {
+	t.Helper()
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	jar, _ := cookiejar.New(nil)
+	return srv, &http.Client{Jar: jar}
+}
+
+func fetch(t *testing.T, client *http.Client, method, target string) string {
+	t.Helper()
+	var resp *http.Response
+	var err error
+	if method == "POST" {
+		resp, err = client.PostForm(target, url.Values{})
+	} else {
+		resp, err = client.Get(target)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("%s %s: status %d: %s", method, target, resp.StatusCode, body)
+	}
+	return string(body)
+}
+
+func TestFlashCookie(t *testing.T) {
+	r := New(WithCookieSecret([]byte("flash-secret")))
+	flashApp(r)
+	srv, client := flashClient(t, r)
+
+	// The POST redirects, so this is the body of the following GET
+	if got, want := fetch(t, client, "POST", srv.URL+"/todos"), "[success:Todo created,error:Sync <delayed>]"; got != want {
+		t.Errorf("after redirect: %q, want %q", got, want)
+	}
+	if got := fetch(t, client, "GET", srv.URL+"/todos"); got != "[]" {
+		t.Errorf("second GET: %q, want no flashes", got)
+	}
+}
+
+func TestFlashSession(t *testing.T) {
+	store := NewMemoryStore(0)
+	r := New()
+	r.Use(SessionMiddleware(store))
+	flashApp(r)
+	srv, client := flashClient(t, r)
+
+	if got, want := fetch(t, client, "POST", srv.URL+"/todos"), "[success:Todo created,error:Sync <delayed>]"; got != want {
+		t.Errorf("after redirect: %q, want %q", got, want)
+	}
+	if got := fetch(t, client, "GET", srv.URL+"/todos"); got != "[]" {
+		t.Errorf("second GET: %q, want no flashes", got)
+	}
+	u, _ := url.Parse(srv.URL)
+	for _, c := range client.Jar.Cookies(u) {
+		if c.Name == FlashCookieName {
+			t.Errorf("flash cookie set with sessions installed")
+		}
+	}
+}
+
+func TestFlashAccumulates(t *testing.T) {
+	r := New(WithCookieSecret([]byte("flash-secret")))
+	r.POST("/step", func(ctx *Context) (string, error) {
+		return "", ctx.Flash("info", "step "+ctx.Query("n"))
+	})
+	flashApp(r)
+	srv, client := flashClient(t, r)
+
+	fetch(t, client, "POST", srv.URL+"/step?n=1")
+	fetch(t, client, "POST", srv.URL+"/step?n=2")
+	if got, want := fetch(t, client, "GET", srv.URL+"/todos"), "[info:step 1,info:step 2]"; got != want {
+		t.Errorf("flashes = %q, want %q", got, want)
+	}
+}
+
+func TestFlashTampered(t *testing.T) {
+	r := New(WithCookieSecret([]byte("flash-secret")))
+	flashApp(r)
+	req := httptest.NewRequest("GET", "/todos", nil)
+	req.AddCookie(&http.Cookie{Name: FlashCookieName, Value: "W3sia2luZCI6ImVycm9yIn1d.0.forged"})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if got := w.Body.String(); got != "[]" {
+		t.Errorf("tampered cookie: %q, want no flashes", got)
+	}
+}
+
+func TestFlashNoSecret(t *testing.T) {
+	ctx := NewContext(httptest.NewRecorder(), httptest.NewRequest("POST", "/", nil))
+	if err := ctx.Flash("success", "hi"); !errors.Is(err, ErrNoCookieSecret) {
+		t.Errorf("Flash without secret or session = %v, want ErrNoCookieSecret", err)
+	}
+}
@@ -0,0 +1,104 @@
+package router
+
+import (
+	"bytes"
+	"html/template"
+
+	"github.com/a-h/templ"
+	"github.com/stukennedy/irgo/pkg/datastar"
+)
+
+// Fragments builds a response that updates several regions at once. Each
+// fragment is rendered when added and buffered; Commit writes the first as
+// the primary response (retargeted to its target with HX-Retarget) and the
+// rest as hx-swap-oob elements. If any fragment
+// fails to render, Commit returns the error and writes nothing, so the
+// handler's normal error path runs:
+//
+//	return "", ctx.Fragments().
+//		AddTempl("#todo-1", TodoRow(todo)).
+//		Add("#count", strconv.Itoa(n)).
+//		Commit()
+type Fragments struct {
+	ctx   *Context
+	parts []fragment
+	err   error
+}
+
+type fragment struct {
+	target string
+	html   string
+}
+
+// Fragments returns a builder for a multi-fragment response.
+func (c *Context) Fragments() *Fragments {
+	return &Fragments{ctx: c}
+}
+
+// Add buffers an HTML fragment for the element matching target.
+func (f *Fragments) Add(target, html string) *Fragments {
+	if f.err == nil {
+		f.parts = append(f.parts, fragment{target: target, html: html})
+	}
+	return f
+}
+
+// AddTempl renders a component now and buffers it for target.
+func (f *Fragments) AddTempl(target string, c templ.Component) *Fragments {
+	if f.err != nil {
+		return f
+	}
+	var buf bytes.Buffer
+	if err := c.Render(f.ctx.Context(), &buf); err != nil {
+		f.err = err
+		return f
+	}
+	return f.Add(target, buf.String())
+}
+
+// Err returns the first render error, if any.
+func (f *Fragments) Err() error {
+	return f.err
+}
+
+// Commit writes all fragments in one response, or returns the first render
+// error without writing anything.
+func (f *Fragments) Commit() error {
+	if f.err != nil {
+		return f.err
+	}
+
+	var buf bytes.Buffer
+	for i, p := range f.parts {
+		if i == 0 {
+			buf.WriteString(p.html)
+			continue
+		}
+		buf.WriteString(`<div hx-swap-oob="innerHTML:`)
+		buf.WriteString(template.HTMLEscapeString(p.target))
+		buf.WriteString(`">`)
+		buf.WriteString(p.html)
+		buf.WriteString(`</div>`)
+	}
+	if len(f.parts) > 0 {
+		f.ctx.SetHeader("HX-Retarget", f.parts[0].target)
+	}
+	f.ctx.HTML(buf.String())
+	return nil
+}
+
+// SSEBatch returns a Datastar batch that renders with the request context.
+// Flush it with CommitSSE to send every patch at once.
+func (c *Context) SSEBatch() *datastar.Batch {
+	return datastar.NewBatch(c.Context())
+}
+
+// CommitSSE flushes a batch to a new SSE stream. If the batch has a render
+// error it is returned and nothing is written.
+func (c *Context) CommitSSE(b *datastar.Batch) error {
+	if err := b.Err(); err != nil {
+		return err
+	}
+	c.written = true
+	return b.Flush(c.SSE())
+}
@@ -0,0 +1,123 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/a-h/templ"
+)
+
+var errRender = errors.New("render failed")
+
+func failingComponent() templ.Component {
+	return templ.ComponentFunc(func(_ context.Context, w io.Writer) error {
+		io.WriteString(w, "<p>half")
+		return errRender
+	})
+}
+
+func TestFragmentsCommit(t *testing.T) {
+	r := New()
+	r.POST("/todos/1/toggle", func(ctx *Context) (string, error) {
+		return "", ctx.Fragments().
+			AddTempl("#todo-1", textComponent(`<li id="todo-1">done</li>`)).
+			Add("#count", "3").
+			Add("#flash", "<p>Saved</p>").
+			Commit()
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/todos/1/toggle", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	want := `<li id="todo-1">done</li>` +
+		`<div hx-swap-oob="innerHTML:#count">3</div>` +
+		`<div hx-swap-oob="innerHTML:#flash"><p>Saved</p></div>`
+	if w.Body.String() != want {
+		t.Errorf("unexpected body:\n got %s\nwant %s", w.Body.String(), want)
+	}
+	if w.Header().Get("HX-Retarget") != "#todo-1" {
+		t.Errorf("expected HX-Retarget #todo-1, got %q", w.Header().Get("HX-Retarget"))
+	}
+}
+
+func TestFragmentsRenderErrorWritesNothing(t *testing.T) {
+	r := New()
+	r.POST("/todos/1/toggle", func(ctx *Context) (string, error) {
+		f := ctx.Fragments().
+			Add("#todo-1", "<li>done</li>").
+			AddTempl("#count", failingComponent()).
+			Add("#flash", "<p>Saved</p>")
+		if !errors.Is(f.Err(), errRender) {
+			t.Errorf("expected render error from Err, got %v", f.Err())
+		}
+		if ctx.Written() {
+			t.Error("expected nothing written before Commit")
+		}
+		return "", f.Commit()
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/todos/1/toggle", nil))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500 from the error path, got %d", w.Code)
+	}
+	for _, part := range []string{"done", "half", "Saved"} {
+		if strings.Contains(w.Body.String(), part) {
+			t.Errorf("expected no fragment output, found %q in %q", part, w.Body.String())
+		}
+	}
+	if w.Header().Get("HX-Retarget") != "" {
+		t.Error("expected no HX-Retarget header")
+	}
+}
+
+func TestSSEBatch(t *testing.T) {
+	r := New()
+	r.DSPost("/ok", func(ctx *Context) error {
+		b := ctx.SSEBatch().
+			PatchTemplByID("todo-1", textComponent(`<li id="todo-1">done</li>`)).
+			PatchHTMLByID("count", "<span>3</span>").
+			PatchSignals(map[string]int{"count": 3})
+		return ctx.CommitSSE(b)
+	})
+	r.DSPost("/fail", func(ctx *Context) error {
+		b := ctx.SSEBatch().
+			PatchHTMLByID("count", "<span>3</span>").
+			PatchTempl(failingComponent())
+		return ctx.CommitSSE(b)
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/ok", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	r.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	row := strings.Index(body, "todo-1")
+	count := strings.Index(body, "<span>3</span>")
+	signals := strings.Index(body, "datastar-patch-signals")
+	if row < 0 || count < 0 || signals < 0 || !(row < count && count < signals) {
+		t.Errorf("expected row, count then signals in order, got:\n%s", body)
+	}
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("POST", "/fail", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500, got %d", w.Code)
+	}
+	if strings.Contains(w.Body.String(), "<span>3</span>") || strings.Contains(w.Body.String(), "event:") {
+		t.Errorf("expected no SSE events, got %q", w.Body.String())
+	}
+}
@@ -0,0 +1,104 @@
+package router
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// HEAD registers a HEAD handler. Without one, HEAD requests are answered by
+// the route's GET handler with the body suppressed.
+func (r *Router) HEAD(pattern string, handler FragmentHandler) *Route {
+	return r.Fragment(http.MethodHead, pattern, handler)
+}
+
+// OPTIONS registers an OPTIONS handler that returns HTML fragments.
+func (r *Router) OPTIONS(pattern string, handler FragmentHandler) *Route {
+	return r.Fragment(http.MethodOptions, pattern, handler)
+}
+
+// anyMethods are the methods Any registers.
+var anyMethods = []string{
+	http.MethodConnect, http.MethodDelete, http.MethodGet, http.MethodHead,
+	http.MethodOptions, http.MethodPatch, http.MethodPost, http.MethodPut,
+	http.MethodTrace,
+}
+
+// Any registers a handler for every HTTP method. Handlers registered for a
+// specific method on the same pattern take precedence, whether registered
+// before or after Any.
+func (r *Router) Any(pattern string, handler FragmentHandler) *Route {
+	route := r.newRoute("*", pattern)
+	h := suppressHeadBody(r.fragmentHandler(route, handler))
+	for _, method := range anyMethods {
+		if !r.config.methods[method+" "+r.prefix+pattern] {
+			r.mux.Method(method, pattern, h)
+		}
+	}
+	return route
+}
+
+// handle registers h for method. GET handlers also answer HEAD unless a
+// HEAD handler is registered for the same pattern.
+func (r *Router) handle(method, pattern string, h http.Handler) {
+	h = suppressHeadBody(h)
+	key := r.prefix + pattern
+	switch method {
+	case http.MethodHead:
+		r.config.heads[key] = true
+	case http.MethodGet:
+		if !r.config.heads[key] {
+			r.method(http.MethodHead, pattern, h)
+		}
+	}
+	r.method(method, pattern, h)
+}
+
+// method registers h for method, recording it so Any leaves it in place.
+func (r *Router) method(method, pattern string, h http.Handler) {
+	r.config.methods[method+" "+r.prefix+pattern] = true
+	r.mux.Method(method, pattern, h)
+}
+
+// suppressHeadBody discards the response body of HEAD requests, keeping
+// the Content-Length the handler's body would have had.
+func suppressHeadBody(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodHead {
+			h.ServeHTTP(w, req)
+			return
+		}
+		hw := &headWriter{ResponseWriter: w}
+		h.ServeHTTP(hw, req)
+		hw.finish()
+	})
+}
+
+// headWriter counts and drops the body, deferring the header write so
+// Content-Length can be set once the handler returns.
+type headWriter struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+func (w *headWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+}
+
+func (w *headWriter) Write(b []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	w.size += len(b)
+	return len(b), nil
+}
+
+func (w *headWriter) finish() {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if w.Header().Get("Content-Length") == "" {
+		w.Header().Set("Content-Length", strconv.Itoa(w.size))
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+}
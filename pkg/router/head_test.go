@@ -0,0 +1,143 @@
+package router
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/a-h/templ"
+)
+
+func TestAnyRoute(t *testing.T) {
+	r := New()
+	r.Any("/echo", func(ctx *Context) (string, error) {
+		return ctx.Request.Method, nil
+	})
+
+	for _, method := range []string{"GET", "POST", "PATCH", "OPTIONS"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, "/echo", nil))
+		if w.Code != http.StatusOK || w.Body.String() != method {
+			t.Errorf("%s: got %d %q", method, w.Code, w.Body.String())
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("HEAD", "/echo", nil))
+	if w.Body.Len() != 0 || w.Header().Get("Content-Length") != "4" {
+		t.Errorf("HEAD: expected empty body with Content-Length 4, got %q %q", w.Body.String(), w.Header().Get("Content-Length"))
+	}
+}
+
+func TestAnyRouteKeepsSpecificMethods(t *testing.T) {
+	r := New()
+	r.GET("/echo", func(ctx *Context) (string, error) {
+		return "get", nil
+	})
+	r.Any("/echo", func(ctx *Context) (string, error) {
+		return "any", nil
+	})
+	r.POST("/echo", func(ctx *Context) (string, error) {
+		return "post", nil
+	})
+
+	for method, want := range map[string]string{"GET": "get", "POST": "post", "PUT": "any"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, "/echo", nil))
+		if w.Body.String() != want {
+			t.Errorf("%s: body = %q, want %q", method, w.Body.String(), want)
+		}
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("HEAD", "/echo", nil))
+	if got := w.Header().Get("Content-Length"); got != "3" {
+		t.Errorf("HEAD: Content-Length = %q, want GET's 3", got)
+	}
+}
+
+func TestHeadFromGet(t *testing.T) {
+	r := New()
+	calls := 0
+	r.GET("/page", func(ctx *Context) (string, error) {
+		calls++
+		return "<p>hello</p>", nil
+	}).Cache("static")
+
+	get := httptest.NewRecorder()
+	r.ServeHTTP(get, httptest.NewRequest("GET", "/page", nil))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("HEAD", "/page", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("expected empty body, got %q", w.Body.String())
+	}
+	if got := w.Header().Get("Content-Length"); got != "12" {
+		t.Errorf("expected Content-Length 12, got %q", got)
+	}
+	for _, h := range []string{"Content-Type", "Cache-Control"} {
+		if w.Header().Get(h) != get.Header().Get(h) {
+			t.Errorf("expected %s %q, got %q", h, get.Header().Get(h), w.Header().Get(h))
+		}
+	}
+	if calls != 2 {
+		t.Errorf("expected GET handler to serve HEAD, got %d calls", calls)
+	}
+
+	// Errors keep their status
+	r.GET("/missing", func(ctx *Context) (string, error) {
+		return "", ErrNotFound("no such page")
+	})
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("HEAD", "/missing", nil))
+	if w.Code != http.StatusNotFound || w.Body.Len() != 0 {
+		t.Errorf("expected empty 404, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestExplicitHead(t *testing.T) {
+	r := New()
+	r.HEAD("/page", func(ctx *Context) (string, error) {
+		ctx.SetHeader("X-Head", "1")
+		return "", nil
+	})
+	r.GET("/page", func(ctx *Context) (string, error) {
+		return "<p>hello</p>", nil
+	})
+	r.OPTIONS("/page", func(ctx *Context) (string, error) {
+		ctx.SetHeader("Allow", "GET, HEAD, OPTIONS")
+		return "", nil
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("HEAD", "/page", nil))
+	if w.Header().Get("X-Head") != "1" {
+		t.Error("expected explicit HEAD handler to be kept")
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("OPTIONS", "/page", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Allow") != "GET, HEAD, OPTIONS" {
+		t.Errorf("OPTIONS: got %d %q", w.Code, w.Header().Get("Allow"))
+	}
+}
+
+func TestHeadComponent(t *testing.T) {
+	r := New()
+	r.GETC("/c", func(ctx *Context) (templ.Component, error) {
+		return templ.ComponentFunc(func(_ context.Context, w io.Writer) error {
+			_, err := io.WriteString(w, "<b>hi</b>")
+			return err
+		}), nil
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("HEAD", "/c", nil))
+	if w.Code != http.StatusOK || w.Body.Len() != 0 || w.Header().Get("Content-Length") != "9" {
+		t.Errorf("expected empty 200 with Content-Length 9, got %d %q %q", w.Code, w.Body.String(), w.Header().Get("Content-Length"))
+	}
+}
@@ -0,0 +1,43 @@
+package router
+
+import (
+	"net/http"
+	"strings"
+)
+
+// IsBoostedRequest returns true if the request came from an element
+// using hx-boost, which expects a full page.
+func IsBoostedRequest(r *http.Request) bool {
+	return r.Header.Get("HX-Boosted") == "true"
+}
+
+// IsHistoryRestoreRequest returns true if HTMX is restoring a page
+// missing from its history cache, which expects a full page.
+func IsHistoryRestoreRequest(r *http.Request) bool {
+	return r.Header.Get("HX-History-Restore-Request") == "true"
+}
+
+// IsBoosted returns true if the request came from an hx-boost link or
+// form. The response varies on HX-Boosted.
+func (c *Context) IsBoosted() bool {
+	AddVary(c.Response.Header(), "HX-Boosted")
+	return IsBoostedRequest(c.Request)
+}
+
+// IsHistoryRestore returns true if HTMX is restoring history after a
+// cache miss. The response varies on HX-History-Restore-Request.
+func (c *Context) IsHistoryRestore() bool {
+	AddVary(c.Response.Header(), "HX-History-Restore-Request")
+	return IsHistoryRestoreRequest(c.Request)
+}
+
+// Trigger fires events on the client through the HX-Trigger header, and
+// purges response cache entries tagged with them (see
+// ResponseCacheMiddleware).
+func (c *Context) Trigger(events ...string) {
+	h := c.Response.Header()
+	if existing := h.Get("HX-Trigger"); existing != "" {
+		events = append([]string{existing}, events...)
+	}
+	h.Set("HX-Trigger", strings.Join(events, ", "))
+}
@@ -0,0 +1,94 @@
+package router
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestContextIsBoosted(t *testing.T) {
+	r := New()
+	r.GET("/page", func(ctx *Context) (string, error) {
+		if ctx.IsBoosted() {
+			return "boosted", nil
+		}
+		if ctx.IsHistoryRestore() {
+			return "restore", nil
+		}
+		return "plain", nil
+	})
+
+	tests := []struct {
+		header, want, vary string
+	}{
+		{"", "plain", "HX-Boosted, HX-History-Restore-Request"},
+		{"HX-Boosted", "boosted", "HX-Boosted"},
+		{"HX-History-Restore-Request", "restore", "HX-Boosted, HX-History-Restore-Request"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/page", nil)
+		req.Header.Set("HX-Request", "true")
+		if tt.header != "" {
+			req.Header.Set(tt.header, "true")
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Body.String() != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.header, tt.want, w.Body.String())
+		}
+		if got := w.Header().Get("Vary"); got != tt.vary {
+			t.Errorf("%s: expected Vary %q, got %q", tt.header, tt.vary, got)
+		}
+	}
+}
+
+func TestLayoutWrapperBoosted(t *testing.T) {
+	tests := []struct {
+		name                  string
+		hxRequest, hxBoosted  bool
+		historyRestore        bool
+		wrapBoosted, wantPage bool
+	}{
+		{name: "navigation", wrapBoosted: true, wantPage: true},
+		{name: "boosted only", hxBoosted: true, wrapBoosted: true, wantPage: true},
+		{name: "htmx", hxRequest: true, wrapBoosted: true, wantPage: false},
+		{name: "htmx boosted", hxRequest: true, hxBoosted: true, wrapBoosted: true, wantPage: true},
+		{name: "history restore", hxRequest: true, historyRestore: true, wrapBoosted: true, wantPage: true},
+		{name: "boosted not wrapped", hxRequest: true, hxBoosted: true, wantPage: false},
+		{name: "restore not wrapped", hxRequest: true, historyRestore: true, wantPage: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := New()
+			lw := &LayoutWrapper{
+				Layout:      func(content string) string { return "<html>" + content + "</html>" },
+				WrapBoosted: tt.wrapBoosted,
+			}
+			r.Use(lw.Wrap)
+			r.GET("/todos", func(ctx *Context) (string, error) {
+				return "<ul></ul>", nil
+			})
+
+			req := httptest.NewRequest("GET", "/todos", nil)
+			if tt.hxRequest {
+				req.Header.Set("HX-Request", "true")
+			}
+			if tt.hxBoosted {
+				req.Header.Set("HX-Boosted", "true")
+			}
+			if tt.historyRestore {
+				req.Header.Set("HX-History-Restore-Request", "true")
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			want := "<ul></ul>"
+			if tt.wantPage {
+				want = "<html><ul></ul></html>"
+			}
+			if w.Body.String() != want {
+				t.Errorf("expected %q, got %q", want, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package router
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/stukennedy/irgo/pkg/i18n"
+)
+
+const (
+	// LocaleParam is the query parameter that switches the request's
+	// locale, e.g. "?lang=fr". The choice is remembered in LocaleCookieName.
+	LocaleParam = "lang"
+
+	// LocaleCookieName is the cookie remembering a locale chosen with
+	// LocaleParam.
+	LocaleCookieName = "irgo_lang"
+
+	// localeCookieTTL is how long a chosen locale is remembered.
+	localeCookieTTL = 365 * 24 * time.Hour
+)
+
+// I18nMiddleware picks each request's locale from bundle and stores its
+// localizer in the request context for Context.T and the t template
+// function. The locale comes from, in order: a supported ?lang= parameter
+// (remembered in a cookie), the cookie, the Accept-Language header, and
+// the bundle's default locale.
+func I18nMiddleware(bundle *i18n.Bundle) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			locale := requestLocale(w, r, bundle)
+			w.Header().Set("Content-Language", locale)
+			// Responses differ by language, so keep caches from mixing them
+			w.Header().Add("Vary", "Accept-Language")
+			w.Header().Add("Vary", "Cookie")
+			ctx := i18n.WithContext(r.Context(), bundle.Localizer(locale))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// requestLocale resolves the request's locale, setting the cookie when
+// ?lang= chooses one.
+func requestLocale(w http.ResponseWriter, r *http.Request, bundle *i18n.Bundle) string {
+	if lang := r.URL.Query().Get(LocaleParam); lang != "" {
+		if locale, ok := bundle.Supported(lang); ok {
+			http.SetCookie(w, newCookie(LocaleCookieName, locale, []CookieOption{CookieMaxAge(localeCookieTTL)}))
+			return locale
+		}
+	}
+	if c, err := r.Cookie(LocaleCookieName); err == nil {
+		if locale, ok := bundle.Supported(c.Value); ok {
+			return locale
+		}
+	}
+	return bundle.Match(r.Header.Get("Accept-Language"))
+}
+
+// T translates key into the request's locale (see i18n.Bundle.Translate).
+// Without I18nMiddleware it returns the key.
+//
+//	ctx.T("todos.count", len(todos))
+func (c *Context) T(key string, args ...any) string {
+	return i18n.T(c.Context(), key, args...)
+}
+
+// Locale returns the request's locale chosen by I18nMiddleware, or "" if
+// the middleware isn't installed.
+func (c *Context) Locale() string {
+	if l, ok := i18n.FromContext(c.Context()); ok {
+		return l.Locale()
+	}
+	return ""
+}
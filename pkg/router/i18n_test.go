@@ -0,0 +1,72 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stukennedy/irgo/pkg/i18n"
+)
+
+func i18nRouter() *Router {
+	b := i18n.NewBundle("en")
+	b.Add("en", map[string]string{"hello": "Hello", "todos.one": "%d todo", "todos.other": "%d todos"})
+	b.Add("fr", map[string]string{"hello": "Bonjour"})
+
+	r := New()
+	r.Use(I18nMiddleware(b))
+	r.GET("/", func(ctx *Context) (string, error) {
+		return ctx.Locale() + ": " + ctx.T("hello") + ", " + ctx.T("todos", 1), nil
+	})
+	return r
+}
+
+func TestI18nMiddleware(t *testing.T) {
+	r := i18nRouter()
+	tests := []struct {
+		name, target, cookie, accept string
+		want, setCookie              string
+	}{
+		{"default", "/", "", "", "en: Hello, 1 todo", ""},
+		{"accept-language", "/", "", "fr-CA,en;q=0.5", "fr: Bonjour, 1 todo", ""},
+		{"unsupported language", "/", "", "de", "en: Hello, 1 todo", ""},
+		{"cookie beats header", "/", "fr", "en", "fr: Bonjour, 1 todo", ""},
+		{"query beats cookie", "/?lang=en", "fr", "fr", "en: Hello, 1 todo", "en"},
+		{"query sets cookie", "/?lang=FR", "", "", "fr: Bonjour, 1 todo", "fr"},
+		{"unsupported query", "/?lang=xx", "fr", "", "fr: Bonjour, 1 todo", ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.target, nil)
+		if tt.cookie != "" {
+			req.AddCookie(&http.Cookie{Name: LocaleCookieName, Value: tt.cookie})
+		}
+		if tt.accept != "" {
+			req.Header.Set("Accept-Language", tt.accept)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if got := w.Body.String(); got != tt.want {
+			t.Errorf("%s: body = %q, want %q", tt.name, got, tt.want)
+		}
+		var set string
+		for _, c := range w.Result().Cookies() {
+			if c.Name == LocaleCookieName {
+				set = c.Value
+			}
+		}
+		if set != tt.setCookie {
+			t.Errorf("%s: locale cookie = %q, want %q", tt.name, set, tt.setCookie)
+		}
+	}
+}
+
+func TestI18nWithoutMiddleware(t *testing.T) {
+	ctx := NewContext(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if got := ctx.T("hello"); got != "hello" {
+		t.Errorf("T() = %q, want the key", got)
+	}
+	if got := ctx.Locale(); got != "" {
+		t.Errorf("Locale() = %q, want empty", got)
+	}
+}
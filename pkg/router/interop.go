@@ -0,0 +1,85 @@
+package router
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// AsMiddleware lets an existing service adopt the router for part of its
+// URL space. Requests under prefix that match a route are served by the
+// router, with patterns relative to prefix; everything else, including
+// paths that match no route, falls through to the wrapped handler instead
+// of the router's 404:
+//
+//	mux := http.NewServeMux()
+//	mux.HandleFunc("/", legacy)
+//	http.ListenAndServe(":8080", r.AsMiddleware("/app")(mux))
+//
+// The request's context values are kept, and URL params matched by an
+// enclosing chi router stay readable with Context.Param.
+func (r *Router) AsMiddleware(prefix string) func(http.Handler) http.Handler {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			routePath, ok := stripPrefix(req, prefix)
+			if !ok || !r.mux.Match(chi.NewRouteContext(), req.Method, routePath) {
+				next.ServeHTTP(w, req)
+				return
+			}
+
+			rctx := chi.NewRouteContext()
+			rctx.RoutePath = routePath
+			if outer := chi.RouteContext(req.Context()); outer != nil {
+				for i, key := range outer.URLParams.Keys {
+					rctx.URLParams.Add(key, outer.URLParams.Values[i])
+				}
+			}
+			r.mux.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx)))
+		})
+	}
+}
+
+// stripPrefix returns the request path below prefix, matching whole
+// segments only, so "/app" matches "/app" and "/app/x" but not "/apple".
+func stripPrefix(req *http.Request, prefix string) (string, bool) {
+	p := req.URL.RawPath
+	if p == "" {
+		p = req.URL.Path
+	}
+	rest, ok := strings.CutPrefix(p, prefix)
+	if !ok || (rest != "" && rest[0] != '/') {
+		return "", false
+	}
+	if rest == "" {
+		rest = "/"
+	}
+	return rest, true
+}
+
+// MountExternal attaches a handler tree from another framework (chi, echo,
+// gin, a plain http.ServeMux) at pattern, wrapped in middlewares. Unlike
+// Mount, the handler sees the request path relative to pattern, since
+// foreign routers match on URL.Path rather than chi's route context.
+// Middleware added with Use still runs first.
+func (r *Router) MountExternal(pattern string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) {
+	h := chi.Chain(middlewares...).Handler(handler)
+	r.mux.Mount(pattern, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		// Mount has moved the remainder from the wildcard to RoutePath,
+		// which is still escaped when the request has a RawPath
+		rest := chi.RouteContext(req.Context()).RoutePath
+		req2 := req.Clone(req.Context())
+		req2.URL.Path = rest
+		req2.URL.RawPath = ""
+		if req.URL.RawPath != "" {
+			if u, err := url.PathUnescape(rest); err == nil {
+				req2.URL.Path = u
+				req2.URL.RawPath = rest
+			}
+		}
+		h.ServeHTTP(w, req2)
+	}))
+}
@@ -0,0 +1,155 @@
+package router
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// trace returns middleware appending name to the X-Trace response header.
+func trace(name string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Add("X-Trace", name)
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+func serve(h http.Handler, method, target string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+	return w
+}
+
+func TestAsMiddlewareServeMux(t *testing.T) {
+	r := New()
+	r.GET("/todos/{id}", func(ctx *Context) (string, error) {
+		return "todo " + ctx.Param("id"), nil
+	})
+	r.GET("/", func(ctx *Context) (string, error) {
+		return "app home", nil
+	})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, req *http.Request) {
+		io.WriteString(w, "legacy "+req.URL.Path)
+	})
+	h := r.AsMiddleware("/app")(mux)
+
+	tests := []struct {
+		method, target, want string
+	}{
+		{"GET", "/app/todos/7", "todo 7"},
+		{"GET", "/app", "app home"},
+		{"GET", "/app/", "app home"},
+		{"GET", "/app/missing", "legacy /app/missing"},
+		{"POST", "/app/todos/7", "legacy /app/todos/7"},
+		{"GET", "/apple", "legacy /apple"},
+		{"GET", "/todos/7", "legacy /todos/7"},
+	}
+	for _, tt := range tests {
+		w := serve(h, tt.method, tt.target)
+		if w.Code != http.StatusOK || w.Body.String() != tt.want {
+			t.Errorf("%s %s: got %d %q, want %q", tt.method, tt.target, w.Code, w.Body.String(), tt.want)
+		}
+	}
+}
+
+func TestAsMiddlewareChi(t *testing.T) {
+	type ctxKey struct{}
+
+	r := New()
+	r.Use(trace("inner"))
+	r.GET("/items/{item}", func(ctx *Context) (string, error) {
+		user, _ := ctx.Context().Value(ctxKey{}).(string)
+		return ctx.Param("org") + "/" + ctx.Param("item") + " for " + user, nil
+	})
+
+	outer := chi.NewRouter()
+	outer.Use(trace("outer"))
+	outer.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			next.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), ctxKey{}, "ada")))
+		})
+	})
+	outer.With(r.AsMiddleware("/orgs/acme")).Get("/orgs/{org}/*", func(w http.ResponseWriter, req *http.Request) {
+		io.WriteString(w, "outer "+chi.URLParam(req, "org"))
+	})
+
+	w := serve(outer, "GET", "/orgs/acme/items/42")
+	if got := w.Body.String(); got != "acme/42 for ada" {
+		t.Errorf("unexpected body %q", got)
+	}
+	if got := strings.Join(w.Header().Values("X-Trace"), ","); got != "outer,inner" {
+		t.Errorf("expected outer,inner middleware order, got %q", got)
+	}
+
+	w = serve(outer, "GET", "/orgs/acme/other")
+	if got := w.Body.String(); got != "outer acme" {
+		t.Errorf("expected fallthrough, got %q", got)
+	}
+	if got := strings.Join(w.Header().Values("X-Trace"), ","); got != "outer" {
+		t.Errorf("router middleware ran on fallthrough: %q", got)
+	}
+}
+
+func TestMountExternal(t *testing.T) {
+	legacy := http.NewServeMux()
+	legacy.HandleFunc("/reports/{year}", func(w http.ResponseWriter, req *http.Request) {
+		io.WriteString(w, "report "+req.PathValue("year")+" at "+req.URL.Path)
+	})
+
+	r := New()
+	r.Use(trace("router"))
+	r.MountExternal("/legacy", legacy, trace("mount"))
+	r.GET("/", func(ctx *Context) (string, error) {
+		return "home", nil
+	})
+
+	w := serve(r, "GET", "/legacy/reports/2024")
+	if got := w.Body.String(); got != "report 2024 at /reports/2024" {
+		t.Errorf("unexpected body %q", got)
+	}
+	if got := strings.Join(w.Header().Values("X-Trace"), ","); got != "router,mount" {
+		t.Errorf("expected router,mount middleware order, got %q", got)
+	}
+
+	if w := serve(r, "GET", "/"); w.Body.String() != "home" {
+		t.Errorf("router route shadowed: %q", w.Body.String())
+	}
+}
+
+func TestMountExternalChi(t *testing.T) {
+	api := chi.NewRouter()
+	api.Get("/users/{id}", func(w http.ResponseWriter, req *http.Request) {
+		io.WriteString(w, "user "+chi.URLParam(req, "id"))
+	})
+
+	r := New()
+	r.MountExternal("/api", api)
+
+	if w := serve(r, "GET", "/api/users/3"); w.Body.String() != "user 3" {
+		t.Errorf("unexpected body %q", w.Body.String())
+	}
+}
+
+func TestMountExternalEscapedPath(t *testing.T) {
+	legacy := http.NewServeMux()
+	legacy.HandleFunc("/", func(w http.ResponseWriter, req *http.Request) {
+		io.WriteString(w, req.URL.Path+" "+req.URL.EscapedPath())
+	})
+
+	r := New()
+	r.MountExternal("/legacy", legacy)
+
+	w := serve(r, "GET", "/legacy/a%2Fb/c")
+	if got := w.Body.String(); got != "/a/b/c /a%2Fb/c" {
+		t.Errorf("unexpected body %q", got)
+	}
+}
@@ -0,0 +1,66 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// location is the JSON form of the HX-Location header.
+type location struct {
+	Path   string         `json:"path"`
+	Target string         `json:"target,omitempty"`
+	Swap   string         `json:"swap,omitempty"`
+	Values map[string]any `json:"values,omitempty"`
+	Select string         `json:"select,omitempty"`
+}
+
+// LocationOption configures a client-side navigation made with Location.
+type LocationOption func(*location)
+
+// WithTarget sets the CSS selector the response is swapped into.
+func WithTarget(selector string) LocationOption {
+	return func(l *location) { l.Target = selector }
+}
+
+// WithSwap sets the swap strategy, e.g. "outerHTML".
+func WithSwap(swap string) LocationOption {
+	return func(l *location) { l.Swap = swap }
+}
+
+// WithValues sets values submitted with the request.
+func WithValues(values map[string]any) LocationOption {
+	return func(l *location) { l.Values = values }
+}
+
+// WithSelect sets the CSS selector picking content out of the response.
+func WithSelect(selector string) LocationOption {
+	return func(l *location) { l.Select = selector }
+}
+
+// Location navigates an HTMX client to path without a full page reload,
+// using the HX-Location header. The header is the bare path when no
+// options are given and JSON otherwise. Non-HTMX requests get a 303
+// redirect instead.
+func (c *Context) Location(path string, opts ...LocationOption) {
+	if c.Header("HX-Request") != "true" {
+		c.Redirect(path)
+		return
+	}
+
+	value := path
+	if len(opts) > 0 {
+		l := location{Path: path}
+		for _, opt := range opts {
+			opt(&l)
+		}
+		data, err := json.Marshal(l)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		value = string(data)
+	}
+	c.written = true
+	c.Response.Header().Set("HX-Location", value)
+	c.Response.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,126 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLocationBarePath(t *testing.T) {
+	r := New()
+	r.POST("/save", func(ctx *Context) (string, error) {
+		ctx.Location("/todos")
+		return "", nil
+	})
+
+	req := httptest.NewRequest("POST", "/save", nil)
+	req.Header.Set("HX-Request", "true")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+	if got := w.Header().Get("HX-Location"); got != "/todos" {
+		t.Errorf("expected HX-Location='/todos', got %q", got)
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("expected empty body, got %q", w.Body.String())
+	}
+}
+
+func TestLocationOptions(t *testing.T) {
+	r := New()
+	r.POST("/save", func(ctx *Context) (string, error) {
+		ctx.Location("/todos",
+			WithTarget("#main"),
+			WithSwap("outerHTML"),
+			WithValues(map[string]any{"page": 2, "filter": "done"}),
+			WithSelect("#list"),
+		)
+		return "", nil
+	})
+
+	req := httptest.NewRequest("POST", "/save", nil)
+	req.Header.Set("HX-Request", "true")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var got struct {
+		Path   string         `json:"path"`
+		Target string         `json:"target"`
+		Swap   string         `json:"swap"`
+		Values map[string]any `json:"values"`
+		Select string         `json:"select"`
+	}
+	if err := json.Unmarshal([]byte(w.Header().Get("HX-Location")), &got); err != nil {
+		t.Fatalf("decoding HX-Location %q: %v", w.Header().Get("HX-Location"), err)
+	}
+	if got.Path != "/todos" || got.Target != "#main" || got.Swap != "outerHTML" || got.Select != "#list" {
+		t.Errorf("unexpected location: %+v", got)
+	}
+	if got.Values["page"] != float64(2) || got.Values["filter"] != "done" {
+		t.Errorf("unexpected values: %v", got.Values)
+	}
+}
+
+func TestLocationOmitsUnsetOptions(t *testing.T) {
+	r := New()
+	r.POST("/save", func(ctx *Context) (string, error) {
+		ctx.Location("/todos", WithTarget("#main"))
+		return "", nil
+	})
+
+	req := httptest.NewRequest("POST", "/save", nil)
+	req.Header.Set("HX-Request", "true")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var got map[string]any
+	if err := json.Unmarshal([]byte(w.Header().Get("HX-Location")), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got["path"] != "/todos" || got["target"] != "#main" {
+		t.Errorf("unexpected location: %v", got)
+	}
+}
+
+func TestLocationNonHTMX(t *testing.T) {
+	r := New()
+	r.POST("/save", func(ctx *Context) (string, error) {
+		ctx.Location("/todos", WithTarget("#main"))
+		return "", nil
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/save", nil))
+
+	if w.Code != http.StatusSeeOther {
+		t.Errorf("expected status 303, got %d", w.Code)
+	}
+	if w.Header().Get("Location") != "/todos" {
+		t.Errorf("expected Location='/todos', got %q", w.Header().Get("Location"))
+	}
+	if w.Header().Get("HX-Location") != "" {
+		t.Error("non-HTMX request should not get HX-Location")
+	}
+}
+
+func TestRedirectStatus(t *testing.T) {
+	r := New()
+	r.GET("/old", func(ctx *Context) (string, error) {
+		ctx.Redirect("/new", http.StatusMovedPermanently)
+		return "", nil
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/old", nil))
+
+	if w.Code != http.StatusMovedPermanently {
+		t.Errorf("expected status 301, got %d", w.Code)
+	}
+	if w.Header().Get("Location") != "/new" {
+		t.Errorf("expected Location='/new', got %q", w.Header().Get("Location"))
+	}
+}
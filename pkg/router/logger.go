@@ -0,0 +1,93 @@
+package router
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// LoggerOption configures LoggerMiddleware.
+type LoggerOption func(*loggerConfig)
+
+type loggerConfig struct {
+	skip []string
+}
+
+// WithSkipPaths stops LoggerMiddleware logging matching requests. A path
+// ending in "/" skips everything under it, e.g. "/static/"; others must
+// match exactly, e.g. "/healthz".
+func WithSkipPaths(paths ...string) LoggerOption {
+	return func(c *loggerConfig) {
+		c.skip = append(c.skip, paths...)
+	}
+}
+
+// LoggerMiddleware logs one record per request with its method, path,
+// status, duration, bytes written, HX-Request flag and chi request ID.
+// Server errors log at Error, client errors at Warn, the rest at Info.
+// A nil logger uses slog.Default(). Register it after middleware.RequestID
+// (which New installs) so the ID is set; streaming SSE responses still
+// flush.
+func LoggerMiddleware(logger *slog.Logger, opts ...LoggerOption) func(http.Handler) http.Handler {
+	cfg := &loggerConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if cfg.skipped(r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			start := time.Now()
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			level := slog.LevelInfo
+			switch {
+			case status >= 500:
+				level = slog.LevelError
+			case status >= 400:
+				level = slog.LevelWarn
+			}
+
+			l := logger
+			if l == nil {
+				l = slog.Default()
+			}
+			l.LogAttrs(r.Context(), level, "request",
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", status),
+				slog.Duration("duration", time.Since(start)),
+				slog.Int("bytes", ww.BytesWritten()),
+				slog.Bool("hx_request", r.Header.Get("HX-Request") == "true"),
+				slog.String("request_id", middleware.GetReqID(r.Context())),
+			)
+		})
+	}
+}
+
+func (c *loggerConfig) skipped(path string) bool {
+	return matchPaths(c.skip, path)
+}
+
+// matchPaths reports whether path equals one of paths or falls under one
+// ending in "/".
+func matchPaths(paths []string, path string) bool {
+	for _, p := range paths {
+		if path == p || (strings.HasSuffix(p, "/") && strings.HasPrefix(path, p)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package router
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// recordHandler is a slog.Handler that keeps every record it receives.
+type recordHandler struct {
+	mu      sync.Mutex
+	records []slog.Record
+}
+
+func (h *recordHandler) Enabled(context.Context, slog.Level) bool { return true }
+func (h *recordHandler) WithAttrs([]slog.Attr) slog.Handler       { return h }
+func (h *recordHandler) WithGroup(string) slog.Handler            { return h }
+
+func (h *recordHandler) Handle(_ context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records = append(h.records, r)
+	return nil
+}
+
+func (h *recordHandler) attrs(i int) map[string]slog.Value {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	m := make(map[string]slog.Value)
+	h.records[i].Attrs(func(a slog.Attr) bool {
+		m[a.Key] = a.Value
+		return true
+	})
+	return m
+}
+
+func TestLoggerMiddleware(t *testing.T) {
+	h := &recordHandler{}
+	r := New()
+	r.Use(LoggerMiddleware(slog.New(h), WithSkipPaths("/healthz", "/static/")))
+	r.GET("/todos", func(ctx *Context) (string, error) {
+		return "<ul></ul>", nil
+	})
+	r.GET("/missing", func(ctx *Context) (string, error) {
+		return "", ErrNotFound("gone")
+	})
+	r.GET("/healthz", func(ctx *Context) (string, error) { return "ok", nil })
+	r.GET("/static/*", func(ctx *Context) (string, error) { return "css", nil })
+
+	req := httptest.NewRequest("GET", "/todos", nil)
+	req.Header.Set("HX-Request", "true")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/missing", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/healthz", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/static/app.css", nil))
+
+	if len(h.records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(h.records))
+	}
+
+	rec := h.records[0]
+	if rec.Message != "request" || rec.Level != slog.LevelInfo {
+		t.Errorf("got %q at %v", rec.Message, rec.Level)
+	}
+	a := h.attrs(0)
+	if a["method"].String() != "GET" || a["path"].String() != "/todos" {
+		t.Errorf("method/path = %v %v", a["method"], a["path"])
+	}
+	if a["status"].Int64() != 200 || a["bytes"].Int64() != int64(len("<ul></ul>")) {
+		t.Errorf("status/bytes = %v %v", a["status"], a["bytes"])
+	}
+	if !a["hx_request"].Bool() {
+		t.Error("hx_request not set")
+	}
+	if a["request_id"].String() == "" {
+		t.Error("request_id empty")
+	}
+	if _, ok := a["duration"]; !ok {
+		t.Error("duration missing")
+	}
+
+	if h.records[1].Level != slog.LevelWarn {
+		t.Errorf("404 logged at %v, want WARN", h.records[1].Level)
+	}
+	if a := h.attrs(1); a["status"].Int64() != 404 || a["hx_request"].Bool() {
+		t.Errorf("404 attrs = %v", a)
+	}
+}
+
+func TestLoggerMiddlewareFlushes(t *testing.T) {
+	h := &recordHandler{}
+	r := New()
+	r.Use(LoggerMiddleware(slog.New(h)))
+
+	flushable := false
+	r.DSGet("/stream", func(ctx *Context) error {
+		_, flushable = ctx.Response.(http.Flusher)
+		return ctx.SSE().PatchSignals(map[string]int{"n": 1})
+	})
+
+	req := httptest.NewRequest("GET", "/stream", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if !flushable {
+		t.Error("wrapped writer lost http.Flusher")
+	}
+	if !w.Flushed {
+		t.Error("SSE response not flushed")
+	}
+	if len(h.records) != 1 || h.attrs(0)["bytes"].Int64() == 0 {
+		t.Errorf("SSE request not logged with bytes: %d records", len(h.records))
+	}
+}
@@ -1,8 +1,15 @@
 package router
 
 import (
+	"bufio"
 	"context"
+	"html/template"
+	"net"
 	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/stukennedy/irgo/pkg/render"
 )
 
 // contextKey is used for context values.
@@ -31,31 +38,119 @@ func IsDatastarRequest(r *http.Request) bool {
 	return r.Header.Get("Accept") == "text/event-stream"
 }
 
+// NoJSMiddleware detects clients whose JavaScript bundle failed to load,
+// as reported by the render.NoJSDetector snippet, and flags the request
+// context so render.Engine.RenderContext emits plain links and forms.
+//
+// The ?__nojs=1 query parameter (from the noscript fallback) sets the
+// irgo-nojs cookie; ?__nojs=0 clears it once JavaScript works again.
+func NoJSMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		noJS := false
+		if c, err := r.Cookie(render.NoJSCookie); err == nil && c.Value == "1" {
+			noJS = true
+		}
+
+		switch r.URL.Query().Get(render.NoJSQuery) {
+		case "1":
+			noJS = true
+			http.SetCookie(w, &http.Cookie{Name: render.NoJSCookie, Value: "1", Path: "/", MaxAge: 3600})
+		case "0":
+			noJS = false
+			http.SetCookie(w, &http.Cookie{Name: render.NoJSCookie, Value: "", Path: "/", MaxAge: -1})
+		}
+
+		if noJS {
+			r = r.WithContext(render.WithNoJS(r.Context()))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// IsNoJSRequest returns true if the request was flagged by NoJSMiddleware.
+func IsNoJSRequest(r *http.Request) bool {
+	return render.IsNoJS(r.Context())
+}
+
+// TemplateFuncsMiddleware attaches request-scoped template funcs, such as
+// the signed-in user or the request's CSRF token, to each request's
+// context. render.Engine.RenderContext renders with them:
+//
+//	r.Use(router.TemplateFuncsMiddleware(func(req *http.Request) template.FuncMap {
+//		user := auth.UserFrom(req)
+//		return template.FuncMap{"currentUser": func() *User { return user }}
+//	}))
+//
+// The names must also be registered on the engine, if only as
+// placeholders, for templates using them to parse.
+func TemplateFuncsMiddleware(funcs func(*http.Request) template.FuncMap) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if fm := funcs(r); len(fm) > 0 {
+				r = r.WithContext(render.WithFuncs(r.Context(), fm))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // LayoutWrapper wraps fragment responses in a full page layout
-// when the request is not from Datastar (direct browser navigation).
+// when the request is not from Datastar or HTMX (direct browser navigation).
+// Only successful HTML responses are wrapped. Routes opt out with Exclude,
+// the SkipLayout middleware or Context.SkipLayout.
 type LayoutWrapper struct {
 	Layout func(content string) string
+
+	// LayoutRequest is used instead of Layout when set, so the page can
+	// depend on the request, e.g. its title or the active nav item.
+	LayoutRequest func(r *http.Request, content string) string
+
+	// WrapBoosted wraps HTMX requests from hx-boost elements and history
+	// restores too, since both swap in a full page.
+	WrapBoosted bool
+
+	// Exclude lists paths that are never wrapped. A path ending in "/"
+	// excludes everything under it, e.g. "/api/"; others must match
+	// exactly.
+	Exclude []string
 }
 
-// Wrap returns middleware that wraps non-Datastar responses in a layout.
+// Wrap returns middleware that wraps non-Datastar, non-HTMX responses in
+// a layout.
 func (l *LayoutWrapper) Wrap(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if IsDatastarRequest(r) || l.Layout == nil {
+		AddVary(w.Header(), "Accept", "HX-Request")
+		if l.WrapBoosted {
+			AddVary(w.Header(), "HX-Boosted", "HX-History-Restore-Request")
+		}
+		if (IsDatastarRequest(r) && !IsNoJSRequest(r)) || !l.fullPage(r) ||
+			(l.Layout == nil && l.LayoutRequest == nil) || matchPaths(l.Exclude, r.URL.Path) {
 			next.ServeHTTP(w, r)
 			return
 		}
 
 		// Capture the response
+		state := &layoutState{}
+		r = r.WithContext(context.WithValue(r.Context(), layoutStateKey{}, state))
 		rec := &responseRecorder{
 			ResponseWriter: w,
 			statusCode:     http.StatusOK,
 		}
 		next.ServeHTTP(rec, r)
+		if rec.passthrough {
+			return
+		}
 
-		// If it's HTML and not an error, wrap in layout
+		// If it's a successful HTML page, wrap in layout
 		contentType := rec.Header().Get("Content-Type")
-		if rec.statusCode < 400 && isHTML(contentType) {
-			wrapped := l.Layout(string(rec.body))
+		ok := rec.statusCode >= 200 && rec.statusCode < 300 && rec.statusCode != http.StatusNoContent
+		if ok && isHTML(contentType) && !state.skip.Load() {
+			var wrapped string
+			if l.LayoutRequest != nil {
+				wrapped = l.LayoutRequest(r, string(rec.body))
+			} else {
+				wrapped = l.Layout(string(rec.body))
+			}
 			w.Header().Set("Content-Type", "text/html; charset=utf-8")
 			w.WriteHeader(rec.statusCode)
 			w.Write([]byte(wrapped))
@@ -67,28 +162,157 @@ func (l *LayoutWrapper) Wrap(next http.Handler) http.Handler {
 	})
 }
 
-// responseRecorder captures the response for post-processing.
+// layoutState lets handlers behind LayoutWrapper opt out of wrapping.
+type layoutState struct {
+	skip atomic.Bool
+}
+
+type layoutStateKey struct{}
+
+// SkipLayout is middleware that stops LayoutWrapper, and layouts set with
+// Router.SetLayout, wrapping the routes it's applied to, e.g. fragment
+// endpoints fetched from custom JS:
+//
+//	r.With(router.SkipLayout).GET("/partials/cart", cartFragment)
+func SkipLayout(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Value(layoutStateKey{}).(*layoutState); !ok {
+			// No LayoutWrapper; leave the flag for a SetLayout layout
+			r = r.WithContext(context.WithValue(r.Context(), layoutStateKey{}, &layoutState{}))
+		}
+		skipLayout(r)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// SkipLayout stops LayoutWrapper, or the layout set with
+// Router.SetLayout, wrapping this response.
+func (c *Context) SkipLayout() {
+	skipLayout(c.Request)
+}
+
+func skipLayout(r *http.Request) {
+	if state, ok := r.Context().Value(layoutStateKey{}).(*layoutState); ok {
+		state.skip.Store(true)
+	}
+}
+
+// fullPage reports whether an HTMX request needs the layout.
+func (l *LayoutWrapper) fullPage(r *http.Request) bool {
+	if r.Header.Get("HX-Request") != "true" {
+		return true
+	}
+	return l.WrapBoosted && (IsBoostedRequest(r) || IsHistoryRestoreRequest(r))
+}
+
+// responseRecorder captures the response for post-processing. An event
+// stream can't be wrapped, so once the handler sets Content-Type to
+// text/event-stream the recorder passes everything straight through.
 type responseRecorder struct {
 	http.ResponseWriter
-	statusCode int
-	body       []byte
+	statusCode  int
+	body        []byte
+	passthrough bool
 }
 
 func (r *responseRecorder) WriteHeader(code int) {
+	if r.passthrough {
+		r.ResponseWriter.WriteHeader(code)
+		return
+	}
 	r.statusCode = code
+	r.checkStream()
 }
 
 func (r *responseRecorder) Write(b []byte) (int, error) {
+	if !r.passthrough {
+		r.checkStream()
+	}
+	if r.passthrough {
+		return r.ResponseWriter.Write(b)
+	}
 	r.body = append(r.body, b...)
 	return len(b), nil
 }
 
+// checkStream switches to pass-through mode for event streams, sending
+// the status and anything recorded so far.
+func (r *responseRecorder) checkStream() {
+	if !strings.HasPrefix(r.Header().Get("Content-Type"), "text/event-stream") {
+		return
+	}
+	r.passthrough = true
+	r.ResponseWriter.WriteHeader(r.statusCode)
+	if len(r.body) > 0 {
+		r.ResponseWriter.Write(r.body)
+		r.body = nil
+	}
+}
+
+// Flush sends an event stream on to the client. Other responses are
+// still buffered, since the layout needs the whole body.
+func (r *responseRecorder) Flush() {
+	if !r.passthrough {
+		r.checkStream()
+	}
+	if r.passthrough {
+		http.NewResponseController(r.ResponseWriter).Flush()
+	}
+}
+
+// Hijack hands the connection to the handler, e.g. for a WebSocket
+// upgrade; nothing is wrapped afterwards.
+func (r *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(r.ResponseWriter).Hijack()
+	if err == nil {
+		r.passthrough = true
+	}
+	return conn, rw, err
+}
+
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
 func isHTML(contentType string) bool {
 	return contentType == "" ||
 		contentType == "text/html" ||
 		contentType == "text/html; charset=utf-8"
 }
 
+// MethodOverrideField is the form field consulted by MethodOverrideMiddleware.
+const MethodOverrideField = "_method"
+
+// MethodOverrideMiddleware lets plain HTML forms reach PUT, PATCH and DELETE
+// routes. For POST requests it reads the X-HTTP-Method-Override header or the
+// _method form field and rewrites the request method before routing.
+// Only PUT, PATCH and DELETE are accepted as overrides.
+//
+// Register it with Router.Use so it runs before route matching. The parsed
+// form remains available to later middleware and handlers.
+func MethodOverrideMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPost {
+				method := r.Header.Get("X-HTTP-Method-Override")
+				if method == "" && isFormContent(r.Header.Get("Content-Type")) {
+					method = r.PostFormValue(MethodOverrideField)
+				}
+				switch method = strings.ToUpper(method); method {
+				case http.MethodPut, http.MethodPatch, http.MethodDelete:
+					r.Method = method
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func isFormContent(contentType string) bool {
+	return strings.HasPrefix(contentType, "application/x-www-form-urlencoded") ||
+		strings.HasPrefix(contentType, "multipart/form-data")
+}
+
 // NoCacheMiddleware sets headers to prevent caching.
 func NoCacheMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,317 @@
+package router
+
+import (
+	"bufio"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stukennedy/irgo/pkg/render"
+)
+
+func TestMethodOverrideFormField(t *testing.T) {
+	r := New()
+	r.Use(MethodOverrideMiddleware())
+
+	var title string
+	r.DELETE("/todos/{id}", func(ctx *Context) (string, error) {
+		title = ctx.FormValue("title")
+		return "deleted " + ctx.Param("id"), nil
+	})
+	r.POST("/todos/{id}", func(ctx *Context) (string, error) {
+		return "posted", nil
+	})
+
+	body := strings.NewReader("_method=delete&title=Milk")
+	req := httptest.NewRequest("POST", "/todos/7", body)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Body.String() != "deleted 7" {
+		t.Errorf("expected DELETE route to fire, got %q", w.Body.String())
+	}
+	if title != "Milk" {
+		t.Errorf("expected form to remain readable, got title=%q", title)
+	}
+}
+
+func TestMethodOverrideHeader(t *testing.T) {
+	r := New()
+	r.Use(MethodOverrideMiddleware())
+	r.PATCH("/x", func(ctx *Context) (string, error) {
+		return "patched", nil
+	})
+
+	req := httptest.NewRequest("POST", "/x", nil)
+	req.Header.Set("X-HTTP-Method-Override", "PATCH")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Body.String() != "patched" {
+		t.Errorf("expected PATCH route to fire, got %q", w.Body.String())
+	}
+}
+
+func TestMethodOverrideAllowlist(t *testing.T) {
+	r := New()
+	r.Use(MethodOverrideMiddleware())
+	r.POST("/x", func(ctx *Context) (string, error) {
+		return "posted", nil
+	})
+	r.GET("/x", func(ctx *Context) (string, error) {
+		return "got", nil
+	})
+
+	// GET is not an allowed override
+	req := httptest.NewRequest("POST", "/x", strings.NewReader("_method=GET"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Body.String() != "posted" {
+		t.Errorf("expected POST route for disallowed override, got %q", w.Body.String())
+	}
+
+	// Overrides only apply to POST
+	req = httptest.NewRequest("GET", "/x", nil)
+	req.Header.Set("X-HTTP-Method-Override", "DELETE")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || w.Body.String() != "got" {
+		t.Errorf("expected GET route, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestNoJSFullPageNavigation(t *testing.T) {
+	engine := render.New()
+	engine.Parse("list", `<ul><li><a {{dsGet "/todos/1"}}>Milk</a></li></ul>`)
+
+	r := New()
+	r.Use(NoJSMiddleware)
+	layout := &LayoutWrapper{Layout: func(content string) string {
+		return "<html><body>" + content + "</body></html>"
+	}}
+	r.Use(layout.Wrap)
+	r.GET("/todos", func(ctx *Context) (string, error) {
+		return engine.RenderContext(ctx.Context(), "list", nil)
+	})
+
+	// Noscript refresh lands with ?__nojs=1: cookie set, full page with plain links
+	req := httptest.NewRequest("GET", "/todos?__nojs=1", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	if !strings.HasPrefix(body, "<html>") || !strings.Contains(body, `href="/todos/1"`) {
+		t.Errorf("expected full page with plain links, got %q", body)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != render.NoJSCookie {
+		t.Fatalf("expected no-JS cookie, got %+v", cookies)
+	}
+
+	// Subsequent navigation carries the cookie, even with a Datastar Accept header
+	req = httptest.NewRequest("GET", "/todos", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	req.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	body = w.Body.String()
+	if !strings.HasPrefix(body, "<html>") || strings.Contains(body, "data-on:click") {
+		t.Errorf("expected full page fallback, got %q", body)
+	}
+
+	// Without the flag, normal Datastar attributes are rendered
+	req = httptest.NewRequest("GET", "/todos", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if !strings.Contains(w.Body.String(), "data-on:click") {
+		t.Errorf("expected Datastar attributes, got %q", w.Body.String())
+	}
+}
+
+func TestLayoutWrapperStreamsSSE(t *testing.T) {
+	firstSeen := make(chan struct{})
+	done := make(chan struct{})
+
+	r := New()
+	layout := &LayoutWrapper{Layout: func(content string) string {
+		return "<html>" + content + "</html>"
+	}}
+	r.Use(layout.Wrap)
+	r.GET("/events", func(ctx *Context) (string, error) {
+		defer close(done)
+		sse := ctx.SSE()
+		if err := sse.PatchHTML(`<p id="a">first</p>`); err != nil {
+			return "", err
+		}
+		select {
+		case <-firstSeen:
+		case <-time.After(5 * time.Second):
+			t.Error("client never saw the first event")
+		}
+		return "", sse.PatchHTML(`<p id="b">second</p>`)
+	})
+
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	// A plain EventSource-style request, so the wrapper records it
+	resp, err := http.Get(srv.URL + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected text/event-stream, got %q", ct)
+	}
+
+	var body strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		body.WriteString(line + "\n")
+		if strings.Contains(line, "first") {
+			select {
+			case <-done:
+				t.Error("handler finished before the first event arrived")
+			default:
+			}
+			close(firstSeen)
+		}
+	}
+
+	got := body.String()
+	if !strings.Contains(got, "second") || strings.Contains(got, "<html>") {
+		t.Errorf("unexpected stream:\n%s", got)
+	}
+}
+
+func TestLayoutWrapperStillWrapsHTML(t *testing.T) {
+	r := New()
+	layout := &LayoutWrapper{Layout: func(content string) string {
+		return "<html>" + content + "</html>"
+	}}
+	r.Use(layout.Wrap)
+	r.GET("/page", func(ctx *Context) (string, error) {
+		ctx.Response.(http.Flusher).Flush()
+		return "<p>hi</p>", nil
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/page", nil))
+	if w.Body.String() != "<html><p>hi</p></html>" {
+		t.Errorf("expected wrapped page, got %q", w.Body.String())
+	}
+}
+
+func TestLayoutWrapperSkip(t *testing.T) {
+	r := New()
+	layout := &LayoutWrapper{
+		Layout:  func(content string) string { return "<html>" + content + "</html>" },
+		Exclude: []string{"/api/", "/raw"},
+	}
+	r.Use(layout.Wrap)
+	r.GET("/page", func(ctx *Context) (string, error) {
+		return "<p>page</p>", nil
+	})
+	r.With(SkipLayout).GET("/partials/cart", func(ctx *Context) (string, error) {
+		return "<p>cart</p>", nil
+	})
+	r.GET("/partials/menu", func(ctx *Context) (string, error) {
+		ctx.SkipLayout()
+		return "<p>menu</p>", nil
+	})
+	r.GET("/api/items", func(ctx *Context) (string, error) {
+		return "<p>items</p>", nil
+	})
+	r.GET("/raw", func(ctx *Context) (string, error) {
+		return "<p>raw</p>", nil
+	})
+	r.GET("/raw/nested", func(ctx *Context) (string, error) {
+		return "<p>nested</p>", nil
+	})
+	r.GET("/old", func(ctx *Context) (string, error) {
+		ctx.Redirect("/page")
+		return "", nil
+	})
+
+	tests := map[string]string{
+		"/page":          "<html><p>page</p></html>",
+		"/partials/cart": "<p>cart</p>",
+		"/partials/menu": "<p>menu</p>",
+		"/api/items":     "<p>items</p>",
+		"/raw":           "<p>raw</p>",
+		"/raw/nested":    "<html><p>nested</p></html>",
+	}
+	for path, want := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Body.String() != want {
+			t.Errorf("%s: expected %q, got %q", path, want, w.Body.String())
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/old", nil))
+	if w.Code != http.StatusSeeOther || strings.Contains(w.Body.String(), "<html>") {
+		t.Errorf("redirect should not be wrapped, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestLayoutWrapperRequest(t *testing.T) {
+	r := New()
+	layout := &LayoutWrapper{
+		Layout: func(content string) string { return "unused" },
+		LayoutRequest: func(req *http.Request, content string) string {
+			return "<title>" + req.URL.Path + "</title>" + content
+		},
+	}
+	r.Use(layout.Wrap)
+	r.GET("/settings", func(ctx *Context) (string, error) {
+		return "<p>settings</p>", nil
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/settings", nil))
+	if got := w.Body.String(); got != "<title>/settings</title><p>settings</p>" {
+		t.Errorf("unexpected page %q", got)
+	}
+}
+
+func TestTemplateFuncsMiddleware(t *testing.T) {
+	engine := render.New()
+	engine.AddFunc("currentUser", func() string { return "guest" })
+	if err := engine.Parse("nav", `<nav>{{currentUser}}</nav>`); err != nil {
+		t.Fatal(err)
+	}
+
+	r := New()
+	r.Use(TemplateFuncsMiddleware(func(req *http.Request) template.FuncMap {
+		user := req.Header.Get("X-User")
+		if user == "" {
+			return nil
+		}
+		return template.FuncMap{"currentUser": func() string { return user }}
+	}))
+	r.GET("/nav", func(ctx *Context) (string, error) {
+		return engine.RenderContext(ctx.Context(), "nav", nil)
+	})
+
+	for user, want := range map[string]string{"ada": "<nav>ada</nav>", "": "<nav>guest</nav>"} {
+		req := httptest.NewRequest("GET", "/nav", nil)
+		req.Header.Set("X-User", user)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Body.String() != want {
+			t.Errorf("user %q: got %q, want %q", user, w.Body.String(), want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package router
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// paramPattern matches {name} and {name:regexp} segments in route patterns.
+var paramPattern = regexp.MustCompile(`\{([^}:]+)(?::([^}]*))?\}`)
+
+// Name registers a name for the route so URLs can be built with Router.URL
+// and the url template function. Panics if the name is already in use.
+func (rt *Route) Name(name string) *Route {
+	cfg := rt.router.config
+	if existing, ok := cfg.names[name]; ok {
+		panic(fmt.Sprintf("router: route name %q already used for %s", name, existing))
+	}
+	cfg.names[name] = rt.Pattern
+	cfg.routeNames[rt.Method+" "+rt.Pattern] = name
+	return rt
+}
+
+// URL builds the path for a named route, substituting params in order for
+// the pattern's {param} segments and trailing * wildcard. A url.Values or
+// map[string]string as the final argument is appended as the query string.
+//
+//	r.URL("todo.toggle", 42)                              // "/todos/42/toggle"
+//	r.URL("files", "docs/read me.txt")                    // "/files/docs/read%20me.txt"
+//	r.URL("todos", url.Values{"filter": {"done"}})        // "/todos?filter=done"
+func (r *Router) URL(name string, params ...any) (string, error) {
+	pattern, ok := r.config.names[name]
+	if !ok {
+		return "", fmt.Errorf("router: no route named %q", name)
+	}
+
+	var query url.Values
+	if n := len(params); n > 0 {
+		switch q := params[n-1].(type) {
+		case url.Values:
+			query = q
+			params = params[:n-1]
+		case map[string]string:
+			query = make(url.Values, len(q))
+			for k, v := range q {
+				query.Set(k, v)
+			}
+			params = params[:n-1]
+		}
+	}
+
+	matches := paramPattern.FindAllStringSubmatchIndex(pattern, -1)
+	wildcard := strings.HasSuffix(pattern, "*")
+	want := len(matches)
+	if wildcard {
+		want++
+	}
+	if len(params) != want {
+		return "", fmt.Errorf("router: route %q (%s) takes %d params, got %d", name, pattern, want, len(params))
+	}
+
+	var b strings.Builder
+	last := 0
+	for i, m := range matches {
+		value := fmt.Sprint(params[i])
+		if m[4] >= 0 {
+			re, err := regexp.Compile("^(?:" + pattern[m[4]:m[5]] + ")$")
+			if err == nil && !re.MatchString(value) {
+				return "", fmt.Errorf("router: route %q param %q: %q does not match %s",
+					name, pattern[m[2]:m[3]], value, pattern[m[4]:m[5]])
+			}
+		}
+		b.WriteString(pattern[last:m[0]])
+		b.WriteString(url.PathEscape(value))
+		last = m[1]
+	}
+
+	if wildcard {
+		b.WriteString(pattern[last : len(pattern)-1])
+		segments := strings.Split(fmt.Sprint(params[len(params)-1]), "/")
+		for i, s := range segments {
+			segments[i] = url.PathEscape(s)
+		}
+		b.WriteString(strings.Join(segments, "/"))
+	} else {
+		b.WriteString(pattern[last:])
+	}
+
+	if len(query) > 0 {
+		b.WriteString("?")
+		b.WriteString(query.Encode())
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,104 @@
+package router
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stukennedy/irgo/pkg/render"
+)
+
+func newNamedRouter() *Router {
+	noop := func(ctx *Context) (string, error) { return "", nil }
+	r := New()
+	r.GET("/todos", noop).Name("todos")
+	r.POST("/todos/{id}/toggle", noop).Name("todo.toggle")
+	r.Route("/api", func(api *Router) {
+		api.GET("/users/{userID:[0-9]+}/posts/{slug}", noop).Name("user.post")
+	})
+	r.GET("/files/*", noop).Name("files")
+	return r
+}
+
+func TestURL(t *testing.T) {
+	r := newNamedRouter()
+
+	tests := []struct {
+		name   string
+		params []any
+		want   string
+	}{
+		{"todos", nil, "/todos"},
+		{"todo.toggle", []any{42}, "/todos/42/toggle"},
+		{"user.post", []any{7, "hello world"}, "/api/users/7/posts/hello%20world"},
+		{"files", []any{"docs/read me.txt"}, "/files/docs/read%20me.txt"},
+		{"todos", []any{url.Values{"filter": {"done"}, "page": {"2"}}}, "/todos?filter=done&page=2"},
+		{"todo.toggle", []any{1, map[string]string{"undo": "true"}}, "/todos/1/toggle?undo=true"},
+	}
+	for _, tt := range tests {
+		got, err := r.URL(tt.name, tt.params...)
+		if err != nil {
+			t.Errorf("URL(%q, %v): unexpected error %v", tt.name, tt.params, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("URL(%q, %v) = %q, want %q", tt.name, tt.params, got, tt.want)
+		}
+	}
+}
+
+func TestURLErrors(t *testing.T) {
+	r := newNamedRouter()
+
+	tests := []struct {
+		name   string
+		params []any
+		want   string
+	}{
+		{"missing", nil, "no route named"},
+		{"todo.toggle", nil, "takes 1 params, got 0"},
+		{"todos", []any{1}, "takes 0 params, got 1"},
+		{"user.post", []any{"abc", "slug"}, "does not match"},
+	}
+	for _, tt := range tests {
+		_, err := r.URL(tt.name, tt.params...)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("URL(%q, %v): expected error containing %q, got %v", tt.name, tt.params, tt.want, err)
+		}
+	}
+}
+
+func TestDuplicateRouteNamePanics(t *testing.T) {
+	r := newNamedRouter()
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for duplicate route name")
+		}
+	}()
+	r.GET("/other", func(ctx *Context) (string, error) { return "", nil }).Name("todos")
+}
+
+func TestURLTemplateFunc(t *testing.T) {
+	r := newNamedRouter()
+	engine := render.New()
+	engine.SetURLResolver(r)
+	if err := engine.Parse("link", `<a href="{{url "todo.toggle" .ID}}">toggle</a>`); err != nil {
+		t.Fatal(err)
+	}
+
+	html, err := engine.Render("link", map[string]any{"ID": 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if html != `<a href="/todos/5/toggle">toggle</a>` {
+		t.Errorf("unexpected output %q", html)
+	}
+
+	unwired := render.New()
+	if err := unwired.Parse("link", `{{url "todos"}}`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := unwired.Render("link", nil); err == nil || !strings.Contains(err.Error(), "SetURLResolver") {
+		t.Errorf("expected unresolved url error, got %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+package router
+
+import (
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// negotiatePreference orders offers when the client accepts several
+// equally; unlisted types follow alphabetically.
+var negotiatePreference = []string{"text/html", "application/json", "text/event-stream"}
+
+// Negotiate dispatches to the offer best matching the Accept header, e.g.
+//
+//	return "", ctx.Negotiate(map[string]func() error{
+//	    "text/html":        func() error { ctx.HTML(page); return nil },
+//	    "application/json": func() error { ctx.JSON(todo); return nil },
+//	})
+//
+// Datastar requests (see IsDatastarRequest) get the "text/event-stream"
+// offer, or "text/html" if there is none. If nothing matches, Negotiate
+// returns a 406 HTTPError listing the supported types.
+func (c *Context) Negotiate(offers map[string]func() error) error {
+	AddVary(c.Response.Header(), "Accept")
+
+	types := make([]string, 0, len(offers))
+	for mime := range offers {
+		types = append(types, mime)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		return preferredBefore(types[i], types[j])
+	})
+
+	if IsDatastarRequest(c.Request) {
+		for _, mime := range []string{"text/event-stream", "text/html"} {
+			if render, ok := offers[mime]; ok {
+				return render()
+			}
+		}
+	}
+
+	ranges := parseAccept(c.Request.Header.Get("Accept"))
+	best, bestMatch := "", acceptMatch{}
+	for _, mime := range types {
+		if m, ok := matchAccept(ranges, mime); ok && m.better(bestMatch) {
+			best, bestMatch = mime, m
+		}
+	}
+	if best == "" {
+		return NewHTTPError(http.StatusNotAcceptable,
+			"Not Acceptable. Supported types: "+strings.Join(types, ", "))
+	}
+	return offers[best]()
+}
+
+// Accepts reports whether the Accept header allows mime. A request without
+// an Accept header accepts anything.
+func (c *Context) Accepts(mime string) bool {
+	_, ok := matchAccept(parseAccept(c.Request.Header.Get("Accept")), mime)
+	return ok
+}
+
+// acceptRange is one media range of an Accept header.
+type acceptRange struct {
+	typ, subtype string
+	q            float64
+	index        int
+}
+
+func parseAccept(header string) []acceptRange {
+	if strings.TrimSpace(header) == "" {
+		return []acceptRange{{typ: "*", subtype: "*", q: 1}}
+	}
+
+	var ranges []acceptRange
+	for i, part := range strings.Split(header, ",") {
+		mime, params, _ := strings.Cut(part, ";")
+		typ, subtype, ok := strings.Cut(strings.ToLower(strings.TrimSpace(mime)), "/")
+		if !ok || typ == "" || subtype == "" {
+			continue
+		}
+		r := acceptRange{typ: typ, subtype: subtype, q: 1, index: i}
+		for _, param := range strings.Split(params, ";") {
+			k, v, _ := strings.Cut(param, "=")
+			if strings.TrimSpace(k) == "q" {
+				if q, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+					r.q = min(max(q, 0), 1)
+				}
+			}
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges
+}
+
+// acceptMatch is how well an Accept header matches an offered type.
+type acceptMatch struct {
+	q           float64
+	specificity int // 2 exact, 1 type/*, 0 */*
+	index       int
+	matched     bool
+}
+
+// better reports whether m should be preferred to o. Offers are visited in
+// server preference order, so ties keep the earlier offer.
+func (m acceptMatch) better(o acceptMatch) bool {
+	if !o.matched {
+		return true
+	}
+	if m.q != o.q {
+		return m.q > o.q
+	}
+	if m.specificity != o.specificity {
+		return m.specificity > o.specificity
+	}
+	return m.index < o.index
+}
+
+// matchAccept finds the most specific range matching mime. A q of 0
+// means the type is refused.
+func matchAccept(ranges []acceptRange, mime string) (acceptMatch, bool) {
+	typ, subtype, _ := strings.Cut(strings.ToLower(mime), "/")
+	best := acceptMatch{specificity: -1}
+	for _, r := range ranges {
+		spec := -1
+		switch {
+		case r.typ == typ && r.subtype == subtype:
+			spec = 2
+		case r.typ == typ && r.subtype == "*":
+			spec = 1
+		case r.typ == "*" && r.subtype == "*":
+			spec = 0
+		}
+		if spec > best.specificity {
+			best = acceptMatch{q: r.q, specificity: spec, index: r.index, matched: true}
+		}
+	}
+	return best, best.matched && best.q > 0
+}
+
+func preferredBefore(a, b string) bool {
+	ia, ib := slices.Index(negotiatePreference, a), slices.Index(negotiatePreference, b)
+	switch {
+	case ia < 0 && ib < 0:
+		return a < b
+	case ia < 0 || ib < 0:
+		return ia >= 0
+	}
+	return ia < ib
+}
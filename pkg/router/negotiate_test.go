@@ -0,0 +1,105 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func negotiateRouter() *Router {
+	r := New()
+	offers := func(ctx *Context) map[string]func() error {
+		return map[string]func() error{
+			"text/html":        func() error { ctx.HTML("<p>todo</p>"); return nil },
+			"application/json": func() error { ctx.JSON(map[string]string{"title": "todo"}); return nil },
+			"text/event-stream": func() error {
+				return ctx.SSE().PatchSignals(map[string]string{"title": "todo"})
+			},
+		}
+	}
+	r.GET("/todo", func(ctx *Context) (string, error) {
+		return "", ctx.Negotiate(offers(ctx))
+	})
+	r.GET("/page", func(ctx *Context) (string, error) {
+		o := offers(ctx)
+		delete(o, "text/event-stream")
+		return "", ctx.Negotiate(o)
+	})
+	return r
+}
+
+func TestNegotiate(t *testing.T) {
+	r := negotiateRouter()
+	tests := []struct {
+		path   string
+		accept string
+		want   string
+	}{
+		{"/todo", "", "text/html"},
+		{"/todo", "*/*", "text/html"},
+		{"/todo", "application/json", "application/json"},
+		{"/todo", "application/*", "application/json"},
+		{"/todo", "text/html;q=0.5, application/json", "application/json"},
+		{"/todo", "application/json;q=0.2, text/*;q=0.8", "text/html"},
+		{"/todo", "application/json, text/html", "application/json"},
+		{"/todo", "*/*;q=0.1, application/json;q=0.9", "application/json"},
+		{"/todo", "text/html;q=0, */*", "application/json"},
+		{"/todo", "text/event-stream", "text/event-stream"},
+		{"/page", "text/event-stream", "text/html"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), tt.want) {
+			t.Errorf("%s Accept %q: expected %s, got %d %q", tt.path, tt.accept, tt.want, w.Code, w.Header().Get("Content-Type"))
+		}
+		if w.Header().Get("Vary") != "Accept" {
+			t.Errorf("%s Accept %q: expected Vary: Accept, got %q", tt.path, tt.accept, w.Header().Get("Vary"))
+		}
+	}
+}
+
+func TestNegotiateNotAcceptable(t *testing.T) {
+	r := negotiateRouter()
+	req := httptest.NewRequest("GET", "/page", nil)
+	req.Header.Set("Accept", "image/png, text/html;q=0")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotAcceptable {
+		t.Fatalf("expected 406, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "Supported types: text/html, application/json") {
+		t.Errorf("expected supported types, got %q", w.Body.String())
+	}
+}
+
+func TestAccepts(t *testing.T) {
+	tests := []struct {
+		accept string
+		mime   string
+		want   bool
+	}{
+		{"", "application/json", true},
+		{"*/*", "image/webp", true},
+		{"image/*", "image/webp", true},
+		{"image/*;q=0", "image/webp", false},
+		{"image/*, image/webp;q=0", "image/webp", false},
+		{"text/html", "application/json", false},
+		{"TEXT/HTML", "text/html", true},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+		ctx := NewContext(httptest.NewRecorder(), req)
+		if got := ctx.Accepts(tt.mime); got != tt.want {
+			t.Errorf("Accept %q, Accepts(%q) = %v, want %v", tt.accept, tt.mime, got, tt.want)
+		}
+	}
+}
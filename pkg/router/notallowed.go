@@ -0,0 +1,60 @@
+package router
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// MethodNotAllowedEvent is the client event triggered when an HTMX
+// request gets a 405 from the default handler. Its detail holds the
+// attempted method and the allowed ones.
+const MethodNotAllowedEvent = "gohtmx:method-not-allowed"
+
+// routeMethods are the methods checked when listing a path's routes.
+var routeMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodDelete, http.MethodOptions, http.MethodConnect, http.MethodTrace,
+}
+
+// allowedMethods returns the methods with a route matching req's path.
+func (r *Router) allowedMethods(req *http.Request) []string {
+	path := req.URL.RawPath
+	if path == "" {
+		path = req.URL.Path
+	}
+	var allowed []string
+	for _, method := range routeMethods {
+		if r.mux.Match(chi.NewRouteContext(), method, path) {
+			allowed = append(allowed, method)
+		}
+	}
+	return allowed
+}
+
+// methodNotAllowed is the default 405 handler installed by New. It sets
+// the Allow header and writes an error fragment. For HTMX requests it
+// also prevents the swap and triggers MethodNotAllowedEvent, so the
+// failure is visible rather than nothing happening.
+func (r *Router) methodNotAllowed(w http.ResponseWriter, req *http.Request) {
+	allowed := r.allowedMethods(req)
+	h := w.Header()
+	h.Set("Allow", strings.Join(allowed, ", "))
+
+	if req.Header.Get("HX-Request") == "true" {
+		detail, _ := json.Marshal(map[string]any{
+			MethodNotAllowedEvent: map[string]any{"method": req.Method, "allowed": allowed},
+		})
+		h.Set("HX-Reswap", "none")
+		h.Set("HX-Trigger", string(detail))
+	}
+
+	message := template.HTMLEscapeString(req.Method) + " is not allowed here"
+	if len(allowed) > 0 {
+		message += " (allowed: " + strings.Join(allowed, ", ") + ")"
+	}
+	NewContext(w, req).ErrorStatus(http.StatusMethodNotAllowed, message)
+}
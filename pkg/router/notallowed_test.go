@@ -0,0 +1,82 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func notAllowedRouter() *Router {
+	r := New()
+	r.GET("/todos/{id}", func(ctx *Context) (string, error) { return "todo", nil })
+	r.DELETE("/todos/{id}", func(ctx *Context) (string, error) { return "", nil })
+	r.Route("/api", func(api *Router) {
+		api.POST("/items", func(ctx *Context) (string, error) { return "item", nil })
+	})
+	return r
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	r := notAllowedRouter()
+
+	w := serve(r, "POST", "/todos/1")
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("status = %d, want 405", w.Code)
+	}
+	if got := w.Header().Get("Allow"); got != "GET, HEAD, DELETE" {
+		t.Errorf("Allow = %q", got)
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, `class="error"`) || !strings.Contains(body, "POST is not allowed") || !strings.Contains(body, "GET, HEAD, DELETE") {
+		t.Errorf("body = %q", body)
+	}
+	if w.Header().Get("HX-Reswap") != "" || w.Header().Get("HX-Trigger") != "" {
+		t.Errorf("plain request got HTMX headers: %v", w.Header())
+	}
+
+	w = serve(r, "GET", "/api/items")
+	if got := w.Header().Get("Allow"); w.Code != http.StatusMethodNotAllowed || got != "POST" {
+		t.Errorf("subrouter: status %d, Allow %q", w.Code, got)
+	}
+}
+
+func TestMethodNotAllowedHTMX(t *testing.T) {
+	r := notAllowedRouter()
+	req := httptest.NewRequest("PUT", "/todos/1", nil)
+	req.Header.Set("HX-Request", "true")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("status = %d, want 405", w.Code)
+	}
+	if got := w.Header().Get("HX-Reswap"); got != "none" {
+		t.Errorf("HX-Reswap = %q, want none", got)
+	}
+	var trigger map[string]struct {
+		Method  string   `json:"method"`
+		Allowed []string `json:"allowed"`
+	}
+	if err := json.Unmarshal([]byte(w.Header().Get("HX-Trigger")), &trigger); err != nil {
+		t.Fatalf("HX-Trigger = %q: %v", w.Header().Get("HX-Trigger"), err)
+	}
+	detail, ok := trigger[MethodNotAllowedEvent]
+	if !ok || detail.Method != "PUT" || !reflect.DeepEqual(detail.Allowed, []string{"GET", "HEAD", "DELETE"}) {
+		t.Errorf("trigger = %+v", trigger)
+	}
+}
+
+func TestMethodNotAllowedCustom(t *testing.T) {
+	r := notAllowedRouter()
+	r.MethodNotAllowed(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	for _, target := range []string{"/todos/1", "/api/items"} {
+		if w := serve(r, "PATCH", target); w.Code != http.StatusTeapot {
+			t.Errorf("PATCH %s: status = %d, want custom handler", target, w.Code)
+		}
+	}
+}
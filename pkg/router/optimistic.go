@@ -0,0 +1,34 @@
+package router
+
+import (
+	"github.com/stukennedy/irgo/pkg/datastar"
+	"github.com/stukennedy/irgo/pkg/render"
+)
+
+// optimisticDetail is the detail of the optimistic confirm and rollback
+// events.
+type optimisticDetail struct {
+	Target  string `json:"target"`
+	Message string `json:"message,omitempty"`
+}
+
+// OptimisticConfirm tells the client the mutation on target (e.g.
+// "#todo-1") succeeded, clearing the pending state set by dsOptimistic.
+// The optimistic markup is kept as is.
+func (c *Context) OptimisticConfirm(target string) error {
+	return c.SSE().DispatchEvent(render.OptimisticConfirmEvent, optimisticDetail{Target: target})
+}
+
+// OptimisticRollback tells the client the mutation on target failed: it
+// replaces target with correctFragment, the authoritative markup, sets
+// message as the error flash and clears the pending state.
+func (c *Context) OptimisticRollback(target, correctFragment, message string) error {
+	sse := c.SSE()
+	if err := sse.PatchHTML(correctFragment, datastar.WithSelector(target)); err != nil {
+		return err
+	}
+	if err := sse.PatchSignals(map[string]any{render.OptimisticErrorSignal: message}); err != nil {
+		return err
+	}
+	return sse.DispatchEvent(render.OptimisticRollbackEvent, optimisticDetail{Target: target, Message: message})
+}
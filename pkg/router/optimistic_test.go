@@ -0,0 +1,90 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stukennedy/irgo/pkg/render"
+	irgotest "github.com/stukennedy/irgo/pkg/testing"
+)
+
+// eventDetail extracts the detail of a custom event dispatched by an
+// execute-script patch.
+func eventDetail(t *testing.T, e irgotest.SSEEvent, name string) map[string]any {
+	t.Helper()
+	script := strings.Join(e.Data, "\n")
+	_, rest, ok := strings.Cut(script, "new CustomEvent("+`"`+name+`"`)
+	if !ok {
+		t.Fatalf("no %s event in %q", name, script)
+	}
+	start := strings.Index(rest, "detail:")
+	if start < 0 {
+		t.Fatalf("no detail in %q", rest)
+	}
+	var detail map[string]any
+	dec := json.NewDecoder(strings.NewReader(strings.TrimSpace(rest[start+len("detail:"):])))
+	if err := dec.Decode(&detail); err != nil {
+		t.Fatalf("decoding detail from %q: %v", rest, err)
+	}
+	return detail
+}
+
+func TestOptimisticConfirm(t *testing.T) {
+	r := New()
+	r.DSPost("/todos/{id}/toggle", func(ctx *Context) error {
+		return ctx.OptimisticConfirm("#todo-" + ctx.Param("id"))
+	})
+
+	resp := irgotest.NewClient(r).Datastar().Post("/todos/1/toggle", nil)
+	events := resp.SSEEvents()
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event, got %+v", events)
+	}
+	detail := eventDetail(t, events[0], render.OptimisticConfirmEvent)
+	if detail["target"] != "#todo-1" || detail["message"] != nil {
+		t.Errorf("unexpected detail %v", detail)
+	}
+}
+
+func TestOptimisticRollback(t *testing.T) {
+	r := New()
+	r.DSPost("/todos/{id}/toggle", func(ctx *Context) error {
+		err := errors.New("todo is locked")
+		return ctx.OptimisticRollback("#todo-1", `<li id="todo-1">Buy milk</li>`, err.Error())
+	})
+
+	resp := irgotest.NewClient(r).Datastar().Post("/todos/1/toggle", nil)
+	events := resp.SSEEvents()
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events, got %+v", events)
+	}
+
+	if events[0].Type != "datastar-patch-elements" {
+		t.Errorf("expected element patch first, got %q", events[0].Type)
+	}
+	if sel, _ := events[0].DataLine("selector "); sel != "#todo-1" {
+		t.Errorf("expected selector #todo-1, got %q", sel)
+	}
+	if el, _ := events[0].DataLine("elements "); el != `<li id="todo-1">Buy milk</li>` {
+		t.Errorf("unexpected elements %q", el)
+	}
+
+	if events[1].Type != "datastar-patch-signals" {
+		t.Errorf("expected signal patch second, got %q", events[1].Type)
+	}
+	sig, _ := events[1].DataLine("signals ")
+	var signals map[string]any
+	if err := json.Unmarshal([]byte(sig), &signals); err != nil {
+		t.Fatal(err)
+	}
+	if signals[render.OptimisticErrorSignal] != "todo is locked" {
+		t.Errorf("unexpected signals %v", signals)
+	}
+
+	detail := eventDetail(t, events[2], render.OptimisticRollbackEvent)
+	if detail["target"] != "#todo-1" || detail["message"] != "todo is locked" {
+		t.Errorf("unexpected detail %v", detail)
+	}
+}
@@ -0,0 +1,162 @@
+package router
+
+import (
+	"hash"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/stukennedy/irgo/pkg/render"
+)
+
+// PollIntervalHeader carries the milliseconds a client should wait before
+// fetching a polling route again (see Route.Polling).
+const PollIntervalHeader = "X-Irgo-Poll-Interval"
+
+const (
+	// pollMaxFactor caps automatic backoff at this multiple of the
+	// route's interval.
+	pollMaxFactor = 16
+
+	// batterySaverFactor multiplies poll intervals while battery saver is on.
+	batterySaverFactor = 4
+)
+
+var batterySaver atomic.Bool
+
+// SetBatterySaver records whether the device is in battery saver (low
+// power) mode. While on, polling routes ask clients to poll 4x less often.
+// The mobile bridge updates it from native lifecycle events.
+func SetBatterySaver(on bool) {
+	batterySaver.Store(on)
+}
+
+// Polling makes the route tell clients when to poll it again, starting at
+// interval. Each response carries PollIntervalHeader; SSE routes also
+// patch the interval into the "_poll" signal read by the dsPoll template
+// helper. When a response is identical to the previous one for the same
+// path, the interval doubles, up to 16x; any change resets it. Handlers
+// can choose the interval themselves with Context.NextPoll.
+func (rt *Route) Polling(interval time.Duration) *Route {
+	rt.poll = &poller{base: interval, paths: make(map[string]*pollEntry)}
+	return rt
+}
+
+// NextPoll sets the interval sent with this response on a polling route,
+// overriding automatic backoff. Later unchanged responses back off from d.
+func (c *Context) NextPoll(d time.Duration) {
+	c.nextPoll = d
+}
+
+// poller tracks the backoff state of a polling route per request path.
+type poller struct {
+	base  time.Duration
+	mu    sync.Mutex
+	paths map[string]*pollEntry
+}
+
+type pollEntry struct {
+	sum      uint64
+	interval time.Duration
+}
+
+// next records the hash of a response for path and returns the interval
+// to send with it.
+func (p *poller) next(path string, sum uint64, override time.Duration) time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	prev := p.paths[path]
+	interval := p.base
+	switch {
+	case override > 0:
+		interval = override
+	case prev != nil && prev.sum == sum:
+		interval = min(prev.interval*2, max(p.base*pollMaxFactor, prev.interval))
+	}
+	p.paths[path] = &pollEntry{sum: sum, interval: interval}
+	return withBatterySaver(interval)
+}
+
+// current returns the interval in effect for path.
+func (p *poller) current(path string) time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if e := p.paths[path]; e != nil {
+		return withBatterySaver(e.interval)
+	}
+	return withBatterySaver(p.base)
+}
+
+func withBatterySaver(d time.Duration) time.Duration {
+	if batterySaver.Load() {
+		return d * batterySaverFactor
+	}
+	return d
+}
+
+func setPollHeader(w http.ResponseWriter, d time.Duration) {
+	w.Header().Set(PollIntervalHeader, strconv.FormatInt(d.Milliseconds(), 10))
+}
+
+func hashString(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return h.Sum64()
+}
+
+// pollFragment sets the poll header for a fragment response about to be
+// written.
+func (p *poller) pollFragment(ctx *Context, html string) {
+	d := p.next(ctx.Request.URL.Path, hashString(html), ctx.nextPoll)
+	setPollHeader(ctx.Response, d)
+}
+
+// pollStream runs an SSE handler on a polling route. Headers are sent when
+// the stream opens, so the header carries the interval currently in
+// effect; the interval computed from this response is patched into the
+// "_poll" signal when the handler returns.
+func (p *poller) pollStream(ctx *Context, handler SSEHandler) error {
+	path := ctx.Request.URL.Path
+	setPollHeader(ctx.Response, p.current(path))
+
+	hw := &hashWriter{ResponseWriter: ctx.Response, h: fnv.New64a()}
+	ctx.Response = hw
+	if err := handler(ctx); err != nil {
+		return err
+	}
+	if !ctx.Written() {
+		return nil
+	}
+
+	d := p.next(path, hw.h.Sum64(), ctx.nextPoll)
+	return ctx.SSE().PatchSignals(map[string]any{
+		"_poll": map[string]any{
+			render.PollKey(path): map[string]int64{"every": d.Milliseconds()},
+		},
+	})
+}
+
+// hashWriter hashes a response body as it is written.
+type hashWriter struct {
+	http.ResponseWriter
+	h hash.Hash64
+}
+
+func (w *hashWriter) Write(b []byte) (int, error) {
+	w.h.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *hashWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *hashWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
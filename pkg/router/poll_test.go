@@ -0,0 +1,121 @@
+package router
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPollingBackoff(t *testing.T) {
+	r := New()
+	content := "v1"
+	r.GET("/stats", func(ctx *Context) (string, error) {
+		return content, nil
+	}).Polling(time.Second)
+
+	poll := func() string {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/stats", nil))
+		return w.Header().Get(PollIntervalHeader)
+	}
+
+	want := []string{"1000", "2000", "4000", "8000", "16000", "16000"}
+	for i, w := range want {
+		if got := poll(); got != w {
+			t.Errorf("poll %d: interval %s, want %s", i, got, w)
+		}
+	}
+
+	content = "v2"
+	if got := poll(); got != "1000" {
+		t.Errorf("after change: interval %s, want 1000", got)
+	}
+	if got := poll(); got != "2000" {
+		t.Errorf("unchanged after reset: interval %s, want 2000", got)
+	}
+}
+
+func TestPollingPerPath(t *testing.T) {
+	r := New()
+	r.GET("/feed/{id}", func(ctx *Context) (string, error) {
+		return "same", nil
+	}).Polling(time.Second)
+
+	get := func(path string) string {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w.Header().Get(PollIntervalHeader)
+	}
+	get("/feed/1")
+	if got := get("/feed/1"); got != "2000" {
+		t.Errorf("/feed/1 interval %s, want 2000", got)
+	}
+	if got := get("/feed/2"); got != "1000" {
+		t.Errorf("/feed/2 interval %s, want 1000", got)
+	}
+}
+
+func TestNextPollAndBatterySaver(t *testing.T) {
+	r := New()
+	var next time.Duration
+	r.GET("/x", func(ctx *Context) (string, error) {
+		if next > 0 {
+			ctx.NextPoll(next)
+		}
+		return "same", nil
+	}).Polling(time.Second)
+
+	get := func() string {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/x", nil))
+		return w.Header().Get(PollIntervalHeader)
+	}
+
+	next = 30 * time.Second
+	if got := get(); got != "30000" {
+		t.Errorf("NextPoll: interval %s, want 30000", got)
+	}
+	next = 0
+	if got := get(); got != "30000" {
+		t.Errorf("backoff above cap: interval %s, want 30000", got)
+	}
+
+	SetBatterySaver(true)
+	defer SetBatterySaver(false)
+	next = 5 * time.Second
+	if got := get(); got != "20000" {
+		t.Errorf("battery saver: interval %s, want 20000", got)
+	}
+}
+
+func TestPollingSSE(t *testing.T) {
+	r := New()
+	r.DSGet("/live", func(ctx *Context) error {
+		return ctx.SSE().PatchHTML(`<div id="live">same</div>`)
+	}).Polling(time.Second)
+
+	poll := func() (string, string) {
+		req := httptest.NewRequest("GET", "/live", nil)
+		req.Header.Set("Accept", "text/event-stream")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Header().Get(PollIntervalHeader), w.Body.String()
+	}
+
+	header, body := poll()
+	if header != "1000" {
+		t.Errorf("header %s, want 1000", header)
+	}
+	if !strings.Contains(body, `{"_poll":{"live":{"every":1000}}}`) {
+		t.Errorf("missing poll signal in:\n%s", body)
+	}
+
+	header, body = poll()
+	if header != "1000" || !strings.Contains(body, `"every":2000`) {
+		t.Errorf("second poll: header %s, body:\n%s", header, body)
+	}
+	if header, _ = poll(); header != "2000" {
+		t.Errorf("third poll header %s, want 2000", header)
+	}
+}
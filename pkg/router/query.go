@@ -0,0 +1,155 @@
+package router
+
+import (
+	"strconv"
+	"time"
+)
+
+// QuerySlice returns all values of a repeated query parameter, e.g.
+// ["a", "b"] for ?tag=a&tag=b, or nil if it is absent.
+func (c *Context) QuerySlice(key string) []string {
+	return c.Request.URL.Query()[key]
+}
+
+// QueryInt returns a query parameter as an int, or def if it is missing,
+// empty or not a valid int (including out of range).
+func (c *Context) QueryInt(key string, def int) int {
+	v, err := c.QueryIntStrict(key)
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+// QueryInt64 is QueryInt for int64 values.
+func (c *Context) QueryInt64(key string, def int64) int64 {
+	v, err := c.QueryInt64Strict(key)
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+// QueryFloat returns a query parameter as a float64, or def if it is
+// missing, empty or invalid.
+func (c *Context) QueryFloat(key string, def float64) float64 {
+	v, err := c.QueryFloatStrict(key)
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+// QueryBool returns a query parameter as a bool, or def if it is missing,
+// empty or invalid. Accepts the strconv.ParseBool forms ("1", "t",
+// "true", "0", "f", "false", ...) plus "on" and "off" as sent by
+// checkboxes.
+func (c *Context) QueryBool(key string, def bool) bool {
+	v, err := c.QueryBoolStrict(key)
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+// QueryTime returns a query parameter parsed with layout, e.g.
+// time.DateOnly for ?since=2024-01-01, or def if it is missing, empty or
+// invalid.
+func (c *Context) QueryTime(key, layout string, def time.Time) time.Time {
+	v, err := c.QueryTimeStrict(key, layout)
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+// QueryIntStrict returns a query parameter as an int. A missing, empty or
+// invalid value is a 400 HTTPError, so handlers can return it directly.
+func (c *Context) QueryIntStrict(key string) (int, error) {
+	s, err := c.requiredQuery(key)
+	if err != nil {
+		return 0, err
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, invalidQuery(key, err)
+	}
+	return v, nil
+}
+
+// QueryInt64Strict is QueryIntStrict for int64 values.
+func (c *Context) QueryInt64Strict(key string) (int64, error) {
+	s, err := c.requiredQuery(key)
+	if err != nil {
+		return 0, err
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, invalidQuery(key, err)
+	}
+	return v, nil
+}
+
+// QueryFloatStrict is QueryIntStrict for float64 values.
+func (c *Context) QueryFloatStrict(key string) (float64, error) {
+	s, err := c.requiredQuery(key)
+	if err != nil {
+		return 0, err
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, invalidQuery(key, err)
+	}
+	return v, nil
+}
+
+// QueryBoolStrict is QueryIntStrict for bool values, accepting the forms
+// QueryBool does.
+func (c *Context) QueryBoolStrict(key string) (bool, error) {
+	s, err := c.requiredQuery(key)
+	if err != nil {
+		return false, err
+	}
+	v, err := parseBool(s)
+	if err != nil {
+		return false, invalidQuery(key, err)
+	}
+	return v, nil
+}
+
+// parseBool is strconv.ParseBool plus the checkbox values "on" and "off".
+func parseBool(s string) (bool, error) {
+	switch s {
+	case "on":
+		return true, nil
+	case "off":
+		return false, nil
+	}
+	return strconv.ParseBool(s)
+}
+
+// QueryTimeStrict is QueryIntStrict for times parsed with layout.
+func (c *Context) QueryTimeStrict(key, layout string) (time.Time, error) {
+	s, err := c.requiredQuery(key)
+	if err != nil {
+		return time.Time{}, err
+	}
+	v, err := time.Parse(layout, s)
+	if err != nil {
+		return time.Time{}, invalidQuery(key, err)
+	}
+	return v, nil
+}
+
+// requiredQuery returns a non-empty query parameter.
+func (c *Context) requiredQuery(key string) (string, error) {
+	s := c.Query(key)
+	if s == "" {
+		return "", ErrBadRequest("Missing query parameter: " + key)
+	}
+	return s, nil
+}
+
+func invalidQuery(key string, err error) *HTTPError {
+	return ErrBadRequest("Invalid query parameter: " + key).WithInternal(err)
+}
@@ -0,0 +1,137 @@
+package router
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func queryContext(query string) *Context {
+	return NewContext(httptest.NewRecorder(), httptest.NewRequest("GET", "/?"+query, nil))
+}
+
+func TestQueryInt(t *testing.T) {
+	ctx := queryContext("page=2&zero=0&neg=-3&empty=&bad=two&big=99999999999999999999&f=1.5")
+	tests := []struct {
+		key  string
+		want int
+	}{
+		{"page", 2},
+		{"zero", 0},
+		{"neg", -3},
+		{"empty", 7},
+		{"bad", 7},
+		{"big", 7},
+		{"f", 7},
+		{"missing", 7},
+	}
+	for _, tt := range tests {
+		if got := ctx.QueryInt(tt.key, 7); got != tt.want {
+			t.Errorf("QueryInt(%q) = %d, want %d", tt.key, got, tt.want)
+		}
+	}
+
+	if got := ctx.QueryInt64("page", 7); got != 2 {
+		t.Errorf("QueryInt64(page) = %d", got)
+	}
+	if got := ctx.QueryInt64("big", 7); got != 7 {
+		t.Errorf("QueryInt64(big) = %d, want default on overflow", got)
+	}
+	if got := ctx.QueryFloat("f", 0.5); got != 1.5 {
+		t.Errorf("QueryFloat(f) = %v", got)
+	}
+	if got := ctx.QueryFloat("bad", 0.5); got != 0.5 {
+		t.Errorf("QueryFloat(bad) = %v", got)
+	}
+}
+
+func TestQueryBool(t *testing.T) {
+	ctx := queryContext("a=true&b=false&c=0&d=1&e=on&f=off&g=&h=maybe")
+	tests := []struct {
+		key      string
+		def      bool
+		want     bool
+		strictOK bool
+	}{
+		{"a", false, true, true},
+		{"b", true, false, true},
+		{"c", true, false, true},
+		{"d", false, true, true},
+		{"e", false, true, true},
+		{"f", true, false, true},
+		{"g", true, true, false},
+		{"h", true, true, false},
+		{"missing", true, true, false},
+	}
+	for _, tt := range tests {
+		if got := ctx.QueryBool(tt.key, tt.def); got != tt.want {
+			t.Errorf("QueryBool(%q, %v) = %v, want %v", tt.key, tt.def, got, tt.want)
+		}
+		if _, err := ctx.QueryBoolStrict(tt.key); (err == nil) != tt.strictOK {
+			t.Errorf("QueryBoolStrict(%q) error = %v", tt.key, err)
+		}
+	}
+}
+
+func TestQueryTime(t *testing.T) {
+	ctx := queryContext("since=2024-01-01&bad=01/02/2024")
+	def := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	if got := ctx.QueryTime("since", time.DateOnly, def); !got.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("QueryTime(since) = %v", got)
+	}
+	if got := ctx.QueryTime("bad", time.DateOnly, def); !got.Equal(def) {
+		t.Errorf("QueryTime(bad) = %v, want default", got)
+	}
+	if got := ctx.QueryTime("missing", time.DateOnly, def); !got.Equal(def) {
+		t.Errorf("QueryTime(missing) = %v, want default", got)
+	}
+}
+
+func TestQuerySlice(t *testing.T) {
+	ctx := queryContext("tag=a&tag=b&one=x")
+	if got := ctx.QuerySlice("tag"); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("QuerySlice(tag) = %v", got)
+	}
+	if got := ctx.QuerySlice("one"); !reflect.DeepEqual(got, []string{"x"}) {
+		t.Errorf("QuerySlice(one) = %v", got)
+	}
+	if got := ctx.QuerySlice("missing"); got != nil {
+		t.Errorf("QuerySlice(missing) = %v, want nil", got)
+	}
+}
+
+func TestQueryIntStrict(t *testing.T) {
+	ctx := queryContext("page=0&bad=x&empty=")
+
+	if v, err := ctx.QueryIntStrict("page"); err != nil || v != 0 {
+		t.Errorf("QueryIntStrict(page) = %d, %v", v, err)
+	}
+	for _, key := range []string{"bad", "empty", "missing"} {
+		_, err := ctx.QueryIntStrict(key)
+		var httpErr *HTTPError
+		if !errors.As(err, &httpErr) || httpErr.Status != http.StatusBadRequest {
+			t.Errorf("QueryIntStrict(%q) error = %v, want a 400 HTTPError", key, err)
+		}
+	}
+
+	// Returned from a handler, the error becomes a 400 response
+	r := New()
+	r.GET("/items", func(ctx *Context) (string, error) {
+		page, err := ctx.QueryIntStrict("page")
+		if err != nil {
+			return "", err
+		}
+		return "page " + strconv.Itoa(page), nil
+	})
+	if w := serve(r, "GET", "/items?page=abc"); w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+	if w := serve(r, "GET", "/items?page=3"); w.Body.String() != "page 3" {
+		t.Errorf("expected 'page 3', got %q", w.Body.String())
+	}
+}
@@ -0,0 +1,128 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"sort"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stukennedy/irgo/pkg/crash"
+	"github.com/stukennedy/irgo/pkg/render"
+)
+
+// WithDebug makes New install RecovererWithDebug(true), so panics render a
+// developer error page. Pass config.Config.Debug; never enable it in
+// production, as the page shows request headers and source paths.
+func WithDebug(debug bool) Option {
+	return func(c *routerConfig) {
+		c.debug = debug
+	}
+}
+
+// RecovererWithDebug returns middleware that recovers from panics and
+// responds with a 500. With showDebug the response is a page showing the
+// panic value, stack trace and request; otherwise it is the standard
+// error fragment and the stack is logged. Either way the panic is saved
+// with the default crash.Reporter, if one is installed. HTMX requests also get
+// HX-Retarget: body and HX-Reswap: innerHTML so the error replaces the
+// page rather than a small swap target.
+func RecovererWithDebug(showDebug bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+
+				stack := debug.Stack()
+				log.Printf("router: panic serving %s %s: %v\n%s", r.Method, r.URL.Path, rec, stack)
+				crash.Capture(crash.SourceHTTP, rec, stack, crash.RequestSummary(r), nil)
+				if r.Header.Get("Connection") == "Upgrade" {
+					return
+				}
+
+				if r.Header.Get("HX-Request") == "true" {
+					w.Header().Set("HX-Retarget", "body")
+					w.Header().Set("HX-Reswap", "innerHTML")
+				}
+				if !showDebug {
+					NewContext(w, r).ErrorStatus(http.StatusInternalServerError, "Internal Server Error")
+					return
+				}
+				w.Header().Set("Content-Type", "text/html; charset=utf-8")
+				w.WriteHeader(http.StatusInternalServerError)
+				debugPage.Execute(w, newPanicReport(r, rec, stack))
+			}()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// panicReport is the data shown on the debug error page.
+type panicReport struct {
+	Value     string
+	Source    string // Template source excerpt, for render.TemplateError panics
+	Stack     string
+	Method    string
+	URL       string
+	RequestID string
+	Headers   [][2]string
+	Fragment  bool // HTMX request: body content only
+}
+
+func newPanicReport(r *http.Request, rec any, stack []byte) panicReport {
+	report := panicReport{
+		Value:     fmt.Sprint(rec),
+		Stack:     trimStack(string(stack)),
+		Method:    r.Method,
+		URL:       r.URL.String(),
+		RequestID: middleware.GetReqID(r.Context()),
+		Fragment:  r.Header.Get("HX-Request") == "true",
+	}
+	var templateErr *render.TemplateError
+	if err, ok := rec.(error); ok && errors.As(err, &templateErr) {
+		report.Source = templateErr.SourceSnippet()
+	}
+	for name, values := range r.Header {
+		report.Headers = append(report.Headers, [2]string{name, strings.Join(values, ", ")})
+	}
+	sort.Slice(report.Headers, func(i, j int) bool { return report.Headers[i][0] < report.Headers[j][0] })
+	return report
+}
+
+// trimStack drops the frames for debug.Stack and the recovering function
+// so the trace starts at the panic.
+func trimStack(stack string) string {
+	if i := strings.Index(stack, "panic("); i >= 0 {
+		if j := strings.LastIndex(stack[:i], "\n"); j >= 0 {
+			return stack[:strings.Index(stack, "\n")+1] + stack[j+1:]
+		}
+	}
+	return stack
+}
+
+var debugPage = template.Must(template.New("panic").Parse(`{{if not .Fragment}}<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Panic: {{.Value}}</title></head><body>
+{{end}}<div class="irgo-panic" style="font-family: system-ui, sans-serif; padding: 1.5rem; color: #1f2937;">
+<h1 style="color: #b91c1c; margin-top: 0;">panic: {{.Value}}</h1>
+<p><strong>{{.Method}} {{.URL}}</strong>{{with .RequestID}} &middot; request {{.}}{{end}}</p>
+{{with .Source}}<h2>Template source</h2>
+<pre style="background: #f3f4f6; padding: 1rem; overflow-x: auto; font-size: 0.8rem;">{{.}}</pre>
+{{end}}<h2>Stack trace</h2>
+<pre style="background: #f3f4f6; padding: 1rem; overflow-x: auto; font-size: 0.8rem;">{{.Stack}}</pre>
+<h2>Request headers</h2>
+<table style="font-size: 0.85rem;">{{range .Headers}}
+<tr><th style="text-align: left; padding-right: 1rem;">{{index . 0}}</th><td>{{index . 1}}</td></tr>{{end}}
+</table>
+</div>{{if not .Fragment}}
+</body></html>{{end}}
+`))
@@ -0,0 +1,149 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stukennedy/irgo/pkg/crash"
+	"github.com/stukennedy/irgo/pkg/render"
+)
+
+func panicRouter(opts ...Option) *Router {
+	r := New(opts...)
+	r.GET("/boom", func(ctx *Context) (string, error) {
+		explode()
+		return "", nil
+	})
+	return r
+}
+
+func explode() {
+	panic("widget <exploded>")
+}
+
+func TestRecovererDebugPage(t *testing.T) {
+	r := panicRouter(WithDebug(true))
+	req := httptest.NewRequest("GET", "/boom?id=7", nil)
+	req.Header.Set("X-Trace", "abc")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status %d, want 500", w.Code)
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		"<!DOCTYPE html>",
+		"panic: widget &lt;exploded&gt;",
+		"GET /boom?id=7",
+		"router.explode",
+		"recover_test.go",
+		"X-Trace",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("debug page missing %q", want)
+		}
+	}
+	if strings.Contains(body, "runtime/debug.Stack") {
+		t.Error("stack not trimmed to the panic")
+	}
+	if w.Header().Get("HX-Retarget") != "" {
+		t.Error("HX-Retarget set for non-HTMX request")
+	}
+}
+
+func TestRecovererDebugPageTemplateSource(t *testing.T) {
+	engine := render.New()
+	engine.SetDebug(true)
+	if err := engine.Parse("greeting", "<p>\n{{.Name.First}}\n</p>"); err != nil {
+		t.Fatal(err)
+	}
+	r := New(WithDebug(true))
+	r.GET("/greet", func(ctx *Context) (string, error) {
+		return engine.MustRender("greeting", map[string]string{"Name": "Ada"}), nil
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/greet", nil))
+
+	body := w.Body.String()
+	if !strings.Contains(body, "Template source") || !strings.Contains(body, "&gt; 2 | {{.Name.First}}") {
+		t.Errorf("debug page missing template source:\n%s", body)
+	}
+}
+
+func TestRecovererProduction(t *testing.T) {
+	r := panicRouter()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/boom", nil))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status %d, want 500", w.Code)
+	}
+	body := w.Body.String()
+	if body != `<div class="error" role="alert">Internal Server Error</div>` {
+		t.Errorf("body = %q", body)
+	}
+	if strings.Contains(body, "goroutine") || strings.Contains(body, "exploded") {
+		t.Error("production response leaks panic details")
+	}
+}
+
+func TestRecovererHTMXRetarget(t *testing.T) {
+	for _, debug := range []bool{true, false} {
+		r := panicRouter(WithDebug(debug))
+		req := httptest.NewRequest("GET", "/boom", nil)
+		req.Header.Set("HX-Request", "true")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Header().Get("HX-Retarget") != "body" || w.Header().Get("HX-Reswap") != "innerHTML" {
+			t.Errorf("debug=%v: HX-Retarget=%q HX-Reswap=%q", debug, w.Header().Get("HX-Retarget"), w.Header().Get("HX-Reswap"))
+		}
+		if debug && strings.Contains(w.Body.String(), "<!DOCTYPE html>") {
+			t.Error("HTMX debug response should be a fragment")
+		}
+	}
+}
+
+func TestRecovererAbortHandler(t *testing.T) {
+	h := RecovererWithDebug(true)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+	defer func() {
+		if recover() != http.ErrAbortHandler {
+			t.Error("ErrAbortHandler not re-panicked")
+		}
+	}()
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+}
+
+func TestRecovererCrashReport(t *testing.T) {
+	rep, err := crash.NewReporter(t.TempDir(), "1.0.0", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	crash.SetDefault(rep)
+	defer crash.SetDefault(nil)
+
+	r := panicRouter()
+	req := httptest.NewRequest("GET", "/boom", nil)
+	req.Header.Set("Authorization", "Bearer secret-token")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	reports, _ := rep.Pending()
+	if len(reports) != 1 {
+		t.Fatalf("expected 1 crash report, got %d", len(reports))
+	}
+	report := reports[0]
+	if report.Source != crash.SourceHTTP || report.Panic != "widget <exploded>" {
+		t.Errorf("unexpected report %+v", report)
+	}
+	if !strings.Contains(report.Stack, "router.explode") {
+		t.Error("stack missing panicking function")
+	}
+	if report.Request == nil || report.Request.URL != "/boom" || report.Request.Headers["Authorization"] != crash.Redacted {
+		t.Errorf("unexpected request summary %+v", report.Request)
+	}
+}
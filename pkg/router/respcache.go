@@ -0,0 +1,355 @@
+package router
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// responseCacheEntries caps each response cache's LRU.
+	responseCacheEntries = 512
+
+	// maxCachedResponse is the largest body a response cache stores.
+	maxCachedResponse = 1 << 20
+)
+
+// triggerHeaders carry the events a response fires on the client.
+var triggerHeaders = []string{"HX-Trigger", "HX-Trigger-After-Swap", "HX-Trigger-After-Settle"}
+
+// responseCaches holds every cache created by ResponseCacheMiddleware, so
+// CacheInvalidate reaches all of them.
+var responseCaches struct {
+	mu  sync.Mutex
+	all []*responseCache
+}
+
+// ResponseCacheMiddleware caches successful GET responses in memory for
+// ttl, so fragments that re-render identically aren't rebuilt on every
+// request. Entries are keyed by path, query, HX-Request and HX-Target, or
+// by keyFunc if given, and also honour the response's Vary header. The
+// least recently used entries are evicted past 512.
+//
+// Responses that set a cookie, aren't 200, stream (flush), are larger
+// than 1MB or are marked Cache-Control private, no-store or no-cache are
+// not cached, and requests with an Authorization header bypass the
+// cache. The default key ignores cookies, so handlers rendering per-user
+// data must mark it private (or use a keyFunc that tells users apart).
+// Tag routes with Router.WithCacheTags so their
+// entries are purged when a response triggers a matching event:
+//
+//	r.Use(router.ResponseCacheMiddleware(time.Minute))
+//	r.WithCacheTags("todoCreated").GET("/todos", listTodos)
+//	r.POST("/todos", func(ctx *router.Context) (string, error) {
+//	    ...
+//	    ctx.Trigger("todoCreated") // Next GET /todos misses
+//	    return "", nil
+//	})
+func ResponseCacheMiddleware(ttl time.Duration, keyFunc ...func(*http.Request) string) func(http.Handler) http.Handler {
+	key := defaultCacheKey
+	if len(keyFunc) > 0 && keyFunc[0] != nil {
+		key = keyFunc[0]
+	}
+	c := &responseCache{
+		ttl:     ttl,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+	}
+	responseCaches.mu.Lock()
+	responseCaches.all = append(responseCaches.all, c)
+	responseCaches.mu.Unlock()
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet || r.Header.Get("Authorization") != "" {
+				tw := &triggerWatcher{ResponseWriter: w}
+				next.ServeHTTP(tw, r)
+				if !tw.wroteHeader {
+					tw.WriteHeader(http.StatusOK)
+				}
+				return
+			}
+
+			k := key(r)
+			if e, ok := c.get(k, r); ok {
+				h := w.Header()
+				for name, values := range e.header {
+					h[name] = slices.Clone(values)
+				}
+				w.WriteHeader(http.StatusOK)
+				w.Write(e.body)
+				return
+			}
+
+			gen := c.generation()
+			tags := new(cacheTags)
+			rec := &cacheRecorder{triggerWatcher: triggerWatcher{ResponseWriter: w}}
+			next.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), cacheTagsKey{}, tags)))
+			if rec.status == 0 {
+				rec.WriteHeader(http.StatusOK)
+			}
+			if rec.status == http.StatusOK && !rec.uncacheable {
+				c.put(k, r, gen, &cacheEntry{
+					header:  rec.Header().Clone(),
+					body:    rec.body.Bytes(),
+					expires: time.Now().Add(c.ttl),
+					tags:    tags.tags,
+				})
+			}
+		})
+	}
+}
+
+// CacheInvalidate purges the entries of every response cache tagged with
+// event (see Router.WithCacheTags). Responses triggering the event do
+// this automatically; call it when data changes outside a request.
+func CacheInvalidate(event string) {
+	responseCaches.mu.Lock()
+	caches := slices.Clone(responseCaches.all)
+	responseCaches.mu.Unlock()
+	for _, c := range caches {
+		c.invalidate(event)
+	}
+}
+
+// WithCacheTags returns a router whose routes tag their cached responses,
+// so responses triggering any of the events purge them. Requires
+// ResponseCacheMiddleware.
+func (r *Router) WithCacheTags(events ...string) *Router {
+	return r.With(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if tags, ok := req.Context().Value(cacheTagsKey{}).(*cacheTags); ok {
+				tags.tags = append(tags.tags, events...)
+			}
+			next.ServeHTTP(w, req)
+		})
+	})
+}
+
+func defaultCacheKey(r *http.Request) string {
+	return r.URL.Path + "?" + r.URL.RawQuery + "\x00" + r.Header.Get("HX-Request") + "\x00" + r.Header.Get("HX-Target")
+}
+
+type cacheTagsKey struct{}
+
+// cacheTags collects the tags of the route serving a request.
+type cacheTags struct {
+	tags []string
+}
+
+type responseCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	gen     uint64     // Bumped by each invalidation
+	lru     *list.List // Front = most recently used
+	entries map[string]*list.Element
+}
+
+type cacheEntry struct {
+	key     string
+	header  http.Header
+	body    []byte
+	expires time.Time
+	tags    []string
+	vary    map[string]string // Request headers the response varies on
+}
+
+func (c *responseCache) get(key string, r *http.Request) (*cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*cacheEntry)
+	if time.Now().After(e.expires) {
+		c.remove(el)
+		return nil, false
+	}
+	for name, value := range e.vary {
+		if r.Header.Get(name) != value {
+			return nil, false
+		}
+	}
+	c.lru.MoveToFront(el)
+	return e, true
+}
+
+// put stores e unless the cache was invalidated since gen, when the
+// response may predate the change.
+func (c *responseCache) put(key string, r *http.Request, gen uint64, e *cacheEntry) {
+	e.key = key
+	e.vary = make(map[string]string)
+	for _, v := range e.header.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				e.vary[name] = r.Header.Get(name)
+			}
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.gen != gen {
+		return
+	}
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+	}
+	c.entries[key] = c.lru.PushFront(e)
+	for c.lru.Len() > responseCacheEntries {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *responseCache) generation() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.gen
+}
+
+func (c *responseCache) invalidate(event string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	for el := c.lru.Front(); el != nil; {
+		next := el.Next()
+		if slices.Contains(el.Value.(*cacheEntry).tags, event) {
+			c.remove(el)
+		}
+		el = next
+	}
+}
+
+// remove deletes an entry. Must be called with c.mu held.
+func (c *responseCache) remove(el *list.Element) {
+	c.lru.Remove(el)
+	delete(c.entries, el.Value.(*cacheEntry).key)
+}
+
+// triggerWatcher invalidates the events a response triggers as its
+// headers are written, before the client can act on them.
+type triggerWatcher struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (w *triggerWatcher) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		for _, event := range triggeredEvents(w.Header()) {
+			CacheInvalidate(event)
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *triggerWatcher) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *triggerWatcher) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *triggerWatcher) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// cacheRecorder passes a response through while keeping a copy to cache.
+type cacheRecorder struct {
+	triggerWatcher
+	status      int
+	body        bytes.Buffer
+	uncacheable bool
+}
+
+func (r *cacheRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+		h := r.Header()
+		if h.Get("Set-Cookie") != "" || len(triggeredEvents(h)) > 0 || privateResponse(h) {
+			r.uncacheable = true
+		}
+	}
+	r.triggerWatcher.WriteHeader(status)
+}
+
+func (r *cacheRecorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.WriteHeader(http.StatusOK)
+	}
+	if !r.uncacheable {
+		if r.body.Len()+len(p) > maxCachedResponse {
+			r.uncacheable = true
+			r.body = bytes.Buffer{}
+		} else {
+			r.body.Write(p)
+		}
+	}
+	return r.triggerWatcher.Write(p)
+}
+
+// Flush marks the response as a stream, which isn't cached.
+func (r *cacheRecorder) Flush() {
+	if r.status == 0 {
+		r.WriteHeader(http.StatusOK)
+	}
+	r.uncacheable = true
+	r.triggerWatcher.Flush()
+}
+
+// privateResponse reports whether h's Cache-Control keeps the response
+// out of shared caches.
+func privateResponse(h http.Header) bool {
+	for _, v := range h.Values("Cache-Control") {
+		for _, directive := range strings.Split(v, ",") {
+			name, _, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			switch strings.ToLower(name) {
+			case "private", "no-store", "no-cache":
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// triggeredEvents returns the event names in h's trigger headers, which
+// hold either a comma-separated list or a JSON object keyed by event.
+func triggeredEvents(h http.Header) []string {
+	var events []string
+	for _, name := range triggerHeaders {
+		v := strings.TrimSpace(h.Get(name))
+		if v == "" {
+			continue
+		}
+		if strings.HasPrefix(v, "{") {
+			var obj map[string]json.RawMessage
+			if json.Unmarshal([]byte(v), &obj) == nil {
+				for event := range obj {
+					events = append(events, event)
+				}
+			}
+			continue
+		}
+		for _, event := range strings.Split(v, ",") {
+			if event = strings.TrimSpace(event); event != "" {
+				events = append(events, event)
+			}
+		}
+	}
+	return events
+}
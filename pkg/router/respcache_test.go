@@ -0,0 +1,190 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// cachedTodos returns a router with a cached, tagged GET /todos that
+// counts renders.
+func cachedTodos(ttl time.Duration) (*Router, *int) {
+	renders := 0
+	r := New()
+	r.Use(ResponseCacheMiddleware(ttl))
+	r.WithCacheTags("todoCreated").GET("/todos", func(ctx *Context) (string, error) {
+		renders++
+		return "todos v" + strconv.Itoa(renders), nil
+	})
+	r.POST("/todos", func(ctx *Context) (string, error) {
+		ctx.Trigger("todoCreated")
+		return "created", nil
+	})
+	r.POST("/other", func(ctx *Context) (string, error) {
+		ctx.Trigger("userChanged")
+		return "ok", nil
+	})
+	return r, &renders
+}
+
+func TestResponseCacheHit(t *testing.T) {
+	r, renders := cachedTodos(time.Minute)
+	for i := 0; i < 3; i++ {
+		if w := serve(r, "GET", "/todos"); w.Body.String() != "todos v1" {
+			t.Fatalf("GET %d: body = %q", i, w.Body.String())
+		}
+	}
+	if *renders != 1 {
+		t.Errorf("renders = %d, want 1", *renders)
+	}
+
+	serve(r, "GET", "/todos?page=2")
+	req := httptest.NewRequest("GET", "/todos", nil)
+	req.Header.Set("HX-Target", "#list")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	if *renders != 3 {
+		t.Errorf("renders = %d, want query and HX-Target to key separately", *renders)
+	}
+}
+
+func TestResponseCacheTriggerInvalidates(t *testing.T) {
+	r, renders := cachedTodos(time.Minute)
+	serve(r, "GET", "/todos")
+
+	serve(r, "POST", "/other")
+	if w := serve(r, "GET", "/todos"); w.Body.String() != "todos v1" {
+		t.Errorf("after unrelated event: body = %q, want hit", w.Body.String())
+	}
+
+	w := serve(r, "POST", "/todos")
+	if got := w.Header().Get("HX-Trigger"); got != "todoCreated" {
+		t.Errorf("HX-Trigger = %q", got)
+	}
+	if w := serve(r, "GET", "/todos"); w.Body.String() != "todos v2" {
+		t.Errorf("after todoCreated: body = %q, want miss", w.Body.String())
+	}
+	if *renders != 2 {
+		t.Errorf("renders = %d, want 2", *renders)
+	}
+
+	CacheInvalidate("todoCreated")
+	if w := serve(r, "GET", "/todos"); w.Body.String() != "todos v3" {
+		t.Errorf("after CacheInvalidate: body = %q, want miss", w.Body.String())
+	}
+}
+
+func TestResponseCacheExpires(t *testing.T) {
+	r, renders := cachedTodos(time.Nanosecond)
+	serve(r, "GET", "/todos")
+	time.Sleep(time.Millisecond)
+	serve(r, "GET", "/todos")
+	if *renders != 2 {
+		t.Errorf("renders = %d, want expired entry to miss", *renders)
+	}
+}
+
+func TestResponseCacheSkips(t *testing.T) {
+	renders := 0
+	r := New()
+	r.Use(ResponseCacheMiddleware(time.Minute))
+	r.GET("/cookie", func(ctx *Context) (string, error) {
+		renders++
+		ctx.SetCookie(&http.Cookie{Name: "seen", Value: "1"})
+		return "hi", nil
+	})
+	r.GET("/missing", func(ctx *Context) (string, error) {
+		renders++
+		return "", ErrNotFound("nope")
+	})
+	r.Handle("/stream", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		renders++
+		w.Write([]byte("data: 1\n\n"))
+		w.(http.Flusher).Flush()
+	}))
+
+	for _, path := range []string{"/cookie", "/missing", "/stream"} {
+		renders = 0
+		serve(r, "GET", path)
+		serve(r, "GET", path)
+		if renders != 2 {
+			t.Errorf("%s: renders = %d, want uncached", path, renders)
+		}
+	}
+}
+
+func TestResponseCachePrivate(t *testing.T) {
+	renders := 0
+	r := New()
+	r.Use(ResponseCacheMiddleware(time.Minute))
+	r.GET("/me", func(ctx *Context) (string, error) {
+		renders++
+		ctx.Response.Header().Set("Cache-Control", "private, no-store")
+		c, err := ctx.Request.Cookie("session")
+		if err != nil {
+			return "", err
+		}
+		return "hello " + c.Value, nil
+	})
+	r.GET("/profile", func(ctx *Context) (string, error) {
+		renders++
+		return "profile of " + ctx.Header("Authorization"), nil
+	})
+
+	for _, user := range []string{"alice", "bob", "alice"} {
+		req := httptest.NewRequest("GET", "/me", nil)
+		req.AddCookie(&http.Cookie{Name: "session", Value: user})
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if want := "hello " + user; w.Body.String() != want {
+			t.Errorf("cookie %s: body = %q, want %q", user, w.Body.String(), want)
+		}
+	}
+	for _, user := range []string{"alice", "bob"} {
+		req := httptest.NewRequest("GET", "/profile", nil)
+		req.Header.Set("Authorization", "Bearer "+user)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if want := "profile of Bearer " + user; w.Body.String() != want {
+			t.Errorf("Authorization %s: body = %q, want %q", user, w.Body.String(), want)
+		}
+	}
+	if renders != 5 {
+		t.Errorf("renders = %d, want every private response rendered", renders)
+	}
+}
+
+func TestResponseCacheVary(t *testing.T) {
+	renders := 0
+	r := New()
+	r.Use(ResponseCacheMiddleware(time.Minute))
+	r.GET("/", func(ctx *Context) (string, error) {
+		renders++
+		AddVary(ctx.Response.Header(), "Accept-Language")
+		return ctx.Header("Accept-Language"), nil
+	})
+
+	for _, lang := range []string{"en", "en", "fr", "en"} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept-Language", lang)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Body.String() != lang {
+			t.Errorf("Accept-Language %s: body = %q", lang, w.Body.String())
+		}
+	}
+	if renders != 3 {
+		t.Errorf("renders = %d, want 3", renders)
+	}
+}
+
+func TestTriggeredEvents(t *testing.T) {
+	h := http.Header{}
+	h.Set("HX-Trigger", "a, b")
+	h.Set("HX-Trigger-After-Settle", `{"c": {"id": 1}}`)
+	if got, want := triggeredEvents(h), []string{"a", "b", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("triggeredEvents = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,65 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/stukennedy/irgo/pkg/routemeta"
+)
+
+// Route is a handle to a registered route, returned by the registration
+// methods so per-route options can be chained:
+//
+//	r.GET("/assets/app.css", handler).Cache("static")
+type Route struct {
+	// Method is the HTTP method the route responds to, or "*" for Any.
+	Method string
+
+	// Pattern is the full route pattern, including any Route() prefixes.
+	Pattern string
+
+	router *Router
+	cache  *CacheConfig
+	poll   *poller
+}
+
+func (r *Router) newRoute(method, pattern string) *Route {
+	return &Route{
+		Method:  method,
+		Pattern: r.prefix + pattern,
+		router:  r,
+	}
+}
+
+// Cache applies a named cache profile to the route's successful
+// responses. Panics if the profile has not been registered with
+// Router.CacheProfile (see Router.HasCacheProfile).
+func (rt *Route) Cache(profile string) *Route {
+	cfg := rt.router.cacheProfile(profile)
+	rt.cache = &cfg
+	return rt
+}
+
+// Describe documents the route for client generation and conformance tests.
+// Method and Pattern are filled in from the route.
+func (rt *Route) Describe(doc routemeta.Doc) *Route {
+	doc.Method = rt.Method
+	doc.Pattern = rt.Pattern
+	rt.router.config.docs = append(rt.router.config.docs, doc)
+	return rt
+}
+
+// Docs returns the metadata of all routes documented with Route.Describe,
+// in registration order. Write it with routemeta.Write for
+// "irgo generate client".
+func (r *Router) Docs() []routemeta.Doc {
+	return append([]routemeta.Doc(nil), r.config.docs...)
+}
+
+// apply sets per-route response headers before the handler runs,
+// returning the writer the handler should use.
+func (rt *Route) apply(w http.ResponseWriter) http.ResponseWriter {
+	if rt.cache != nil {
+		return rt.cache.writer(w)
+	}
+	return w
+}
@@ -2,10 +2,16 @@
 package router
 
 import (
+	"log"
 	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stukennedy/irgo/pkg/images"
+	"github.com/stukennedy/irgo/pkg/routemeta"
 )
 
 // FragmentHandler is a handler function that returns an HTML fragment.
@@ -20,24 +26,108 @@ type SSEHandler func(ctx *Context) error
 
 // Router wraps chi with hypermedia-specific conventions.
 type Router struct {
-	mux *chi.Mux
+	mux    chi.Router
+	prefix string
+	config *routerConfig
+	stack  *middlewareStack // Middleware added with Use
+	layout *templLayout     // Set with SetLayout
 }
 
-// New creates a new Router with default middleware.
-func New() *Router {
+// routerConfig holds state shared by a router and all of its sub-routers.
+type routerConfig struct {
+	cacheProfiles map[string]CacheConfig
+	cookieSecret  []byte
+	data          sync.Map // Content tables for Context.HandlerData
+	debug         bool     // Panics render a developer error page
+	docs          []routemeta.Doc
+	heads         map[string]bool   // Patterns with an explicit HEAD handler
+	methods       map[string]bool   // "METHOD pattern" with a handler; see Any
+	mounts        []mountedRouter   // Routers attached with Mount
+	notAllowed    http.HandlerFunc  // 405 handler dispatched to by New's default
+	names         map[string]string // Route name → pattern
+	routeNames    map[string]string // "METHOD pattern" → route name
+	proxies       []netip.Prefix    // Trusted proxies for Context.ClientIP
+	sandboxes     []sandbox         // Prefixes whose HTML is sanitised; see WithSandbox
+	validator     Validator         // Set with WithValidator; nil uses ValidateStruct
+}
+
+func newRouterConfig(opts []Option) *routerConfig {
+	c := &routerConfig{
+		cacheProfiles: defaultCacheProfiles(),
+		heads:         make(map[string]bool),
+		methods:       make(map[string]bool),
+		names:         make(map[string]string),
+		routeNames:    make(map[string]string),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Option configures a Router.
+type Option func(*routerConfig)
+
+// WithCookieSecret sets the HMAC key used by Context.SetSignedCookie
+// and Context.SignedCookie.
+func WithCookieSecret(secret []byte) Option {
+	return func(c *routerConfig) {
+		c.cookieSecret = secret
+	}
+}
+
+// New creates a new Router with default middleware and a 405 handler
+// that lists the allowed methods (see MethodNotAllowedEvent).
+func New(opts ...Option) *Router {
 	r := chi.NewRouter()
+	config := newRouterConfig(opts)
 
 	// Default middleware
-	r.Use(middleware.Recoverer)
+	r.Use(RecovererWithDebug(config.debug))
 	r.Use(middleware.RequestID)
 	r.Use(DatastarRequestMiddleware)
+	if len(config.sandboxes) > 0 {
+		r.Use(sandboxMiddleware(config))
+	}
 
-	return &Router{mux: r}
+	router := newRouter(r, "", config)
+	config.notAllowed = router.methodNotAllowed
+	r.MethodNotAllowed(func(w http.ResponseWriter, req *http.Request) {
+		config.notAllowed(w, req)
+	})
+	return router
 }
 
 // NewWithoutMiddleware creates a Router without default middleware.
-func NewWithoutMiddleware() *Router {
-	return &Router{mux: chi.NewRouter()}
+// Sandboxes set with WithSandbox are still enforced.
+func NewWithoutMiddleware(opts ...Option) *Router {
+	r := chi.NewRouter()
+	config := newRouterConfig(opts)
+	if len(config.sandboxes) > 0 {
+		r.Use(sandboxMiddleware(config))
+	}
+	return newRouter(r, "", config)
+}
+
+// newRouter wraps mux, installing the stack that Use adds to.
+func newRouter(mux chi.Router, prefix string, config *routerConfig) *Router {
+	stack := &middlewareStack{}
+	mux.Use(stack.handler)
+	return &Router{mux: mux, prefix: prefix, config: config, stack: stack, layout: &templLayout{}}
+}
+
+// newContext creates a handler Context bound to this router's configuration.
+func (r *Router) newContext(w http.ResponseWriter, req *http.Request) *Context {
+	ctx := NewContext(w, req)
+	ctx.config = r.config
+	return ctx
+}
+
+// sub returns a Router sharing this router's configuration.
+func (r *Router) sub(mux chi.Router, prefix string) *Router {
+	s := newRouter(mux, prefix, r.config)
+	s.layout.parent = r.layout
+	return s
 }
 
 // Handler returns the underlying http.Handler for use with the adapter.
@@ -45,31 +135,54 @@ func (r *Router) Handler() http.Handler {
 	return r.mux
 }
 
-// Use adds middleware to the router.
+// Use adds middleware to the router. Unlike chi, it may be called after
+// routes are registered; the middleware still runs for them.
 func (r *Router) Use(middlewares ...func(http.Handler) http.Handler) {
-	r.mux.Use(middlewares...)
+	r.stack.add(middlewares...)
 }
 
 // Fragment registers a handler that returns HTML fragments (for initial page loads).
-func (r *Router) Fragment(method, pattern string, handler FragmentHandler) {
-	r.mux.Method(method, pattern, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		ctx := NewContext(w, req)
+func (r *Router) Fragment(method, pattern string, handler FragmentHandler) *Route {
+	route := r.newRoute(method, pattern)
+	r.handle(method, pattern, r.fragmentHandler(route, handler))
+	return route
+}
+
+func (r *Router) fragmentHandler(route *Route, handler FragmentHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w = route.apply(w)
+		ctx := r.newContext(w, req)
 		html, err := handler(ctx)
 		if err != nil {
+			if ctx.Written() {
+				// The response is under way, e.g. a template streamed
+				// by StreamTemplate failed partway, and can't be replaced
+				log.Printf("router: %s %s: %v", req.Method, req.URL.Path, err)
+				return
+			}
 			ctx.Error(err)
 			return
 		}
 		if !ctx.Written() {
+			if route.poll != nil {
+				route.poll.pollFragment(ctx, html)
+			}
 			ctx.HTML(html)
 		}
-	}))
+	})
 }
 
 // SSE registers a handler for Datastar SSE requests.
-func (r *Router) SSE(method, pattern string, handler SSEHandler) {
-	r.mux.Method(method, pattern, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		ctx := NewContext(w, req)
-		if err := handler(ctx); err != nil {
+func (r *Router) SSE(method, pattern string, handler SSEHandler) *Route {
+	route := r.newRoute(method, pattern)
+	r.method(method, pattern, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w = route.apply(w)
+		ctx := r.newContext(w, req)
+		run := handler
+		if route.poll != nil {
+			run = func(ctx *Context) error { return route.poll.pollStream(ctx, handler) }
+		}
+		if err := run(ctx); err != nil {
 			// If not yet streaming, we can send an error response
 			if !ctx.Written() {
 				ctx.Error(err)
@@ -77,31 +190,32 @@ func (r *Router) SSE(method, pattern string, handler SSEHandler) {
 			// If already streaming, error was already logged via SSE.ConsoleError
 		}
 	}))
+	return route
 }
 
 // GET registers a GET handler that returns HTML fragments.
-func (r *Router) GET(pattern string, handler FragmentHandler) {
-	r.Fragment(http.MethodGet, pattern, handler)
+func (r *Router) GET(pattern string, handler FragmentHandler) *Route {
+	return r.Fragment(http.MethodGet, pattern, handler)
 }
 
 // POST registers a POST handler that returns HTML fragments.
-func (r *Router) POST(pattern string, handler FragmentHandler) {
-	r.Fragment(http.MethodPost, pattern, handler)
+func (r *Router) POST(pattern string, handler FragmentHandler) *Route {
+	return r.Fragment(http.MethodPost, pattern, handler)
 }
 
 // PUT registers a PUT handler that returns HTML fragments.
-func (r *Router) PUT(pattern string, handler FragmentHandler) {
-	r.Fragment(http.MethodPut, pattern, handler)
+func (r *Router) PUT(pattern string, handler FragmentHandler) *Route {
+	return r.Fragment(http.MethodPut, pattern, handler)
 }
 
 // PATCH registers a PATCH handler that returns HTML fragments.
-func (r *Router) PATCH(pattern string, handler FragmentHandler) {
-	r.Fragment(http.MethodPatch, pattern, handler)
+func (r *Router) PATCH(pattern string, handler FragmentHandler) *Route {
+	return r.Fragment(http.MethodPatch, pattern, handler)
 }
 
 // DELETE registers a DELETE handler that returns HTML fragments.
-func (r *Router) DELETE(pattern string, handler FragmentHandler) {
-	r.Fragment(http.MethodDelete, pattern, handler)
+func (r *Router) DELETE(pattern string, handler FragmentHandler) *Route {
+	return r.Fragment(http.MethodDelete, pattern, handler)
 }
 
 // --- Datastar SSE Handlers ---
@@ -109,28 +223,28 @@ func (r *Router) DELETE(pattern string, handler FragmentHandler) {
 // Use ctx.SSE() methods to stream DOM patches and signal updates.
 
 // DSGet registers a GET handler for Datastar SSE requests.
-func (r *Router) DSGet(pattern string, handler SSEHandler) {
-	r.SSE(http.MethodGet, pattern, handler)
+func (r *Router) DSGet(pattern string, handler SSEHandler) *Route {
+	return r.SSE(http.MethodGet, pattern, handler)
 }
 
 // DSPost registers a POST handler for Datastar SSE requests.
-func (r *Router) DSPost(pattern string, handler SSEHandler) {
-	r.SSE(http.MethodPost, pattern, handler)
+func (r *Router) DSPost(pattern string, handler SSEHandler) *Route {
+	return r.SSE(http.MethodPost, pattern, handler)
 }
 
 // DSPut registers a PUT handler for Datastar SSE requests.
-func (r *Router) DSPut(pattern string, handler SSEHandler) {
-	r.SSE(http.MethodPut, pattern, handler)
+func (r *Router) DSPut(pattern string, handler SSEHandler) *Route {
+	return r.SSE(http.MethodPut, pattern, handler)
 }
 
 // DSPatch registers a PATCH handler for Datastar SSE requests.
-func (r *Router) DSPatch(pattern string, handler SSEHandler) {
-	r.SSE(http.MethodPatch, pattern, handler)
+func (r *Router) DSPatch(pattern string, handler SSEHandler) *Route {
+	return r.SSE(http.MethodPatch, pattern, handler)
 }
 
 // DSDelete registers a DELETE handler for Datastar SSE requests.
-func (r *Router) DSDelete(pattern string, handler SSEHandler) {
-	r.SSE(http.MethodDelete, pattern, handler)
+func (r *Router) DSDelete(pattern string, handler SSEHandler) *Route {
+	return r.SSE(http.MethodDelete, pattern, handler)
 }
 
 // Handle registers a standard http.Handler.
@@ -145,31 +259,34 @@ func (r *Router) HandleFunc(pattern string, handler http.HandlerFunc) {
 
 // Mount attaches a sub-router at the given pattern.
 func (r *Router) Mount(pattern string, handler http.Handler) {
+	if sub, ok := handler.(*Router); ok {
+		// Mount the mux itself so chi, and Routes, can see its routes
+		handler = sub.mux
+		r.config.mounts = append(r.config.mounts, mountedRouter{r.prefix + strings.TrimSuffix(pattern, "/"), sub.config})
+	}
 	r.mux.Mount(pattern, handler)
 }
 
-// Group creates a new route group with shared middleware.
+// Group creates a route group at the current path. Middleware added with
+// Use inside fn applies only to routes registered in the group.
 func (r *Router) Group(fn func(r *Router)) {
 	r.mux.Group(func(c chi.Router) {
-		// Create sub-router that wraps the chi Router interface
-		subRouter := &Router{mux: chi.NewRouter()}
-		fn(subRouter)
-		// Mount the sub-router's routes
-		c.Mount("/", subRouter.mux)
+		fn(r.sub(c, r.prefix))
 	})
 }
 
-// Route creates a new route group at the given pattern.
+// Route creates a route group at the given pattern. Patterns registered in
+// fn are relative to it, and middleware added with Use applies only to the
+// group.
 func (r *Router) Route(pattern string, fn func(r *Router)) {
 	r.mux.Route(pattern, func(c chi.Router) {
-		subRouter := &Router{mux: c.(*chi.Mux)}
-		fn(subRouter)
+		fn(r.sub(c, r.prefix+pattern))
 	})
 }
 
 // With adds inline middleware for a route.
 func (r *Router) With(middlewares ...func(http.Handler) http.Handler) *Router {
-	return &Router{mux: r.mux.With(middlewares...).(*chi.Mux)}
+	return r.sub(r.mux.With(middlewares...), r.prefix)
 }
 
 // NotFound registers a custom 404 handler.
@@ -177,8 +294,14 @@ func (r *Router) NotFound(handler http.HandlerFunc) {
 	r.mux.NotFound(handler)
 }
 
-// MethodNotAllowed registers a custom 405 handler.
+// MethodNotAllowed registers a custom 405 handler. On a router from New
+// it replaces the default handler everywhere, including in subrouters
+// already mounted.
 func (r *Router) MethodNotAllowed(handler http.HandlerFunc) {
+	if r.config.notAllowed != nil {
+		r.config.notAllowed = handler
+		return
+	}
 	r.mux.MethodNotAllowed(handler)
 }
 
@@ -190,13 +313,38 @@ func (r *Router) Static(pattern string, root http.FileSystem) {
 	}
 	pattern += "*"
 
+	fs := http.FileServer(root)
 	r.mux.Get(pattern, func(w http.ResponseWriter, req *http.Request) {
-		rctx := chi.RouteContext(req.Context())
-		pathPrefix := pattern[:len(pattern)-1]
-		fs := http.StripPrefix(pathPrefix, http.FileServer(root))
-		rctx.URLParams.Add("*", req.URL.Path[len(pathPrefix):])
-		fs.ServeHTTP(w, req)
+		// Serve the decoded, cleaned wildcard so dot segments can't climb
+		// above the prefix and mounting under any prefix works
+		p := cleanWildcard(req)
+		if p != "/" && strings.HasSuffix(wildcard(req), "/") {
+			p += "/"
+		}
+		req2 := req.Clone(req.Context())
+		req2.URL.Path = p
+		req2.URL.RawPath = ""
+		fs.ServeHTTP(w, req2)
+	})
+}
+
+// Images serves resized images from load under pattern, e.g.
+// r.Images("/img", images.FromFS(assets), images.Options{}) serves
+// /img/{name}?w=300&dpr=2. Returns an error if the cache directory
+// can't be created.
+func (r *Router) Images(pattern string, load images.Loader, opts images.Options) error {
+	h, err := images.NewHandler(load, opts)
+	if err != nil {
+		return err
+	}
+	r.mux.Get(strings.TrimSuffix(pattern, "/")+"/*", func(w http.ResponseWriter, req *http.Request) {
+		// Serve the wildcard remainder so mounting under any prefix works
+		req2 := req.Clone(req.Context())
+		req2.URL.Path = cleanWildcard(req)
+		req2.URL.RawPath = ""
+		h.ServeHTTP(w, req2)
 	})
+	return nil
 }
 
 // ServeHTTP implements http.Handler.
@@ -1,11 +1,18 @@
 package router
 
 import (
+	"bytes"
+	"image"
+	"image/png"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"testing/fstest"
+
+	"github.com/stukennedy/irgo/pkg/images"
+	"github.com/stukennedy/irgo/pkg/routemeta"
 )
 
 func TestNew(t *testing.T) {
@@ -189,6 +196,95 @@ func TestRoute(t *testing.T) {
 	}
 }
 
+func TestGroupScopedMiddleware(t *testing.T) {
+	requireAuth := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.Header.Get("Authorization") == "" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, req)
+		})
+	}
+
+	r := New()
+	r.Group(func(g *Router) {
+		g.Use(requireAuth)
+		g.Route("/admin", func(admin *Router) {
+			admin.GET("/users/{id}", func(ctx *Context) (string, error) {
+				return "admin " + ctx.Param("id"), nil
+			})
+		})
+	})
+	r.Route("/public", func(pub *Router) {
+		pub.GET("/users/{id}", func(ctx *Context) (string, error) {
+			return "public " + ctx.Param("id"), nil
+		})
+	})
+
+	tests := []struct {
+		path   string
+		auth   string
+		status int
+		body   string
+	}{
+		{"/admin/users/7", "", http.StatusUnauthorized, ""},
+		{"/admin/users/7", "Bearer x", http.StatusOK, "admin 7"},
+		{"/public/users/7", "", http.StatusOK, "public 7"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		if tt.auth != "" {
+			req.Header.Set("Authorization", tt.auth)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != tt.status {
+			t.Errorf("%s (auth %q): expected status %d, got %d", tt.path, tt.auth, tt.status, w.Code)
+		}
+		if tt.body != "" && w.Body.String() != tt.body {
+			t.Errorf("%s: expected %q, got %q", tt.path, tt.body, w.Body.String())
+		}
+	}
+}
+
+func TestNestedRouteParams(t *testing.T) {
+	r := New()
+	var calls []string
+	r.Route("/api", func(api *Router) {
+		api.Use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				calls = append(calls, req.URL.Path)
+				next.ServeHTTP(w, req)
+			})
+		})
+		api.Route("/users/{id}", func(user *Router) {
+			user.GET("/posts/{postID}", func(ctx *Context) (string, error) {
+				return ctx.Param("id") + "/" + ctx.Param("postID"), nil
+			}).Describe(routemeta.Doc{Name: "userPost"})
+		})
+	})
+	r.GET("/health", func(ctx *Context) (string, error) {
+		return "ok", nil
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/users/3/posts/9", nil))
+	if w.Body.String() != "3/9" {
+		t.Errorf("expected params 3/9, got %q", w.Body.String())
+	}
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/health", nil))
+	if len(calls) != 1 || calls[0] != "/api/users/3/posts/9" {
+		t.Errorf("expected group middleware only for /api routes, got %v", calls)
+	}
+
+	if docs := r.Docs(); len(docs) != 1 || docs[0].Pattern != "/api/users/{id}/posts/{postID}" {
+		t.Errorf("expected composed pattern, got %+v", docs)
+	}
+}
+
 func TestNotFound(t *testing.T) {
 	r := New()
 	r.NotFound(func(w http.ResponseWriter, req *http.Request) {
@@ -414,3 +510,51 @@ func readBody(resp *http.Response) string {
 	resp.Body.Close()
 	return string(body)
 }
+
+func TestImages(t *testing.T) {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 40, 20)))
+	fsys := fstest.MapFS{"avatars/a.png": {Data: buf.Bytes()}}
+
+	r := New()
+	r.Route("/media", func(r *Router) {
+		if err := r.Images("/img", images.FromFS(fsys), images.Options{}); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	req := httptest.NewRequest("GET", "/media/img/avatars/a.png?w=10", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	cfg, err := png.DecodeConfig(w.Body)
+	if err != nil || cfg.Width != 10 || cfg.Height != 5 {
+		t.Errorf("expected 10x5 image, got %+v (err %v)", cfg, err)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	r := New()
+	r.POST("/todos", func(ctx *Context) (string, error) {
+		return "", nil
+	}).Describe(routemeta.Doc{Name: "createTodo", Fields: []routemeta.Field{{Name: "title", Required: true}}})
+	r.Route("/api", func(api *Router) {
+		api.GET("/items/{id}", func(ctx *Context) (string, error) {
+			return "", nil
+		}).Describe(routemeta.Doc{Summary: "Get an item."})
+	})
+
+	docs := r.Docs()
+	if len(docs) != 2 {
+		t.Fatalf("expected 2 docs, got %d", len(docs))
+	}
+	if docs[0].Method != "POST" || docs[0].Pattern != "/todos" || docs[0].Name != "createTodo" {
+		t.Errorf("unexpected first doc: %+v", docs[0])
+	}
+	if docs[1].Method != "GET" || docs[1].Pattern != "/api/items/{id}" {
+		t.Errorf("unexpected second doc: %+v", docs[1])
+	}
+}
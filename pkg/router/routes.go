@@ -0,0 +1,131 @@
+package router
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// RouteInfo describes a registered route.
+type RouteInfo struct {
+	Method     string
+	Pattern    string   // Full pattern, including Route and Mount prefixes
+	Name       string   // Set with Route.Name, or ""
+	Middleware []string // Function names, outermost first
+}
+
+// Routes lists the routes in the router's tree, including those added
+// through Route, Group, With and Mount, sorted by pattern and then method.
+// Middleware added with Use is listed in place, even if added after the
+// route.
+func (r *Router) Routes() []RouteInfo {
+	var routes []RouteInfo
+	chi.Walk(r.mux, func(method, pattern string, _ http.Handler, mws ...func(http.Handler) http.Handler) error {
+		pattern = r.prefix + pattern
+		routes = append(routes, RouteInfo{
+			Method:     method,
+			Pattern:    pattern,
+			Name:       r.routeName(method, pattern),
+			Middleware: middlewareNames(mws),
+		})
+		return nil
+	})
+	slices.SortFunc(routes, func(a, b RouteInfo) int {
+		if c := strings.Compare(a.Pattern, b.Pattern); c != 0 {
+			return c
+		}
+		return slices.Index(routeMethods, a.Method) - slices.Index(routeMethods, b.Method)
+	})
+	return routes
+}
+
+// Match returns the route a request for method and path would hit, for
+// checking routing in tests:
+//
+//	route, ok := r.Match("GET", "/todos/42")
+//	// route.Pattern == "/todos/{id}"
+func (r *Router) Match(method, path string) (RouteInfo, bool) {
+	pattern := r.mux.Find(chi.NewRouteContext(), method, strings.TrimPrefix(path, r.prefix))
+	if pattern == "" {
+		return RouteInfo{}, false
+	}
+	pattern = strings.ReplaceAll(r.prefix+pattern, "/*/", "/")
+	for _, route := range r.Routes() {
+		if route.Method == method && route.Pattern == pattern {
+			return route, true
+		}
+	}
+	return RouteInfo{Method: method, Pattern: pattern, Name: r.routeName(method, pattern)}, true
+}
+
+// mountedRouter is a Router attached with Mount, whose route names are
+// relative to prefix.
+type mountedRouter struct {
+	prefix string
+	config *routerConfig
+}
+
+func (r *Router) routeName(method, pattern string) string {
+	return r.config.routeName(method, pattern)
+}
+
+func (c *routerConfig) routeName(method, pattern string) string {
+	if name, ok := c.routeNames[method+" "+pattern]; ok {
+		return name
+	}
+	if name, ok := c.routeNames["* "+pattern]; ok {
+		return name
+	}
+	for _, m := range c.mounts {
+		if rest, ok := strings.CutPrefix(pattern, m.prefix); ok && m.config != c {
+			if name := m.config.routeName(method, rest); name != "" {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// closureSuffix matches the suffix Go gives closures and method values.
+var closureSuffix = regexp.MustCompile(`(\.func\d+|\.\d+)+$|-fm$`)
+
+// middlewareNames names each middleware, expanding Use stacks into the
+// middleware they hold.
+func middlewareNames(mws []func(http.Handler) http.Handler) []string {
+	var names []string
+	for _, mw := range mws {
+		if isStack(mw) {
+			if h, ok := mw(http.NotFoundHandler()).(*stackHandler); ok {
+				names = append(names, middlewareNames(h.stack.snapshot())...)
+				continue
+			}
+		}
+		names = append(names, funcName(mw))
+	}
+	return names
+}
+
+var stackHandlerName = funcName((&middlewareStack{}).handler)
+
+func isStack(mw func(http.Handler) http.Handler) bool {
+	return funcName(mw) == stackHandlerName
+}
+
+// funcName returns a function's package-qualified name without its
+// import path or closure suffixes, e.g. "middleware.RequestID".
+func funcName(fn any) string {
+	f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer())
+	if f == nil {
+		return "?"
+	}
+	name := f.Name()
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	return closureSuffix.ReplaceAllString(name, "")
+}
@@ -0,0 +1,78 @@
+package router
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func okHandler(ctx *Context) (string, error) { return "ok", nil }
+
+func introspectedRouter() *Router {
+	r := NewWithoutMiddleware()
+	r.Use(NoCacheMiddleware)
+	r.GET("/todos/{id}", okHandler).Name("todo")
+	r.Group(func(g *Router) {
+		g.Use(RequireDatastar)
+		g.POST("/todos", okHandler)
+	})
+	r.Route("/api", func(api *Router) {
+		api.Route("/v1", func(v1 *Router) {
+			v1.With(MaxBodySize(1024)).PUT("/items/{id}", okHandler).Name("item.update")
+		})
+	})
+
+	admin := NewWithoutMiddleware()
+	admin.Use(NoJSMiddleware)
+	admin.GET("/users", okHandler).Name("admin.users")
+	r.Mount("/admin", admin)
+	return r
+}
+
+func TestRoutes(t *testing.T) {
+	r := introspectedRouter()
+	// Added after the routes, still listed
+	r.Use(VaryHTMX())
+
+	var got []RouteInfo
+	for _, route := range r.Routes() {
+		if route.Method != http.MethodHead {
+			got = append(got, route)
+		}
+	}
+	want := []RouteInfo{
+		{"GET", "/admin/users", "admin.users", []string{"router.NoCacheMiddleware", "router.VaryHTMX", "router.NoJSMiddleware"}},
+		{"PUT", "/api/v1/items/{id}", "item.update", []string{"router.NoCacheMiddleware", "router.VaryHTMX", "router.MaxBodySize"}},
+		{"POST", "/todos", "", []string{"router.NoCacheMiddleware", "router.VaryHTMX", "router.RequireDatastar"}},
+		{"GET", "/todos/{id}", "todo", []string{"router.NoCacheMiddleware", "router.VaryHTMX"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Routes() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	r := introspectedRouter()
+	tests := []struct {
+		method, path string
+		pattern      string
+		name         string
+	}{
+		{"GET", "/todos/42", "/todos/{id}", "todo"},
+		{"POST", "/todos", "/todos", ""},
+		{"PUT", "/api/v1/items/7", "/api/v1/items/{id}", "item.update"},
+		{"GET", "/admin/users", "/admin/users", "admin.users"},
+	}
+	for _, tt := range tests {
+		route, ok := r.Match(tt.method, tt.path)
+		if !ok || route.Pattern != tt.pattern || route.Name != tt.name || route.Method != tt.method {
+			t.Errorf("Match(%s, %s) = %+v, %v; want %s %q", tt.method, tt.path, route, ok, tt.pattern, tt.name)
+		}
+	}
+
+	for _, miss := range [][2]string{{"DELETE", "/todos/42"}, {"GET", "/nope"}, {"GET", "/admin/nope"}} {
+		if route, ok := r.Match(miss[0], miss[1]); ok {
+			t.Errorf("Match(%s, %s) = %+v, want no match", miss[0], miss[1], route)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package router
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/stukennedy/irgo/pkg/render"
+)
+
+// sandbox marks a path prefix whose HTML responses are untrusted.
+type sandbox struct {
+	prefix string
+	policy render.SandboxPolicy
+}
+
+// WithSandbox runs HTML responses from routes under prefix, such as a
+// plugin's mount point, through render.SandboxFragment:
+//
+//	router.New(router.WithSandbox("/plugins/weather", render.SandboxPolicy{}))
+//
+// An empty policy.Prefix is derived from the path ("plugins-weather").
+// HX-* response headers are removed, and event streams are refused with a
+// 500, since their patches can't be sanitised without buffering.
+func WithSandbox(prefix string, policy render.SandboxPolicy) Option {
+	prefix = "/" + strings.Trim(prefix, "/")
+	if policy.Prefix == "" {
+		policy.Prefix = strings.ReplaceAll(strings.Trim(prefix, "/"), "/", "-")
+	}
+	return func(c *routerConfig) {
+		c.sandboxes = append(c.sandboxes, sandbox{prefix: prefix, policy: policy})
+	}
+}
+
+// sandboxFor returns the sandbox covering path, if any.
+func (c *routerConfig) sandboxFor(path string) (sandbox, bool) {
+	for _, s := range c.sandboxes {
+		if s.prefix == "/" || path == s.prefix || strings.HasPrefix(path, s.prefix+"/") {
+			return s, true
+		}
+	}
+	return sandbox{}, false
+}
+
+// sandboxMiddleware buffers and sanitises responses from sandboxed routes.
+func sandboxMiddleware(config *routerConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			s, ok := config.sandboxFor(r.URL.Path)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			rec := &sandboxRecorder{header: make(http.Header)}
+			next.ServeHTTP(rec, r)
+			if rec.status == 0 {
+				rec.status = http.StatusOK
+			}
+
+			contentType := rec.header.Get("Content-Type")
+			if contentType == "" && rec.body.Len() > 0 {
+				contentType = http.DetectContentType(rec.body.Bytes())
+			}
+			if strings.HasPrefix(contentType, "text/event-stream") {
+				log.Printf("router: refused event stream from sandboxed route %s", r.URL.Path)
+				http.Error(w, "Event streams are not allowed from sandboxed routes", http.StatusInternalServerError)
+				return
+			}
+
+			h := w.Header()
+			for name, values := range rec.header {
+				if !strings.HasPrefix(strings.ToLower(name), "hx-") {
+					h[name] = values
+				}
+			}
+			body := rec.body.Bytes()
+			if strings.HasPrefix(contentType, "text/html") {
+				body = []byte(render.SandboxFragment(rec.body.String(), s.policy))
+				h.Set("Content-Type", "text/html; charset=utf-8")
+				h.Del("Content-Length")
+				h.Del("ETag")
+			}
+			w.WriteHeader(rec.status)
+			w.Write(body)
+		})
+	}
+}
+
+// sandboxRecorder buffers a whole response. Flush is a no-op so streaming
+// handlers don't fail before the middleware sees what they sent.
+type sandboxRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *sandboxRecorder) Header() http.Header { return r.header }
+
+func (r *sandboxRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+func (r *sandboxRecorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.body.Write(p)
+}
+
+func (r *sandboxRecorder) Flush() {}
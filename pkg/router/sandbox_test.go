@@ -0,0 +1,61 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stukennedy/irgo/pkg/render"
+)
+
+func TestSandboxMount(t *testing.T) {
+	r := New(WithSandbox("/plugins/weather/", render.SandboxPolicy{}))
+	plugin := NewWithoutMiddleware()
+	plugin.GET("/", func(ctx *Context) (string, error) {
+		ctx.Response.Header().Set("HX-Retarget", "#app")
+		return `<div id="app" hx-swap-oob="true"><b>Sunny</b><script>steal()</script></div>`, nil
+	})
+	plugin.GET("/data", func(ctx *Context) (string, error) {
+		ctx.JSON(map[string]string{"id": "app"})
+		return "", nil
+	})
+	r.Mount("/plugins/weather", plugin)
+	r.GET("/", func(ctx *Context) (string, error) {
+		return `<div id="app"></div>`, nil
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/plugins/weather/", nil))
+	want := `<div data-sandbox="plugins-weather"><div id="plugins-weather-app"><b>Sunny</b></div></div>`
+	if got := w.Body.String(); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+	if got := w.Header().Get("HX-Retarget"); got != "" {
+		t.Errorf("expected HX-Retarget stripped, got %q", got)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/plugins/weather/data", nil))
+	if got := w.Body.String(); got != `{"id":"app"}`+"\n" {
+		t.Errorf("expected JSON untouched, got %q", got)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if got := w.Body.String(); got != `<div id="app"></div>` {
+		t.Errorf("expected host route untouched, got %q", got)
+	}
+}
+
+func TestSandboxRefusesEventStream(t *testing.T) {
+	r := New(WithSandbox("/plugins/x", render.SandboxPolicy{}))
+	r.DSGet("/plugins/x/live", func(ctx *Context) error {
+		return ctx.SSE().PatchElements(`<div id="app">owned</div>`)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/plugins/x/live", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500, got %d", w.Code)
+	}
+}
@@ -0,0 +1,80 @@
+package router
+
+import (
+	"sync"
+	"time"
+)
+
+// SecretProvider supplies the secret checked by the secret middlewares,
+// allowing it to be rotated while the server runs. Requests carrying
+// either secret are accepted; Previous returns "" when there is none.
+type SecretProvider interface {
+	Current() string
+	Previous() string
+}
+
+// StaticSecret is a SecretProvider that never rotates.
+type StaticSecret string
+
+// Current returns the secret.
+func (s StaticSecret) Current() string { return string(s) }
+
+// Previous returns "".
+func (s StaticSecret) Previous() string { return "" }
+
+// RotatingSecret is a SecretProvider whose previous secret keeps working
+// for a grace window after Rotate, so in-flight requests and pages that
+// haven't picked up the new secret yet aren't rejected. It is safe for
+// concurrent use.
+type RotatingSecret struct {
+	grace time.Duration
+	now   func() time.Time // For tests
+
+	mu       sync.RWMutex
+	current  string
+	previous string
+	expires  time.Time
+}
+
+// NewRotatingSecret returns a provider starting with secret, accepting the
+// previous secret for grace after each rotation.
+func NewRotatingSecret(secret string, grace time.Duration) *RotatingSecret {
+	return &RotatingSecret{current: secret, grace: grace, now: time.Now}
+}
+
+// Current returns the current secret.
+func (s *RotatingSecret) Current() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current
+}
+
+// Previous returns the secret replaced by the last Rotate, or "" once its
+// grace window has passed.
+func (s *RotatingSecret) Previous() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.previous == "" || !s.now().Before(s.expires) {
+		return ""
+	}
+	return s.previous
+}
+
+// Rotate makes secret current, starting the grace window for the old one.
+func (s *RotatingSecret) Rotate(secret string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.previous = s.current
+	s.current = secret
+	s.expires = s.now().Add(s.grace)
+}
+
+// validSecret reports whether given matches the provider's current or
+// previous secret, comparing both in constant time.
+func validSecret(p SecretProvider, given string) bool {
+	ok := SecureCompare(given, p.Current())
+	if prev := p.Previous(); prev != "" && SecureCompare(given, prev) {
+		ok = true
+	}
+	return ok
+}
@@ -0,0 +1,101 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRotatingSecretGrace(t *testing.T) {
+	now := time.Unix(1000, 0)
+	secrets := NewRotatingSecret("old", 30*time.Second)
+	secrets.now = func() time.Time { return now }
+
+	h := SecretValidationMiddlewareWithOptions(SecretValidationOptions{Provider: secrets})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	status := func(secret string) int {
+		req := httptest.NewRequest("POST", "/todos", nil)
+		req.Header.Set("X-Irgo-Secret", secret)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if got := status("old"); got != http.StatusOK {
+		t.Errorf("before rotation: old secret status = %d", got)
+	}
+
+	secrets.Rotate("new")
+	now = now.Add(29 * time.Second)
+	if got := status("new"); got != http.StatusOK {
+		t.Errorf("new secret status = %d", got)
+	}
+	if got := status("old"); got != http.StatusOK {
+		t.Errorf("old secret within grace: status = %d, want 200", got)
+	}
+
+	now = now.Add(time.Second)
+	if got := status("old"); got != http.StatusForbidden {
+		t.Errorf("old secret after grace: status = %d, want 403", got)
+	}
+	if got := status("new"); got != http.StatusOK {
+		t.Errorf("new secret after grace: status = %d", got)
+	}
+	if got := secrets.Previous(); got != "" {
+		t.Errorf("Previous() after grace = %q, want empty", got)
+	}
+
+	// A second rotation retires "new" and drops "old" entirely
+	secrets.Rotate("newer")
+	if got := status("old"); got != http.StatusForbidden {
+		t.Errorf("secret from two rotations ago: status = %d, want 403", got)
+	}
+	if got := status("new"); got != http.StatusOK {
+		t.Errorf("previous secret within grace: status = %d, want 200", got)
+	}
+}
+
+func TestWebSocketRotatingSecret(t *testing.T) {
+	secrets := NewRotatingSecret("old", time.Minute)
+	h := WebSocketSecretProviderMiddleware(secrets)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	secrets.Rotate("new")
+
+	for secret, want := range map[string]int{"old": http.StatusOK, "new": http.StatusOK, "other": http.StatusForbidden} {
+		req := httptest.NewRequest("GET", "/ws", nil)
+		req.Header.Set("Upgrade", "websocket")
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Sec-WebSocket-Protocol", SecretProtocol(secret))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Errorf("%s: status = %d, want %d", secret, w.Code, want)
+		}
+	}
+}
+
+func TestRequireSecretProvider(t *testing.T) {
+	now := time.Unix(1000, 0)
+	secrets := NewRotatingSecret("old", time.Minute)
+	secrets.now = func() time.Time { return now }
+	h := RequireSecretProvider(secrets)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	secrets.Rotate("new")
+
+	status := func(secret string) int {
+		req := httptest.NewRequest("GET", "/account", nil)
+		req.Header.Set("X-Irgo-Secret", secret)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w.Code
+	}
+	for secret, want := range map[string]int{"old": http.StatusOK, "new": http.StatusOK, "other": http.StatusForbidden} {
+		if got := status(secret); got != want {
+			t.Errorf("%s: status = %d, want %d", secret, got, want)
+		}
+	}
+
+	now = now.Add(time.Minute)
+	if got := status("old"); got != http.StatusForbidden {
+		t.Errorf("old secret after grace: status = %d, want 403", got)
+	}
+}
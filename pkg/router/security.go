@@ -1,6 +1,8 @@
 package router
 
 import (
+	"context"
+	"encoding/base64"
 	"net/http"
 	"strings"
 )
@@ -15,26 +17,70 @@ import (
 // This allows the webview to load the initial page and static assets,
 // while protecting state-changing operations (POST, PUT, DELETE, PATCH).
 func SecretValidationMiddleware(secret string, excludePaths []string) func(http.Handler) http.Handler {
+	return SecretValidationMiddlewareWithOptions(SecretValidationOptions{
+		Secret:       secret,
+		ExcludePaths: excludePaths,
+	})
+}
+
+// SecretValidationOptions configures SecretValidationMiddlewareWithOptions.
+type SecretValidationOptions struct {
+	// Secret is the expected value of the X-Irgo-Secret header, or of an
+	// "Authorization: Bearer" token for clients that can't set custom
+	// headers.
+	Secret string
+
+	// Provider, if set, supplies the secret instead of Secret, so it can
+	// be rotated (see RotatingSecret).
+	Provider SecretProvider
+
+	// ExcludePaths are path prefixes that bypass validation.
+	ExcludePaths []string
+
+	// IncludeGETPaths are path prefixes whose GET and HEAD requests are
+	// validated too, for routes returning sensitive data. They take
+	// precedence over ExcludePaths. WebSocket upgrades on these paths
+	// pass the secret as for WebSocketSecretMiddleware.
+	IncludeGETPaths []string
+
+	// OnReject, if set, is called for each request rejected with 403,
+	// e.g. to log it.
+	OnReject func(*http.Request)
+}
+
+// SecretValidationMiddlewareWithOptions is SecretValidationMiddleware with
+// a reject hook and rotatable secrets. The secret is compared in constant
+// time.
+func SecretValidationMiddlewareWithOptions(opts SecretValidationOptions) func(http.Handler) http.Handler {
+	provider := opts.Provider
+	if provider == nil {
+		provider = StaticSecret(opts.Secret)
+	}
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Safe methods bypass secret validation
-			// GET/HEAD can't mutate state, OPTIONS is for CORS preflight
-			if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
-				next.ServeHTTP(w, r)
-				return
-			}
+			included := (r.Method == http.MethodGet || r.Method == http.MethodHead) &&
+				hasPathPrefix(r.URL.Path, opts.IncludeGETPaths)
+
+			if !included {
+				// Safe methods bypass secret validation
+				// GET/HEAD can't mutate state, OPTIONS is for CORS preflight
+				if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+					next.ServeHTTP(w, r)
+					return
+				}
 
-			// Check if path is excluded
-			for _, prefix := range excludePaths {
-				if strings.HasPrefix(r.URL.Path, prefix) {
+				// Check if path is excluded
+				if hasPathPrefix(r.URL.Path, opts.ExcludePaths) {
 					next.ServeHTTP(w, r)
 					return
 				}
 			}
 
 			// Validate secret header for state-changing requests
-			headerSecret := r.Header.Get("X-Irgo-Secret")
-			if headerSecret != secret {
+			if !validSecret(provider, requestSecret(r)) {
+				if opts.OnReject != nil {
+					opts.OnReject(r)
+				}
 				http.Error(w, "Forbidden", http.StatusForbidden)
 				return
 			}
@@ -44,6 +90,62 @@ func SecretValidationMiddleware(secret string, excludePaths []string) func(http.
 	}
 }
 
+// RequireSecret returns per-route middleware validating the secret on
+// every method, including GET, for routes serving sensitive data that
+// another local app could otherwise read from the loopback port:
+//
+//	r.With(router.RequireSecret(secret)).GET("/export", exportHandler)
+//
+// The secret is read as by SecretValidationMiddleware. WebSocket upgrades
+// can't set headers, so they pass it as for WebSocketSecretMiddleware.
+func RequireSecret(secret string) func(http.Handler) http.Handler {
+	return RequireSecretProvider(StaticSecret(secret))
+}
+
+// RequireSecretProvider is RequireSecret checking the secrets supplied by
+// provider, so they can be rotated.
+func RequireSecretProvider(provider SecretProvider) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !validSecret(provider, requestSecret(r)) {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// hasPathPrefix reports whether path starts with any of prefixes.
+func hasPathPrefix(path string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// requestSecret returns the X-Irgo-Secret header, falling back to an
+// "Authorization: Bearer" token. WebSocket upgrades use the secret
+// protocol or the ?secret= query parameter.
+func requestSecret(r *http.Request) string {
+	if isWebSocketUpgrade(r) {
+		if secret, token, _ := splitSecretProtocol(r); token != "" {
+			return secret
+		}
+		return r.URL.Query().Get("secret")
+	}
+	if secret := r.Header.Get("X-Irgo-Secret"); secret != "" {
+		return secret
+	}
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
 // StrictOriginMiddleware validates the Origin header exactly.
 // For non-safe methods (not GET, HEAD, OPTIONS), the Origin must exactly match
 // one of the allowed origins. This prevents DNS rebinding and CSRF attacks.
@@ -84,10 +186,37 @@ func StrictOriginMiddleware(allowedOrigins ...string) func(http.Handler) http.Ha
 	}
 }
 
+// SecretProtocolPrefix starts the WebSocket subprotocol carrying the
+// secret (see SecretProtocol).
+const SecretProtocolPrefix = "irgo-secret."
+
+// SecretProtocol returns the Sec-WebSocket-Protocol token carrying secret:
+// SecretProtocolPrefix followed by the secret as unpadded URL-safe base64,
+// which keeps it a valid protocol token. From JavaScript:
+//
+//	new WebSocket(url, ["irgo-secret." + btoa(secret).replace(/\+/g, "-").replace(/\//g, "_").replace(/=+$/, "")])
+func SecretProtocol(secret string) string {
+	return SecretProtocolPrefix + base64.RawURLEncoding.EncodeToString([]byte(secret))
+}
+
+type secretProtocolKey struct{}
+
 // WebSocketSecretMiddleware validates the secret for WebSocket upgrade requests.
 // Since the WebSocket API doesn't support custom headers, the secret is passed
-// as a query parameter: ?secret=xxx
+// as a subprotocol (see SecretProtocol), or as a query parameter: ?secret=xxx.
+// The query parameter ends up in logs and URL history and is deprecated.
+//
+// The secret protocol is removed from the request before it reaches the
+// upgrade handler, which negotiates any remaining protocols as usual. Pass
+// WebSocketResponseHeader(r) to Upgrade so a client that offered only the
+// secret protocol gets it echoed back, as browsers require.
 func WebSocketSecretMiddleware(secret string) func(http.Handler) http.Handler {
+	return WebSocketSecretProviderMiddleware(StaticSecret(secret))
+}
+
+// WebSocketSecretProviderMiddleware is WebSocketSecretMiddleware checking
+// the secrets supplied by provider, so they can be rotated.
+func WebSocketSecretProviderMiddleware(provider SecretProvider) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Only check WebSocket upgrade requests
@@ -96,18 +225,65 @@ func WebSocketSecretMiddleware(secret string) func(http.Handler) http.Handler {
 				return
 			}
 
-			// Validate secret from query parameter
-			querySecret := r.URL.Query().Get("secret")
-			if querySecret != secret {
+			given, token, others := splitSecretProtocol(r)
+			if token == "" {
+				// Deprecated: secret from query parameter
+				given = r.URL.Query().Get("secret")
+			}
+			if !validSecret(provider, given) {
 				http.Error(w, "Forbidden", http.StatusForbidden)
 				return
 			}
 
+			if token != "" {
+				r = r.WithContext(context.WithValue(r.Context(), secretProtocolKey{}, token))
+				r.Header = r.Header.Clone()
+				r.Header.Del("Sec-WebSocket-Protocol")
+				if len(others) > 0 {
+					r.Header.Set("Sec-WebSocket-Protocol", strings.Join(others, ", "))
+				}
+			}
 			next.ServeHTTP(w, r)
 		})
 	}
 }
 
+// WebSocketResponseHeader returns the header to pass to a WebSocket
+// upgrader behind WebSocketSecretMiddleware. When the client offered only
+// the secret protocol it is echoed back; otherwise it returns nil and the
+// upgrader picks from the remaining protocols.
+func WebSocketResponseHeader(r *http.Request) http.Header {
+	token, _ := r.Context().Value(secretProtocolKey{}).(string)
+	if token == "" || r.Header.Get("Sec-WebSocket-Protocol") != "" {
+		return nil
+	}
+	return http.Header{"Sec-Websocket-Protocol": {token}}
+}
+
+// splitSecretProtocol finds the secret protocol in the request's
+// Sec-WebSocket-Protocol headers. It returns the decoded secret, the
+// protocol token ("" if absent) and the other protocols offered.
+func splitSecretProtocol(r *http.Request) (secret, token string, others []string) {
+	for _, value := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, p := range strings.Split(value, ",") {
+			p = strings.TrimSpace(p)
+			if p == "" {
+				continue
+			}
+			encoded, ok := strings.CutPrefix(p, SecretProtocolPrefix)
+			if !ok || token != "" {
+				others = append(others, p)
+				continue
+			}
+			token = p
+			if b, err := base64.RawURLEncoding.DecodeString(encoded); err == nil {
+				secret = string(b)
+			}
+		}
+	}
+	return secret, token, others
+}
+
 // isWebSocketUpgrade checks if the request is a WebSocket upgrade.
 func isWebSocketUpgrade(r *http.Request) bool {
 	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket") &&
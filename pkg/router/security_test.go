@@ -0,0 +1,184 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSecretValidation(t *testing.T) {
+	var rejected []string
+	h := SecretValidationMiddlewareWithOptions(SecretValidationOptions{
+		Secret:       "s3cret",
+		ExcludePaths: []string{"/static/"},
+		OnReject:     func(r *http.Request) { rejected = append(rejected, r.Method+" "+r.URL.Path) },
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+
+	tests := []struct {
+		name, method, path string
+		header, value      string
+		want               int
+	}{
+		{"secret header", "POST", "/todos", "X-Irgo-Secret", "s3cret", http.StatusOK},
+		{"bearer token", "POST", "/todos", "Authorization", "Bearer s3cret", http.StatusOK},
+		{"wrong secret", "POST", "/todos", "X-Irgo-Secret", "guess", http.StatusForbidden},
+		{"wrong bearer", "DELETE", "/todos/1", "Authorization", "Bearer guess", http.StatusForbidden},
+		{"basic scheme", "PUT", "/todos/1", "Authorization", "Basic s3cret", http.StatusForbidden},
+		{"missing", "PATCH", "/todos/1", "", "", http.StatusForbidden},
+		{"safe method", "GET", "/todos", "", "", http.StatusOK},
+		{"excluded path", "POST", "/static/app.js", "", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		if tt.header != "" {
+			req.Header.Set(tt.header, tt.value)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.want)
+		}
+	}
+
+	want := []string{"POST /todos", "DELETE /todos/1", "PUT /todos/1", "PATCH /todos/1"}
+	if len(rejected) != len(want) {
+		t.Fatalf("OnReject calls = %v, want %v", rejected, want)
+	}
+	for i := range want {
+		if rejected[i] != want[i] {
+			t.Errorf("OnReject call %d = %q, want %q", i, rejected[i], want[i])
+		}
+	}
+}
+
+func TestSecretValidationLegacy(t *testing.T) {
+	h := SecretValidationMiddleware("s3cret", nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	req := httptest.NewRequest("POST", "/", nil)
+	req.Header.Set("X-Irgo-Secret", "s3cret")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200", w.Code)
+	}
+}
+
+func TestSecretValidationIncludeGET(t *testing.T) {
+	h := SecretValidationMiddlewareWithOptions(SecretValidationOptions{
+		Secret:          "s3cret",
+		ExcludePaths:    []string{"/api/"},
+		IncludeGETPaths: []string{"/export/", "/api/account"},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		name, method, target, secret string
+		websocket                    bool
+		want                         int
+	}{
+		{"included without secret", "GET", "/export/todos.csv", "", false, http.StatusForbidden},
+		{"included HEAD", "HEAD", "/export/todos.csv", "", false, http.StatusForbidden},
+		{"included with secret", "GET", "/export/todos.csv", "s3cret", false, http.StatusOK},
+		{"include beats exclude", "GET", "/api/account", "", false, http.StatusForbidden},
+		{"elsewhere", "GET", "/todos", "", false, http.StatusOK},
+		{"excluded elsewhere", "GET", "/api/todos", "", false, http.StatusOK},
+		{"options preflight", "OPTIONS", "/export/todos.csv", "", false, http.StatusOK},
+		{"websocket query secret", "GET", "/export/live?secret=s3cret", "", true, http.StatusOK},
+		{"websocket header ignored", "GET", "/export/live", "s3cret", true, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.target, nil)
+		if tt.secret != "" {
+			req.Header.Set("X-Irgo-Secret", tt.secret)
+		}
+		if tt.websocket {
+			req.Header.Set("Upgrade", "websocket")
+			req.Header.Set("Connection", "Upgrade")
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.want)
+		}
+	}
+}
+
+func TestRequireSecret(t *testing.T) {
+	r := New()
+	r.With(RequireSecret("s3cret")).GET("/account", func(ctx *Context) (string, error) {
+		return "account", nil
+	})
+	r.GET("/todos", func(ctx *Context) (string, error) {
+		return "todos", nil
+	})
+
+	for _, tt := range []struct {
+		target, secret string
+		want           int
+	}{
+		{"/account", "", http.StatusForbidden},
+		{"/account", "guess", http.StatusForbidden},
+		{"/account", "s3cret", http.StatusOK},
+		{"/todos", "", http.StatusOK},
+	} {
+		req := httptest.NewRequest("GET", tt.target, nil)
+		if tt.secret != "" {
+			req.Header.Set("X-Irgo-Secret", tt.secret)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.want {
+			t.Errorf("GET %s with %q: status = %d, want %d", tt.target, tt.secret, w.Code, tt.want)
+		}
+	}
+}
+
+func TestWebSocketSecretProtocol(t *testing.T) {
+	var seen *http.Request
+	h := WebSocketSecretMiddleware("s3cret+/=")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = r
+	}))
+
+	tests := []struct {
+		name, target string
+		protocols    []string
+		want         int
+		remaining    string
+		echo         string
+	}{
+		{"secret protocol only", "/ws", []string{SecretProtocol("s3cret+/=")}, http.StatusOK, "", SecretProtocol("s3cret+/=")},
+		{"secret among others", "/ws", []string{"chat, " + SecretProtocol("s3cret+/="), "json"}, http.StatusOK, "chat, json", ""},
+		{"wrong secret protocol", "/ws?secret=s3cret%2B%2F%3D", []string{SecretProtocol("guess")}, http.StatusForbidden, "", ""},
+		{"undecodable protocol", "/ws", []string{SecretProtocolPrefix + "!!"}, http.StatusForbidden, "", ""},
+		{"deprecated query", "/ws?secret=s3cret%2B%2F%3D", []string{"chat"}, http.StatusOK, "chat", ""},
+		{"missing", "/ws", nil, http.StatusForbidden, "", ""},
+	}
+	for _, tt := range tests {
+		seen = nil
+		req := httptest.NewRequest("GET", tt.target, nil)
+		req.Header.Set("Upgrade", "websocket")
+		req.Header.Set("Connection", "Upgrade")
+		for _, p := range tt.protocols {
+			req.Header.Add("Sec-WebSocket-Protocol", p)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		if w.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.want)
+			continue
+		}
+		if tt.want != http.StatusOK {
+			continue
+		}
+		if len(tt.protocols) > 0 && req.Header.Get("Sec-WebSocket-Protocol") != tt.protocols[0] {
+			t.Errorf("%s: caller's request headers modified", tt.name)
+		}
+		if got := seen.Header.Get("Sec-WebSocket-Protocol"); got != tt.remaining {
+			t.Errorf("%s: protocols passed on = %q, want %q", tt.name, got, tt.remaining)
+		}
+		if got := WebSocketResponseHeader(seen).Get("Sec-WebSocket-Protocol"); got != tt.echo {
+			t.Errorf("%s: echoed protocol = %q, want %q", tt.name, got, tt.echo)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package router
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/stukennedy/irgo/pkg/servertiming"
+)
+
+// ServerTimingMiddleware collects Server-Timing metrics for each request
+// in its context, such as the spans httpclient records for outbound calls,
+// and writes them in the Server-Timing header along with a "total" metric.
+// Metrics added after the response headers are sent are dropped.
+func ServerTimingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timing := &servertiming.Timing{}
+		tw := &timingWriter{ResponseWriter: w, timing: timing, start: time.Now()}
+		next.ServeHTTP(tw, r.WithContext(servertiming.WithContext(r.Context(), timing)))
+		if !tw.wroteHeader {
+			tw.WriteHeader(http.StatusOK)
+		}
+	})
+}
+
+// ServerTiming returns the request's metrics collector, or nil if
+// ServerTimingMiddleware is not installed. Adding to nil is a no-op.
+func (c *Context) ServerTiming() *servertiming.Timing {
+	return servertiming.FromContext(c.Request.Context())
+}
+
+// timingWriter writes the Server-Timing header before the response headers
+// are sent.
+type timingWriter struct {
+	http.ResponseWriter
+	timing      *servertiming.Timing
+	start       time.Time
+	wroteHeader bool
+}
+
+func (w *timingWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.timing.Add("total", time.Since(w.start), "")
+		w.Header().Add(servertiming.Header, w.timing.String())
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *timingWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher so SSE handlers keep streaming.
+func (w *timingWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController.
+func (w *timingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
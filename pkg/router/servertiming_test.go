@@ -0,0 +1,36 @@
+package router
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServerTimingMiddleware(t *testing.T) {
+	r := New()
+	r.Use(ServerTimingMiddleware)
+	r.GET("/", func(ctx *Context) (string, error) {
+		ctx.ServerTiming().Add("db", 2*time.Millisecond, "todos")
+		return "ok", nil
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	got := w.Header().Get("Server-Timing")
+	if !strings.HasPrefix(got, `db;dur=2;desc="todos", total;dur=`) {
+		t.Errorf("Server-Timing = %q, want the handler's metric and total", got)
+	}
+
+	// Without the middleware, metrics are discarded
+	r = New()
+	r.GET("/", func(ctx *Context) (string, error) {
+		ctx.ServerTiming().Add("db", time.Millisecond, "")
+		return "ok", nil
+	})
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if got := w.Header().Get("Server-Timing"); got != "" {
+		t.Errorf("Server-Timing = %q without the middleware", got)
+	}
+}
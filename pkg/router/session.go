@@ -0,0 +1,390 @@
+package router
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// SessionStore persists session data keyed by session ID.
+type SessionStore interface {
+	// Get returns the data for a session, or nil if it doesn't exist or has expired.
+	Get(id string) (map[string]any, error)
+
+	// Save stores the data for a session with the given lifetime.
+	Save(id string, data map[string]any, ttl time.Duration) error
+
+	// Delete removes a session.
+	Delete(id string) error
+}
+
+// MemoryStore is an in-memory SessionStore with TTL expiry.
+type MemoryStore struct {
+	sessions map[string]memoryEntry
+	mu       sync.Mutex
+	done     chan struct{}
+	once     sync.Once
+}
+
+type memoryEntry struct {
+	data    map[string]any
+	expires time.Time
+}
+
+// NewMemoryStore creates an in-memory store that removes expired sessions
+// every cleanupInterval. Call Close to stop the cleanup goroutine.
+func NewMemoryStore(cleanupInterval time.Duration) *MemoryStore {
+	s := &MemoryStore{
+		sessions: make(map[string]memoryEntry),
+		done:     make(chan struct{}),
+	}
+	if cleanupInterval > 0 {
+		go s.cleanupLoop(cleanupInterval)
+	}
+	return s
+}
+
+// Get implements SessionStore.
+func (s *MemoryStore) Get(id string) (map[string]any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.sessions[id]
+	if !ok || time.Now().After(e.expires) {
+		return nil, nil
+	}
+	return copyValues(e.data), nil
+}
+
+// Save implements SessionStore.
+func (s *MemoryStore) Save(id string, data map[string]any, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[id] = memoryEntry{data: copyValues(data), expires: time.Now().Add(ttl)}
+	return nil
+}
+
+// Delete implements SessionStore.
+func (s *MemoryStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+	return nil
+}
+
+// Len returns the number of stored sessions, including expired ones not yet cleaned up.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.sessions)
+}
+
+// Cleanup removes expired sessions.
+func (s *MemoryStore) Cleanup() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for id, e := range s.sessions {
+		if now.After(e.expires) {
+			delete(s.sessions, id)
+		}
+	}
+}
+
+// Close stops the cleanup goroutine.
+func (s *MemoryStore) Close() {
+	s.once.Do(func() { close(s.done) })
+}
+
+func (s *MemoryStore) cleanupLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.Cleanup()
+		}
+	}
+}
+
+// flashKey holds one-shot flash values inside the session data.
+const flashKey = "_flash"
+
+// Session is the server-side session for a request.
+type Session struct {
+	ID string
+
+	values   map[string]any
+	isNew    bool
+	modified bool
+	mu       sync.Mutex
+}
+
+// Get returns a session value, or nil if not set.
+func (s *Session) Get(key string) any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.values[key]
+}
+
+// GetString returns a string session value, or "" if not set.
+func (s *Session) GetString(key string) string {
+	v, _ := s.Get(key).(string)
+	return v
+}
+
+// Set stores a session value.
+func (s *Session) Set(key string, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = value
+	s.modified = true
+}
+
+// Delete removes a session value.
+func (s *Session) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.values[key]; ok {
+		delete(s.values, key)
+		s.modified = true
+	}
+}
+
+// Flash stores a value that is removed the first time it is read
+// with PopFlash, typically on the next request.
+func (s *Session) Flash(key string, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	flashes, _ := s.values[flashKey].(map[string]any)
+	if flashes == nil {
+		flashes = make(map[string]any)
+		s.values[flashKey] = flashes
+	}
+	flashes[key] = value
+	s.modified = true
+}
+
+// PopFlash returns and removes a flash value, or nil if not set.
+func (s *Session) PopFlash(key string) any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	flashes, _ := s.values[flashKey].(map[string]any)
+	v, ok := flashes[key]
+	if !ok {
+		return nil
+	}
+	delete(flashes, key)
+	if len(flashes) == 0 {
+		delete(s.values, flashKey)
+	}
+	s.modified = true
+	return v
+}
+
+// Clear removes all session values.
+func (s *Session) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values = make(map[string]any)
+	s.modified = true
+}
+
+// SessionOption configures SessionMiddleware.
+type SessionOption func(*sessionConfig)
+
+type sessionConfig struct {
+	cookieName string
+	ttl        time.Duration
+	secure     bool
+}
+
+// SessionCookieName sets the session cookie name (default: "irgo_session").
+func SessionCookieName(name string) SessionOption {
+	return func(c *sessionConfig) {
+		c.cookieName = name
+	}
+}
+
+// SessionTTL sets the session lifetime (default: 24h).
+func SessionTTL(ttl time.Duration) SessionOption {
+	return func(c *sessionConfig) {
+		c.ttl = ttl
+	}
+}
+
+// SessionSecure marks the session cookie Secure (HTTPS only).
+func SessionSecure(secure bool) SessionOption {
+	return func(c *sessionConfig) {
+		c.secure = secure
+	}
+}
+
+type sessionKey struct{}
+
+// sessionState lazily loads the session for one request.
+type sessionState struct {
+	store   SessionStore
+	config  *sessionConfig
+	req     *http.Request
+	session *Session
+	cookie  bool // Set-Cookie already written
+}
+
+func (st *sessionState) load() *Session {
+	if st.session != nil {
+		return st.session
+	}
+	if c, err := st.req.Cookie(st.config.cookieName); err == nil && c.Value != "" {
+		if data, err := st.store.Get(c.Value); err == nil && data != nil {
+			st.session = &Session{ID: c.Value, values: data}
+			return st.session
+		}
+	}
+	st.session = &Session{ID: newSessionID(), values: make(map[string]any), isNew: true}
+	return st.session
+}
+
+// writeCookie sets the session cookie for new, modified sessions.
+func (st *sessionState) writeCookie(w http.ResponseWriter) {
+	if st.cookie || st.session == nil {
+		return
+	}
+	st.session.mu.Lock()
+	send := st.session.isNew && st.session.modified
+	st.session.mu.Unlock()
+	if !send {
+		return
+	}
+	st.cookie = true
+	http.SetCookie(w, &http.Cookie{
+		Name:     st.config.cookieName,
+		Value:    st.session.ID,
+		Path:     "/",
+		MaxAge:   int(st.config.ttl / time.Second),
+		HttpOnly: true,
+		Secure:   st.config.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// save persists the session if it was modified.
+func (st *sessionState) save() error {
+	if st.session == nil {
+		return nil
+	}
+	st.session.mu.Lock()
+	defer st.session.mu.Unlock()
+	if !st.session.modified {
+		return nil
+	}
+	if len(st.session.values) == 0 && !st.session.isNew {
+		return st.store.Delete(st.session.ID)
+	}
+	return st.store.Save(st.session.ID, st.session.values, st.config.ttl)
+}
+
+// SessionMiddleware provides server-side sessions backed by store.
+// Sessions are loaded lazily on first access via Context.Session, a cookie
+// is issued only for new sessions that were modified, and data is persisted
+// only when changed. The cookie is sent with the response headers, so new
+// sessions must be modified before the handler starts writing.
+func SessionMiddleware(store SessionStore, opts ...SessionOption) func(http.Handler) http.Handler {
+	config := &sessionConfig{
+		cookieName: "irgo_session",
+		ttl:        24 * time.Hour,
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			st := &sessionState{store: store, config: config}
+			r = r.WithContext(context.WithValue(r.Context(), sessionKey{}, st))
+			st.req = r
+
+			sw := &sessionWriter{ResponseWriter: w, state: st}
+			next.ServeHTTP(sw, r)
+			if !sw.wroteHeader {
+				st.writeCookie(w)
+			}
+			st.save()
+		})
+	}
+}
+
+// GetSession returns the session for a request handled by SessionMiddleware,
+// or nil if the middleware is not installed.
+func GetSession(r *http.Request) *Session {
+	st, ok := r.Context().Value(sessionKey{}).(*sessionState)
+	if !ok {
+		return nil
+	}
+	return st.load()
+}
+
+// Session returns the request's session, or nil if SessionMiddleware is not installed.
+func (c *Context) Session() *Session {
+	return GetSession(c.Request)
+}
+
+// sessionWriter issues the session cookie before the response headers are sent.
+type sessionWriter struct {
+	http.ResponseWriter
+	state       *sessionState
+	wroteHeader bool
+}
+
+func (w *sessionWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.state.writeCookie(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *sessionWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher so SSE handlers keep streaming.
+func (w *sessionWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController.
+func (w *sessionWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func newSessionID() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic("router: generating session ID: " + err.Error())
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func copyValues(m map[string]any) map[string]any {
+	c := make(map[string]any, len(m))
+	for k, v := range m {
+		if inner, ok := v.(map[string]any); ok {
+			v = copyValues(inner)
+		}
+		c[k] = v
+	}
+	return c
+}
@@ -0,0 +1,74 @@
+package router
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	irgotest "github.com/stukennedy/irgo/pkg/testing"
+)
+
+func sessionRouter(store SessionStore) *Router {
+	r := New()
+	r.Use(SessionMiddleware(store))
+	r.POST("/login", func(ctx *Context) (string, error) {
+		ctx.Session().Set("user", "ada")
+		ctx.Session().Flash("notice", "Welcome back")
+		return "ok", nil
+	})
+	r.GET("/", func(ctx *Context) (string, error) {
+		notice, _ := ctx.Session().PopFlash("notice").(string)
+		return ctx.Session().GetString("user") + "|" + notice, nil
+	})
+	r.GET("/anon", func(ctx *Context) (string, error) {
+		return ctx.Session().GetString("user"), nil
+	})
+	return r
+}
+
+func TestSessionFlashOneShot(t *testing.T) {
+	store := NewMemoryStore(0)
+	client := irgotest.NewClient(sessionRouter(store))
+
+	client.Post("/login", nil).AssertOK(t)
+	if client.Cookie("irgo_session") == "" {
+		t.Fatal("expected session cookie after login")
+	}
+
+	client.Get("/").AssertBodyEquals(t, "ada|Welcome back")
+	client.Get("/").AssertBodyEquals(t, "ada|")
+}
+
+func TestSessionLazyCookie(t *testing.T) {
+	store := NewMemoryStore(0)
+	r := sessionRouter(store)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/anon", nil))
+
+	if len(w.Result().Cookies()) != 0 {
+		t.Error("expected no cookie for unmodified session")
+	}
+	if store.Len() != 0 {
+		t.Errorf("expected nothing persisted, got %d sessions", store.Len())
+	}
+}
+
+func TestSessionUnknownIDStartsFresh(t *testing.T) {
+	client := irgotest.NewClient(sessionRouter(NewMemoryStore(0)))
+	client.WithHeader("Cookie", "irgo_session=forged").Get("/anon").AssertBodyEquals(t, "")
+}
+
+func TestMemoryStoreTTL(t *testing.T) {
+	store := NewMemoryStore(0)
+	store.Save("a", map[string]any{"k": "v"}, -time.Second)
+	store.Save("b", map[string]any{"k": "v"}, time.Hour)
+
+	if data, _ := store.Get("a"); data != nil {
+		t.Error("expected expired session to be hidden")
+	}
+	store.Cleanup()
+	if store.Len() != 1 {
+		t.Errorf("expected 1 session after cleanup, got %d", store.Len())
+	}
+}
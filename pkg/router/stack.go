@@ -0,0 +1,67 @@
+package router
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// middlewareStack holds the middleware added with Router.Use. It is
+// installed in the chi mux when the router is created and builds the chain
+// lazily, so Use works after routes are registered, which chi forbids.
+type middlewareStack struct {
+	mu  sync.Mutex
+	mws []func(http.Handler) http.Handler
+	gen atomic.Uint64 // Bumped by add so built chains are rebuilt
+}
+
+type builtChain struct {
+	gen uint64
+	h   http.Handler
+}
+
+func (s *middlewareStack) add(mws ...func(http.Handler) http.Handler) {
+	s.mu.Lock()
+	s.mws = append(s.mws, mws...)
+	s.gen.Add(1)
+	s.mu.Unlock()
+}
+
+// handler is the chi middleware standing in for the stack.
+func (s *middlewareStack) handler(next http.Handler) http.Handler {
+	return &stackHandler{stack: s, next: next}
+}
+
+// stackHandler runs a stack's chain in front of next, rebuilding it after
+// Use.
+type stackHandler struct {
+	stack *middlewareStack
+	next  http.Handler
+	built atomic.Pointer[builtChain]
+}
+
+func (h *stackHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	b := h.built.Load()
+	if b == nil || b.gen != h.stack.gen.Load() {
+		b = h.stack.build(h.next)
+		h.built.Store(b)
+	}
+	b.h.ServeHTTP(w, req)
+}
+
+// snapshot returns the stack's middleware.
+func (s *middlewareStack) snapshot() []func(http.Handler) http.Handler {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]func(http.Handler) http.Handler(nil), s.mws...)
+}
+
+func (s *middlewareStack) build(next http.Handler) *builtChain {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h := next
+	for i := len(s.mws) - 1; i >= 0; i-- {
+		h = s.mws[i](h)
+	}
+	return &builtChain{gen: s.gen.Load(), h: h}
+}
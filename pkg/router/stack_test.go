@@ -0,0 +1,61 @@
+package router
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUseAfterRoutes(t *testing.T) {
+	r := New()
+	r.Use(trace("first"))
+	r.GET("/", func(ctx *Context) (string, error) {
+		return "home", nil
+	})
+	r.Use(trace("second"))
+
+	w := serve(r, "GET", "/")
+	if w.Body.String() != "home" {
+		t.Errorf("expected body 'home', got %q", w.Body.String())
+	}
+	if got := strings.Join(w.Header().Values("X-Trace"), ","); got != "first,second" {
+		t.Errorf("expected middleware in Use order, got %q", got)
+	}
+
+	// Middleware added after the first request applies from then on
+	r.Use(trace("third"))
+	w = serve(r, "GET", "/")
+	if got := strings.Join(w.Header().Values("X-Trace"), ","); got != "first,second,third" {
+		t.Errorf("expected third middleware to run, got %q", got)
+	}
+}
+
+func TestUseAfterRoutesInGroups(t *testing.T) {
+	r := New()
+	r.Route("/api", func(api *Router) {
+		api.GET("/todos", func(ctx *Context) (string, error) {
+			return "todos", nil
+		})
+		api.Use(trace("api"))
+	})
+	r.Group(func(g *Router) {
+		g.GET("/admin", func(ctx *Context) (string, error) {
+			return "admin", nil
+		})
+		g.Use(trace("admin"))
+	})
+	r.GET("/", func(ctx *Context) (string, error) {
+		return "home", nil
+	})
+
+	tests := []struct{ path, trace string }{
+		{"/api/todos", "api"},
+		{"/admin", "admin"},
+		{"/", ""},
+	}
+	for _, tt := range tests {
+		w := serve(r, "GET", tt.path)
+		if got := strings.Join(w.Header().Values("X-Trace"), ","); got != tt.trace {
+			t.Errorf("%s: expected trace %q, got %q", tt.path, tt.trace, got)
+		}
+	}
+}
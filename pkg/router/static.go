@@ -0,0 +1,170 @@
+package router
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stukennedy/irgo/pkg/render"
+)
+
+// StaticOption configures Router.StaticFS.
+type StaticOption func(*staticConfig)
+
+type staticConfig struct {
+	maxAge    time.Duration
+	noIndex   bool
+	cacheBust bool
+	assets    *render.AssetResolver
+}
+
+// StaticMaxAge sets the Cache-Control max-age for files. Without it
+// clients revalidate every request (no-cache), which the ETag makes cheap.
+func StaticMaxAge(d time.Duration) StaticOption {
+	return func(c *staticConfig) {
+		c.maxAge = d
+	}
+}
+
+// StaticIndex sets whether a directory serves its index.html (the
+// default) or 404s. Directory listings are never served.
+func StaticIndex(serve bool) StaticOption {
+	return func(c *staticConfig) {
+		c.noIndex = !serve
+	}
+}
+
+// StaticCacheBusting registers each file's content hash with
+// render.RegisterAssets, so {{asset "/static/app.css"}} renders
+// "/static/app.css?v=<hash>". Requests carrying the current hash are
+// cached for a year as immutable.
+func StaticCacheBusting() StaticOption {
+	return func(c *staticConfig) {
+		c.cacheBust = true
+	}
+}
+
+// StaticAssets serves files with the content hashes assets computed,
+// instead of hashing them again, and caches requests for the URLs its
+// asset function renders for a year as immutable: those carrying the
+// current hash, and hashed file names from its manifest. assets must have
+// been created from the same files:
+//
+//	assets, _ := render.NewAssetResolver("/static", public)
+//	r.StaticFS("/static", public, router.StaticAssets(assets))
+func StaticAssets(assets *render.AssetResolver) StaticOption {
+	return func(c *staticConfig) {
+		c.assets = assets
+	}
+}
+
+// immutableCacheControl is sent for requests carrying the current hash.
+const immutableCacheControl = "public, max-age=31536000, immutable"
+
+// StaticFS serves files from fsys, such as an embed.FS compiled into a
+// mobile build, under pattern:
+//
+//	//go:embed static
+//	var static embed.FS
+//
+//	public, _ := fs.Sub(static, "static")
+//	if err := r.StaticFS("/static", public, router.StaticCacheBusting()); err != nil {
+//	    log.Fatal(err)
+//	}
+//
+// Files are hashed up front; the hash is sent as the ETag and
+// If-None-Match is answered with 304. Returns an error if fsys can't be
+// read.
+func (r *Router) StaticFS(pattern string, fsys fs.FS, opts ...StaticOption) error {
+	var cfg staticConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	var hashes map[string]string
+	if cfg.assets != nil {
+		hashes = cfg.assets.Hashes()
+	} else {
+		var err error
+		if hashes, err = render.HashFiles(fsys); err != nil {
+			return err
+		}
+	}
+	base := strings.TrimSuffix(pattern, "/")
+	if cfg.cacheBust {
+		assets := make(map[string]string, len(hashes))
+		for name, hash := range hashes {
+			assets[r.prefix+base+"/"+name] = hash
+		}
+		render.RegisterAssets(assets)
+	}
+
+	cacheControl := "no-cache"
+	if cfg.maxAge > 0 {
+		cacheControl = "public, max-age=" + strconv.Itoa(int(cfg.maxAge.Seconds()))
+	}
+
+	if base != "" {
+		r.mux.Get(base, http.RedirectHandler(r.prefix+base+"/", http.StatusMovedPermanently).ServeHTTP)
+	}
+	r.mux.Get(base+"/*", func(w http.ResponseWriter, req *http.Request) {
+		name := strings.TrimPrefix(cleanWildcard(req), "/")
+		if name == "" {
+			name = "."
+		}
+		info, err := fs.Stat(fsys, name)
+		if err != nil {
+			http.NotFound(w, req)
+			return
+		}
+		if info.IsDir() {
+			if cfg.noIndex {
+				http.NotFound(w, req)
+				return
+			}
+			if !strings.HasSuffix(req.URL.Path, "/") {
+				http.Redirect(w, req, path.Base(req.URL.Path)+"/", http.StatusMovedPermanently)
+				return
+			}
+			name = path.Join(name, "index.html")
+		}
+		hash, ok := hashes[name]
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+
+		f, err := fsys.Open(name)
+		if err != nil {
+			http.NotFound(w, req)
+			return
+		}
+		defer f.Close()
+
+		h := w.Header()
+		h.Set("ETag", `"`+hash+`"`)
+		version := req.URL.Query().Get(render.AssetVersionParam)
+		if (cfg.cacheBust && version == hash) || (cfg.assets != nil && cfg.assets.Immutable(name, version)) {
+			h.Set("Cache-Control", immutableCacheControl)
+		} else {
+			h.Set("Cache-Control", cacheControl)
+		}
+		rs, ok := f.(io.ReadSeeker)
+		if !ok {
+			data, err := io.ReadAll(f)
+			if err != nil {
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+			rs = bytes.NewReader(data)
+		}
+		// Handles If-None-Match, ranges and HEAD
+		http.ServeContent(w, req, name, time.Time{}, rs)
+	})
+	return nil
+}
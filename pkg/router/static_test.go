@@ -0,0 +1,176 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stukennedy/irgo/pkg/render"
+)
+
+func staticFiles() fstest.MapFS {
+	return fstest.MapFS{
+		"app.css":         {Data: []byte("body{}")},
+		"docs/index.html": {Data: []byte("<h1>Docs</h1>")},
+		"empty/.keep":     {Data: []byte{}},
+	}
+}
+
+func TestStaticFS(t *testing.T) {
+	r := New()
+	if err := r.StaticFS("/static", staticFiles(), StaticMaxAge(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/static/app.css", http.StatusOK, "body{}"},
+		{"/static/docs/", http.StatusOK, "<h1>Docs</h1>"},
+		{"/static/docs", http.StatusMovedPermanently, ""},
+		{"/static/empty/", http.StatusNotFound, ""},
+		{"/static/missing.css", http.StatusNotFound, ""},
+		{"/static/../../secret.txt", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		w := serve(r, "GET", tt.path)
+		if w.Code != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.path, tt.status, w.Code)
+		}
+		if tt.body != "" && w.Body.String() != tt.body {
+			t.Errorf("%s: expected %q, got %q", tt.path, tt.body, w.Body.String())
+		}
+	}
+
+	w := serve(r, "GET", "/static/app.css")
+	if got := w.Header().Get("Cache-Control"); got != "public, max-age=3600" {
+		t.Errorf("expected max-age=3600, got %q", got)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/css") {
+		t.Errorf("expected text/css, got %q", ct)
+	}
+}
+
+func TestStaticFSNotModified(t *testing.T) {
+	r := New()
+	if err := r.StaticFS("/static", staticFiles()); err != nil {
+		t.Fatal(err)
+	}
+
+	w := serve(r, "GET", "/static/app.css")
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expected an ETag")
+	}
+	if got := w.Header().Get("Cache-Control"); got != "no-cache" {
+		t.Errorf("expected no-cache by default, got %q", got)
+	}
+
+	req := httptest.NewRequest("GET", "/static/app.css", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("expected status 304, got %d", w.Code)
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("expected empty body, got %q", w.Body.String())
+	}
+
+	req = httptest.NewRequest("GET", "/static/app.css", nil)
+	req.Header.Set("If-None-Match", `"stale"`)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200 for a stale ETag, got %d", w.Code)
+	}
+}
+
+func TestStaticFSNoIndex(t *testing.T) {
+	r := New()
+	if err := r.StaticFS("/static", staticFiles(), StaticIndex(false)); err != nil {
+		t.Fatal(err)
+	}
+	if w := serve(r, "GET", "/static/docs/"); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+	if w := serve(r, "GET", "/static/docs/index.html"); w.Code != http.StatusOK {
+		t.Errorf("expected index.html by name, got %d", w.Code)
+	}
+}
+
+func TestStaticFSCacheBusting(t *testing.T) {
+	r := New()
+	r.Route("/v1", func(v1 *Router) {
+		if err := v1.StaticFS("/assets", staticFiles(), StaticCacheBusting()); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	url := render.AssetURL("/v1/assets/app.css")
+	if !strings.HasPrefix(url, "/v1/assets/app.css?v=") {
+		t.Fatalf("expected hashed URL, got %q", url)
+	}
+	w := serve(r, "GET", url)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if got := w.Header().Get("Cache-Control"); got != immutableCacheControl {
+		t.Errorf("expected immutable caching, got %q", got)
+	}
+	if got := w.Header().Get("ETag"); got != `"`+strings.TrimPrefix(url, "/v1/assets/app.css?v=")+`"` {
+		t.Errorf("expected ETag from the hash, got %q", got)
+	}
+
+	// A stale hash is served but revalidated
+	w = serve(r, "GET", "/v1/assets/app.css?v=old")
+	if got := w.Header().Get("Cache-Control"); got != "no-cache" {
+		t.Errorf("expected no-cache for a stale hash, got %q", got)
+	}
+}
+
+func TestStaticFSAssetResolver(t *testing.T) {
+	files := staticFiles()
+	files["app-3f2a9c1e.css"] = &fstest.MapFile{Data: []byte("body{}")}
+	files["manifest.json"] = &fstest.MapFile{Data: []byte(`{"app.css": "app-3f2a9c1e.css"}`)}
+	assets, err := render.NewAssetResolver("/static", files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := New()
+	if err := r.StaticFS("/static", files, StaticAssets(assets)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Query string hash
+	w := serve(r, "GET", assets.URL("docs/index.html"))
+	if w.Code != http.StatusOK || w.Header().Get("Cache-Control") != immutableCacheControl {
+		t.Errorf("hashed URL: status %d, Cache-Control %q", w.Code, w.Header().Get("Cache-Control"))
+	}
+	if got := w.Header().Get("ETag"); got != `"`+assets.Hashes()["docs/index.html"]+`"` {
+		t.Errorf("ETag = %q", got)
+	}
+	if w := serve(r, "GET", "/static/docs/index.html?v=old"); w.Header().Get("Cache-Control") != "no-cache" {
+		t.Errorf("stale hash cached: %q", w.Header().Get("Cache-Control"))
+	}
+
+	// Manifest output
+	if err := assets.UseManifest("manifest.json"); err != nil {
+		t.Fatal(err)
+	}
+	url := assets.URL("app.css")
+	if url != "/static/app-3f2a9c1e.css" {
+		t.Fatalf("URL = %q", url)
+	}
+	if w := serve(r, "GET", url); w.Code != http.StatusOK || w.Header().Get("Cache-Control") != immutableCacheControl {
+		t.Errorf("manifest output: status %d, Cache-Control %q", w.Code, w.Header().Get("Cache-Control"))
+	}
+	if w := serve(r, "GET", "/static/app.css"); w.Header().Get("Cache-Control") != "no-cache" {
+		t.Errorf("unhashed file cached: %q", w.Header().Get("Cache-Control"))
+	}
+}
@@ -0,0 +1,129 @@
+package router
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// timeoutMessage is shown when a request exceeds its deadline.
+const timeoutMessage = "Request timed out"
+
+// WithTimeout returns a copy of the request context that is cancelled after
+// d, for bounding slow calls made by a handler:
+//
+//	c, cancel := ctx.WithTimeout(2 * time.Second)
+//	defer cancel()
+//	rows, err := db.QueryContext(c, query)
+func (c *Context) WithTimeout(d time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(c.Context(), d)
+}
+
+// TimeoutMiddleware cancels the request context after d. If the handler has
+// not started writing by then, a 504 error fragment is sent; either way,
+// later writes by the handler fail with http.ErrHandlerTimeout. Handlers
+// returning context.DeadlineExceeded get the same 504 from Context.Error.
+//
+// Unlike http.TimeoutHandler the response isn't buffered, so streaming
+// (Datastar SSE) handlers still flush as they go.
+func TimeoutMiddleware(d time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			r = r.WithContext(ctx)
+
+			tw := &timeoutWriter{w: w, header: w.Header().Clone()}
+			done := make(chan struct{})
+			panicked := make(chan any, 1)
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						panicked <- p
+					}
+				}()
+				next.ServeHTTP(tw, r)
+				close(done)
+			}()
+
+			select {
+			case p := <-panicked:
+				panic(p)
+			case <-done:
+			case <-ctx.Done():
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				tw.timedOut = true
+				if ctx.Err() == context.DeadlineExceeded && !tw.wroteHeader {
+					log.Printf("router: %s %s: timed out after %s", r.Method, r.URL.Path, d)
+					NewContext(w, r).ErrorStatus(http.StatusGatewayTimeout, timeoutMessage)
+				}
+			}
+		})
+	}
+}
+
+// timeoutWriter passes writes through until the deadline, after which
+// it rejects them. Headers are kept apart until the first write so the
+// handler goroutine never touches the real header map concurrently.
+type timeoutWriter struct {
+	w      http.ResponseWriter
+	header http.Header
+
+	mu          sync.Mutex
+	wroteHeader bool
+	timedOut    bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.wroteHeader {
+		return
+	}
+	tw.writeHeaderLocked(code)
+}
+
+func (tw *timeoutWriter) writeHeaderLocked(code int) {
+	dst := tw.w.Header()
+	for k := range dst {
+		if _, ok := tw.header[k]; !ok {
+			delete(dst, k)
+		}
+	}
+	for k, v := range tw.header {
+		dst[k] = v
+	}
+	tw.wroteHeader = true
+	tw.w.WriteHeader(code)
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if !tw.wroteHeader {
+		tw.writeHeaderLocked(http.StatusOK)
+	}
+	return tw.w.Write(b)
+}
+
+// Flush implements http.Flusher for streaming handlers.
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return
+	}
+	if f, ok := tw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,99 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeoutMiddleware(t *testing.T) {
+	r := New()
+	r.Use(TimeoutMiddleware(20 * time.Millisecond))
+
+	cancelled := make(chan error, 1)
+	r.GET("/slow", func(ctx *Context) (string, error) {
+		select {
+		case <-ctx.Context().Done():
+			cancelled <- ctx.Context().Err()
+			return "", ctx.Context().Err()
+		case <-time.After(time.Second):
+			cancelled <- nil
+			return "too late", nil
+		}
+	})
+	r.GET("/fast", func(ctx *Context) (string, error) {
+		ctx.SetHeader("X-Fast", "1")
+		return "fast", nil
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/slow", nil))
+	if w.Code != http.StatusGatewayTimeout {
+		t.Errorf("expected 504, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `role="alert">Request timed out`) {
+		t.Errorf("expected error fragment, got %q", w.Body.String())
+	}
+	if err := <-cancelled; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected handler context to be cancelled, got %v", err)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/fast", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "fast" || w.Header().Get("X-Fast") != "1" {
+		t.Errorf("expected fast response, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestTimeoutAfterWrite(t *testing.T) {
+	r := New()
+	r.Use(TimeoutMiddleware(20 * time.Millisecond))
+
+	lateErr := make(chan error, 1)
+	r.GET("/stream", func(ctx *Context) (string, error) {
+		ctx.Response.WriteHeader(http.StatusOK)
+		ctx.Response.Write([]byte("partial"))
+		<-ctx.Context().Done()
+		time.Sleep(5 * time.Millisecond)
+		_, err := ctx.Response.Write([]byte("late"))
+		lateErr <- err
+		return "", nil
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/stream", nil))
+	if err := <-lateErr; !errors.Is(err, http.ErrHandlerTimeout) {
+		t.Errorf("expected ErrHandlerTimeout for late write, got %v", err)
+	}
+	if w.Code != http.StatusOK || w.Body.String() != "partial" {
+		t.Errorf("expected untouched partial response, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestTimeoutPanic(t *testing.T) {
+	r := New()
+	r.Use(TimeoutMiddleware(time.Second))
+	r.GET("/panic", func(ctx *Context) (string, error) {
+		panic("boom")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/panic", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected panic to reach Recoverer, got %d", w.Code)
+	}
+}
+
+func TestContextWithTimeout(t *testing.T) {
+	ctx := NewContext(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	c, cancel := ctx.WithTimeout(time.Millisecond)
+	defer cancel()
+	<-c.Done()
+	if !errors.Is(c.Err(), context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", c.Err())
+	}
+}
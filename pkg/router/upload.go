@@ -0,0 +1,88 @@
+package router
+
+import (
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// defaultMaxMemory is the multipart memory limit used by FormFile, matching
+// net/http. Larger parts are stored in temporary files.
+const defaultMaxMemory = 32 << 20
+
+// MaxBodySize limits request bodies to n bytes. Requests declaring a larger
+// Content-Length get a 413 error fragment straight away; bodies that turn
+// out larger fail when read, and Context.Error turns that into a 413.
+func MaxBodySize(n int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > n {
+				NewContext(w, r).Error(ErrPayloadTooLarge("Request body too large"))
+				return
+			}
+			if r.Body != nil {
+				r.Body = http.MaxBytesReader(w, r.Body, n)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// MultipartForm parses a multipart/form-data body, keeping up to maxMemory
+// bytes of file parts in memory and the rest in temporary files.
+func (c *Context) MultipartForm(maxMemory int64) (*multipart.Form, error) {
+	if err := c.Request.ParseMultipartForm(maxMemory); err != nil {
+		return nil, uploadError(err)
+	}
+	return c.Request.MultipartForm, nil
+}
+
+// FormFile returns the first file uploaded in the named form field.
+func (c *Context) FormFile(name string) (*multipart.FileHeader, error) {
+	if c.Request.MultipartForm == nil {
+		if _, err := c.MultipartForm(defaultMaxMemory); err != nil {
+			return nil, err
+		}
+	}
+	files := c.Request.MultipartForm.File[name]
+	if len(files) == 0 {
+		return nil, ErrBadRequest("Missing file: " + name)
+	}
+	return files[0], nil
+}
+
+// SaveUploadedFile writes an uploaded file to dst, creating its directory.
+// dst must not come from the client unsanitized.
+func (c *Context) SaveUploadedFile(fh *multipart.FileHeader, dst string) error {
+	src, err := fh.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, src); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// uploadError maps multipart parsing failures to HTTP errors. Oversized
+// bodies keep their *http.MaxBytesError for Context.Error.
+func uploadError(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return err
+	}
+	return ErrBadRequest("Invalid multipart form").WithInternal(err)
+}
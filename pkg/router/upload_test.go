@@ -0,0 +1,123 @@
+package router
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func multipartBody(t *testing.T, field, filename string, data []byte) (*bytes.Buffer, string) {
+	t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	if err := mw.WriteField("caption", "sunset"); err != nil {
+		t.Fatal(err)
+	}
+	fw, err := mw.CreateFormFile(field, filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write(data)
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf, mw.FormDataContentType()
+}
+
+func TestUpload(t *testing.T) {
+	dir := t.TempDir()
+	r := New()
+	r.POST("/photos", func(ctx *Context) (string, error) {
+		fh, err := ctx.FormFile("photo")
+		if err != nil {
+			return "", err
+		}
+		dst := filepath.Join(dir, "uploads", filepath.Base(fh.Filename))
+		if err := ctx.SaveUploadedFile(fh, dst); err != nil {
+			return "", err
+		}
+		return ctx.FormValue("caption") + ":" + fh.Filename, nil
+	})
+
+	photo := bytes.Repeat([]byte{0xFF, 0xD8, 0x42}, 1000)
+	body, contentType := multipartBody(t, "photo", "beach.jpg", photo)
+	req := httptest.NewRequest("POST", "/photos", body)
+	req.Header.Set("Content-Type", contentType)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || w.Body.String() != "sunset:beach.jpg" {
+		t.Fatalf("expected upload to succeed, got %d %q", w.Code, w.Body.String())
+	}
+	saved, err := os.ReadFile(filepath.Join(dir, "uploads", "beach.jpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(saved, photo) {
+		t.Errorf("saved file differs: %d bytes, want %d", len(saved), len(photo))
+	}
+
+	// Missing field
+	body, contentType = multipartBody(t, "other", "x.txt", []byte("x"))
+	req = httptest.NewRequest("POST", "/photos", body)
+	req.Header.Set("Content-Type", contentType)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "Missing file: photo") {
+		t.Errorf("expected 400 for missing file, got %d %q", w.Code, w.Body.String())
+	}
+
+	// Not multipart
+	req = httptest.NewRequest("POST", "/photos", strings.NewReader("a=b"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for non-multipart body, got %d", w.Code)
+	}
+}
+
+func TestMaxBodySize(t *testing.T) {
+	r := New()
+	r.Use(MaxBodySize(1024))
+	r.POST("/photos", func(ctx *Context) (string, error) {
+		form, err := ctx.MultipartForm(512)
+		if err != nil {
+			return "", err
+		}
+		return form.Value["caption"][0], nil
+	})
+
+	post := func(data []byte, chunked bool) *httptest.ResponseRecorder {
+		body, contentType := multipartBody(t, "photo", "big.jpg", data)
+		req := httptest.NewRequest("POST", "/photos", body)
+		req.Header.Set("Content-Type", contentType)
+		if chunked {
+			req.ContentLength = -1
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := post([]byte("small"), false); w.Code != http.StatusOK || w.Body.String() != "sunset" {
+		t.Errorf("expected small upload to succeed, got %d %q", w.Code, w.Body.String())
+	}
+
+	// Declared Content-Length is rejected before the handler runs
+	w := post(bytes.Repeat([]byte("x"), 4096), false)
+	if w.Code != http.StatusRequestEntityTooLarge || !strings.Contains(w.Body.String(), `role="alert">Request body too large`) {
+		t.Errorf("expected 413 fragment, got %d %q", w.Code, w.Body.String())
+	}
+
+	// Unknown length fails while reading
+	w = post(bytes.Repeat([]byte("x"), 4096), true)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 for chunked body, got %d %q", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,276 @@
+package router
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ValidationErrors maps field names to messages. Returned from a handler,
+// it produces a 422 with the messages as a <ul class="error"> fragment;
+// pass it to the dsValidationSignals template helper to show the messages
+// next to their fields.
+type ValidationErrors map[string]string
+
+func (e ValidationErrors) Error() string {
+	fields := e.fields()
+	msgs := make([]string, len(fields))
+	for i, field := range fields {
+		msgs[i] = e[field]
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+// fields returns the field names in order.
+func (e ValidationErrors) fields() []string {
+	fields := make([]string, 0, len(e))
+	for field := range e {
+		fields = append(fields, field)
+	}
+	slices.Sort(fields)
+	return fields
+}
+
+// Validator validates a bound request struct, returning ValidationErrors
+// for invalid input. Set one with WithValidator to replace the built-in
+// `validate` tags.
+type Validator interface {
+	Validate(v any) error
+}
+
+// ValidatorFunc adapts a function to Validator.
+type ValidatorFunc func(v any) error
+
+// Validate calls f(v).
+func (f ValidatorFunc) Validate(v any) error { return f(v) }
+
+// WithValidator sets the Validator used by Context.Validate.
+func WithValidator(v Validator) Option {
+	return func(c *routerConfig) {
+		c.validator = v
+	}
+}
+
+// Validate checks v, usually just filled by Bind or BindAll, with the
+// router's Validator, by default ValidateStruct. Handlers can return the
+// error directly:
+//
+//	var in CreateTodo
+//	if err := ctx.BindAll(&in); err != nil {
+//	    return "", err
+//	}
+//	if err := ctx.Validate(&in); err != nil {
+//	    return "", err // 422 listing the problems
+//	}
+func (c *Context) Validate(v any) error {
+	if c.config != nil && c.config.validator != nil {
+		return c.config.validator.Validate(v)
+	}
+	return ValidateStruct(v)
+}
+
+// ValidateStruct checks the `validate` tags of the struct v points to,
+// including embedded structs:
+//
+//	type CreateTodo struct {
+//	    Title string `json:"title" validate:"required,min=3,max=100"`
+//	    Email string `json:"email" validate:"omitempty,email"`
+//	}
+//
+// Rules are required, omitempty (skip the rest when empty), min and max
+// (length for strings and slices, value for numbers), len, email, url and
+// oneof (space-separated values). Errors are keyed by the field's json
+// tag name, else its Go name. Returns ValidationErrors, nil if v is valid,
+// or a plain error for a malformed tag.
+func ValidateStruct(v any) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return fmt.Errorf("router: cannot validate nil %T", v)
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("router: cannot validate %T, want a struct", v)
+	}
+	errs := make(ValidationErrors)
+	if err := validateStruct(rv, errs); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func validateStruct(sv reflect.Value, errs ValidationErrors) error {
+	st := sv.Type()
+	for i := 0; i < st.NumField(); i++ {
+		f := st.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		fv := sv.Field(i)
+		tag := f.Tag.Get("validate")
+		if f.Anonymous && tag == "" {
+			for fv.Kind() == reflect.Pointer && !fv.IsNil() {
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct {
+				if err := validateStruct(fv, errs); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if tag == "" || tag == "-" {
+			continue
+		}
+
+		name := fieldName(f)
+		msg, err := validateField(fv, tag)
+		if err != nil {
+			return fmt.Errorf("router: field %s.%s: %w", st.Name(), f.Name, err)
+		}
+		if msg != "" {
+			errs[name] = name + " " + msg
+		}
+	}
+	return nil
+}
+
+// validationRules are the rules ValidateStruct knows.
+var validationRules = []string{"required", "omitempty", "min", "max", "len", "email", "url", "oneof"}
+
+// fieldName returns the json tag name of f, else its Go name.
+func fieldName(f reflect.StructField) string {
+	if name, _, _ := strings.Cut(f.Tag.Get("json"), ","); name != "" && name != "-" {
+		return name
+	}
+	return f.Name
+}
+
+// validateField applies the comma-separated rules in tag to fv, returning
+// the message for the first that fails.
+func validateField(fv reflect.Value, tag string) (string, error) {
+	rules := strings.Split(tag, ",")
+	for i, rule := range rules {
+		rules[i] = strings.TrimSpace(rule)
+		if name, _, _ := strings.Cut(rules[i], "="); !slices.Contains(validationRules, name) {
+			return "", fmt.Errorf("unknown validation rule %q", name)
+		}
+	}
+	if fv.Kind() == reflect.Pointer {
+		if fv.IsNil() {
+			if slices.Contains(rules, "required") {
+				return "is required", nil
+			}
+			return "", nil
+		}
+		fv = fv.Elem()
+	}
+
+	for _, rule := range rules {
+		name, arg, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			if fv.IsZero() {
+				return "is required", nil
+			}
+		case "omitempty":
+			if fv.IsZero() {
+				return "", nil
+			}
+		case "min", "max", "len":
+			msg, err := checkSize(fv, name, arg)
+			if msg != "" || err != nil {
+				return msg, err
+			}
+		case "email":
+			if fv.Kind() != reflect.String {
+				return "", fmt.Errorf("email does not apply to %s", fv.Type())
+			}
+			if s := fv.String(); s != "" {
+				if a, err := mail.ParseAddress(s); err != nil || a.Address != s {
+					return "must be a valid email address", nil
+				}
+			}
+		case "url":
+			if fv.Kind() != reflect.String {
+				return "", fmt.Errorf("url does not apply to %s", fv.Type())
+			}
+			if s := fv.String(); s != "" {
+				if u, err := url.Parse(s); err != nil || u.Scheme == "" || u.Host == "" {
+					return "must be a valid URL", nil
+				}
+			}
+		case "oneof":
+			options := strings.Fields(arg)
+			if s := fmt.Sprint(fv.Interface()); !slices.Contains(options, s) {
+				return "must be one of " + strings.Join(options, ", "), nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// checkSize applies a min, max or len rule: lengths for strings (in
+// characters), slices and maps, values for numbers.
+func checkSize(fv reflect.Value, rule, arg string) (string, error) {
+	limit, err := strconv.ParseFloat(arg, 64)
+	if err != nil {
+		return "", fmt.Errorf("bad %s argument %q", rule, arg)
+	}
+
+	var n float64
+	var unit string
+	switch fv.Kind() {
+	case reflect.String:
+		n, unit = float64(utf8.RuneCountInString(fv.String())), " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		n, unit = float64(fv.Len()), " items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = float64(fv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n = float64(fv.Uint())
+	case reflect.Float32, reflect.Float64:
+		n = fv.Float()
+	default:
+		return "", fmt.Errorf("%s does not apply to %s", rule, fv.Type())
+	}
+
+	switch {
+	case rule == "min" && n < limit:
+		return "must be at least " + arg + unit, nil
+	case rule == "max" && n > limit:
+		return "must be at most " + arg + unit, nil
+	case rule == "len" && n != limit:
+		return "must be exactly " + arg + unit, nil
+	}
+	return "", nil
+}
+
+// writeValidationErrors writes errs as a 422 error fragment, one list item
+// per field in name order.
+func (c *Context) writeValidationErrors(errs ValidationErrors) {
+	var b strings.Builder
+	b.WriteString(`<ul class="error" role="alert">`)
+	for _, field := range errs.fields() {
+		b.WriteString(`<li data-field="` + template.HTMLEscapeString(field) + `">`)
+		b.WriteString(template.HTMLEscapeString(errs[field]))
+		b.WriteString(`</li>`)
+	}
+	b.WriteString(`</ul>`)
+
+	c.written = true
+	c.Response.Header().Set("Content-Type", "text/html; charset=utf-8")
+	c.Response.WriteHeader(http.StatusUnprocessableEntity)
+	c.Response.Write([]byte(b.String()))
+}
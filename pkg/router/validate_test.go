@@ -0,0 +1,124 @@
+package router
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type Audit struct {
+	Reason string `json:"reason" validate:"required"`
+}
+
+type createTodo struct {
+	Audit
+	Title    string   `json:"title" validate:"required,min=3,max=10"`
+	Email    string   `json:"email" validate:"omitempty,email"`
+	Site     string   `json:"site,omitempty" validate:"omitempty,url"`
+	Priority int      `json:"priority" validate:"min=1,max=5"`
+	Status   string   `json:"status" validate:"oneof=open done"`
+	Tags     []string `json:"tags" validate:"max=2"`
+	Due      *string  `validate:"required"`
+	Code     string   `json:"code" validate:"omitempty,len=4"`
+}
+
+func TestValidateStruct(t *testing.T) {
+	due := "tomorrow"
+	valid := createTodo{
+		Audit: Audit{Reason: "test"}, Title: "Milk", Email: "a@b.co", Site: "https://example.com",
+		Priority: 3, Status: "open", Tags: []string{"x"}, Due: &due, Code: "ABCD",
+	}
+	if err := ValidateStruct(&valid); err != nil {
+		t.Fatalf("valid: %v", err)
+	}
+
+	invalid := createTodo{
+		Title: "Mi", Email: "nope", Site: "example.com", Priority: 9,
+		Status: "later", Tags: []string{"a", "b", "c"}, Code: "ABC",
+	}
+	err := ValidateStruct(&invalid)
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("err = %v, want ValidationErrors", err)
+	}
+	want := ValidationErrors{
+		"reason":   "reason is required",
+		"title":    "title must be at least 3 characters",
+		"email":    "email must be a valid email address",
+		"site":     "site must be a valid URL",
+		"priority": "priority must be at most 5",
+		"status":   "status must be one of open, done",
+		"tags":     "tags must be at most 2 items",
+		"Due":      "Due is required",
+		"code":     "code must be exactly 4 characters",
+	}
+	if !reflect.DeepEqual(errs, want) {
+		t.Errorf("errors =\n%v\nwant\n%v", errs, want)
+	}
+}
+
+func TestValidateStructMalformed(t *testing.T) {
+	var unknown struct {
+		Name string `validate:"required,shiny"`
+	}
+	var badArg struct {
+		Name string `validate:"min=three"`
+	}
+	for _, v := range []any{&unknown, &badArg, nil, new(int)} {
+		err := ValidateStruct(v)
+		var errs ValidationErrors
+		if err == nil || errors.As(err, &errs) {
+			t.Errorf("ValidateStruct(%T) = %v, want a plain error", v, err)
+		}
+	}
+}
+
+func TestValidationErrorFragment(t *testing.T) {
+	r := New()
+	r.POST("/todos", func(ctx *Context) (string, error) {
+		var in createTodo
+		if err := ctx.Bind(&in); err != nil {
+			return "", err
+		}
+		if err := ctx.Validate(&in); err != nil {
+			return "", err
+		}
+		return "created", nil
+	})
+
+	w := httptest.NewRecorder()
+	body := `{"reason": "r", "title": "<b", "priority": 2, "status": "open", "Due": "x"}`
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/todos", strings.NewReader(body)))
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want 422", w.Code)
+	}
+	want := `<ul class="error" role="alert"><li data-field="title">title must be at least 3 characters</li></ul>`
+	if got := w.Body.String(); got != want {
+		t.Errorf("body = %s, want %s", got, want)
+	}
+
+	w = httptest.NewRecorder()
+	body = `{"reason": "r", "title": "Milk", "priority": 2, "status": "open", "Due": "x"}`
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/todos", strings.NewReader(body)))
+	if w.Code != http.StatusOK || w.Body.String() != "created" {
+		t.Errorf("valid: %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestWithValidator(t *testing.T) {
+	r := New(WithValidator(ValidatorFunc(func(v any) error {
+		return ValidationErrors{"name": "name is taken", "a<b": "x & y"}
+	})))
+	r.GET("/", func(ctx *Context) (string, error) {
+		return "", ctx.Validate(struct{}{})
+	})
+
+	w := serve(r, "GET", "/")
+	want := `<ul class="error" role="alert"><li data-field="a&lt;b">x &amp; y</li><li data-field="name">name is taken</li></ul>`
+	if w.Code != http.StatusUnprocessableEntity || w.Body.String() != want {
+		t.Errorf("got %d %s, want 422 %s", w.Code, w.Body.String(), want)
+	}
+}
@@ -0,0 +1,64 @@
+package router
+
+import (
+	"net/http"
+	"strings"
+)
+
+// htmxVary lists the request headers HTMX clients use to pick a variant.
+var htmxVary = []string{"HX-Request", "HX-Target"}
+
+// VaryHTMX returns middleware that adds "Vary: HX-Request, HX-Target" to
+// every response, for routes that return a fragment to HTMX requests and a
+// full page otherwise. Context.Header adds the same values automatically
+// when a handler reads those headers.
+func VaryHTMX() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			AddVary(w.Header(), htmxVary...)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// AddVary appends names to the Vary header, keeping existing values and
+// skipping names already present.
+func AddVary(h http.Header, names ...string) {
+	var existing []string
+	for _, v := range h.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				existing = append(existing, name)
+			}
+		}
+	}
+
+	merged := existing
+	for _, name := range names {
+		if !containsFold(merged, name) && !containsFold(merged, "*") {
+			merged = append(merged, name)
+		}
+	}
+	if len(merged) > len(existing) || len(h.Values("Vary")) > 1 {
+		h.Set("Vary", strings.Join(merged, ", "))
+	}
+}
+
+// varyOn records that the response depends on a request header.
+func (c *Context) varyOn(header string) {
+	for _, name := range htmxVary {
+		if strings.EqualFold(header, name) {
+			AddVary(c.Response.Header(), name)
+			return
+		}
+	}
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAddVary(t *testing.T) {
+	tests := []struct {
+		existing []string
+		add      []string
+		want     string
+	}{
+		{nil, []string{"HX-Request"}, "HX-Request"},
+		{[]string{"Accept-Encoding"}, []string{"HX-Request", "HX-Target"}, "Accept-Encoding, HX-Request, HX-Target"},
+		{[]string{"Accept-Encoding, hx-request"}, []string{"HX-Request"}, "Accept-Encoding, hx-request"},
+		{[]string{"Accept-Encoding", "Cookie"}, []string{"Accept"}, "Accept-Encoding, Cookie, Accept"},
+		{[]string{"*"}, []string{"HX-Request"}, "*"},
+	}
+	for _, tt := range tests {
+		h := http.Header{}
+		for _, v := range tt.existing {
+			h.Add("Vary", v)
+		}
+		AddVary(h, tt.add...)
+		if got := h.Values("Vary"); len(got) != 1 || got[0] != tt.want {
+			t.Errorf("AddVary(%q, %q) = %q, want %q", tt.existing, tt.add, got, tt.want)
+		}
+	}
+}
+
+func TestVaryHTMX(t *testing.T) {
+	r := New()
+	r.Use(NoCacheMiddleware, VaryHTMX())
+	r.GET("/page", func(ctx *Context) (string, error) {
+		ctx.Response.Header().Add("Vary", "Accept-Encoding")
+		return "<p>page</p>", nil
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/page", nil))
+	if got := w.Header().Values("Vary"); len(got) != 2 || got[0] != "HX-Request, HX-Target" || got[1] != "Accept-Encoding" {
+		t.Errorf("expected Vary values to be kept, got %q", got)
+	}
+	if w.Header().Get("Cache-Control") != "no-cache, no-store, must-revalidate" {
+		t.Errorf("expected no-cache headers, got %q", w.Header().Get("Cache-Control"))
+	}
+}
+
+func TestVaryOnInspectedHeaders(t *testing.T) {
+	r := New()
+	r.GET("/list", func(ctx *Context) (string, error) {
+		htmx, target := ctx.Header("hx-request"), ctx.Header("HX-Target")
+		if htmx == "true" && target == "list" {
+			return "<li>item</li>", nil
+		}
+		return "<ul><li>item</li></ul>", nil
+	})
+	r.GET("/plain", func(ctx *Context) (string, error) {
+		ctx.Header("Authorization")
+		return "plain", nil
+	})
+	r.GET("/datastar", func(ctx *Context) (string, error) {
+		ctx.IsDatastar()
+		return "ok", nil
+	})
+
+	vary := func(path string) string {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w.Header().Get("Vary")
+	}
+	if got := vary("/list"); got != "HX-Request, HX-Target" {
+		t.Errorf("expected HX-Request, HX-Target, got %q", got)
+	}
+	if got := vary("/plain"); got != "" {
+		t.Errorf("expected no Vary, got %q", got)
+	}
+	if got := vary("/datastar"); got != "Accept" {
+		t.Errorf("expected Accept, got %q", got)
+	}
+
+	lw := &LayoutWrapper{Layout: func(content string) string { return "<html>" + content + "</html>" }}
+	r.With(NoCacheMiddleware, lw.Wrap).GET("/wrapped", func(ctx *Context) (string, error) {
+		ctx.Header("HX-Request")
+		return "content", nil
+	})
+	if got := vary("/wrapped"); got != "Accept, HX-Request" {
+		t.Errorf("expected Accept, HX-Request, got %q", got)
+	}
+}
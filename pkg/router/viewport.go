@@ -0,0 +1,77 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/stukennedy/irgo/pkg/viewport"
+)
+
+// maxSignalBody caps how much of a Datastar request body is read when
+// looking for the viewport signal.
+const maxSignalBody = 64 << 10
+
+// ViewportMiddleware parses device viewport hints from the X-Irgo-Viewport
+// header or the "viewport" Datastar signal into the request context (see
+// Context.Viewport). Requests without hints get fallback; a zero fallback
+// means viewport.Desktop.
+func ViewportMiddleware(fallback viewport.Viewport) func(http.Handler) http.Handler {
+	if fallback == (viewport.Viewport{}) {
+		fallback = viewport.Desktop
+	}
+	fallback = fallback.Clamp()
+	fallback.Reported = false
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			v, ok := viewportFromRequest(r)
+			if !ok {
+				v = fallback
+			}
+			next.ServeHTTP(w, r.WithContext(viewport.WithContext(r.Context(), v)))
+		})
+	}
+}
+
+// viewportFromRequest reads hints from the header, then Datastar signals.
+func viewportFromRequest(r *http.Request) (viewport.Viewport, bool) {
+	if h := r.Header.Get(viewport.Header); h != "" {
+		v, err := viewport.Parse(h)
+		return v, err == nil
+	}
+	if !IsDatastarRequest(r) {
+		return viewport.Viewport{}, false
+	}
+
+	var data []byte
+	if r.Method == http.MethodGet {
+		data = []byte(r.URL.Query().Get("datastar"))
+	} else if r.Body != nil {
+		// Read the body and put it back for the handler
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxSignalBody))
+		r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), r.Body))
+		if err != nil {
+			return viewport.Viewport{}, false
+		}
+		data = body
+	}
+
+	var signals struct {
+		Viewport *viewport.Viewport `json:"viewport"`
+	}
+	if len(data) == 0 || json.Unmarshal(data, &signals) != nil || signals.Viewport == nil {
+		return viewport.Viewport{}, false
+	}
+	v := signals.Viewport.Clamp()
+	v.Reported = true
+	return v, true
+}
+
+// Viewport returns the client's viewport hints parsed by ViewportMiddleware,
+// or viewport.Desktop if the middleware isn't installed.
+func (c *Context) Viewport() viewport.Viewport {
+	v, _ := viewport.FromContext(c.Context())
+	return v
+}
@@ -0,0 +1,135 @@
+package router
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stukennedy/irgo/pkg/images"
+	"github.com/stukennedy/irgo/pkg/viewport"
+)
+
+func TestViewportMiddleware(t *testing.T) {
+	r := New()
+	r.Use(ViewportMiddleware(viewport.Viewport{}))
+
+	var got viewport.Viewport
+	var body string
+	handler := func(ctx *Context) (string, error) {
+		got = ctx.Viewport()
+		b, _ := io.ReadAll(ctx.Request.Body)
+		body = string(b)
+		return "", nil
+	}
+	r.GET("/", handler)
+	r.POST("/", handler)
+
+	// Header
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(viewport.Header, "390x844@3")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	if want := (viewport.Viewport{Width: 390, Height: 844, DPR: 3, Reported: true}); got != want {
+		t.Errorf("header: got %+v, want %+v", got, want)
+	}
+
+	// Datastar signal on a GET
+	signals := `{"viewport":{"width":820,"height":1180,"dpr":2}}`
+	req = httptest.NewRequest("GET", "/?datastar="+url.QueryEscape(signals), nil)
+	req.Header.Set("Accept", "text/event-stream")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	if !got.Reported || got.Width != 820 || got.DPR != 2 {
+		t.Errorf("GET signal: got %+v", got)
+	}
+
+	// Datastar signal in a POST body, still readable by the handler
+	req = httptest.NewRequest("POST", "/", strings.NewReader(signals))
+	req.Header.Set("Accept", "text/event-stream")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	if !got.Reported || got.Height != 1180 {
+		t.Errorf("POST signal: got %+v", got)
+	}
+	if body != signals {
+		t.Errorf("expected body to be restored, got %q", body)
+	}
+
+	// Absurd values are clamped
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(viewport.Header, "100000x0@40")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	if got.Width != viewport.MaxSize || got.Height != viewport.MinSize || got.DPR != viewport.MaxDPR {
+		t.Errorf("expected clamped viewport, got %+v", got)
+	}
+
+	// No hints
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if got.Reported || got.Width != viewport.Desktop.Width {
+		t.Errorf("expected desktop fallback, got %+v", got)
+	}
+}
+
+func TestViewportFallback(t *testing.T) {
+	r := New()
+	r.Use(ViewportMiddleware(viewport.Phone))
+
+	var got viewport.Viewport
+	r.GET("/", func(ctx *Context) (string, error) {
+		got = ctx.Viewport()
+		return "", nil
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if got.Reported || !got.IsCompact() {
+		t.Errorf("expected unreported phone fallback, got %+v", got)
+	}
+}
+
+func TestViewportImages(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 400, 200))
+	img.Set(0, 0, color.White)
+	var buf strings.Builder
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	assets := fstest.MapFS{"photo.png": {Data: []byte(buf.String())}}
+
+	r := New()
+	r.Use(ViewportMiddleware(viewport.Viewport{}))
+	if err := r.Images("/img", images.FromFS(assets), images.Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	width := func(target, hint string) (int, http.Header) {
+		t.Helper()
+		req := httptest.NewRequest("GET", target, nil)
+		if hint != "" {
+			req.Header.Set(viewport.Header, hint)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", target, w.Code)
+		}
+		cfg, err := png.DecodeConfig(w.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cfg.Width, w.Header()
+	}
+
+	if got, h := width("/img/photo.png?w=100", "390x844@2"); got != 200 || !strings.Contains(h.Get("Vary"), viewport.Header) {
+		t.Errorf("expected 200px at dpr 2 varying on viewport, got %d (Vary %q)", got, h.Get("Vary"))
+	}
+	if got, _ := width("/img/photo.png?w=100&dpr=1", "390x844@3"); got != 100 {
+		t.Errorf("expected explicit dpr to win, got %d", got)
+	}
+	if got, _ := width("/img/photo.png?w=100", ""); got != 100 {
+		t.Errorf("expected 100px without hints, got %d", got)
+	}
+}
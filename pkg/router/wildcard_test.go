@@ -0,0 +1,86 @@
+package router
+
+import (
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestWildcard(t *testing.T) {
+	var wildcard string
+	var parts []string
+	r := New()
+	r.Route("/api", func(api *Router) {
+		api.GET("/files/*", func(ctx *Context) (string, error) {
+			wildcard = ctx.Wildcard()
+			parts = ctx.WildcardParts()
+			return "", nil
+		})
+	})
+
+	tests := []struct {
+		path     string
+		wildcard string
+		parts    []string
+	}{
+		{"/api/files/docs/2024/report.pdf", "docs/2024/report.pdf", []string{"docs", "2024", "report.pdf"}},
+		{"/api/files/read%20me.txt", "read me.txt", []string{"read me.txt"}},
+		{"/api/files/a%2Fb/c.txt", "a/b/c.txt", []string{"a", "b", "c.txt"}},
+		{"/api/files/a/./b/../c", "a/./b/../c", []string{"a", "c"}},
+		{"/api/files/a/../../../etc/passwd", "a/../../../etc/passwd", []string{"etc", "passwd"}},
+		{"/api/files/", "", nil},
+	}
+	for _, tt := range tests {
+		wildcard, parts = "", nil
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", tt.path, nil))
+		if wildcard != tt.wildcard {
+			t.Errorf("%s: Wildcard() = %q, want %q", tt.path, wildcard, tt.wildcard)
+		}
+		if !reflect.DeepEqual(parts, tt.parts) {
+			t.Errorf("%s: WildcardParts() = %q, want %q", tt.path, parts, tt.parts)
+		}
+	}
+}
+
+func TestStatic(t *testing.T) {
+	files := fstest.MapFS{
+		"public/app.css":        {Data: []byte("body{}")},
+		"public/my docs/a.txt":  {Data: []byte("spaced")},
+		"public/nested/x/y.txt": {Data: []byte("nested")},
+	}
+	public, err := fs.Sub(files, "public")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := New()
+	r.Route("/assets", func(assets *Router) {
+		assets.Static("/static", http.FS(public))
+	})
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/assets/static/app.css", http.StatusOK, "body{}"},
+		{"/assets/static/my%20docs/a.txt", http.StatusOK, "spaced"},
+		{"/assets/static/nested/x/y.txt", http.StatusOK, "nested"},
+		{"/assets/static/nested/../app.css", http.StatusOK, "body{}"},
+		{"/assets/static/../../public/app.css", http.StatusNotFound, ""},
+		{"/assets/static/missing.css", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+		if w.Code != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.path, tt.status, w.Code)
+		}
+		if tt.body != "" && strings.TrimSpace(w.Body.String()) != tt.body {
+			t.Errorf("%s: expected %q, got %q", tt.path, tt.body, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,102 @@
+// Package servertiming collects Server-Timing metrics for a request.
+//
+// router.ServerTimingMiddleware puts a Timing in each request's context and
+// writes its metrics in the Server-Timing response header, where browser
+// dev tools show them. httpclient records a metric for each outbound call
+// made with that context, and handlers can add their own:
+//
+//	start := time.Now()
+//	rows := db.Query(...)
+//	servertiming.FromContext(ctx.Request.Context()).Add("db", time.Since(start), "todos")
+package servertiming
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Header is the response header carrying the metrics.
+const Header = "Server-Timing"
+
+// Metric is one named duration.
+type Metric struct {
+	Name     string
+	Duration time.Duration
+	Desc     string
+}
+
+// String formats the metric for the header, e.g. `db;dur=12.5;desc="todos"`.
+func (m Metric) String() string {
+	var b strings.Builder
+	b.WriteString(m.Name)
+	b.WriteString(";dur=")
+	b.WriteString(strconv.FormatFloat(float64(m.Duration.Microseconds())/1000, 'f', -1, 64))
+	if m.Desc != "" {
+		b.WriteString(`;desc="`)
+		for _, r := range m.Desc {
+			if r == '"' || r == '\\' {
+				b.WriteByte('\\')
+			}
+			b.WriteRune(r)
+		}
+		b.WriteByte('"')
+	}
+	return b.String()
+}
+
+// Timing collects the metrics of one request. It is safe for concurrent
+// use, and a nil Timing discards what is added to it.
+type Timing struct {
+	mu      sync.Mutex
+	metrics []Metric
+}
+
+// Add records a metric.
+func (t *Timing) Add(name string, d time.Duration, desc string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.metrics = append(t.metrics, Metric{Name: name, Duration: d, Desc: desc})
+}
+
+// Metrics returns the metrics recorded so far.
+func (t *Timing) Metrics() []Metric {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Metric(nil), t.metrics...)
+}
+
+// String formats the metrics recorded so far as a Server-Timing header
+// value, or "" if there are none.
+func (t *Timing) String() string {
+	metrics := t.Metrics()
+	parts := make([]string, len(metrics))
+	for i, m := range metrics {
+		parts[i] = m.String()
+	}
+	return strings.Join(parts, ", ")
+}
+
+type contextKey struct{}
+
+// WithContext returns a context carrying t.
+func WithContext(ctx context.Context, t *Timing) context.Context {
+	return context.WithValue(ctx, contextKey{}, t)
+}
+
+// FromContext returns the Timing stored in ctx, or nil if there is none.
+func FromContext(ctx context.Context) *Timing {
+	if ctx == nil {
+		return nil
+	}
+	t, _ := ctx.Value(contextKey{}).(*Timing)
+	return t
+}
@@ -0,0 +1,25 @@
+package servertiming
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTiming(t *testing.T) {
+	timing := &Timing{}
+	ctx := WithContext(context.Background(), timing)
+
+	FromContext(ctx).Add("db", 12500*time.Microsecond, "todos")
+	FromContext(ctx).Add("http", 3*time.Millisecond, `GET "api"`)
+	want := `db;dur=12.5;desc="todos", http;dur=3;desc="GET \"api\""`
+	if got := timing.String(); got != want {
+		t.Errorf("String = %s, want %s", got, want)
+	}
+
+	// Without a Timing, metrics are discarded
+	FromContext(context.Background()).Add("db", time.Second, "")
+	if got := FromContext(context.Background()).String(); got != "" {
+		t.Errorf("nil String = %q, want empty", got)
+	}
+}
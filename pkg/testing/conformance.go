@@ -0,0 +1,113 @@
+package testing
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stukennedy/irgo/pkg/adapter"
+	"github.com/stukennedy/irgo/pkg/core"
+	"github.com/stukennedy/irgo/pkg/routemeta"
+)
+
+// sampleValues are the form values sent for each field type during
+// conformance checks.
+var sampleValues = map[string]string{
+	"":       "test",
+	"string": "test",
+	"int":    "1",
+	"bool":   "true",
+}
+
+// CheckConformance round-trips each documented route through the in-process
+// adapter, exactly as the generated native clients do, and reports every way
+// the handler disagrees with its metadata:
+//   - the route must respond to its method with a non-404/405, non-5xx status
+//     when sent all documented fields (path params are "1")
+//   - every documented trigger must appear in the response headers or body
+//   - omitting any required field must produce a 4xx response
+//
+// Handlers must succeed on the sample values, so seed any data they need
+// before calling it.
+func CheckConformance(handler http.Handler, docs []routemeta.Doc) []error {
+	a := adapter.NewHTTPAdapter(handler)
+	var errs []error
+
+	for _, doc := range docs {
+		if err := doc.Validate(); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		name := doc.Method + " " + doc.Pattern
+
+		form := url.Values{}
+		for _, f := range doc.Fields {
+			form.Set(f.Name, sampleValues[f.Type])
+		}
+
+		resp := conformanceRequest(a, doc, form)
+		switch {
+		case resp.Status == http.StatusNotFound || resp.Status == http.StatusMethodNotAllowed:
+			errs = append(errs, fmt.Errorf("%s: route not registered (status %d)", name, resp.Status))
+			continue
+		case resp.Status >= 500:
+			errs = append(errs, fmt.Errorf("%s: status %d with documented fields", name, resp.Status))
+			continue
+		}
+
+		for _, trigger := range doc.Triggers {
+			if !strings.Contains(resp.Headers, trigger) && !strings.Contains(string(resp.Body), trigger) {
+				errs = append(errs, fmt.Errorf("%s: documented trigger %q not in response", name, trigger))
+			}
+		}
+
+		for _, f := range doc.Fields {
+			if !f.Required {
+				continue
+			}
+			partial := url.Values{}
+			for k, v := range form {
+				if k != f.Name {
+					partial[k] = v
+				}
+			}
+			if resp := conformanceRequest(a, doc, partial); resp.Status < 400 || resp.Status >= 500 {
+				errs = append(errs, fmt.Errorf("%s: required field %q omitted but got status %d", name, f.Name, resp.Status))
+			}
+		}
+	}
+	return errs
+}
+
+// AssertConformance fails the test for each error reported by CheckConformance.
+func AssertConformance(t *testing.T, handler http.Handler, docs []routemeta.Doc) {
+	t.Helper()
+	for _, err := range CheckConformance(handler, docs) {
+		t.Error(err)
+	}
+}
+
+// conformanceRequest sends a request shaped like the generated clients':
+// query string for GET/DELETE, form body otherwise.
+func conformanceRequest(a *adapter.HTTPAdapter, doc routemeta.Doc, form url.Values) *core.Response {
+	// "1" satisfies numeric constraints such as {id:[0-9]+}
+	params := make(map[string]string)
+	for _, p := range doc.PathParams() {
+		params[p] = "1"
+	}
+	path := doc.Path(params)
+
+	if doc.Method == http.MethodGet || doc.Method == http.MethodDelete {
+		if len(form) > 0 {
+			path += "?" + form.Encode()
+		}
+		return a.HandleRequest(core.NewRequest(doc.Method, path))
+	}
+
+	req := core.NewRequest(doc.Method, path)
+	req.SetHeader("Content-Type", "application/x-www-form-urlencoded")
+	req.Body = []byte(form.Encode())
+	return a.HandleRequest(req)
+}
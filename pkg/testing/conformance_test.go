@@ -0,0 +1,94 @@
+package testing
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stukennedy/irgo/pkg/routemeta"
+)
+
+func newConformanceHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /todos", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<ul></ul>"))
+	})
+	mux.HandleFunc("POST /todos", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("title") == "" {
+			http.Error(w, "title required", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("HX-Trigger", "todo-created")
+		w.WriteHeader(http.StatusCreated)
+	})
+	mux.HandleFunc("PATCH /todos/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<li id="todo-` + r.PathValue("id") + `"></li>`))
+	})
+	return mux
+}
+
+func TestCheckConformance(t *testing.T) {
+	docs := []routemeta.Doc{
+		{Method: "GET", Pattern: "/todos", Fields: []routemeta.Field{{Name: "filter"}}},
+		{
+			Method:   "POST",
+			Pattern:  "/todos",
+			Fields:   []routemeta.Field{{Name: "title", Required: true}},
+			Triggers: []string{"todo-created"},
+		},
+		{Method: "PATCH", Pattern: "/todos/{id:[0-9]+}", Fields: []routemeta.Field{{Name: "done", Type: "bool"}}},
+	}
+
+	if errs := CheckConformance(newConformanceHandler(), docs); len(errs) != 0 {
+		t.Errorf("expected no conformance errors, got %v", errs)
+	}
+}
+
+func TestCheckConformanceLyingMetadata(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  routemeta.Doc
+		want string
+	}{
+		{
+			name: "unregistered route",
+			doc:  routemeta.Doc{Method: "DELETE", Pattern: "/todos/{id}"},
+			want: "route not registered",
+		},
+		{
+			name: "missing trigger",
+			doc:  routemeta.Doc{Method: "GET", Pattern: "/todos", Triggers: []string{"todos-loaded"}},
+			want: `trigger "todos-loaded"`,
+		},
+		{
+			name: "required field not enforced",
+			doc: routemeta.Doc{
+				Method:  "PATCH",
+				Pattern: "/todos/{id}",
+				Fields:  []routemeta.Field{{Name: "done", Type: "bool", Required: true}},
+			},
+			want: `required field "done"`,
+		},
+		{
+			name: "invalid metadata",
+			doc: routemeta.Doc{
+				Method:  "POST",
+				Pattern: "/todos",
+				Fields:  []routemeta.Field{{Name: "title", Type: "date"}},
+			},
+			want: "unknown type",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := CheckConformance(newConformanceHandler(), []routemeta.Doc{tt.doc})
+			if len(errs) != 1 {
+				t.Fatalf("expected 1 error, got %v", errs)
+			}
+			if !strings.Contains(errs[0].Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, errs[0])
+			}
+		})
+	}
+}
@@ -33,9 +33,12 @@ import (
 )
 
 // Client provides test utilities for irgo applications.
+// Cookies set by responses are stored and sent with later requests,
+// shared by all clients derived with WithHeader.
 type Client struct {
 	handler http.Handler
 	headers map[string]string
+	cookies map[string]*http.Cookie
 }
 
 // NewClient creates a new test client for the given handler.
@@ -43,6 +46,7 @@ func NewClient(handler http.Handler) *Client {
 	return &Client{
 		handler: handler,
 		headers: make(map[string]string),
+		cookies: make(map[string]*http.Cookie),
 	}
 }
 
@@ -51,6 +55,7 @@ func (c *Client) WithHeader(key, value string) *Client {
 	newClient := &Client{
 		handler: c.handler,
 		headers: make(map[string]string),
+		cookies: c.cookies,
 	}
 	for k, v := range c.headers {
 		newClient.headers[k] = v
@@ -64,6 +69,11 @@ func (c *Client) Datastar() *Client {
 	return c.WithHeader("Accept", "text/event-stream")
 }
 
+// HTMX returns a client configured for HTMX requests.
+func (c *Client) HTMX() *Client {
+	return c.WithHeader("HX-Request", "true")
+}
+
 // DatastarWithSignals returns a client configured for Datastar requests with initial signals.
 func (c *Client) DatastarWithSignals(signalsJSON string) *Client {
 	return c.Datastar().WithHeader("Content-Type", "application/json").
@@ -133,15 +143,41 @@ func (c *Client) Delete(path string) *Response {
 	return c.request("DELETE", path, nil)
 }
 
+// Cookie returns the value of a stored cookie, or "" if not set.
+func (c *Client) Cookie(name string) string {
+	if cookie, ok := c.cookies[name]; ok {
+		return cookie.Value
+	}
+	return ""
+}
+
+// ClearCookies removes all stored cookies.
+func (c *Client) ClearCookies() {
+	for name := range c.cookies {
+		delete(c.cookies, name)
+	}
+}
+
 func (c *Client) request(method, path string, body io.Reader) *Response {
 	req := httptest.NewRequest(method, path, body)
 	for k, v := range c.headers {
 		req.Header.Set(k, v)
 	}
+	for _, cookie := range c.cookies {
+		req.AddCookie(cookie)
+	}
 
 	w := httptest.NewRecorder()
 	c.handler.ServeHTTP(w, req)
 
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.MaxAge < 0 || cookie.Value == "" {
+			delete(c.cookies, cookie.Name)
+		} else {
+			c.cookies[cookie.Name] = &http.Cookie{Name: cookie.Name, Value: cookie.Value}
+		}
+	}
+
 	return &Response{
 		StatusCode: w.Code,
 		Headers:    w.Header(),
@@ -276,6 +312,46 @@ func (r *Response) AssertSSEContains(t *testing.T, expected string) {
 	}
 }
 
+// SSEEvent is one event from a Server-Sent Events response.
+type SSEEvent struct {
+	Type string   // The event field, e.g. "datastar-patch-elements"
+	Data []string // Data lines, without the "data: " prefix
+}
+
+// DataLine returns the value of the first data line starting with
+// prefix, with the prefix removed, e.g. DataLine("selector ").
+func (e SSEEvent) DataLine(prefix string) (string, bool) {
+	for _, line := range e.Data {
+		if v, ok := strings.CutPrefix(line, prefix); ok {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// SSEEvents parses the body as a Server-Sent Events stream, in order.
+// Comments and the id and retry fields are skipped.
+func (r *Response) SSEEvents() []SSEEvent {
+	var events []SSEEvent
+	for _, block := range strings.Split(strings.ReplaceAll(r.BodyString(), "\r\n", "\n"), "\n\n") {
+		var e SSEEvent
+		for _, line := range strings.Split(block, "\n") {
+			field, value, _ := strings.Cut(line, ":")
+			value = strings.TrimPrefix(value, " ")
+			switch field {
+			case "event":
+				e.Type = value
+			case "data":
+				e.Data = append(e.Data, value)
+			}
+		}
+		if e.Type != "" || len(e.Data) > 0 {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
 // AssertContentType asserts the Content-Type header.
 func (r *Response) AssertContentType(t *testing.T, expected string) {
 	t.Helper()
@@ -95,6 +95,33 @@ func TestClientDatastar(t *testing.T) {
 	resp.AssertSSEContains(t, "SSE Response")
 }
 
+func TestSSEEvents(t *testing.T) {
+	resp := &Response{Body: []byte("event: datastar-patch-elements\n" +
+		"data: selector #list\n" +
+		"data: elements <li>one</li>\n\n" +
+		": keep-alive\n\n" +
+		"event: datastar-patch-signals\n" +
+		"id: 7\n" +
+		"data: signals {\"count\":1}\n\n")}
+
+	events := resp.SSEEvents()
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %+v", events)
+	}
+	if events[0].Type != "datastar-patch-elements" || len(events[0].Data) != 2 {
+		t.Errorf("unexpected first event %+v", events[0])
+	}
+	if sel, ok := events[0].DataLine("selector "); !ok || sel != "#list" {
+		t.Errorf("expected selector #list, got %q", sel)
+	}
+	if sig, _ := events[1].DataLine("signals "); sig != `{"count":1}` {
+		t.Errorf("unexpected signals %q", sig)
+	}
+	if _, ok := events[1].DataLine("elements "); ok {
+		t.Error("unexpected elements line")
+	}
+}
+
 func TestClientDelete(t *testing.T) {
 	client := NewClient(newTestHandler())
 
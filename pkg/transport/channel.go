@@ -2,6 +2,7 @@ package transport
 
 import (
 	"context"
+	"time"
 )
 
 // Channel represents a bidirectional communication channel (WebSocket-like).
@@ -36,6 +37,25 @@ type Channel interface {
 
 	// Get retrieves metadata from the channel.
 	Get(key string) (any, bool)
+
+	// GetString, GetInt, GetInt64, GetBool and GetTime retrieve metadata
+	// of that type, or the zero value if it's missing or of another type.
+	GetString(key string) string
+	GetInt(key string) int
+	GetInt64(key string) int64
+	GetBool(key string) bool
+	GetTime(key string) time.Time
+
+	// GetOrSet returns the metadata for key, first storing factory's
+	// result if there is none, atomically.
+	GetOrSet(key string, factory func() any) any
+
+	// Increment atomically adds delta to the integer metadata for key,
+	// stored as an int64, and returns the new value.
+	Increment(key string, delta int64) int64
+
+	// MetadataSnapshot returns a copy of the channel's metadata.
+	MetadataSnapshot() map[string]any
 }
 
 // StreamingChannel extends Channel with streaming capabilities.
@@ -0,0 +1,54 @@
+package transport
+
+import (
+	"sync"
+	"testing"
+
+	ws "github.com/stukennedy/irgo/pkg/websocket"
+)
+
+func TestChannelMetadata(t *testing.T) {
+	hub := ws.NewHub()
+	hub.SetDefaultHandler(ws.MessageHandlerFunc(func(*ws.Session, *ws.Request) (*ws.Envelope, error) {
+		return nil, nil
+	}))
+	session, err := hub.Connect("/ws/chat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	channels := map[string]Channel{
+		"inprocess": newInProcessChannel(session, 0),
+		"loopback":  &LoopbackChannel{metadata: make(map[string]any)},
+	}
+
+	for name, ch := range channels {
+		t.Run(name, func(t *testing.T) {
+			ch.Set("admin", true)
+			ch.Set("count", 1)
+
+			var wg sync.WaitGroup
+			for range 8 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for range 100 {
+						ch.Increment("count", 1)
+					}
+					ch.GetOrSet("room", func() any { return "lobby" })
+				}()
+			}
+			wg.Wait()
+
+			if got := ch.GetInt64("count"); got != 801 {
+				t.Errorf("count = %d, want 801", got)
+			}
+			if got := ch.GetString("room"); got != "lobby" {
+				t.Errorf("room = %q, want lobby", got)
+			}
+			snap := ch.MetadataSnapshot()
+			if len(snap) != 3 || !ch.GetBool("admin") || ch.GetInt("count") != 0 {
+				t.Errorf("snapshot = %v", snap)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package transport
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strings"
 	"sync"
@@ -15,9 +16,10 @@ import (
 // No network I/O occurs - all requests are processed directly in Go.
 // This is used for mobile platforms and can be enabled on desktop for testing.
 type InProcessTransport struct {
-	adapter *adapter.HTTPAdapter
-	wsHub   *ws.Hub
-	config  *Config
+	adapter   *adapter.HTTPAdapter
+	wsHub     *ws.Hub
+	config    *Config
+	scheduler *Scheduler // nil without MaxConcurrentRequests
 
 	handlers       map[string]ChannelHandler
 	defaultHandler ChannelHandler
@@ -39,12 +41,16 @@ func NewInProcessTransport(handler http.Handler, wsHub *ws.Hub, opts ...Option)
 		wsHub = ws.NewHub()
 	}
 
-	return &InProcessTransport{
+	t := &InProcessTransport{
 		adapter:  adapter.NewHTTPAdapter(handler),
 		wsHub:    wsHub,
 		config:   config,
 		handlers: make(map[string]ChannelHandler),
 	}
+	if config.MaxConcurrentRequests > 0 {
+		t.scheduler = NewScheduler(config.MaxConcurrentRequests)
+	}
+	return t
 }
 
 // HandleRequest processes a request entirely in-memory using httptest.
@@ -56,10 +62,30 @@ func (t *InProcessTransport) HandleRequest(ctx context.Context, req *core.Reques
 	}
 	t.mu.RUnlock()
 
+	if t.scheduler != nil {
+		release, err := t.scheduler.Acquire(ctx, req.EffectivePriority())
+		if errors.Is(err, ErrOverloaded) {
+			return OverloadedResponse(), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+
 	// The adapter handles all the virtual HTTP processing
 	return t.adapter.HandleRequest(req), nil
 }
 
+// SchedulerStats returns queue statistics per priority, or nil without
+// MaxConcurrentRequests.
+func (t *InProcessTransport) SchedulerStats() []PriorityStats {
+	if t.scheduler == nil {
+		return nil
+	}
+	return t.scheduler.Stats()
+}
+
 // OpenChannel creates a virtual WebSocket session via the Hub.
 func (t *InProcessTransport) OpenChannel(ctx context.Context, url string) (Channel, error) {
 	t.mu.RLock()
@@ -3,6 +3,7 @@ package transport
 import (
 	"context"
 	"sync"
+	"time"
 
 	ws "github.com/stukennedy/irgo/pkg/websocket"
 )
@@ -94,6 +95,47 @@ func (c *InProcessChannel) Get(key string) (any, bool) {
 	return c.session.Get(key)
 }
 
+// GetString retrieves string metadata.
+func (c *InProcessChannel) GetString(key string) string {
+	return c.session.GetString(key)
+}
+
+// GetInt retrieves int metadata.
+func (c *InProcessChannel) GetInt(key string) int {
+	return c.session.GetInt(key)
+}
+
+// GetInt64 retrieves int64 metadata.
+func (c *InProcessChannel) GetInt64(key string) int64 {
+	return c.session.GetInt64(key)
+}
+
+// GetBool retrieves bool metadata.
+func (c *InProcessChannel) GetBool(key string) bool {
+	return c.session.GetBool(key)
+}
+
+// GetTime retrieves time.Time metadata.
+func (c *InProcessChannel) GetTime(key string) time.Time {
+	return c.session.GetTime(key)
+}
+
+// GetOrSet returns the metadata for key, first storing factory's result
+// if there is none.
+func (c *InProcessChannel) GetOrSet(key string, factory func() any) any {
+	return c.session.GetOrSet(key, factory)
+}
+
+// Increment adds delta to the integer metadata for key.
+func (c *InProcessChannel) Increment(key string, delta int64) int64 {
+	return c.session.Increment(key, delta)
+}
+
+// MetadataSnapshot returns a copy of the channel's metadata.
+func (c *InProcessChannel) MetadataSnapshot() map[string]any {
+	return c.session.MetadataSnapshot()
+}
+
 // SendStream sends messages from a stream with backpressure handling.
 // Implements StreamingChannel interface.
 func (c *InProcessChannel) SendStream(ctx context.Context, stream <-chan *Message) error {
@@ -9,6 +9,7 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"slices"
 	"sync"
 	"time"
 
@@ -26,6 +27,7 @@ type LoopbackTransport struct {
 	server   *http.Server
 	config   *Config
 	upgrader websocket.Upgrader
+	secrets  *router.RotatingSecret // Set by Start
 
 	handlers       map[string]ChannelHandler
 	defaultHandler ChannelHandler
@@ -87,8 +89,8 @@ func (t *LoopbackTransport) HandleRequest(ctx context.Context, req *core.Request
 	}
 
 	// Add secret header
-	if t.config.Secret != "" {
-		httpReq.Header.Set("X-Irgo-Secret", t.config.Secret)
+	if secret := t.Secret(); secret != "" {
+		httpReq.Header.Set("X-Irgo-Secret", secret)
 	}
 
 	client := &http.Client{Timeout: 30 * time.Second}
@@ -129,13 +131,13 @@ func (t *LoopbackTransport) OpenChannel(ctx context.Context, url string) (Channe
 	t.mu.RUnlock()
 
 	wsURL := fmt.Sprintf("ws://%s:%d%s", t.config.Address, t.config.Port, url)
-	if t.config.Secret != "" {
-		wsURL += "?secret=" + t.config.Secret
-	}
 
 	dialer := websocket.Dialer{
 		HandshakeTimeout: 10 * time.Second,
 	}
+	if secret := t.Secret(); secret != "" {
+		dialer.Subprotocols = []string{router.SecretProtocol(secret)}
+	}
 
 	conn, _, err := dialer.DialContext(ctx, wsURL, nil)
 	if err != nil {
@@ -197,10 +199,16 @@ func (t *LoopbackTransport) Start() error {
 		t.config.Secret = secret
 	}
 
-	// Set allowed origins to include our own origin
+	grace := t.config.SecretGrace
+	if grace <= 0 {
+		grace = DefaultSecretGrace
+	}
+	t.secrets = router.NewRotatingSecret(t.config.Secret, grace)
+
+	// Always allow our own origin, plus any configured extras
 	origin := fmt.Sprintf("http://%s:%d", t.config.Address, t.config.Port)
-	if len(t.config.AllowedOrigins) == 0 {
-		t.config.AllowedOrigins = []string{origin}
+	if !slices.Contains(t.config.AllowedOrigins, origin) {
+		t.config.AllowedOrigins = append([]string{origin}, t.config.AllowedOrigins...)
 	}
 
 	// Wrap handler with security middleware
@@ -210,8 +218,11 @@ func (t *LoopbackTransport) Start() error {
 	handler = t.wrapWithWebSocketHandler(handler)
 
 	// Security middleware (applied in reverse order)
-	handler = router.WebSocketSecretMiddleware(t.config.Secret)(handler)
-	handler = router.SecretValidationMiddleware(t.config.Secret, []string{"/static/", "/api/"})(handler)
+	handler = router.WebSocketSecretProviderMiddleware(t.secrets)(handler)
+	handler = router.SecretValidationMiddlewareWithOptions(router.SecretValidationOptions{
+		Provider:     t.secrets,
+		ExcludePaths: []string{"/static/", "/api/"},
+	})(handler)
 	handler = router.StrictOriginMiddleware(t.config.AllowedOrigins...)(handler)
 	handler = router.CORSMiddleware(t.config.AllowedOrigins...)(handler)
 
@@ -262,11 +273,41 @@ func (t *LoopbackTransport) Stop(ctx context.Context) error {
 	return nil
 }
 
-// Config returns the transport configuration.
+// Config returns the transport configuration. Its Secret is the one the
+// transport started with; use Secret for the current one.
 func (t *LoopbackTransport) Config() *Config {
 	return t.config
 }
 
+// Secret returns the current authentication secret, which changes on
+// Rotate.
+func (t *LoopbackTransport) Secret() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.secrets == nil {
+		return t.config.Secret
+	}
+	return t.secrets.Current()
+}
+
+// Rotate replaces the authentication secret with a new random one and
+// returns it. Requests using the old secret are still accepted for
+// Config.SecretGrace, so the webview can pick up the new one.
+func (t *LoopbackTransport) Rotate() (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.running {
+		return "", ErrTransportClosed
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return "", fmt.Errorf("generating secret: %w", err)
+	}
+	t.secrets.Rotate(secret)
+	return secret, nil
+}
+
 // wrapWithWebSocketHandler adds WebSocket upgrade handling to the handler chain.
 func (t *LoopbackTransport) wrapWithWebSocketHandler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -277,7 +318,7 @@ func (t *LoopbackTransport) wrapWithWebSocketHandler(next http.Handler) http.Han
 		}
 
 		// Upgrade to WebSocket
-		conn, err := t.upgrader.Upgrade(w, r, nil)
+		conn, err := t.upgrader.Upgrade(w, r, router.WebSocketResponseHeader(r))
 		if err != nil {
 			return
 		}
@@ -288,6 +329,10 @@ func (t *LoopbackTransport) wrapWithWebSocketHandler(next http.Handler) http.Han
 			conn.Close()
 			return
 		}
+		// Clients that can take binary frames connect with ?frames=binary
+		if r.URL.Query().Get("frames") == "binary" {
+			session.SetBinaryFrames(true)
+		}
 
 		// Start goroutines for reading/writing
 		go t.wsWriter(conn, session)
@@ -299,14 +344,24 @@ func (t *LoopbackTransport) wsWriter(conn *websocket.Conn, session *ws.Session)
 	defer conn.Close()
 
 	for envelope := range session.SendChan {
-		data, err := envelope.JSON()
+		kind, encode := websocket.TextMessage, envelope.JSON
+		if envelope.Binary != nil && session.BinaryFrames() {
+			kind, encode = websocket.BinaryMessage, envelope.Frame
+		}
+		data, err := encode()
 		if err != nil {
 			continue
 		}
-		if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+		if err := conn.WriteMessage(kind, data); err != nil {
 			return
 		}
+		t.wsHub.MarkDelivered(session.ID, envelope)
 	}
+
+	// Session closed by the server
+	reason := session.CloseReason()
+	conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(reason.Code, reason.Text), time.Now().Add(time.Second))
 }
 
 func (t *LoopbackTransport) wsReader(conn *websocket.Conn, session *ws.Session) {
@@ -421,6 +476,26 @@ func (a *sessionChannelAdapter) Get(key string) (any, bool) {
 	return a.session.Get(key)
 }
 
+func (a *sessionChannelAdapter) GetString(key string) string { return a.session.GetString(key) }
+func (a *sessionChannelAdapter) GetInt(key string) int       { return a.session.GetInt(key) }
+func (a *sessionChannelAdapter) GetInt64(key string) int64   { return a.session.GetInt64(key) }
+func (a *sessionChannelAdapter) GetBool(key string) bool     { return a.session.GetBool(key) }
+func (a *sessionChannelAdapter) GetTime(key string) time.Time {
+	return a.session.GetTime(key)
+}
+
+func (a *sessionChannelAdapter) GetOrSet(key string, factory func() any) any {
+	return a.session.GetOrSet(key, factory)
+}
+
+func (a *sessionChannelAdapter) Increment(key string, delta int64) int64 {
+	return a.session.Increment(key, delta)
+}
+
+func (a *sessionChannelAdapter) MetadataSnapshot() map[string]any {
+	return a.session.MetadataSnapshot()
+}
+
 // Conversion helpers
 
 func wsRequestToMessage(req *ws.Request) *Message {
@@ -2,7 +2,9 @@ package transport
 
 import (
 	"context"
+	"maps"
 	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
@@ -114,6 +116,78 @@ func (c *LoopbackChannel) Get(key string) (any, bool) {
 	return v, ok
 }
 
+// GetString retrieves string metadata.
+func (c *LoopbackChannel) GetString(key string) string {
+	v, _ := c.Get(key)
+	s, _ := v.(string)
+	return s
+}
+
+// GetInt retrieves int metadata.
+func (c *LoopbackChannel) GetInt(key string) int {
+	v, _ := c.Get(key)
+	i, _ := v.(int)
+	return i
+}
+
+// GetInt64 retrieves int64 metadata.
+func (c *LoopbackChannel) GetInt64(key string) int64 {
+	v, _ := c.Get(key)
+	i, _ := v.(int64)
+	return i
+}
+
+// GetBool retrieves bool metadata.
+func (c *LoopbackChannel) GetBool(key string) bool {
+	v, _ := c.Get(key)
+	b, _ := v.(bool)
+	return b
+}
+
+// GetTime retrieves time.Time metadata.
+func (c *LoopbackChannel) GetTime(key string) time.Time {
+	v, _ := c.Get(key)
+	t, _ := v.(time.Time)
+	return t
+}
+
+// GetOrSet returns the metadata for key, first storing factory's result
+// if there is none. factory runs with the metadata locked.
+func (c *LoopbackChannel) GetOrSet(key string, factory func() any) any {
+	c.metadataMu.Lock()
+	defer c.metadataMu.Unlock()
+	if v, ok := c.metadata[key]; ok {
+		return v
+	}
+	v := factory()
+	c.metadata[key] = v
+	return v
+}
+
+// Increment adds delta to the integer metadata for key, stored as an
+// int64. A missing or non-integer value counts as 0.
+func (c *LoopbackChannel) Increment(key string, delta int64) int64 {
+	c.metadataMu.Lock()
+	defer c.metadataMu.Unlock()
+	var n int64
+	switch v := c.metadata[key].(type) {
+	case int64:
+		n = v
+	case int:
+		n = int64(v)
+	}
+	n += delta
+	c.metadata[key] = n
+	return n
+}
+
+// MetadataSnapshot returns a copy of the channel's metadata.
+func (c *LoopbackChannel) MetadataSnapshot() map[string]any {
+	c.metadataMu.RLock()
+	defer c.metadataMu.RUnlock()
+	return maps.Clone(c.metadata)
+}
+
 // SendStream sends messages from a stream with backpressure handling.
 func (c *LoopbackChannel) SendStream(ctx context.Context, stream <-chan *Message) error {
 	for {
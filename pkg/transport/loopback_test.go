@@ -0,0 +1,46 @@
+package transport
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+
+	ws "github.com/stukennedy/irgo/pkg/websocket"
+)
+
+func TestLoopbackRotate(t *testing.T) {
+	lt := NewLoopbackTransport(http.NotFoundHandler(), ws.NewHub(), WithSecret("launch"))
+	if err := lt.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer lt.Stop(context.Background())
+
+	// Rotating alongside readers of the config must not race
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				_ = lt.Config().Secret
+				_ = lt.Secret()
+			}
+		}()
+	}
+	var secret string
+	for range 5 {
+		var err error
+		if secret, err = lt.Rotate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wg.Wait()
+
+	if got := lt.Secret(); got != secret {
+		t.Errorf("Secret = %q, want the rotated %q", got, secret)
+	}
+	if got := lt.Config().Secret; got != "launch" {
+		t.Errorf("Config().Secret = %q, want the launch secret", got)
+	}
+}
@@ -0,0 +1,218 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/stukennedy/irgo/pkg/core"
+)
+
+// ErrOverloaded is returned by Scheduler.Acquire when a request can't be
+// queued, or is evicted from the queue by a more urgent one.
+var ErrOverloaded = errors.New("transport: too many requests")
+
+// DefaultAging is how long a queued request waits before it is treated
+// as one priority level more urgent, so background work can't starve.
+const DefaultAging = 500 * time.Millisecond
+
+// queueFactor sizes a Scheduler's wait queue relative to its limit.
+const queueFactor = 4
+
+// Scheduler limits concurrent requests, running queued ones strictly by
+// priority (see core.Request.EffectivePriority) with aging. When the wait
+// queue is full, the newest, least urgent waiter is rejected with
+// ErrOverloaded, so background requests are the first casualties.
+type Scheduler struct {
+	limit    int
+	maxQueue int
+	aging    time.Duration
+	now      func() time.Time
+
+	mu     sync.Mutex
+	active int
+	queue  []*waiter // Arrival order
+	stats  [core.PriorityBackground + 1]PriorityStats
+}
+
+type waiter struct {
+	priority int
+	enqueued time.Time
+	ready    chan error // Receives nil when the request may run
+}
+
+// PriorityStats summarises scheduling for one priority.
+type PriorityStats struct {
+	Priority  string        `json:"priority"` // See core.PriorityName
+	Waiting   int           `json:"waiting"`
+	Served    int64         `json:"served"`
+	Rejected  int64         `json:"rejected"`
+	TotalWait time.Duration `json:"total_wait_ns"`
+	MaxWait   time.Duration `json:"max_wait_ns"`
+}
+
+// MeanWait returns the average queue wait of served requests.
+func (s PriorityStats) MeanWait() time.Duration {
+	if s.Served == 0 {
+		return 0
+	}
+	return s.TotalWait / time.Duration(s.Served)
+}
+
+// NewScheduler returns a Scheduler running at most limit requests at once
+// and queueing up to four times as many.
+func NewScheduler(limit int) *Scheduler {
+	if limit < 1 {
+		limit = 1
+	}
+	s := &Scheduler{limit: limit, maxQueue: queueFactor * limit, aging: DefaultAging, now: time.Now}
+	for p := core.PriorityInteractive; p <= core.PriorityBackground; p++ {
+		s.stats[p].Priority = core.PriorityName(p)
+	}
+	return s
+}
+
+// Acquire waits for a slot to run a request of the given priority. The
+// caller must call release when the request is done.
+func (s *Scheduler) Acquire(ctx context.Context, priority int) (release func(), err error) {
+	if priority < core.PriorityInteractive || priority > core.PriorityBackground {
+		priority = core.PriorityNormal
+	}
+
+	s.mu.Lock()
+	if s.active < s.limit {
+		s.active++
+		s.served(priority, 0)
+		s.mu.Unlock()
+		return sync.OnceFunc(s.release), nil
+	}
+	if len(s.queue) >= s.maxQueue && !s.evict(priority) {
+		s.stats[priority].Rejected++
+		s.mu.Unlock()
+		return nil, ErrOverloaded
+	}
+	w := &waiter{priority: priority, enqueued: s.now(), ready: make(chan error, 1)}
+	s.queue = append(s.queue, w)
+	s.mu.Unlock()
+
+	select {
+	case err := <-w.ready:
+		if err != nil {
+			return nil, err
+		}
+		return sync.OnceFunc(s.release), nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		if i := s.index(w); i >= 0 {
+			s.remove(i)
+			s.mu.Unlock()
+			return nil, ctx.Err()
+		}
+		s.mu.Unlock()
+		// Granted or evicted while cancelling
+		if err := <-w.ready; err == nil {
+			s.release()
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// release hands the slot to the next waiter, or frees it.
+func (s *Scheduler) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.next()
+	if i < 0 {
+		s.active--
+		return
+	}
+	w := s.queue[i]
+	s.remove(i)
+	s.served(w.priority, s.now().Sub(w.enqueued))
+	w.ready <- nil
+}
+
+// next returns the index of the waiter to run: the most urgent once aged,
+// oldest first; or -1 if none is waiting.
+func (s *Scheduler) next() int {
+	now := s.now()
+	best, bestRank := -1, 0
+	for i, w := range s.queue {
+		rank := w.priority - int(now.Sub(w.enqueued)/s.aging)
+		if best < 0 || rank < bestRank {
+			best, bestRank = i, rank
+		}
+	}
+	return best
+}
+
+// evict rejects the newest waiter less urgent than priority, choosing the
+// least urgent first, and reports whether there was one.
+func (s *Scheduler) evict(priority int) bool {
+	victim := -1
+	for i, w := range s.queue {
+		if w.priority > priority && (victim < 0 || w.priority >= s.queue[victim].priority) {
+			victim = i
+		}
+	}
+	if victim < 0 {
+		return false
+	}
+	w := s.queue[victim]
+	s.remove(victim)
+	s.stats[w.priority].Rejected++
+	w.ready <- ErrOverloaded
+	return true
+}
+
+func (s *Scheduler) index(w *waiter) int {
+	for i, q := range s.queue {
+		if q == w {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *Scheduler) remove(i int) {
+	copy(s.queue[i:], s.queue[i+1:])
+	s.queue[len(s.queue)-1] = nil
+	s.queue = s.queue[:len(s.queue)-1]
+}
+
+func (s *Scheduler) served(priority int, wait time.Duration) {
+	st := &s.stats[priority]
+	st.Served++
+	st.TotalWait += wait
+	if wait > st.MaxWait {
+		st.MaxWait = wait
+	}
+}
+
+// Stats returns scheduling statistics for each priority, most urgent
+// first.
+func (s *Scheduler) Stats() []PriorityStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := make([]PriorityStats, 0, core.PriorityBackground)
+	for p := core.PriorityInteractive; p <= core.PriorityBackground; p++ {
+		st := s.stats[p]
+		for _, w := range s.queue {
+			if w.priority == p {
+				st.Waiting++
+			}
+		}
+		stats = append(stats, st)
+	}
+	return stats
+}
+
+// OverloadedResponse is the response to a request rejected with
+// ErrOverloaded: a 503 with Retry-After, so clients and offline queues
+// retry it later.
+func OverloadedResponse() *core.Response {
+	resp := core.ErrorResponse(503, "Too many requests, try again shortly")
+	resp.SetHeader("Retry-After", "1")
+	return resp
+}
@@ -0,0 +1,194 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stukennedy/irgo/pkg/core"
+)
+
+// waitQueued waits until n requests are queued in s.
+func waitQueued(t *testing.T, s *Scheduler, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		total := 0
+		for _, st := range s.Stats() {
+			total += st.Waiting
+		}
+		if total == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d queued requests", n)
+}
+
+func TestInteractivePreemptsBackground(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		order   []string
+		block   = make(chan struct{})
+		started = make(chan struct{})
+	)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/busy" {
+			close(started)
+			<-block
+		}
+		mu.Lock()
+		order = append(order, r.URL.Path)
+		mu.Unlock()
+	})
+	tr := NewInProcessTransport(handler, nil, WithMaxConcurrentRequests(1))
+	tr.Start()
+	defer tr.Stop(context.Background())
+
+	var wg sync.WaitGroup
+	send := func(path string, priority int) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := core.NewRequest("GET", path)
+			req.Priority = priority
+			tr.HandleRequest(context.Background(), req)
+		}()
+	}
+
+	send("/busy", core.PriorityNormal)
+	<-started
+	for i := 0; i < 3; i++ {
+		send("/poll", core.PriorityBackground)
+	}
+	waitQueued(t, tr.scheduler, 3)
+	req := core.NewRequest("POST", "/tap")
+	req.SetHeader("Datastar-Request", "true")
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		tr.HandleRequest(context.Background(), req)
+	}()
+	waitQueued(t, tr.scheduler, 4)
+
+	close(block)
+	wg.Wait()
+	if len(order) != 5 || order[0] != "/busy" || order[1] != "/tap" {
+		t.Errorf("expected the tap to run next, got %v", order)
+	}
+
+	stats := tr.SchedulerStats()
+	if stats[0].Priority != "interactive" || stats[0].Served != 1 || stats[2].Served != 3 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	if stats[2].MaxWait < stats[0].MaxWait {
+		t.Errorf("expected background to wait longest, got %+v", stats)
+	}
+}
+
+func TestSchedulerRejectsBackgroundFirst(t *testing.T) {
+	s := NewScheduler(1)
+	release, _ := s.Acquire(context.Background(), core.PriorityNormal)
+
+	results := make(chan error, 10)
+	for i := 0; i < 4; i++ {
+		go func() {
+			r, err := s.Acquire(context.Background(), core.PriorityBackground)
+			if err == nil {
+				r()
+			}
+			results <- err
+		}()
+	}
+	waitQueued(t, s, 4)
+
+	// The queue is full: a normal request evicts a background one, and
+	// another background request is turned away
+	go func() {
+		r, err := s.Acquire(context.Background(), core.PriorityNormal)
+		if err == nil {
+			r()
+		}
+		results <- err
+	}()
+	if err := <-results; !errors.Is(err, ErrOverloaded) {
+		t.Fatalf("expected a background request evicted, got %v", err)
+	}
+	if _, err := s.Acquire(context.Background(), core.PriorityBackground); !errors.Is(err, ErrOverloaded) {
+		t.Errorf("expected ErrOverloaded, got %v", err)
+	}
+
+	release()
+	for i := 0; i < 4; i++ {
+		if err := <-results; err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+	}
+	if st := s.Stats()[2]; st.Rejected != 2 || st.Served != 3 {
+		t.Errorf("unexpected background stats %+v", st)
+	}
+}
+
+func TestSchedulerAging(t *testing.T) {
+	s := NewScheduler(1)
+	now := time.Now()
+	s.now = func() time.Time { return now }
+	release, _ := s.Acquire(context.Background(), core.PriorityNormal)
+
+	done := make(chan string, 2)
+	acquire := func(name string, priority int) {
+		r, err := s.Acquire(context.Background(), priority)
+		if err == nil {
+			done <- name
+			r()
+		}
+	}
+	go acquire("background", core.PriorityBackground)
+	waitQueued(t, s, 1)
+
+	// After waiting two aging periods the background request ranks above
+	// a fresh normal one
+	now = now.Add(2*DefaultAging + time.Millisecond)
+	go acquire("normal", core.PriorityNormal)
+	waitQueued(t, s, 2)
+
+	release()
+	if first := <-done; first != "background" {
+		t.Errorf("expected the aged request first, got %s", first)
+	}
+	<-done
+}
+
+func TestSchedulerCancel(t *testing.T) {
+	s := NewScheduler(1)
+	release, _ := s.Acquire(context.Background(), core.PriorityNormal)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error)
+	go func() {
+		_, err := s.Acquire(ctx, core.PriorityNormal)
+		errc <- err
+	}()
+	waitQueued(t, s, 1)
+	cancel()
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+
+	release()
+	r, err := s.Acquire(context.Background(), core.PriorityNormal)
+	if err != nil {
+		t.Fatalf("slot not freed: %v", err)
+	}
+	r()
+}
+
+func TestOverloadedResponse(t *testing.T) {
+	resp := OverloadedResponse()
+	if resp.Status != http.StatusServiceUnavailable || resp.GetHeader("Retry-After") == "" {
+		t.Errorf("expected a retryable 503, got %d %v", resp.Status, resp.GetHeaders())
+	}
+}
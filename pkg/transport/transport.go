@@ -6,6 +6,7 @@ package transport
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/stukennedy/irgo/pkg/core"
 )
@@ -55,11 +56,16 @@ type Transport interface {
 	Config() *Config
 }
 
+// DefaultSecretGrace is how long the previous secret is accepted after
+// LoopbackTransport.Rotate.
+const DefaultSecretGrace = 30 * time.Second
+
 // Config holds transport configuration.
 type Config struct {
 	// Security settings (LoopbackTransport only)
-	Secret         string   // Per-launch authentication secret
-	AllowedOrigins []string // Origins allowed for CORS/security
+	Secret         string        // Per-launch authentication secret, as at launch (see LoopbackTransport.Secret)
+	SecretGrace    time.Duration // How long a rotated secret stays valid (default: DefaultSecretGrace)
+	AllowedOrigins []string      // Origins allowed for CORS/security
 
 	// Server settings (LoopbackTransport only)
 	Port    int    // Port number (0 for auto-select)
@@ -67,6 +73,9 @@ type Config struct {
 
 	// Channel settings
 	ChannelBufferSize int // Buffer size for channel messages (default: 100)
+
+	// Request scheduling (InProcessTransport only); see Scheduler
+	MaxConcurrentRequests int // 0 = unlimited
 }
 
 // DefaultConfig returns a Config with sensible defaults.
@@ -94,6 +103,14 @@ func WithSecret(secret string) Option {
 	}
 }
 
+// WithSecretGrace sets how long the previous secret is accepted after a
+// rotation (LoopbackTransport only).
+func WithSecretGrace(d time.Duration) Option {
+	return func(c *Config) {
+		c.SecretGrace = d
+	}
+}
+
 // WithAllowedOrigins sets the allowed origins for CORS/security.
 func WithAllowedOrigins(origins ...string) Option {
 	return func(c *Config) {
@@ -101,6 +118,14 @@ func WithAllowedOrigins(origins ...string) Option {
 	}
 }
 
+// WithMaxConcurrentRequests caps concurrent requests, queueing the rest
+// by priority (InProcessTransport only).
+func WithMaxConcurrentRequests(n int) Option {
+	return func(c *Config) {
+		c.MaxConcurrentRequests = n
+	}
+}
+
 // WithChannelBufferSize sets the channel message buffer size.
 func WithChannelBufferSize(size int) Option {
 	return func(c *Config) {
@@ -0,0 +1,127 @@
+// Package viewport carries device viewport hints (CSS size and device pixel
+// ratio) from the client to handlers, templates and the image endpoint.
+//
+// Clients report hints in the X-Irgo-Viewport header ("390x844@3") or the
+// "viewport" Datastar signal ({"width":390,"height":844,"dpr":3});
+// router.ViewportMiddleware parses them into the request context.
+package viewport
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Header is the request header carrying viewport hints.
+const Header = "X-Irgo-Viewport"
+
+// Signal is the Datastar signal carrying viewport hints.
+const Signal = "viewport"
+
+// Bounds applied to client-reported values.
+const (
+	MinSize = 200
+	MaxSize = 8192
+	MinDPR  = 1.0
+	MaxDPR  = 4.0
+)
+
+// CompactWidth is the CSS width below which a viewport is compact.
+const CompactWidth = 768
+
+// Viewport describes the client's viewport in CSS pixels.
+type Viewport struct {
+	Width  int     `json:"width"`
+	Height int     `json:"height"`
+	DPR    float64 `json:"dpr"`
+
+	// Reported is true if the client sent hints, false for a fallback profile.
+	Reported bool `json:"-"`
+}
+
+// Default profiles for requests without hints.
+var (
+	Phone   = Viewport{Width: 390, Height: 844, DPR: 3}
+	Tablet  = Viewport{Width: 820, Height: 1180, DPR: 2}
+	Desktop = Viewport{Width: 1280, Height: 800, DPR: 1}
+)
+
+// IsCompact reports whether the viewport is phone-sized.
+func (v Viewport) IsCompact() bool {
+	return v.Width < CompactWidth
+}
+
+// String formats the viewport in header form, e.g. "390x844@3".
+func (v Viewport) String() string {
+	return fmt.Sprintf("%dx%d@%s", v.Width, v.Height, strconv.FormatFloat(v.DPR, 'f', -1, 64))
+}
+
+// Clamp limits values to sane bounds. A missing (zero) DPR becomes 1.
+func (v Viewport) Clamp() Viewport {
+	v.Width = clampInt(v.Width, MinSize, MaxSize)
+	v.Height = clampInt(v.Height, MinSize, MaxSize)
+	if math.IsNaN(v.DPR) || v.DPR < MinDPR {
+		v.DPR = MinDPR
+	} else if v.DPR > MaxDPR {
+		v.DPR = MaxDPR
+	}
+	return v
+}
+
+// Parse reads a header value like "390x844@3" or "390x844" and clamps it.
+func Parse(s string) (Viewport, error) {
+	size, dpr, hasDPR := strings.Cut(strings.TrimSpace(s), "@")
+	ws, hs, ok := strings.Cut(size, "x")
+	if !ok {
+		return Viewport{}, fmt.Errorf("viewport: invalid value %q", s)
+	}
+
+	var v Viewport
+	var err error
+	if v.Width, err = strconv.Atoi(ws); err != nil {
+		return Viewport{}, fmt.Errorf("viewport: invalid width %q", ws)
+	}
+	if v.Height, err = strconv.Atoi(hs); err != nil {
+		return Viewport{}, fmt.Errorf("viewport: invalid height %q", hs)
+	}
+	if hasDPR {
+		if v.DPR, err = strconv.ParseFloat(dpr, 64); err != nil {
+			return Viewport{}, fmt.Errorf("viewport: invalid dpr %q", dpr)
+		}
+	}
+	v = v.Clamp()
+	v.Reported = true
+	return v, nil
+}
+
+type contextKey struct{}
+
+// WithContext returns a context carrying v.
+func WithContext(ctx context.Context, v Viewport) context.Context {
+	return context.WithValue(ctx, contextKey{}, v)
+}
+
+// FromContext returns the viewport stored in ctx, or Desktop and false if
+// there is none.
+func FromContext(ctx context.Context) (Viewport, bool) {
+	if ctx == nil {
+		return Desktop, false
+	}
+	v, ok := ctx.Value(contextKey{}).(Viewport)
+	if !ok {
+		return Desktop, false
+	}
+	return v, true
+}
+
+func clampInt(n, lo, hi int) int {
+	if n < lo {
+		return lo
+	}
+	if n > hi {
+		return hi
+	}
+	return n
+}
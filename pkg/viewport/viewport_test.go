@@ -0,0 +1,56 @@
+package viewport
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Viewport
+	}{
+		{"390x844@3", Viewport{Width: 390, Height: 844, DPR: 3, Reported: true}},
+		{" 1280x800 ", Viewport{Width: 1280, Height: 800, DPR: 1, Reported: true}},
+		{"390x844@2.5", Viewport{Width: 390, Height: 844, DPR: 2.5, Reported: true}},
+		{"1x1@0", Viewport{Width: MinSize, Height: MinSize, DPR: MinDPR, Reported: true}},
+		{"99999x-5@50", Viewport{Width: MaxSize, Height: MinSize, DPR: MaxDPR, Reported: true}},
+		{"390x844@NaN", Viewport{Width: 390, Height: 844, DPR: MinDPR, Reported: true}},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+
+	for _, in := range []string{"", "390", "axb", "390x844@x"} {
+		if _, err := Parse(in); err == nil {
+			t.Errorf("Parse(%q): expected error", in)
+		}
+	}
+}
+
+func TestIsCompact(t *testing.T) {
+	if !Phone.IsCompact() {
+		t.Error("expected phone to be compact")
+	}
+	if Tablet.IsCompact() || Desktop.IsCompact() {
+		t.Error("expected tablet and desktop not to be compact")
+	}
+}
+
+func TestContext(t *testing.T) {
+	v, ok := FromContext(context.Background())
+	if ok || v != Desktop {
+		t.Errorf("expected Desktop default, got %+v %v", v, ok)
+	}
+	v, ok = FromContext(WithContext(context.Background(), Phone))
+	if !ok || v != Phone {
+		t.Errorf("expected Phone, got %+v %v", v, ok)
+	}
+}
@@ -0,0 +1,245 @@
+// Package watchdog detects stalled request handlers and WebSocket send
+// queues that have stopped draining, which otherwise show up only as a
+// frozen UI.
+//
+// A Watchdog samples in-flight requests and queue depths periodically.
+// When one crosses its threshold it writes a goroutine dump, with the
+// offending request or session, as a crash report and calls OnStall:
+//
+//	w := watchdog.New(watchdog.Config{
+//	    Requests: func() []watchdog.Request { ... },
+//	    Queues:   func() []watchdog.Queue { ... },
+//	    OnStall:  func(s watchdog.Stall) { log.Print(s) },
+//	})
+//	go w.Run(ctx)
+//
+// Dumps are rate limited by Config.DumpInterval, so a stall that persists
+// is reported once.
+package watchdog
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/stukennedy/irgo/pkg/crash"
+)
+
+// Defaults for zero Config fields.
+const (
+	DefaultInterval       = time.Second
+	DefaultRequestTimeout = 10 * time.Second
+	DefaultQueueDepth     = 80 // Of the 100 envelopes a session buffers
+	DefaultDumpInterval   = 5 * time.Minute
+)
+
+// maxDumpSize caps the goroutine dump.
+const maxDumpSize = 8 << 20
+
+// Request is a sample of a request being served.
+type Request struct {
+	Method  string
+	URL     string
+	Started time.Time
+}
+
+// Queue is a sample of a session's unread envelopes.
+type Queue struct {
+	SessionID string
+	URL       string
+	Depth     int
+}
+
+// Stall describes what tripped the watchdog. Exactly one of Request and
+// Queue is set.
+type Stall struct {
+	Request  *Request
+	Queue    *Queue
+	Age      time.Duration // How long Request has been running
+	ReportID string        // Crash report with the goroutine dump, if written
+}
+
+func (s Stall) String() string {
+	if s.Request != nil {
+		return fmt.Sprintf("request %s %s running for %s", s.Request.Method, s.Request.URL, s.Age.Round(time.Millisecond))
+	}
+	return fmt.Sprintf("session %s (%s) has %d unread envelopes", s.Queue.SessionID, s.Queue.URL, s.Queue.Depth)
+}
+
+// Config configures a Watchdog. Zero durations and depths use the
+// defaults.
+type Config struct {
+	Interval       time.Duration // Sampling period
+	RequestTimeout time.Duration // A request running longer has stalled
+	QueueDepth     int           // A queue this deep has stalled
+	DumpInterval   time.Duration // Minimum time between dumps
+
+	// Requests and Queues sample the app; either may be nil.
+	Requests func() []Request
+	Queues   func() []Queue
+
+	// Reporter receives dumps; nil uses crash.Default, and if that is
+	// nil too the stall is only logged.
+	Reporter *crash.Reporter
+
+	// OnStall is called after each dump, on the sampling goroutine.
+	OnStall func(Stall)
+}
+
+// Watchdog samples an app for stalls.
+type Watchdog struct {
+	cfg Config
+	now func() time.Time
+
+	mu       sync.Mutex
+	lastDump time.Time // Zero until the first dump
+}
+
+// New returns a Watchdog; start it with Run.
+func New(cfg Config) *Watchdog {
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultInterval
+	}
+	if cfg.RequestTimeout <= 0 {
+		cfg.RequestTimeout = DefaultRequestTimeout
+	}
+	if cfg.QueueDepth <= 0 {
+		cfg.QueueDepth = DefaultQueueDepth
+	}
+	if cfg.DumpInterval <= 0 {
+		cfg.DumpInterval = DefaultDumpInterval
+	}
+	return &Watchdog{cfg: cfg, now: time.Now}
+}
+
+// Run samples every Config.Interval until ctx is cancelled.
+func (w *Watchdog) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.Check()
+		}
+	}
+}
+
+// Check samples once, dumping and reporting the worst stall unless a dump
+// was written within Config.DumpInterval. It reports whether it dumped.
+func (w *Watchdog) Check() bool {
+	stall, ok := w.sample()
+	if !ok {
+		return false
+	}
+
+	w.mu.Lock()
+	now := w.now()
+	if !w.lastDump.IsZero() && now.Sub(w.lastDump) < w.cfg.DumpInterval {
+		w.mu.Unlock()
+		return false
+	}
+	w.lastDump = now
+	w.mu.Unlock()
+
+	if stall.Request != nil {
+		stall.Request.URL = redactURL(stall.Request.URL)
+	} else {
+		stall.Queue.URL = redactURL(stall.Queue.URL)
+	}
+	log.Printf("watchdog: %s", stall)
+	stall.ReportID = w.dump(stall)
+	if w.cfg.OnStall != nil {
+		w.cfg.OnStall(stall)
+	}
+	return true
+}
+
+// sample returns the longest-running stalled request, else the deepest
+// stalled queue.
+func (w *Watchdog) sample() (Stall, bool) {
+	var stall Stall
+	if w.cfg.Requests != nil {
+		now := w.now()
+		for _, r := range w.cfg.Requests() {
+			if age := now.Sub(r.Started); age >= w.cfg.RequestTimeout && age > stall.Age {
+				stall = Stall{Request: &r, Age: age}
+			}
+		}
+		if stall.Request != nil {
+			return stall, true
+		}
+	}
+	if w.cfg.Queues != nil {
+		for _, q := range w.cfg.Queues() {
+			if q.Depth >= w.cfg.QueueDepth && (stall.Queue == nil || q.Depth > stall.Queue.Depth) {
+				stall.Queue = &q
+			}
+		}
+	}
+	return stall, stall.Queue != nil
+}
+
+// dump writes a crash report holding every goroutine's stack, returning
+// its ID, or "" if there is no reporter or writing fails.
+func (w *Watchdog) dump(stall Stall) string {
+	rep := w.cfg.Reporter
+	if rep == nil {
+		rep = crash.Default()
+	}
+	if rep == nil {
+		return ""
+	}
+
+	report := crash.Report{
+		Source: crash.SourceWatchdog,
+		Panic:  "stall: " + stall.String(),
+		Stack:  string(allStacks()),
+	}
+	if r := stall.Request; r != nil {
+		report.Request = &crash.Request{Method: r.Method, URL: r.URL}
+	} else {
+		report.Message = &crash.Message{SessionID: stall.Queue.SessionID, URL: stall.Queue.URL}
+	}
+	id, err := rep.Capture(report)
+	if err != nil {
+		log.Printf("watchdog: writing dump: %v", err)
+		return ""
+	}
+	return id
+}
+
+// allStacks returns the stacks of all goroutines, truncated at
+// maxDumpSize.
+func allStacks() []byte {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) || len(buf) >= maxDumpSize {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// redactURL redacts secret-looking query parameters, as crash reports do
+// for requests.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.RawQuery == "" {
+		return raw
+	}
+	q := u.Query()
+	for name, values := range q {
+		for i := range values {
+			values[i] = crash.RedactValue(name, values[i])
+		}
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
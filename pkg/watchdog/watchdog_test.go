@@ -0,0 +1,121 @@
+package watchdog
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stukennedy/irgo/pkg/crash"
+)
+
+// clock is a fake time source for the watchdog.
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time          { return c.t }
+func (c *clock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestWatchdog(t *testing.T, cfg Config) (*Watchdog, *clock, *crash.Reporter) {
+	t.Helper()
+	rep, err := crash.NewReporter(t.TempDir(), "1.0.0", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Reporter = rep
+	w := New(cfg)
+	c := &clock{t: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}
+	w.now = c.now
+	return w, c, rep
+}
+
+func TestStuckHandler(t *testing.T) {
+	var started time.Time
+	var stalls []Stall
+	w, c, rep := newTestWatchdog(t, Config{
+		RequestTimeout: 5 * time.Second,
+		DumpInterval:   time.Minute,
+		Requests: func() []Request {
+			return []Request{
+				{Method: "GET", URL: "/stuck?token=s3cret", Started: started},
+				{Method: "GET", URL: "/fresh", Started: started.Add(4 * time.Second)},
+			}
+		},
+		OnStall: func(s Stall) { stalls = append(stalls, s) },
+	})
+	started = c.t
+
+	c.advance(4 * time.Second)
+	if w.Check() {
+		t.Fatal("dumped before the timeout")
+	}
+
+	c.advance(2 * time.Second)
+	if !w.Check() {
+		t.Fatal("expected a dump")
+	}
+	reports, _ := rep.Pending()
+	if len(reports) != 1 {
+		t.Fatalf("expected 1 report, got %d", len(reports))
+	}
+	r := reports[0]
+	if r.Source != crash.SourceWatchdog || r.Request == nil || r.Request.Method != "GET" {
+		t.Errorf("unexpected report %+v", r)
+	}
+	if !strings.HasPrefix(r.Request.URL, "/stuck?") || strings.Contains(r.Request.URL, "s3cret") {
+		t.Errorf("expected the stuck request, redacted, got %q", r.Request.URL)
+	}
+	if !strings.Contains(r.Stack, "goroutine ") || !strings.Contains(r.Stack, "TestStuckHandler") {
+		t.Error("expected a dump of all goroutines")
+	}
+	if len(stalls) != 1 || stalls[0].ReportID != r.ID || stalls[0].Age != 6*time.Second {
+		t.Errorf("unexpected stalls %+v", stalls)
+	}
+
+	// The stall persists but is within the rate limit
+	c.advance(30 * time.Second)
+	if w.Check() {
+		t.Error("expected repeat suppressed")
+	}
+	if reports, _ := rep.Pending(); len(reports) != 1 || len(stalls) != 1 {
+		t.Errorf("expected no new dump, got %d reports and %d stalls", len(reports), len(stalls))
+	}
+
+	c.advance(30 * time.Second)
+	if !w.Check() {
+		t.Error("expected a dump once the rate limit passed")
+	}
+}
+
+func TestStalledQueue(t *testing.T) {
+	var stalls []Stall
+	w, _, rep := newTestWatchdog(t, Config{
+		Queues: func() []Queue {
+			return []Queue{
+				{SessionID: "a", URL: "/ws/feed", Depth: 85},
+				{SessionID: "b", URL: "/ws/chat", Depth: 99},
+				{SessionID: "c", URL: "/ws/idle", Depth: 2},
+			}
+		},
+		OnStall: func(s Stall) { stalls = append(stalls, s) },
+	})
+
+	if !w.Check() {
+		t.Fatal("expected a dump")
+	}
+	if len(stalls) != 1 || stalls[0].Queue == nil || stalls[0].Queue.SessionID != "b" {
+		t.Fatalf("expected the deepest queue, got %+v", stalls)
+	}
+	reports, _ := rep.Pending()
+	if len(reports) != 1 || reports[0].Message == nil || reports[0].Message.SessionID != "b" {
+		t.Errorf("unexpected reports %+v", reports)
+	}
+}
+
+func TestNoStall(t *testing.T) {
+	w, c, _ := newTestWatchdog(t, Config{
+		Queues: func() []Queue { return []Queue{{SessionID: "a", Depth: 3}} },
+	})
+	w.cfg.Requests = func() []Request { return []Request{{URL: "/", Started: c.t}} }
+	if w.Check() {
+		t.Error("expected no dump")
+	}
+}
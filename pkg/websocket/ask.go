@@ -0,0 +1,79 @@
+package websocket
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+)
+
+// ResponseType is the Request.Type of a client's reply to Session.Request.
+// Replies carry the request_id of the envelope they answer; they are
+// never passed to the session's handler.
+const ResponseType = "response"
+
+// Request sends an envelope to the client and waits for its reply, e.g.
+// to read a device sensor through the bridge. The envelope is sent with
+// a new RequestID, and the client answers with a message of type
+// ResponseType and the same request_id. Request returns ctx.Err() if ctx
+// is done first, and ErrSessionClosed if the session closes; a reply
+// arriving after that is discarded.
+func (s *Session) Request(ctx context.Context, envelope *Envelope) (*Request, error) {
+	e := *envelope
+	e.RequestID = newRequestID()
+
+	reply := make(chan *Request, 1)
+	s.waitersMu.Lock()
+	if s.waiters == nil {
+		s.waiters = make(map[string]chan *Request)
+	}
+	s.waiters[e.RequestID] = reply
+	s.waitersMu.Unlock()
+	defer s.stopWaiting(e.RequestID)
+
+	if err := s.SendContext(ctx, &e); err != nil {
+		return nil, err
+	}
+	select {
+	case req := <-reply:
+		return req, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-s.done:
+		return nil, ErrSessionClosed
+	}
+}
+
+// Request sends an envelope to a session and waits for its reply; see
+// Session.Request.
+func (h *Hub) Request(ctx context.Context, sessionID string, envelope *Envelope) (*Request, error) {
+	session, ok := h.GetSession(sessionID)
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	return session.Request(ctx, envelope)
+}
+
+// deliverResponse hands a reply to the Request waiting for it. Replies
+// nobody waits for any more are dropped.
+func (s *Session) deliverResponse(req *Request) {
+	s.waitersMu.Lock()
+	reply, ok := s.waiters[req.RequestID]
+	delete(s.waiters, req.RequestID)
+	s.waitersMu.Unlock()
+	if ok {
+		reply <- req // Buffered, and only sent once
+	}
+}
+
+func (s *Session) stopWaiting(requestID string) {
+	s.waitersMu.Lock()
+	delete(s.waiters, requestID)
+	s.waitersMu.Unlock()
+}
+
+// newRequestID returns a random ID for a server-sent request.
+func newRequestID() string {
+	var b [12]byte
+	rand.Read(b[:])
+	return "srv_" + base64.RawURLEncoding.EncodeToString(b[:])
+}
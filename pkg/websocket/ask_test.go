@@ -0,0 +1,171 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// reply answers envelope as the client bridge would.
+func reply(t *testing.T, hub *Hub, s *Session, envelope *Envelope, value string) {
+	t.Helper()
+	msg := fmt.Sprintf(`{"type":"response","request_id":%q,"values":{"value":%q}}`, envelope.RequestID, value)
+	if _, err := hub.HandleMessage(s.ID, []byte(msg)); err != nil {
+		t.Error(err)
+	}
+}
+
+func waiterCount(s *Session) int {
+	s.waitersMu.Lock()
+	defer s.waitersMu.Unlock()
+	return len(s.waiters)
+}
+
+func TestRequest(t *testing.T) {
+	var handled int
+	hub := NewHub()
+	hub.HandleFunc("/ws/", func(*Session, *Request) (*Envelope, error) {
+		handled++
+		return nil, nil
+	})
+	s, _ := hub.ConnectQueued("/ws/device")
+
+	go func() {
+		e, _ := s.Next(time.Second)
+		reply(t, hub, s, e, "21.5")
+	}()
+	req, err := hub.Request(context.Background(), s.ID, mustJSONEnvelope(t, "sensor", "temperature"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.GetStringValue("value") != "21.5" {
+		t.Errorf("got reply %+v", req)
+	}
+	if handled != 0 {
+		t.Error("expected the reply not to reach the handler")
+	}
+	if waiterCount(s) != 0 {
+		t.Error("expected the waiter to be removed")
+	}
+
+	if _, err := hub.Request(context.Background(), "missing", NewEnvelope("x")); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Request to unknown session err = %v", err)
+	}
+}
+
+func mustJSONEnvelope(t *testing.T, channel string, data any) *Envelope {
+	t.Helper()
+	e, err := JSONEnvelope(channel, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestRequestTimeout(t *testing.T) {
+	var handled int
+	hub := NewHub()
+	hub.HandleFunc("/ws/", func(*Session, *Request) (*Envelope, error) {
+		handled++
+		return nil, nil
+	})
+	s, _ := hub.ConnectQueued("/ws/device")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := s.Request(ctx, NewEnvelope("ask")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want DeadlineExceeded", err)
+	}
+	if waiterCount(s) != 0 {
+		t.Error("expected the waiter to be removed on timeout")
+	}
+
+	// A late reply is dropped, not handled or delivered to a later request
+	late, _ := s.Next(0)
+	reply(t, hub, s, late, "late")
+	if handled != 0 {
+		t.Error("expected the late reply to be discarded")
+	}
+
+	go func() {
+		e, _ := s.Next(time.Second)
+		reply(t, hub, s, late, "late again")
+		reply(t, hub, s, e, "fresh")
+	}()
+	req, err := s.Request(context.Background(), NewEnvelope("ask"))
+	if err != nil || req.GetStringValue("value") != "fresh" {
+		t.Errorf("got %+v, %v", req, err)
+	}
+}
+
+func TestRequestClose(t *testing.T) {
+	hub := NewHub()
+	hub.Handle("/ws/", echoHandler())
+	s, _ := hub.ConnectQueued("/ws/device")
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := s.Request(context.Background(), NewEnvelope("ask"))
+		errs <- err
+	}()
+	s.Next(time.Second)
+	hub.Disconnect(s.ID)
+	if err := <-errs; !errors.Is(err, ErrSessionClosed) {
+		t.Errorf("err = %v, want ErrSessionClosed", err)
+	}
+	if _, err := s.Request(context.Background(), NewEnvelope("ask")); !errors.Is(err, ErrSessionClosed) {
+		t.Errorf("Request after close err = %v, want ErrSessionClosed", err)
+	}
+	if waiterCount(s) != 0 {
+		t.Error("expected waiters removed on close")
+	}
+}
+
+func TestConcurrentRequests(t *testing.T) {
+	hub := NewHub()
+	hub.Handle("/ws/", echoHandler())
+	s, _ := hub.ConnectQueued("/ws/device")
+
+	const n = 50
+	// The client answers each request with its own payload, out of order
+	go func() {
+		var got []*Envelope
+		for len(got) < n {
+			e, err := s.Next(time.Second)
+			if err != nil || e == nil {
+				return
+			}
+			s.Ack(e.Seq)
+			got = append(got, e)
+		}
+		for i := len(got) - 1; i >= 0; i-- {
+			reply(t, hub, s, got[i], got[i].Payload)
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			want := fmt.Sprint(i)
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			req, err := s.Request(ctx, NewEnvelope(want))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if got := req.GetStringValue("value"); got != want {
+				t.Errorf("request %s got reply %q", want, got)
+			}
+		}()
+	}
+	wg.Wait()
+	if waiterCount(s) != 0 {
+		t.Errorf("%d waiters leaked", waiterCount(s))
+	}
+}
@@ -0,0 +1,172 @@
+package websocket
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Binary frames carry an envelope or request with its Binary data raw,
+// rather than base64 in JSON:
+//
+//	kind (1 byte) | header length (4 bytes, big-endian) | header | data
+//
+// The header is the envelope or request as JSON, without Binary. JSON
+// messages start with "{" or whitespace, so the kind byte tells the two
+// apart. Sessions use frames only once they opt in (see SetBinaryFrames).
+const (
+	FrameEnvelope byte = 0x01 // Server to client
+	FrameRequest  byte = 0x02 // Client to server
+)
+
+const frameHeaderSize = 5
+
+// DefaultMaxBinarySize is the default limit on a message's Binary data,
+// either way (see Hub.SetMaxBinarySize).
+const DefaultMaxBinarySize = 1 << 20
+
+var (
+	// ErrBinaryTooLarge is returned for Binary data over the session's
+	// limit, wrapped with the size and the limit.
+	ErrBinaryTooLarge = errors.New("websocket binary data too large")
+
+	// ErrBadFrame is returned for binary frames that can't be decoded.
+	ErrBadFrame = errors.New("websocket binary frame malformed")
+)
+
+// BinaryEnvelope creates an envelope delivering data to listeners on
+// channel.
+func BinaryEnvelope(channel string, data []byte) *Envelope {
+	return &Envelope{
+		Type:    TypeBinary,
+		Channel: channel,
+		Format:  "binary",
+		Binary:  data,
+	}
+}
+
+// Frame encodes the envelope as a FrameEnvelope binary frame.
+func (e *Envelope) Frame() ([]byte, error) {
+	header := *e
+	header.Binary = nil
+	return encodeFrame(FrameEnvelope, &header, e.Binary)
+}
+
+// Frame encodes the request as a FrameRequest binary frame, as a client
+// sends it.
+func (r *Request) Frame() ([]byte, error) {
+	header := *r
+	header.Binary = nil
+	return encodeFrame(FrameRequest, &header, r.Binary)
+}
+
+// ParseEnvelope parses a JSON message or, if data starts with
+// FrameEnvelope, a binary frame into an Envelope. A frame's Binary data
+// shares data's memory.
+func ParseEnvelope(data []byte) (*Envelope, error) {
+	var e Envelope
+	if !isFrame(data) {
+		if err := json.Unmarshal(data, &e); err != nil {
+			return nil, err
+		}
+		return &e, nil
+	}
+	payload, err := decodeFrame(data, FrameEnvelope, &e)
+	if err != nil {
+		return nil, err
+	}
+	e.Binary = payload
+	return &e, nil
+}
+
+func parseRequestFrame(data []byte) (*Request, error) {
+	var req Request
+	payload, err := decodeFrame(data, FrameRequest, &req)
+	if err != nil {
+		return nil, err
+	}
+	req.Binary = payload
+	return &req, nil
+}
+
+func isFrame(data []byte) bool {
+	return len(data) > 0 && (data[0] == FrameEnvelope || data[0] == FrameRequest)
+}
+
+func encodeFrame(kind byte, header any, payload []byte) ([]byte, error) {
+	h, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+	frame := make([]byte, frameHeaderSize, frameHeaderSize+len(h)+len(payload))
+	frame[0] = kind
+	binary.BigEndian.PutUint32(frame[1:frameHeaderSize], uint32(len(h)))
+	frame = append(frame, h...)
+	return append(frame, payload...), nil
+}
+
+// decodeFrame unmarshals a frame's header into header and returns its
+// data, or nil if it has none.
+func decodeFrame(frame []byte, kind byte, header any) ([]byte, error) {
+	if len(frame) < frameHeaderSize {
+		return nil, fmt.Errorf("%w: %d bytes", ErrBadFrame, len(frame))
+	}
+	if frame[0] != kind {
+		return nil, fmt.Errorf("%w: kind %#x, want %#x", ErrBadFrame, frame[0], kind)
+	}
+	n := binary.BigEndian.Uint32(frame[1:frameHeaderSize])
+	if uint64(n) > uint64(len(frame)-frameHeaderSize) {
+		return nil, fmt.Errorf("%w: header length %d past the end", ErrBadFrame, n)
+	}
+	end := frameHeaderSize + int(n)
+	if err := json.Unmarshal(frame[frameHeaderSize:end], header); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadFrame, err)
+	}
+	if end == len(frame) {
+		return nil, nil
+	}
+	return frame[end:], nil
+}
+
+// SetMaxBinarySize limits the Binary data of messages to and from each
+// session to n bytes: 0 restores DefaultMaxBinarySize, and n < 0 removes
+// the limit. It applies to sessions connected after the call.
+func (h *Hub) SetMaxBinarySize(n int) {
+	h.maxBinary = n
+}
+
+// SetBinaryFrames sets whether the session's client takes binary frames.
+// If so, transports send envelopes with Binary data as FrameEnvelope
+// frames rather than JSON. Either way, HandleMessage accepts both.
+func (s *Session) SetBinaryFrames(on bool) {
+	s.binaryFrames.Store(on)
+}
+
+// BinaryFrames reports whether the session's client takes binary frames.
+func (s *Session) BinaryFrames() bool {
+	return s.binaryFrames.Load()
+}
+
+// SendBinary sends data to the client's listeners on channel. It returns
+// ErrBinaryTooLarge if data is over the session's limit, and
+// ErrSessionClosed or ErrEnvelopeDropped if it can't be queued.
+func (s *Session) SendBinary(channel string, data []byte) error {
+	if err := s.checkBinary(len(data)); err != nil {
+		return err
+	}
+	return s.sendOrError(BinaryEnvelope(channel, data))
+}
+
+// checkBinary returns ErrBinaryTooLarge, wrapped with the sizes, if n
+// bytes is over the session's limit.
+func (s *Session) checkBinary(n int) error {
+	limit := s.maxBinary
+	if limit == 0 {
+		limit = DefaultMaxBinarySize
+	}
+	if limit > 0 && n > limit {
+		return fmt.Errorf("%w: %d bytes, limit %d", ErrBinaryTooLarge, n, limit)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package websocket
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBinaryJSONRoundTrip(t *testing.T) {
+	data := []byte{0, 1, 2, 0xff, '{'}
+	env := BinaryEnvelope("canvas", data).WithRequestID("r1")
+
+	encoded, err := env.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(encoded), `"binary":"AAEC/3s="`) {
+		t.Errorf("JSON = %s, want binary base64 encoded", encoded)
+	}
+	got, err := ParseEnvelope(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, env) {
+		t.Errorf("round trip = %+v, want %+v", got, env)
+	}
+
+	req, err := ParseRequest([]byte(`{"type":"request","request_id":"a","binary":"AAEC/3s="}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(req.Binary, data) {
+		t.Errorf("request Binary = %v, want %v", req.Binary, data)
+	}
+}
+
+func TestBinaryFrameRoundTrip(t *testing.T) {
+	data := bytes.Repeat([]byte{0, '{', 0xff}, 1000)
+
+	env := BinaryEnvelope("audio", data).WithTarget("#player")
+	frame, err := env.Frame()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if frame[0] != FrameEnvelope || bytes.Contains(frame, []byte(`"binary":`)) {
+		t.Errorf("frame starts %#x, want FrameEnvelope and no binary in the header", frame[0])
+	}
+	got, err := ParseEnvelope(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, env) {
+		t.Errorf("envelope round trip = %+v, want %+v", got, env)
+	}
+
+	req := &Request{Type: "request", RequestID: "r1", Values: map[string]any{"n": 1.0}, Binary: data}
+	frame, err = req.Frame()
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotReq, err := ParseRequest(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(gotReq, req) {
+		t.Errorf("request round trip = %+v, want %+v", gotReq, req)
+	}
+
+	// Frames without data decode to nil Binary
+	frame, _ = NewEnvelope("<p>hi</p>").Frame()
+	if got, err := ParseEnvelope(frame); err != nil || got.Binary != nil || got.Payload != "<p>hi</p>" {
+		t.Errorf("empty frame = %+v, %v", got, err)
+	}
+}
+
+func TestBadFrames(t *testing.T) {
+	envFrame, _ := NewEnvelope("x").Frame()
+	tests := map[string][]byte{
+		"short":         {FrameRequest, 0, 0},
+		"wrong kind":    envFrame,
+		"header length": {FrameRequest, 0, 0, 1, 0, '{', '}'},
+		"bad header":    {FrameRequest, 0, 0, 0, 2, '{', 'x'},
+	}
+	for name, frame := range tests {
+		if _, err := ParseRequest(frame); !errors.Is(err, ErrBadFrame) {
+			t.Errorf("%s: err = %v, want ErrBadFrame", name, err)
+		}
+	}
+}
+
+func TestBinarySizeLimit(t *testing.T) {
+	hub := NewHub()
+	hub.Handle("/ws/", echoHandler())
+	hub.SetMaxBinarySize(4)
+	s, _ := hub.Connect("/ws/canvas")
+
+	if err := s.SendBinary("canvas", []byte("1234")); err != nil {
+		t.Errorf("SendBinary at the limit err = %v", err)
+	}
+	if env := <-s.SendChan; env.Type != TypeBinary || string(env.Binary) != "1234" {
+		t.Errorf("sent %+v", env)
+	}
+	err := s.SendBinary("canvas", []byte("12345"))
+	if !errors.Is(err, ErrBinaryTooLarge) || !strings.Contains(err.Error(), "5 bytes, limit 4") {
+		t.Errorf("SendBinary over the limit err = %v, want ErrBinaryTooLarge with sizes", err)
+	}
+
+	frame, _ := (&Request{Type: "request", Binary: []byte("12345")}).Frame()
+	if _, err := s.HandleMessage(frame); !errors.Is(err, ErrBinaryTooLarge) {
+		t.Errorf("HandleMessage over the limit err = %v, want ErrBinaryTooLarge", err)
+	}
+	msg, _ := json.Marshal(&Request{Type: "request", Binary: []byte("12345")})
+	if _, err := s.HandleMessage(msg); !errors.Is(err, ErrBinaryTooLarge) {
+		t.Errorf("HandleMessage of JSON over the limit err = %v, want ErrBinaryTooLarge", err)
+	}
+
+	// The default applies unless the limit is removed
+	hub.SetMaxBinarySize(0)
+	s, _ = hub.Connect("/ws/canvas")
+	if err := s.SendBinary("canvas", make([]byte, DefaultMaxBinarySize+1)); !errors.Is(err, ErrBinaryTooLarge) {
+		t.Errorf("SendBinary over the default err = %v, want ErrBinaryTooLarge", err)
+	}
+	hub.SetMaxBinarySize(-1)
+	s, _ = hub.Connect("/ws/canvas")
+	if err := s.SendBinary("canvas", make([]byte, DefaultMaxBinarySize+1)); err != nil {
+		t.Errorf("SendBinary without a limit err = %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+package websocket
+
+import (
+	"slices"
+	"sync"
+)
+
+// sessionHooks is a list of session callbacks. Callbacks may be added
+// or removed while it runs.
+type sessionHooks struct {
+	mu     sync.Mutex
+	nextID int
+	hooks  []sessionHook
+}
+
+type sessionHook struct {
+	id int
+	fn func(*Session)
+}
+
+// add registers fn, at the end or, if first, the start of the list. The
+// returned func removes it; it is safe to call more than once.
+func (l *sessionHooks) add(fn func(*Session), first bool) (remove func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.nextID++
+	hook := sessionHook{id: l.nextID, fn: fn}
+	if first {
+		l.hooks = slices.Insert(l.hooks, 0, hook)
+	} else {
+		l.hooks = append(l.hooks, hook)
+	}
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.hooks = slices.DeleteFunc(l.hooks, func(h sessionHook) bool { return h.id == hook.id })
+	}
+}
+
+// run calls every callback in order, outside the lock.
+func (l *sessionHooks) run(s *Session) {
+	l.mu.Lock()
+	hooks := slices.Clone(l.hooks)
+	l.mu.Unlock()
+	for _, h := range hooks {
+		h.fn(s)
+	}
+}
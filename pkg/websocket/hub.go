@@ -1,11 +1,18 @@
 package websocket
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
 	"errors"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/a-h/templ"
+	"github.com/stukennedy/irgo/pkg/render"
 )
 
 var (
@@ -15,22 +22,51 @@ var (
 	// ErrSessionClosed is returned when trying to use a closed session.
 	ErrSessionClosed = errors.New("websocket session closed")
 
+	// ErrTooManyPending is returned when a request would take a session
+	// past its pending request limit (see Hub.SetMaxPending).
+	ErrTooManyPending = errors.New("websocket session has too many pending requests")
+
 	// ErrNoHandler is returned when no handler is registered for a URL.
 	ErrNoHandler = errors.New("no handler registered for URL")
+
+	// ErrHandlerPanic is returned when a message handler panics.
+	ErrHandlerPanic = errors.New("websocket handler panicked")
 )
 
 // Hub manages all WebSocket sessions and message routing.
 type Hub struct {
 	sessions    map[string]*Session
 	handlers    map[string]MessageHandler // URL pattern → handler
+	patterns    []*pattern                // handlers' patterns, most specific first
 	defaultHandler MessageHandler
 	sessionsMu  sync.RWMutex
 	handlersMu  sync.RWMutex
-	counter     uint64
-
-	// Callback for when sessions are created/destroyed
-	onSessionCreated  func(session *Session)
-	onSessionDestroyed func(session *Session)
+	newID       func() string // Set by SetIDGenerator
+
+	// Callbacks for when sessions are created/destroyed
+	onSessionCreated   sessionHooks
+	onSessionDestroyed sessionHooks
+
+	renderer   *render.TemplRenderer     // For SendTempl and BroadcastTempl
+	bufferSize int                       // Per-session buffer; 0 for DefaultBufferSize
+	maxPending int                       // Per-session pending requests; 0 for no limit
+	limit      rateLimit                 // Per-session message rate (see SetMessageRateLimit)
+	hubLimiter *tokenBucket              // nil unless SetHubMessageRateLimit
+	ratePolicy RateLimitPolicy           // What happens to rate limited messages
+	maxBinary  int                       // Per-message Binary bytes; 0 for DefaultMaxBinarySize
+	replay     *replayStore              // nil unless EnableReplay
+	metrics    hubMetrics                // See Metrics
+	onDrop     func(*Session, *Envelope) // Envelopes dropped by Session.Send
+
+	// Envelope tracing; nil when disabled (see trace.go)
+	tracer  atomic.Pointer[tracer]
+	traceMu sync.Mutex
+
+	// Room membership (see room.go). roomsMu is never held with
+	// sessionsMu.
+	roomsMu sync.RWMutex
+	rooms   map[string]map[*Session]struct{} // Room → members
+	joined  map[*Session]map[string]struct{} // Session → rooms
 }
 
 // NewHub creates a new WebSocket hub.
@@ -38,15 +74,22 @@ func NewHub() *Hub {
 	return &Hub{
 		sessions: make(map[string]*Session),
 		handlers: make(map[string]MessageHandler),
+		renderer: render.NewTemplRenderer(),
+		rooms:    make(map[string]map[*Session]struct{}),
+		joined:   make(map[*Session]map[string]struct{}),
 	}
 }
 
 // Handle registers a handler for a URL pattern.
-// Patterns can be exact ("/ws/chat") or prefix ("/ws/").
+// Patterns can be exact ("/ws/chat") or prefix ("/ws/"), and can capture
+// segments chi-style ("/ws/rooms/{roomID}", see Session.Param). A URL
+// matching several patterns goes to the most specific: the longest, then
+// exact over prefix, then literal segments over params.
 func (h *Hub) Handle(pattern string, handler MessageHandler) {
 	h.handlersMu.Lock()
 	defer h.handlersMu.Unlock()
 	h.handlers[pattern] = handler
+	h.sortPatterns()
 }
 
 // HandleFunc registers a function handler for a URL pattern.
@@ -61,97 +104,221 @@ func (h *Hub) SetDefaultHandler(handler MessageHandler) {
 	h.defaultHandler = handler
 }
 
-// OnSessionCreated sets a callback for when sessions are created.
-func (h *Hub) OnSessionCreated(fn func(*Session)) {
-	h.onSessionCreated = fn
+// OnSessionCreated adds a callback for when sessions are created.
+// Callbacks run in the order they were added; call unsubscribe to remove
+// this one.
+func (h *Hub) OnSessionCreated(fn func(*Session)) (unsubscribe func()) {
+	return h.onSessionCreated.add(fn, false)
 }
 
-// OnSessionDestroyed sets a callback for when sessions are destroyed.
-func (h *Hub) OnSessionDestroyed(fn func(*Session)) {
-	h.onSessionDestroyed = fn
+// OnSessionCreatedFirst is OnSessionCreated, running fn before the
+// callbacks already added.
+func (h *Hub) OnSessionCreatedFirst(fn func(*Session)) (unsubscribe func()) {
+	return h.onSessionCreated.add(fn, true)
 }
 
-// Connect creates a new session for the given URL.
-// Returns the session ID and the session.
-func (h *Hub) Connect(url string) (*Session, error) {
-	handler := h.findHandler(url)
-	if handler == nil && h.defaultHandler == nil {
-		return nil, ErrNoHandler
-	}
-	if handler == nil {
-		handler = h.defaultHandler
-	}
+// OnSessionDestroyed adds a callback for when sessions are destroyed.
+// Callbacks run in the order they were added; call unsubscribe to remove
+// this one.
+func (h *Hub) OnSessionDestroyed(fn func(*Session)) (unsubscribe func()) {
+	return h.onSessionDestroyed.add(fn, false)
+}
 
-	sessionID := h.generateSessionID()
-	session := NewSession(sessionID, url, handler)
+// OnSessionDestroyedFirst is OnSessionDestroyed, running fn before the
+// callbacks already added.
+func (h *Hub) OnSessionDestroyedFirst(fn func(*Session)) (unsubscribe func()) {
+	return h.onSessionDestroyed.add(fn, true)
+}
 
-	h.sessionsMu.Lock()
-	h.sessions[sessionID] = session
-	h.sessionsMu.Unlock()
+// OnDrop sets a callback for envelopes dropped by the non-blocking
+// Session.Send, because the session's buffer was full or it was closed.
+// Use Session.SendContext to wait for room instead.
+func (h *Hub) OnDrop(fn func(*Session, *Envelope)) {
+	h.onDrop = fn
+}
 
-	// Call OnConnect
-	if err := handler.OnConnect(session); err != nil {
-		h.sessionsMu.Lock()
-		delete(h.sessions, sessionID)
-		h.sessionsMu.Unlock()
-		return nil, err
-	}
+// SetBufferSize sets how many envelopes each session buffers for the
+// client, in SendChan or the QueueMode queue; n <= 0 restores
+// DefaultBufferSize. It applies to sessions connected after the call.
+func (h *Hub) SetBufferSize(n int) {
+	h.bufferSize = n
+}
 
-	if h.onSessionCreated != nil {
-		h.onSessionCreated(session)
-	}
+// SetRenderContext sets the context templ components are rendered with
+// by SendTempl, ReplyTempl and BroadcastTempl, e.g. one carrying the
+// locale. Call it before sending.
+func (h *Hub) SetRenderContext(ctx context.Context) {
+	h.renderer = render.NewTemplRenderer().WithContext(ctx)
+}
 
-	return session, nil
+// SetMaxPending limits how many requests each session tracks while they
+// await a reply; beyond it HandleMessage fails with ErrTooManyPending.
+// n <= 0 means no limit, the default. It applies to sessions connected
+// after the call.
+func (h *Hub) SetMaxPending(n int) {
+	h.maxPending = max(n, 0)
+}
+
+// SetIDGenerator replaces the random IDs given to sessions by Connect and
+// ConnectQueued, for tests that need predictable IDs. nil restores random
+// IDs. Call it before connecting sessions.
+func (h *Hub) SetIDGenerator(fn func() string) {
+	h.newID = fn
+}
+
+// Connect creates a new session for the given URL.
+// Returns the session ID and the session.
+func (h *Hub) Connect(url string) (*Session, error) {
+	return h.connect(h.generateSessionID(), url, ChannelMode)
 }
 
 // ConnectWithID creates a session with a specific ID (for reconnection).
 func (h *Hub) ConnectWithID(sessionID, url string) (*Session, error) {
-	handler := h.findHandler(url)
-	if handler == nil && h.defaultHandler == nil {
-		return nil, ErrNoHandler
-	}
+	return h.connect(sessionID, url, ChannelMode)
+}
+
+// ConnectQueued creates a QueueMode session, read with Session.Next
+// instead of SendChan.
+func (h *Hub) ConnectQueued(url string) (*Session, error) {
+	return h.connect(h.generateSessionID(), url, QueueMode)
+}
+
+// ConnectQueuedWithID creates a QueueMode session with a specific ID.
+// Reconnecting replays the old session's unacknowledged envelopes; it
+// fails with ErrConsumerMode if the old session used SendChan.
+func (h *Hub) ConnectQueuedWithID(sessionID, url string) (*Session, error) {
+	return h.connect(sessionID, url, QueueMode)
+}
+
+func (h *Hub) connect(sessionID, url string, mode ConsumerMode) (*Session, error) {
+	// Hold handlersMu until the session is registered, so Unhandle and
+	// CloseURL never miss a session created from a handler they remove.
+	h.handlersMu.RLock()
+	handler, params := h.findHandler(url)
 	if handler == nil {
 		handler = h.defaultHandler
 	}
+	if handler == nil {
+		h.handlersMu.RUnlock()
+		return nil, ErrNoHandler
+	}
 
-	session := NewSession(sessionID, url, handler)
+	buffer := h.bufferSize
+	if buffer <= 0 {
+		buffer = DefaultBufferSize
+	}
+	session := newSession(sessionID, url, handler, buffer)
+	session.hub = h
+	session.params = params
+	session.maxPending = h.maxPending
+	session.limiter = h.limit.bucket()
+	session.maxBinary = h.maxBinary
+	if mode == QueueMode {
+		session.queue = newEnvelopeQueue(buffer)
+	}
 
 	h.sessionsMu.Lock()
 	// If session already exists, close the old one
-	if old, exists := h.sessions[sessionID]; exists {
-		old.Close()
+	old := h.sessions[sessionID]
+	if old != nil && old.Mode() != mode {
+		h.sessionsMu.Unlock()
+		h.handlersMu.RUnlock()
+		return nil, ErrConsumerMode
+	}
+	h.replayTo(session)
+	if old != nil && old.queue != nil {
+		// Stop the old queue first so nothing lands after the handover
+		old.queue.close()
+		session.queue.adopt(old.queue)
 	}
 	h.sessions[sessionID] = session
 	h.sessionsMu.Unlock()
+	h.handlersMu.RUnlock()
+	h.metrics.connects.Add(1)
+
+	if old != nil {
+		old.Close()
+		h.metrics.disconnects.Add(1)
+	}
 
 	if err := handler.OnConnect(session); err != nil {
 		h.sessionsMu.Lock()
-		delete(h.sessions, sessionID)
+		if h.sessions[sessionID] == session {
+			delete(h.sessions, sessionID)
+			h.metrics.disconnects.Add(1)
+		}
 		h.sessionsMu.Unlock()
+		// Rooms OnConnect joined before failing
+		h.leaveAll(session)
 		return nil, err
 	}
 
-	if h.onSessionCreated != nil {
-		h.onSessionCreated(session)
+	// Closed by CloseURL while OnConnect ran
+	if session.IsClosed() {
+		return nil, ErrSessionClosed
 	}
 
+	h.onSessionCreated.run(session)
+
 	return session, nil
 }
 
+// Unhandle removes the handler for a URL pattern. Later connects to
+// matching URLs fail with ErrNoHandler (unless a default handler is set);
+// existing sessions are left open, see CloseURL.
+func (h *Hub) Unhandle(pattern string) {
+	h.handlersMu.Lock()
+	defer h.handlersMu.Unlock()
+	delete(h.handlers, pattern)
+	h.sortPatterns()
+}
+
+// CloseURL closes the sessions connected to URLs matching the pattern,
+// e.g. when a module is unmounted, and returns how many were closed.
+// Connects racing with CloseURL either complete first and are closed, or
+// start afterwards.
+func (h *Hub) CloseURL(urlPattern string, reason CloseReason) int {
+	h.handlersMu.Lock()
+	h.sessionsMu.Lock()
+	var closing []*Session
+	for id, s := range h.sessions {
+		if h.matchURL(s.URL, urlPattern) {
+			closing = append(closing, s)
+			delete(h.sessions, id)
+		}
+	}
+	h.sessionsMu.Unlock()
+	h.handlersMu.Unlock()
+	h.metrics.disconnects.Add(int64(len(closing)))
+
+	for _, s := range closing {
+		s.CloseWithReason(reason)
+		h.forget(s.ID)
+		h.onSessionDestroyed.run(s)
+	}
+	return len(closing)
+}
+
 // Disconnect closes and removes a session.
 func (h *Hub) Disconnect(sessionID string) {
 	h.sessionsMu.Lock()
 	session, exists := h.sessions[sessionID]
 	if exists {
 		delete(h.sessions, sessionID)
+		if h.replay != nil {
+			h.replay.open(session)
+		}
 	}
 	h.sessionsMu.Unlock()
 
 	if exists {
+		h.metrics.disconnects.Add(1)
 		session.Close()
-		if h.onSessionDestroyed != nil {
-			h.onSessionDestroyed(session)
+		if h.replay != nil {
+			h.replay.hold(session)
 		}
+		h.forget(sessionID)
+		h.onSessionDestroyed.run(session)
 	}
 }
 
@@ -175,25 +342,62 @@ func (h *Hub) HandleMessage(sessionID string, data []byte) (*Envelope, error) {
 	return session.HandleMessage(data)
 }
 
-// Send sends an envelope to a specific session.
+// Send sends an envelope to a specific session without blocking, like
+// Session.Send. It returns ErrEnvelopeDropped if the buffer was full.
 func (h *Hub) Send(sessionID string, envelope *Envelope) error {
 	session, ok := h.GetSession(sessionID)
 	if !ok {
+		if h.holdForReplay(sessionID, envelope) {
+			return nil
+		}
 		return ErrSessionNotFound
 	}
 	if !session.Send(envelope) {
-		return ErrSessionClosed
+		if session.IsClosed() {
+			return ErrSessionClosed
+		}
+		return ErrEnvelopeDropped
 	}
 	return nil
 }
 
-// SendHTML sends an HTML fragment to a session.
+// SendContext sends an envelope to a specific session, waiting for room
+// in its buffer until ctx is done (see Session.SendContext). When tracing
+// is enabled the envelope's TraceID is taken from ctx (see
+// TraceIDFromContext).
+func (h *Hub) SendContext(ctx context.Context, sessionID string, envelope *Envelope) error {
+	session, ok := h.GetSession(sessionID)
+	if !ok {
+		if h.holdForReplay(sessionID, envelope) {
+			return nil
+		}
+		return ErrSessionNotFound
+	}
+	return session.SendContext(ctx, envelope)
+}
+
+// SendHTML sends an HTML fragment to a session without blocking, like
+// Send.
 func (h *Hub) SendHTML(sessionID, target, html string) error {
 	return h.Send(sessionID, HTMLEnvelope(target, html))
 }
 
+// SendHTMLContext sends an HTML fragment to a session, tracing it to ctx.
+// Like SendContext, it waits for room in the session's buffer until ctx
+// is done.
+func (h *Hub) SendHTMLContext(ctx context.Context, sessionID, target, html string) error {
+	return h.SendContext(ctx, sessionID, HTMLEnvelope(target, html))
+}
+
 // Broadcast sends an envelope to all sessions.
 func (h *Hub) Broadcast(envelope *Envelope) {
+	h.BroadcastContext(context.Background(), envelope)
+}
+
+// BroadcastContext sends an envelope to all sessions, tracing it to ctx.
+func (h *Hub) BroadcastContext(ctx context.Context, envelope *Envelope) {
+	h.stamp(ctx, envelope)
+
 	h.sessionsMu.RLock()
 	sessions := make([]*Session, 0, len(h.sessions))
 	for _, s := range h.sessions {
@@ -204,6 +408,10 @@ func (h *Hub) Broadcast(envelope *Envelope) {
 	for _, s := range sessions {
 		s.Send(envelope)
 	}
+	h.metrics.broadcast(len(sessions))
+	if h.replay != nil {
+		h.replay.broadcast(envelope, nil)
+	}
 }
 
 // BroadcastHTML sends HTML to all sessions.
@@ -211,8 +419,22 @@ func (h *Hub) BroadcastHTML(target, html string) {
 	h.Broadcast(HTMLEnvelope(target, html))
 }
 
+// BroadcastTempl renders a templ component once and sends it to all
+// sessions, swapped into target. It returns the render error, if any,
+// without sending.
+func (h *Hub) BroadcastTempl(target string, component templ.Component) error {
+	html, err := h.renderer.Render(component)
+	if err != nil {
+		return err
+	}
+	h.Broadcast(HTMLEnvelope(target, html))
+	return nil
+}
+
 // BroadcastToURL sends to all sessions connected to URLs matching the pattern.
 func (h *Hub) BroadcastToURL(urlPattern string, envelope *Envelope) {
+	h.stamp(context.Background(), envelope)
+
 	h.sessionsMu.RLock()
 	sessions := make([]*Session, 0)
 	for _, s := range h.sessions {
@@ -225,6 +447,54 @@ func (h *Hub) BroadcastToURL(urlPattern string, envelope *Envelope) {
 	for _, s := range sessions {
 		s.Send(envelope)
 	}
+	h.metrics.broadcast(len(sessions))
+	if h.replay != nil {
+		h.replay.broadcast(envelope, func(url string) bool { return h.matchURL(url, urlPattern) })
+	}
+}
+
+// BroadcastExcept sends an envelope to all sessions except the given
+// ones, typically the sender.
+func (h *Hub) BroadcastExcept(envelope *Envelope, excludeSessionIDs ...string) {
+	h.BroadcastFunc(except(excludeSessionIDs), envelope)
+}
+
+// BroadcastFunc sends an envelope to all sessions for which pred returns
+// true. pred runs without hub locks held, so it may call back into the
+// hub.
+func (h *Hub) BroadcastFunc(pred func(*Session) bool, envelope *Envelope) {
+	h.stamp(context.Background(), envelope)
+	n := 0
+	for _, s := range h.AllSessions() {
+		if pred(s) {
+			s.Send(envelope)
+			n++
+		}
+	}
+	h.metrics.broadcast(n)
+}
+
+// SendToUser sends an envelope to every session whose metadata key is
+// set to value, e.g. all of a user's tabs:
+//
+//	hub.SendToUser("user_id", userID, envelope)
+func (h *Hub) SendToUser(key, value string, envelope *Envelope) {
+	h.BroadcastFunc(metadataEquals(key, value), envelope)
+}
+
+// except matches sessions not in ids.
+func except(ids []string) func(*Session) bool {
+	return func(s *Session) bool {
+		return !slices.Contains(ids, s.ID)
+	}
+}
+
+// metadataEquals matches sessions with key set to the string value.
+func metadataEquals(key, value string) func(*Session) bool {
+	return func(s *Session) bool {
+		v, ok := s.Get(key)
+		return ok && v == value
+	}
 }
 
 // Sessions returns the number of active sessions.
@@ -260,7 +530,8 @@ func (h *Hub) AllSessions() []*Session {
 	return result
 }
 
-// CleanupExpired removes stale pending requests from all sessions.
+// CleanupExpired removes stale pending requests from all sessions, and
+// expired replay buffers (see EnableReplay).
 func (h *Hub) CleanupExpired(ttl time.Duration) {
 	h.sessionsMu.RLock()
 	sessions := make([]*Session, 0, len(h.sessions))
@@ -272,6 +543,44 @@ func (h *Hub) CleanupExpired(ttl time.Duration) {
 	for _, s := range sessions {
 		s.CleanupExpiredPending(ttl)
 	}
+	if h.replay != nil {
+		h.replay.mu.Lock()
+		h.replay.prune()
+		h.replay.mu.Unlock()
+	}
+}
+
+// Defaults for StartCleanup, used by the desktop app and mobile bridge.
+const (
+	DefaultCleanupInterval = time.Minute
+	DefaultPendingTTL      = 5 * time.Minute
+)
+
+// StartCleanup runs CleanupExpired(ttl) every interval in the background,
+// so pending requests that never get a reply don't pile up. stop ends it,
+// waiting for a cleanup in progress; it is safe to call more than once.
+func (h *Hub) StartCleanup(interval, ttl time.Duration) (stop func()) {
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				h.CleanupExpired(ttl)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+		<-exited
+	}
 }
 
 // Close closes all sessions and cleans up the hub.
@@ -283,66 +592,25 @@ func (h *Hub) Close() {
 	}
 	h.sessions = make(map[string]*Session)
 	h.sessionsMu.Unlock()
+	h.metrics.disconnects.Add(int64(len(sessions)))
 
 	for _, s := range sessions {
 		s.Close()
-		if h.onSessionDestroyed != nil {
-			h.onSessionDestroyed(s)
-		}
-	}
-}
-
-func (h *Hub) findHandler(url string) MessageHandler {
-	h.handlersMu.RLock()
-	defer h.handlersMu.RUnlock()
-
-	// Exact match first
-	if handler, ok := h.handlers[url]; ok {
-		return handler
-	}
-
-	// Prefix match
-	for pattern, handler := range h.handlers {
-		if strings.HasSuffix(pattern, "/") && strings.HasPrefix(url, pattern) {
-			return handler
-		}
+		h.forget(s.ID)
+		h.onSessionDestroyed.run(s)
 	}
-
-	// Path extraction (remove protocol and host)
-	path := extractPath(url)
-	if handler, ok := h.handlers[path]; ok {
-		return handler
-	}
-
-	for pattern, handler := range h.handlers {
-		if strings.HasSuffix(pattern, "/") && strings.HasPrefix(path, pattern) {
-			return handler
-		}
-	}
-
-	return nil
-}
-
-func (h *Hub) matchURL(url, pattern string) bool {
-	if url == pattern {
-		return true
-	}
-	if strings.HasSuffix(pattern, "/") && strings.HasPrefix(url, pattern) {
-		return true
-	}
-	path := extractPath(url)
-	if path == pattern {
-		return true
-	}
-	if strings.HasSuffix(pattern, "/") && strings.HasPrefix(path, pattern) {
-		return true
-	}
-	return false
 }
 
+// generateSessionID returns an ID for a new session: "ws_" and 16 random
+// bytes, base64url-encoded. They can't be guessed or collide with IDs
+// that clients stored before a restart and reconnect with.
 func (h *Hub) generateSessionID() string {
-	id := atomic.AddUint64(&h.counter, 1)
-	return "ws_" + time.Now().Format("20060102150405") + "_" + itoa(id)
+	if h.newID != nil {
+		return h.newID()
+	}
+	var b [16]byte
+	rand.Read(b[:])
+	return "ws_" + base64.RawURLEncoding.EncodeToString(b[:])
 }
 
 func extractPath(url string) string {
@@ -356,18 +624,3 @@ func extractPath(url string) string {
 	}
 	return "/"
 }
-
-func itoa(n uint64) string {
-	const digits = "0123456789"
-	if n == 0 {
-		return "0"
-	}
-	var buf [20]byte
-	i := len(buf) - 1
-	for n > 0 {
-		buf[i] = digits[n%10]
-		n /= 10
-		i--
-	}
-	return string(buf[i+1:])
-}
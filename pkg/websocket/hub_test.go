@@ -0,0 +1,708 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stukennedy/irgo/pkg/crash"
+)
+
+func echoHandler() MessageHandler {
+	return MessageHandlerFunc(func(s *Session, req *Request) (*Envelope, error) {
+		return NewEnvelope("ok"), nil
+	})
+}
+
+func TestCloseURL(t *testing.T) {
+	hub := NewHub()
+	hub.Handle("/ws/tenant-a/", echoHandler())
+	hub.Handle("/ws/tenant-b/", echoHandler())
+
+	var a []*Session
+	for i := 0; i < 3; i++ {
+		s, err := hub.Connect("/ws/tenant-a/feed")
+		if err != nil {
+			t.Fatal(err)
+		}
+		a = append(a, s)
+	}
+	b, err := hub.Connect("/ws/tenant-b/feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var destroyed atomic.Int32
+	hub.OnSessionDestroyed(func(*Session) { destroyed.Add(1) })
+
+	if n := hub.CloseURL("/ws/tenant-a/", CloseGoingAway); n != 3 {
+		t.Errorf("expected 3 sessions closed, got %d", n)
+	}
+	for _, s := range a {
+		if !s.IsClosed() || s.CloseReason() != CloseGoingAway {
+			t.Errorf("expected %s closed with going away, got %v %+v", s.ID, s.IsClosed(), s.CloseReason())
+		}
+		if _, ok := hub.GetSession(s.ID); ok {
+			t.Errorf("expected %s removed from hub", s.ID)
+		}
+	}
+	if b.IsClosed() || hub.SessionCount() != 1 {
+		t.Error("expected tenant-b session to stay open")
+	}
+	if destroyed.Load() != 3 {
+		t.Errorf("expected 3 destroyed callbacks, got %d", destroyed.Load())
+	}
+
+	// The handler is still registered
+	if _, err := hub.Connect("/ws/tenant-a/feed"); err != nil {
+		t.Errorf("expected connect to succeed, got %v", err)
+	}
+}
+
+func TestUnhandle(t *testing.T) {
+	hub := NewHub()
+	hub.Handle("/ws/chat", echoHandler())
+	s, err := hub.Connect("/ws/chat")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hub.Unhandle("/ws/chat")
+	if _, err := hub.Connect("/ws/chat"); !errors.Is(err, ErrNoHandler) {
+		t.Errorf("expected ErrNoHandler, got %v", err)
+	}
+	if s.IsClosed() {
+		t.Error("expected existing session to stay open")
+	}
+	if s.CloseReason() != (CloseReason{}) {
+		t.Errorf("expected no close reason for open session, got %+v", s.CloseReason())
+	}
+	s.Close()
+	if s.CloseReason() != CloseNormal {
+		t.Errorf("expected normal close, got %+v", s.CloseReason())
+	}
+}
+
+// TestShutdownRace connects concurrently with Unhandle and CloseURL: every
+// connect must either fail or produce a session that CloseURL closed.
+func TestShutdownRace(t *testing.T) {
+	for round := 0; round < 20; round++ {
+		hub := NewHub()
+		hub.Handle("/ws/module/", echoHandler())
+		hub.Handle("/ws/other", echoHandler())
+
+		var (
+			wg        sync.WaitGroup
+			mu        sync.Mutex
+			connected []*Session
+			aborted   atomic.Int32 // Closed while connecting
+			start     = make(chan struct{})
+		)
+		for i := 0; i < 16; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				for j := 0; j < 10; j++ {
+					s, err := hub.Connect("/ws/module/live")
+					if errors.Is(err, ErrSessionClosed) {
+						aborted.Add(1)
+						continue
+					}
+					if err != nil {
+						if !errors.Is(err, ErrNoHandler) {
+							t.Errorf("unexpected error: %v", err)
+						}
+						continue
+					}
+					mu.Lock()
+					connected = append(connected, s)
+					mu.Unlock()
+				}
+			}()
+		}
+
+		close(start)
+		hub.Unhandle("/ws/module/")
+		closed := hub.CloseURL("/ws/module/", CloseGoingAway)
+		wg.Wait()
+
+		// Nothing can connect after Unhandle, so nothing is left behind
+		if n := len(hub.SessionsForURL("/ws/module/")); n != 0 {
+			t.Fatalf("round %d: %d sessions leaked", round, n)
+		}
+		if closed != len(connected)+int(aborted.Load()) {
+			t.Fatalf("round %d: closed %d sessions, %d connected, %d aborted", round, closed, len(connected), aborted.Load())
+		}
+		for _, s := range connected {
+			if !s.IsClosed() {
+				t.Fatalf("round %d: session %s left open", round, s.ID)
+			}
+		}
+	}
+}
+
+func TestHandlerPanicReported(t *testing.T) {
+	rep, err := crash.NewReporter(t.TempDir(), "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	crash.SetDefault(rep)
+	defer crash.SetDefault(nil)
+
+	hub := NewHub()
+	hub.HandleFunc("/ws/chat", func(s *Session, req *Request) (*Envelope, error) {
+		panic("chat handler exploded")
+	})
+	s, err := hub.Connect("/ws/chat")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := `{"type":"request","event":"submit","path":"/ws/chat","values":{"text":"hi","password":"hunter2"}}`
+	if _, err := hub.HandleMessage(s.ID, []byte(msg)); !errors.Is(err, ErrHandlerPanic) {
+		t.Fatalf("err = %v, want ErrHandlerPanic", err)
+	}
+	if s.IsClosed() {
+		t.Error("session closed by handler panic")
+	}
+
+	reports, _ := rep.Pending()
+	if len(reports) != 1 {
+		t.Fatalf("expected 1 crash report, got %d", len(reports))
+	}
+	r := reports[0]
+	if r.Source != crash.SourceWebSocket || r.Panic != "chat handler exploded" || !strings.Contains(r.Stack, "hub_test.go") {
+		t.Errorf("unexpected report %+v", r)
+	}
+	m := r.Message
+	if m == nil || m.SessionID != s.ID || m.Event != "submit" || m.Values["text"] != "hi" || m.Values["password"] != crash.Redacted {
+		t.Errorf("unexpected message summary %+v", m)
+	}
+}
+
+func TestSessionIDs(t *testing.T) {
+	hub := NewHub()
+	const n = 100000
+	seen := make(map[string]bool, n)
+	for range n {
+		id := hub.generateSessionID()
+		if seen[id] {
+			t.Fatalf("duplicate session ID %s", id)
+		}
+		seen[id] = true
+
+		rest, ok := strings.CutPrefix(id, "ws_")
+		if !ok || len(rest) != 22 || strings.Trim(rest, "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_") != "" {
+			t.Fatalf("session ID %q isn't ws_ and 16 base64url bytes", id)
+		}
+	}
+
+	// A fresh hub, as after a restart, doesn't reissue IDs
+	for range 1000 {
+		if id := NewHub().generateSessionID(); seen[id] {
+			t.Fatalf("new hub reissued %s", id)
+		}
+	}
+}
+
+func TestSetIDGenerator(t *testing.T) {
+	hub := NewHub()
+	hub.Handle("/ws/", echoHandler())
+	next := 0
+	hub.SetIDGenerator(func() string {
+		next++
+		return "test-" + string(rune('0'+next))
+	})
+
+	first, err := hub.Connect("/ws/chat/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := hub.ConnectQueued("/ws/chat/2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.ID != "test-1" || second.ID != "test-2" {
+		t.Errorf("IDs = %s, %s", first.ID, second.ID)
+	}
+
+	hub.SetIDGenerator(nil)
+	third, err := hub.Connect("/ws/chat/3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(third.ID, "ws_") {
+		t.Errorf("ID = %s, want a random ID after resetting the generator", third.ID)
+	}
+}
+
+func TestRandomIDsWithPrefixHandlers(t *testing.T) {
+	hub := NewHub()
+	hub.Handle("/ws/", echoHandler())
+
+	session, err := hub.Connect("/ws/chat/room-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := hub.GetSession(session.ID); !ok || got != session {
+		t.Fatalf("GetSession(%s) = %v, %v", session.ID, got, ok)
+	}
+	if n := len(hub.SessionsForURL("/ws/")); n != 1 {
+		t.Errorf("SessionsForURL(/ws/) = %d sessions, want 1", n)
+	}
+
+	// Reconnecting with a stored ID replaces the session under that ID
+	again, err := hub.ConnectWithID(session.ID, "/ws/chat/room-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := hub.GetSession(session.ID); got != again || hub.SessionCount() != 1 {
+		t.Errorf("reconnect left %d sessions", hub.SessionCount())
+	}
+}
+
+func TestBroadcastVariants(t *testing.T) {
+	hub := NewHub()
+	hub.Handle("/ws/", echoHandler())
+
+	// alice has two tabs, bob one, and one session is anonymous
+	var sessions []*Session
+	for _, user := range []string{"alice", "alice", "bob", ""} {
+		s, _ := hub.ConnectQueued("/ws/chat")
+		if user != "" {
+			s.Set("user_id", user)
+		}
+		sessions = append(sessions, s)
+	}
+	sessions[3].Set("role", "admin")
+	ids := func(i ...int) []string {
+		var out []string
+		for _, n := range i {
+			out = append(out, sessions[n].ID)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name string
+		send func(*Envelope)
+		want []int // Queued per session
+	}{
+		{"except sender", func(e *Envelope) { hub.BroadcastExcept(e, ids(0)...) }, []int{0, 1, 1, 1}},
+		{"except several", func(e *Envelope) { hub.BroadcastExcept(e, ids(1, 3)...) }, []int{1, 0, 1, 0}},
+		{"except none", func(e *Envelope) { hub.BroadcastExcept(e) }, []int{1, 1, 1, 1}},
+		{"except unknown", func(e *Envelope) { hub.BroadcastExcept(e, "ws_missing") }, []int{1, 1, 1, 1}},
+		{"func", func(e *Envelope) {
+			hub.BroadcastFunc(func(s *Session) bool { return s.GetString("role") == "admin" }, e)
+		}, []int{0, 0, 0, 1}},
+		{"func and except", func(e *Envelope) {
+			hub.BroadcastFunc(func(s *Session) bool {
+				return s.GetString("user_id") == "alice" && s.ID != sessions[0].ID
+			}, e)
+		}, []int{0, 1, 0, 0}},
+		{"func calls hub", func(e *Envelope) {
+			hub.BroadcastFunc(func(s *Session) bool { return hub.SessionCount() == 4 }, e)
+		}, []int{1, 1, 1, 1}},
+		{"user", func(e *Envelope) { hub.SendToUser("user_id", "alice", e) }, []int{1, 1, 0, 0}},
+		{"user unknown", func(e *Envelope) { hub.SendToUser("user_id", "carol", e) }, []int{0, 0, 0, 0}},
+		// Sessions without the key don't match an empty value
+		{"user empty", func(e *Envelope) { hub.SendToUser("user_id", "", e) }, []int{0, 0, 0, 0}},
+	}
+	for _, tt := range tests {
+		tt.send(NewEnvelope(tt.name))
+		for i, s := range sessions {
+			if got := s.Queued(); got != tt.want[i] {
+				t.Errorf("%s: session %d got %d envelopes, want %d", tt.name, i, got, tt.want[i])
+			}
+			for s.Queued() > 0 {
+				env, _ := s.Next(0)
+				s.Ack(env.Seq)
+			}
+		}
+	}
+}
+
+func TestSendContextBlocks(t *testing.T) {
+	hub := NewHub()
+	hub.Handle("/ws/", echoHandler())
+	hub.SetBufferSize(2)
+
+	var dropped []*Envelope
+	hub.OnDrop(func(s *Session, e *Envelope) { dropped = append(dropped, e) })
+
+	s, _ := hub.Connect("/ws/chat")
+	if cap(s.SendChan) != 2 {
+		t.Fatalf("expected buffer of 2, got %d", cap(s.SendChan))
+	}
+	s.Send(NewEnvelope("a"))
+	s.Send(NewEnvelope("b"))
+
+	// The legacy path drops, and reports it
+	full := NewEnvelope("full")
+	if s.Send(full) {
+		t.Error("expected Send on a full buffer to drop")
+	}
+	if len(dropped) != 1 || dropped[0] != full {
+		t.Errorf("expected the dropped envelope reported, got %v", dropped)
+	}
+	if err := hub.Send(s.ID, NewEnvelope("full")); !errors.Is(err, ErrEnvelopeDropped) {
+		t.Errorf("Hub.Send on a full buffer err = %v, want ErrEnvelopeDropped", err)
+	}
+
+	// SendContext gives up when the context does
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := s.SendContext(ctx, NewEnvelope("late")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("SendContext on a full buffer err = %v, want DeadlineExceeded", err)
+	}
+
+	// and unblocks once the client reads
+	sent := make(chan error, 1)
+	go func() { sent <- hub.SendContext(context.Background(), s.ID, NewEnvelope("c")) }()
+	select {
+	case err := <-sent:
+		t.Fatalf("SendContext returned %v before there was room", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	<-s.SendChan
+	if err := <-sent; err != nil {
+		t.Fatalf("SendContext err = %v", err)
+	}
+	var got []string
+	for range 2 {
+		got = append(got, (<-s.SendChan).Payload)
+	}
+	if strings.Join(got, ",") != "b,c" {
+		t.Errorf("expected b,c buffered, got %v", got)
+	}
+	if len(dropped) != 2 {
+		t.Errorf("expected SendContext not to report drops, got %d", len(dropped))
+	}
+}
+
+func TestSendContextClose(t *testing.T) {
+	hub := NewHub()
+	hub.Handle("/ws/", echoHandler())
+	hub.SetBufferSize(1)
+
+	s, _ := hub.Connect("/ws/chat")
+	s.Send(NewEnvelope("a"))
+
+	// Senders blocked on a full buffer are released by Close
+	const senders = 4
+	errs := make(chan error, senders)
+	for range senders {
+		go func() { errs <- s.SendContext(context.Background(), NewEnvelope("b")) }()
+	}
+	time.Sleep(10 * time.Millisecond)
+	hub.Disconnect(s.ID)
+	for range senders {
+		if err := <-errs; !errors.Is(err, ErrSessionClosed) {
+			t.Errorf("blocked SendContext err = %v, want ErrSessionClosed", err)
+		}
+	}
+	if err := s.SendContext(context.Background(), NewEnvelope("c")); !errors.Is(err, ErrSessionClosed) {
+		t.Errorf("SendContext after close err = %v, want ErrSessionClosed", err)
+	}
+
+	var dropped atomic.Int32
+	hub.OnDrop(func(*Session, *Envelope) { dropped.Add(1) })
+	if s.Send(NewEnvelope("d")) || dropped.Load() != 1 {
+		t.Errorf("expected Send after close to drop and report, got %d drops", dropped.Load())
+	}
+}
+
+func TestSendHTMLFullBuffer(t *testing.T) {
+	hub := NewHub()
+	hub.Handle("/ws/", echoHandler())
+	hub.SetBufferSize(1)
+	s, _ := hub.Connect("/ws/chat")
+	s.Send(NewEnvelope("a"))
+
+	// SendHTML doesn't block on a full buffer
+	sent := make(chan error, 1)
+	go func() { sent <- hub.SendHTML(s.ID, "#x", "<p>b</p>") }()
+	select {
+	case err := <-sent:
+		if !errors.Is(err, ErrEnvelopeDropped) {
+			t.Errorf("SendHTML on a full buffer err = %v, want ErrEnvelopeDropped", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("SendHTML blocked on a full buffer")
+	}
+
+	// SendHTMLContext waits until ctx is done
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := hub.SendHTMLContext(ctx, s.ID, "#x", "<p>c</p>"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("SendHTMLContext on a full buffer err = %v, want DeadlineExceeded", err)
+	}
+}
+
+func TestSendCloseRace(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(max(4, runtime.NumCPU())))
+
+	hub := NewHub()
+	hub.Handle("/ws/", echoHandler())
+	hub.SetBufferSize(4) // Small, so SendContext blocks too
+
+	const sessions = 2000
+	var wg sync.WaitGroup
+	for i := 0; i < sessions; i++ {
+		s, err := hub.Connect("/ws/chat")
+		if err != nil {
+			t.Fatal(err)
+		}
+		start := make(chan struct{})
+		for j := 0; j < 3; j++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				for k := 0; k < 10; k++ {
+					if j == 0 {
+						s.SendContext(context.Background(), NewEnvelope("x"))
+					} else {
+						s.Send(NewEnvelope("x"))
+					}
+				}
+			}()
+		}
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for range s.SendChan {
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			<-start
+			if i%2 == 0 {
+				hub.Disconnect(s.ID)
+			} else {
+				s.Close()
+			}
+		}()
+		close(start)
+		if i%100 == 0 {
+			hub.Broadcast(NewEnvelope("all"))
+		}
+	}
+	wg.Wait()
+}
+
+func TestStartCleanup(t *testing.T) {
+	hub := NewHub()
+	hub.Handle("/ws/", echoHandler())
+	s, _ := hub.Connect("/ws/chat")
+
+	for _, id := range []string{"old", "fresh"} {
+		if _, err := s.HandleMessage([]byte(`{"type":"request","request_id":"` + id + `"}`)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s.PendingCount() != 2 {
+		t.Fatalf("expected 2 pending, got %d", s.PendingCount())
+	}
+	s.pendingMu.Lock()
+	s.pending["old"].Timestamp = time.Now().Add(-time.Hour)
+	s.pendingMu.Unlock()
+
+	stop := hub.StartCleanup(5*time.Millisecond, time.Minute)
+	defer stop()
+	deadline := time.Now().Add(time.Second)
+	for s.GetPendingRequest("old") != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the old request to be purged")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if s.GetPendingRequest("fresh") == nil || s.PendingCount() != 1 {
+		t.Errorf("expected only the fresh request to survive, got %d pending", s.PendingCount())
+	}
+
+	stop()
+	stop() // Safe to call twice
+	s.pendingMu.Lock()
+	s.pending["fresh"].Timestamp = time.Now().Add(-time.Hour)
+	s.pendingMu.Unlock()
+	time.Sleep(20 * time.Millisecond)
+	if s.PendingCount() != 1 {
+		t.Error("expected no cleanup after stop")
+	}
+}
+
+func TestMaxPending(t *testing.T) {
+	hub := NewHub()
+	hub.Handle("/ws/", echoHandler())
+	hub.SetMaxPending(2)
+	s, _ := hub.Connect("/ws/chat")
+
+	request := func(id string) error {
+		_, err := hub.HandleMessage(s.ID, []byte(`{"type":"request","request_id":"`+id+`"}`))
+		return err
+	}
+	for _, id := range []string{"a", "b", "b"} { // Repeating an ID doesn't count
+		if err := request(id); err != nil {
+			t.Fatalf("request %s: %v", id, err)
+		}
+	}
+	if err := request("c"); !errors.Is(err, ErrTooManyPending) {
+		t.Errorf("request over the limit err = %v, want ErrTooManyPending", err)
+	}
+	if s.PendingCount() != 2 {
+		t.Errorf("expected 2 pending, got %d", s.PendingCount())
+	}
+	if err := request(""); err != nil {
+		t.Errorf("requests without an ID aren't tracked, got %v", err)
+	}
+
+	s.CleanupExpiredPending(-1)
+	if err := request("c"); err != nil {
+		t.Errorf("request after cleanup: %v", err)
+	}
+}
+
+func TestSessionHooks(t *testing.T) {
+	hub := NewHub()
+	hub.Handle("/ws/", echoHandler())
+
+	var mu sync.Mutex
+	var calls []string
+	record := func(name string) func(*Session) {
+		return func(s *Session) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls = append(calls, name)
+		}
+	}
+	take := func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		out := strings.Join(calls, ",")
+		calls = nil
+		return out
+	}
+
+	hub.OnSessionCreated(record("transport"))
+	unsubscribe := hub.OnSessionCreated(record("app"))
+	hub.OnSessionCreatedFirst(record("first"))
+	hub.OnSessionDestroyed(record("transport-closed"))
+	hub.OnSessionDestroyed(record("app-closed"))
+	hub.OnSessionDestroyedFirst(record("first-closed"))
+
+	s, _ := hub.Connect("/ws/chat")
+	if got := take(); got != "first,transport,app" {
+		t.Errorf("on connect got %s", got)
+	}
+	hub.Disconnect(s.ID)
+	if got := take(); got != "first-closed,transport-closed,app-closed" {
+		t.Errorf("on disconnect got %s", got)
+	}
+
+	unsubscribe()
+	unsubscribe() // Safe to call twice
+	hub.Connect("/ws/chat")
+	if got := take(); got != "first,transport" {
+		t.Errorf("after unsubscribe got %s", got)
+	}
+	hub.Close()
+	if got := take(); got != "first-closed,transport-closed,app-closed" {
+		t.Errorf("on close got %s", got)
+	}
+
+	// Hooks can unsubscribe themselves while running
+	var once func()
+	once = hub.OnSessionCreated(func(*Session) { once() })
+	hub.Connect("/ws/chat")
+	hub.Connect("/ws/chat")
+	if got := take(); got != "first,transport,first,transport" {
+		t.Errorf("self-removing hook got %s", got)
+	}
+}
+
+func TestSessionMetadata(t *testing.T) {
+	hub := NewHub()
+	hub.Handle("/ws/", echoHandler())
+	s, _ := hub.Connect("/ws/chat")
+
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	s.Set("admin", true)
+	s.Set("joined", at)
+	s.Set("bytes", int64(42))
+	s.Set("count", 3)
+	if !s.GetBool("admin") || !s.GetTime("joined").Equal(at) || s.GetInt64("bytes") != 42 {
+		t.Errorf("typed getters got %v, %v, %v", s.GetBool("admin"), s.GetTime("joined"), s.GetInt64("bytes"))
+	}
+	if s.GetBool("joined") || !s.GetTime("admin").IsZero() || s.GetInt64("count") != 0 {
+		t.Error("expected zero values for mismatched types")
+	}
+
+	// Increment carries on from an int and stores an int64
+	if n := s.Increment("count", 2); n != 5 {
+		t.Errorf("Increment = %d, want 5", n)
+	}
+	if n := s.Increment("missing", -1); n != -1 {
+		t.Errorf("Increment of a missing key = %d, want -1", n)
+	}
+
+	calls := 0
+	factory := func() any { calls++; return "v" }
+	if v := s.GetOrSet("k", factory); v != "v" {
+		t.Errorf("GetOrSet = %v, want v", v)
+	}
+	s.GetOrSet("k", factory)
+	if calls != 1 {
+		t.Errorf("factory called %d times, want 1", calls)
+	}
+
+	snap := s.MetadataSnapshot()
+	snap["admin"] = false
+	if !s.GetBool("admin") || snap["count"] != int64(5) {
+		t.Errorf("snapshot = %v, want a copy", snap)
+	}
+}
+
+func TestSessionMetadataConcurrent(t *testing.T) {
+	hub := NewHub()
+	hub.Handle("/ws/", echoHandler())
+	s, _ := hub.Connect("/ws/chat")
+
+	const workers, rounds = 8, 500
+	var calls atomic.Int64
+	values := make([]any, workers)
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range rounds {
+				s.Increment("n", 1)
+			}
+			values[w] = s.GetOrSet("once", func() any {
+				calls.Add(1)
+				return new(int)
+			})
+			s.MetadataSnapshot()
+		}()
+	}
+	wg.Wait()
+
+	if got := s.GetInt64("n"); got != workers*rounds {
+		t.Errorf("n = %d, want %d", got, workers*rounds)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("factory called %d times, want 1", calls.Load())
+	}
+	for _, v := range values {
+		if v != values[0] {
+			t.Fatal("expected every GetOrSet to return the first value")
+		}
+	}
+}
@@ -10,13 +10,18 @@ import (
 // Request represents a message from the client via WebSocket.
 // Used for real-time bidirectional communication alongside Datastar's SSE.
 type Request struct {
-	Type      string            `json:"type"`                 // Always "request" for client messages
+	Type      string            `json:"type"`                 // "request", or ResponseType for replies to Session.Request
 	RequestID string            `json:"request_id"`           // Unique ID for request-response matching
 	Event     string            `json:"event"`                // DOM event that triggered the send (click, submit, etc.)
 	Headers   map[string]string `json:"headers"`              // Request headers
 	Values    map[string]any    `json:"values"`               // Form data and hx-vals
 	Path      string            `json:"path"`                 // Normalized WebSocket URL
 	ID        string            `json:"id,omitempty"`         // Element ID (if element has id attribute)
+	Binary    []byte            `json:"binary,omitempty"`     // Binary data: base64 in JSON, raw in a frame (see ParseRequest)
+
+	// PathParams holds the session's URL params (see Session.Params);
+	// set by the hub, not the client.
+	PathParams map[string]string `json:"-"`
 }
 
 // GetValue returns a value from the Values map.
@@ -50,14 +55,47 @@ func (r *Request) CurrentURL() string {
 // Envelope represents a message from the server to the client.
 // Used for WebSocket-based real-time updates.
 type Envelope struct {
+	Type      string `json:"type,omitempty"`       // What the client does with it (see TypeHTML); empty for an HTML swap
 	Channel   string `json:"channel,omitempty"`    // Channel identifier (default: "ui")
 	Format    string `json:"format,omitempty"`     // Message format (default: "html")
 	Target    string `json:"target,omitempty"`     // Target selector for swap
 	Swap      string `json:"swap,omitempty"`       // Swap strategy (innerHTML, outerHTML, etc.)
 	Payload   string `json:"payload"`              // The actual content (HTML for ui/html)
+	Binary    []byte `json:"binary,omitempty"`     // Binary data: base64 in JSON, raw in a frame (see Envelope.Frame)
 	RequestID string `json:"request_id,omitempty"` // Matches original request for response matching
+	TraceID   string `json:"trace_id,omitempty"`   // Originating request ID or call site (when tracing)
+	Seq       int64  `json:"seq,omitempty"`        // Per-session sequence number (QueueMode sessions only)
+	Replayed  bool   `json:"replayed,omitempty"`   // Sent while the session was offline (see Hub.EnableReplay)
+
+	origin string // Sending function, captured with the call site
 }
 
+// Envelope types, for Envelope.Type. The client bridge dispatches on
+// them; an empty Type is treated as TypeHTML.
+const (
+	// TypeHTML swaps Payload into Target using Swap.
+	TypeHTML = "html"
+
+	// TypeError reports a failed request: Payload is a JSON object with
+	// "status" and "message", and RequestID names the request.
+	TypeError = "error"
+
+	// TypeSignals patches Datastar signals: Payload is a JSON merge patch.
+	TypeSignals = "signals"
+
+	// TypeScript runs Payload as JavaScript.
+	TypeScript = "script"
+
+	// TypeJSON delivers Payload, JSON data, to listeners on Channel.
+	TypeJSON = "json"
+
+	// TypeRemove removes the elements matching Target.
+	TypeRemove = "remove"
+
+	// TypeBinary delivers Binary, raw data, to listeners on Channel.
+	TypeBinary = "binary"
+)
+
 // NewEnvelope creates a new UI/HTML envelope with the given payload.
 func NewEnvelope(payload string) *Envelope {
 	return &Envelope{
@@ -111,8 +149,12 @@ func (e *Envelope) MustJSON() string {
 	return string(data)
 }
 
-// ParseRequest parses a JSON message into a Request.
+// ParseRequest parses a JSON message or, if data starts with
+// FrameRequest, a binary frame into a Request.
 func ParseRequest(data []byte) (*Request, error) {
+	if isFrame(data) {
+		return parseRequestFrame(data)
+	}
 	var req Request
 	if err := json.Unmarshal(data, &req); err != nil {
 		return nil, err
@@ -158,8 +200,64 @@ func JSONEnvelope(channel string, data any) (*Envelope, error) {
 		return nil, err
 	}
 	return &Envelope{
+		Type:    TypeJSON,
 		Channel: channel,
 		Format:  "json",
 		Payload: string(payload),
 	}, nil
 }
+
+// ErrorEnvelope creates an envelope reporting that a request failed with
+// an HTTP-style status and a message for the user.
+func ErrorEnvelope(requestID string, status int, message string) *Envelope {
+	payload, _ := json.Marshal(struct {
+		Status  int    `json:"status"`
+		Message string `json:"message"`
+	}{status, message})
+	return &Envelope{
+		Type:      TypeError,
+		Channel:   "ui",
+		Format:    "json",
+		Payload:   string(payload),
+		RequestID: requestID,
+	}
+}
+
+// SignalsEnvelope creates an envelope on SignalsChannel that patches the
+// client's Datastar signals with signals, marshaled to JSON.
+func SignalsEnvelope(signals any) (*Envelope, error) {
+	payload, err := json.Marshal(signals)
+	if err != nil {
+		return nil, err
+	}
+	return signalsEnvelope(payload), nil
+}
+
+func signalsEnvelope(patch []byte) *Envelope {
+	return &Envelope{
+		Type:    TypeSignals,
+		Channel: SignalsChannel,
+		Format:  "json",
+		Payload: string(patch),
+	}
+}
+
+// ScriptEnvelope creates an envelope that runs js in the WebView.
+func ScriptEnvelope(js string) *Envelope {
+	return &Envelope{
+		Type:    TypeScript,
+		Channel: "ui",
+		Format:  "js",
+		Payload: js,
+	}
+}
+
+// RemoveEnvelope creates an envelope that removes the elements matching
+// selector.
+func RemoveEnvelope(selector string) *Envelope {
+	return &Envelope{
+		Type:    TypeRemove,
+		Channel: "ui",
+		Target:  selector,
+	}
+}
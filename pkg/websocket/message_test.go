@@ -0,0 +1,109 @@
+package websocket
+
+import (
+	"encoding/json"
+	"maps"
+	"slices"
+	"testing"
+)
+
+// wire decodes an envelope as the client bridge sees it.
+func wire(t *testing.T, e *Envelope) map[string]any {
+	t.Helper()
+	data, err := e.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestEnvelopeContract(t *testing.T) {
+	signals, err := SignalsEnvelope(map[string]any{"count": 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := JSONEnvelope("presence", []string{"ann"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		envelope *Envelope
+		want     map[string]any
+	}{
+		{"html", HTMLEnvelope("#list", "<li>a</li>"), map[string]any{
+			"channel": "ui", "format": "html", "target": "#list", "payload": "<li>a</li>",
+		}},
+		{"error", ErrorEnvelope("req-1", 422, "Name is required"), map[string]any{
+			"type": "error", "channel": "ui", "format": "json", "request_id": "req-1",
+			"payload": `{"status":422,"message":"Name is required"}`,
+		}},
+		{"signals", signals, map[string]any{
+			"type": "signals", "channel": "signals", "format": "json", "payload": `{"count":2}`,
+		}},
+		{"script", ScriptEnvelope("console.log(1)"), map[string]any{
+			"type": "script", "channel": "ui", "format": "js", "payload": "console.log(1)",
+		}},
+		{"json", data, map[string]any{
+			"type": "json", "channel": "presence", "format": "json", "payload": `["ann"]`,
+		}},
+		{"remove", RemoveEnvelope("#toast"), map[string]any{
+			"type": "remove", "channel": "ui", "target": "#toast", "payload": "",
+		}},
+	}
+	for _, tt := range tests {
+		got := wire(t, tt.envelope)
+		if !maps.Equal(got, tt.want) {
+			keys := slices.Sorted(maps.Keys(got))
+			t.Errorf("%s:\n got %v (keys %v)\nwant %v", tt.name, got, keys, tt.want)
+		}
+	}
+
+	if _, err := SignalsEnvelope(func() {}); err == nil {
+		t.Error("expected an error for signals that can't be marshaled")
+	}
+}
+
+func TestSessionTypedSends(t *testing.T) {
+	hub := NewHub()
+	hub.Handle("/ws/", echoHandler())
+	s, _ := hub.ConnectQueued("/ws/chat")
+
+	if _, err := s.HandleMessage([]byte(`{"type":"request","request_id":"r1"}`)); err != nil {
+		t.Fatal(err)
+	}
+	s.SendError("r1", 500, "boom")
+	if s.PendingCount() != 0 {
+		t.Error("expected SendError to clear the pending request")
+	}
+	s.SendScript("go()")
+	if err := s.SendSignals(map[string]int{"n": 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	var types []string
+	for s.Queued() > 0 {
+		e, _ := s.Next(0)
+		types = append(types, e.Type)
+	}
+	if !slices.Equal(types, []string{TypeError, TypeScript, TypeSignals}) {
+		t.Errorf("sent types %v", types)
+	}
+
+	// A full send makes the next diff resend everything
+	s.SendSignalsDiff(map[string]int{"n": 1})
+	s.SendSignals(map[string]int{"n": 2})
+	s.SendSignalsDiff(map[string]int{"n": 1})
+	var last *Envelope
+	for s.Queued() > 0 {
+		last, _ = s.Next(0)
+	}
+	if last == nil || last.Payload != `{"n":1}` || last.Type != TypeSignals {
+		t.Errorf("expected the diff after SendSignals to resend n, got %+v", last)
+	}
+}
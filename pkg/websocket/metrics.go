@@ -0,0 +1,90 @@
+package websocket
+
+import (
+	"expvar"
+	"fmt"
+	"sync/atomic"
+)
+
+// Metrics is a snapshot of a hub's counters (see Hub.Metrics). Counts are
+// totals since the hub was created.
+type Metrics struct {
+	ActiveSessions int64 `json:"active_sessions"`
+	Connects       int64 `json:"connects"`
+	Disconnects    int64 `json:"disconnects"`  // Includes sessions replaced by a reconnect
+	MessagesIn     int64 `json:"messages_in"`  // Client messages received by HandleMessage
+	MessagesOut    int64 `json:"messages_out"` // Envelopes queued on sessions
+	Dropped        int64 `json:"dropped"`      // Envelopes dropped: buffer full, closed or timed out
+	RateLimited    int64 `json:"rate_limited"` // Client messages rejected by a rate limit
+
+	Broadcasts          int64 `json:"broadcasts"`
+	BroadcastRecipients int64 `json:"broadcast_recipients"` // Sessions reached, over all broadcasts
+	MaxFanout           int64 `json:"max_fanout"`           // Most sessions reached by one broadcast
+}
+
+// AvgFanout returns the average number of sessions a broadcast reached.
+func (m Metrics) AvgFanout() float64 {
+	if m.Broadcasts == 0 {
+		return 0
+	}
+	return float64(m.BroadcastRecipients) / float64(m.Broadcasts)
+}
+
+// hubMetrics holds the hub's live counters.
+type hubMetrics struct {
+	connects, disconnects   atomic.Int64
+	messagesIn, messagesOut atomic.Int64
+	dropped, rateLimited    atomic.Int64
+	broadcasts, recipients  atomic.Int64
+	maxFanout               atomic.Int64
+}
+
+// Metrics returns the hub's current counters.
+func (h *Hub) Metrics() Metrics {
+	m := &h.metrics
+	return Metrics{
+		ActiveSessions:      int64(h.SessionCount()),
+		Connects:            m.connects.Load(),
+		Disconnects:         m.disconnects.Load(),
+		MessagesIn:          m.messagesIn.Load(),
+		MessagesOut:         m.messagesOut.Load(),
+		Dropped:             m.dropped.Load(),
+		RateLimited:         m.rateLimited.Load(),
+		Broadcasts:          m.broadcasts.Load(),
+		BroadcastRecipients: m.recipients.Load(),
+		MaxFanout:           m.maxFanout.Load(),
+	}
+}
+
+// RegisterExpvar publishes the hub's Metrics as the expvar name, shown
+// by expvar.Handler, e.g. mounted at /debug/vars in a dev server. It
+// fails if name is already published.
+func (h *Hub) RegisterExpvar(name string) error {
+	if expvar.Get(name) != nil {
+		return fmt.Errorf("expvar %q already published", name)
+	}
+	expvar.Publish(name, expvar.Func(func() any { return h.Metrics() }))
+	return nil
+}
+
+// count records a send or drop traced by a session.
+func (m *hubMetrics) count(kind TraceKind) {
+	switch kind {
+	case TraceSend:
+		m.messagesOut.Add(1)
+	case TraceDrop:
+		m.dropped.Add(1)
+	}
+}
+
+// broadcast records a broadcast reaching n sessions.
+func (m *hubMetrics) broadcast(n int) {
+	m.broadcasts.Add(1)
+	m.recipients.Add(int64(n))
+	for {
+		cur := m.maxFanout.Load()
+		if int64(n) <= cur || m.maxFanout.CompareAndSwap(cur, int64(n)) {
+			return
+		}
+	}
+}
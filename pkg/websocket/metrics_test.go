@@ -0,0 +1,74 @@
+package websocket
+
+import (
+	"encoding/json"
+	"expvar"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	hub := NewHub()
+	hub.Handle("/ws/", echoHandler())
+	hub.SetBufferSize(2)
+
+	a, _ := hub.ConnectQueued("/ws/chat")
+	b, _ := hub.ConnectQueued("/ws/chat")
+	c, _ := hub.ConnectQueued("/ws/other")
+	hub.ConnectQueuedWithID(c.ID, "/ws/other") // Replaces c
+
+	hub.HandleMessage(a.ID, []byte(`{"type":"request"}`))
+	hub.HandleMessage(a.ID, []byte(`{"type":"request"}`))
+	hub.HandleMessage(b.ID, []byte(`not json`)) // Not counted
+
+	hub.Broadcast(NewEnvelope("all"))                   // 3 sessions
+	hub.BroadcastToURL("/ws/chat", NewEnvelope("chat")) // 2 sessions
+	hub.Send(a.ID, NewEnvelope("third"))                // a's buffer is full
+	hub.Join("room", b.ID)
+	hub.BroadcastToRoom("room", NewEnvelope("room")) // b's buffer is full
+
+	hub.Disconnect(b.ID)
+
+	want := Metrics{
+		ActiveSessions:      2,
+		Connects:            4,
+		Disconnects:         2, // The replaced c and b
+		MessagesIn:          2,
+		MessagesOut:         5,
+		Dropped:             2,
+		Broadcasts:          3,
+		BroadcastRecipients: 6,
+		MaxFanout:           3,
+	}
+	if got := hub.Metrics(); got != want {
+		t.Errorf("Metrics:\n got %+v\nwant %+v", got, want)
+	}
+	if avg := hub.Metrics().AvgFanout(); avg != 2 {
+		t.Errorf("AvgFanout = %v, want 2", avg)
+	}
+
+	hub.Close()
+	if m := hub.Metrics(); m.ActiveSessions != 0 || m.Connects-m.Disconnects != 0 {
+		t.Errorf("after Close: %+v", m)
+	}
+}
+
+func TestRegisterExpvar(t *testing.T) {
+	hub := NewHub()
+	hub.Handle("/ws/", echoHandler())
+	hub.Connect("/ws/chat")
+
+	if err := hub.RegisterExpvar("websocket_test"); err != nil {
+		t.Fatal(err)
+	}
+	if err := hub.RegisterExpvar("websocket_test"); err == nil {
+		t.Error("expected an error publishing the same name twice")
+	}
+
+	var m Metrics
+	if err := json.Unmarshal([]byte(expvar.Get("websocket_test").String()), &m); err != nil {
+		t.Fatal(err)
+	}
+	if m.ActiveSessions != 1 || m.Connects != 1 {
+		t.Errorf("published %+v", m)
+	}
+}
@@ -0,0 +1,131 @@
+package websocket
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// pattern is a parsed handler or broadcast URL pattern. A pattern ending
+// in "/" matches every URL under it; otherwise the URL must match it
+// exactly. Segments written as {name}, chi-style, match any one
+// non-empty path segment and capture it as a param:
+//
+//	/ws/chat            exactly /ws/chat
+//	/ws/                /ws/ and anything under it
+//	/ws/rooms/{roomID}  /ws/rooms/42, with roomID "42"
+type pattern struct {
+	raw      string
+	segments []string
+	prefix   bool
+}
+
+func parsePattern(raw string) *pattern {
+	p := &pattern{raw: raw, prefix: strings.HasSuffix(raw, "/")}
+	p.segments = strings.Split(strings.TrimSuffix(raw, "/"), "/")
+	return p
+}
+
+// param returns the name of a {name} segment.
+func param(segment string) (string, bool) {
+	if len(segment) > 2 && segment[0] == '{' && segment[len(segment)-1] == '}' {
+		return segment[1 : len(segment)-1], true
+	}
+	return "", false
+}
+
+// match reports whether url matches p, returning its params. url may be
+// a path or a full URL; a query string is ignored.
+func (p *pattern) match(url string) (map[string]string, bool) {
+	if params, ok := p.matchPath(url); ok {
+		return params, true
+	}
+	return p.matchPath(extractPath(url))
+}
+
+func (p *pattern) matchPath(path string) (map[string]string, bool) {
+	path, _, _ = strings.Cut(path, "?")
+	segments := strings.Split(path, "/")
+	if p.prefix {
+		// Prefixes end at a "/", so the path needs at least one more
+		// (possibly empty) segment
+		if len(segments) <= len(p.segments) {
+			return nil, false
+		}
+	} else if len(segments) != len(p.segments) {
+		return nil, false
+	}
+
+	var params map[string]string
+	for i, seg := range p.segments {
+		if name, ok := param(seg); ok {
+			if segments[i] == "" {
+				return nil, false
+			}
+			if params == nil {
+				params = make(map[string]string)
+			}
+			params[name] = segments[i]
+			continue
+		}
+		if seg != segments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// comparePatterns orders patterns most specific first: longer patterns,
+// then exact before prefix, then literal segments before params. Ties
+// are broken by the raw pattern so the order never depends on map
+// iteration.
+func comparePatterns(a, b *pattern) int {
+	if c := cmp.Compare(len(b.segments), len(a.segments)); c != 0 {
+		return c
+	}
+	if a.prefix != b.prefix {
+		if b.prefix {
+			return -1
+		}
+		return 1
+	}
+	for i := range a.segments {
+		_, aParam := param(a.segments[i])
+		_, bParam := param(b.segments[i])
+		if aParam != bParam {
+			if bParam {
+				return -1
+			}
+			return 1
+		}
+	}
+	return strings.Compare(a.raw, b.raw)
+}
+
+// sortPatterns rebuilds h.patterns from h.handlers. The caller must hold
+// handlersMu for writing.
+func (h *Hub) sortPatterns() {
+	h.patterns = h.patterns[:0]
+	for raw := range h.handlers {
+		h.patterns = append(h.patterns, parsePattern(raw))
+	}
+	slices.SortFunc(h.patterns, comparePatterns)
+}
+
+// findHandler returns the handler for url, from the most specific
+// matching pattern, and the params it captured. The caller must hold
+// handlersMu.
+func (h *Hub) findHandler(url string) (MessageHandler, map[string]string) {
+	for _, p := range h.patterns {
+		if params, ok := p.match(url); ok {
+			return h.handlers[p.raw], params
+		}
+	}
+	return nil, nil
+}
+
+// matchURL reports whether url matches a handler-style pattern.
+func (h *Hub) matchURL(url, urlPattern string) bool {
+	_, ok := parsePattern(urlPattern).match(url)
+	return ok
+}
@@ -0,0 +1,120 @@
+package websocket
+
+import (
+	"maps"
+	"testing"
+)
+
+func namedHandler(name string) MessageHandler {
+	return MessageHandlerFunc(func(s *Session, req *Request) (*Envelope, error) {
+		return NewEnvelope(name), nil
+	})
+}
+
+func TestFindHandler(t *testing.T) {
+	hub := NewHub()
+	for _, p := range []string{
+		"/ws/",
+		"/ws/chat/",
+		"/ws/chat",
+		"/ws/rooms/{roomID}",
+		"/ws/rooms/lobby",
+		"/ws/rooms/{roomID}/",
+		"/ws/orgs/{org}/rooms/{room}",
+	} {
+		hub.Handle(p, namedHandler(p))
+	}
+
+	tests := []struct {
+		url    string
+		want   string
+		params map[string]string
+	}{
+		{"/ws/chat", "/ws/chat", nil},
+		{"/ws/chat/general", "/ws/chat/", nil},
+		{"/ws/chatter", "/ws/", nil},
+		{"/ws/other/deep/path", "/ws/", nil},
+		{"/ws/rooms/42", "/ws/rooms/{roomID}", map[string]string{"roomID": "42"}},
+		{"/ws/rooms/lobby", "/ws/rooms/lobby", nil},
+		{"/ws/rooms/42/thread", "/ws/rooms/{roomID}/", map[string]string{"roomID": "42"}},
+		{"/ws/rooms/", "/ws/", nil}, // Params never match empty segments
+		{"/ws/orgs/acme/rooms/7", "/ws/orgs/{org}/rooms/{room}", map[string]string{"org": "acme", "room": "7"}},
+		{"ws://localhost/ws/rooms/42?token=x", "/ws/rooms/{roomID}", map[string]string{"roomID": "42"}},
+		{"/api/ws", "", nil},
+		{"/ws", "", nil},
+	}
+	// Map iteration order varies, so repeat to catch order dependence
+	for range 20 {
+		for _, tt := range tests {
+			handler, params := hub.findHandler(tt.url)
+			got := ""
+			if handler != nil {
+				env, _ := handler.OnMessage(nil, &Request{})
+				got = env.Payload
+			}
+			if got != tt.want || !maps.Equal(params, tt.params) {
+				t.Fatalf("%s:\n got %q %v\nwant %q %v", tt.url, got, params, tt.want, tt.params)
+			}
+		}
+	}
+
+	hub.Unhandle("/ws/chat/")
+	if handler, _ := hub.findHandler("/ws/chat/general"); handler == nil {
+		t.Error("expected /ws/ to match after unhandling /ws/chat/")
+	}
+}
+
+func TestSessionParams(t *testing.T) {
+	hub := NewHub()
+	var got map[string]string
+	hub.HandleFunc("/ws/rooms/{roomID}", func(s *Session, req *Request) (*Envelope, error) {
+		got = req.PathParams
+		return nil, nil
+	})
+
+	s, err := hub.Connect("/ws/rooms/42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Param("roomID") != "42" || s.Param("missing") != "" {
+		t.Errorf("Param(roomID) = %q, Param(missing) = %q", s.Param("roomID"), s.Param("missing"))
+	}
+	if !maps.Equal(s.Params(), map[string]string{"roomID": "42"}) {
+		t.Errorf("Params() = %v", s.Params())
+	}
+
+	if _, err := hub.HandleMessage(s.ID, []byte(`{"type":"request","event":"click"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if got["roomID"] != "42" {
+		t.Errorf("req.PathParams = %v", got)
+	}
+	// Handlers can't change the session's params through the request
+	got["roomID"] = "x"
+	if s.Param("roomID") != "42" {
+		t.Error("req.PathParams aliases the session's params")
+	}
+
+	// A client can't set them either
+	hub.HandleMessage(s.ID, []byte(`{"type":"request","PathParams":{"roomID":"evil"}}`))
+	if got["roomID"] != "42" {
+		t.Errorf("req.PathParams = %v, set by the client", got)
+	}
+}
+
+func TestMatchURLParams(t *testing.T) {
+	hub := NewHub()
+	hub.Handle("/ws/", echoHandler())
+
+	in, _ := hub.ConnectQueued("/ws/rooms/1/feed")
+	other, _ := hub.ConnectQueued("/ws/rooms/2/feed")
+	out, _ := hub.ConnectQueued("/ws/rooms/1")
+
+	hub.BroadcastToURL("/ws/rooms/{roomID}/", NewEnvelope("x"))
+	if in.Queued() != 1 || other.Queued() != 1 || out.Queued() != 0 {
+		t.Errorf("queued %d %d %d, want 1 1 0", in.Queued(), other.Queued(), out.Queued())
+	}
+	if n := len(hub.SessionsForURL("/ws/rooms/{roomID}")); n != 1 {
+		t.Errorf("expected 1 session for the exact pattern, got %d", n)
+	}
+}
@@ -0,0 +1,244 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ConsumerMode selects how a session's outgoing envelopes are read. It is
+// fixed when the session connects.
+type ConsumerMode int
+
+const (
+	// ChannelMode delivers envelopes on Session.SendChan (the default).
+	ChannelMode ConsumerMode = iota
+
+	// QueueMode keeps envelopes in a pull queue read with Session.Next and
+	// acknowledged with Session.Ack. SendChan is never written. Envelopes
+	// read but not acknowledged are replayed when the session reconnects
+	// with the same ID.
+	QueueMode
+)
+
+// ErrConsumerMode is returned when a session is read, or reconnected, in a
+// consumer mode other than the one it was connected with.
+var ErrConsumerMode = errors.New("websocket session consumer mode mismatch")
+
+// envelopeQueue is the pull queue behind QueueMode sessions.
+type envelopeQueue struct {
+	mu       sync.Mutex
+	ready    []*Envelope // Not yet read
+	inflight []*Envelope // Read but not acknowledged
+	seq      int64
+	limit    int // Bounds ready, like the SendChan buffer
+	closed   bool
+
+	signal chan struct{} // Wakes a waiting Next after push
+	space  chan struct{} // Wakes a waiting pushWait after pop
+	done   chan struct{} // Closed with the session
+}
+
+func newEnvelopeQueue(limit int) *envelopeQueue {
+	return &envelopeQueue{
+		limit:  limit,
+		signal: make(chan struct{}, 1),
+		space:  make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+}
+
+// push queues a copy of envelope with the next sequence number; the
+// original may be shared by a broadcast.
+func (q *envelopeQueue) push(envelope *Envelope) bool {
+	ok, _ := q.tryPush(envelope)
+	return ok
+}
+
+// tryPush is push, also reporting whether the queue is closed.
+func (q *envelopeQueue) tryPush(envelope *Envelope) (ok, closed bool) {
+	q.mu.Lock()
+	if q.closed || len(q.ready) >= q.limit {
+		closed := q.closed
+		q.mu.Unlock()
+		return false, closed
+	}
+	q.seq++
+	e := *envelope
+	e.Seq = q.seq
+	q.ready = append(q.ready, &e)
+	room := len(q.ready) < q.limit
+	q.mu.Unlock()
+
+	wake(q.signal)
+	if room {
+		// Pass on a wakeup this push may have consumed
+		wake(q.space)
+	}
+	return true, false
+}
+
+// pushWait is push, waiting for room in a full queue until ctx is done
+// or the queue closes.
+func (q *envelopeQueue) pushWait(ctx context.Context, envelope *Envelope) error {
+	for {
+		ok, closed := q.tryPush(envelope)
+		if ok {
+			return nil
+		}
+		if closed {
+			return ErrSessionClosed
+		}
+		select {
+		case <-q.space:
+		case <-q.done:
+			return ErrSessionClosed
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// wake signals c without blocking.
+func wake(c chan struct{}) {
+	select {
+	case c <- struct{}{}:
+	default:
+	}
+}
+
+// pop returns the oldest unread envelope, moving it in flight.
+func (q *envelopeQueue) pop() (*Envelope, bool) {
+	q.mu.Lock()
+	if len(q.ready) == 0 {
+		closed := q.closed
+		q.mu.Unlock()
+		return nil, closed
+	}
+	e := q.ready[0]
+	q.ready[0] = nil
+	q.ready = q.ready[1:]
+	q.inflight = append(q.inflight, e)
+	q.mu.Unlock()
+
+	wake(q.space)
+	return e, false
+}
+
+func (q *envelopeQueue) next(timeout time.Duration) (*Envelope, error) {
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	for {
+		e, closed := q.pop()
+		if e != nil {
+			return e, nil
+		}
+		if closed {
+			return nil, ErrSessionClosed
+		}
+		if expired == nil {
+			return nil, nil
+		}
+		select {
+		case <-q.signal:
+		case <-q.done:
+		case <-expired:
+			return nil, nil
+		}
+	}
+}
+
+func (q *envelopeQueue) ack(seq int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := 0
+	for n < len(q.inflight) && q.inflight[n].Seq <= seq {
+		n++
+	}
+	q.inflight = q.inflight[n:]
+}
+
+func (q *envelopeQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.ready)
+}
+
+// adopt takes over old's unacknowledged and unread envelopes, in order,
+// so a reconnecting client sees them again.
+func (q *envelopeQueue) adopt(old *envelopeQueue) {
+	old.mu.Lock()
+	replay := append(old.inflight, old.ready...)
+	seq := old.seq
+	old.inflight, old.ready = nil, nil
+	old.mu.Unlock()
+
+	q.mu.Lock()
+	q.ready = append(replay, q.ready...)
+	if seq > q.seq {
+		q.seq = seq
+	}
+	q.mu.Unlock()
+}
+
+func (q *envelopeQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.closed {
+		q.closed = true
+		close(q.done)
+	}
+}
+
+// Mode returns the session's consumer mode.
+func (s *Session) Mode() ConsumerMode {
+	if s.queue != nil {
+		return QueueMode
+	}
+	return ChannelMode
+}
+
+// Next returns the oldest unread envelope of a QueueMode session, waiting
+// up to timeout for one to arrive (not at all if timeout <= 0). It returns
+// nil on timeout, and ErrSessionClosed once the session is closed and
+// drained. The envelope stays buffered for replay until acknowledged
+// with Ack.
+func (s *Session) Next(timeout time.Duration) (*Envelope, error) {
+	if s.queue == nil {
+		return nil, ErrConsumerMode
+	}
+	return s.queue.next(timeout)
+}
+
+// Ack acknowledges every envelope read with Next up to and including seq,
+// releasing them from the replay buffer.
+func (s *Session) Ack(seq int64) error {
+	if s.queue == nil {
+		return ErrConsumerMode
+	}
+	s.queue.ack(seq)
+	return nil
+}
+
+// Queued returns the number of unread envelopes of a QueueMode session.
+func (s *Session) Queued() int {
+	if s.queue == nil {
+		return 0
+	}
+	return s.queue.len()
+}
+
+// QueueDepth returns the number of envelopes waiting to be read, from
+// SendChan or the pull queue depending on the session's mode.
+func (s *Session) QueueDepth() int {
+	if s.queue != nil {
+		return s.queue.len()
+	}
+	return len(s.SendChan)
+}
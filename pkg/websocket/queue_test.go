@@ -0,0 +1,211 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQueueOrderAndSeq(t *testing.T) {
+	hub := NewHub()
+	hub.Handle("/ws", echoHandler())
+	s, err := hub.ConnectQueued("/ws")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shared := NewEnvelope("broadcast")
+	for _, p := range []string{"a", "b", "c"} {
+		if !s.Send(NewEnvelope(p)) {
+			t.Fatalf("send %s failed", p)
+		}
+	}
+	hub.Broadcast(shared)
+	if s.Queued() != 4 {
+		t.Fatalf("Queued() = %d, want 4", s.Queued())
+	}
+	if shared.Seq != 0 {
+		t.Error("broadcast envelope was modified")
+	}
+
+	for i, want := range []string{"a", "b", "c", "broadcast"} {
+		e, err := s.Next(0)
+		if err != nil || e == nil {
+			t.Fatalf("Next() = %v, %v", e, err)
+		}
+		if e.Payload != want || e.Seq != int64(i+1) {
+			t.Errorf("got %q seq %d, want %q seq %d", e.Payload, e.Seq, want, i+1)
+		}
+	}
+	if s.Queued() != 0 {
+		t.Errorf("Queued() = %d after draining", s.Queued())
+	}
+}
+
+func TestQueueNextTimeout(t *testing.T) {
+	hub := NewHub()
+	hub.Handle("/ws", echoHandler())
+	s, _ := hub.ConnectQueued("/ws")
+
+	if e, err := s.Next(0); e != nil || err != nil {
+		t.Fatalf("non-blocking Next() = %v, %v", e, err)
+	}
+
+	start := time.Now()
+	if e, err := s.Next(30 * time.Millisecond); e != nil || err != nil {
+		t.Fatalf("Next() = %v, %v, want timeout", e, err)
+	}
+	if time.Since(start) < 25*time.Millisecond {
+		t.Error("Next returned before the timeout")
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		s.SendHTML("#x", "late")
+	}()
+	e, err := s.Next(time.Second)
+	if err != nil || e == nil || e.Payload != "late" {
+		t.Fatalf("Next() = %v, %v, want late envelope", e, err)
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		s.Close()
+	}()
+	if _, err := s.Next(time.Second); !errors.Is(err, ErrSessionClosed) {
+		t.Errorf("Next() after close err = %v, want ErrSessionClosed", err)
+	}
+}
+
+func TestQueueAckAndReplay(t *testing.T) {
+	hub := NewHub()
+	hub.Handle("/ws", echoHandler())
+	s, err := hub.ConnectQueuedWithID("abc", "/ws")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"a", "b", "c", "d"} {
+		s.Send(NewEnvelope(p))
+	}
+	s.Next(0) // a
+	b, _ := s.Next(0)
+	s.Next(0) // c, read but not acknowledged
+	s.Ack(b.Seq)
+
+	// WebView reloads and reconnects with the same ID
+	s2, err := hub.ConnectQueuedWithID("abc", "/ws")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.IsClosed() {
+		t.Error("old session not closed")
+	}
+	s2.Send(NewEnvelope("e"))
+
+	var got []string
+	var seqs []int64
+	for {
+		e, _ := s2.Next(0)
+		if e == nil {
+			break
+		}
+		got = append(got, e.Payload)
+		seqs = append(seqs, e.Seq)
+	}
+	if want := "c,d,e"; strings.Join(got, ",") != want {
+		t.Errorf("replayed %v, want %s", got, want)
+	}
+	if seqs[len(seqs)-1] != 5 {
+		t.Errorf("seqs = %v, want numbering to continue", seqs)
+	}
+}
+
+func TestQueueModeMismatch(t *testing.T) {
+	hub := NewHub()
+	hub.Handle("/ws", echoHandler())
+
+	ch, _ := hub.ConnectWithID("chan", "/ws")
+	if _, err := ch.Next(0); !errors.Is(err, ErrConsumerMode) {
+		t.Errorf("Next on channel session err = %v", err)
+	}
+	if err := ch.Ack(1); !errors.Is(err, ErrConsumerMode) {
+		t.Errorf("Ack on channel session err = %v", err)
+	}
+	if _, err := hub.ConnectQueuedWithID("chan", "/ws"); !errors.Is(err, ErrConsumerMode) {
+		t.Errorf("switching to queue mode err = %v", err)
+	}
+	if ch.IsClosed() {
+		t.Error("rejected reconnect closed the existing session")
+	}
+
+	hub.ConnectQueuedWithID("queue", "/ws")
+	if _, err := hub.ConnectWithID("queue", "/ws"); !errors.Is(err, ErrConsumerMode) {
+		t.Errorf("switching to channel mode err = %v", err)
+	}
+	if s, _ := hub.GetSession("queue"); s.Mode() != QueueMode {
+		t.Error("queued session replaced")
+	}
+}
+
+func TestQueueDepth(t *testing.T) {
+	hub := NewHub()
+	hub.Handle("/ws", echoHandler())
+	ch, _ := hub.Connect("/ws")
+	q, _ := hub.ConnectQueued("/ws")
+
+	for _, s := range []*Session{ch, q} {
+		s.Send(NewEnvelope("a"))
+		s.Send(NewEnvelope("b"))
+		if got := s.QueueDepth(); got != 2 {
+			t.Errorf("mode %d: QueueDepth() = %d, want 2", s.Mode(), got)
+		}
+	}
+}
+
+func TestQueueSendContext(t *testing.T) {
+	hub := NewHub()
+	hub.Handle("/ws", echoHandler())
+	hub.SetBufferSize(2)
+
+	var dropped int
+	hub.OnDrop(func(*Session, *Envelope) { dropped++ })
+
+	s, _ := hub.ConnectQueued("/ws")
+	s.Send(NewEnvelope("a"))
+	s.Send(NewEnvelope("b"))
+	if s.Send(NewEnvelope("c")) || dropped != 1 {
+		t.Errorf("expected Send on a full queue to drop and report, got %d drops", dropped)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := s.SendContext(ctx, NewEnvelope("late")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("SendContext on a full queue err = %v, want DeadlineExceeded", err)
+	}
+
+	// Two blocked senders; each read makes room for one
+	sent := make(chan error, 2)
+	for range 2 {
+		go func() { sent <- s.SendContext(context.Background(), NewEnvelope("c")) }()
+	}
+	time.Sleep(10 * time.Millisecond)
+	s.Next(0)
+	s.Next(0)
+	for range 2 {
+		if err := <-sent; err != nil {
+			t.Fatalf("SendContext err = %v", err)
+		}
+	}
+	if s.Queued() != 2 {
+		t.Errorf("expected 2 queued, got %d", s.Queued())
+	}
+
+	go func() { sent <- s.SendContext(context.Background(), NewEnvelope("d")) }()
+	time.Sleep(10 * time.Millisecond)
+	s.Close()
+	if err := <-sent; !errors.Is(err, ErrSessionClosed) {
+		t.Errorf("blocked SendContext err = %v, want ErrSessionClosed", err)
+	}
+}
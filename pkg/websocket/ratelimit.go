@@ -0,0 +1,139 @@
+package websocket
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is returned by HandleMessage when a session, or the hub
+// as a whole, is over its message rate limit (see SetMessageRateLimit).
+var ErrRateLimited = errors.New("websocket message rate limit exceeded")
+
+// ClosePolicyViolation is the reason for sessions disconnected for
+// exceeding their message rate limit too often.
+var ClosePolicyViolation = CloseReason{Code: 1008, Text: "Too many messages"}
+
+// RateLimitPolicy says what happens to a session's messages beyond its
+// rate limit, besides HandleMessage returning ErrRateLimited.
+type RateLimitPolicy struct {
+	// Reply sends an ErrorEnvelope with status 429 for each rejected
+	// message.
+	Reply bool
+
+	// DisconnectAfter disconnects a session with ClosePolicyViolation
+	// once this many of its messages in a row are rejected; 0 never does.
+	DisconnectAfter int
+}
+
+// SetMessageRateLimit limits each session to perSecond client messages
+// on average, with bursts of up to burst. perSecond <= 0 removes the
+// limit, the default. It applies to sessions connected after the call.
+// Replies to Session.Request aren't limited.
+func (h *Hub) SetMessageRateLimit(perSecond float64, burst int) {
+	h.limit = rateLimit{perSecond: perSecond, burst: burst}
+}
+
+// SetHubMessageRateLimit limits client messages across all sessions,
+// e.g. to protect a shared backend. Rejections here don't count toward
+// a session's DisconnectAfter. perSecond <= 0 removes the limit.
+func (h *Hub) SetHubMessageRateLimit(perSecond float64, burst int) {
+	h.hubLimiter = rateLimit{perSecond: perSecond, burst: burst}.bucket()
+}
+
+// SetRateLimitPolicy sets what happens to rate limited messages.
+func (h *Hub) SetRateLimitPolicy(policy RateLimitPolicy) {
+	h.ratePolicy = policy
+}
+
+type rateLimit struct {
+	perSecond float64
+	burst     int
+}
+
+// bucket returns a full token bucket for the limit, or nil if there is
+// no limit.
+func (l rateLimit) bucket() *tokenBucket {
+	if l.perSecond <= 0 {
+		return nil
+	}
+	burst := float64(max(l.burst, 1))
+	return &tokenBucket{rate: l.perSecond, burst: burst, tokens: burst, last: timeNow()}
+}
+
+// tokenBucket refills at rate tokens a second, up to burst.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// allow takes a token if one is available.
+func (b *tokenBucket) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := timeNow()
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = min(b.burst, b.tokens+elapsed*b.rate)
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// admit applies the session and hub rate limits to a client message,
+// returning ErrRateLimited and carrying out the policy if it is over.
+func (s *Session) admit(req *Request) error {
+	h := s.hub
+	if h == nil {
+		return nil
+	}
+
+	if s.limiter != nil && !s.limiter.allow() {
+		h.metrics.rateLimited.Add(1)
+		violations := s.violations.Add(1)
+		if after := h.ratePolicy.DisconnectAfter; after > 0 && violations >= int64(after) {
+			h.evict(s, ClosePolicyViolation)
+			return ErrRateLimited
+		}
+		s.rejectMessage(req)
+		return ErrRateLimited
+	}
+	if h.hubLimiter != nil && !h.hubLimiter.allow() {
+		h.metrics.rateLimited.Add(1)
+		s.rejectMessage(req)
+		return ErrRateLimited
+	}
+	s.violations.Store(0)
+	return nil
+}
+
+func (s *Session) rejectMessage(req *Request) {
+	if s.hub.ratePolicy.Reply {
+		s.Send(ErrorEnvelope(req.RequestID, http.StatusTooManyRequests, ClosePolicyViolation.Text))
+	}
+}
+
+// evict closes and removes a session for misbehaving, unless it has
+// already been replaced. Unlike Disconnect it keeps no replay buffer.
+func (h *Hub) evict(s *Session, reason CloseReason) {
+	h.sessionsMu.Lock()
+	current := h.sessions[s.ID] == s
+	if current {
+		delete(h.sessions, s.ID)
+	}
+	h.sessionsMu.Unlock()
+
+	s.CloseWithReason(reason)
+	if current {
+		h.metrics.disconnects.Add(1)
+		h.forget(s.ID)
+		h.onSessionDestroyed.run(s)
+	}
+}
@@ -0,0 +1,159 @@
+package websocket
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestMessageRateLimit(t *testing.T) {
+	now := time.Now()
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	hub := NewHub()
+	hub.Handle("/ws/", echoHandler())
+	hub.SetMessageRateLimit(2, 3)
+	s, _ := hub.Connect("/ws/chat")
+	other, _ := hub.Connect("/ws/chat")
+
+	msg := []byte(`{"type":"request"}`)
+	for i := 0; i < 3; i++ {
+		if _, err := s.HandleMessage(msg); err != nil {
+			t.Fatalf("message %d err = %v, want nil within the burst", i, err)
+		}
+	}
+	if _, err := s.HandleMessage(msg); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("HandleMessage past the burst err = %v, want ErrRateLimited", err)
+	}
+
+	// Other sessions have their own bucket
+	if _, err := other.HandleMessage(msg); err != nil {
+		t.Errorf("other session err = %v, want nil", err)
+	}
+
+	// Tokens refill at the rate, up to the burst
+	now = now.Add(500 * time.Millisecond)
+	if _, err := s.HandleMessage(msg); err != nil {
+		t.Errorf("HandleMessage after refilling err = %v, want nil", err)
+	}
+	if _, err := s.HandleMessage(msg); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("HandleMessage after one token err = %v, want ErrRateLimited", err)
+	}
+	now = now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		if _, err := s.HandleMessage(msg); err != nil {
+			t.Fatalf("message %d after an hour err = %v, want nil", i, err)
+		}
+	}
+	if _, err := s.HandleMessage(msg); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("HandleMessage past a refilled burst err = %v, want ErrRateLimited", err)
+	}
+
+	// Replies to Session.Request aren't limited
+	if _, err := s.HandleMessage([]byte(`{"type":"response","request_id":"srv_x"}`)); err != nil {
+		t.Errorf("response err = %v, want nil", err)
+	}
+
+	if got := hub.Metrics().RateLimited; got != 3 {
+		t.Errorf("RateLimited = %d, want 3", got)
+	}
+	if s.IsClosed() {
+		t.Error("expected session to stay open without a DisconnectAfter policy")
+	}
+}
+
+func TestRateLimitPolicy(t *testing.T) {
+	now := time.Now()
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	hub := NewHub()
+	hub.Handle("/ws/", echoHandler())
+	hub.SetMessageRateLimit(1, 1)
+	hub.SetRateLimitPolicy(RateLimitPolicy{Reply: true, DisconnectAfter: 3})
+	s, _ := hub.Connect("/ws/chat")
+
+	msg := func(id string) []byte {
+		return []byte(`{"type":"request","request_id":"` + id + `"}`)
+	}
+	if _, err := s.HandleMessage(msg("a")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Rejected messages get an error reply
+	if _, err := s.HandleMessage(msg("b")); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("err = %v, want ErrRateLimited", err)
+	}
+	env := <-s.SendChan
+	if env.Type != TypeError || env.RequestID != "b" {
+		t.Errorf("reply = %+v, want an error for request b", env)
+	}
+	var payload struct{ Status int }
+	if err := json.Unmarshal([]byte(env.Payload), &payload); err != nil || payload.Status != http.StatusTooManyRequests {
+		t.Errorf("reply payload = %s, want status 429", env.Payload)
+	}
+
+	// An accepted message resets the count of violations
+	if _, err := s.HandleMessage(msg("c")); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("err = %v, want ErrRateLimited", err)
+	}
+	now = now.Add(time.Second)
+	if _, err := s.HandleMessage(msg("d")); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"e", "f"} {
+		if _, err := s.HandleMessage(msg(id)); !errors.Is(err, ErrRateLimited) {
+			t.Fatalf("err = %v, want ErrRateLimited", err)
+		}
+	}
+	if s.IsClosed() {
+		t.Fatal("expected session open after two violations in a row")
+	}
+
+	// The third in a row disconnects
+	if _, err := s.HandleMessage(msg("g")); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("err = %v, want ErrRateLimited", err)
+	}
+	if !s.IsClosed() {
+		t.Fatal("expected session closed after three violations in a row")
+	}
+	if got := s.CloseReason(); got != ClosePolicyViolation {
+		t.Errorf("CloseReason = %+v, want ClosePolicyViolation", got)
+	}
+	if _, ok := hub.GetSession(s.ID); ok {
+		t.Error("expected session removed from the hub")
+	}
+}
+
+func TestHubMessageRateLimit(t *testing.T) {
+	now := time.Now()
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	hub := NewHub()
+	hub.Handle("/ws/", echoHandler())
+	hub.SetHubMessageRateLimit(1, 2)
+	hub.SetRateLimitPolicy(RateLimitPolicy{DisconnectAfter: 1})
+	a, _ := hub.Connect("/ws/chat")
+	b, _ := hub.Connect("/ws/chat")
+
+	msg := []byte(`{"type":"request"}`)
+	for _, s := range []*Session{a, b} {
+		if _, err := s.HandleMessage(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := a.HandleMessage(msg); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("err = %v, want ErrRateLimited once the hub's burst is used", err)
+	}
+	if a.IsClosed() {
+		t.Error("expected hub-wide rejections not to disconnect")
+	}
+	now = now.Add(time.Second)
+	if _, err := b.HandleMessage(msg); err != nil {
+		t.Errorf("HandleMessage after refilling err = %v, want nil", err)
+	}
+}
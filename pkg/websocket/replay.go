@@ -0,0 +1,214 @@
+package websocket
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// timeNow is replaced in tests.
+var timeNow = time.Now
+
+// replayMaxBytes caps the payload bytes held for replay across all
+// offline sessions; the oldest buffers are dropped beyond it.
+const replayMaxBytes = 4 << 20
+
+// replayStore holds envelopes for sessions that disconnected, until they
+// reconnect with the same ID or their window passes (see EnableReplay).
+type replayStore struct {
+	mu       sync.Mutex
+	max      int
+	window   time.Duration
+	maxBytes int64
+	bytes    int64
+	buffers  map[string]*replayBuffer // Session ID → buffer
+}
+
+type replayBuffer struct {
+	url       string
+	envelopes []*Envelope
+	bytes     int64
+	expires   time.Time
+}
+
+// EnableReplay keeps up to maxMessages envelopes for each session that
+// disconnects, for window: the ones it hadn't yet delivered, then those
+// sent to it with Send or SendContext, Broadcast or BroadcastToURL while
+// it was offline. A session reconnecting with ConnectWithID (or
+// ConnectQueuedWithID) gets them first, in order, marked Replayed, up to
+// its buffer size. maxMessages <= 0 disables replay, the default. Call
+// it before connecting sessions.
+func (h *Hub) EnableReplay(maxMessages int, window time.Duration) {
+	if maxMessages <= 0 {
+		h.replay = nil
+		return
+	}
+	h.replay = &replayStore{
+		max:      maxMessages,
+		window:   window,
+		maxBytes: replayMaxBytes,
+		buffers:  make(map[string]*replayBuffer),
+	}
+}
+
+// open starts a buffer for a session that just disconnected. Called with
+// sessionsMu held, so no send finds neither the session nor its buffer.
+func (r *replayStore) open(s *Session) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.drop(s.ID)
+	r.buffers[s.ID] = &replayBuffer{url: s.URL, expires: timeNow().Add(r.window)}
+}
+
+// hold puts a closed session's undelivered envelopes ahead of anything
+// sent since it disconnected.
+func (r *replayStore) hold(s *Session) {
+	var undelivered []*Envelope
+	if s.queue != nil {
+		q := s.queue
+		q.mu.Lock()
+		undelivered = append(append(undelivered, q.inflight...), q.ready...)
+		q.mu.Unlock()
+	} else {
+		// Closed, so this stops once it's empty
+		for e := range s.SendChan {
+			undelivered = append(undelivered, e)
+		}
+	}
+	if len(undelivered) == 0 {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b := r.buffers[s.ID]
+	if b == nil {
+		return
+	}
+	for _, e := range undelivered {
+		b.bytes += envelopeSize(e)
+		r.bytes += envelopeSize(e)
+	}
+	b.envelopes = append(undelivered, b.envelopes...)
+	r.trim(b)
+}
+
+// add buffers an envelope for an offline session, reporting whether it
+// had a buffer.
+func (r *replayStore) add(sessionID string, envelope *Envelope) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.prune()
+	b := r.buffers[sessionID]
+	if b == nil {
+		return false
+	}
+	r.push(b, envelope)
+	return true
+}
+
+// broadcast buffers an envelope for every offline session whose URL
+// matches, or all of them if match is nil.
+func (r *replayStore) broadcast(envelope *Envelope, match func(url string) bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.prune()
+	for _, b := range r.buffers {
+		if match == nil || match(b.url) {
+			r.push(b, envelope)
+		}
+	}
+}
+
+// take removes and returns the envelopes buffered for a session ID.
+func (r *replayStore) take(sessionID string) []*Envelope {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.prune()
+	b := r.buffers[sessionID]
+	if b == nil {
+		return nil
+	}
+	r.drop(sessionID)
+	return b.envelopes
+}
+
+// push appends to b, then trims. Must be called with r.mu held.
+func (r *replayStore) push(b *replayBuffer, envelope *Envelope) {
+	b.envelopes = append(b.envelopes, envelope)
+	b.bytes += envelopeSize(envelope)
+	r.bytes += envelopeSize(envelope)
+	r.trim(b)
+}
+
+// trim drops b's oldest envelopes beyond max, then whole buffers, those
+// expiring first, beyond maxBytes. Must be called with r.mu held.
+func (r *replayStore) trim(b *replayBuffer) {
+	if n := len(b.envelopes) - r.max; n > 0 {
+		for _, e := range b.envelopes[:n] {
+			b.bytes -= envelopeSize(e)
+			r.bytes -= envelopeSize(e)
+		}
+		b.envelopes = slices.Clone(b.envelopes[n:])
+	}
+	for r.bytes > r.maxBytes && len(r.buffers) > 0 {
+		var oldest string
+		for id, other := range r.buffers {
+			if oldest == "" || other.expires.Before(r.buffers[oldest].expires) {
+				oldest = id
+			}
+		}
+		r.drop(oldest)
+	}
+}
+
+// prune drops expired buffers. Must be called with r.mu held.
+func (r *replayStore) prune() {
+	now := timeNow()
+	for id, b := range r.buffers {
+		if now.After(b.expires) {
+			r.drop(id)
+		}
+	}
+}
+
+// drop removes a buffer. Must be called with r.mu held.
+func (r *replayStore) drop(sessionID string) {
+	if b, ok := r.buffers[sessionID]; ok {
+		r.bytes -= b.bytes
+		delete(r.buffers, sessionID)
+	}
+}
+
+// envelopeSize approximates the memory an envelope holds.
+func envelopeSize(e *Envelope) int64 {
+	return int64(len(e.Payload) + len(e.Binary) + len(e.Target) + len(e.Swap) + len(e.Channel) + len(e.RequestID) + len(e.TraceID))
+}
+
+// replayTo queues a reconnecting session's buffered envelopes, before it
+// is registered and can receive anything live. Called with sessionsMu
+// held, so it doesn't run callbacks like OnDrop.
+func (h *Hub) replayTo(s *Session) {
+	if h.replay == nil {
+		return
+	}
+	for _, e := range h.replay.take(s.ID) {
+		replayed := *e
+		replayed.Replayed = true
+		s.trySend(&replayed)
+	}
+}
+
+// holdForReplay buffers an envelope sent to a session that is offline,
+// reporting whether it had a replay buffer.
+func (h *Hub) holdForReplay(sessionID string, envelope *Envelope) bool {
+	if h.replay == nil {
+		return false
+	}
+	h.sessionsMu.RLock()
+	defer h.sessionsMu.RUnlock()
+	if _, ok := h.sessions[sessionID]; ok {
+		return false // Reconnected since the caller looked
+	}
+	return h.replay.add(sessionID, envelope)
+}
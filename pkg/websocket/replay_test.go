@@ -0,0 +1,149 @@
+package websocket
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// drain reads a ChannelMode session's buffered payloads, marking
+// replayed ones with a trailing "*".
+func drain(s *Session) []string {
+	var got []string
+	for {
+		select {
+		case e, ok := <-s.SendChan:
+			if !ok {
+				return got
+			}
+			if e.Replayed {
+				got = append(got, e.Payload+"*")
+			} else {
+				got = append(got, e.Payload)
+			}
+		default:
+			return got
+		}
+	}
+}
+
+func TestReplay(t *testing.T) {
+	hub := NewHub()
+	hub.Handle("/ws/", echoHandler())
+	hub.EnableReplay(10, time.Minute)
+
+	s, _ := hub.ConnectWithID("ws_tab", "/ws/feed")
+	other, _ := hub.Connect("/ws/other")
+	hub.Send(s.ID, NewEnvelope("a")) // Never read before the disconnect
+
+	hub.Disconnect(s.ID)
+	for _, p := range []string{"b", "c"} {
+		if err := hub.Send(s.ID, NewEnvelope(p)); err != nil {
+			t.Fatalf("Send to an offline session: %v", err)
+		}
+	}
+	hub.Broadcast(NewEnvelope("d"))
+	hub.BroadcastToURL("/ws/feed", NewEnvelope("e"))
+	hub.BroadcastToURL("/ws/other", NewEnvelope("not for feed"))
+
+	s, _ = hub.ConnectWithID("ws_tab", "/ws/feed")
+	hub.Send(s.ID, NewEnvelope("live"))
+	if got := strings.Join(drain(s), ","); got != "a*,b*,c*,d*,e*,live" {
+		t.Errorf("got %s", got)
+	}
+	if got := strings.Join(drain(other), ","); got != "d,not for feed" {
+		t.Errorf("other session got %s", got)
+	}
+
+	// Replayed once only
+	hub.Disconnect(s.ID)
+	s, _ = hub.ConnectWithID("ws_tab", "/ws/feed")
+	if got := drain(s); len(got) != 0 {
+		t.Errorf("expected nothing replayed twice, got %v", got)
+	}
+
+	// Sessions that never connected have nothing to replay
+	if err := hub.Send("ws_unknown", NewEnvelope("x")); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Send to unknown session err = %v", err)
+	}
+}
+
+func TestReplayQueued(t *testing.T) {
+	hub := NewHub()
+	hub.Handle("/ws/", echoHandler())
+	hub.EnableReplay(10, time.Minute)
+
+	s, _ := hub.ConnectQueuedWithID("ws_tab", "/ws/feed")
+	hub.Send(s.ID, NewEnvelope("a"))
+	hub.Send(s.ID, NewEnvelope("b"))
+	s.Next(0) // Read but not acknowledged
+	hub.Disconnect(s.ID)
+	hub.Send(s.ID, NewEnvelope("c"))
+
+	s, _ = hub.ConnectQueuedWithID("ws_tab", "/ws/feed")
+	var got []string
+	for s.Queued() > 0 {
+		e, _ := s.Next(0)
+		if !e.Replayed {
+			t.Errorf("expected %s marked replayed", e.Payload)
+		}
+		got = append(got, e.Payload)
+	}
+	if strings.Join(got, ",") != "a,b,c" {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestReplayLimits(t *testing.T) {
+	now := time.Now()
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	hub := NewHub()
+	hub.Handle("/ws/", echoHandler())
+	hub.EnableReplay(3, time.Minute)
+
+	// Only the last maxMessages are kept
+	hub.ConnectWithID("ws_a", "/ws/feed")
+	hub.Disconnect("ws_a")
+	for _, p := range []string{"1", "2", "3", "4", "5"} {
+		hub.Send("ws_a", NewEnvelope(p))
+	}
+	s, _ := hub.ConnectWithID("ws_a", "/ws/feed")
+	if got := strings.Join(drain(s), ","); got != "3*,4*,5*" {
+		t.Errorf("got %s", got)
+	}
+
+	// Buffers expire after the window
+	hub.Disconnect("ws_a")
+	hub.Send("ws_a", NewEnvelope("stale"))
+	now = now.Add(2 * time.Minute)
+	if err := hub.Send("ws_a", NewEnvelope("x")); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Send after the window err = %v, want ErrSessionNotFound", err)
+	}
+	s, _ = hub.ConnectWithID("ws_a", "/ws/feed")
+	if got := drain(s); len(got) != 0 {
+		t.Errorf("expected nothing replayed after the window, got %v", got)
+	}
+
+	// The total size is capped, dropping the oldest buffers first
+	hub.replay.maxBytes = 100
+	hub.ConnectWithID("ws_old", "/ws/feed")
+	hub.Disconnect("ws_old")
+	hub.Send("ws_old", NewEnvelope(strings.Repeat("o", 60)))
+	now = now.Add(time.Second)
+	hub.ConnectWithID("ws_new", "/ws/feed")
+	hub.Disconnect("ws_new")
+	hub.Send("ws_new", NewEnvelope(strings.Repeat("n", 60)))
+	if _, ok := hub.replay.buffers["ws_old"]; ok {
+		t.Error("expected the oldest buffer dropped over the byte cap")
+	}
+	if hub.replay.bytes > 100 {
+		t.Errorf("replay holds %d bytes, over the cap", hub.replay.bytes)
+	}
+	s, _ = hub.ConnectWithID("ws_new", "/ws/feed")
+	if got := drain(s); len(got) != 1 {
+		t.Errorf("expected the newest buffer kept, got %v", got)
+	}
+}
@@ -0,0 +1,143 @@
+package websocket
+
+import (
+	"context"
+	"slices"
+)
+
+// Join adds a session to a named room, for BroadcastToRoom. Rooms are
+// created on first join and dropped when their last member leaves; a
+// session leaves all its rooms when it is closed or disconnected.
+func (h *Hub) Join(room, sessionID string) error {
+	s, ok := h.GetSession(sessionID)
+	if !ok {
+		return ErrSessionNotFound
+	}
+
+	h.roomsMu.Lock()
+	defer h.roomsMu.Unlock()
+	// Checked under roomsMu: a session closing now either sees this join
+	// in leaveAll or is seen closed here.
+	if s.IsClosed() {
+		return ErrSessionClosed
+	}
+	if h.rooms[room] == nil {
+		h.rooms[room] = make(map[*Session]struct{})
+	}
+	h.rooms[room][s] = struct{}{}
+	if h.joined[s] == nil {
+		h.joined[s] = make(map[string]struct{})
+	}
+	h.joined[s][room] = struct{}{}
+	return nil
+}
+
+// Leave removes a session from a room. It does nothing if the session
+// isn't in the room.
+func (h *Hub) Leave(room, sessionID string) {
+	s, ok := h.GetSession(sessionID)
+	if !ok {
+		return
+	}
+	h.roomsMu.Lock()
+	defer h.roomsMu.Unlock()
+	h.leave(room, s)
+}
+
+// BroadcastToRoom sends an envelope to every session in a room.
+func (h *Hub) BroadcastToRoom(room string, envelope *Envelope) {
+	h.stamp(context.Background(), envelope)
+	sessions := h.RoomSessions(room)
+	for _, s := range sessions {
+		s.Send(envelope)
+	}
+	h.metrics.broadcast(len(sessions))
+}
+
+// BroadcastToRoomExcept sends an envelope to every session in a room
+// except the given ones, typically the sender.
+func (h *Hub) BroadcastToRoomExcept(room string, envelope *Envelope, excludeSessionIDs ...string) {
+	h.BroadcastToRoomFunc(room, except(excludeSessionIDs), envelope)
+}
+
+// BroadcastToRoomFunc sends an envelope to every session in a room for
+// which pred returns true. pred runs without hub locks held.
+func (h *Hub) BroadcastToRoomFunc(room string, pred func(*Session) bool, envelope *Envelope) {
+	h.stamp(context.Background(), envelope)
+	n := 0
+	for _, s := range h.RoomSessions(room) {
+		if pred(s) {
+			s.Send(envelope)
+			n++
+		}
+	}
+	h.metrics.broadcast(n)
+}
+
+// RoomSessions returns the sessions in a room.
+func (h *Hub) RoomSessions(room string) []*Session {
+	h.roomsMu.RLock()
+	defer h.roomsMu.RUnlock()
+
+	members := h.rooms[room]
+	result := make([]*Session, 0, len(members))
+	for s := range members {
+		result = append(result, s)
+	}
+	return result
+}
+
+// Rooms returns the rooms a session is in, sorted.
+func (h *Hub) Rooms(sessionID string) []string {
+	s, ok := h.GetSession(sessionID)
+	if !ok {
+		return nil
+	}
+	return h.sessionRooms(s)
+}
+
+// Rooms returns the rooms the session is in, sorted (see Hub.Join).
+func (s *Session) Rooms() []string {
+	if s.hub == nil {
+		return nil
+	}
+	return s.hub.sessionRooms(s)
+}
+
+func (h *Hub) sessionRooms(s *Session) []string {
+	h.roomsMu.RLock()
+	defer h.roomsMu.RUnlock()
+
+	rooms := make([]string, 0, len(h.joined[s]))
+	for room := range h.joined[s] {
+		rooms = append(rooms, room)
+	}
+	slices.Sort(rooms)
+	return rooms
+}
+
+// leaveAll removes a session from all its rooms.
+func (h *Hub) leaveAll(s *Session) {
+	h.roomsMu.Lock()
+	defer h.roomsMu.Unlock()
+	for room := range h.joined[s] {
+		h.leave(room, s)
+	}
+}
+
+// leave removes s from room, dropping empty entries. Must be called with
+// roomsMu held.
+func (h *Hub) leave(room string, s *Session) {
+	if members, ok := h.rooms[room]; ok {
+		delete(members, s)
+		if len(members) == 0 {
+			delete(h.rooms, room)
+		}
+	}
+	if rooms, ok := h.joined[s]; ok {
+		delete(rooms, room)
+		if len(rooms) == 0 {
+			delete(h.joined, s)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package websocket
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestRooms(t *testing.T) {
+	hub := NewHub()
+	hub.Handle("/ws/", echoHandler())
+
+	a, _ := hub.ConnectQueued("/ws/chat")
+	b, _ := hub.ConnectQueued("/ws/chat")
+	c, _ := hub.ConnectQueued("/ws/chat")
+
+	for _, join := range []struct{ room, id string }{
+		{"general", a.ID}, {"general", b.ID}, {"random", b.ID}, {"random", c.ID},
+	} {
+		if err := hub.Join(join.room, join.id); err != nil {
+			t.Fatalf("Join(%q, %q): %v", join.room, join.id, err)
+		}
+	}
+	// Joining twice is a no-op
+	if err := hub.Join("general", a.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := hub.Rooms(b.ID); !slices.Equal(got, []string{"general", "random"}) {
+		t.Errorf("Rooms(b) = %v", got)
+	}
+	if got := c.Rooms(); !slices.Equal(got, []string{"random"}) {
+		t.Errorf("c.Rooms() = %v", got)
+	}
+	if n := len(hub.RoomSessions("general")); n != 2 {
+		t.Errorf("expected 2 sessions in general, got %d", n)
+	}
+
+	hub.BroadcastToRoom("general", NewEnvelope("hi"))
+	if a.Queued() != 1 || b.Queued() != 1 || c.Queued() != 0 {
+		t.Errorf("queued a=%d b=%d c=%d, want 1 1 0", a.Queued(), b.Queued(), c.Queued())
+	}
+
+	hub.Leave("general", a.ID)
+	hub.Leave("general", a.ID) // Not a member any more
+	if got := a.Rooms(); len(got) != 0 {
+		t.Errorf("a.Rooms() after leave = %v", got)
+	}
+
+	hub.Disconnect(b.ID)
+	if got := hub.RoomSessions("general"); len(got) != 0 {
+		t.Errorf("expected general to be empty, got %d sessions", len(got))
+	}
+	if got := hub.RoomSessions("random"); len(got) != 1 || got[0] != c {
+		t.Errorf("expected only c in random, got %v", got)
+	}
+	if got := b.Rooms(); len(got) != 0 {
+		t.Errorf("b.Rooms() after disconnect = %v", got)
+	}
+
+	// Closing the session directly also leaves
+	c.Close()
+	if got := hub.RoomSessions("random"); len(got) != 0 {
+		t.Errorf("expected random to be empty, got %d sessions", len(got))
+	}
+	if len(hub.rooms) != 0 || len(hub.joined) != 0 {
+		t.Errorf("membership leaked: %v %v", hub.rooms, hub.joined)
+	}
+}
+
+func TestJoinErrors(t *testing.T) {
+	hub := NewHub()
+	hub.Handle("/ws/", echoHandler())
+
+	if err := hub.Join("room", "missing"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Join unknown session err = %v, want ErrSessionNotFound", err)
+	}
+
+	s, _ := hub.Connect("/ws/chat")
+	s.Close() // Still registered, but closed
+	if err := hub.Join("room", s.ID); !errors.Is(err, ErrSessionClosed) {
+		t.Errorf("Join closed session err = %v, want ErrSessionClosed", err)
+	}
+	if n := len(hub.RoomSessions("room")); n != 0 {
+		t.Errorf("expected empty room, got %d sessions", n)
+	}
+}
+
+func TestRoomsReplacedSession(t *testing.T) {
+	hub := NewHub()
+	hub.Handle("/ws/", echoHandler())
+
+	old, _ := hub.ConnectWithID("ws_fixed", "/ws/chat")
+	hub.Join("lobby", old.ID)
+
+	// Reconnecting with the same ID closes the old session; the new one
+	// joins afresh and must not lose its membership to the old close
+	s, _ := hub.ConnectWithID("ws_fixed", "/ws/chat")
+	if got := s.Rooms(); len(got) != 0 {
+		t.Errorf("new session inherited rooms %v", got)
+	}
+	hub.Join("lobby", s.ID)
+	old.Close()
+	if got := hub.RoomSessions("lobby"); len(got) != 1 || got[0] != s {
+		t.Errorf("expected only the new session in lobby, got %v", got)
+	}
+}
+
+type roomOnClose struct {
+	MessageHandlerFunc
+	rooms chan []string
+}
+
+func (h roomOnClose) OnClose(s *Session) { h.rooms <- s.Rooms() }
+
+func TestRoomsVisibleInOnClose(t *testing.T) {
+	hub := NewHub()
+	h := roomOnClose{MessageHandlerFunc: echoHandler().(MessageHandlerFunc), rooms: make(chan []string, 1)}
+	hub.Handle("/ws/", h)
+
+	s, _ := hub.Connect("/ws/chat")
+	hub.Join("lobby", s.ID)
+	hub.Disconnect(s.ID)
+	if got := <-h.rooms; !slices.Equal(got, []string{"lobby"}) {
+		t.Errorf("Rooms() in OnClose = %v", got)
+	}
+	if got := s.Rooms(); len(got) != 0 {
+		t.Errorf("Rooms() after close = %v", got)
+	}
+}
+
+func TestRoomsRace(t *testing.T) {
+	hub := NewHub()
+	hub.Handle("/ws/", echoHandler())
+
+	var (
+		wg    sync.WaitGroup
+		start = make(chan struct{})
+	)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			for j := 0; j < 50; j++ {
+				s, err := hub.ConnectQueued("/ws/chat")
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				room := fmt.Sprintf("room-%d", j%3)
+				if err := hub.Join(room, s.ID); err != nil {
+					t.Error(err)
+				}
+				hub.Join("all", s.ID)
+				hub.BroadcastToRoom(room, NewEnvelope("x"))
+				hub.BroadcastToRoom("all", NewEnvelope("y"))
+				_ = hub.Rooms(s.ID)
+				_ = hub.RoomSessions("all")
+				hub.Leave(room, s.ID)
+				if j%2 == 0 {
+					hub.Disconnect(s.ID)
+				} else {
+					s.Close()
+				}
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	hub.roomsMu.RLock()
+	defer hub.roomsMu.RUnlock()
+	if len(hub.rooms) != 0 || len(hub.joined) != 0 {
+		t.Errorf("membership leaked: %d rooms, %d sessions", len(hub.rooms), len(hub.joined))
+	}
+}
+
+func TestBroadcastToRoomVariants(t *testing.T) {
+	hub := NewHub()
+	hub.Handle("/ws/", echoHandler())
+
+	a, _ := hub.ConnectQueued("/ws/chat")
+	b, _ := hub.ConnectQueued("/ws/chat")
+	c, _ := hub.ConnectQueued("/ws/chat")
+	outside, _ := hub.ConnectQueued("/ws/chat")
+	for _, s := range []*Session{a, b, c} {
+		hub.Join("general", s.ID)
+	}
+	b.Set("muted", true)
+
+	hub.BroadcastToRoomExcept("general", NewEnvelope("msg"), a.ID)
+	hub.BroadcastToRoomFunc("general", func(s *Session) bool {
+		_, muted := s.Get("muted")
+		return !muted
+	}, NewEnvelope("msg"))
+	hub.BroadcastToRoomExcept("empty", NewEnvelope("msg"))
+
+	for _, tt := range []struct {
+		name string
+		s    *Session
+		want int
+	}{
+		{"sender", a, 1}, {"muted", b, 1}, {"member", c, 2}, {"outside", outside, 0},
+	} {
+		if got := tt.s.Queued(); got != tt.want {
+			t.Errorf("%s: got %d envelopes, want %d", tt.name, got, tt.want)
+		}
+	}
+}
@@ -2,8 +2,18 @@ package websocket
 
 import (
 	"context"
+	"fmt"
+	"log"
+	"maps"
+	"runtime/debug"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/a-h/templ"
+	"github.com/stukennedy/irgo/pkg/crash"
+	"github.com/stukennedy/irgo/pkg/datastar"
+	"github.com/stukennedy/irgo/pkg/render"
 )
 
 // Session represents a virtual WebSocket connection.
@@ -17,22 +27,77 @@ type Session struct {
 	// The mobile bridge reads from this channel.
 	SendChan chan *Envelope
 
+	// sendMu is held for reading while sending on SendChan, so Close
+	// doesn't close it under a blocked sender.
+	sendMu sync.RWMutex
+
+	// queue replaces SendChan for QueueMode sessions; nil otherwise.
+	queue *envelopeQueue
+
+	// signals holds the signals last sent by SendSignalsDiff.
+	signals *datastar.SignalDiffer
+
 	// Handler processes incoming messages.
 	Handler MessageHandler
 
+	// hub reports trace events; nil for sessions created outside a hub.
+	hub *Hub
+
+	// params holds the URL params captured by the handler's pattern.
+	params map[string]string
+
+	// maxPending caps pending; 0 means no limit (see Hub.SetMaxPending).
+	maxPending int
+
+	// limiter rate limits client messages; nil for no limit. violations
+	// counts consecutive rejections (see Hub.SetRateLimitPolicy).
+	limiter    *tokenBucket
+	violations atomic.Int64
+
+	// maxBinary limits Binary data (see Hub.SetMaxBinarySize), and
+	// binaryFrames is set once the client opts into binary frames.
+	maxBinary    int
+	binaryFrames atomic.Bool
+
 	// Pending tracks requests awaiting responses.
 	pending   map[string]*pendingRequest
 	pendingMu sync.RWMutex
 
+	// waiters holds Request calls awaiting the client's reply, by ID.
+	waiters   map[string]chan *Request
+	waitersMu sync.Mutex
+
 	// Metadata allows storing arbitrary data with the session.
 	metadata   map[string]any
 	metadataMu sync.RWMutex
 
-	// closed tracks if the session has been closed.
-	closed bool
-	mu     sync.RWMutex
+	// closed tracks if the session has been closed; done is closed with it.
+	closed      bool
+	closeReason CloseReason
+	done        chan struct{}
+	mu          sync.RWMutex
+}
+
+// DefaultBufferSize is the number of envelopes a session buffers for the
+// client before Send drops them (see Hub.SetBufferSize).
+const DefaultBufferSize = 100
+
+// CloseReason tells the client why the server closed a session.
+// Code is a WebSocket close code (RFC 6455).
+type CloseReason struct {
+	Code int
+	Text string
 }
 
+var (
+	// CloseNormal is the reason for sessions closed without one.
+	CloseNormal = CloseReason{Code: 1000, Text: "Session closed"}
+
+	// CloseGoingAway is for sessions closed because their feature or
+	// tenant was shut down.
+	CloseGoingAway = CloseReason{Code: 1001, Text: "Going away"}
+)
+
 type pendingRequest struct {
 	Request   *Request
 	Timestamp time.Time
@@ -70,26 +135,54 @@ func (f MessageHandlerFunc) OnClose(session *Session) {
 
 // NewSession creates a new WebSocket session.
 func NewSession(id, url string, handler MessageHandler) *Session {
+	return newSession(id, url, handler, DefaultBufferSize)
+}
+
+func newSession(id, url string, handler MessageHandler, buffer int) *Session {
 	return &Session{
 		ID:        id,
 		URL:       url,
 		CreatedAt: time.Now(),
-		SendChan:  make(chan *Envelope, 100), // Buffered to prevent blocking
+		SendChan:  make(chan *Envelope, buffer), // Buffered to prevent blocking
 		Handler:   handler,
 		pending:   make(map[string]*pendingRequest),
 		metadata:  make(map[string]any),
+		signals:   datastar.NewSignalDiffer(1),
+		done:      make(chan struct{}),
 	}
 }
 
-// Send queues an envelope to be sent to the client.
+// Send queues an envelope to be sent to the client. It never blocks: the
+// envelope is dropped, and false returned, if the buffer is full or the
+// session is closed. Drops are reported to Hub.OnDrop.
 func (s *Session) Send(envelope *Envelope) bool {
-	s.mu.RLock()
-	if s.closed {
-		s.mu.RUnlock()
+	if s.hub != nil {
+		s.hub.stamp(context.Background(), envelope)
+	}
+
+	if !s.trySend(envelope) {
+		s.trace(TraceDrop, envelope)
+		if s.hub != nil && s.hub.onDrop != nil {
+			s.hub.onDrop(s, envelope)
+		}
 		return false
 	}
-	s.mu.RUnlock()
+	s.trace(TraceSend, envelope)
+	return true
+}
+
+func (s *Session) trySend(envelope *Envelope) bool {
+	if s.queue != nil {
+		return s.queue.push(envelope)
+	}
 
+	s.sendMu.RLock()
+	defer s.sendMu.RUnlock()
+	select {
+	case <-s.done:
+		return false
+	default:
+	}
 	select {
 	case s.SendChan <- envelope:
 		return true
@@ -99,6 +192,52 @@ func (s *Session) Send(envelope *Envelope) bool {
 	}
 }
 
+// SendContext queues an envelope, waiting for room in a full buffer. It
+// returns ErrSessionClosed if the session is or becomes closed, and
+// ctx.Err() if ctx is done first. The envelope takes its trace ID from
+// ctx when tracing is enabled on the hub.
+func (s *Session) SendContext(ctx context.Context, envelope *Envelope) error {
+	if s.hub != nil {
+		s.hub.stamp(ctx, envelope)
+	}
+
+	if err := s.sendWait(ctx, envelope); err != nil {
+		s.trace(TraceDrop, envelope)
+		return err
+	}
+	s.trace(TraceSend, envelope)
+	return nil
+}
+
+func (s *Session) sendWait(ctx context.Context, envelope *Envelope) error {
+	if s.queue != nil {
+		return s.queue.pushWait(ctx, envelope)
+	}
+
+	s.sendMu.RLock()
+	defer s.sendMu.RUnlock()
+	select {
+	case <-s.done:
+		return ErrSessionClosed
+	default:
+	}
+	select {
+	case s.SendChan <- envelope:
+		return nil
+	case <-s.done:
+		return ErrSessionClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Session) trace(kind TraceKind, envelope *Envelope) {
+	if s.hub != nil {
+		s.hub.metrics.count(kind)
+		s.hub.emit(kind, s.ID, envelope)
+	}
+}
+
 // SendHTML sends an HTML fragment to a target element.
 func (s *Session) SendHTML(target, html string) bool {
 	return s.Send(HTMLEnvelope(target, html))
@@ -110,39 +249,170 @@ func (s *Session) Reply(requestID, html string) bool {
 	return s.Send(ReplyEnvelope(requestID, html))
 }
 
+// SendError replies to a request with an error envelope (see
+// ErrorEnvelope).
+func (s *Session) SendError(requestID string, status int, message string) bool {
+	s.clearPending(requestID)
+	return s.Send(ErrorEnvelope(requestID, status, message))
+}
+
+// SendScript runs js in the WebView.
+func (s *Session) SendScript(js string) bool {
+	return s.Send(ScriptEnvelope(js))
+}
+
+// SendTempl renders a templ component and swaps it into target. It
+// returns the render error, ErrSessionClosed, or ErrEnvelopeDropped if
+// the buffer was full.
+func (s *Session) SendTempl(target string, component templ.Component) error {
+	html, err := s.renderer().Render(component)
+	if err != nil {
+		return err
+	}
+	return s.sendOrError(HTMLEnvelope(target, html))
+}
+
+// ReplyTempl renders a templ component as the response to a request.
+// Errors are as for SendTempl.
+func (s *Session) ReplyTempl(requestID string, component templ.Component) error {
+	html, err := s.renderer().Render(component)
+	if err != nil {
+		return err
+	}
+	s.clearPending(requestID)
+	return s.sendOrError(ReplyEnvelope(requestID, html))
+}
+
+// renderer returns the hub's templ renderer (see Hub.SetRenderContext).
+func (s *Session) renderer() *render.TemplRenderer {
+	if s.hub != nil {
+		return s.hub.renderer
+	}
+	return render.NewTemplRenderer()
+}
+
+// sendOrError is Send, reporting why an envelope was dropped.
+func (s *Session) sendOrError(envelope *Envelope) error {
+	if !s.Send(envelope) {
+		if s.IsClosed() {
+			return ErrSessionClosed
+		}
+		return ErrEnvelopeDropped
+	}
+	return nil
+}
+
 // HandleMessage processes an incoming message from the client.
 func (s *Session) HandleMessage(data []byte) (*Envelope, error) {
 	req, err := ParseRequest(data)
 	if err != nil {
 		return nil, err
 	}
+	if s.hub != nil {
+		s.hub.metrics.messagesIn.Add(1)
+	}
+	if err := s.checkBinary(len(req.Binary)); err != nil {
+		return nil, err
+	}
+	if req.Type == ResponseType {
+		s.deliverResponse(req)
+		return nil, nil
+	}
+	if err := s.admit(req); err != nil {
+		return nil, err
+	}
 
 	// Track pending request for response matching
 	if req.RequestID != "" {
-		s.trackPending(req)
+		if err := s.trackPending(req); err != nil {
+			return nil, err
+		}
 	}
 
 	if s.Handler != nil {
-		return s.Handler.OnMessage(s, req)
+		req.PathParams = maps.Clone(s.params)
+		return s.onMessage(req)
 	}
 	return nil, nil
 }
 
+// Params returns the URL params captured by the pattern the session's
+// handler was registered with, e.g. roomID for "/ws/rooms/{roomID}".
+func (s *Session) Params() map[string]string {
+	return maps.Clone(s.params)
+}
+
+// Param returns a URL param, or "" if the pattern has none by that name.
+func (s *Session) Param(name string) string {
+	return s.params[name]
+}
+
+// onMessage runs the handler, turning a panic into ErrHandlerPanic and a
+// crash report so one bad message can't take down the app.
+func (s *Session) onMessage(req *Request) (envelope *Envelope, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			stack := debug.Stack()
+			log.Printf("websocket: panic handling %s message: %v\n%s", s.URL, p, stack)
+			crash.Capture(crash.SourceWebSocket, p, stack, nil, messageSummary(s, req))
+			envelope, err = nil, ErrHandlerPanic
+		}
+	}()
+	return s.Handler.OnMessage(s, req)
+}
+
+// messageSummary describes req for a crash report, redacting secret values.
+func messageSummary(s *Session, req *Request) *crash.Message {
+	msg := &crash.Message{SessionID: s.ID, URL: s.URL, Event: req.Event, Path: req.Path}
+	if len(req.Values) > 0 {
+		msg.Values = make(map[string]string, len(req.Values))
+		for k, v := range req.Values {
+			msg.Values[k] = crash.RedactValue(k, fmt.Sprint(v))
+		}
+	}
+	return msg
+}
+
 // Close marks the session as closed and cleans up.
 func (s *Session) Close() {
+	s.CloseWithReason(CloseNormal)
+}
+
+// CloseWithReason closes the session, recording why for the client.
+func (s *Session) CloseWithReason(reason CloseReason) {
 	s.mu.Lock()
 	if s.closed {
 		s.mu.Unlock()
 		return
 	}
 	s.closed = true
+	s.closeReason = reason
+	close(s.done)
 	s.mu.Unlock()
 
+	// Blocked senders see done and release sendMu
+	s.sendMu.Lock()
 	close(s.SendChan)
+	s.sendMu.Unlock()
+	if s.queue != nil {
+		s.queue.close()
+	}
 
 	if s.Handler != nil {
 		s.Handler.OnClose(s)
 	}
+	// After OnClose, so handlers can still see Rooms
+	if s.hub != nil {
+		s.hub.leaveAll(s)
+	}
+}
+
+// CloseReason returns why the session was closed; the zero value if it
+// is still open.
+func (s *Session) CloseReason() CloseReason {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.closeReason
 }
 
 // IsClosed returns true if the session has been closed.
@@ -187,6 +457,78 @@ func (s *Session) GetInt(key string) int {
 	return 0
 }
 
+// GetInt64 retrieves int64 metadata, such as a counter kept with
+// Increment.
+func (s *Session) GetInt64(key string) int64 {
+	if v, ok := s.Get(key); ok {
+		if i, ok := v.(int64); ok {
+			return i
+		}
+	}
+	return 0
+}
+
+// GetBool retrieves bool metadata.
+func (s *Session) GetBool(key string) bool {
+	if v, ok := s.Get(key); ok {
+		if b, ok := v.(bool); ok {
+			return b
+		}
+	}
+	return false
+}
+
+// GetTime retrieves time.Time metadata.
+func (s *Session) GetTime(key string) time.Time {
+	if v, ok := s.Get(key); ok {
+		if t, ok := v.(time.Time); ok {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// GetOrSet returns the metadata for key, first storing factory's result
+// if there is none. Concurrent calls for the same key all get the value
+// stored by the first. factory runs with the metadata locked, so it must
+// not use the session's metadata itself.
+func (s *Session) GetOrSet(key string, factory func() any) any {
+	s.metadataMu.Lock()
+	defer s.metadataMu.Unlock()
+	if v, ok := s.metadata[key]; ok {
+		return v
+	}
+	v := factory()
+	s.metadata[key] = v
+	return v
+}
+
+// Increment atomically adds delta to the integer metadata for key and
+// returns the new value, stored as an int64. A missing or non-integer
+// value counts as 0.
+func (s *Session) Increment(key string, delta int64) int64 {
+	s.metadataMu.Lock()
+	defer s.metadataMu.Unlock()
+	var n int64
+	switch v := s.metadata[key].(type) {
+	case int64:
+		n = v
+	case int:
+		n = int64(v)
+	}
+	n += delta
+	s.metadata[key] = n
+	return n
+}
+
+// MetadataSnapshot returns a copy of the session's metadata, e.g. for
+// logging.
+func (s *Session) MetadataSnapshot() map[string]any {
+	s.metadataMu.RLock()
+	defer s.metadataMu.RUnlock()
+	return maps.Clone(s.metadata)
+}
+
 // Delete removes metadata.
 func (s *Session) Delete(key string) {
 	s.metadataMu.Lock()
@@ -194,13 +536,17 @@ func (s *Session) Delete(key string) {
 	delete(s.metadata, key)
 }
 
-func (s *Session) trackPending(req *Request) {
+func (s *Session) trackPending(req *Request) error {
 	s.pendingMu.Lock()
 	defer s.pendingMu.Unlock()
+	if _, ok := s.pending[req.RequestID]; !ok && s.maxPending > 0 && len(s.pending) >= s.maxPending {
+		return ErrTooManyPending
+	}
 	s.pending[req.RequestID] = &pendingRequest{
 		Request:   req,
 		Timestamp: time.Now(),
 	}
+	return nil
 }
 
 func (s *Session) clearPending(requestID string) {
@@ -219,6 +565,13 @@ func (s *Session) GetPendingRequest(requestID string) *Request {
 	return nil
 }
 
+// PendingCount returns the number of requests awaiting a reply.
+func (s *Session) PendingCount() int {
+	s.pendingMu.RLock()
+	defer s.pendingMu.RUnlock()
+	return len(s.pending)
+}
+
 // CleanupExpiredPending removes pending requests older than ttl.
 func (s *Session) CleanupExpiredPending(ttl time.Duration) {
 	s.pendingMu.Lock()
@@ -0,0 +1,68 @@
+package websocket
+
+import "errors"
+
+// SignalsChannel is the channel of envelopes carrying Datastar signal
+// patches. The payload is a JSON merge patch.
+const SignalsChannel = "signals"
+
+// ErrEnvelopeDropped is returned when an envelope can't be queued
+// because the session's buffer is full.
+var ErrEnvelopeDropped = errors.New("websocket envelope dropped")
+
+// SendSignalsDiff sends only the signals that changed since the last
+// SendSignalsDiff on this session, as a JSON envelope on SignalsChannel.
+// The first call sends everything; a reconnect creates a new session,
+// so it resyncs too. Nothing is sent when nothing changed.
+func (s *Session) SendSignalsDiff(signals any) error {
+	patch, err := s.signals.Diff("", signals)
+	if err != nil || patch == nil {
+		return err
+	}
+	if !s.Send(signalsEnvelope(patch)) {
+		s.signals.Resync("")
+		if s.IsClosed() {
+			return ErrSessionClosed
+		}
+		return ErrEnvelopeDropped
+	}
+	return nil
+}
+
+// SendSignals sends signals, marshaled to JSON, as a patch on
+// SignalsChannel. The next SendSignalsDiff sends every signal again, as
+// it can't tell what this changed.
+func (s *Session) SendSignals(signals any) error {
+	envelope, err := SignalsEnvelope(signals)
+	if err != nil {
+		return err
+	}
+	s.signals.Resync("")
+	return s.sendOrError(envelope)
+}
+
+// ResyncSignals makes the next SendSignalsDiff send every signal.
+func (s *Session) ResyncSignals() {
+	s.signals.Resync("")
+}
+
+// SendSignalsDiff sends changed signals to a session; see
+// Session.SendSignalsDiff.
+func (h *Hub) SendSignalsDiff(sessionID string, signals any) error {
+	session, ok := h.GetSession(sessionID)
+	if !ok {
+		return ErrSessionNotFound
+	}
+	return session.SendSignalsDiff(signals)
+}
+
+// ResyncSignals makes the next SendSignalsDiff to a session send every
+// signal.
+func (h *Hub) ResyncSignals(sessionID string) error {
+	session, ok := h.GetSession(sessionID)
+	if !ok {
+		return ErrSessionNotFound
+	}
+	session.ResyncSignals()
+	return nil
+}
@@ -0,0 +1,73 @@
+package websocket
+
+import (
+	"testing"
+)
+
+func TestSendSignalsDiff(t *testing.T) {
+	hub := NewHub()
+	hub.HandleFunc("/ws/todos", func(s *Session, req *Request) (*Envelope, error) {
+		return nil, nil
+	})
+
+	session, err := hub.ConnectWithID("tab-1", "/ws/todos")
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := func(s *Session) *Envelope {
+		select {
+		case e := <-s.SendChan:
+			return e
+		default:
+			return nil
+		}
+	}
+
+	if err := hub.SendSignalsDiff("tab-1", map[string]any{"count": 1, "title": "Todos"}); err != nil {
+		t.Fatal(err)
+	}
+	e := next(session)
+	if e == nil || e.Channel != SignalsChannel || e.Format != "json" || e.Payload != `{"count":1,"title":"Todos"}` {
+		t.Fatalf("unexpected first envelope %+v", e)
+	}
+
+	hub.SendSignalsDiff("tab-1", map[string]any{"count": 2, "title": "Todos"})
+	if e := next(session); e == nil || e.Payload != `{"count":2}` {
+		t.Fatalf("expected diff, got %+v", e)
+	}
+
+	hub.SendSignalsDiff("tab-1", map[string]any{"count": 2, "title": "Todos"})
+	if e := next(session); e != nil {
+		t.Fatalf("expected nothing sent, got %+v", e)
+	}
+
+	if err := hub.ResyncSignals("tab-1"); err != nil {
+		t.Fatal(err)
+	}
+	hub.SendSignalsDiff("tab-1", map[string]any{"count": 2})
+	if e := next(session); e == nil || e.Payload != `{"count":2}` {
+		t.Fatalf("expected full resync, got %+v", e)
+	}
+
+	// Reconnecting starts a new session, which sends everything again
+	session, err = hub.ConnectWithID("tab-1", "/ws/todos")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hub.SendSignalsDiff("tab-1", map[string]any{"count": 2, "title": "Todos"})
+	if e := next(session); e == nil || e.Payload != `{"count":2,"title":"Todos"}` {
+		t.Fatalf("expected resync after reconnect, got %+v", e)
+	}
+
+	if err := hub.SendSignalsDiff("missing", map[string]any{}); err != ErrSessionNotFound {
+		t.Errorf("expected ErrSessionNotFound, got %v", err)
+	}
+}
+
+func TestSendSignalsDiffClosed(t *testing.T) {
+	session := NewSession("s", "/ws", nil)
+	session.Close()
+	if err := session.SendSignalsDiff(map[string]any{"a": 1}); err != ErrSessionClosed {
+		t.Errorf("expected ErrSessionClosed, got %v", err)
+	}
+}
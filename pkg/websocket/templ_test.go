@@ -0,0 +1,104 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/a-h/templ"
+)
+
+type greetingKey struct{}
+
+// greeting renders a paragraph, greeting whoever the context names.
+func greeting(name string) templ.Component {
+	return templ.ComponentFunc(func(ctx context.Context, w io.Writer) error {
+		greet, _ := ctx.Value(greetingKey{}).(string)
+		if greet == "" {
+			greet = "Hello"
+		}
+		_, err := io.WriteString(w, "<p>"+greet+", "+templ.EscapeString(name)+"</p>")
+		return err
+	})
+}
+
+var errRender = errors.New("render failed")
+
+func failing() templ.Component {
+	return templ.ComponentFunc(func(context.Context, io.Writer) error { return errRender })
+}
+
+func TestSendTempl(t *testing.T) {
+	hub := NewHub()
+	hub.Handle("/ws/", echoHandler())
+	s, _ := hub.ConnectQueued("/ws/chat")
+
+	if err := s.SendTempl("#greeting", greeting("<Ann>")); err != nil {
+		t.Fatal(err)
+	}
+	e, _ := s.Next(0)
+	if e.Target != "#greeting" || e.Payload != "<p>Hello, &lt;Ann&gt;</p>" {
+		t.Errorf("got %q %q", e.Target, e.Payload)
+	}
+
+	if _, err := s.HandleMessage([]byte(`{"type":"request","request_id":"r1"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ReplyTempl("r1", greeting("Bob")); err != nil {
+		t.Fatal(err)
+	}
+	e, _ = s.Next(0)
+	if e.RequestID != "r1" || e.Payload != "<p>Hello, Bob</p>" || s.PendingCount() != 0 {
+		t.Errorf("got %q %q, %d pending", e.RequestID, e.Payload, s.PendingCount())
+	}
+
+	// Render errors are returned and nothing is sent
+	if err := s.SendTempl("#greeting", failing()); !errors.Is(err, errRender) {
+		t.Errorf("SendTempl err = %v, want the render error", err)
+	}
+	if err := s.ReplyTempl("r2", failing()); !errors.Is(err, errRender) {
+		t.Errorf("ReplyTempl err = %v, want the render error", err)
+	}
+	if s.Queued() != 0 {
+		t.Errorf("expected nothing sent on render errors, got %d", s.Queued())
+	}
+
+	s.Close()
+	if err := s.SendTempl("#greeting", greeting("Ann")); !errors.Is(err, ErrSessionClosed) {
+		t.Errorf("SendTempl after close err = %v, want ErrSessionClosed", err)
+	}
+}
+
+func TestBroadcastTempl(t *testing.T) {
+	hub := NewHub()
+	hub.Handle("/ws/", echoHandler())
+	hub.SetRenderContext(context.WithValue(context.Background(), greetingKey{}, "Bonjour"))
+	a, _ := hub.ConnectQueued("/ws/chat")
+	b, _ := hub.ConnectQueued("/ws/chat")
+
+	if err := hub.BroadcastTempl("#greeting", greeting("tous")); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []*Session{a, b} {
+		if e, _ := s.Next(0); e == nil || e.Payload != "<p>Bonjour, tous</p>" {
+			t.Errorf("got %+v", e)
+		}
+	}
+
+	if err := hub.BroadcastTempl("#greeting", failing()); !errors.Is(err, errRender) {
+		t.Errorf("BroadcastTempl err = %v, want the render error", err)
+	}
+	if a.Queued() != 0 || b.Queued() != 0 {
+		t.Error("expected nothing broadcast on a render error")
+	}
+
+	// Sessions outside a hub render with a background context
+	s := NewSession("solo", "/ws/solo", echoHandler())
+	if err := s.SendTempl("#greeting", greeting("Ann")); err != nil {
+		t.Fatal(err)
+	}
+	if e := <-s.SendChan; e.Payload != "<p>Hello, Ann</p>" {
+		t.Errorf("got %q", e.Payload)
+	}
+}
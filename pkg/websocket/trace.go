@@ -0,0 +1,259 @@
+package websocket
+
+import (
+	"context"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// TraceKind identifies what happened to a traced envelope.
+type TraceKind string
+
+const (
+	TraceSend    TraceKind = "send"    // Queued on a session
+	TraceDrop    TraceKind = "drop"    // Session closed or buffer full
+	TraceDeliver TraceKind = "deliver" // Handed to the WebView by the bridge
+)
+
+// TraceEvent describes an envelope moving through the hub.
+type TraceEvent struct {
+	Kind      TraceKind
+	SessionID string
+	TraceID   string // Envelope.TraceID
+	Origin    string // Function that sent the envelope, when captured
+	Envelope  *Envelope
+	Time      time.Time
+}
+
+// tracer holds trace settings. Tracing is enabled only while a hook or
+// history is set; a nil tracer means it was never configured.
+type tracer struct {
+	hook    func(TraceEvent)
+	callers bool // Capture broadcast call sites (debug mode)
+	history int  // Events kept per session for RecentEnvelopes
+
+	mu     sync.Mutex
+	recent map[string][]TraceEvent
+}
+
+type traceIDKey struct{}
+
+// ContextWithTraceID returns a context carrying a trace ID for envelopes sent
+// with SendContext, SendHTMLContext or BroadcastContext.
+func ContextWithTraceID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, traceIDKey{}, id)
+}
+
+// TraceIDFromContext returns the trace ID set with ContextWithTraceID, or the
+// request ID assigned by the router's RequestID middleware.
+func TraceIDFromContext(ctx context.Context) string {
+	if id, ok := ctx.Value(traceIDKey{}).(string); ok {
+		return id
+	}
+	return middleware.GetReqID(ctx)
+}
+
+// SetTraceHook enables tracing and calls fn for every send, drop and
+// deliver. Pass nil to disable. Hooks run synchronously on the sending
+// goroutine, so keep them fast.
+func (h *Hub) SetTraceHook(fn func(TraceEvent)) {
+	h.updateTracer(func(t *tracer) { t.hook = fn })
+}
+
+// SetTraceCallers enables capturing the call site of sends that have no
+// request context (typically broadcasts) as their trace ID and origin.
+// Intended for debug builds; it has no effect unless tracing is enabled.
+func (h *Hub) SetTraceCallers(enabled bool) {
+	h.updateTracer(func(t *tracer) { t.callers = enabled })
+}
+
+// SetTraceHistory keeps the last n trace events per session for
+// RecentEnvelopes. Zero disables history.
+func (h *Hub) SetTraceHistory(n int) {
+	h.updateTracer(func(t *tracer) {
+		t.history = n
+		if n <= 0 {
+			t.recent = nil
+		}
+	})
+}
+
+// RecentEnvelopes returns the last trace events recorded for a session,
+// oldest first. Requires SetTraceHistory.
+func (h *Hub) RecentEnvelopes(sessionID string) []TraceEvent {
+	t := h.tracer.Load()
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]TraceEvent(nil), t.recent[sessionID]...)
+}
+
+// traceEventOverhead approximates the memory of a trace event besides
+// its envelope's payload.
+const traceEventOverhead = 256
+
+// TraceBytes returns the approximate memory held by trace history. For
+// use with memory.Budget.
+func (h *Hub) TraceBytes() int64 {
+	t := h.tracer.Load()
+	if t == nil {
+		return 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var n int64
+	for _, events := range t.recent {
+		for _, ev := range events {
+			n += traceEventSize(ev)
+		}
+	}
+	return n
+}
+
+// ShrinkTraces drops the oldest history of every session, in proportion,
+// until about target bytes remain. For use with memory.Budget.
+func (h *Hub) ShrinkTraces(target int64) {
+	total := h.TraceBytes()
+	t := h.tracer.Load()
+	if t == nil || total <= target {
+		return
+	}
+	ratio := float64(target) / float64(total)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for id, events := range t.recent {
+		keep := int(float64(len(events)) * ratio)
+		if keep == 0 {
+			delete(t.recent, id)
+			continue
+		}
+		t.recent[id] = append([]TraceEvent(nil), events[len(events)-keep:]...)
+	}
+}
+
+func traceEventSize(ev TraceEvent) int64 {
+	return traceEventOverhead + int64(len(ev.Envelope.Payload))
+}
+
+// MarkDelivered records that an envelope was handed to the WebView.
+// Called by the mobile and desktop bridges after forwarding.
+func (h *Hub) MarkDelivered(sessionID string, envelope *Envelope) {
+	h.emit(TraceDeliver, sessionID, envelope)
+}
+
+// updateTracer copies the current settings, applies fn and installs the
+// result.
+func (h *Hub) updateTracer(fn func(*tracer)) {
+	h.traceMu.Lock()
+	defer h.traceMu.Unlock()
+
+	next := &tracer{}
+	if cur := h.tracer.Load(); cur != nil {
+		cur.mu.Lock()
+		next.hook, next.callers, next.history, next.recent = cur.hook, cur.callers, cur.history, cur.recent
+		cur.mu.Unlock()
+	}
+	fn(next)
+
+	if next.history > 0 && next.recent == nil {
+		next.recent = make(map[string][]TraceEvent)
+	}
+	h.tracer.Store(next)
+}
+
+// activeTracer returns the tracer if tracing is enabled, else nil.
+func (h *Hub) activeTracer() *tracer {
+	t := h.tracer.Load()
+	if t == nil || (t.hook == nil && t.history <= 0) {
+		return nil
+	}
+	return t
+}
+
+// stamp fills in envelope.TraceID from ctx or, in debug mode, the caller.
+// It does nothing when tracing is disabled.
+func (h *Hub) stamp(ctx context.Context, envelope *Envelope) {
+	t := h.activeTracer()
+	if t == nil || envelope == nil || envelope.TraceID != "" {
+		return
+	}
+	if id := TraceIDFromContext(ctx); id != "" {
+		envelope.TraceID = id
+		return
+	}
+	if t.callers {
+		envelope.TraceID, envelope.origin = captureCaller()
+	}
+}
+
+// emit reports a trace event if tracing is enabled.
+func (h *Hub) emit(kind TraceKind, sessionID string, envelope *Envelope) {
+	t := h.activeTracer()
+	if t == nil || envelope == nil {
+		return
+	}
+	ev := TraceEvent{
+		Kind:      kind,
+		SessionID: sessionID,
+		TraceID:   envelope.TraceID,
+		Origin:    envelope.origin,
+		Envelope:  envelope,
+		Time:      time.Now(),
+	}
+	if t.history > 0 {
+		t.mu.Lock()
+		events := append(t.recent[sessionID], ev)
+		if len(events) > t.history {
+			events = events[len(events)-t.history:]
+		}
+		t.recent[sessionID] = events
+		t.mu.Unlock()
+	}
+	if t.hook != nil {
+		t.hook(ev)
+	}
+}
+
+// forget drops recorded history for a closed session.
+func (h *Hub) forget(sessionID string) {
+	t := h.tracer.Load()
+	if t == nil || t.recent == nil {
+		return
+	}
+	t.mu.Lock()
+	delete(t.recent, sessionID)
+	t.mu.Unlock()
+}
+
+const packagePrefix = "github.com/stukennedy/irgo/pkg/websocket."
+
+// captureCaller returns the first call site outside this package as
+// "file.go:line" and the calling function name. A variable so tests can
+// observe whether it runs.
+var captureCaller = func() (site, function string) {
+	pcs := make([]uintptr, 16)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, packagePrefix) || strings.HasSuffix(frame.File, "_test.go") {
+			file := frame.File
+			if i := strings.LastIndex(file, "/"); i >= 0 {
+				if j := strings.LastIndex(file[:i], "/"); j >= 0 {
+					file = file[j+1:]
+				}
+			}
+			return file + ":" + strconv.Itoa(frame.Line), frame.Function
+		}
+		if !more {
+			return "", ""
+		}
+	}
+}
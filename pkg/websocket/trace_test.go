@@ -0,0 +1,184 @@
+package websocket
+
+import (
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stukennedy/irgo/pkg/router"
+)
+
+type traceRecorder struct {
+	mu     sync.Mutex
+	events []TraceEvent
+}
+
+func (r *traceRecorder) hook(ev TraceEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, ev)
+}
+
+func (r *traceRecorder) kinds() []TraceKind {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var kinds []TraceKind
+	for _, ev := range r.events {
+		kinds = append(kinds, ev.Kind)
+	}
+	return kinds
+}
+
+func newTraceHub(t *testing.T) (*Hub, *Session) {
+	t.Helper()
+	hub := NewHub()
+	hub.HandleFunc("/ws/", func(*Session, *Request) (*Envelope, error) { return nil, nil })
+	session, err := hub.Connect("/ws/todos")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hub, session
+}
+
+func TestTraceFromHandler(t *testing.T) {
+	hub, session := newTraceHub(t)
+	rec := &traceRecorder{}
+	hub.SetTraceHook(rec.hook)
+
+	r := router.New()
+	r.POST("/todos", func(ctx *router.Context) (string, error) {
+		return "", hub.SendHTMLContext(ctx.Context(), session.ID, "#todos", "<li>new</li>")
+	})
+
+	req := httptest.NewRequest("POST", "/todos", nil)
+	req.Header.Set("X-Request-Id", "req-42")
+	r.Handler().ServeHTTP(httptest.NewRecorder(), req)
+
+	envelope := <-session.SendChan
+	if !strings.HasSuffix(envelope.TraceID, "req-42") {
+		t.Errorf("expected trace ID from request, got %q", envelope.TraceID)
+	}
+	hub.MarkDelivered(session.ID, envelope)
+
+	kinds := rec.kinds()
+	if len(kinds) != 2 || kinds[0] != TraceSend || kinds[1] != TraceDeliver {
+		t.Fatalf("expected send then deliver, got %v", kinds)
+	}
+	if rec.events[1].TraceID != envelope.TraceID || rec.events[1].SessionID != session.ID {
+		t.Errorf("unexpected deliver event: %+v", rec.events[1])
+	}
+}
+
+func TestTraceBroadcastCallSite(t *testing.T) {
+	hub, session := newTraceHub(t)
+	rec := &traceRecorder{}
+	hub.SetTraceHook(rec.hook)
+	hub.SetTraceCallers(true)
+
+	hub.BroadcastHTML("#status", "<p>online</p>")
+
+	envelope := <-session.SendChan
+	if !strings.HasPrefix(envelope.TraceID, "websocket/trace_test.go:") {
+		t.Errorf("expected call site trace ID, got %q", envelope.TraceID)
+	}
+	if len(rec.events) != 1 || !strings.HasSuffix(rec.events[0].Origin, "TestTraceBroadcastCallSite") {
+		t.Errorf("expected origin to be the test function, got %+v", rec.events)
+	}
+}
+
+func TestTraceDrop(t *testing.T) {
+	hub, session := newTraceHub(t)
+	rec := &traceRecorder{}
+	hub.SetTraceHook(rec.hook)
+
+	session.Close()
+	session.SendHTML("#x", "late")
+
+	if kinds := rec.kinds(); len(kinds) != 1 || kinds[0] != TraceDrop {
+		t.Errorf("expected drop event, got %v", kinds)
+	}
+}
+
+func TestTraceDisabledSkipsCallerCapture(t *testing.T) {
+	calls := 0
+	orig := captureCaller
+	captureCaller = func() (string, string) {
+		calls++
+		return orig()
+	}
+	defer func() { captureCaller = orig }()
+
+	hub, session := newTraceHub(t)
+	hub.SetTraceCallers(true) // no effect without a hook or history
+	hub.BroadcastHTML("#status", "<p>online</p>")
+
+	envelope := <-session.SendChan
+	if envelope.TraceID != "" {
+		t.Errorf("expected no trace ID, got %q", envelope.TraceID)
+	}
+	if calls != 0 {
+		t.Errorf("expected no caller capture, got %d", calls)
+	}
+
+	hub.SetTraceHook(func(TraceEvent) {})
+	hub.BroadcastHTML("#status", "<p>offline</p>")
+	if envelope := <-session.SendChan; envelope.TraceID == "" || calls != 1 {
+		t.Errorf("expected caller capture once a hook is set, got %q (%d calls)", envelope.TraceID, calls)
+	}
+
+	hub.SetTraceHook(nil)
+	hub.BroadcastHTML("#status", "<p>online</p>")
+	<-session.SendChan
+	if calls != 1 {
+		t.Errorf("expected no caller capture after removing hook, got %d", calls)
+	}
+}
+
+func TestRecentEnvelopes(t *testing.T) {
+	hub, session := newTraceHub(t)
+	hub.SetTraceHistory(2)
+
+	for _, html := range []string{"a", "b", "c"} {
+		session.SendHTML("#x", html)
+	}
+
+	recent := hub.RecentEnvelopes(session.ID)
+	if len(recent) != 2 {
+		t.Fatalf("expected 2 recent events, got %d", len(recent))
+	}
+	if recent[0].Envelope.Payload != "b" || recent[1].Envelope.Payload != "c" {
+		t.Errorf("expected last two envelopes, got %q %q", recent[0].Envelope.Payload, recent[1].Envelope.Payload)
+	}
+
+	hub.Disconnect(session.ID)
+	if len(hub.RecentEnvelopes(session.ID)) != 0 {
+		t.Error("expected history cleared on disconnect")
+	}
+}
+
+func TestShrinkTraces(t *testing.T) {
+	hub, session := newTraceHub(t)
+	if hub.TraceBytes() != 0 {
+		t.Errorf("expected no trace memory, got %d", hub.TraceBytes())
+	}
+	hub.SetTraceHistory(10)
+
+	for range 4 {
+		session.SendHTML("#x", strings.Repeat("x", 44))
+	}
+	if got := hub.TraceBytes(); got != 4*300 {
+		t.Fatalf("expected 1200 bytes, got %d", got)
+	}
+
+	hub.ShrinkTraces(600)
+	recent := hub.RecentEnvelopes(session.ID)
+	if len(recent) != 2 || hub.TraceBytes() != 600 {
+		t.Errorf("expected 2 events in 600 bytes, got %d in %d", len(recent), hub.TraceBytes())
+	}
+
+	hub.ShrinkTraces(0)
+	if len(hub.RecentEnvelopes(session.ID)) != 0 {
+		t.Error("expected history dropped")
+	}
+}